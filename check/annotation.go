@@ -16,6 +16,8 @@ package check
 
 import (
 	"errors"
+	"net/url"
+	"slices"
 	"sort"
 
 	checkv1 "buf.build/gen/go/bufbuild/bufplugin/protocolbuffers/go/buf/plugin/check/v1"
@@ -37,13 +39,33 @@ type Annotation interface {
 	// This will always be present.
 	RuleID() string
 	// Message is a user-readable message describing the failure.
+	//
+	// Will be truncated if a Client was configured with a maximum message length, see
+	// ClientWithMaxMessageLength and CheckCallWithMaxMessageLength.
 	Message() string
+	// FullMessage is the full user-readable message describing the failure.
+	//
+	// If Message was truncated, this is the original message before truncation. Otherwise,
+	// this is equal to Message.
+	FullMessage() string
 	// FileLocation is the location of the failure.
 	FileLocation() descriptor.FileLocation
 	// AgainstFileLocation is the FileLocation of the failure in the against FileDescriptors.
 	//
 	// Will only potentially be produced for breaking change rules.
 	AgainstFileLocation() descriptor.FileLocation
+	// Owners are the owners of the file that the failure is located within.
+	//
+	// Owners are not sent by plugins. They are attached by a Client that was configured
+	// with an Ownership, see ClientWithOwnership and CheckCallWithOwnership. Will be empty
+	// if no Ownership was configured, or if no owners matched the file.
+	Owners() []string
+	// DocURL is the documentation URL for the Rule that failed.
+	//
+	// Documentation URLs are not sent by plugins. They are attached by a Client that was
+	// configured with a DocURLResolver, see ClientWithDocURLResolver. Will be nil if no
+	// DocURLResolver was configured, or if the DocURLResolver returned nil for the Rule.
+	DocURL() *url.URL
 
 	toProto() *checkv1.Annotation
 
@@ -55,8 +77,11 @@ type Annotation interface {
 type annotation struct {
 	ruleID              string
 	message             string
+	fullMessage         string
 	fileLocation        descriptor.FileLocation
 	againstFileLocation descriptor.FileLocation
+	owners              []string
+	docURL              *url.URL
 }
 
 func newAnnotation(
@@ -84,6 +109,13 @@ func (a *annotation) Message() string {
 	return a.message
 }
 
+func (a *annotation) FullMessage() string {
+	if a.fullMessage != "" {
+		return a.fullMessage
+	}
+	return a.message
+}
+
 func (a *annotation) FileLocation() descriptor.FileLocation {
 	return a.fileLocation
 }
@@ -92,6 +124,14 @@ func (a *annotation) AgainstFileLocation() descriptor.FileLocation {
 	return a.againstFileLocation
 }
 
+func (a *annotation) Owners() []string {
+	return slices.Clone(a.owners)
+}
+
+func (a *annotation) DocURL() *url.URL {
+	return a.docURL
+}
+
 func (a *annotation) toProto() *checkv1.Annotation {
 	if a == nil {
 		return nil
@@ -0,0 +1,79 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// AnnotationSink receives Annotations as they are produced.
+//
+// An AnnotationSink is never called concurrently, however it is called while Rules are running,
+// and will block the Rule that produced the Annotation until it returns. An AnnotationSink should
+// return quickly, for example by handing off the Annotation to a UI.
+type AnnotationSink func(Annotation)
+
+// CheckCallWithAnnotationSink returns a new CheckCallOption that will result in each Annotation
+// being passed to the AnnotationSink.
+//
+// For Clients that run the plugin in-process, such as Clients created with NewClientForSpec,
+// Annotations are passed to the AnnotationSink as soon as they are added by a RuleHandler,
+// allowing interactive UIs to show findings immediately during long Checks instead of after
+// completion. The Annotations are passed in the order they are produced, and Annotations
+// suppressed as vendored are never passed.
+//
+// For all other Clients, the plugin protocol does not allow streaming, and the Annotations of
+// the Response are passed to the AnnotationSink once the plugin returns, before Check returns.
+//
+// In either case, the Annotations passed to the AnnotationSink are the same as the Annotations
+// on the Response returned from Check, including owners set with an Ownership. If Check returns
+// an error, Annotations may have been passed to the AnnotationSink before the error occurred.
+func CheckCallWithAnnotationSink(annotationSink AnnotationSink) CheckCallOption {
+	return func(checkCallOptions *checkCallOptions) {
+		checkCallOptions.annotationSink = annotationSink
+	}
+}
+
+// *** PRIVATE ***
+
+type annotationSinkContextKey struct{}
+
+// contextAnnotationSink is an AnnotationSink passed from a client to an in-process
+// checkServiceHandler via the context.
+//
+// pluginrpc passes the context of a call to in-process Servers, which allows the
+// checkServiceHandler to push Annotations directly. For out-of-process Servers, the context
+// is not propagated, and used will never be set.
+type contextAnnotationSink struct {
+	annotationSink AnnotationSink
+	used           atomic.Bool
+}
+
+func contextWithAnnotationSink(ctx context.Context, contextAnnotationSink *contextAnnotationSink) context.Context {
+	return context.WithValue(ctx, annotationSinkContextKey{}, contextAnnotationSink)
+}
+
+// annotationSinkForContext returns the AnnotationSink on the context, or nil if none is present.
+//
+// The AnnotationSink on the context is marked as used.
+func annotationSinkForContext(ctx context.Context) AnnotationSink {
+	contextAnnotationSink, ok := ctx.Value(annotationSinkContextKey{}).(*contextAnnotationSink)
+	if !ok || contextAnnotationSink == nil {
+		return nil
+	}
+	contextAnnotationSink.used.Store(true)
+	return contextAnnotationSink.annotationSink
+}
@@ -0,0 +1,146 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"context"
+	"testing"
+
+	descriptorv1 "buf.build/gen/go/bufbuild/bufplugin/protocolbuffers/go/buf/plugin/descriptor/v1"
+	"buf.build/go/bufplugin/descriptor"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"pluginrpc.com/pluginrpc"
+)
+
+func TestAnnotationSinkLive(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	request := testNewAnnotationSinkRequest(t)
+	ownership, err := NewOwnership([]OwnershipRule{{Path: OwnershipAllPath, Owners: []string{"@team"}}})
+	require.NoError(t, err)
+	var sinkAnnotations []Annotation
+	var numSinkAnnotationsSeenByHandler []int
+	client, err := NewClientForSpec(
+		&Spec{
+			Rules: []*RuleSpec{
+				{
+					ID:      "RULE1",
+					Default: true,
+					Purpose: "Checks RULE1.",
+					Type:    RuleTypeLint,
+					Handler: RuleHandlerFunc(
+						func(_ context.Context, responseWriter ResponseWriter, _ Request) error {
+							for _, fileName := range []string{"foo.proto", "third_party/a.proto", "foo.proto"} {
+								responseWriter.AddAnnotation(WithFileName(fileName))
+								numSinkAnnotationsSeenByHandler = append(numSinkAnnotationsSeenByHandler, len(sinkAnnotations))
+							}
+							return nil
+						},
+					),
+				},
+			},
+			VendoredPaths: []string{"third_party"},
+		},
+		ClientWithOwnership(ownership),
+	)
+	require.NoError(t, err)
+	response, err := client.Check(
+		ctx,
+		request,
+		CheckCallWithAnnotationSink(
+			func(annotation Annotation) {
+				sinkAnnotations = append(sinkAnnotations, annotation)
+			},
+		),
+	)
+	require.NoError(t, err)
+	// The Annotations were passed to the AnnotationSink before the RuleHandler returned.
+	// The vendored Annotation was never passed.
+	require.Equal(t, []int{1, 1, 2}, numSinkAnnotationsSeenByHandler)
+	require.Equal(t, []string{"RULE1:foo.proto", "RULE1:foo.proto"}, testAnnotationStrings(sinkAnnotations))
+	require.Equal(t, testAnnotationStrings(response.Annotations()), testAnnotationStrings(sinkAnnotations))
+	require.Equal(t, [][]string{{"@team"}, {"@team"}}, testAnnotationOwners(sinkAnnotations))
+}
+
+func TestAnnotationSinkNotInProcess(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	request := testNewAnnotationSinkRequest(t)
+	server, err := NewServer(
+		&Spec{
+			Rules: []*RuleSpec{
+				testNewAnnotatingLintRuleSpec("RULE1", "foo.proto", "third_party/a.proto"),
+			},
+		},
+	)
+	require.NoError(t, err)
+	client := NewClient(
+		pluginrpc.NewClient(
+			testContextDroppingRunner{runner: pluginrpc.NewServerRunner(server)},
+		),
+	)
+	var sinkAnnotations []Annotation
+	response, err := client.Check(
+		ctx,
+		request,
+		CheckCallWithAnnotationSink(
+			func(annotation Annotation) {
+				sinkAnnotations = append(sinkAnnotations, annotation)
+			},
+		),
+	)
+	require.NoError(t, err)
+	require.Equal(t, []string{"RULE1:foo.proto", "RULE1:third_party/a.proto"}, testAnnotationStrings(sinkAnnotations))
+	require.Equal(t, testAnnotationStrings(response.Annotations()), testAnnotationStrings(sinkAnnotations))
+}
+
+func testNewAnnotationSinkRequest(t *testing.T) Request {
+	fileDescriptors, err := descriptor.FileDescriptorsForProtoFileDescriptors(
+		[]*descriptorv1.FileDescriptor{
+			{
+				FileDescriptorProto: &descriptorpb.FileDescriptorProto{
+					Name:           proto.String("foo.proto"),
+					Package:        proto.String("foo"),
+					SourceCodeInfo: &descriptorpb.SourceCodeInfo{},
+				},
+			},
+			{
+				FileDescriptorProto: &descriptorpb.FileDescriptorProto{
+					Name:           proto.String("third_party/a.proto"),
+					Package:        proto.String("a"),
+					SourceCodeInfo: &descriptorpb.SourceCodeInfo{},
+				},
+			},
+		},
+	)
+	require.NoError(t, err)
+	request, err := NewRequest(fileDescriptors)
+	require.NoError(t, err)
+	return request
+}
+
+// testContextDroppingRunner simulates a plugin that is not run in-process, where the
+// context of the call is not propagated to the Server.
+type testContextDroppingRunner struct {
+	runner pluginrpc.Runner
+}
+
+func (r testContextDroppingRunner) Run(_ context.Context, env pluginrpc.Env) error {
+	return r.runner.Run(context.Background(), env)
+}
@@ -20,6 +20,7 @@ import (
 	"slices"
 
 	checkv1 "buf.build/gen/go/bufbuild/bufplugin/protocolbuffers/go/buf/plugin/check/v1"
+	"buf.build/go/bufplugin/descriptor"
 	"buf.build/go/bufplugin/internal/gen/buf/plugin/check/v1/v1pluginrpc"
 	"buf.build/go/bufplugin/internal/pkg/thread"
 	"buf.build/go/bufplugin/internal/pkg/xslices"
@@ -55,19 +56,43 @@ func CheckServiceHandlerWithParallelism(parallelism int) CheckServiceHandlerOpti
 	}
 }
 
+// CheckServiceHandlerWithoutImportSourceCodeInfo returns a new CheckServiceHandlerOption that
+// drops the SourceCodeInfo of all files that are imports when constructing Requests.
+//
+// This reduces memory usage for large dependency trees, at the cost of Rules having no source
+// locations or comments for imports. See descriptor.FileDescriptorsWithoutImportSourceCodeInfo.
+func CheckServiceHandlerWithoutImportSourceCodeInfo() CheckServiceHandlerOption {
+	return func(checkServiceHandlerOptions *checkServiceHandlerOptions) {
+		checkServiceHandlerOptions.withoutImportSourceCodeInfo = true
+	}
+}
+
+// CheckServiceHandlerWithFileDescriptorPool returns a new CheckServiceHandlerOption that
+// interns the files of all Requests within the given descriptor.FileDescriptorPool.
+//
+// This allows long-lived CheckServiceHandlers that see the same files across many requests
+// to only link each distinct file once. See descriptor.FileDescriptorsWithPool.
+func CheckServiceHandlerWithFileDescriptorPool(fileDescriptorPool descriptor.FileDescriptorPool) CheckServiceHandlerOption {
+	return func(checkServiceHandlerOptions *checkServiceHandlerOptions) {
+		checkServiceHandlerOptions.fileDescriptorPool = fileDescriptorPool
+	}
+}
+
 // *** PRIVATE ***
 
 type checkServiceHandler struct {
-	spec                 *Spec
-	parallelism          int
-	validator            *protovalidate.Validator
-	rules                []Rule
-	ruleIDToRule         map[string]Rule
-	ruleIDToRuleHandler  map[string]RuleHandler
-	ruleIDToIndex        map[string]int
-	categories           []Category
-	categoryIDToCategory map[string]Category
-	categoryIDToIndex    map[string]int
+	spec                   *Spec
+	parallelism            int
+	fileDescriptorsOptions []descriptor.FileDescriptorsOption
+	validator              *protovalidate.Validator
+	rules                  []Rule
+	ruleIDToRule           map[string]Rule
+	ruleIDToRuleHandler    map[string]RuleHandler
+	ruleIDToFileFilter     map[string]func(descriptor.FileDescriptor) bool
+	ruleIDToIndex          map[string]int
+	categories             []Category
+	categoryIDToCategory   map[string]Category
+	categoryIDToIndex      map[string]int
 }
 
 func newCheckServiceHandler(spec *Spec, options ...CheckServiceHandlerOption) (*checkServiceHandler, error) {
@@ -79,7 +104,9 @@ func newCheckServiceHandler(spec *Spec, options ...CheckServiceHandlerOption) (*
 		return nil, err
 	}
 	categorySpecs := slices.Clone(spec.Categories)
-	sortCategorySpecs(categorySpecs)
+	if !spec.PreserveOrder {
+		sortCategorySpecs(categorySpecs)
+	}
 	categories := make([]Category, len(categorySpecs))
 	categoryIDToCategory := make(map[string]Category, len(categorySpecs))
 	categoryIDToIndex := make(map[string]int, len(categorySpecs))
@@ -98,9 +125,12 @@ func newCheckServiceHandler(spec *Spec, options ...CheckServiceHandlerOption) (*
 		categoryIDToIndex[id] = i
 	}
 	ruleSpecs := slices.Clone(spec.Rules)
-	sortRuleSpecs(ruleSpecs)
+	if !spec.PreserveOrder {
+		sortRuleSpecs(ruleSpecs)
+	}
 	rules := make([]Rule, len(ruleSpecs))
 	ruleIDToRuleHandler := make(map[string]RuleHandler, len(ruleSpecs))
+	ruleIDToFileFilter := make(map[string]func(descriptor.FileDescriptor) bool, len(ruleSpecs))
 	ruleIDToRule := make(map[string]Rule, len(ruleSpecs))
 	ruleIDToIndex := make(map[string]int, len(ruleSpecs))
 	for i, ruleSpec := range ruleSpecs {
@@ -114,7 +144,8 @@ func newCheckServiceHandler(spec *Spec, options ...CheckServiceHandlerOption) (*
 			return nil, fmt.Errorf("duplicate Rule ID: %q", id)
 		}
 		rules[i] = rule
-		ruleIDToRuleHandler[id] = ruleSpec.Handler
+		ruleIDToRuleHandler[id] = WrapRuleHandler(ruleSpec.Handler, spec.RuleHandlerMiddlewares...)
+		ruleIDToFileFilter[id] = ruleSpec.FileFilter
 		ruleIDToRule[id] = rule
 		ruleIDToIndex[id] = i
 	}
@@ -122,17 +153,26 @@ func newCheckServiceHandler(spec *Spec, options ...CheckServiceHandlerOption) (*
 	if err != nil {
 		return nil, err
 	}
+	var fileDescriptorsOptions []descriptor.FileDescriptorsOption
+	if checkServiceHandlerOptions.withoutImportSourceCodeInfo {
+		fileDescriptorsOptions = append(fileDescriptorsOptions, descriptor.FileDescriptorsWithoutImportSourceCodeInfo())
+	}
+	if checkServiceHandlerOptions.fileDescriptorPool != nil {
+		fileDescriptorsOptions = append(fileDescriptorsOptions, descriptor.FileDescriptorsWithPool(checkServiceHandlerOptions.fileDescriptorPool))
+	}
 	return &checkServiceHandler{
-		spec:                 spec,
-		parallelism:          checkServiceHandlerOptions.parallelism,
-		validator:            validator,
-		rules:                rules,
-		ruleIDToRuleHandler:  ruleIDToRuleHandler,
-		ruleIDToRule:         ruleIDToRule,
-		ruleIDToIndex:        ruleIDToIndex,
-		categories:           categories,
-		categoryIDToCategory: categoryIDToCategory,
-		categoryIDToIndex:    categoryIDToIndex,
+		spec:                   spec,
+		parallelism:            checkServiceHandlerOptions.parallelism,
+		fileDescriptorsOptions: fileDescriptorsOptions,
+		validator:              validator,
+		rules:                  rules,
+		ruleIDToRuleHandler:    ruleIDToRuleHandler,
+		ruleIDToFileFilter:     ruleIDToFileFilter,
+		ruleIDToRule:           ruleIDToRule,
+		ruleIDToIndex:          ruleIDToIndex,
+		categories:             categories,
+		categoryIDToCategory:   categoryIDToCategory,
+		categoryIDToIndex:      categoryIDToIndex,
 	}, nil
 }
 
@@ -143,7 +183,7 @@ func (c *checkServiceHandler) Check(
 	if err := c.validator.Validate(checkRequest); err != nil {
 		return nil, pluginrpc.NewError(pluginrpc.CodeInvalidArgument, err)
 	}
-	request, err := RequestForProtoRequest(checkRequest)
+	request, err := RequestForProtoRequest(checkRequest, c.fileDescriptorsOptions...)
 	if err != nil {
 		return nil, err
 	}
@@ -164,10 +204,17 @@ func (c *checkServiceHandler) Check(
 			rules = append(rules, rule)
 		}
 	}
+	vendoredMatcher, err := newVendoredMatcher(c.spec, request.Options())
+	if err != nil {
+		return nil, pluginrpc.NewError(pluginrpc.CodeInvalidArgument, err)
+	}
 	multiResponseWriter, err := newMultiResponseWriter(request)
 	if err != nil {
 		return nil, err
 	}
+	if annotationSink := annotationSinkForContext(ctx); annotationSink != nil {
+		multiResponseWriter.annotationSink = annotationSinkWithVendoredMatcher(annotationSink, vendoredMatcher)
+	}
 	if err := thread.Parallelize(
 		ctx,
 		xslices.Map(
@@ -179,10 +226,17 @@ func (c *checkServiceHandler) Check(
 						// This should never happen.
 						return fmt.Errorf("no RuleHandler for id %q", rule.ID())
 					}
+					ruleRequest, ok, err := requestForFileFilter(request, c.ruleIDToFileFilter[rule.ID()])
+					if err != nil {
+						return err
+					}
+					if !ok {
+						return nil
+					}
 					return ruleHandler.Handle(
-						ctx,
+						contextWithRuleID(ctx, rule.ID()),
 						multiResponseWriter.newResponseWriter(rule.ID()),
-						request,
+						ruleRequest,
 					)
 				}
 			},
@@ -195,6 +249,12 @@ func (c *checkServiceHandler) Check(
 	if err != nil {
 		return nil, err
 	}
+	if vendoredMatcher != nil {
+		response, err = newResponse(vendoredMatcher.filterAnnotations(response.Annotations()))
+		if err != nil {
+			return nil, err
+		}
+	}
 	checkResponse := response.toProto()
 	if err := c.validator.Validate(checkResponse); err != nil {
 		return nil, err
@@ -245,13 +305,9 @@ func (c *checkServiceHandler) ListCategories(_ context.Context, listCategoriesRe
 }
 
 func (c *checkServiceHandler) getRulesAndNextPageToken(pageSize int, pageToken string) ([]Rule, string, error) {
-	index := 0
-	if pageToken != "" {
-		var ok bool
-		index, ok = c.ruleIDToIndex[pageToken]
-		if !ok {
-			return nil, "", pluginrpc.NewErrorf(pluginrpc.CodeInvalidArgument, "unknown page token: %q", pageToken)
-		}
+	index, err := indexForPageTokenOrID(pageToken, c.ruleIDToIndex, len(c.rules))
+	if err != nil {
+		return nil, "", err
 	}
 	if pageSize == 0 {
 		pageSize = defaultPageSize
@@ -266,19 +322,15 @@ func (c *checkServiceHandler) getRulesAndNextPageToken(pageSize int, pageToken s
 	}
 	var nextPageToken string
 	if index < len(c.rules) {
-		nextPageToken = c.rules[index].ID()
+		nextPageToken = pageTokenForIndex(index, len(c.rules))
 	}
 	return resultRules, nextPageToken, nil
 }
 
 func (c *checkServiceHandler) getCategoriesAndNextPageToken(pageSize int, pageToken string) ([]Category, string, error) {
-	index := 0
-	if pageToken != "" {
-		var ok bool
-		index, ok = c.categoryIDToIndex[pageToken]
-		if !ok {
-			return nil, "", pluginrpc.NewErrorf(pluginrpc.CodeInvalidArgument, "unknown page token: %q", pageToken)
-		}
+	index, err := indexForPageTokenOrID(pageToken, c.categoryIDToIndex, len(c.categories))
+	if err != nil {
+		return nil, "", err
 	}
 	if pageSize == 0 {
 		pageSize = defaultPageSize
@@ -293,15 +345,34 @@ func (c *checkServiceHandler) getCategoriesAndNextPageToken(pageSize int, pageTo
 	}
 	var nextPageToken string
 	if index < len(c.categories) {
-		nextPageToken = c.categories[index].ID()
+		nextPageToken = pageTokenForIndex(index, len(c.categories))
 	}
 	return resultCategories, nextPageToken, nil
 }
 
 type checkServiceHandlerOptions struct {
-	parallelism int
+	parallelism                 int
+	withoutImportSourceCodeInfo bool
+	fileDescriptorPool          descriptor.FileDescriptorPool
 }
 
 func newCheckServiceHandlerOptions() *checkServiceHandlerOptions {
 	return &checkServiceHandlerOptions{}
 }
+
+// indexForPageTokenOrID returns the index of the first item of the page for the page token.
+//
+// Page tokens are either indexes, or IDs, which were returned as page tokens by previous
+// versions.
+func indexForPageTokenOrID(pageToken string, idToIndex map[string]int, length int) (int, error) {
+	if pageToken == "" {
+		return 0, nil
+	}
+	if index, pageTokenLength, ok := indexForPageToken(pageToken); ok && pageTokenLength == length {
+		return index, nil
+	}
+	if index, ok := idToIndex[pageToken]; ok {
+		return index, nil
+	}
+	return 0, pluginrpc.NewErrorf(pluginrpc.CodeInvalidArgument, "unknown page token: %q", pageToken)
+}
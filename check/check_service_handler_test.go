@@ -20,6 +20,7 @@ import (
 
 	checkv1 "buf.build/gen/go/bufbuild/bufplugin/protocolbuffers/go/buf/plugin/check/v1"
 	descriptorv1 "buf.build/gen/go/bufbuild/bufplugin/protocolbuffers/go/buf/plugin/descriptor/v1"
+	"buf.build/go/bufplugin/descriptor"
 	"github.com/stretchr/testify/require"
 	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/types/descriptorpb"
@@ -115,3 +116,193 @@ func TestCheckServiceHandlerUniqueFiles(t *testing.T) {
 	require.ErrorAs(t, err, &pluginrpcError)
 	require.Equal(t, pluginrpc.CodeInvalidArgument, pluginrpcError.Code())
 }
+
+func TestCheckServiceHandlerWithoutImportSourceCodeInfo(t *testing.T) {
+	t.Parallel()
+
+	var request Request
+	checkServiceHandler, err := NewCheckServiceHandler(
+		&Spec{
+			Rules: []*RuleSpec{
+				testNewSimpleLintRuleSpec("RULE1", nil, true, false, nil),
+			},
+			Before: func(ctx context.Context, beforeRequest Request) (context.Context, Request, error) {
+				request = beforeRequest
+				return ctx, beforeRequest, nil
+			},
+		},
+		CheckServiceHandlerWithoutImportSourceCodeInfo(),
+	)
+	require.NoError(t, err)
+	newSourceCodeInfo := func() *descriptorpb.SourceCodeInfo {
+		return &descriptorpb.SourceCodeInfo{
+			Location: []*descriptorpb.SourceCodeInfo_Location{
+				{
+					Path: []int32{},
+					Span: []int32{0, 0, 1, 0},
+				},
+			},
+		}
+	}
+	checkRequest := &checkv1.CheckRequest{
+		FileDescriptors: []*descriptorv1.FileDescriptor{
+			{
+				FileDescriptorProto: &descriptorpb.FileDescriptorProto{
+					Name:           proto.String("foo.proto"),
+					SourceCodeInfo: newSourceCodeInfo(),
+				},
+			},
+			{
+				FileDescriptorProto: &descriptorpb.FileDescriptorProto{
+					Name:           proto.String("bar.proto"),
+					SourceCodeInfo: newSourceCodeInfo(),
+				},
+				IsImport: true,
+			},
+		},
+	}
+	_, err = checkServiceHandler.Check(context.Background(), checkRequest)
+	require.NoError(t, err)
+	fileDescriptors := request.FileDescriptors()
+	require.Len(t, fileDescriptors, 2)
+	require.Equal(t, 1, fileDescriptors[0].ProtoreflectFileDescriptor().SourceLocations().Len())
+	require.True(t, fileDescriptors[1].IsImport())
+	require.Equal(t, 0, fileDescriptors[1].ProtoreflectFileDescriptor().SourceLocations().Len())
+	// The CheckRequest is not modified.
+	require.Len(t, checkRequest.GetFileDescriptors()[1].GetFileDescriptorProto().GetSourceCodeInfo().GetLocation(), 1)
+}
+
+func TestCheckServiceHandlerWithFileDescriptorPool(t *testing.T) {
+	t.Parallel()
+
+	var requests []Request
+	fileDescriptorPool := descriptor.NewFileDescriptorPool()
+	checkServiceHandler, err := NewCheckServiceHandler(
+		&Spec{
+			Rules: []*RuleSpec{
+				testNewSimpleLintRuleSpec("RULE1", nil, true, false, nil),
+			},
+			Before: func(ctx context.Context, beforeRequest Request) (context.Context, Request, error) {
+				requests = append(requests, beforeRequest)
+				return ctx, beforeRequest, nil
+			},
+		},
+		CheckServiceHandlerWithFileDescriptorPool(fileDescriptorPool),
+	)
+	require.NoError(t, err)
+	newCheckRequest := func() *checkv1.CheckRequest {
+		return &checkv1.CheckRequest{
+			FileDescriptors: []*descriptorv1.FileDescriptor{
+				{
+					FileDescriptorProto: &descriptorpb.FileDescriptorProto{
+						Name:           proto.String("foo.proto"),
+						Dependency:     []string{"bar.proto"},
+						SourceCodeInfo: &descriptorpb.SourceCodeInfo{},
+					},
+				},
+				{
+					FileDescriptorProto: &descriptorpb.FileDescriptorProto{
+						Name:           proto.String("bar.proto"),
+						SourceCodeInfo: &descriptorpb.SourceCodeInfo{},
+					},
+					IsImport: true,
+				},
+			},
+		}
+	}
+	_, err = checkServiceHandler.Check(context.Background(), newCheckRequest())
+	require.NoError(t, err)
+	_, err = checkServiceHandler.Check(context.Background(), newCheckRequest())
+	require.NoError(t, err)
+	require.Len(t, requests, 2)
+	require.Equal(t, 2, fileDescriptorPool.Len())
+	for i := 0; i < 2; i++ {
+		require.Same(
+			t,
+			requests[0].FileDescriptors()[i].ProtoreflectFileDescriptor(),
+			requests[1].FileDescriptors()[i].ProtoreflectFileDescriptor(),
+		)
+	}
+}
+
+func TestCheckServiceHandlerUnlinkableFiles(t *testing.T) {
+	t.Parallel()
+
+	checkServiceHandler, err := NewCheckServiceHandler(
+		&Spec{
+			Rules: []*RuleSpec{
+				testNewSimpleLintRuleSpec("RULE1", nil, true, false, nil),
+			},
+		},
+	)
+	require.NoError(t, err)
+	testCases := []struct {
+		name                string
+		fileDescriptorProto *descriptorpb.FileDescriptorProto
+		expectedError       string
+	}{
+		{
+			name:          "unresolved_type_name",
+			expectedError: `cannot resolve type: "a.Bar" not found`,
+			fileDescriptorProto: &descriptorpb.FileDescriptorProto{
+				Name:    proto.String("foo.proto"),
+				Package: proto.String("a"),
+				MessageType: []*descriptorpb.DescriptorProto{
+					{
+						Name: proto.String("Foo"),
+						Field: []*descriptorpb.FieldDescriptorProto{
+							{
+								Name:     proto.String("bar"),
+								Number:   proto.Int32(1),
+								Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+								Type:     descriptorpb.FieldDescriptorProto_TYPE_MESSAGE.Enum(),
+								TypeName: proto.String(".a.Bar"),
+							},
+						},
+					},
+				},
+				SourceCodeInfo: &descriptorpb.SourceCodeInfo{},
+			},
+		},
+		{
+			name:          "proto3_required_field",
+			expectedError: "cannot be required",
+			fileDescriptorProto: &descriptorpb.FileDescriptorProto{
+				Name:    proto.String("foo.proto"),
+				Package: proto.String("a"),
+				Syntax:  proto.String("proto3"),
+				MessageType: []*descriptorpb.DescriptorProto{
+					{
+						Name: proto.String("Foo"),
+						Field: []*descriptorpb.FieldDescriptorProto{
+							{
+								Name:   proto.String("bar"),
+								Number: proto.Int32(1),
+								Label:  descriptorpb.FieldDescriptorProto_LABEL_REQUIRED.Enum(),
+								Type:   descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(),
+							},
+						},
+					},
+				},
+				SourceCodeInfo: &descriptorpb.SourceCodeInfo{},
+			},
+		},
+	}
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+			_, err := checkServiceHandler.Check(
+				context.Background(),
+				&checkv1.CheckRequest{
+					FileDescriptors: []*descriptorv1.FileDescriptor{
+						{
+							FileDescriptorProto: testCase.fileDescriptorProto,
+						},
+					},
+				},
+			)
+			require.ErrorContains(t, err, testCase.expectedError)
+		})
+	}
+}
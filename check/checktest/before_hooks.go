@@ -0,0 +1,107 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package checktest
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"buf.build/go/bufplugin/check"
+	"github.com/stretchr/testify/require"
+)
+
+// *** PRIVATE ***
+
+// beforeHooks wraps Spec.Before to call the AssertBeforeInput and AssertBeforeOutput
+// functions of a CheckTest.
+//
+// The Spec is wrapped once per CheckTest, but assertions are made against the *testing.T
+// of the Check call currently in progress, which may be a subtest. Calls are counted so
+// that a CheckTest fails if the hooks were never reached.
+type beforeHooks struct {
+	assertInput  func(t *testing.T, ctx context.Context, request check.Request)
+	assertOutput func(t *testing.T, ctx context.Context, request check.Request, err error)
+
+	lock     sync.Mutex
+	t        *testing.T
+	numCalls int
+}
+
+// newBeforeHooks returns a new beforeHooks, or nil if neither function is set.
+func newBeforeHooks(
+	assertInput func(t *testing.T, ctx context.Context, request check.Request),
+	assertOutput func(t *testing.T, ctx context.Context, request check.Request, err error),
+) *beforeHooks {
+	if assertInput == nil && assertOutput == nil {
+		return nil
+	}
+	return &beforeHooks{
+		assertInput:  assertInput,
+		assertOutput: assertOutput,
+	}
+}
+
+// wrapSpec returns a copy of the Spec with Before wrapped to call the hooks.
+func (b *beforeHooks) wrapSpec(spec *check.Spec) *check.Spec {
+	before := spec.Before
+	wrappedSpec := *spec
+	wrappedSpec.Before = func(ctx context.Context, request check.Request) (context.Context, check.Request, error) {
+		t := b.called()
+		if b.assertInput != nil {
+			b.assertInput(t, ctx, request)
+		}
+		var err error
+		if before != nil {
+			ctx, request, err = before(ctx, request)
+		}
+		if b.assertOutput != nil {
+			b.assertOutput(t, ctx, request, err)
+		}
+		return ctx, request, err
+	}
+	return &wrappedSpec
+}
+
+// start sets the *testing.T for the Check call about to be made, and returns the number
+// of calls so far. It is a no-op on a nil beforeHooks.
+func (b *beforeHooks) start(t *testing.T) int {
+	if b == nil {
+		return 0
+	}
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.t = t
+	return b.numCalls
+}
+
+// requireCalledSince fails the test if the hooks were not called since start returned
+// numCalls. It is a no-op on a nil beforeHooks.
+func (b *beforeHooks) requireCalledSince(t *testing.T, numCalls int) {
+	if b == nil {
+		return
+	}
+	b.lock.Lock()
+	called := b.numCalls > numCalls
+	b.lock.Unlock()
+	require.True(t, called, "Spec.Before was not called, but AssertBeforeInput or AssertBeforeOutput was set")
+}
+
+func (b *beforeHooks) called() *testing.T {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.numCalls++
+	return b.t
+}
@@ -0,0 +1,80 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package checktest
+
+import (
+	"context"
+	"testing"
+
+	"buf.build/go/bufplugin/check"
+	"github.com/stretchr/testify/require"
+)
+
+// BenchmarkRule benchmarks the RuleHandler for the Rule with the given ID within the Spec.
+//
+// The RequestSpec is compiled once, and Spec.Before is called once, outside of the benchmark
+// timer. The RuleHandler is then invoked directly with check.HandleRule b.N times, so that
+// the results measure the RuleHandler, and not compilation or serialization. Allocations
+// are reported, along with the number of annotations produced per operation.
+//
+//	func BenchmarkFieldLowerSnakeCase(b *testing.B) {
+//		checktest.BenchmarkRule(
+//			b,
+//			spec,
+//			&checktest.RequestSpec{
+//				Files: &checktest.ProtoFileSpec{
+//					DirPaths:  []string{"testdata/simple"},
+//					FilePaths: []string{"simple.proto"},
+//				},
+//			},
+//			fieldLowerSnakeCaseRuleID,
+//		)
+//	}
+//
+// The RuleIDs on the RequestSpec are ignored.
+func BenchmarkRule(b *testing.B, spec *check.Spec, requestSpec *RequestSpec, ruleID string) {
+	ctx := context.Background()
+
+	require.NotNil(b, spec)
+	require.NotNil(b, requestSpec)
+	var ruleSpec *check.RuleSpec
+	for _, candidate := range spec.Rules {
+		if candidate.ID == ruleID {
+			ruleSpec = candidate
+			break
+		}
+	}
+	require.NotNil(b, ruleSpec, "no Rule with ID %q in Spec", ruleID)
+
+	request, err := requestSpec.ToRequest(ctx)
+	require.NoError(b, err)
+	if spec.Before != nil {
+		ctx, request, err = spec.Before(ctx, request)
+		require.NoError(b, err)
+	}
+
+	var numAnnotations int
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		response, err := check.HandleRule(ctx, ruleSpec, request)
+		if err != nil {
+			require.NoError(b, err)
+		}
+		numAnnotations = len(response.Annotations())
+	}
+	b.StopTimer()
+	b.ReportMetric(float64(numAnnotations), "annotations/op")
+}
@@ -0,0 +1,320 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package checktest
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"buf.build/go/bufplugin/check"
+	"buf.build/go/bufplugin/option"
+	"github.com/stretchr/testify/require"
+)
+
+// CheckTests is a set of Check tests that share the same files.
+//
+// The Files and AgainstFiles are compiled once, and then each case is run as a subtest.
+// This is useful when many tests use the same testdata with different RuleIDs
+// or Options, as compilation usually dominates the runtime of a test.
+//
+//	checktest.CheckTests{
+//		Files: &checktest.ProtoFileSpec{
+//			DirPaths:  []string{"testdata"},
+//			FilePaths: []string{"simple.proto"},
+//		},
+//		Spec: spec,
+//		Cases: []checktest.CheckTestCase{
+//			{
+//				Name:    "default",
+//				RuleIDs: []string{"RULE_ONE"},
+//				ExpectedAnnotations: ...,
+//			},
+//			{
+//				Name:    "with_suffix",
+//				RuleIDs: []string{"RULE_ONE"},
+//				Options: map[string]any{"suffix": "_time"},
+//				ExpectedAnnotations: ...,
+//			},
+//		},
+//	}.Run(t)
+type CheckTests struct {
+	// Files specifies the input files to test against.
+	//
+	// Required.
+	Files *ProtoFileSpec
+	// AgainstFiles specifies the input against files to test against, if any.
+	AgainstFiles *ProtoFileSpec
+	// Spec is the Spec to test.
+	//
+	// Required unless Binary is set. Must not be set if Binary is set.
+	Spec *check.Spec
+	// Binary is the path to a built plugin binary to test.
+	//
+	// See CheckTest.Binary.
+	Binary string
+	// BinaryArgs are the arguments for the Binary. See CheckTest.BinaryArgs.
+	BinaryArgs []string
+	// ClientOptions are the options for the Client used to call Check.
+	//
+	// See CheckTest.ClientOptions.
+	ClientOptions []check.ClientOption
+	// Wire says to call the Spec as if it was running in a separate process.
+	//
+	// See CheckTest.Wire.
+	Wire bool
+	// Cases are the cases to run.
+	//
+	// Required to have at least one element.
+	Cases []CheckTestCase
+	// Parallel says to run the cases in parallel with each other.
+	//
+	// Each case calls t.Parallel within its subtest. The Files and AgainstFiles are still
+	// built once and the Client is shared, so cases must not depend on being run in order.
+	Parallel bool
+	// ReportTiming says to log how long the Files and AgainstFiles took to build, and how
+	// long each case took, once all cases have completed.
+	//
+	// Cases are listed from slowest to fastest. The report is logged with t.Logf, and is
+	// therefore only shown with go test -v, or if the test fails. This is useful to find
+	// the cases that dominate the runtime of a large test.
+	ReportTiming bool
+	// ReportRuleCounts says to log the number of Annotations produced by each Rule across
+	// all cases, once all cases have completed.
+	//
+	// See CheckTest.ReportRuleCounts.
+	ReportRuleCounts bool
+}
+
+// CheckTestCase is a single case within CheckTests.
+type CheckTestCase struct {
+	// Name is the name of the subtest.
+	//
+	// Required.
+	Name string
+	// RuleIDs are the specific RuleIDs to run.
+	RuleIDs []string
+	// Options are any options to pass to the plugin.
+	Options map[string]any
+	// ExpectedAnnotations are the expected Annotations that should be returned.
+	//
+	// Must not be set if GoldenFilePath or ExpectedAnnotationsFilePath is set.
+	ExpectedAnnotations []ExpectedAnnotation
+	// ExpectedAnnotationsFilePath is the path to a YAML or JSON fixture file containing the
+	// expected Annotations.
+	//
+	// See CheckTest.ExpectedAnnotationsFilePath.
+	//
+	// Must not be set if ExpectedAnnotations, GoldenFilePath, or WantComments is set.
+	ExpectedAnnotationsFilePath string
+	// GoldenFilePath is the path to a golden file containing the expected Annotations.
+	//
+	// See CheckTest.GoldenFilePath.
+	//
+	// Must not be set if ExpectedAnnotations is set.
+	GoldenFilePath string
+	// ForbiddenAnnotations are Annotations that must not be returned.
+	//
+	// See CheckTest.ForbiddenAnnotations.
+	ForbiddenAnnotations []ExpectedAnnotation
+	// SuppressedAnnotations are Annotations that must be produced by the Rules, but suppressed.
+	//
+	// See CheckTest.SuppressedAnnotations.
+	SuppressedAnnotations []ExpectedSuppressedAnnotation
+	// SubtestGrouping determines how the comparison with ExpectedAnnotations is split into subtests.
+	//
+	// See CheckTest.SubtestGrouping.
+	SubtestGrouping SubtestGrouping
+	// WantComments says to derive the expected Annotations from want comments within the Files.
+	//
+	// See CheckTest.WantComments.
+	//
+	// Must not be set if ExpectedAnnotations or GoldenFilePath is set.
+	WantComments bool
+	// ExpectedError is the error that the Check call is expected to fail with.
+	//
+	// See CheckTest.ExpectedError.
+	ExpectedError *ExpectedError
+}
+
+// Run runs the tests.
+//
+// This will:
+//
+//   - Build the Files and AgainstFiles once.
+//   - Create a new Client based on the Spec, or the Binary if set, that is shared by all cases.
+//   - For each case, run a subtest named by the case Name that will create a new Request with
+//     the case RuleIDs and Options, call Check on the Client, and compare the resulting Annotations
+//     with the ExpectedAnnotations, the fixture file at ExpectedAnnotationsFilePath, or the golden
+//     file at GoldenFilePath, failing if there is a mismatch, and fail if any of the resulting
+//     Annotations match the case ForbiddenAnnotations, or any of the case SuppressedAnnotations
+//     were not suppressed.
+//   - If ReportTiming is set, log the time taken to build the files and to run each case.
+//   - If ReportRuleCounts is set, log the number of Annotations produced by each Rule across all cases.
+//
+// If Parallel is set, the cases are run in parallel.
+func (c CheckTests) Run(t *testing.T) {
+	ctx := context.Background()
+
+	require.NotNil(t, c.Files)
+	require.NotEmpty(t, c.Cases)
+	client, err := newClientForTest(c.Spec, c.Binary, c.BinaryArgs, c.ClientOptions, c.Wire)
+	require.NoError(t, err)
+
+	start := time.Now()
+	fileDescriptors, err := c.Files.ToFileDescriptors(ctx)
+	require.NoError(t, err)
+	againstFileDescriptors, err := c.AgainstFiles.ToFileDescriptors(ctx)
+	require.NoError(t, err)
+	var report *timingReport
+	if c.ReportTiming {
+		report = newTimingReport(time.Since(start))
+		// Cleanup functions run after all parallel subtests have completed.
+		t.Cleanup(func() { t.Log(report.String()) })
+	}
+
+	var ruleCountReport *ruleCountReport
+	if c.ReportRuleCounts {
+		ruleCountReport = newRuleCountReportForTest(ctx, t, client)
+	}
+
+	names := make(map[string]struct{}, len(c.Cases))
+	for _, checkTestCase := range c.Cases {
+		require.NotEmpty(t, checkTestCase.Name, "CheckTestCase.Name is required")
+		_, ok := names[checkTestCase.Name]
+		require.False(t, ok, "duplicate CheckTestCase.Name: %q", checkTestCase.Name)
+		names[checkTestCase.Name] = struct{}{}
+	}
+	for _, checkTestCase := range c.Cases {
+		checkTestCase := checkTestCase
+		t.Run(
+			checkTestCase.Name,
+			func(t *testing.T) {
+				if c.Parallel {
+					t.Parallel()
+				}
+				if report != nil {
+					start := time.Now()
+					defer func() { report.add(checkTestCase.Name, time.Since(start)) }()
+				}
+				if checkTestCase.GoldenFilePath != "" {
+					require.Empty(t, checkTestCase.ExpectedAnnotations, "ExpectedAnnotations cannot be set if GoldenFilePath is set")
+				}
+				if checkTestCase.WantComments {
+					require.Empty(t, checkTestCase.ExpectedAnnotations, "ExpectedAnnotations cannot be set if WantComments is set")
+					require.Empty(t, checkTestCase.GoldenFilePath, "GoldenFilePath cannot be set if WantComments is set")
+				}
+				expectedAnnotations := checkTestCase.ExpectedAnnotations
+				if checkTestCase.ExpectedAnnotationsFilePath != "" {
+					require.Empty(t, checkTestCase.ExpectedAnnotations, "ExpectedAnnotations cannot be set if ExpectedAnnotationsFilePath is set")
+					require.Empty(t, checkTestCase.GoldenFilePath, "GoldenFilePath cannot be set if ExpectedAnnotationsFilePath is set")
+					require.False(t, checkTestCase.WantComments, "WantComments cannot be set if ExpectedAnnotationsFilePath is set")
+					var err error
+					expectedAnnotations, err = ReadExpectedAnnotationsFile(checkTestCase.ExpectedAnnotationsFilePath)
+					require.NoError(t, err)
+				}
+				options, err := option.NewOptions(checkTestCase.Options)
+				require.NoError(t, err)
+				request, err := check.NewRequest(
+					fileDescriptors,
+					check.WithAgainstFileDescriptors(againstFileDescriptors),
+					check.WithOptions(options),
+					check.WithRuleIDs(checkTestCase.RuleIDs...),
+				)
+				require.NoError(t, err)
+				runCheckAndAssert(
+					ctx,
+					t,
+					client,
+					request,
+					&checkAssertions{
+						expectedAnnotations:   expectedAnnotations,
+						goldenFilePath:        checkTestCase.GoldenFilePath,
+						forbiddenAnnotations:  checkTestCase.ForbiddenAnnotations,
+						suppressedAnnotations: checkTestCase.SuppressedAnnotations,
+						spec:                  c.Spec,
+						subtestGrouping:       checkTestCase.SubtestGrouping,
+						wantComments:          checkTestCase.WantComments,
+						expectedError:         checkTestCase.ExpectedError,
+						ruleCountReport:       ruleCountReport,
+					},
+				)
+			},
+		)
+	}
+}
+
+// *** PRIVATE ***
+
+// timingReport records the durations of the cases within CheckTests.
+//
+// Cases may complete concurrently if CheckTests.Parallel is set.
+type timingReport struct {
+	buildDuration time.Duration
+	caseTimings   []caseTiming
+	lock          sync.Mutex
+}
+
+type caseTiming struct {
+	name     string
+	duration time.Duration
+}
+
+func newTimingReport(buildDuration time.Duration) *timingReport {
+	return &timingReport{
+		buildDuration: buildDuration,
+	}
+}
+
+func (r *timingReport) add(name string, duration time.Duration) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.caseTimings = append(r.caseTimings, caseTiming{name: name, duration: duration})
+}
+
+func (r *timingReport) String() string {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	caseTimings := make([]caseTiming, len(r.caseTimings))
+	copy(caseTimings, r.caseTimings)
+	sort.SliceStable(
+		caseTimings,
+		func(i int, j int) bool {
+			return caseTimings[i].duration > caseTimings[j].duration
+		},
+	)
+	var total time.Duration
+	nameWidth := 0
+	for _, caseTiming := range caseTimings {
+		total += caseTiming.duration
+		nameWidth = max(nameWidth, len(caseTiming.name))
+	}
+	var sb strings.Builder
+	_, _ = fmt.Fprintf(
+		&sb,
+		"built files in %v, ran %d cases in %v total\n",
+		r.buildDuration.Round(time.Microsecond),
+		len(caseTimings),
+		total.Round(time.Microsecond),
+	)
+	for _, caseTiming := range caseTimings {
+		_, _ = fmt.Fprintf(&sb, "  %-*s  %v\n", nameWidth, caseTiming.name, caseTiming.duration.Round(time.Microsecond))
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
@@ -21,25 +21,25 @@ package checktest
 import (
 	"context"
 	"errors"
-	"path/filepath"
+	"fmt"
+	"io/fs"
+	"regexp"
 	"strconv"
+	"strings"
 	"testing"
 
-	descriptorv1 "buf.build/gen/go/bufbuild/bufplugin/protocolbuffers/go/buf/plugin/descriptor/v1"
 	"buf.build/go/bufplugin/check"
 	"buf.build/go/bufplugin/descriptor"
+	"buf.build/go/bufplugin/descriptor/descriptortest"
+	"buf.build/go/bufplugin/internal/pkg/xprotocompile"
 	"buf.build/go/bufplugin/internal/pkg/xslices"
 	"buf.build/go/bufplugin/option"
 	"github.com/bufbuild/protocompile"
-	"github.com/bufbuild/protocompile/linker"
-	"github.com/bufbuild/protocompile/parser"
-	"github.com/bufbuild/protocompile/protoutil"
-	"github.com/bufbuild/protocompile/reporter"
-	"github.com/bufbuild/protocompile/wellknownimports"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"google.golang.org/protobuf/reflect/protoreflect"
 	"google.golang.org/protobuf/types/descriptorpb"
+	"pluginrpc.com/pluginrpc"
 )
 
 // SpecTest tests your spec with check.ValidateSpec.
@@ -60,10 +60,155 @@ type CheckTest struct {
 	Request *RequestSpec
 	// Spec is the Spec to test.
 	//
-	// Required.
+	// Required unless Binary is set. Must not be set if Binary is set.
 	Spec *check.Spec
+	// Binary is the path to a built plugin binary to test.
+	//
+	// If set, the binary is invoked via pluginrpc instead of using the Spec directly. This
+	// verifies the actual shipped artifact end to end, including the wiring of Main and flags.
+	// The binary is run in the current working directory with an empty environment.
+	//
+	// Must not be set if Spec is set.
+	Binary string
+	// BinaryArgs are the arguments to invoke the plugin under on the Binary, if any.
+	//
+	// This is used when the plugin is implemented under a sub-command of the Binary, for example
+	// if the Binary hosts multiple plugins. The BinaryArgs are passed before the arguments of each
+	// procedure, so that BinaryArgs of "foo" results in "foo check" being invoked on the Binary
+	// for the Check procedure. See pluginrpc.ExecRunnerWithArgs.
+	//
+	// Ignored unless Binary is set.
+	BinaryArgs []string
+	// ClientOptions are the options for the Client used to call Check.
+	//
+	// This allows testing Annotations enriched by the Client, such as with
+	// check.ClientWithOwnership or check.ClientWithDocURLResolver.
+	ClientOptions []check.ClientOption
+	// Wire says to call the Spec as if it was running in a separate process.
+	//
+	// By default, the Client calls the Server for the Spec directly with in-memory buffers,
+	// passing through the context of the test. If Wire is set, the Server is instead served
+	// over OS pipes, with a new context that does not carry the values of the context of the
+	// test, and with errors converted to exit codes in the same way as check.Main. This catches
+	// Rules that depend on state that does not cross a process boundary, at the cost of speed.
+	//
+	// Requires Spec to be set.
+	Wire bool
 	// ExpectedAnnotations are the expected Annotations that should be returned.
+	//
+	// Must not be set if GoldenFilePath or ExpectedAnnotationsFilePath is set.
 	ExpectedAnnotations []ExpectedAnnotation
+	// ExpectedAnnotationsFilePath is the path to a YAML or JSON fixture file containing the
+	// expected Annotations.
+	//
+	// This allows expectations to be maintained without editing Go code. The path is relative
+	// to the directory of the test. See ReadExpectedAnnotationsFile for the format. The
+	// Annotations within the file are used as if they were set as ExpectedAnnotations.
+	//
+	// Must not be set if ExpectedAnnotations, GoldenFilePath, or WantComments is set.
+	ExpectedAnnotationsFilePath string
+	// GoldenFilePath is the path to a golden file containing the expected Annotations.
+	//
+	// This is useful when a test produces many Annotations. The path is relative to the
+	// directory of the test, typically within testdata. See AssertAnnotationsGolden for
+	// how golden files are created and updated.
+	//
+	// Must not be set if ExpectedAnnotations is set.
+	GoldenFilePath string
+	// ForbiddenAnnotations are Annotations that must not be returned.
+	//
+	// See AssertAnnotationsAbsent for how forbidden Annotations are matched.
+	//
+	// If ForbiddenAnnotations is set, and neither ExpectedAnnotations nor GoldenFilePath
+	// are set, the returned Annotations are only checked against ForbiddenAnnotations.
+	ForbiddenAnnotations []ExpectedAnnotation
+	// SuppressedAnnotations are Annotations that must be produced by the Rules, but suppressed.
+	//
+	// Each suppressed Annotation must not be returned, and must be returned if vendoring is
+	// disabled. The reason for the suppression can optionally be verified. This allows testing
+	// the interaction of Rules with vendored paths and packages without inspecting Responses.
+	//
+	// Requires Spec to be set. If SuppressedAnnotations is set, and neither ExpectedAnnotations
+	// nor GoldenFilePath are set, the returned Annotations are not otherwise compared.
+	SuppressedAnnotations []ExpectedSuppressedAnnotation
+	// SubtestGrouping determines how the comparison with ExpectedAnnotations is split into subtests.
+	//
+	// The default is to compare all Annotations in a single assertion. This is ignored if
+	// GoldenFilePath is set.
+	SubtestGrouping SubtestGrouping
+	// WantComments says to derive the expected Annotations from want comments within the Files.
+	//
+	// A want comment is a trailing comment on a Protobuf element that expects an Annotation
+	// of the given Rule to start on the line of the element, optionally with a message that
+	// contains the given substring:
+	//
+	//	message Foo {
+	//	  int32 PascalCase = 1; // want FIELD_LOWER_SNAKE_CASE: should be lower_snake_case
+	//	}
+	//
+	// Multiple directives can be separated by semicolons, for example
+	// "// want RULE_ONE; want RULE_TWO: message". Want comments within import files are ignored.
+	// Every Annotation returned must have a want comment, and every want comment must have a
+	// corresponding Annotation. This keeps expectations adjacent to the code that triggers them.
+	//
+	// Must not be set if ExpectedAnnotations or GoldenFilePath is set.
+	WantComments bool
+	// ExpectedError is the error that the Check call is expected to fail with.
+	//
+	// If set, the Check call must fail, and the error must match the ExpectedError. By default,
+	// the Check call must succeed.
+	//
+	// Must not be set if ExpectedAnnotations, GoldenFilePath, ForbiddenAnnotations,
+	// SuppressedAnnotations, or WantComments is set.
+	ExpectedError *ExpectedError
+	// OptionsMatrix is a set of option configurations to run the Request under.
+	//
+	// If set, a subtest is run for each OptionsConfiguration, with the Options of the
+	// OptionsConfiguration set on top of the Options of the Request. This allows the same files
+	// and expectations to be declared once and verified across multiple configurations of a
+	// plugin. Each OptionsConfiguration may override ExpectedAnnotations.
+	//
+	// Must not be set if GoldenFilePath or ExpectedError is set.
+	OptionsMatrix []OptionsConfiguration
+	// Syntaxes are the syntaxes to run the Request under.
+	//
+	// If set, a subtest is run for each Syntax, named by the Syntax, with the Files and
+	// AgainstFiles converted to the Syntax with descriptortest.ConvertSyntax. All assertions
+	// apply to every Syntax. This verifies that Rules that claim to be independent of syntax
+	// produce the same Annotations for proto2, proto3, and editions. Set this to
+	// descriptortest.AllSyntaxes to run under all syntaxes.
+	//
+	// The Files and AgainstFiles that are not imports must be proto3.
+	Syntaxes []descriptortest.Syntax
+	// AssertBeforeInput is called with the Context and Request that are passed to Spec.Before.
+	//
+	// This is called within the same code path that invokes Spec.Before for the plugin, so that
+	// assertions can be made on what Spec.Before receives. This is called even if Spec.Before
+	// is not set.
+	//
+	// Requires Spec to be set. If AssertBeforeInput or AssertBeforeOutput is set, the CheckTest
+	// fails if Spec.Before was not called for each Check call.
+	AssertBeforeInput func(t *testing.T, ctx context.Context, request check.Request)
+	// AssertBeforeOutput is called with the Context, Request, and error returned from Spec.Before.
+	//
+	// This allows assertions on the side effects of Spec.Before, such as values added to the
+	// Context or changes to the Request, before they are passed to the RuleHandlers. If
+	// Spec.Before is not set, this is called with the input Context and Request and a nil error.
+	//
+	// To test that an error returned from Spec.Before is propagated, assert on the error here,
+	// and set ExpectedError to the error that the Check call is expected to fail with.
+	//
+	// Requires Spec to be set.
+	AssertBeforeOutput func(t *testing.T, ctx context.Context, request check.Request, err error)
+	// ReportRuleCounts says to log the number of Annotations produced by each Rule, once the
+	// test has completed.
+	//
+	// Counts are summed across all Check calls made by the test, including those for each
+	// OptionsConfiguration and Syntax. Every Rule of the plugin is listed, including Rules that
+	// produced no Annotations. The report is logged with t.Logf, and is therefore only shown
+	// with go test -v, or if the test fails. This is useful when triaging large mismatches, or
+	// to validate that a change did not silently disable Rules.
+	ReportRuleCounts bool
 }
 
 // Run runs the test.
@@ -71,23 +216,86 @@ type CheckTest struct {
 // This will:
 //
 //   - Build the Files and AgainstFiles.
-//   - Create a new Request.
-//   - Create a new Client based on the Spec.
-//   - Call Check on the Client.
-//   - Compare the resulting Annotations with the ExpectedAnnotations, failing if there is a mismatch.
+//   - Create a new Request. If the Request has a Config, the RuleIDs are resolved from the
+//     Config, and Annotations for ignored paths are removed before any comparisons.
+//   - Create a new Client based on the Spec, or the Binary if set. If Wire is set,
+//     the Client calls the Spec over OS pipes. If AssertBeforeInput or AssertBeforeOutput
+//     is set, Spec.Before is wrapped to call them.
+//   - Call Check on the Client. If ExpectedError is set, fail if the Check call does not fail
+//     with a matching error, and stop.
+//   - Compare the resulting Annotations with the ExpectedAnnotations, the fixture file at
+//     ExpectedAnnotationsFilePath, the golden file at GoldenFilePath, or the want comments if
+//     WantComments is set, failing if there is a mismatch.
+//   - Fail if any of the resulting Annotations match the ForbiddenAnnotations.
+//   - Fail if any of the SuppressedAnnotations were not produced by the Rules and suppressed.
+//   - If ReportRuleCounts is set, log the number of Annotations produced by each Rule.
+//
+// If OptionsMatrix is set, the Request is built and Check is called within a subtest for each
+// OptionsConfiguration. If Syntaxes is set, this is all done within a subtest for each Syntax.
 func (c CheckTest) Run(t *testing.T) {
 	ctx := context.Background()
 
 	require.NotNil(t, c.Request)
-	require.NotNil(t, c.Spec)
-
-	request, err := c.Request.ToRequest(ctx)
-	require.NoError(t, err)
-	client, err := check.NewClientForSpec(c.Spec)
-	require.NoError(t, err)
-	response, err := client.Check(ctx, request)
+	beforeHooks := newBeforeHooks(c.AssertBeforeInput, c.AssertBeforeOutput)
+	if beforeHooks != nil {
+		require.NotNil(t, c.Spec, "Spec must be set if AssertBeforeInput or AssertBeforeOutput is set")
+		c.Spec = beforeHooks.wrapSpec(c.Spec)
+	}
+	client, err := newClientForTest(c.Spec, c.Binary, c.BinaryArgs, c.ClientOptions, c.Wire)
 	require.NoError(t, err)
-	AssertAnnotationsEqual(t, c.ExpectedAnnotations, response.Annotations())
+	var ruleCountReport *ruleCountReport
+	if c.ReportRuleCounts {
+		ruleCountReport = newRuleCountReportForTest(ctx, t, client)
+	}
+	var resolvedConfig *resolvedConfig
+	if c.Request.Config != nil {
+		require.Empty(t, c.Request.RuleIDs, "RuleIDs cannot be set if Config is set")
+		resolvedConfig, err = resolveConfig(ctx, client, c.Request.Config)
+		require.NoError(t, err)
+		requestSpec := *c.Request
+		requestSpec.RuleIDs = resolvedConfig.ruleIDs
+		requestSpec.Config = nil
+		c.Request = &requestSpec
+	}
+	if c.GoldenFilePath != "" {
+		require.Empty(t, c.ExpectedAnnotations, "ExpectedAnnotations cannot be set if GoldenFilePath is set")
+	}
+	if c.WantComments {
+		require.Empty(t, c.ExpectedAnnotations, "ExpectedAnnotations cannot be set if WantComments is set")
+		require.Empty(t, c.GoldenFilePath, "GoldenFilePath cannot be set if WantComments is set")
+	}
+	if c.ExpectedAnnotationsFilePath != "" {
+		require.Empty(t, c.ExpectedAnnotations, "ExpectedAnnotations cannot be set if ExpectedAnnotationsFilePath is set")
+		require.Empty(t, c.GoldenFilePath, "GoldenFilePath cannot be set if ExpectedAnnotationsFilePath is set")
+		require.False(t, c.WantComments, "WantComments cannot be set if ExpectedAnnotationsFilePath is set")
+		c.ExpectedAnnotations, err = ReadExpectedAnnotationsFile(c.ExpectedAnnotationsFilePath)
+		require.NoError(t, err)
+	}
+	checkAssertions := &checkAssertions{
+		expectedAnnotations:   c.ExpectedAnnotations,
+		goldenFilePath:        c.GoldenFilePath,
+		forbiddenAnnotations:  c.ForbiddenAnnotations,
+		suppressedAnnotations: c.SuppressedAnnotations,
+		spec:                  c.Spec,
+		subtestGrouping:       c.SubtestGrouping,
+		wantComments:          c.WantComments,
+		expectedError:         c.ExpectedError,
+		beforeHooks:           beforeHooks,
+		ruleCountReport:       ruleCountReport,
+		resolvedConfig:        resolvedConfig,
+	}
+	if len(c.Syntaxes) > 0 {
+		for _, syntax := range c.Syntaxes {
+			t.Run(
+				syntax.String(),
+				func(t *testing.T) {
+					c.run(ctx, t, client, checkAssertions, syntax)
+				},
+			)
+		}
+		return
+	}
+	c.run(ctx, t, client, checkAssertions, 0)
 }
 
 // RequestSpec specifies request parameters to be compiled for testing.
@@ -101,9 +309,22 @@ type RequestSpec struct {
 	// AgainstFiles specifies the input against files to test against, if anoy.
 	AgainstFiles *ProtoFileSpec
 	// RuleIDs are the specific RuleIDs to run.
+	//
+	// Must not be set if Config is set.
 	RuleIDs []string
 	// Options are any options to pass to the plugin.
 	Options map[string]any
+	// Config is a simulated buf.yaml configuration to run the plugin under.
+	//
+	// If set, the RuleIDs of the Request are determined by the Rules and Categories that the
+	// Config uses, and Annotations for paths that the Config ignores are removed before any
+	// assertions are made. See Config for more details.
+	//
+	// Resolving a Config requires the Rules and Categories of the plugin, and therefore Config
+	// is only supported by CheckTest. ToRequest returns an error if Config is set.
+	//
+	// Must not be set if RuleIDs is set.
+	Config *Config
 }
 
 // ToRequest converts the spec into a check.Request.
@@ -117,6 +338,9 @@ func (r *RequestSpec) ToRequest(ctx context.Context) (check.Request, error) {
 	if r.Files == nil {
 		return nil, errors.New("RequestSpec.Files not set")
 	}
+	if r.Config != nil {
+		return nil, errors.New("RequestSpec.Config is only supported by CheckTest")
+	}
 
 	againstFileDescriptors, err := r.AgainstFiles.ToFileDescriptors(ctx)
 	if err != nil {
@@ -144,35 +368,129 @@ func (r *RequestSpec) ToRequest(ctx context.Context) (check.Request, error) {
 // This allows tests to effectively point at a directory, and get back a
 // *descriptorpb.FileDesriptorSet, or more to the point, check.Files
 // that can be passed on a Request.
+//
+// Files can also be specified inline with Sources, which is useful for small
+// reproduction tests that do not warrant testdata files:
+//
+//	&checktest.ProtoFileSpec{
+//		Sources: map[string]string{
+//			"simple.proto": `syntax = "proto3"; package simple; message Foo {}`,
+//		},
+//	}
 type ProtoFileSpec struct {
 	// DirPaths are the paths where .proto files are contained.
 	//
 	// Imports within .proto files should derive from one of these directories.
-	// This must contain at least one element.
+	// This must contain at least one element, unless Sources is set.
 	//
 	// This corresponds to the -I flag in protoc.
 	DirPaths []string
-	// FilePaths are the specific paths to build within the DirPaths.
+	// Sources are .proto file contents, keyed by their path.
+	//
+	// Paths use forward slashes, and are relative in the same way paths within DirPaths are.
+	// Sources take precedence over files with the same path within DirPaths. Files within
+	// Sources can import each other, files within DirPaths, and the Well-Known Types.
+	Sources map[string]string
+	// FilePaths are the specific paths to build within the DirPaths and Sources.
 	//
 	// Any imports of the FilePaths will be built as well, and marked as imports.
-	// This must contain at least one element.
+	// This must contain at least one element, unless Sources is set, in which case
+	// this defaults to all paths within Sources.
 	// Paths should be relative to DirPaths.
 	//
 	// This corresponds to arguments passed to protoc.
+	//
+	// If FileDescriptorSetPath is set, this is optional, and specifies the files that are
+	// not imports. Files within the FileDescriptorSet that are not within FilePaths are
+	// marked as imports.
 	FilePaths []string
+	// FileDescriptorSetPath is the path to a serialized FileDescriptorSet or buf image to use
+	// instead of compiling .proto files.
+	//
+	// This allows tests to exercise exactly the descriptors produced by a real build, including
+	// source retention behaviors. The file must be in the binary Protobuf format, for example as
+	// produced by "buf build -o image.binpb" or "protoc --include_imports --include_source_info -o".
+	// For a buf image, the files that are imports are read from the image. For a FileDescriptorSet,
+	// no files are imports. In both cases, this is overridden if FilePaths is set.
+	//
+	// Must not be set if DirPaths or Sources is set.
+	FileDescriptorSetPath string
+	// FS is the file system to read the DirPaths and FileDescriptorSetPath from.
+	//
+	// This allows testdata to be embedded with embed.FS, so that tests can be run from a
+	// self-contained test binary, for example within bazel or on another platform:
+	//
+	//	//go:embed testdata
+	//	var testdataFS embed.FS
+	//
+	//	&checktest.ProtoFileSpec{
+	//		FS:        testdataFS,
+	//		DirPaths:  []string{"testdata"},
+	//		FilePaths: []string{"simple.proto"},
+	//	}
+	//
+	// If set, DirPaths and FileDescriptorSetPath must be valid paths within the FS per
+	// fs.ValidPath, for example "testdata" instead of "./testdata". If not set, the paths
+	// are read from the local file system.
+	FS fs.FS
+	// SourceInfoMode is the mode used to produce source info when compiling.
+	//
+	// This allows tests to exercise how rules behave when plugins are invoked with reduced
+	// source info. The default is descriptortest.SourceInfoModeBuf.
+	//
+	// Must not be set if FileDescriptorSetPath is set.
+	SourceInfoMode descriptortest.SourceInfoMode
+	// MaxEdition results in an error if any compiled file has an edition greater than
+	// MaxEdition, if set.
+	//
+	// See descriptortest.CompileWithMaxEdition.
+	//
+	// Must not be set if FileDescriptorSetPath is set.
+	MaxEdition descriptorpb.Edition
 }
 
 // ToFileDescriptors compiles the files into descriptor.FileDescriptors.
 //
+// Compilation results are cached for the lifetime of the test binary, keyed by the DirPaths,
+// FilePaths, Sources, SourceInfoMode, MaxEdition, and the contents of all .proto files within the DirPaths, read from the
+// FS if set. Repeated tests against the same testdata therefore only compile once. The returned
+// FileDescriptors may be shared between tests, and must not be modified. The cache is shared
+// with descriptortest.Compile, see descriptortest.CurrentCompileCacheStats. The FileDescriptors
+// are in the same deterministic order as with descriptortest.CompileStrings.
+//
+// If FileDescriptorSetPath is set, the FileDescriptorSet is read instead, and is not cached.
+//
 // If p is nil, this returns an empty slice.
 func (p *ProtoFileSpec) ToFileDescriptors(ctx context.Context) ([]descriptor.FileDescriptor, error) {
 	if p == nil {
 		return nil, nil
 	}
+	if p.FileDescriptorSetPath != "" {
+		if err := validateFileDescriptorSetProtoFileSpec(p); err != nil {
+			return nil, err
+		}
+		return readFileDescriptorSetFile(p.FS, p.FileDescriptorSetPath, p.FilePaths)
+	}
 	if err := validateProtoFileSpec(p); err != nil {
 		return nil, err
 	}
-	return compile(ctx, p.DirPaths, p.FilePaths)
+	filePaths := p.FilePaths
+	if len(filePaths) == 0 {
+		filePaths = xslices.MapKeysToSortedSlice(p.Sources)
+	}
+	sourceInfoMode, err := protocompileSourceInfoModeForSourceInfoMode(p.SourceInfoMode)
+	if err != nil {
+		return nil, err
+	}
+	return xprotocompile.CachedCompile(
+		ctx,
+		p.FS,
+		p.DirPaths,
+		p.Sources,
+		filePaths,
+		xprotocompile.CompileWithSourceInfoMode(sourceInfoMode),
+		xprotocompile.CompileWithMaxEdition(p.MaxEdition),
+	)
 }
 
 // ExpectedAnnotation contains the values expected from an Annotation.
@@ -186,19 +504,69 @@ type ExpectedAnnotation struct {
 	// If Message is not set on ExpectedAnnotation, this field will *not* be compared
 	// against the value in Annotation. That is, it is valid to have an Annotation return
 	// a message but to not set it on ExpectedAnnotation.
+	//
+	// At most one of Message, MessageContains, and MessagePattern may be set.
 	Message string
+	// MessageContains is a substring that the message returned from the annotation must contain.
+	//
+	// This is useful when a message contains dynamic content such as names or numbers.
+	//
+	// At most one of Message, MessageContains, and MessagePattern may be set.
+	MessageContains string
+	// MessagePattern is a regular expression that the message returned from the annotation must match.
+	//
+	// The pattern is not anchored, use ^ and $ to match the entire message.
+	//
+	// At most one of Message, MessageContains, and MessagePattern may be set.
+	MessagePattern *regexp.Regexp
 	// FileLocation is the location of the failure.
 	FileLocation *ExpectedFileLocation
 	// AgainstFileLocation is the against location of the failure.
 	AgainstFileLocation *ExpectedFileLocation
+	// Owners are the owners of the file that the failure is located within.
+	//
+	// Owners are only set by Clients configured with an Ownership, see ClientOptions on
+	// CheckTest. If Owners is nil, this field will *not* be compared against the value in
+	// Annotation. Use an empty, non-nil slice to assert that an Annotation has no owners.
+	Owners []string
+	// DocURL is the documentation URL for the Rule that failed.
+	//
+	// Documentation URLs are only set by Clients configured with a DocURLResolver, see
+	// ClientOptions on CheckTest. If DocURL is not set, this field will *not* be compared
+	// against the value in Annotation.
+	DocURL string
 }
 
 // String implements fmt.Stringer.
 func (ea ExpectedAnnotation) String() string {
 	return "ruleID=\"" + ea.RuleID + "\"" +
 		" message=\"" + ea.Message + "\"" +
+		ea.messageMatcherString() +
 		" location=\"" + ea.FileLocation.String() + "\"" +
-		" againstLocation=\"" + ea.AgainstFileLocation.String() + "\""
+		" againstLocation=\"" + ea.AgainstFileLocation.String() + "\"" +
+		ea.enrichmentString()
+}
+
+func (ea ExpectedAnnotation) enrichmentString() string {
+	var s string
+	if ea.Owners != nil {
+		s += " owners=\"" + strings.Join(ea.Owners, ",") + "\""
+	}
+	if ea.DocURL != "" {
+		s += " docURL=\"" + ea.DocURL + "\""
+	}
+	return s
+}
+
+func (ea ExpectedAnnotation) messageMatcherString() string {
+	var s string
+	if ea.MessageContains != "" {
+		s += " messageContains=\"" + ea.MessageContains + "\""
+	}
+	if ea.MessagePattern != nil {
+		s += " messagePattern=\"" + ea.MessagePattern.String() + "\""
+	}
+	return s
 }
 
 // ExpectedFileLocation contains the values expected from a Location.
@@ -213,6 +581,25 @@ type ExpectedFileLocation struct {
 	EndLine int
 	// EndColumn is the zero-indexed end column.
 	EndColumn int
+	// SourcePath is the path within the FileDescriptorProto of the location.
+	//
+	// If SourcePath is not set on ExpectedFileLocation, this field will *not* be compared
+	// against the value in Annotation. Source paths are stable when testdata is reformatted,
+	// unlike lines and columns, so SourcePath is typically used together with IgnoreSpan.
+	SourcePath protoreflect.SourcePath
+	// IgnoreSpan denotes that StartLine, StartColumn, EndLine, and EndColumn should not be
+	// compared against the values in Annotation.
+	//
+	// This is typically used with SourcePath, so that only the FileName and SourcePath are
+	// compared:
+	//
+	//	FileLocation: &checktest.ExpectedFileLocation{
+	//		FileName: "simple.proto",
+	//		// The second field of the first message.
+	//		SourcePath: protoreflect.SourcePath{4, 0, 2, 1},
+	//		IgnoreSpan: true,
+	//	},
+	IgnoreSpan bool
 }
 
 // String implements fmt.Stringer.
@@ -220,56 +607,229 @@ func (el *ExpectedFileLocation) String() string {
 	if el == nil {
 		return "nil"
 	}
-	return el.FileName +
-		" startLine=" + strconv.Itoa(el.StartLine) +
-		" startColumn=" + strconv.Itoa(el.StartColumn) +
-		" endLine=" + strconv.Itoa(el.EndLine) +
-		" endColumn=" + strconv.Itoa(el.EndColumn)
+	s := el.FileName
+	if !el.IgnoreSpan {
+		s += " startLine=" + strconv.Itoa(el.StartLine) +
+			" startColumn=" + strconv.Itoa(el.StartColumn) +
+			" endLine=" + strconv.Itoa(el.EndLine) +
+			" endColumn=" + strconv.Itoa(el.EndColumn)
+	}
+	if el.SourcePath != nil {
+		s += " sourcePath=" + el.SourcePath.String()
+	}
+	return s
 }
 
 // AssertAnnotationsEqual asserts that the Annotations equal the expected Annotations.
-func AssertAnnotationsEqual(t *testing.T, expectedAnnotations []ExpectedAnnotation, actualAnnotations []check.Annotation) {
-	if len(expectedAnnotations) == 0 {
-		expectedAnnotations = nil
-	}
-	if len(actualAnnotations) == 0 {
-		actualAnnotations = nil
-	}
-	actualExpectedAnnotations := expectedAnnotationsForAnnotations(actualAnnotations)
-	msgAndArgs := []any{"expected:\n%v\nactual:\n%v", expectedAnnotations, actualExpectedAnnotations}
-	require.Equal(t, len(expectedAnnotations), len(actualExpectedAnnotations), msgAndArgs...)
-	for i, expectedAnnotation := range expectedAnnotations {
-		if expectedAnnotation.Message == "" {
-			actualExpectedAnnotations[i].Message = ""
-		}
+//
+// On failure, a diff of the missing and unexpected Annotations is reported, grouped by file.
+func AssertAnnotationsEqual(
+	t *testing.T,
+	expectedAnnotations []ExpectedAnnotation,
+	actualAnnotations []check.Annotation,
+	options ...AnnotationsEqualOption,
+) {
+	require.NoError(t, validateExpectedAnnotations(expectedAnnotations))
+	if diff := annotationsDiffForOptions(expectedAnnotations, actualAnnotations, options); diff != "" {
+		assert.Fail(t, diff)
 	}
-	assert.Equal(t, expectedAnnotations, actualExpectedAnnotations, msgAndArgs...)
 }
 
 // RequireAnnotationsEqual requires that the Annotations equal the expected Annotations.
-func RequireAnnotationsEqual(t *testing.T, expectedAnnotations []ExpectedAnnotation, actualAnnotations []check.Annotation) {
-	if len(expectedAnnotations) == 0 {
-		expectedAnnotations = nil
-	}
-	if len(actualAnnotations) == 0 {
-		actualAnnotations = nil
-	}
-	actualExpectedAnnotations := expectedAnnotationsForAnnotations(actualAnnotations)
-	msgAndArgs := []any{"expected:\n%v\nactual:\n%v", expectedAnnotations, actualExpectedAnnotations}
-	require.Equal(t, len(expectedAnnotations), len(actualExpectedAnnotations), msgAndArgs...)
-	for i, expectedAnnotation := range expectedAnnotations {
-		if expectedAnnotation.Message == "" {
-			actualExpectedAnnotations[i].Message = ""
-		}
+//
+// On failure, a diff of the missing and unexpected Annotations is reported, grouped by file.
+func RequireAnnotationsEqual(
+	t *testing.T,
+	expectedAnnotations []ExpectedAnnotation,
+	actualAnnotations []check.Annotation,
+	options ...AnnotationsEqualOption,
+) {
+	require.NoError(t, validateExpectedAnnotations(expectedAnnotations))
+	if diff := annotationsDiffForOptions(expectedAnnotations, actualAnnotations, options); diff != "" {
+		require.Fail(t, diff)
 	}
-	require.Equal(t, expectedAnnotations, actualExpectedAnnotations, msgAndArgs...)
 }
 
 // *** PRIVATE ***
 
+// checkAssertions are the assertions to make on the Annotations returned from a Check.
+type checkAssertions struct {
+	expectedAnnotations  []ExpectedAnnotation
+	goldenFilePath       string
+	forbiddenAnnotations []ExpectedAnnotation
+	// suppressedAnnotations requires spec to be set.
+	suppressedAnnotations []ExpectedSuppressedAnnotation
+	spec                  *check.Spec
+	subtestGrouping       SubtestGrouping
+	wantComments          bool
+	expectedError         *ExpectedError
+	// beforeHooks may be nil.
+	beforeHooks *beforeHooks
+	// ruleCountReport may be nil.
+	ruleCountReport *ruleCountReport
+	// resolvedConfig may be nil.
+	resolvedConfig *resolvedConfig
+}
+
+// run runs the test with the Request converted to the syntax, or unconverted if syntax is 0.
+func (c CheckTest) run(
+	ctx context.Context,
+	t *testing.T,
+	client check.Client,
+	checkAssertions *checkAssertions,
+	syntax descriptortest.Syntax,
+) {
+	if len(c.OptionsMatrix) > 0 {
+		runOptionsMatrix(ctx, t, client, c, checkAssertions, syntax)
+		return
+	}
+	request, err := requestForSyntax(ctx, c.Request, syntax)
+	require.NoError(t, err)
+	runCheckAndAssert(ctx, t, client, request, checkAssertions)
+}
+
+// requestForSyntax builds the Request for the RequestSpec, with the files converted to the
+// syntax with descriptortest.ConvertSyntax. If syntax is 0, the files are not converted.
+func requestForSyntax(ctx context.Context, requestSpec *RequestSpec, syntax descriptortest.Syntax) (check.Request, error) {
+	request, err := requestSpec.ToRequest(ctx)
+	if err != nil || syntax == 0 {
+		return request, err
+	}
+	fileDescriptors, err := descriptortest.ConvertSyntax(request.FileDescriptors(), syntax)
+	if err != nil {
+		return nil, err
+	}
+	againstFileDescriptors, err := descriptortest.ConvertSyntax(request.AgainstFileDescriptors(), syntax)
+	if err != nil {
+		return nil, err
+	}
+	return check.NewRequest(
+		fileDescriptors,
+		check.WithAgainstFileDescriptors(againstFileDescriptors),
+		check.WithOptions(request.Options()),
+		check.WithRuleIDs(request.RuleIDs()...),
+	)
+}
+
+// newClientForTest returns a new Client for the Spec or the plugin binary, calling the
+// Spec over the wire if wire is set.
+func newClientForTest(
+	spec *check.Spec,
+	binary string,
+	binaryArgs []string,
+	clientOptions []check.ClientOption,
+	wire bool,
+) (check.Client, error) {
+	if wire {
+		if binary != "" {
+			return nil, errors.New("cannot set Wire if Binary is set")
+		}
+		return newWireClientForSpec(spec, clientOptions)
+	}
+	return newClientForSpecOrBinary(spec, binary, binaryArgs, clientOptions)
+}
+
+// newClientForSpecOrBinary returns a new Client for the Spec, or for the plugin binary if set.
+//
+// Exactly one of spec and binary must be set.
+func newClientForSpecOrBinary(
+	spec *check.Spec,
+	binary string,
+	binaryArgs []string,
+	clientOptions []check.ClientOption,
+) (check.Client, error) {
+	switch {
+	case spec != nil && binary != "":
+		return nil, errors.New("only one of Spec and Binary can be set")
+	case binary != "":
+		return check.NewClient(
+			pluginrpc.NewClient(
+				pluginrpc.NewExecRunner(
+					binary,
+					pluginrpc.ExecRunnerWithArgs(binaryArgs...),
+				),
+			),
+			append([]check.ClientOption{check.ClientWithCaching()}, clientOptions...)...,
+		), nil
+	case spec != nil:
+		return check.NewClientForSpec(
+			spec,
+			xslices.Map(
+				clientOptions,
+				func(clientOption check.ClientOption) check.ClientForSpecOption {
+					return clientOption
+				},
+			)...,
+		)
+	default:
+		return nil, errors.New("one of Spec or Binary must be set")
+	}
+}
+
+func runCheckAndAssert(
+	ctx context.Context,
+	t *testing.T,
+	client check.Client,
+	request check.Request,
+	checkAssertions *checkAssertions,
+) {
+	numBeforeCalls := checkAssertions.beforeHooks.start(t)
+	response, err := client.Check(ctx, request)
+	checkAssertions.beforeHooks.requireCalledSince(t, numBeforeCalls)
+	if checkAssertions.expectedError != nil {
+		require.Empty(t, checkAssertions.expectedAnnotations, "ExpectedAnnotations cannot be set if ExpectedError is set")
+		require.Empty(t, checkAssertions.goldenFilePath, "GoldenFilePath cannot be set if ExpectedError is set")
+		require.Empty(t, checkAssertions.forbiddenAnnotations, "ForbiddenAnnotations cannot be set if ExpectedError is set")
+		require.Empty(t, checkAssertions.suppressedAnnotations, "SuppressedAnnotations cannot be set if ExpectedError is set")
+		require.False(t, checkAssertions.wantComments, "WantComments cannot be set if ExpectedError is set")
+		assertErrorMatches(t, checkAssertions.expectedError, err)
+		return
+	}
+	require.NoError(t, err)
+	annotations := checkAssertions.resolvedConfig.filterAnnotations(response.Annotations())
+	checkAssertions.ruleCountReport.add(annotations)
+	globalRuleCoverage.addAnnotations(annotations)
+	globalRuleCoverage.addExpectedAnnotations(checkAssertions.expectedAnnotations)
+	globalRuleCoverage.addExpectedAnnotations(
+		xslices.Map(
+			checkAssertions.suppressedAnnotations,
+			func(suppressedAnnotation ExpectedSuppressedAnnotation) ExpectedAnnotation {
+				return suppressedAnnotation.Annotation
+			},
+		),
+	)
+	if len(checkAssertions.forbiddenAnnotations) > 0 {
+		AssertAnnotationsAbsent(t, checkAssertions.forbiddenAnnotations, annotations)
+	}
+	if len(checkAssertions.suppressedAnnotations) > 0 {
+		assertAnnotationsSuppressed(ctx, t, checkAssertions.spec, request, checkAssertions.suppressedAnnotations, annotations)
+	}
+	switch {
+	case checkAssertions.wantComments:
+		expectedAnnotations, err := expectedAnnotationsForWantComments(request.FileDescriptors(), annotations)
+		require.NoError(t, err)
+		assertAnnotationsEqualForSubtestGrouping(t, expectedAnnotations, annotations, checkAssertions.subtestGrouping)
+	case checkAssertions.goldenFilePath != "":
+		AssertAnnotationsGolden(t, checkAssertions.goldenFilePath, annotations)
+	case len(checkAssertions.expectedAnnotations) > 0 ||
+		(len(checkAssertions.forbiddenAnnotations) == 0 && len(checkAssertions.suppressedAnnotations) == 0):
+		assertAnnotationsEqualForSubtestGrouping(t, checkAssertions.expectedAnnotations, annotations, checkAssertions.subtestGrouping)
+	}
+}
+
 func validateProtoFileSpec(protoFileSpec *ProtoFileSpec) error {
+	if protoFileSpec.FS != nil {
+		for _, dirPath := range protoFileSpec.DirPaths {
+			if !fs.ValidPath(dirPath) {
+				return fmt.Errorf("invalid DirPath %q for FS on ProtoFileSpec", dirPath)
+			}
+		}
+	}
+	if len(protoFileSpec.Sources) > 0 {
+		return nil
+	}
 	if len(protoFileSpec.DirPaths) == 0 {
-		return errors.New("no DirPaths specified on ProtoFileSpec")
+		return errors.New("no DirPaths or Sources specified on ProtoFileSpec")
 	}
 	if len(protoFileSpec.FilePaths) == 0 {
 		return errors.New("no FilePaths specified on ProtoFileSpec")
@@ -277,22 +837,70 @@ func validateProtoFileSpec(protoFileSpec *ProtoFileSpec) error {
 	return nil
 }
 
+func protocompileSourceInfoModeForSourceInfoMode(sourceInfoMode descriptortest.SourceInfoMode) (protocompile.SourceInfoMode, error) {
+	switch sourceInfoMode {
+	case descriptortest.SourceInfoModeBuf:
+		return protocompile.SourceInfoExtraOptionLocations, nil
+	case descriptortest.SourceInfoModeStandard:
+		return protocompile.SourceInfoStandard, nil
+	case descriptortest.SourceInfoModeNone:
+		return protocompile.SourceInfoNone, nil
+	default:
+		return 0, fmt.Errorf("unknown SourceInfoMode on ProtoFileSpec: %d", sourceInfoMode)
+	}
+}
+
+func validateExpectedAnnotations(expectedAnnotations []ExpectedAnnotation) error {
+	for _, expectedAnnotation := range expectedAnnotations {
+		var numSet int
+		if expectedAnnotation.Message != "" {
+			numSet++
+		}
+		if expectedAnnotation.MessageContains != "" {
+			numSet++
+		}
+		if expectedAnnotation.MessagePattern != nil {
+			numSet++
+		}
+		if numSet > 1 {
+			return fmt.Errorf("at most one of Message, MessageContains, and MessagePattern may be set on ExpectedAnnotation: %v", expectedAnnotation)
+		}
+	}
+	return nil
+}
+
+func annotationsDiffForOptions(
+	expectedAnnotations []ExpectedAnnotation,
+	actualAnnotations []check.Annotation,
+	options []AnnotationsEqualOption,
+) string {
+	annotationsEqualOptions := newAnnotationsEqualOptions()
+	for _, option := range options {
+		option(annotationsEqualOptions)
+	}
+	return annotationsDiff(expectedAnnotations, expectedAnnotationsForAnnotations(actualAnnotations), annotationsEqualOptions)
+}
+
 // expectedAnnotationsForAnnotations returns ExpectedAnnotations for the given Annotations.
 //
-// Callers will need to filter out the Messages from the returned ExpectedAnnotations to conform
-// to the ExpectedAnnotations that are being compared against. See the note on ExpectedAnnotation.Message.
+// The returned ExpectedAnnotations should be compared with expectedAnnotationMatches, which
+// respects the note on ExpectedAnnotation.Message.
 func expectedAnnotationsForAnnotations(annotations []check.Annotation) []ExpectedAnnotation {
 	return xslices.Map(annotations, expectedAnnotationForAnnotation)
 }
 
 // expectedAnnotationForAnnotation returns an ExpectedAnnotation for the given Annotation.
 //
-// Callers will need to filter out the Messages from the returned ExpectedAnnotations to conform
-// to the ExpectedAnnotations that are being compared against. See the note on ExpectedAnnotation.Message.
+// The returned ExpectedAnnotation should be compared with expectedAnnotationMatches, which
+// respects the note on ExpectedAnnotation.Message.
 func expectedAnnotationForAnnotation(annotation check.Annotation) ExpectedAnnotation {
 	expectedAnnotation := ExpectedAnnotation{
 		RuleID:  annotation.RuleID(),
 		Message: annotation.Message(),
+		Owners:  annotation.Owners(),
+	}
+	if docURL := annotation.DocURL(); docURL != nil {
+		expectedAnnotation.DocURL = docURL.String()
 	}
 	if fileLocation := annotation.FileLocation(); fileLocation != nil {
 		expectedAnnotation.FileLocation = &ExpectedFileLocation{
@@ -301,6 +909,7 @@ func expectedAnnotationForAnnotation(annotation check.Annotation) ExpectedAnnota
 			StartColumn: fileLocation.StartColumn(),
 			EndLine:     fileLocation.EndLine(),
 			EndColumn:   fileLocation.EndColumn(),
+			SourcePath:  fileLocation.SourcePath(),
 		}
 	}
 	if againstFileLocation := annotation.AgainstFileLocation(); againstFileLocation != nil {
@@ -310,137 +919,8 @@ func expectedAnnotationForAnnotation(annotation check.Annotation) ExpectedAnnota
 			StartColumn: againstFileLocation.StartColumn(),
 			EndLine:     againstFileLocation.EndLine(),
 			EndColumn:   againstFileLocation.EndColumn(),
+			SourcePath:  againstFileLocation.SourcePath(),
 		}
 	}
 	return expectedAnnotation
 }
-
-func compile(ctx context.Context, dirPaths []string, filePaths []string) ([]descriptor.FileDescriptor, error) {
-	dirPaths = fromSlashPaths(dirPaths)
-	filePaths = fromSlashPaths(filePaths)
-	toSlashFilePathMap := make(map[string]struct{}, len(filePaths))
-	for _, filePath := range filePaths {
-		toSlashFilePathMap[filepath.ToSlash(filePath)] = struct{}{}
-	}
-
-	var warningErrorsWithPos []reporter.ErrorWithPos
-	compiler := protocompile.Compiler{
-		Resolver: wellknownimports.WithStandardImports(
-			&protocompile.SourceResolver{
-				ImportPaths: dirPaths,
-			},
-		),
-		Reporter: reporter.NewReporter(
-			func(reporter.ErrorWithPos) error {
-				return nil
-			},
-			func(errorWithPos reporter.ErrorWithPos) {
-				warningErrorsWithPos = append(warningErrorsWithPos, errorWithPos)
-			},
-		),
-		// This is what buf uses.
-		SourceInfoMode: protocompile.SourceInfoExtraOptionLocations,
-	}
-	files, err := compiler.Compile(ctx, filePaths...)
-	if err != nil {
-		return nil, err
-	}
-	syntaxUnspecifiedFilePaths := make(map[string]struct{})
-	filePathToUnusedDependencyFilePaths := make(map[string]map[string]struct{})
-	for _, warningErrorWithPos := range warningErrorsWithPos {
-		maybeAddSyntaxUnspecified(syntaxUnspecifiedFilePaths, warningErrorWithPos)
-		maybeAddUnusedDependency(filePathToUnusedDependencyFilePaths, warningErrorWithPos)
-	}
-	fileDescriptorSet := fileDescriptorSetForFileDescriptors(files)
-
-	protoFileDescriptors := make([]*descriptorv1.FileDescriptor, len(fileDescriptorSet.GetFile()))
-	for i, fileDescriptorProto := range fileDescriptorSet.GetFile() {
-		_, isNotImport := toSlashFilePathMap[fileDescriptorProto.GetName()]
-		_, isSyntaxUnspecified := syntaxUnspecifiedFilePaths[fileDescriptorProto.GetName()]
-		unusedDependencyIndexes := unusedDependencyIndexesForFilePathToUnusedDependencyFilePaths(
-			fileDescriptorProto,
-			filePathToUnusedDependencyFilePaths[fileDescriptorProto.GetName()],
-		)
-		protoFileDescriptors[i] = &descriptorv1.FileDescriptor{
-			FileDescriptorProto: fileDescriptorProto,
-			IsImport:            !isNotImport,
-			IsSyntaxUnspecified: isSyntaxUnspecified,
-			UnusedDependency:    unusedDependencyIndexes,
-		}
-	}
-	return descriptor.FileDescriptorsForProtoFileDescriptors(protoFileDescriptors)
-}
-
-func unusedDependencyIndexesForFilePathToUnusedDependencyFilePaths(
-	fileDescriptorProto *descriptorpb.FileDescriptorProto,
-	unusedDependencyFilePaths map[string]struct{},
-) []int32 {
-	unusedDependencyIndexes := make([]int32, 0, len(unusedDependencyFilePaths))
-	if len(unusedDependencyFilePaths) == 0 {
-		return unusedDependencyIndexes
-	}
-	dependencyFilePaths := fileDescriptorProto.GetDependency()
-	for i := 0; i < len(dependencyFilePaths); i++ {
-		if _, ok := unusedDependencyFilePaths[dependencyFilePaths[i]]; ok {
-			unusedDependencyIndexes = append(unusedDependencyIndexes, int32(i))
-		}
-	}
-	return unusedDependencyIndexes
-}
-
-func maybeAddSyntaxUnspecified(
-	syntaxUnspecifiedFilePaths map[string]struct{},
-	errorWithPos reporter.ErrorWithPos,
-) {
-	if !errors.Is(errorWithPos, parser.ErrNoSyntax) {
-		return
-	}
-	syntaxUnspecifiedFilePaths[errorWithPos.GetPosition().Filename] = struct{}{}
-}
-
-func maybeAddUnusedDependency(
-	filePathToUnusedDependencyFilePaths map[string]map[string]struct{},
-	errorWithPos reporter.ErrorWithPos,
-) {
-	var errorUnusedImport linker.ErrorUnusedImport
-	if !errors.As(errorWithPos, &errorUnusedImport) {
-		return
-	}
-	pos := errorWithPos.GetPosition()
-	unusedDependencyFilePaths, ok := filePathToUnusedDependencyFilePaths[pos.Filename]
-	if !ok {
-		unusedDependencyFilePaths = make(map[string]struct{})
-		filePathToUnusedDependencyFilePaths[pos.Filename] = unusedDependencyFilePaths
-	}
-	unusedDependencyFilePaths[errorUnusedImport.UnusedImport()] = struct{}{}
-}
-
-func fileDescriptorSetForFileDescriptors[D protoreflect.FileDescriptor](files []D) *descriptorpb.FileDescriptorSet {
-	soFar := make(map[string]struct{}, len(files))
-	slice := make([]*descriptorpb.FileDescriptorProto, 0, len(files))
-	for _, file := range files {
-		toFileDescriptorProtoSlice(file, &slice, soFar)
-	}
-	return &descriptorpb.FileDescriptorSet{File: slice}
-}
-
-func toFileDescriptorProtoSlice(file protoreflect.FileDescriptor, results *[]*descriptorpb.FileDescriptorProto, soFar map[string]struct{}) {
-	if _, exists := soFar[file.Path()]; exists {
-		return
-	}
-	soFar[file.Path()] = struct{}{}
-	// Add dependencies first so the resulting slice is in topological order
-	imports := file.Imports()
-	for i, length := 0, imports.Len(); i < length; i++ {
-		toFileDescriptorProtoSlice(imports.Get(i).FileDescriptor, results, soFar)
-	}
-	*results = append(*results, protoutil.ProtoFromFileDescriptor(file))
-}
-
-func fromSlashPaths(paths []string) []string {
-	fromSlashPaths := make([]string, len(paths))
-	for i, path := range paths {
-		fromSlashPaths[i] = filepath.Clean(filepath.FromSlash(path))
-	}
-	return fromSlashPaths
-}
@@ -0,0 +1,242 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package checktest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"buf.build/go/bufplugin/check"
+)
+
+// Config is a simulated lint or breaking configuration, as would be specified within a buf.yaml.
+//
+// This allows tests to verify how a plugin behaves under a realistic configuration, rather
+// than a raw list of RuleIDs. For example, the buf.yaml:
+//
+//	lint:
+//	  use:
+//	    - STANDARD
+//	  except:
+//	    - FIELD_LOWER_SNAKE_CASE
+//	  ignore:
+//	    - legacy
+//	  ignore_only:
+//	    TIMESTAMP_SUFFIX:
+//	      - foo/v1/foo.proto
+//
+// Is specified as:
+//
+//	&checktest.Config{
+//		Use:    []string{"STANDARD"},
+//		Except: []string{"FIELD_LOWER_SNAKE_CASE"},
+//		Ignore: []string{"legacy"},
+//		IgnoreOnly: map[string][]string{
+//			"TIMESTAMP_SUFFIX": {"foo/v1/foo.proto"},
+//		},
+//	}
+//
+// IDs may be the IDs of Rules or Categories of the plugin. As with buf, a deprecated Rule that
+// is specified by ID is replaced by its replacement Rules, and deprecated Rules are never
+// selected through a Category.
+//
+// Paths are relative to the root of the files, use forward slashes, and match the file or
+// directory at the path. Annotations for files that are ignored are removed from the Response
+// before any assertions are made, in the same way as buf does. An Annotation is matched by the
+// path of its FileLocation, or by the path of its AgainstFileLocation if there is no
+// FileLocation. Annotations without a location are never ignored.
+type Config struct {
+	// Use are the IDs of the Rules and Categories to use.
+	//
+	// If empty, the default Rules of the plugin are used.
+	Use []string
+	// Except are the IDs of the Rules and Categories to not use.
+	Except []string
+	// Ignore are the paths for which all Annotations are ignored.
+	Ignore []string
+	// IgnoreOnly is a map from the IDs of Rules and Categories to the paths for which
+	// the Annotations of these Rules are ignored.
+	IgnoreOnly map[string][]string
+}
+
+// *** PRIVATE ***
+
+// resolvedConfig is a Config resolved against the Rules and Categories of a plugin.
+type resolvedConfig struct {
+	ruleIDs []string
+	// ignore are the paths that are ignored for all Rules.
+	ignore []string
+	// ruleIDToIgnoreOnly are the paths that are ignored for specific Rules.
+	ruleIDToIgnoreOnly map[string][]string
+}
+
+// resolveConfig resolves the Config against the Rules and Categories of the plugin
+// that the Client is for.
+func resolveConfig(ctx context.Context, client check.Client, config *Config) (*resolvedConfig, error) {
+	rules, err := client.ListRules(ctx)
+	if err != nil {
+		return nil, err
+	}
+	categories, err := client.ListCategories(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return newResolvedConfig(rules, categories, config)
+}
+
+func newResolvedConfig(rules []check.Rule, categories []check.Category, config *Config) (*resolvedConfig, error) {
+	ruleIDToRule := make(map[string]check.Rule, len(rules))
+	for _, rule := range rules {
+		ruleIDToRule[rule.ID()] = rule
+	}
+	categoryIDs := make(map[string]struct{}, len(categories))
+	for _, category := range categories {
+		categoryIDs[category.ID()] = struct{}{}
+	}
+	// ruleIDsForID returns the IDs of the Rules selected by a Rule or Category ID.
+	ruleIDsForID := func(id string) ([]string, error) {
+		if rule, ok := ruleIDToRule[id]; ok {
+			if rule.Deprecated() {
+				return rule.ReplacementIDs(), nil
+			}
+			return []string{id}, nil
+		}
+		if _, ok := categoryIDs[id]; !ok {
+			return nil, fmt.Errorf("unknown Rule or Category ID in Config: %q", id)
+		}
+		var ruleIDs []string
+		for _, rule := range rules {
+			if rule.Deprecated() {
+				continue
+			}
+			for _, category := range rule.Categories() {
+				if category.ID() == id {
+					ruleIDs = append(ruleIDs, rule.ID())
+					break
+				}
+			}
+		}
+		return ruleIDs, nil
+	}
+
+	useRuleIDs := make(map[string]struct{})
+	if len(config.Use) == 0 {
+		for _, rule := range rules {
+			if rule.Default() {
+				useRuleIDs[rule.ID()] = struct{}{}
+			}
+		}
+	}
+	for _, id := range config.Use {
+		ruleIDs, err := ruleIDsForID(id)
+		if err != nil {
+			return nil, err
+		}
+		for _, ruleID := range ruleIDs {
+			useRuleIDs[ruleID] = struct{}{}
+		}
+	}
+	for _, id := range config.Except {
+		ruleIDs, err := ruleIDsForID(id)
+		if err != nil {
+			return nil, err
+		}
+		for _, ruleID := range ruleIDs {
+			delete(useRuleIDs, ruleID)
+		}
+	}
+	if err := validateConfigPaths(config.Ignore); err != nil {
+		return nil, err
+	}
+	ruleIDToIgnoreOnly := make(map[string][]string)
+	for id, paths := range config.IgnoreOnly {
+		ruleIDs, err := ruleIDsForID(id)
+		if err != nil {
+			return nil, err
+		}
+		if err := validateConfigPaths(paths); err != nil {
+			return nil, err
+		}
+		for _, ruleID := range ruleIDs {
+			ruleIDToIgnoreOnly[ruleID] = append(ruleIDToIgnoreOnly[ruleID], paths...)
+		}
+	}
+
+	ruleIDs := make([]string, 0, len(useRuleIDs))
+	for ruleID := range useRuleIDs {
+		ruleIDs = append(ruleIDs, ruleID)
+	}
+	if len(ruleIDs) == 0 {
+		// An empty list of RuleIDs on a Request means to run the default Rules.
+		return nil, errors.New("no Rules are selected by Config")
+	}
+	sort.Strings(ruleIDs)
+	return &resolvedConfig{
+		ruleIDs:            ruleIDs,
+		ignore:             config.Ignore,
+		ruleIDToIgnoreOnly: ruleIDToIgnoreOnly,
+	}, nil
+}
+
+// filterAnnotations returns the Annotations that are not ignored. It returns the
+// Annotations unchanged on a nil resolvedConfig.
+func (r *resolvedConfig) filterAnnotations(annotations []check.Annotation) []check.Annotation {
+	if r == nil {
+		return annotations
+	}
+	filtered := make([]check.Annotation, 0, len(annotations))
+	for _, annotation := range annotations {
+		if !r.isIgnored(annotation) {
+			filtered = append(filtered, annotation)
+		}
+	}
+	return filtered
+}
+
+func (r *resolvedConfig) isIgnored(annotation check.Annotation) bool {
+	fileLocation := annotation.FileLocation()
+	if fileLocation == nil {
+		fileLocation = annotation.AgainstFileLocation()
+	}
+	if fileLocation == nil {
+		return false
+	}
+	filePath := fileLocation.FileDescriptor().ProtoreflectFileDescriptor().Path()
+	return configPathsContain(r.ignore, filePath) ||
+		configPathsContain(r.ruleIDToIgnoreOnly[annotation.RuleID()], filePath)
+}
+
+func validateConfigPaths(paths []string) error {
+	for _, configPath := range paths {
+		if configPath == "" || path.IsAbs(configPath) || path.Clean(configPath) != configPath ||
+			configPath == ".." || strings.HasPrefix(configPath, "../") {
+			return fmt.Errorf("invalid path in Config: %q must be a relative, normalized path", configPath)
+		}
+	}
+	return nil
+}
+
+func configPathsContain(configPaths []string, filePath string) bool {
+	for _, configPath := range configPaths {
+		if configPath == "." || filePath == configPath || strings.HasPrefix(filePath, configPath+"/") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,175 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package checktest
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"buf.build/go/bufplugin/check"
+)
+
+// RunWithRuleCoverage runs the tests, and then fails if any Rule within the Spec has no test coverage.
+//
+// A Rule has test coverage if any CheckTest, CheckTests case, or DeprecationTest within the test
+// binary either expected an Annotation for the Rule, or had the Rule produce an Annotation.
+// FuzzTests do not count towards coverage, as their Annotations are not asserted. This helps
+// keep large Rule inventories tested as Rules are added.
+//
+// This should be called from TestMain, and the result passed to os.Exit:
+//
+//	func TestMain(m *testing.M) {
+//		os.Exit(checktest.RunWithRuleCoverage(m, spec))
+//	}
+//
+// The Rules without coverage are written to stderr. If the tests fail, or only a subset of the
+// tests are run with -run or -skip, the Rules without coverage are not reported, and the exit
+// code of the tests is returned as-is.
+func RunWithRuleCoverage(m *testing.M, spec *check.Spec, options ...RuleCoverageOption) int {
+	code := m.Run()
+	return ruleCoverageExitCode(os.Stderr, globalRuleCoverage, spec, code, isTestSubset(), options...)
+}
+
+// RuleCoverageOption is an option for RunWithRuleCoverage.
+type RuleCoverageOption func(*ruleCoverageOptions)
+
+// RuleCoverageWithReportOnly returns a new RuleCoverageOption that results in Rules without
+// test coverage being reported, but not failing the tests.
+//
+// This is useful to introduce RunWithRuleCoverage to a plugin with many untested Rules.
+func RuleCoverageWithReportOnly() RuleCoverageOption {
+	return func(ruleCoverageOptions *ruleCoverageOptions) {
+		ruleCoverageOptions.reportOnly = true
+	}
+}
+
+// RuleCoverageWithExemptRuleIDs returns a new RuleCoverageOption that exempts the given Rules
+// from requiring test coverage.
+func RuleCoverageWithExemptRuleIDs(ruleIDs ...string) RuleCoverageOption {
+	return func(ruleCoverageOptions *ruleCoverageOptions) {
+		for _, ruleID := range ruleIDs {
+			ruleCoverageOptions.exemptRuleIDs[ruleID] = struct{}{}
+		}
+	}
+}
+
+// *** PRIVATE ***
+
+// globalRuleCoverage records the Rule IDs covered by tests within the test binary.
+var globalRuleCoverage = newRuleCoverage()
+
+// ruleCoverageExitCode returns the exit code for RunWithRuleCoverage given the exit code of
+// the tests, writing the Rules without coverage to stderr.
+func ruleCoverageExitCode(
+	stderr io.Writer,
+	ruleCoverage *ruleCoverage,
+	spec *check.Spec,
+	code int,
+	isTestSubset bool,
+	options ...RuleCoverageOption,
+) int {
+	ruleCoverageOptions := newRuleCoverageOptions()
+	for _, option := range options {
+		option(ruleCoverageOptions)
+	}
+	if code != 0 || isTestSubset {
+		return code
+	}
+	uncoveredRuleIDs := ruleCoverage.uncoveredRuleIDs(spec, ruleCoverageOptions.exemptRuleIDs)
+	if len(uncoveredRuleIDs) == 0 {
+		return 0
+	}
+	_, _ = fmt.Fprintf(
+		stderr,
+		"checktest: %d of %d Rules have no test that expected or produced an Annotation:\n  %s\n",
+		len(uncoveredRuleIDs),
+		len(spec.Rules),
+		strings.Join(uncoveredRuleIDs, "\n  "),
+	)
+	if ruleCoverageOptions.reportOnly {
+		return 0
+	}
+	return 1
+}
+
+type ruleCoverage struct {
+	ruleIDs map[string]struct{}
+	lock    sync.Mutex
+}
+
+func newRuleCoverage() *ruleCoverage {
+	return &ruleCoverage{
+		ruleIDs: make(map[string]struct{}),
+	}
+}
+
+func (r *ruleCoverage) addAnnotations(annotations []check.Annotation) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	for _, annotation := range annotations {
+		r.ruleIDs[annotation.RuleID()] = struct{}{}
+	}
+}
+
+func (r *ruleCoverage) addExpectedAnnotations(expectedAnnotations []ExpectedAnnotation) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	for _, expectedAnnotation := range expectedAnnotations {
+		r.ruleIDs[expectedAnnotation.RuleID] = struct{}{}
+	}
+}
+
+// uncoveredRuleIDs returns the IDs of the Rules within the Spec that are not covered,
+// in the order of the Rules within the Spec.
+func (r *ruleCoverage) uncoveredRuleIDs(spec *check.Spec, exemptRuleIDs map[string]struct{}) []string {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	var uncoveredRuleIDs []string
+	for _, ruleSpec := range spec.Rules {
+		if _, ok := exemptRuleIDs[ruleSpec.ID]; ok {
+			continue
+		}
+		if _, ok := r.ruleIDs[ruleSpec.ID]; !ok {
+			uncoveredRuleIDs = append(uncoveredRuleIDs, ruleSpec.ID)
+		}
+	}
+	return uncoveredRuleIDs
+}
+
+type ruleCoverageOptions struct {
+	reportOnly    bool
+	exemptRuleIDs map[string]struct{}
+}
+
+func newRuleCoverageOptions() *ruleCoverageOptions {
+	return &ruleCoverageOptions{
+		exemptRuleIDs: make(map[string]struct{}),
+	}
+}
+
+// isTestSubset returns true if only a subset of the tests were selected with -run or -skip.
+func isTestSubset() bool {
+	for _, name := range []string{"test.run", "test.skip"} {
+		if f := flag.Lookup(name); f != nil && f.Value.String() != "" {
+			return true
+		}
+	}
+	return false
+}
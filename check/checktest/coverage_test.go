@@ -0,0 +1,100 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package checktest
+
+import (
+	"bytes"
+	"testing"
+
+	"buf.build/go/bufplugin/check"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRuleCoverageExitCode(t *testing.T) {
+	t.Parallel()
+
+	spec := &check.Spec{
+		Rules: []*check.RuleSpec{
+			{ID: "RULE1"},
+			{ID: "RULE2"},
+			{ID: "RULE3"},
+		},
+	}
+	testCases := []struct {
+		name           string
+		code           int
+		isTestSubset   bool
+		options        []RuleCoverageOption
+		expectedCode   int
+		expectedStderr string
+	}{
+		{
+			name:           "uncovered",
+			expectedCode:   1,
+			expectedStderr: "checktest: 2 of 3 Rules have no test that expected or produced an Annotation:\n  RULE2\n  RULE3\n",
+		},
+		{
+			name:           "report_only",
+			options:        []RuleCoverageOption{RuleCoverageWithReportOnly()},
+			expectedCode:   0,
+			expectedStderr: "checktest: 2 of 3 Rules have no test that expected or produced an Annotation:\n  RULE2\n  RULE3\n",
+		},
+		{
+			name:           "exempt_some",
+			options:        []RuleCoverageOption{RuleCoverageWithExemptRuleIDs("RULE3")},
+			expectedCode:   1,
+			expectedStderr: "checktest: 1 of 3 Rules have no test that expected or produced an Annotation:\n  RULE2\n",
+		},
+		{
+			name: "exempt_all",
+			options: []RuleCoverageOption{
+				RuleCoverageWithExemptRuleIDs("RULE2"),
+				RuleCoverageWithExemptRuleIDs("RULE3"),
+			},
+			expectedCode: 0,
+		},
+		{
+			// Coverage is not reported if the tests failed.
+			name:         "tests_failed",
+			code:         2,
+			expectedCode: 2,
+		},
+		{
+			// Coverage is not reported if only a subset of the tests were run.
+			name:         "test_subset",
+			isTestSubset: true,
+			expectedCode: 0,
+		},
+	}
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+			ruleCoverage := newRuleCoverage()
+			ruleCoverage.addExpectedAnnotations([]ExpectedAnnotation{{RuleID: "RULE1"}})
+			stderr := &bytes.Buffer{}
+			code := ruleCoverageExitCode(
+				stderr,
+				ruleCoverage,
+				spec,
+				testCase.code,
+				testCase.isTestSubset,
+				testCase.options...,
+			)
+			assert.Equal(t, testCase.expectedCode, code)
+			assert.Equal(t, testCase.expectedStderr, stderr.String())
+		})
+	}
+}
@@ -0,0 +1,157 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package checktest
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"buf.build/go/bufplugin/check"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// DeprecationTest is a test that verifies that the replacements of deprecated Rules within a
+// Spec produce at least the Annotations of the deprecated Rules.
+//
+// For each deprecated Rule with replacement IDs, the deprecated Rule and its replacements are
+// run separately against the Request. Every Annotation of the deprecated Rule must have an
+// Annotation of one of the replacements at the same locations. Rule IDs and messages are not
+// compared, as replacements typically have different messages. The replacements may produce
+// additional Annotations.
+//
+// This gives confidence that users who move from a deprecated Rule to its replacements will not
+// lose findings. The Request should contain testdata that triggers the deprecated Rules.
+//
+//	func TestDeprecation(t *testing.T) {
+//		t.Parallel()
+//		checktest.DeprecationTest{
+//			Request: &checktest.RequestSpec{
+//				Files: &checktest.ProtoFileSpec{
+//					DirPaths:  []string{"testdata"},
+//					FilePaths: []string{"deprecated.proto"},
+//				},
+//			},
+//			Spec: spec,
+//		}.Run(t)
+//	}
+type DeprecationTest struct {
+	// Request is the request spec to test.
+	//
+	// Required. The RuleIDs on the RequestSpec are ignored.
+	Request *RequestSpec
+	// Spec is the Spec to test.
+	//
+	// Required. Must have at least one deprecated Rule with replacement IDs.
+	Spec *check.Spec
+}
+
+// Run runs the test.
+//
+// This will:
+//
+//   - Create a new Client based on the Spec.
+//   - For each deprecated Rule with replacement IDs, run a subtest named by the Rule ID that will
+//     call Check with the deprecated Rule, and call Check with the replacement Rules.
+//   - Fail if any Annotation of the deprecated Rule does not have an Annotation of a
+//     replacement Rule at the same locations.
+func (d DeprecationTest) Run(t *testing.T) {
+	ctx := context.Background()
+
+	require.NotNil(t, d.Request)
+	require.NotNil(t, d.Spec)
+	client, err := check.NewClientForSpec(d.Spec)
+	require.NoError(t, err)
+
+	var deprecatedRuleSpecs []*check.RuleSpec
+	for _, ruleSpec := range d.Spec.Rules {
+		if ruleSpec.Deprecated && len(ruleSpec.ReplacementIDs) > 0 {
+			deprecatedRuleSpecs = append(deprecatedRuleSpecs, ruleSpec)
+		}
+	}
+	require.NotEmpty(t, deprecatedRuleSpecs, "Spec has no deprecated Rules with replacement IDs")
+
+	for _, deprecatedRuleSpec := range deprecatedRuleSpecs {
+		t.Run(
+			deprecatedRuleSpec.ID,
+			func(t *testing.T) {
+				deprecatedAnnotations := checkForRuleIDs(ctx, t, client, d.Request, []string{deprecatedRuleSpec.ID})
+				replacementAnnotations := checkForRuleIDs(ctx, t, client, d.Request, deprecatedRuleSpec.ReplacementIDs)
+				if len(deprecatedAnnotations) == 0 {
+					t.Logf("deprecated Rule %q produced no Annotations, add testdata that triggers it", deprecatedRuleSpec.ID)
+				}
+				assertAnnotationLocationsCovered(t, deprecatedRuleSpec, deprecatedAnnotations, replacementAnnotations)
+			},
+		)
+	}
+}
+
+// *** PRIVATE ***
+
+func checkForRuleIDs(
+	ctx context.Context,
+	t *testing.T,
+	client check.Client,
+	requestSpec *RequestSpec,
+	ruleIDs []string,
+) []ExpectedAnnotation {
+	ruleIDsRequestSpec := *requestSpec
+	ruleIDsRequestSpec.RuleIDs = ruleIDs
+	request, err := ruleIDsRequestSpec.ToRequest(ctx)
+	require.NoError(t, err)
+	response, err := client.Check(ctx, request)
+	require.NoError(t, err)
+	globalRuleCoverage.addAnnotations(response.Annotations())
+	return expectedAnnotationsForAnnotations(response.Annotations())
+}
+
+// assertAnnotationLocationsCovered asserts that every deprecated Annotation has a distinct
+// replacement Annotation at the same locations.
+func assertAnnotationLocationsCovered(
+	t *testing.T,
+	deprecatedRuleSpec *check.RuleSpec,
+	deprecatedAnnotations []ExpectedAnnotation,
+	replacementAnnotations []ExpectedAnnotation,
+) {
+	used := make([]bool, len(replacementAnnotations))
+	var uncovered []string
+	for _, deprecatedAnnotation := range deprecatedAnnotations {
+		covered := false
+		for i, replacementAnnotation := range replacementAnnotations {
+			if !used[i] && expectedAnnotationLocationsEqual(deprecatedAnnotation, replacementAnnotation) {
+				used[i] = true
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			uncovered = append(uncovered, deprecatedAnnotation.String())
+		}
+	}
+	assert.Empty(
+		t,
+		uncovered,
+		"deprecated Rule %q has Annotations that are not produced by its replacements %v:\n%s",
+		deprecatedRuleSpec.ID,
+		deprecatedRuleSpec.ReplacementIDs,
+		strings.Join(uncovered, "\n"),
+	)
+}
+
+func expectedAnnotationLocationsEqual(one ExpectedAnnotation, two ExpectedAnnotation) bool {
+	return one.FileLocation.String() == two.FileLocation.String() &&
+		one.AgainstFileLocation.String() == two.AgainstFileLocation.String()
+}
@@ -0,0 +1,272 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package checktest
+
+import (
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// AnnotationsEqualOption is an option for AssertAnnotationsEqual and RequireAnnotationsEqual.
+type AnnotationsEqualOption func(*annotationsEqualOptions)
+
+// AnnotationsEqualWithUnordered returns a new AnnotationsEqualOption that compares
+// Annotations without regard to order.
+//
+// By default, the actual Annotations must be in the same order as the expected Annotations.
+// Annotations returned from a Client are sorted, so the expected Annotations usually need to
+// be in sorted order as well. With this option, each expected Annotation only needs to be
+// matched by some actual Annotation. If an actual Annotation matches more than one expected
+// Annotation, for example with MessagePattern and MessageContains, the Annotations are matched
+// so that as many as possible are matched, regardless of the order of the expected Annotations.
+func AnnotationsEqualWithUnordered() AnnotationsEqualOption {
+	return func(annotationsEqualOptions *annotationsEqualOptions) {
+		annotationsEqualOptions.unordered = true
+	}
+}
+
+// *** PRIVATE ***
+
+const noFileName = "<no file>"
+
+type annotationsEqualOptions struct {
+	unordered bool
+}
+
+func newAnnotationsEqualOptions() *annotationsEqualOptions {
+	return &annotationsEqualOptions{}
+}
+
+// annotationsDiff returns a human-readable diff between the expected and actual
+// ExpectedAnnotations, or the empty string if they are equal.
+//
+// Expected Annotations that have no matching actual Annotation are reported as missing
+// with a "-" prefix, and actual Annotations that have no matching expected Annotation are reported
+// as unexpected with a "+" prefix. Both are grouped by the file name of their FileLocation.
+//
+// If the Annotations are ordered and every Annotation has a match, but the order differs,
+// the positions that differ are reported instead.
+func annotationsDiff(
+	expectedAnnotations []ExpectedAnnotation,
+	actualExpectedAnnotations []ExpectedAnnotation,
+	annotationsEqualOptions *annotationsEqualOptions,
+) string {
+	if !annotationsEqualOptions.unordered && len(expectedAnnotations) == len(actualExpectedAnnotations) {
+		equal := true
+		for i, expectedAnnotation := range expectedAnnotations {
+			if !expectedAnnotationMatches(expectedAnnotation, actualExpectedAnnotations[i]) {
+				equal = false
+				break
+			}
+		}
+		if equal {
+			return ""
+		}
+	}
+
+	actualToExpected := matchExpectedAnnotations(expectedAnnotations, actualExpectedAnnotations)
+	matchedExpected := make([]bool, len(expectedAnnotations))
+	var unexpected []ExpectedAnnotation
+	for i, actualExpectedAnnotation := range actualExpectedAnnotations {
+		if actualToExpected[i] < 0 {
+			unexpected = append(unexpected, actualExpectedAnnotation)
+			continue
+		}
+		matchedExpected[actualToExpected[i]] = true
+	}
+	var missing []ExpectedAnnotation
+	for i, expectedAnnotation := range expectedAnnotations {
+		if !matchedExpected[i] {
+			missing = append(missing, expectedAnnotation)
+		}
+	}
+	if len(missing) == 0 && len(unexpected) == 0 {
+		if annotationsEqualOptions.unordered {
+			return ""
+		}
+		return outOfOrderAnnotationsDiff(expectedAnnotations, actualExpectedAnnotations)
+	}
+
+	fileNameToLines := make(map[string][]string)
+	for _, expectedAnnotation := range missing {
+		fileName := expectedAnnotationFileName(expectedAnnotation)
+		fileNameToLines[fileName] = append(fileNameToLines[fileName], "- "+expectedAnnotation.String())
+	}
+	for _, actualExpectedAnnotation := range unexpected {
+		fileName := expectedAnnotationFileName(actualExpectedAnnotation)
+		fileNameToLines[fileName] = append(fileNameToLines[fileName], "+ "+actualExpectedAnnotation.String())
+	}
+	fileNames := make([]string, 0, len(fileNameToLines))
+	for fileName := range fileNameToLines {
+		fileNames = append(fileNames, fileName)
+	}
+	sort.Strings(fileNames)
+
+	var sb strings.Builder
+	_, _ = sb.WriteString("annotations not equal: ")
+	_, _ = sb.WriteString(strconv.Itoa(len(missing)))
+	_, _ = sb.WriteString(" missing (-), ")
+	_, _ = sb.WriteString(strconv.Itoa(len(unexpected)))
+	_, _ = sb.WriteString(" unexpected (+)\n")
+	for _, fileName := range fileNames {
+		_, _ = sb.WriteString("\n--- ")
+		_, _ = sb.WriteString(fileName)
+		_, _ = sb.WriteString("\n")
+		for _, line := range fileNameToLines[fileName] {
+			_, _ = sb.WriteString(line)
+			_, _ = sb.WriteString("\n")
+		}
+	}
+	return sb.String()
+}
+
+// matchExpectedAnnotations matches the expected ExpectedAnnotations to the actual
+// ExpectedAnnotations, and returns the index of the expected ExpectedAnnotation matched to each
+// actual ExpectedAnnotation, or -1 if the actual ExpectedAnnotation is not matched.
+//
+// An expected ExpectedAnnotation can match more than one actual ExpectedAnnotation, for example
+// with MessagePattern, so matching each expected ExpectedAnnotation to the first actual
+// ExpectedAnnotation that it matches can result in expected ExpectedAnnotations that are
+// reported as missing depending on their order. Instead, this finds a maximum matching with
+// augmenting paths, so that as many ExpectedAnnotations as possible are matched.
+func matchExpectedAnnotations(
+	expectedAnnotations []ExpectedAnnotation,
+	actualExpectedAnnotations []ExpectedAnnotation,
+) []int {
+	expectedToCandidates := make([][]int, len(expectedAnnotations))
+	for i, expectedAnnotation := range expectedAnnotations {
+		for j, actualExpectedAnnotation := range actualExpectedAnnotations {
+			if expectedAnnotationMatches(expectedAnnotation, actualExpectedAnnotation) {
+				expectedToCandidates[i] = append(expectedToCandidates[i], j)
+			}
+		}
+	}
+	actualToExpected := make([]int, len(actualExpectedAnnotations))
+	for j := range actualToExpected {
+		actualToExpected[j] = -1
+	}
+	// augment tries to match the expected ExpectedAnnotation, re-matching the expected
+	// ExpectedAnnotations that are already matched to its candidates if needed.
+	var augment func(int, []bool) bool
+	augment = func(i int, visited []bool) bool {
+		for _, j := range expectedToCandidates[i] {
+			if visited[j] {
+				continue
+			}
+			visited[j] = true
+			if actualToExpected[j] < 0 || augment(actualToExpected[j], visited) {
+				actualToExpected[j] = i
+				return true
+			}
+		}
+		return false
+	}
+	for i := range expectedAnnotations {
+		augment(i, make([]bool, len(actualExpectedAnnotations)))
+	}
+	return actualToExpected
+}
+
+func outOfOrderAnnotationsDiff(
+	expectedAnnotations []ExpectedAnnotation,
+	actualExpectedAnnotations []ExpectedAnnotation,
+) string {
+	var sb strings.Builder
+	_, _ = sb.WriteString("annotations are equal but in a different order, use AnnotationsEqualWithUnordered to ignore order\n")
+	for i, expectedAnnotation := range expectedAnnotations {
+		if expectedAnnotationMatches(expectedAnnotation, actualExpectedAnnotations[i]) {
+			continue
+		}
+		_, _ = sb.WriteString("\n@ index ")
+		_, _ = sb.WriteString(strconv.Itoa(i))
+		_, _ = sb.WriteString("\n- ")
+		_, _ = sb.WriteString(expectedAnnotation.String())
+		_, _ = sb.WriteString("\n+ ")
+		_, _ = sb.WriteString(actualExpectedAnnotations[i].String())
+		_, _ = sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+// expectedAnnotationMatches returns true if the actual ExpectedAnnotation matches the
+// expected ExpectedAnnotation.
+//
+// The message is compared according to the note on ExpectedAnnotation.Message, and the
+// MessageContains and MessagePattern fields on the expected ExpectedAnnotation.
+func expectedAnnotationMatches(expectedAnnotation ExpectedAnnotation, actualExpectedAnnotation ExpectedAnnotation) bool {
+	if expectedAnnotation.RuleID != actualExpectedAnnotation.RuleID {
+		return false
+	}
+	actualMessage := actualExpectedAnnotation.Message
+	switch {
+	case expectedAnnotation.MessageContains != "":
+		if !strings.Contains(actualMessage, expectedAnnotation.MessageContains) {
+			return false
+		}
+	case expectedAnnotation.MessagePattern != nil:
+		if !expectedAnnotation.MessagePattern.MatchString(actualMessage) {
+			return false
+		}
+	case expectedAnnotation.Message != "":
+		if expectedAnnotation.Message != actualMessage {
+			return false
+		}
+	}
+	if expectedAnnotation.Owners != nil && !slices.Equal(expectedAnnotation.Owners, actualExpectedAnnotation.Owners) {
+		return false
+	}
+	if expectedAnnotation.DocURL != "" && expectedAnnotation.DocURL != actualExpectedAnnotation.DocURL {
+		return false
+	}
+	return expectedFileLocationMatches(expectedAnnotation.FileLocation, actualExpectedAnnotation.FileLocation) &&
+		expectedFileLocationMatches(expectedAnnotation.AgainstFileLocation, actualExpectedAnnotation.AgainstFileLocation)
+}
+
+// expectedFileLocationMatches returns true if the actual ExpectedFileLocation matches the
+// expected ExpectedFileLocation.
+//
+// The source path and span are compared according to the notes on ExpectedFileLocation.SourcePath
+// and ExpectedFileLocation.IgnoreSpan.
+func expectedFileLocationMatches(expectedFileLocation *ExpectedFileLocation, actualFileLocation *ExpectedFileLocation) bool {
+	if expectedFileLocation == nil || actualFileLocation == nil {
+		return expectedFileLocation == nil && actualFileLocation == nil
+	}
+	if expectedFileLocation.FileName != actualFileLocation.FileName {
+		return false
+	}
+	if expectedFileLocation.SourcePath != nil && !slices.Equal(expectedFileLocation.SourcePath, actualFileLocation.SourcePath) {
+		return false
+	}
+	return expectedFileLocation.IgnoreSpan || expectedFileLocationSpanEqual(expectedFileLocation, actualFileLocation)
+}
+
+func expectedFileLocationSpanEqual(one *ExpectedFileLocation, two *ExpectedFileLocation) bool {
+	return one.StartLine == two.StartLine &&
+		one.StartColumn == two.StartColumn &&
+		one.EndLine == two.EndLine &&
+		one.EndColumn == two.EndColumn
+}
+
+func expectedAnnotationFileName(expectedAnnotation ExpectedAnnotation) string {
+	if expectedAnnotation.FileLocation != nil {
+		return expectedAnnotation.FileLocation.FileName
+	}
+	if expectedAnnotation.AgainstFileLocation != nil {
+		return expectedAnnotation.AgainstFileLocation.FileName
+	}
+	return noFileName
+}
@@ -0,0 +1,166 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package checktest
+
+import (
+	"regexp"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAnnotationsDiffUnordered(t *testing.T) {
+	t.Parallel()
+
+	// The pattern matches both actual Annotations, while the substring only matches the first.
+	broadExpectedAnnotation := ExpectedAnnotation{
+		RuleID:         "RULE1",
+		MessagePattern: regexp.MustCompile(`^field "\w+"`),
+		FileLocation:   &ExpectedFileLocation{FileName: "a.proto", IgnoreSpan: true},
+	}
+	narrowExpectedAnnotation := ExpectedAnnotation{
+		RuleID:          "RULE1",
+		MessageContains: `"foo"`,
+		FileLocation:    &ExpectedFileLocation{FileName: "a.proto", IgnoreSpan: true},
+	}
+	actualExpectedAnnotations := []ExpectedAnnotation{
+		{
+			RuleID:       "RULE1",
+			Message:      `field "foo" is invalid`,
+			FileLocation: &ExpectedFileLocation{FileName: "a.proto", StartLine: 1},
+		},
+		{
+			RuleID:       "RULE1",
+			Message:      `field "bar" is invalid`,
+			FileLocation: &ExpectedFileLocation{FileName: "a.proto", StartLine: 2},
+		},
+	}
+	testCases := []struct {
+		name                      string
+		expectedAnnotations       []ExpectedAnnotation
+		actualExpectedAnnotations []ExpectedAnnotation
+	}{
+		{
+			name:                      "broad_first",
+			expectedAnnotations:       []ExpectedAnnotation{broadExpectedAnnotation, narrowExpectedAnnotation},
+			actualExpectedAnnotations: actualExpectedAnnotations,
+		},
+		{
+			name:                      "narrow_first",
+			expectedAnnotations:       []ExpectedAnnotation{narrowExpectedAnnotation, broadExpectedAnnotation},
+			actualExpectedAnnotations: actualExpectedAnnotations,
+		},
+		{
+			name:                      "broad_first_actual_reversed",
+			expectedAnnotations:       []ExpectedAnnotation{broadExpectedAnnotation, narrowExpectedAnnotation},
+			actualExpectedAnnotations: testReversed(actualExpectedAnnotations),
+		},
+		{
+			name:                      "narrow_first_actual_reversed",
+			expectedAnnotations:       []ExpectedAnnotation{narrowExpectedAnnotation, broadExpectedAnnotation},
+			actualExpectedAnnotations: testReversed(actualExpectedAnnotations),
+		},
+	}
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Empty(
+				t,
+				annotationsDiff(
+					testCase.expectedAnnotations,
+					testCase.actualExpectedAnnotations,
+					testNewAnnotationsEqualOptions(AnnotationsEqualWithUnordered()),
+				),
+			)
+		})
+	}
+}
+
+func TestAnnotationsDiffUnorderedMissingAndUnexpected(t *testing.T) {
+	t.Parallel()
+
+	// Both expected Annotations only match the first actual Annotation, so one is missing,
+	// and the second actual Annotation is unexpected.
+	expectedAnnotations := []ExpectedAnnotation{
+		{
+			RuleID:          "RULE1",
+			MessageContains: `"foo"`,
+		},
+		{
+			RuleID:         "RULE1",
+			MessagePattern: regexp.MustCompile(`foo`),
+		},
+	}
+	actualExpectedAnnotations := []ExpectedAnnotation{
+		{
+			RuleID:  "RULE1",
+			Message: `field "foo" is invalid`,
+		},
+		{
+			RuleID:  "RULE2",
+			Message: `field "bar" is invalid`,
+		},
+	}
+	assert.Equal(
+		t,
+		`annotations not equal: 1 missing (-), 1 unexpected (+)
+
+--- <no file>
+- ruleID="RULE1" message="" messagePattern="foo" location="nil" againstLocation="nil"
++ ruleID="RULE2" message="field "bar" is invalid" location="nil" againstLocation="nil"
+`,
+		annotationsDiff(
+			expectedAnnotations,
+			actualExpectedAnnotations,
+			testNewAnnotationsEqualOptions(AnnotationsEqualWithUnordered()),
+		),
+	)
+}
+
+func TestAnnotationsDiffOrdered(t *testing.T) {
+	t.Parallel()
+
+	expectedAnnotations := []ExpectedAnnotation{
+		{RuleID: "RULE1"},
+		{RuleID: "RULE2"},
+	}
+	assert.Empty(t, annotationsDiff(expectedAnnotations, expectedAnnotations, testNewAnnotationsEqualOptions()))
+	diff := annotationsDiff(expectedAnnotations, testReversed(expectedAnnotations), testNewAnnotationsEqualOptions())
+	assert.Contains(t, diff, "annotations are equal but in a different order")
+	assert.Empty(
+		t,
+		annotationsDiff(
+			expectedAnnotations,
+			testReversed(expectedAnnotations),
+			testNewAnnotationsEqualOptions(AnnotationsEqualWithUnordered()),
+		),
+	)
+}
+
+func testNewAnnotationsEqualOptions(options ...AnnotationsEqualOption) *annotationsEqualOptions {
+	annotationsEqualOptions := newAnnotationsEqualOptions()
+	for _, option := range options {
+		option(annotationsEqualOptions)
+	}
+	return annotationsEqualOptions
+}
+
+func testReversed[T any](values []T) []T {
+	values = slices.Clone(values)
+	slices.Reverse(values)
+	return values
+}
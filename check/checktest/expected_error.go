@@ -0,0 +1,80 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package checktest
+
+import (
+	"errors"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"pluginrpc.com/pluginrpc"
+)
+
+// ExpectedError is an error that a Check call is expected to fail with.
+//
+// This is used to test that a plugin rejects invalid input, for example an option with a
+// value of the wrong type:
+//
+//	checktest.CheckTest{
+//		Request: &checktest.RequestSpec{
+//			Files: ...,
+//			Options: map[string]any{"timestamp_suffix": int64(1)},
+//		},
+//		Spec: spec,
+//		ExpectedError: &checktest.ExpectedError{
+//			MessageContains: "timestamp_suffix",
+//		},
+//	}.Run(t)
+//
+// Errors returned from RuleHandlers are serialized across the plugin boundary, even when
+// testing a Spec, so the identity of an error is not preserved and errors.Is cannot be used.
+// Only the message and the pluginrpc.Code of the error can be compared.
+//
+// If no fields are set, any error matches.
+type ExpectedError struct {
+	// MessageContains is a substring that the error message must contain.
+	MessageContains string
+	// MessagePattern is a regular expression that the error message must match.
+	//
+	// The pattern is not anchored, use ^ and $ to match the entire message.
+	MessagePattern *regexp.Regexp
+	// Code is the pluginrpc.Code of the error.
+	//
+	// If 0, the Code will not be compared. Errors returned from RuleHandlers that are not
+	// *pluginrpc.Errors have the Code pluginrpc.CodeUnknown.
+	Code pluginrpc.Code
+}
+
+// *** PRIVATE ***
+
+func assertErrorMatches(t *testing.T, expectedError *ExpectedError, err error) {
+	require.Error(t, err, "expected Check to fail")
+	if expectedError.MessageContains != "" {
+		assert.ErrorContains(t, err, expectedError.MessageContains)
+	}
+	if expectedError.MessagePattern != nil {
+		assert.Regexp(t, expectedError.MessagePattern, err.Error())
+	}
+	if expectedError.Code != 0 {
+		code := pluginrpc.CodeUnknown
+		var pluginrpcError *pluginrpc.Error
+		if errors.As(err, &pluginrpcError) {
+			code = pluginrpcError.Code()
+		}
+		assert.Equal(t, expectedError.Code, code, "unexpected error code for error: %v", err)
+	}
+}
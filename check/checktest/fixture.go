@@ -0,0 +1,135 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package checktest
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ReadExpectedAnnotationsFile reads ExpectedAnnotations from the YAML or JSON fixture file at the given path.
+//
+// Fixture files allow expectations to be maintained without editing Go code. Unlike golden files,
+// fixture files are written by hand, and fields that are not set are not compared, in the same
+// way as for ExpectedAnnotations. The file contains a list of Annotations:
+//
+//	# testdata/simple.yaml
+//	- rule_id: FIELD_LOWER_SNAKE_CASE
+//	  message_contains: should be lower_snake_case
+//	  file_location:
+//	    file_name: simple.proto
+//	    start_line: 8
+//	    start_column: 2
+//	    end_line: 8
+//	    end_column: 23
+//	- rule_id: TIMESTAMP_SUFFIX
+//	  message_pattern: "^Fields of type google.protobuf.Timestamp must end in .*$"
+//	  owners: [acme-team]
+//	  doc_url: https://example.com/rules/TIMESTAMP_SUFFIX
+//
+// Each entry may set rule_id, message, message_contains, message_pattern, file_location,
+// against_file_location, owners, and doc_url, which correspond to the fields of
+// ExpectedAnnotation. Lines and columns are zero-indexed, as within ExpectedFileLocation.
+// Unknown keys are an error. As JSON is a subset of YAML, the same structure can be written
+// as a JSON array.
+func ReadExpectedAnnotationsFile(path string) ([]ExpectedAnnotation, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	expectedAnnotations, err := unmarshalFixtureAnnotations(data)
+	if err != nil {
+		return nil, fmt.Errorf("could not read %q: %w", path, err)
+	}
+	return expectedAnnotations, nil
+}
+
+// *** PRIVATE ***
+
+type fixtureAnnotation struct {
+	RuleID              string               `yaml:"rule_id"`
+	Message             string               `yaml:"message"`
+	MessageContains     string               `yaml:"message_contains"`
+	MessagePattern      string               `yaml:"message_pattern"`
+	FileLocation        *fixtureFileLocation `yaml:"file_location"`
+	AgainstFileLocation *fixtureFileLocation `yaml:"against_file_location"`
+	Owners              []string             `yaml:"owners"`
+	DocURL              string               `yaml:"doc_url"`
+}
+
+type fixtureFileLocation struct {
+	FileName    string `yaml:"file_name"`
+	StartLine   int    `yaml:"start_line"`
+	StartColumn int    `yaml:"start_column"`
+	EndLine     int    `yaml:"end_line"`
+	EndColumn   int    `yaml:"end_column"`
+}
+
+func unmarshalFixtureAnnotations(data []byte) ([]ExpectedAnnotation, error) {
+	var fixtureAnnotations []fixtureAnnotation
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&fixtureAnnotations); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	// An empty file expects no Annotations.
+	expectedAnnotations := make([]ExpectedAnnotation, len(fixtureAnnotations))
+	for i, fixtureAnnotation := range fixtureAnnotations {
+		if fixtureAnnotation.RuleID == "" {
+			return nil, fmt.Errorf("entry %d: rule_id is required", i)
+		}
+		var messagePattern *regexp.Regexp
+		if fixtureAnnotation.MessagePattern != "" {
+			var err error
+			messagePattern, err = regexp.Compile(fixtureAnnotation.MessagePattern)
+			if err != nil {
+				return nil, fmt.Errorf("entry %d: invalid message_pattern: %w", i, err)
+			}
+		}
+		expectedAnnotations[i] = ExpectedAnnotation{
+			RuleID:              fixtureAnnotation.RuleID,
+			Message:             fixtureAnnotation.Message,
+			MessageContains:     fixtureAnnotation.MessageContains,
+			MessagePattern:      messagePattern,
+			FileLocation:        expectedFileLocationForFixtureFileLocation(fixtureAnnotation.FileLocation),
+			AgainstFileLocation: expectedFileLocationForFixtureFileLocation(fixtureAnnotation.AgainstFileLocation),
+			Owners:              fixtureAnnotation.Owners,
+			DocURL:              fixtureAnnotation.DocURL,
+		}
+	}
+	if err := validateExpectedAnnotations(expectedAnnotations); err != nil {
+		return nil, err
+	}
+	return expectedAnnotations, nil
+}
+
+func expectedFileLocationForFixtureFileLocation(fixtureFileLocation *fixtureFileLocation) *ExpectedFileLocation {
+	if fixtureFileLocation == nil {
+		return nil
+	}
+	return &ExpectedFileLocation{
+		FileName:    fixtureFileLocation.FileName,
+		StartLine:   fixtureFileLocation.StartLine,
+		StartColumn: fixtureFileLocation.StartColumn,
+		EndLine:     fixtureFileLocation.EndLine,
+		EndColumn:   fixtureFileLocation.EndColumn,
+	}
+}
@@ -0,0 +1,66 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package checktest
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	"buf.build/go/bufplugin/check"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// AssertAnnotationsAbsent asserts that none of the Annotations match any of the forbidden Annotations.
+//
+// A forbidden Annotation is matched the same way as an expected Annotation in AssertAnnotationsEqual,
+// except that a nil FileLocation or AgainstFileLocation matches any location. That is, a forbidden
+// Annotation with only a RuleID asserts that the Rule did not produce any Annotations.
+//
+// This is useful to pin down false-positive fixes without enumerating every expected Annotation.
+func AssertAnnotationsAbsent(t *testing.T, forbiddenAnnotations []ExpectedAnnotation, actualAnnotations []check.Annotation) {
+	require.NoError(t, validateExpectedAnnotations(forbiddenAnnotations))
+	actualExpectedAnnotations := expectedAnnotationsForAnnotations(actualAnnotations)
+	var sb strings.Builder
+	var numFound int
+	for _, forbiddenAnnotation := range forbiddenAnnotations {
+		for _, actualExpectedAnnotation := range actualExpectedAnnotations {
+			if forbiddenAnnotationMatches(forbiddenAnnotation, actualExpectedAnnotation) {
+				numFound++
+				_, _ = sb.WriteString("\nforbidden: ")
+				_, _ = sb.WriteString(forbiddenAnnotation.String())
+				_, _ = sb.WriteString("\nactual:    ")
+				_, _ = sb.WriteString(actualExpectedAnnotation.String())
+				_, _ = sb.WriteString("\n")
+			}
+		}
+	}
+	if numFound > 0 {
+		assert.Fail(t, strconv.Itoa(numFound)+" forbidden annotations found:\n"+sb.String())
+	}
+}
+
+// *** PRIVATE ***
+
+func forbiddenAnnotationMatches(forbiddenAnnotation ExpectedAnnotation, actualExpectedAnnotation ExpectedAnnotation) bool {
+	if forbiddenAnnotation.FileLocation == nil {
+		actualExpectedAnnotation.FileLocation = nil
+	}
+	if forbiddenAnnotation.AgainstFileLocation == nil {
+		actualExpectedAnnotation.AgainstFileLocation = nil
+	}
+	return expectedAnnotationMatches(forbiddenAnnotation, actualExpectedAnnotation)
+}
@@ -0,0 +1,152 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package checktest
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"slices"
+	"testing"
+	"time"
+
+	"buf.build/go/bufplugin/check"
+	"buf.build/go/bufplugin/descriptor/descriptortest"
+	"buf.build/go/bufplugin/option"
+	"github.com/stretchr/testify/require"
+)
+
+const defaultFuzzNumIterations = 100
+
+// defaultFuzzRandomSpec is the RandomSpec used if FuzzTest.RandomSpec is not set.
+var defaultFuzzRandomSpec = &descriptortest.RandomSpec{
+	MaxFiles:                 4,
+	MaxMessagesPerFile:       4,
+	MaxFieldsPerMessage:      8,
+	MaxNestingDepth:          3,
+	MaxNestedTypesPerMessage: 2,
+	MaxEnumsPerFile:          2,
+	MaxValuesPerEnum:         4,
+	MaxServicesPerFile:       2,
+	MaxMethodsPerService:     3,
+	MaxExtensionsPerFile:     4,
+	MaxImportsPerFile:        2,
+}
+
+// FuzzTest is a test that runs a Spec against randomly-generated files, and asserts that
+// the Check call does not return an error, and that no RuleHandler panics.
+//
+// This is used to catch crashes in RuleHandlers on unusual shapes of files that are not
+// typically covered by testdata, such as deeply nested messages, empty messages and services,
+// recursive messages, and files with many extensions. The files are generated with
+// descriptortest.NewRandom. The Annotations produced are not asserted.
+//
+//	func TestFuzz(t *testing.T) {
+//		t.Parallel()
+//		checktest.FuzzTest{
+//			Spec: spec,
+//		}.Run(t)
+//	}
+type FuzzTest struct {
+	// Spec is the Spec to test.
+	//
+	// Required.
+	Spec *check.Spec
+	// RandomSpec specifies the shape of the generated files.
+	//
+	// Optional. If not set, a RandomSpec that generates small files exercising all shapes is used.
+	RandomSpec *descriptortest.RandomSpec
+	// NumIterations is the number of sets of files to generate and check.
+	//
+	// Optional. If 0, 100 iterations are run.
+	NumIterations int
+	// Seed is the seed for the first iteration. Each subsequent iteration uses the next seed.
+	//
+	// Optional. If 0, a seed based on the current time is used. The seed of a failing iteration
+	// is included in the failure message, so that the failure can be reproduced by setting
+	// Seed to that value and NumIterations to 1.
+	Seed int64
+	// RuleIDs are the specific RuleIDs to run.
+	RuleIDs []string
+	// Options are any options to pass to the plugin.
+	Options map[string]any
+}
+
+// Run runs the test.
+//
+// This will:
+//
+//   - Create a new Client based on the Spec, recovering from panics within RuleHandlers
+//     and Spec.Before.
+//   - For each iteration, generate a new set of files, and call Check on the Client.
+//   - Fail if any Check call returns an error.
+func (f FuzzTest) Run(t *testing.T) {
+	ctx := context.Background()
+
+	require.NotNil(t, f.Spec)
+	randomSpec := f.RandomSpec
+	if randomSpec == nil {
+		randomSpec = defaultFuzzRandomSpec
+	}
+	numIterations := f.NumIterations
+	if numIterations == 0 {
+		numIterations = defaultFuzzNumIterations
+	}
+	seed := f.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	options, err := option.NewOptions(f.Options)
+	require.NoError(t, err)
+	client, err := check.NewClientForSpec(recoveringSpec(f.Spec))
+	require.NoError(t, err)
+
+	for i := 0; i < numIterations; i++ {
+		iterationSeed := seed + int64(i)
+		fileDescriptors, err := descriptortest.NewRandom(randomSpec, iterationSeed)
+		require.NoError(t, err, "seed %d", iterationSeed)
+		request, err := check.NewRequest(
+			fileDescriptors,
+			check.WithOptions(options),
+			check.WithRuleIDs(f.RuleIDs...),
+		)
+		require.NoError(t, err)
+		_, err = client.Check(ctx, request)
+		require.NoError(t, err, "to reproduce, set Seed to %d and NumIterations to 1", iterationSeed)
+	}
+}
+
+// *** PRIVATE ***
+
+// recoveringSpec returns a shallow copy of the Spec that recovers from panics within
+// RuleHandlers and Spec.Before, so that a panic fails the iteration instead of the test binary.
+func recoveringSpec(spec *check.Spec) *check.Spec {
+	recoveringSpec := *spec
+	recoveringSpec.RuleHandlerMiddlewares = append(
+		[]check.RuleHandlerMiddleware{check.RecoverRuleHandlerMiddleware()},
+		slices.Clone(spec.RuleHandlerMiddlewares)...,
+	)
+	if before := spec.Before; before != nil {
+		recoveringSpec.Before = func(ctx context.Context, request check.Request) (_ context.Context, _ check.Request, retErr error) {
+			defer func() {
+				if recovered := recover(); recovered != nil {
+					retErr = fmt.Errorf("panic in Before: %v\n%s", recovered, debug.Stack())
+				}
+			}()
+			return before(ctx, request)
+		}
+	}
+	return &recoveringSpec
+}
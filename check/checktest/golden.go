@@ -0,0 +1,153 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package checktest
+
+import (
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"buf.build/go/bufplugin/check"
+	"buf.build/go/bufplugin/internal/pkg/xslices"
+	"github.com/stretchr/testify/require"
+)
+
+// The flag is namespaced so that it does not conflict with an -update flag defined
+// by the package under test.
+var updateGoldenFiles = flag.Bool(
+	"checktest.update",
+	false,
+	"update checktest golden files instead of comparing against them",
+)
+
+// AssertAnnotationsGolden asserts that the Annotations equal the Annotations stored in the golden file
+// at the given path.
+//
+// If the test is run with the -checktest.update flag, the golden file is written with the
+// Annotations instead, creating any parent directories as needed:
+//
+//	go test ./path/to/plugin -checktest.update
+//
+// The flag is only defined in test binaries that import checktest, so it should only be passed
+// when running the tests of such packages.
+//
+// The golden file is a JSON array of the rule ID, message, and locations of each Annotation, in the
+// order they were returned. Messages are always compared.
+func AssertAnnotationsGolden(t *testing.T, goldenFilePath string, actualAnnotations []check.Annotation) {
+	actualExpectedAnnotations := expectedAnnotationsForAnnotations(actualAnnotations)
+	if *updateGoldenFiles {
+		data, err := marshalGoldenAnnotations(actualExpectedAnnotations)
+		require.NoError(t, err)
+		require.NoError(t, os.MkdirAll(filepath.Dir(goldenFilePath), 0750))
+		require.NoError(t, os.WriteFile(goldenFilePath, data, 0600))
+		return
+	}
+	data, err := os.ReadFile(goldenFilePath)
+	require.NoError(t, err, "could not read golden file, run with -checktest.update to create it")
+	expectedAnnotations, err := unmarshalGoldenAnnotations(data)
+	require.NoError(t, err)
+	AssertAnnotationsEqual(t, expectedAnnotations, actualAnnotations)
+}
+
+// *** PRIVATE ***
+
+type goldenAnnotation struct {
+	RuleID              string              `json:"rule_id"`
+	Message             string              `json:"message,omitempty"`
+	FileLocation        *goldenFileLocation `json:"file_location,omitempty"`
+	AgainstFileLocation *goldenFileLocation `json:"against_file_location,omitempty"`
+	Owners              []string            `json:"owners,omitempty"`
+	DocURL              string              `json:"doc_url,omitempty"`
+}
+
+type goldenFileLocation struct {
+	FileName    string `json:"file_name"`
+	StartLine   int    `json:"start_line"`
+	StartColumn int    `json:"start_column"`
+	EndLine     int    `json:"end_line"`
+	EndColumn   int    `json:"end_column"`
+}
+
+func marshalGoldenAnnotations(expectedAnnotations []ExpectedAnnotation) ([]byte, error) {
+	goldenAnnotations := xslices.Map(
+		expectedAnnotations,
+		func(expectedAnnotation ExpectedAnnotation) goldenAnnotation {
+			return goldenAnnotation{
+				RuleID:              expectedAnnotation.RuleID,
+				Message:             expectedAnnotation.Message,
+				FileLocation:        goldenFileLocationForExpectedFileLocation(expectedAnnotation.FileLocation),
+				AgainstFileLocation: goldenFileLocationForExpectedFileLocation(expectedAnnotation.AgainstFileLocation),
+				Owners:              expectedAnnotation.Owners,
+				DocURL:              expectedAnnotation.DocURL,
+			}
+		},
+	)
+	if goldenAnnotations == nil {
+		goldenAnnotations = []goldenAnnotation{}
+	}
+	data, err := json.MarshalIndent(goldenAnnotations, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+func unmarshalGoldenAnnotations(data []byte) ([]ExpectedAnnotation, error) {
+	var goldenAnnotations []goldenAnnotation
+	if err := json.Unmarshal(data, &goldenAnnotations); err != nil {
+		return nil, err
+	}
+	return xslices.Map(
+		goldenAnnotations,
+		func(goldenAnnotation goldenAnnotation) ExpectedAnnotation {
+			return ExpectedAnnotation{
+				RuleID:              goldenAnnotation.RuleID,
+				Message:             goldenAnnotation.Message,
+				FileLocation:        expectedFileLocationForGoldenFileLocation(goldenAnnotation.FileLocation),
+				AgainstFileLocation: expectedFileLocationForGoldenFileLocation(goldenAnnotation.AgainstFileLocation),
+				Owners:              goldenAnnotation.Owners,
+				DocURL:              goldenAnnotation.DocURL,
+			}
+		},
+	), nil
+}
+
+func goldenFileLocationForExpectedFileLocation(expectedFileLocation *ExpectedFileLocation) *goldenFileLocation {
+	if expectedFileLocation == nil {
+		return nil
+	}
+	return &goldenFileLocation{
+		FileName:    expectedFileLocation.FileName,
+		StartLine:   expectedFileLocation.StartLine,
+		StartColumn: expectedFileLocation.StartColumn,
+		EndLine:     expectedFileLocation.EndLine,
+		EndColumn:   expectedFileLocation.EndColumn,
+	}
+}
+
+func expectedFileLocationForGoldenFileLocation(goldenFileLocation *goldenFileLocation) *ExpectedFileLocation {
+	if goldenFileLocation == nil {
+		return nil
+	}
+	return &ExpectedFileLocation{
+		FileName:    goldenFileLocation.FileName,
+		StartLine:   goldenFileLocation.StartLine,
+		StartColumn: goldenFileLocation.StartColumn,
+		EndLine:     goldenFileLocation.EndLine,
+		EndColumn:   goldenFileLocation.EndColumn,
+	}
+}
@@ -0,0 +1,64 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package checktest
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+
+	"buf.build/go/bufplugin/descriptor"
+	"buf.build/go/bufplugin/descriptor/descriptortest"
+	"buf.build/go/bufplugin/internal/pkg/bufimage"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// *** PRIVATE ***
+
+// readFileDescriptorSetFile reads the FileDescriptors from the serialized FileDescriptorSet or
+// buf image at the path.
+//
+// If filePaths is set, files not within filePaths are marked as imports. Otherwise, files are
+// marked as imports per the buf image, or not marked as imports if the file is a FileDescriptorSet.
+//
+// If fsys is set, the path is read from fsys instead of the local file system.
+func readFileDescriptorSetFile(fsys fs.FS, path string, filePaths []string) ([]descriptor.FileDescriptor, error) {
+	var data []byte
+	var err error
+	if fsys != nil {
+		data, err = fs.ReadFile(fsys, path)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	fileDescriptors, err := bufimage.FileDescriptorsForData(data, filePaths)
+	if err != nil {
+		return nil, fmt.Errorf("could not read %q: %w", path, err)
+	}
+	return fileDescriptors, nil
+}
+
+func validateFileDescriptorSetProtoFileSpec(protoFileSpec *ProtoFileSpec) error {
+	if len(protoFileSpec.DirPaths) > 0 || len(protoFileSpec.Sources) > 0 {
+		return errors.New("DirPaths and Sources cannot be set on ProtoFileSpec if FileDescriptorSetPath is set")
+	}
+	if protoFileSpec.SourceInfoMode != descriptortest.SourceInfoModeBuf || protoFileSpec.MaxEdition != descriptorpb.Edition_EDITION_UNKNOWN {
+		return errors.New("SourceInfoMode and MaxEdition cannot be set on ProtoFileSpec if FileDescriptorSetPath is set")
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package checktest
+
+import (
+	"context"
+	"testing"
+
+	"buf.build/go/bufplugin/check"
+	"buf.build/go/bufplugin/info"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// InfoTest is a test that calls GetPluginInfo on a plugin, and compares the result with an
+// expected info.Spec.
+//
+// The call is made through a Client, in the same way buf calls a plugin, so that the wiring
+// of the PluginInfoService is covered in addition to the CheckService. The URL, license, and
+// documentation are compared. Deprecation is not compared, as it is not carried by the
+// PluginInfo protocol.
+//
+//	func TestInfo(t *testing.T) {
+//		t.Parallel()
+//		checktest.InfoTest{
+//			Spec: spec,
+//			Expected: &info.Spec{
+//				URL:           "https://github.com/acme/buf-plugin-acme",
+//				SPDXLicenseID: "apache-2.0",
+//				DocShort:      "Enforces the API conventions of Acme.",
+//			},
+//		}.Run(t)
+//	}
+type InfoTest struct {
+	// Spec is the Spec to test.
+	//
+	// Required unless Binary is set. Must not be set if Binary is set.
+	Spec *check.Spec
+	// Binary is the path to a built plugin binary to test.
+	//
+	// See CheckTest.Binary.
+	Binary string
+	// BinaryArgs are the arguments for the Binary. See CheckTest.BinaryArgs.
+	BinaryArgs []string
+	// Expected is the expected information about the plugin.
+	//
+	// Optional if Spec is set, in which case this defaults to the Info of the Spec, verifying
+	// that the Info is served as declared. Required if Binary is set.
+	Expected *info.Spec
+}
+
+// Run runs the test.
+//
+// This will:
+//
+//   - Create a new Client based on the Spec, or the Binary if set.
+//   - Call GetPluginInfo on the Client.
+//   - Compare the resulting PluginInfo with Expected, failing if there is a mismatch.
+func (i InfoTest) Run(t *testing.T) {
+	ctx := context.Background()
+
+	expected := i.Expected
+	if expected == nil && i.Spec != nil {
+		expected = i.Spec.Info
+	}
+	require.NotNil(t, expected, "Expected must be set if Spec.Info is not set")
+	expectedPluginInfo, err := info.NewPluginInfoForSpec(expected)
+	require.NoError(t, err)
+	client, err := newClientForSpecOrBinary(i.Spec, i.Binary, i.BinaryArgs, nil)
+	require.NoError(t, err)
+	pluginInfo, err := client.GetPluginInfo(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, newPluginInfoFields(expectedPluginInfo), newPluginInfoFields(pluginInfo))
+}
+
+// *** PRIVATE ***
+
+// pluginInfoFields are the fields of an info.PluginInfo that are carried by the PluginInfo protocol.
+//
+// A flat struct of strings results in a readable diff on mismatch.
+type pluginInfoFields struct {
+	URL           string
+	SPDXLicenseID string
+	LicenseText   string
+	LicenseURL    string
+	DocShort      string
+	DocLong       string
+}
+
+func newPluginInfoFields(pluginInfo info.PluginInfo) pluginInfoFields {
+	var pluginInfoFields pluginInfoFields
+	if url := pluginInfo.URL(); url != nil {
+		pluginInfoFields.URL = url.String()
+	}
+	if license := pluginInfo.License(); license != nil {
+		pluginInfoFields.SPDXLicenseID = license.SPDXLicenseID()
+		pluginInfoFields.LicenseText = license.Text()
+		if licenseURL := license.URL(); licenseURL != nil {
+			pluginInfoFields.LicenseURL = licenseURL.String()
+		}
+	}
+	if doc := pluginInfo.Doc(); doc != nil {
+		pluginInfoFields.DocShort = doc.Short()
+		pluginInfoFields.DocLong = doc.Long()
+	}
+	return pluginInfoFields
+}
@@ -0,0 +1,151 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package checktest
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"buf.build/go/bufplugin/check"
+	"buf.build/go/bufplugin/internal/pkg/xslices"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// InventoryTest is a test that compares the Rules and Categories of a plugin against a golden file.
+//
+// The golden file contains the ID, Categories, default status, purpose, type, and deprecation of
+// every Rule and Category, in the same JSON format as the rules and categories of a check.Manifest.
+// The test fails if any of these change without the golden file being updated, so that accidental
+// Rule removals, renames, or changes to defaults are visible in review.
+//
+// If the test is run with the -checktest.update flag, the golden file is written instead:
+//
+//	go test ./path/to/plugin -checktest.update
+//
+// For example:
+//
+//	func TestInventory(t *testing.T) {
+//		t.Parallel()
+//		checktest.InventoryTest{
+//			Spec:           spec,
+//			GoldenFilePath: "testdata/inventory.golden.json",
+//		}.Run(t)
+//	}
+type InventoryTest struct {
+	// Spec is the Spec to test.
+	//
+	// Required unless Binary is set. Must not be set if Binary is set.
+	Spec *check.Spec
+	// Binary is the path to a built plugin binary to test.
+	//
+	// See CheckTest.Binary.
+	Binary string
+	// BinaryArgs are the arguments to invoke the plugin under on the Binary, if any.
+	//
+	// See CheckTest.BinaryArgs.
+	BinaryArgs []string
+	// GoldenFilePath is the path to the golden file.
+	//
+	// Required.
+	GoldenFilePath string
+}
+
+// Run runs the test.
+//
+// This will:
+//
+//   - Create a new Client based on the Spec, or the Binary if set.
+//   - Call ListRules and ListCategories on the Client.
+//   - Compare the Rules and Categories with the golden file at GoldenFilePath, failing
+//     if there is a mismatch, or write the golden file if -checktest.update is set.
+func (i InventoryTest) Run(t *testing.T) {
+	ctx := context.Background()
+
+	require.NotEmpty(t, i.GoldenFilePath)
+	client, err := newClientForSpecOrBinary(i.Spec, i.Binary, i.BinaryArgs, nil)
+	require.NoError(t, err)
+	rules, err := client.ListRules(ctx)
+	require.NoError(t, err)
+	categories, err := client.ListCategories(ctx)
+	require.NoError(t, err)
+	data, err := marshalInventory(rules, categories)
+	require.NoError(t, err)
+
+	if *updateGoldenFiles {
+		require.NoError(t, os.MkdirAll(filepath.Dir(i.GoldenFilePath), 0750))
+		require.NoError(t, os.WriteFile(i.GoldenFilePath, data, 0600))
+		return
+	}
+	goldenData, err := os.ReadFile(i.GoldenFilePath)
+	require.NoError(t, err, "could not read golden file, run with -checktest.update to create it")
+	assert.Equal(
+		t,
+		string(goldenData),
+		string(data),
+		"the Rules or Categories of the plugin changed, run with -checktest.update to update the golden file if this is intended",
+	)
+}
+
+// *** PRIVATE ***
+
+type inventory struct {
+	Rules      []check.ManifestRule     `json:"rules"`
+	Categories []check.ManifestCategory `json:"categories"`
+}
+
+func marshalInventory(rules []check.Rule, categories []check.Category) ([]byte, error) {
+	inventory := inventory{
+		Rules: xslices.Map(
+			rules,
+			func(rule check.Rule) check.ManifestRule {
+				return check.ManifestRule{
+					ID:             rule.ID(),
+					CategoryIDs:    xslices.Map(rule.Categories(), check.Category.ID),
+					Default:        rule.Default(),
+					Purpose:        rule.Purpose(),
+					Type:           rule.Type().String(),
+					Deprecated:     rule.Deprecated(),
+					ReplacementIDs: rule.ReplacementIDs(),
+				}
+			},
+		),
+		Categories: xslices.Map(
+			categories,
+			func(category check.Category) check.ManifestCategory {
+				return check.ManifestCategory{
+					ID:             category.ID(),
+					Purpose:        category.Purpose(),
+					Deprecated:     category.Deprecated(),
+					ReplacementIDs: category.ReplacementIDs(),
+				}
+			},
+		),
+	}
+	if inventory.Rules == nil {
+		inventory.Rules = []check.ManifestRule{}
+	}
+	if inventory.Categories == nil {
+		inventory.Categories = []check.ManifestCategory{}
+	}
+	data, err := json.MarshalIndent(inventory, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
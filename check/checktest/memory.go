@@ -0,0 +1,172 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package checktest
+
+import (
+	"context"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"buf.build/go/bufplugin/check"
+	"buf.build/go/bufplugin/descriptor"
+	"buf.build/go/bufplugin/descriptor/descriptortest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const memorySampleInterval = 5 * time.Millisecond
+
+// MemoryTest is a test that asserts bounds on the memory used by a single Check call against a Spec.
+//
+// This is used to catch memory regressions in RuleHandlers. Typically, the FileDescriptors are
+// large and generated with NewSyntheticFileDescriptors.
+//
+// Memory statistics are process-wide, so tests using MemoryTest should not call t.Parallel(),
+// and should not be run in parallel with other tests in the same package.
+type MemoryTest struct {
+	// Spec is the Spec to test.
+	//
+	// Required.
+	Spec *check.Spec
+	// FileDescriptors are the FileDescriptors to check.
+	//
+	// Required.
+	FileDescriptors []descriptor.FileDescriptor
+	// RuleIDs are the specific RuleIDs to run.
+	RuleIDs []string
+	// MaxTotalAllocatedBytes is the maximum number of bytes that can be allocated, cumulatively,
+	// during the Check call.
+	//
+	// If 0, this is not asserted.
+	MaxTotalAllocatedBytes uint64
+	// MaxPeakHeapBytes is the maximum number of heap bytes that can be in use at any one time
+	// during the Check call, above what was in use before the Check call.
+	//
+	// The heap is sampled periodically, so short-lived peaks may not be observed.
+	//
+	// If 0, this is not asserted.
+	MaxPeakHeapBytes uint64
+}
+
+// Run runs the test.
+//
+// This will:
+//
+//   - Create a new Request and a new Client based on the Spec.
+//   - Call Check on the Client once to warm up any lazily-initialized state.
+//   - Call Check on the Client again, measuring the memory used.
+//   - Fail if the measured memory exceeds the specified bounds.
+//
+// The measured values are logged, which is useful for determining initial bounds.
+//
+// Unlike the other tests within checktest, this accepts a testing.TB, so that it can also be
+// run from benchmarks.
+func (m MemoryTest) Run(t testing.TB) {
+	ctx := context.Background()
+
+	require.NotNil(t, m.Spec)
+	require.NotEmpty(t, m.FileDescriptors)
+
+	request, err := check.NewRequest(m.FileDescriptors, check.WithRuleIDs(m.RuleIDs...))
+	require.NoError(t, err)
+	client, err := check.NewClientForSpec(m.Spec)
+	require.NoError(t, err)
+	_, err = client.Check(ctx, request)
+	require.NoError(t, err)
+
+	totalAllocatedBytes, peakHeapBytes, err := measureMemory(
+		func() error {
+			_, err := client.Check(ctx, request)
+			return err
+		},
+	)
+	require.NoError(t, err)
+	t.Logf("total allocated bytes: %d, peak heap bytes: %d", totalAllocatedBytes, peakHeapBytes)
+	if m.MaxTotalAllocatedBytes > 0 {
+		assert.LessOrEqual(t, totalAllocatedBytes, m.MaxTotalAllocatedBytes, "total allocated bytes exceeded MaxTotalAllocatedBytes")
+	}
+	if m.MaxPeakHeapBytes > 0 {
+		assert.LessOrEqual(t, peakHeapBytes, m.MaxPeakHeapBytes, "peak heap bytes exceeded MaxPeakHeapBytes")
+	}
+}
+
+// NewSyntheticFileDescriptors returns a new set of generated FileDescriptors with the given sizes.
+//
+// This is a shorthand for descriptortest.NewCorpus, which should be used directly if more
+// control over the generated FileDescriptors is needed.
+//
+// This is typically used with MemoryTest.
+func NewSyntheticFileDescriptors(
+	numFiles int,
+	numMessagesPerFile int,
+	numFieldsPerMessage int,
+) ([]descriptor.FileDescriptor, error) {
+	return descriptortest.NewCorpus(
+		&descriptortest.CorpusSpec{
+			NumFiles:            numFiles,
+			NumMessagesPerFile:  numMessagesPerFile,
+			NumFieldsPerMessage: numFieldsPerMessage,
+		},
+	)
+}
+
+// *** PRIVATE ***
+
+// measureMemory runs f, and returns the total bytes allocated while running f, along with
+// the peak heap bytes in use above the heap bytes in use before running f.
+func measureMemory(f func() error) (uint64, uint64, error) {
+	runtime.GC()
+	var before runtime.MemStats
+	runtime.ReadMemStats(&before)
+
+	var peakHeapAlloc uint64
+	var lock sync.Mutex
+	sample := func() {
+		var memStats runtime.MemStats
+		runtime.ReadMemStats(&memStats)
+		lock.Lock()
+		peakHeapAlloc = max(peakHeapAlloc, memStats.HeapAlloc)
+		lock.Unlock()
+	}
+	doneC := make(chan struct{})
+	stoppedC := make(chan struct{})
+	go func() {
+		defer close(stoppedC)
+		ticker := time.NewTicker(memorySampleInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-doneC:
+				return
+			case <-ticker.C:
+				sample()
+			}
+		}
+	}()
+	err := f()
+	close(doneC)
+	<-stoppedC
+	sample()
+
+	var after runtime.MemStats
+	runtime.ReadMemStats(&after)
+	var peakHeapBytes uint64
+	if peakHeapAlloc > before.HeapAlloc {
+		peakHeapBytes = peakHeapAlloc - before.HeapAlloc
+	}
+	return after.TotalAlloc - before.TotalAlloc, peakHeapBytes, err
+}
@@ -0,0 +1,56 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package checktest
+
+import (
+	"errors"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Memory statistics are process-wide, so these tests do not call t.Parallel().
+
+func TestMeasureMemory(t *testing.T) {
+	const size = 16 << 20
+
+	var data []byte
+	totalAllocatedBytes, peakHeapBytes, err := measureMemory(
+		func() error {
+			data = make([]byte, size)
+			return nil
+		},
+	)
+	require.NoError(t, err)
+	// The allocation is still in use when the heap is last sampled.
+	assert.GreaterOrEqual(t, totalAllocatedBytes, uint64(size))
+	assert.GreaterOrEqual(t, peakHeapBytes, uint64(size))
+	// Other allocations within the test binary are far smaller than the allocation.
+	assert.Less(t, totalAllocatedBytes, uint64(2*size))
+	assert.Less(t, peakHeapBytes, uint64(2*size))
+	runtime.KeepAlive(data)
+}
+
+func TestMeasureMemoryError(t *testing.T) {
+	measureErr := errors.New("measure error")
+	_, _, err := measureMemory(
+		func() error {
+			return measureErr
+		},
+	)
+	assert.Equal(t, measureErr, err)
+}
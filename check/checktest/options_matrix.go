@@ -0,0 +1,154 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package checktest
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+
+	"buf.build/go/bufplugin/check"
+	"buf.build/go/bufplugin/descriptor/descriptortest"
+	"github.com/stretchr/testify/require"
+)
+
+// OptionsConfiguration is a single configuration of options within the OptionsMatrix of a CheckTest.
+//
+// For example, to verify that a Rule produces the same Annotations regardless of an option,
+// except when the option is set to a specific value:
+//
+//	checktest.CheckTest{
+//		Request: requestSpec,
+//		Spec:    spec,
+//		ExpectedAnnotations: []checktest.ExpectedAnnotation{
+//			{
+//				RuleID: "TIMESTAMP_SUFFIX",
+//			},
+//		},
+//		OptionsMatrix: []checktest.OptionsConfiguration{
+//			{
+//				Options: map[string]any{"timestamp_suffix": "_time"},
+//			},
+//			{
+//				Options: map[string]any{"timestamp_suffix": "_timestamp"},
+//			},
+//			{
+//				Options:             map[string]any{"timestamp_suffix": "_at"},
+//				ExpectedAnnotations: []checktest.ExpectedAnnotation{},
+//			},
+//		},
+//	}.Run(t)
+type OptionsConfiguration struct {
+	// Name is the name of the subtest for the OptionsConfiguration.
+	//
+	// Optional. If not set, the name is derived from the sorted keys and values of the Options,
+	// for example "key1=value1,key2=value2". Names must be unique within an OptionsMatrix.
+	Name string
+	// Options are the options to pass to the plugin.
+	//
+	// These are set on top of the Options of the RequestSpec, overriding any options with the
+	// same keys.
+	Options map[string]any
+	// ExpectedAnnotations are the expected Annotations for this OptionsConfiguration.
+	//
+	// If nil, the ExpectedAnnotations of the CheckTest are used. To expect no Annotations for
+	// this OptionsConfiguration only, set this to an empty, non-nil slice.
+	//
+	// Must not be set if WantComments is set on the CheckTest.
+	ExpectedAnnotations []ExpectedAnnotation
+}
+
+// *** PRIVATE ***
+
+func runOptionsMatrix(
+	ctx context.Context,
+	t *testing.T,
+	client check.Client,
+	c CheckTest,
+	checkAssertions *checkAssertions,
+	syntax descriptortest.Syntax,
+) {
+	require.Empty(t, c.GoldenFilePath, "GoldenFilePath cannot be set if OptionsMatrix is set")
+	require.Nil(t, c.ExpectedError, "ExpectedError cannot be set if OptionsMatrix is set")
+
+	names := make(map[string]struct{}, len(c.OptionsMatrix))
+	for _, optionsConfiguration := range c.OptionsMatrix {
+		name := optionsConfiguration.name()
+		_, ok := names[name]
+		require.False(t, ok, "duplicate OptionsConfiguration name: %q", name)
+		names[name] = struct{}{}
+		if c.WantComments {
+			require.Nil(
+				t,
+				optionsConfiguration.ExpectedAnnotations,
+				"ExpectedAnnotations cannot be set on OptionsConfiguration %q if WantComments is set",
+				name,
+			)
+		}
+	}
+
+	for _, optionsConfiguration := range c.OptionsMatrix {
+		t.Run(
+			optionsConfiguration.name(),
+			func(t *testing.T) {
+				requestSpec := *c.Request
+				requestSpec.Options = mergeOptions(c.Request.Options, optionsConfiguration.Options)
+				request, err := requestForSyntax(ctx, &requestSpec, syntax)
+				require.NoError(t, err)
+				optionsCheckAssertions := *checkAssertions
+				if optionsConfiguration.ExpectedAnnotations != nil {
+					optionsCheckAssertions.expectedAnnotations = optionsConfiguration.ExpectedAnnotations
+				}
+				runCheckAndAssert(ctx, t, client, request, &optionsCheckAssertions)
+			},
+		)
+	}
+}
+
+func (o OptionsConfiguration) name() string {
+	if o.Name != "" {
+		return o.Name
+	}
+	keys := make([]string, 0, len(o.Options))
+	for key := range o.Options {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	keyValues := make([]string, len(keys))
+	for i, key := range keys {
+		keyValues[i] = fmt.Sprintf("%s=%v", key, o.Options[key])
+	}
+	if len(keyValues) == 0 {
+		return "default"
+	}
+	return strings.Join(keyValues, ",")
+}
+
+// mergeOptions returns a new map with the overrides set on top of the base options.
+func mergeOptions(base map[string]any, overrides map[string]any) map[string]any {
+	if len(base) == 0 && len(overrides) == 0 {
+		return nil
+	}
+	merged := make(map[string]any, len(base)+len(overrides))
+	for key, value := range base {
+		merged[key] = value
+	}
+	for key, value := range overrides {
+		merged[key] = value
+	}
+	return merged
+}
@@ -0,0 +1,101 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package checktest
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"buf.build/go/bufplugin/check"
+	"github.com/stretchr/testify/require"
+)
+
+// *** PRIVATE ***
+
+// ruleCountReport records the number of Annotations produced for each Rule across all
+// Check calls within a CheckTest or CheckTests.
+//
+// Check calls may complete concurrently if CheckTests.Parallel is set.
+type ruleCountReport struct {
+	ruleIDToCount map[string]int
+	numChecks     int
+	lock          sync.Mutex
+}
+
+// newRuleCountReportForTest returns a new ruleCountReport that is logged once the test
+// and all its subtests have completed.
+//
+// All Rules of the plugin are listed in the report, including those that did not produce
+// any Annotations.
+func newRuleCountReportForTest(ctx context.Context, t *testing.T, client check.Client) *ruleCountReport {
+	rules, err := client.ListRules(ctx)
+	require.NoError(t, err)
+	report := &ruleCountReport{
+		ruleIDToCount: make(map[string]int, len(rules)),
+	}
+	for _, rule := range rules {
+		report.ruleIDToCount[rule.ID()] = 0
+	}
+	// Cleanup functions run after all parallel subtests have completed.
+	t.Cleanup(func() { t.Log(report.String()) })
+	return report
+}
+
+// add records the Annotations of a single Check call. It is a no-op on a nil ruleCountReport.
+func (r *ruleCountReport) add(annotations []check.Annotation) {
+	if r == nil {
+		return
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.numChecks++
+	for _, annotation := range annotations {
+		r.ruleIDToCount[annotation.RuleID()]++
+	}
+}
+
+func (r *ruleCountReport) String() string {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	ruleIDs := make([]string, 0, len(r.ruleIDToCount))
+	total := 0
+	ruleIDWidth := 0
+	for ruleID, count := range r.ruleIDToCount {
+		ruleIDs = append(ruleIDs, ruleID)
+		total += count
+		ruleIDWidth = max(ruleIDWidth, len(ruleID))
+	}
+	// Most Annotations first, so that both the noisiest Rules and the Rules that never
+	// fired are easy to find.
+	sort.Slice(
+		ruleIDs,
+		func(i int, j int) bool {
+			if iCount, jCount := r.ruleIDToCount[ruleIDs[i]], r.ruleIDToCount[ruleIDs[j]]; iCount != jCount {
+				return iCount > jCount
+			}
+			return ruleIDs[i] < ruleIDs[j]
+		},
+	)
+	var sb strings.Builder
+	_, _ = fmt.Fprintf(&sb, "%d Annotations across %d Check calls\n", total, r.numChecks)
+	for _, ruleID := range ruleIDs {
+		_, _ = fmt.Fprintf(&sb, "  %-*s  %d\n", ruleIDWidth, ruleID, r.ruleIDToCount[ruleID])
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
@@ -0,0 +1,135 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package checktest
+
+import (
+	"testing"
+
+	"buf.build/go/bufplugin/check"
+	"buf.build/go/bufplugin/internal/pkg/xslices"
+)
+
+const (
+	// SubtestGroupingNone verifies all Annotations in a single assertion.
+	//
+	// This is the default.
+	SubtestGroupingNone SubtestGrouping = iota
+	// SubtestGroupingRule verifies the Annotations of each Rule in a subtest named by the Rule ID.
+	SubtestGroupingRule
+	// SubtestGroupingRuleAndFile verifies the Annotations of each Rule in a subtest named by the
+	// Rule ID, and within that, the Annotations of each file in a subtest named by the file name.
+	SubtestGroupingRuleAndFile
+)
+
+// SubtestGrouping determines how the verification of Annotations is split into subtests.
+//
+// Splitting verification into subtests makes a failure report immediately identify which
+// Rule, and optionally which file, regressed. Subtests are created for every Rule and file
+// that has either expected or actual Annotations.
+type SubtestGrouping int
+
+// *** PRIVATE ***
+
+// assertAnnotationsEqualForSubtestGrouping asserts that the Annotations equal the expected Annotations,
+// splitting the assertion into subtests according to the SubtestGrouping.
+func assertAnnotationsEqualForSubtestGrouping(
+	t *testing.T,
+	expectedAnnotations []ExpectedAnnotation,
+	actualAnnotations []check.Annotation,
+	subtestGrouping SubtestGrouping,
+) {
+	switch subtestGrouping {
+	case SubtestGroupingRule:
+		runSubtestsForGroups(
+			t,
+			expectedAnnotations,
+			actualAnnotations,
+			expectedAnnotationRuleID,
+			check.Annotation.RuleID,
+			func(t *testing.T, expectedAnnotations []ExpectedAnnotation, actualAnnotations []check.Annotation) {
+				AssertAnnotationsEqual(t, expectedAnnotations, actualAnnotations)
+			},
+		)
+	case SubtestGroupingRuleAndFile:
+		runSubtestsForGroups(
+			t,
+			expectedAnnotations,
+			actualAnnotations,
+			expectedAnnotationRuleID,
+			check.Annotation.RuleID,
+			func(t *testing.T, expectedAnnotations []ExpectedAnnotation, actualAnnotations []check.Annotation) {
+				runSubtestsForGroups(
+					t,
+					expectedAnnotations,
+					actualAnnotations,
+					expectedAnnotationFileName,
+					annotationFileName,
+					func(t *testing.T, expectedAnnotations []ExpectedAnnotation, actualAnnotations []check.Annotation) {
+						AssertAnnotationsEqual(t, expectedAnnotations, actualAnnotations)
+					},
+				)
+			},
+		)
+	default:
+		AssertAnnotationsEqual(t, expectedAnnotations, actualAnnotations)
+	}
+}
+
+// runSubtestsForGroups groups the expected and actual Annotations by key, and runs
+// assertGroup in a subtest named by the key for each key, in sorted order.
+func runSubtestsForGroups(
+	t *testing.T,
+	expectedAnnotations []ExpectedAnnotation,
+	actualAnnotations []check.Annotation,
+	expectedAnnotationKey func(ExpectedAnnotation) string,
+	actualAnnotationKey func(check.Annotation) string,
+	assertGroup func(*testing.T, []ExpectedAnnotation, []check.Annotation),
+) {
+	keys := make(map[string]struct{})
+	keyToExpectedAnnotations := make(map[string][]ExpectedAnnotation)
+	for _, expectedAnnotation := range expectedAnnotations {
+		key := expectedAnnotationKey(expectedAnnotation)
+		keys[key] = struct{}{}
+		keyToExpectedAnnotations[key] = append(keyToExpectedAnnotations[key], expectedAnnotation)
+	}
+	keyToActualAnnotations := make(map[string][]check.Annotation)
+	for _, actualAnnotation := range actualAnnotations {
+		key := actualAnnotationKey(actualAnnotation)
+		keys[key] = struct{}{}
+		keyToActualAnnotations[key] = append(keyToActualAnnotations[key], actualAnnotation)
+	}
+	for _, key := range xslices.MapKeysToSortedSlice(keys) {
+		t.Run(
+			key,
+			func(t *testing.T) {
+				assertGroup(t, keyToExpectedAnnotations[key], keyToActualAnnotations[key])
+			},
+		)
+	}
+}
+
+func expectedAnnotationRuleID(expectedAnnotation ExpectedAnnotation) string {
+	return expectedAnnotation.RuleID
+}
+
+func annotationFileName(annotation check.Annotation) string {
+	if fileLocation := annotation.FileLocation(); fileLocation != nil {
+		return fileLocation.FileDescriptor().ProtoreflectFileDescriptor().Path()
+	}
+	if againstFileLocation := annotation.AgainstFileLocation(); againstFileLocation != nil {
+		return againstFileLocation.FileDescriptor().ProtoreflectFileDescriptor().Path()
+	}
+	return noFileName
+}
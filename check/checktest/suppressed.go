@@ -0,0 +1,158 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package checktest
+
+import (
+	"context"
+	"testing"
+
+	"buf.build/go/bufplugin/check"
+	"buf.build/go/bufplugin/internal/pkg/xslices"
+	"buf.build/go/bufplugin/option"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	// SuppressionReasonAny does not verify why an Annotation was suppressed.
+	//
+	// This is the default.
+	SuppressionReasonAny SuppressionReason = iota
+	// SuppressionReasonVendoredPath verifies that an Annotation was suppressed because its file
+	// is within a vendored path, set with check.Spec.VendoredPaths or, if
+	// check.Spec.VendoredOptions is set, the check.VendoredPathsOptionKey option.
+	SuppressionReasonVendoredPath
+	// SuppressionReasonVendoredPackage verifies that an Annotation was suppressed because its file
+	// is within a vendored package, set with check.Spec.VendoredPackages or, if
+	// check.Spec.VendoredOptions is set, the check.VendoredPackagesOptionKey option, and not
+	// within a vendored path.
+	SuppressionReasonVendoredPackage
+)
+
+// SuppressionReason is the reason an Annotation was suppressed.
+type SuppressionReason int
+
+// ExpectedSuppressedAnnotation is an Annotation that is expected to be produced by a Rule,
+// but suppressed, and therefore not returned in the Response.
+type ExpectedSuppressedAnnotation struct {
+	// Annotation is the Annotation that is expected to be suppressed.
+	//
+	// Annotation is matched the same way as a forbidden Annotation in AssertAnnotationsAbsent,
+	// that is a nil FileLocation or AgainstFileLocation matches any location.
+	Annotation ExpectedAnnotation
+	// Reason is the reason the Annotation is expected to be suppressed.
+	//
+	// If vendored paths and vendored packages both match the file of an Annotation, the
+	// reason is SuppressionReasonVendoredPath.
+	Reason SuppressionReason
+}
+
+// *** PRIVATE ***
+
+// assertAnnotationsSuppressed asserts that the expected suppressed Annotations are produced by the
+// Rules of the Spec, but suppressed.
+//
+// This runs the Check again with vendoring disabled, and again with only vendored paths, and
+// compares the results with the actual Annotations.
+func assertAnnotationsSuppressed(
+	ctx context.Context,
+	t *testing.T,
+	spec *check.Spec,
+	request check.Request,
+	expectedSuppressedAnnotations []ExpectedSuppressedAnnotation,
+	actualAnnotations []check.Annotation,
+) {
+	require.NotNil(t, spec, "SuppressedAnnotations can only be verified with a Spec")
+	forbiddenAnnotations := xslices.Map(
+		expectedSuppressedAnnotations,
+		func(expectedSuppressedAnnotation ExpectedSuppressedAnnotation) ExpectedAnnotation {
+			return expectedSuppressedAnnotation.Annotation
+		},
+	)
+	require.NoError(t, validateExpectedAnnotations(forbiddenAnnotations))
+	AssertAnnotationsAbsent(t, forbiddenAnnotations, actualAnnotations)
+
+	unsuppressedAnnotations := checkWithVendored(ctx, t, spec, request, false)
+	vendoredPathsOnlyAnnotations := checkWithVendored(ctx, t, spec, request, true)
+	for _, expectedSuppressedAnnotation := range expectedSuppressedAnnotations {
+		numUnsuppressed := numMatchingAnnotations(expectedSuppressedAnnotation.Annotation, unsuppressedAnnotations)
+		if numUnsuppressed == 0 {
+			assert.Fail(t, "expected suppressed annotation was not produced by the Rules: "+expectedSuppressedAnnotation.Annotation.String())
+			continue
+		}
+		numVendoredPathsOnly := numMatchingAnnotations(expectedSuppressedAnnotation.Annotation, vendoredPathsOnlyAnnotations)
+		switch expectedSuppressedAnnotation.Reason {
+		case SuppressionReasonVendoredPath:
+			if numVendoredPathsOnly != 0 {
+				assert.Fail(t, "expected suppressed annotation was not suppressed by a vendored path: "+expectedSuppressedAnnotation.Annotation.String())
+			}
+		case SuppressionReasonVendoredPackage:
+			if numVendoredPathsOnly != numUnsuppressed {
+				assert.Fail(t, "expected suppressed annotation was suppressed by a vendored path, not a vendored package: "+expectedSuppressedAnnotation.Annotation.String())
+			}
+		}
+	}
+}
+
+// checkWithVendored runs a Check for the Spec and Request with vendored packages disabled, and
+// vendored paths disabled unless keepVendoredPaths is true.
+func checkWithVendored(
+	ctx context.Context,
+	t *testing.T,
+	spec *check.Spec,
+	request check.Request,
+	keepVendoredPaths bool,
+) []ExpectedAnnotation {
+	specCopy := *spec
+	specCopy.VendoredPackages = nil
+	removeOptionKeys := []string{check.VendoredPackagesOptionKey}
+	if !keepVendoredPaths {
+		specCopy.VendoredPaths = nil
+		removeOptionKeys = append(removeOptionKeys, check.VendoredPathsOptionKey)
+	}
+	keyToValue := make(map[string]any)
+	request.Options().Range(
+		func(key string, value any) {
+			keyToValue[key] = value
+		},
+	)
+	for _, removeOptionKey := range removeOptionKeys {
+		delete(keyToValue, removeOptionKey)
+	}
+	options, err := option.NewOptions(keyToValue)
+	require.NoError(t, err)
+	request, err = check.NewRequest(
+		request.FileDescriptors(),
+		check.WithAgainstFileDescriptors(request.AgainstFileDescriptors()),
+		check.WithOptions(options),
+		check.WithRuleIDs(request.RuleIDs()...),
+	)
+	require.NoError(t, err)
+	client, err := check.NewClientForSpec(&specCopy)
+	require.NoError(t, err)
+	response, err := client.Check(ctx, request)
+	require.NoError(t, err)
+	return expectedAnnotationsForAnnotations(response.Annotations())
+}
+
+func numMatchingAnnotations(expectedAnnotation ExpectedAnnotation, actualExpectedAnnotations []ExpectedAnnotation) int {
+	var numMatching int
+	for _, actualExpectedAnnotation := range actualExpectedAnnotations {
+		if forbiddenAnnotationMatches(expectedAnnotation, actualExpectedAnnotation) {
+			numMatching++
+		}
+	}
+	return numMatching
+}
@@ -0,0 +1,185 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package checktest
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"buf.build/go/bufplugin/check"
+	"buf.build/go/bufplugin/descriptor"
+)
+
+// *** PRIVATE ***
+
+const wantCommentPrefix = "want "
+
+// wantDirective is a single expectation parsed from a want comment.
+type wantDirective struct {
+	fileName string
+	// line is zero-indexed.
+	line            int
+	ruleID          string
+	messageContains string
+}
+
+// expectedAnnotationsForWantComments returns the ExpectedAnnotations described by the want
+// comments within the non-import FileDescriptors.
+//
+// A want comment is a trailing comment of the form:
+//
+//	int32 PascalCase = 2; // want FIELD_LOWER_SNAKE_CASE: should be lower_snake_case
+//
+// The message after the colon is optional, and if present, must be contained in the message
+// of the Annotation. Multiple directives can be separated by semicolons:
+//
+//	int32 PascalCase = 2; // want FIELD_LOWER_SNAKE_CASE; want FIELD_NO_PASCAL_CASE
+//
+// A directive matches an Annotation of the Rule whose FileLocation starts on the line of the
+// element the comment is attached to. Want comments only specify a line, so if a matching
+// Annotation is found, its full locations are used for the ExpectedAnnotation, and otherwise
+// the ExpectedAnnotation has the line as its location with zero columns, and will be reported
+// as missing.
+func expectedAnnotationsForWantComments(
+	fileDescriptors []descriptor.FileDescriptor,
+	actualAnnotations []check.Annotation,
+) ([]ExpectedAnnotation, error) {
+	wantDirectives, err := wantDirectivesForFileDescriptors(fileDescriptors)
+	if err != nil {
+		return nil, err
+	}
+	actualExpectedAnnotations := expectedAnnotationsForAnnotations(actualAnnotations)
+	used := make([]bool, len(actualExpectedAnnotations))
+	// The ExpectedAnnotations matched to an Annotation are placed at the index of the Annotation,
+	// so that they are in the same order as the Annotations. The order of want comments within
+	// the files does not determine the order of the Annotations.
+	matchedExpectedAnnotations := make([]*ExpectedAnnotation, len(actualExpectedAnnotations))
+	var unmatchedExpectedAnnotations []ExpectedAnnotation
+	for _, wantDirective := range wantDirectives {
+		expectedAnnotation := ExpectedAnnotation{
+			RuleID:          wantDirective.ruleID,
+			MessageContains: wantDirective.messageContains,
+			FileLocation: &ExpectedFileLocation{
+				FileName:  wantDirective.fileName,
+				StartLine: wantDirective.line,
+				EndLine:   wantDirective.line,
+			},
+		}
+		matched := false
+		for i, actualExpectedAnnotation := range actualExpectedAnnotations {
+			if !used[i] && wantDirectiveMatches(wantDirective, actualExpectedAnnotation) {
+				used[i] = true
+				matched = true
+				expectedAnnotation.FileLocation = actualExpectedAnnotation.FileLocation
+				expectedAnnotation.AgainstFileLocation = actualExpectedAnnotation.AgainstFileLocation
+				matchedExpectedAnnotations[i] = &expectedAnnotation
+				break
+			}
+		}
+		if !matched {
+			unmatchedExpectedAnnotations = append(unmatchedExpectedAnnotations, expectedAnnotation)
+		}
+	}
+	expectedAnnotations := make([]ExpectedAnnotation, 0, len(wantDirectives))
+	for _, matchedExpectedAnnotation := range matchedExpectedAnnotations {
+		if matchedExpectedAnnotation != nil {
+			expectedAnnotations = append(expectedAnnotations, *matchedExpectedAnnotation)
+		}
+	}
+	return append(expectedAnnotations, unmatchedExpectedAnnotations...), nil
+}
+
+func wantDirectivesForFileDescriptors(fileDescriptors []descriptor.FileDescriptor) ([]wantDirective, error) {
+	var wantDirectives []wantDirective
+	for _, fileDescriptor := range fileDescriptors {
+		if fileDescriptor.IsImport() {
+			continue
+		}
+		fileDescriptorProto := fileDescriptor.FileDescriptorProto()
+		fileName := fileDescriptorProto.GetName()
+		// The same comment is never attached to more than one location, but be defensive
+		// against duplicate locations.
+		seen := make(map[string]struct{})
+		for _, location := range fileDescriptorProto.GetSourceCodeInfo().GetLocation() {
+			trailingComments := strings.TrimSpace(location.GetTrailingComments())
+			span := location.GetSpan()
+			if trailingComments == "" || len(span) == 0 {
+				continue
+			}
+			key := fmt.Sprintf("%d:%s", span[0], trailingComments)
+			if _, ok := seen[key]; ok {
+				continue
+			}
+			seen[key] = struct{}{}
+			lineWantDirectives, err := parseWantComment(fileName, int(span[0]), trailingComments)
+			if err != nil {
+				return nil, err
+			}
+			wantDirectives = append(wantDirectives, lineWantDirectives...)
+		}
+	}
+	sort.SliceStable(
+		wantDirectives,
+		func(i int, j int) bool {
+			if wantDirectives[i].fileName != wantDirectives[j].fileName {
+				return wantDirectives[i].fileName < wantDirectives[j].fileName
+			}
+			return wantDirectives[i].line < wantDirectives[j].line
+		},
+	)
+	return wantDirectives, nil
+}
+
+// parseWantComment parses the directives in the comment.
+//
+// Returns no directives if the comment is not a want comment.
+func parseWantComment(fileName string, line int, comment string) ([]wantDirective, error) {
+	if !strings.HasPrefix(comment, wantCommentPrefix) {
+		return nil, nil
+	}
+	var wantDirectives []wantDirective
+	for _, directive := range strings.Split(comment, ";") {
+		directive = strings.TrimSpace(directive)
+		directive, ok := strings.CutPrefix(directive, wantCommentPrefix)
+		if !ok {
+			return nil, fmt.Errorf("%s:%d: invalid want comment %q: every directive must start with %q", fileName, line+1, comment, wantCommentPrefix)
+		}
+		ruleID, messageContains, _ := strings.Cut(directive, ":")
+		ruleID = strings.TrimSpace(ruleID)
+		if ruleID == "" {
+			return nil, fmt.Errorf("%s:%d: invalid want comment %q: no rule ID", fileName, line+1, comment)
+		}
+		wantDirectives = append(
+			wantDirectives,
+			wantDirective{
+				fileName:        fileName,
+				line:            line,
+				ruleID:          ruleID,
+				messageContains: strings.TrimSpace(messageContains),
+			},
+		)
+	}
+	return wantDirectives, nil
+}
+
+func wantDirectiveMatches(wantDirective wantDirective, actualExpectedAnnotation ExpectedAnnotation) bool {
+	fileLocation := actualExpectedAnnotation.FileLocation
+	return wantDirective.ruleID == actualExpectedAnnotation.RuleID &&
+		fileLocation != nil &&
+		fileLocation.FileName == wantDirective.fileName &&
+		fileLocation.StartLine == wantDirective.line &&
+		strings.Contains(actualExpectedAnnotation.Message, wantDirective.messageContains)
+}
@@ -0,0 +1,134 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package checktest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"buf.build/go/bufplugin/check"
+	"pluginrpc.com/pluginrpc"
+)
+
+// *** PRIVATE ***
+
+// newWireClientForSpec returns a new Client for the Spec that calls the Server over the wire.
+//
+// See CheckTest.Wire.
+func newWireClientForSpec(spec *check.Spec, clientOptions []check.ClientOption) (check.Client, error) {
+	if spec == nil {
+		return nil, errors.New("a Spec must be set if Wire is set")
+	}
+	server, err := check.NewServer(spec)
+	if err != nil {
+		return nil, err
+	}
+	return check.NewClient(
+		pluginrpc.NewClient(newPipeRunner(server)),
+		append([]check.ClientOption{check.ClientWithCaching()}, clientOptions...)...,
+	), nil
+}
+
+// pipeRunner is a pluginrpc.Runner that serves each invocation as if the Server was
+// running in a separate process invoked with pluginrpc.Main.
+//
+// The stdin, stdout, and stderr of the Server are OS pipes, so that requests and responses
+// are framed the same way as with an exec'd plugin. The Server is run with a new context
+// that does not carry the values of the context of the caller, and errors are converted
+// to the exit codes and stderr output that pluginrpc.Main would produce.
+type pipeRunner struct {
+	server pluginrpc.Server
+}
+
+func newPipeRunner(server pluginrpc.Server) *pipeRunner {
+	return &pipeRunner{
+		server: server,
+	}
+}
+
+func (p *pipeRunner) Run(ctx context.Context, env pluginrpc.Env) error {
+	stdinReader, stdinWriter, err := os.Pipe()
+	if err != nil {
+		return err
+	}
+	stdoutReader, stdoutWriter, err := os.Pipe()
+	if err != nil {
+		return errors.Join(err, stdinReader.Close(), stdinWriter.Close())
+	}
+	stderrReader, stderrWriter, err := os.Pipe()
+	if err != nil {
+		return errors.Join(err, stdinReader.Close(), stdinWriter.Close(), stdoutReader.Close(), stdoutWriter.Close())
+	}
+
+	var waitGroup sync.WaitGroup
+	var copyErrs []error
+	var copyErrsLock sync.Mutex
+	copyAndClose := func(writer io.Writer, reader io.ReadCloser) {
+		defer waitGroup.Done()
+		if writer == nil {
+			writer = io.Discard
+		}
+		_, err := io.Copy(writer, reader)
+		err = errors.Join(err, reader.Close())
+		if err != nil {
+			copyErrsLock.Lock()
+			copyErrs = append(copyErrs, err)
+			copyErrsLock.Unlock()
+		}
+	}
+	waitGroup.Add(3)
+	// Closing the write side of stdin signals EOF to the Server. The Server may not read all of
+	// stdin, in which case the copy fails once the read side is closed, as it would for a process.
+	go func() {
+		defer waitGroup.Done()
+		if env.Stdin != nil {
+			_, _ = io.Copy(stdinWriter, env.Stdin)
+		}
+		_ = stdinWriter.Close()
+	}()
+	go copyAndClose(env.Stdout, stdoutReader)
+	go copyAndClose(env.Stderr, stderrReader)
+
+	serveCtx, cancel := context.WithCancel(context.Background())
+	stop := context.AfterFunc(ctx, cancel)
+	serveErr := p.server.Serve(
+		serveCtx,
+		pluginrpc.Env{
+			Args:   env.Args,
+			Stdin:  stdinReader,
+			Stdout: stdoutWriter,
+			Stderr: stderrWriter,
+		},
+	)
+	stop()
+	cancel()
+	if serveErr != nil {
+		// This is what pluginrpc.Main does with errors returned from Serve.
+		if errString := serveErr.Error(); errString != "" {
+			_, _ = stderrWriter.Write([]byte(errString + "\n"))
+		}
+	}
+	closeErr := errors.Join(stdinReader.Close(), stdoutWriter.Close(), stderrWriter.Close())
+	waitGroup.Wait()
+	if serveErr != nil {
+		exitCode := pluginrpc.WrapExitError(serveErr).ExitCode()
+		return pluginrpc.NewExitError(exitCode, fmt.Errorf("exit status %d", exitCode))
+	}
+	return errors.Join(append([]error{closeErr}, copyErrs...)...)
+}
@@ -38,16 +38,13 @@ func NewFilePairRuleHandler(
 	) error,
 	options ...IteratorOption,
 ) check.RuleHandler {
-	iteratorOptions := newIteratorOptions()
-	for _, option := range options {
-		option(iteratorOptions)
-	}
 	return check.RuleHandlerFunc(
 		func(
 			ctx context.Context,
 			responseWriter check.ResponseWriter,
 			request check.Request,
 		) error {
+			iteratorOptions := newIteratorOptionsForContext(ctx, options)
 			fileDescriptors := filterFileDescriptors(request.FileDescriptors(), iteratorOptions.withoutImports)
 			againstFileDescriptors := filterFileDescriptors(request.AgainstFileDescriptors(), iteratorOptions.withoutImports)
 			pathToFileDescriptor, err := getPathToFileDescriptor(fileDescriptors)
@@ -86,16 +83,13 @@ func NewEnumPairRuleHandler(
 	) error,
 	options ...IteratorOption,
 ) check.RuleHandler {
-	iteratorOptions := newIteratorOptions()
-	for _, option := range options {
-		option(iteratorOptions)
-	}
 	return check.RuleHandlerFunc(
 		func(
 			ctx context.Context,
 			responseWriter check.ResponseWriter,
 			request check.Request,
 		) error {
+			iteratorOptions := newIteratorOptionsForContext(ctx, options)
 			fileDescriptors := filterFileDescriptors(request.FileDescriptors(), iteratorOptions.withoutImports)
 			againstFileDescriptors := filterFileDescriptors(request.AgainstFileDescriptors(), iteratorOptions.withoutImports)
 			fullNameToEnumDescriptor, err := getFullNameToEnumDescriptor(fileDescriptors)
@@ -134,16 +128,13 @@ func NewMessagePairRuleHandler(
 	) error,
 	options ...IteratorOption,
 ) check.RuleHandler {
-	iteratorOptions := newIteratorOptions()
-	for _, option := range options {
-		option(iteratorOptions)
-	}
 	return check.RuleHandlerFunc(
 		func(
 			ctx context.Context,
 			responseWriter check.ResponseWriter,
 			request check.Request,
 		) error {
+			iteratorOptions := newIteratorOptionsForContext(ctx, options)
 			fileDescriptors := filterFileDescriptors(request.FileDescriptors(), iteratorOptions.withoutImports)
 			againstFileDescriptors := filterFileDescriptors(request.AgainstFileDescriptors(), iteratorOptions.withoutImports)
 			fullNameToMessageDescriptor, err := getFullNameToMessageDescriptor(fileDescriptors)
@@ -185,16 +176,13 @@ func NewFieldPairRuleHandler(
 	) error,
 	options ...IteratorOption,
 ) check.RuleHandler {
-	iteratorOptions := newIteratorOptions()
-	for _, option := range options {
-		option(iteratorOptions)
-	}
 	return check.RuleHandlerFunc(
 		func(
 			ctx context.Context,
 			responseWriter check.ResponseWriter,
 			request check.Request,
 		) error {
+			iteratorOptions := newIteratorOptionsForContext(ctx, options)
 			fileDescriptors := filterFileDescriptors(request.FileDescriptors(), iteratorOptions.withoutImports)
 			againstFileDescriptors := filterFileDescriptors(request.AgainstFileDescriptors(), iteratorOptions.withoutImports)
 			containingMessageFullNameToNumberToFieldDescriptor, err := getContainingMessageFullNameToNumberToFieldDescriptor(fileDescriptors)
@@ -237,16 +225,13 @@ func NewServicePairRuleHandler(
 	) error,
 	options ...IteratorOption,
 ) check.RuleHandler {
-	iteratorOptions := newIteratorOptions()
-	for _, option := range options {
-		option(iteratorOptions)
-	}
 	return check.RuleHandlerFunc(
 		func(
 			ctx context.Context,
 			responseWriter check.ResponseWriter,
 			request check.Request,
 		) error {
+			iteratorOptions := newIteratorOptionsForContext(ctx, options)
 			fileDescriptors := filterFileDescriptors(request.FileDescriptors(), iteratorOptions.withoutImports)
 			againstFileDescriptors := filterFileDescriptors(request.AgainstFileDescriptors(), iteratorOptions.withoutImports)
 			fullNameToServiceDescriptor, err := getFullNameToServiceDescriptor(fileDescriptors)
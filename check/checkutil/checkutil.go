@@ -15,6 +15,13 @@
 // Package checkutil implements helpers for the check package.
 package checkutil
 
+import (
+	"context"
+	"slices"
+
+	"buf.build/go/bufplugin/check"
+)
+
 // IteratorOption is an option for any of the New.*RuleHandler functions in this package.
 type IteratorOption func(*iteratorOptions)
 
@@ -31,12 +38,97 @@ func WithoutImports() IteratorOption {
 	}
 }
 
+// WithImports returns a new IteratorOption that will call the provided function
+// for imports.
+//
+// This is the default, and is only needed to override a default WithoutImports()
+// set with ContextWithDefaultIteratorOptions, typically for breaking RuleHandlers.
+func WithImports() IteratorOption {
+	return func(iteratorOptions *iteratorOptions) {
+		iteratorOptions.withoutImports = false
+	}
+}
+
+// WithoutSyntheticOneofs returns a new IteratorOption that will not call the provided function
+// for synthetic oneofs, or fields within synthetic oneofs.
+//
+// Synthetic oneofs are created by the compiler for proto3 optional fields, and are almost never
+// something that a Rule wants to consider. For NewOneofRuleHandler, synthetic oneofs will be
+// skipped. For NewFieldRuleHandler, fields within a synthetic oneof (i.e. proto3 optional fields)
+// will be skipped. This option has no effect on other RuleHandlers.
+//
+// The default is to call the provided function for synthetic oneofs and their fields.
+func WithoutSyntheticOneofs() IteratorOption {
+	return func(iteratorOptions *iteratorOptions) {
+		iteratorOptions.withoutSyntheticOneofs = true
+	}
+}
+
+// ContextWithDefaultIteratorOptions returns a new context that contains default IteratorOptions
+// for any of the New.*RuleHandler functions in this package.
+//
+// When a RuleHandler is invoked with a context derived from the returned context, the default
+// IteratorOptions are applied first, and then the IteratorOptions passed to the New.*RuleHandler
+// function are applied, so that individual RuleHandlers can override the defaults. If the context
+// already has default IteratorOptions, the given IteratorOptions are applied after them.
+//
+// This is typically called within a check.Spec's Before function, so that all Rules within a
+// plugin share the same defaults. See DefaultIteratorOptionsBefore.
+func ContextWithDefaultIteratorOptions(ctx context.Context, options ...IteratorOption) context.Context {
+	return context.WithValue(
+		ctx,
+		defaultIteratorOptionsContextKey{},
+		append(slices.Clone(defaultIteratorOptionsForContext(ctx)), options...),
+	)
+}
+
+// DefaultIteratorOptionsBefore returns a new function suitable for a check.Spec's Before function that
+// sets the default IteratorOptions for all RuleHandlers within the plugin.
+//
+// Most lint plugins will want to use this with WithoutImports():
+//
+//	var spec = &check.Spec{
+//		Rules: ...,
+//		Before: checkutil.DefaultIteratorOptionsBefore(checkutil.WithoutImports()),
+//	}
+//
+// Individual RuleHandlers can override the defaults, for example with WithImports().
+// See ContextWithDefaultIteratorOptions for more details.
+func DefaultIteratorOptionsBefore(
+	options ...IteratorOption,
+) func(context.Context, check.Request) (context.Context, check.Request, error) {
+	return func(ctx context.Context, request check.Request) (context.Context, check.Request, error) {
+		return ContextWithDefaultIteratorOptions(ctx, options...), request, nil
+	}
+}
+
 // *** PRIVATE ***
 
+type defaultIteratorOptionsContextKey struct{}
+
 type iteratorOptions struct {
-	withoutImports bool
+	withoutImports         bool
+	withoutSyntheticOneofs bool
 }
 
 func newIteratorOptions() *iteratorOptions {
 	return &iteratorOptions{}
 }
+
+// newIteratorOptionsForContext returns a new iteratorOptions with the default IteratorOptions
+// for the context applied, and then the given IteratorOptions applied.
+func newIteratorOptionsForContext(ctx context.Context, options []IteratorOption) *iteratorOptions {
+	iteratorOptions := newIteratorOptions()
+	for _, option := range defaultIteratorOptionsForContext(ctx) {
+		option(iteratorOptions)
+	}
+	for _, option := range options {
+		option(iteratorOptions)
+	}
+	return iteratorOptions
+}
+
+func defaultIteratorOptionsForContext(ctx context.Context) []IteratorOption {
+	options, _ := ctx.Value(defaultIteratorOptionsContextKey{}).([]IteratorOption)
+	return options
+}
@@ -0,0 +1,234 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package checkutil
+
+import (
+	"context"
+	"testing"
+
+	"buf.build/go/bufplugin/check"
+	"buf.build/go/bufplugin/descriptor"
+	"buf.build/go/bufplugin/descriptor/descriptortest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+func TestWithoutSyntheticOneofs(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name                 string
+		options              []IteratorOption
+		expectedOneofNames   []string
+		expectedFieldNames   []string
+		expectedMessageNames []string
+	}{
+		{
+			name:                 "default",
+			expectedOneofNames:   []string{"a.Foo.choice", "b.Bar.choice", "b.Bar._optional_name"},
+			expectedFieldNames:   []string{"a.Foo.name", "b.Bar.name", "b.Bar.choice_name", "b.Bar.optional_name"},
+			expectedMessageNames: []string{"a.Foo", "b.Bar"},
+		},
+		{
+			name:                 "without_synthetic_oneofs",
+			options:              []IteratorOption{WithoutSyntheticOneofs()},
+			expectedOneofNames:   []string{"a.Foo.choice", "b.Bar.choice"},
+			expectedFieldNames:   []string{"a.Foo.name", "b.Bar.name", "b.Bar.choice_name"},
+			expectedMessageNames: []string{"a.Foo", "b.Bar"},
+		},
+		{
+			name:                 "without_synthetic_oneofs_and_imports",
+			options:              []IteratorOption{WithoutSyntheticOneofs(), WithoutImports()},
+			expectedOneofNames:   []string{"b.Bar.choice"},
+			expectedFieldNames:   []string{"b.Bar.name", "b.Bar.choice_name"},
+			expectedMessageNames: []string{"b.Bar"},
+		},
+	}
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+			oneofNames, fieldNames, messageNames := testIterate(context.Background(), t, testCase.options...)
+			assert.Equal(t, testCase.expectedOneofNames, oneofNames)
+			assert.Equal(t, testCase.expectedFieldNames, fieldNames)
+			assert.Equal(t, testCase.expectedMessageNames, messageNames)
+		})
+	}
+}
+
+func TestContextWithDefaultIteratorOptions(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	withoutImportsCtx := ContextWithDefaultIteratorOptions(ctx, WithoutImports())
+	testCases := []struct {
+		name                 string
+		ctx                  context.Context
+		options              []IteratorOption
+		expectedOneofNames   []string
+		expectedFieldNames   []string
+		expectedMessageNames []string
+	}{
+		{
+			name:                 "no_defaults",
+			ctx:                  ctx,
+			expectedOneofNames:   []string{"a.Foo.choice", "b.Bar.choice", "b.Bar._optional_name"},
+			expectedFieldNames:   []string{"a.Foo.name", "b.Bar.name", "b.Bar.choice_name", "b.Bar.optional_name"},
+			expectedMessageNames: []string{"a.Foo", "b.Bar"},
+		},
+		{
+			name:                 "defaults",
+			ctx:                  withoutImportsCtx,
+			expectedOneofNames:   []string{"b.Bar.choice", "b.Bar._optional_name"},
+			expectedFieldNames:   []string{"b.Bar.name", "b.Bar.choice_name", "b.Bar.optional_name"},
+			expectedMessageNames: []string{"b.Bar"},
+		},
+		{
+			name:                 "defaults_with_options",
+			ctx:                  withoutImportsCtx,
+			options:              []IteratorOption{WithoutSyntheticOneofs()},
+			expectedOneofNames:   []string{"b.Bar.choice"},
+			expectedFieldNames:   []string{"b.Bar.name", "b.Bar.choice_name"},
+			expectedMessageNames: []string{"b.Bar"},
+		},
+		{
+			// Options passed to the RuleHandler override the defaults.
+			name:                 "options_override_defaults",
+			ctx:                  withoutImportsCtx,
+			options:              []IteratorOption{WithImports()},
+			expectedOneofNames:   []string{"a.Foo.choice", "b.Bar.choice", "b.Bar._optional_name"},
+			expectedFieldNames:   []string{"a.Foo.name", "b.Bar.name", "b.Bar.choice_name", "b.Bar.optional_name"},
+			expectedMessageNames: []string{"a.Foo", "b.Bar"},
+		},
+		{
+			name:                 "nested_defaults_are_applied_after_parent_defaults",
+			ctx:                  ContextWithDefaultIteratorOptions(withoutImportsCtx, WithoutSyntheticOneofs()),
+			expectedOneofNames:   []string{"b.Bar.choice"},
+			expectedFieldNames:   []string{"b.Bar.name", "b.Bar.choice_name"},
+			expectedMessageNames: []string{"b.Bar"},
+		},
+		{
+			name:                 "nested_defaults_override_parent_defaults",
+			ctx:                  ContextWithDefaultIteratorOptions(withoutImportsCtx, WithImports()),
+			expectedOneofNames:   []string{"a.Foo.choice", "b.Bar.choice", "b.Bar._optional_name"},
+			expectedFieldNames:   []string{"a.Foo.name", "b.Bar.name", "b.Bar.choice_name", "b.Bar.optional_name"},
+			expectedMessageNames: []string{"a.Foo", "b.Bar"},
+		},
+	}
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+			oneofNames, fieldNames, messageNames := testIterate(testCase.ctx, t, testCase.options...)
+			assert.Equal(t, testCase.expectedOneofNames, oneofNames)
+			assert.Equal(t, testCase.expectedFieldNames, fieldNames)
+			assert.Equal(t, testCase.expectedMessageNames, messageNames)
+		})
+	}
+}
+
+func TestDefaultIteratorOptionsBefore(t *testing.T) {
+	t.Parallel()
+
+	request := testNewIteratorRequest(t)
+	before := DefaultIteratorOptionsBefore(WithoutImports(), WithoutSyntheticOneofs())
+	ctx, beforeRequest, err := before(context.Background(), request)
+	require.NoError(t, err)
+	assert.Equal(t, request, beforeRequest)
+	oneofNames, fieldNames, messageNames := testIterate(ctx, t)
+	assert.Equal(t, []string{"b.Bar.choice"}, oneofNames)
+	assert.Equal(t, []string{"b.Bar.name", "b.Bar.choice_name"}, fieldNames)
+	assert.Equal(t, []string{"b.Bar"}, messageNames)
+	oneofNames, _, _ = testIterate(ctx, t, WithImports())
+	assert.Equal(t, []string{"a.Foo.choice", "b.Bar.choice"}, oneofNames)
+}
+
+// testIterate runs NewOneofRuleHandler, NewFieldRuleHandler, and NewMessageRuleHandler with the
+// options over the files from testNewIteratorRequest, and returns the full names of the
+// oneofs, fields, and messages that each RuleHandler was called for.
+func testIterate(ctx context.Context, t *testing.T, options ...IteratorOption) ([]string, []string, []string) {
+	request := testNewIteratorRequest(t)
+	var oneofNames []string
+	require.NoError(
+		t,
+		NewOneofRuleHandler(
+			func(_ context.Context, _ check.ResponseWriter, _ check.Request, oneofDescriptor protoreflect.OneofDescriptor) error {
+				oneofNames = append(oneofNames, string(oneofDescriptor.FullName()))
+				return nil
+			},
+			options...,
+		).Handle(ctx, nil, request),
+	)
+	var fieldNames []string
+	require.NoError(
+		t,
+		NewFieldRuleHandler(
+			func(_ context.Context, _ check.ResponseWriter, _ check.Request, fieldDescriptor protoreflect.FieldDescriptor) error {
+				fieldNames = append(fieldNames, string(fieldDescriptor.FullName()))
+				return nil
+			},
+			options...,
+		).Handle(ctx, nil, request),
+	)
+	var messageNames []string
+	require.NoError(
+		t,
+		NewMessageRuleHandler(
+			func(_ context.Context, _ check.ResponseWriter, _ check.Request, messageDescriptor protoreflect.MessageDescriptor) error {
+				messageNames = append(messageNames, string(messageDescriptor.FullName()))
+				return nil
+			},
+			options...,
+		).Handle(ctx, nil, request),
+	)
+	return oneofNames, fieldNames, messageNames
+}
+
+// testNewIteratorRequest returns a new Request where a.proto is an import, and b.proto is not.
+func testNewIteratorRequest(t *testing.T) check.Request {
+	fileDescriptors, err := descriptortest.CompileStrings(
+		context.Background(),
+		map[string]string{
+			"a.proto": `syntax = "proto3";
+package a;
+message Foo {
+  oneof choice {
+    string name = 1;
+  }
+}
+`,
+			"b.proto": `syntax = "proto3";
+package b;
+message Bar {
+  string name = 1;
+  oneof choice {
+    string choice_name = 2;
+  }
+  optional string optional_name = 3;
+}
+`,
+		},
+	)
+	require.NoError(t, err)
+	fileDescriptors, err = descriptor.FileDescriptorsForFileDescriptorSet(
+		descriptor.FileDescriptorSetForFileDescriptors(fileDescriptors),
+		descriptor.FileDescriptorSetWithImportFilePaths("a.proto"),
+	)
+	require.NoError(t, err)
+	request, err := check.NewRequest(fileDescriptors)
+	require.NoError(t, err)
+	return request
+}
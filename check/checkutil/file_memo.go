@@ -0,0 +1,128 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package checkutil
+
+import (
+	"context"
+	"sync"
+
+	"buf.build/go/bufplugin/check"
+	"buf.build/go/bufplugin/internal/pkg/cache"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// FileMemo computes a value at most once per file within a single check.Request.
+//
+// This is used when a Rule needs an expensive per-file artifact, such as an index of all names
+// within a file, from within per-element callbacks. Without a FileMemo, the artifact would
+// be recomputed for every element.
+//
+// A FileMemo is constructed once with NewFileMemo. The RuleHandler for the Rule is wrapped
+// with Wrap, and the per-element callbacks call Get with the context they were given:
+//
+//	var nameIndex = checkutil.NewFileMemo(newNameIndex)
+//
+//	var ruleSpec = &check.RuleSpec{
+//		...
+//		Handler: nameIndex.Wrap(
+//			checkutil.NewFieldRuleHandler(
+//				func(ctx context.Context, responseWriter check.ResponseWriter, request check.Request, fieldDescriptor protoreflect.FieldDescriptor) error {
+//					index, err := nameIndex.Get(ctx, fieldDescriptor.ParentFile())
+//					...
+//				},
+//			),
+//		),
+//	}
+//
+// Values are keyed by file. FileDescriptors and AgainstFileDescriptors with the same path
+// are different files, and will have separate values. Storage is safe for concurrent use.
+type FileMemo[V any] struct {
+	compute func(context.Context, protoreflect.FileDescriptor) (V, error)
+}
+
+// NewFileMemo returns a new FileMemo that will use compute to compute the value for a file.
+//
+// compute must only return the zero value of V on error. If compute returns an error, the
+// error will be returned for every call to Get for the same file within the same Request.
+func NewFileMemo[V any](compute func(context.Context, protoreflect.FileDescriptor) (V, error)) *FileMemo[V] {
+	return &FileMemo[V]{
+		compute: compute,
+	}
+}
+
+// Wrap returns a new RuleHandler that will make memoized values available to Get
+// within the given RuleHandler.
+//
+// Values are stored for the duration of a single call to the returned RuleHandler.
+func (f *FileMemo[V]) Wrap(ruleHandler check.RuleHandler) check.RuleHandler {
+	return check.RuleHandlerFunc(
+		func(
+			ctx context.Context,
+			responseWriter check.ResponseWriter,
+			request check.Request,
+		) error {
+			return ruleHandler.Handle(
+				context.WithValue(ctx, fileMemoContextKey[V]{fileMemo: f}, newFileMemoStore[V]()),
+				responseWriter,
+				request,
+			)
+		},
+	)
+}
+
+// Get gets the value for the given file, computing it if it has not already been computed
+// within the current Request.
+//
+// The context must derive from the context passed to a RuleHandler returned from Wrap. If it
+// does not, the value will be computed on every call.
+func (f *FileMemo[V]) Get(ctx context.Context, fileDescriptor protoreflect.FileDescriptor) (V, error) {
+	fileMemoStore, ok := ctx.Value(fileMemoContextKey[V]{fileMemo: f}).(*fileMemoStore[V])
+	if !ok {
+		return f.compute(ctx, fileDescriptor)
+	}
+	return fileMemoStore.getSingleton(f, fileDescriptor).Get(ctx)
+}
+
+// *** PRIVATE ***
+
+type fileMemoContextKey[V any] struct {
+	fileMemo *FileMemo[V]
+}
+
+type fileMemoStore[V any] struct {
+	fileDescriptorToSingleton map[protoreflect.FileDescriptor]*cache.Singleton[V]
+	lock                      sync.Mutex
+}
+
+func newFileMemoStore[V any]() *fileMemoStore[V] {
+	return &fileMemoStore[V]{
+		fileDescriptorToSingleton: make(map[protoreflect.FileDescriptor]*cache.Singleton[V]),
+	}
+}
+
+func (s *fileMemoStore[V]) getSingleton(fileMemo *FileMemo[V], fileDescriptor protoreflect.FileDescriptor) *cache.Singleton[V] {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	singleton, ok := s.fileDescriptorToSingleton[fileDescriptor]
+	if !ok {
+		singleton = cache.NewSingleton(
+			func(ctx context.Context) (V, error) {
+				return fileMemo.compute(ctx, fileDescriptor)
+			},
+		)
+		s.fileDescriptorToSingleton[fileDescriptor] = singleton
+	}
+	return singleton
+}
@@ -0,0 +1,199 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package checkutil
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"buf.build/go/bufplugin/check"
+	"buf.build/go/bufplugin/descriptor/descriptortest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+func TestFileMemo(t *testing.T) {
+	t.Parallel()
+
+	request := testNewFileMemoRequest(t)
+	var computeCount atomic.Int32
+	fileMemo := NewFileMemo(
+		func(_ context.Context, fileDescriptor protoreflect.FileDescriptor) (string, error) {
+			computeCount.Add(1)
+			return fileDescriptor.Path(), nil
+		},
+	)
+	var paths []string
+	ruleHandler := fileMemo.Wrap(
+		NewFieldRuleHandler(
+			func(ctx context.Context, _ check.ResponseWriter, _ check.Request, fieldDescriptor protoreflect.FieldDescriptor) error {
+				path, err := fileMemo.Get(ctx, fieldDescriptor.ParentFile())
+				if err != nil {
+					return err
+				}
+				paths = append(paths, path)
+				return nil
+			},
+		),
+	)
+
+	require.NoError(t, ruleHandler.Handle(context.Background(), nil, request))
+	// Three fields in a.proto, and two in b.proto.
+	assert.Equal(t, []string{"a.proto", "a.proto", "a.proto", "b.proto", "b.proto"}, paths)
+	// Computed once per file.
+	assert.Equal(t, int32(2), computeCount.Load())
+
+	// Values are not shared across Requests.
+	require.NoError(t, ruleHandler.Handle(context.Background(), nil, request))
+	assert.Equal(t, int32(4), computeCount.Load())
+}
+
+func TestFileMemoConcurrent(t *testing.T) {
+	t.Parallel()
+
+	request := testNewFileMemoRequest(t)
+	var computeCount atomic.Int32
+	fileMemo := NewFileMemo(
+		func(_ context.Context, fileDescriptor protoreflect.FileDescriptor) (string, error) {
+			computeCount.Add(1)
+			return fileDescriptor.Path(), nil
+		},
+	)
+	ruleHandler := fileMemo.Wrap(
+		check.RuleHandlerFunc(
+			func(ctx context.Context, _ check.ResponseWriter, request check.Request) error {
+				var waitGroup sync.WaitGroup
+				errs := make([]error, 16)
+				for i := 0; i < len(errs); i++ {
+					i := i
+					waitGroup.Add(1)
+					go func() {
+						defer waitGroup.Done()
+						fileDescriptor := request.FileDescriptors()[i%len(request.FileDescriptors())]
+						_, errs[i] = fileMemo.Get(ctx, fileDescriptor.ProtoreflectFileDescriptor())
+					}()
+				}
+				waitGroup.Wait()
+				return errors.Join(errs...)
+			},
+		),
+	)
+	require.NoError(t, ruleHandler.Handle(context.Background(), nil, request))
+	assert.Equal(t, int32(2), computeCount.Load())
+}
+
+func TestFileMemoError(t *testing.T) {
+	t.Parallel()
+
+	request := testNewFileMemoRequest(t)
+	computeErr := errors.New("compute error")
+	var computeCount atomic.Int32
+	fileMemo := NewFileMemo(
+		func(_ context.Context, fileDescriptor protoreflect.FileDescriptor) (int, error) {
+			computeCount.Add(1)
+			if fileDescriptor.Path() == "a.proto" {
+				return 0, computeErr
+			}
+			return 1, nil
+		},
+	)
+	var errs []error
+	ruleHandler := fileMemo.Wrap(
+		NewFieldRuleHandler(
+			func(ctx context.Context, _ check.ResponseWriter, _ check.Request, fieldDescriptor protoreflect.FieldDescriptor) error {
+				_, err := fileMemo.Get(ctx, fieldDescriptor.ParentFile())
+				errs = append(errs, err)
+				return nil
+			},
+		),
+	)
+	require.NoError(t, ruleHandler.Handle(context.Background(), nil, request))
+	// The error is returned for every call for the same file, without computing again.
+	assert.Equal(t, []error{computeErr, computeErr, computeErr, nil, nil}, errs)
+	assert.Equal(t, int32(2), computeCount.Load())
+}
+
+func TestFileMemoWithoutWrap(t *testing.T) {
+	t.Parallel()
+
+	request := testNewFileMemoRequest(t)
+	var computeCount atomic.Int32
+	fileMemo := NewFileMemo(
+		func(_ context.Context, fileDescriptor protoreflect.FileDescriptor) (string, error) {
+			computeCount.Add(1)
+			return fileDescriptor.Path(), nil
+		},
+	)
+	otherFileMemo := NewFileMemo(
+		func(_ context.Context, fileDescriptor protoreflect.FileDescriptor) (string, error) {
+			return fileDescriptor.Path(), nil
+		},
+	)
+	fileDescriptor := request.FileDescriptors()[0].ProtoreflectFileDescriptor()
+	for i := 0; i < 2; i++ {
+		path, err := fileMemo.Get(context.Background(), fileDescriptor)
+		require.NoError(t, err)
+		assert.Equal(t, "a.proto", path)
+	}
+	// The value is computed on every call if the context is not from Wrap.
+	assert.Equal(t, int32(2), computeCount.Load())
+
+	// The context from the Wrap of another FileMemo does not store values for this FileMemo.
+	ruleHandler := otherFileMemo.Wrap(
+		check.RuleHandlerFunc(
+			func(ctx context.Context, _ check.ResponseWriter, _ check.Request) error {
+				for i := 0; i < 2; i++ {
+					if _, err := fileMemo.Get(ctx, fileDescriptor); err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+		),
+	)
+	require.NoError(t, ruleHandler.Handle(context.Background(), nil, request))
+	assert.Equal(t, int32(4), computeCount.Load())
+}
+
+func testNewFileMemoRequest(t *testing.T) check.Request {
+	fileDescriptors, err := descriptortest.CompileStrings(
+		context.Background(),
+		map[string]string{
+			"a.proto": `syntax = "proto3";
+package a;
+message Foo {
+  string one = 1;
+  string two = 2;
+  string three = 3;
+}
+`,
+			"b.proto": `syntax = "proto3";
+package b;
+message Bar {
+  string one = 1;
+  string two = 2;
+}
+`,
+		},
+	)
+	require.NoError(t, err)
+	request, err := check.NewRequest(fileDescriptors)
+	require.NoError(t, err)
+	return request
+}
@@ -30,16 +30,13 @@ func NewFileRuleHandler(
 	f func(context.Context, check.ResponseWriter, check.Request, descriptor.FileDescriptor) error,
 	options ...IteratorOption,
 ) check.RuleHandler {
-	iteratorOptions := newIteratorOptions()
-	for _, option := range options {
-		option(iteratorOptions)
-	}
 	return check.RuleHandlerFunc(
 		func(
 			ctx context.Context,
 			responseWriter check.ResponseWriter,
 			request check.Request,
 		) error {
+			iteratorOptions := newIteratorOptionsForContext(ctx, options)
 			for _, fileDescriptor := range request.FileDescriptors() {
 				if iteratorOptions.withoutImports && fileDescriptor.IsImport() {
 					continue
@@ -100,7 +97,7 @@ func NewEnumRuleHandler(
 			fileDescriptor descriptor.FileDescriptor,
 		) error {
 			return forEachEnum(
-				fileDescriptor.ProtoreflectFileDescriptor(),
+				fileDescriptor,
 				func(enumDescriptor protoreflect.EnumDescriptor) error {
 					return f(ctx, responseWriter, request, enumDescriptor)
 				},
@@ -152,7 +149,7 @@ func NewMessageRuleHandler(
 			fileDescriptor descriptor.FileDescriptor,
 		) error {
 			return forEachMessage(
-				fileDescriptor.ProtoreflectFileDescriptor(),
+				fileDescriptor,
 				func(messageDescriptor protoreflect.MessageDescriptor) error {
 					return f(ctx, responseWriter, request, messageDescriptor)
 				},
@@ -165,7 +162,7 @@ func NewMessageRuleHandler(
 // NewFieldRuleHandler returns a new RuleHandler that will call f for every field in every message
 // within the check.Request's FileDescriptors().
 //
-// This includes extensions.
+// This includes extensions. To skip proto3 optional fields, use the WithoutSyntheticOneofs() option.
 //
 // This is typically used for lint Rules. Most callers will use the WithoutImports() options.
 func NewFieldRuleHandler(
@@ -179,9 +176,13 @@ func NewFieldRuleHandler(
 			request check.Request,
 			fileDescriptor descriptor.FileDescriptor,
 		) error {
+			iteratorOptions := newIteratorOptionsForContext(ctx, options)
 			return forEachField(
-				fileDescriptor.ProtoreflectFileDescriptor(),
+				fileDescriptor,
 				func(fieldDescriptor protoreflect.FieldDescriptor) error {
+					if iteratorOptions.withoutSyntheticOneofs && isInSyntheticOneof(fieldDescriptor) {
+						return nil
+					}
 					return f(ctx, responseWriter, request, fieldDescriptor)
 				},
 			)
@@ -193,7 +194,11 @@ func NewFieldRuleHandler(
 // NewOneofRuleHandler returns a new RuleHandler that will call f for every oneof in every message
 // within the check.Request's FileDescriptors().
 //
-// This is typically used for lint Rules. Most callers will use the WithoutImports() options.
+// This includes synthetic oneofs created for proto3 optional fields, unless the
+// WithoutSyntheticOneofs() option is used.
+//
+// This is typically used for lint Rules. Most callers will use the WithoutImports() and
+// WithoutSyntheticOneofs() options.
 func NewOneofRuleHandler(
 	f func(context.Context, check.ResponseWriter, check.Request, protoreflect.OneofDescriptor) error,
 	options ...IteratorOption,
@@ -205,9 +210,13 @@ func NewOneofRuleHandler(
 			request check.Request,
 			messageDescriptor protoreflect.MessageDescriptor,
 		) error {
+			iteratorOptions := newIteratorOptionsForContext(ctx, options)
 			return forEachOneof(
 				messageDescriptor,
 				func(oneofDescriptor protoreflect.OneofDescriptor) error {
+					if iteratorOptions.withoutSyntheticOneofs && descriptor.IsSyntheticOneof(oneofDescriptor) {
+						return nil
+					}
 					return f(ctx, responseWriter, request, oneofDescriptor)
 				},
 			)
@@ -0,0 +1,132 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package checkutil
+
+import (
+	"slices"
+
+	"buf.build/go/bufplugin/descriptor"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// TypeReferences is a graph of which fields and methods reference which message and enum
+// types across a set of FileDescriptors.
+//
+// A field references the message or enum type of its value. This includes extensions. Map
+// fields reference their synthetic map entry message, whose value field in turn references
+// the map value type. A method references both its input and output message types.
+type TypeReferences interface {
+	// ReferencesTo returns the fields and methods that reference the message or enum
+	// with the given full name.
+	//
+	// The returned descriptors will be either protoreflect.FieldDescriptors or
+	// protoreflect.MethodDescriptors, in the order they were declared within the
+	// FileDescriptors. A method that uses the type as both its input and output type
+	// is only returned once.
+	//
+	// Returns an empty slice if nothing references the type.
+	ReferencesTo(fullName protoreflect.FullName) []protoreflect.Descriptor
+	// ReferencedFullNames returns the full names of all message and enum types that are
+	// referenced at least once, sorted.
+	ReferencedFullNames() []protoreflect.FullName
+
+	isTypeReferences()
+}
+
+// NewTypeReferences returns a new TypeReferences for the given FileDescriptors.
+//
+// This is typically called with a check.Request's FileDescriptors(). To exclude references
+// that originate from imports, use the WithoutImports() option. Types declared within imports
+// can still be referenced from non-import files regardless of this option.
+func NewTypeReferences(
+	fileDescriptors []descriptor.FileDescriptor,
+	options ...IteratorOption,
+) (TypeReferences, error) {
+	iteratorOptions := newIteratorOptions()
+	for _, option := range options {
+		option(iteratorOptions)
+	}
+	typeReferences := newTypeReferences()
+	for _, fileDescriptor := range filterFileDescriptors(fileDescriptors, iteratorOptions.withoutImports) {
+		protoreflectFileDescriptor := fileDescriptor.ProtoreflectFileDescriptor()
+		if err := forEachField(
+			fileDescriptor,
+			func(fieldDescriptor protoreflect.FieldDescriptor) error {
+				switch {
+				case fieldDescriptor.Message() != nil:
+					typeReferences.add(fieldDescriptor.Message().FullName(), fieldDescriptor)
+				case fieldDescriptor.Enum() != nil:
+					typeReferences.add(fieldDescriptor.Enum().FullName(), fieldDescriptor)
+				}
+				return nil
+			},
+		); err != nil {
+			return nil, err
+		}
+		if err := forEachService(
+			protoreflectFileDescriptor,
+			func(serviceDescriptor protoreflect.ServiceDescriptor) error {
+				return forEachMethod(
+					serviceDescriptor,
+					func(methodDescriptor protoreflect.MethodDescriptor) error {
+						inputFullName := methodDescriptor.Input().FullName()
+						outputFullName := methodDescriptor.Output().FullName()
+						typeReferences.add(inputFullName, methodDescriptor)
+						if outputFullName != inputFullName {
+							typeReferences.add(outputFullName, methodDescriptor)
+						}
+						return nil
+					},
+				)
+			},
+		); err != nil {
+			return nil, err
+		}
+	}
+	return typeReferences, nil
+}
+
+// *** PRIVATE ***
+
+type typeReferences struct {
+	fullNameToReferences map[protoreflect.FullName][]protoreflect.Descriptor
+}
+
+func newTypeReferences() *typeReferences {
+	return &typeReferences{
+		fullNameToReferences: make(map[protoreflect.FullName][]protoreflect.Descriptor),
+	}
+}
+
+func (t *typeReferences) ReferencesTo(fullName protoreflect.FullName) []protoreflect.Descriptor {
+	references := t.fullNameToReferences[fullName]
+	// Not slices.Clone, which returns nil for nil.
+	return append(make([]protoreflect.Descriptor, 0, len(references)), references...)
+}
+
+func (t *typeReferences) ReferencedFullNames() []protoreflect.FullName {
+	fullNames := make([]protoreflect.FullName, 0, len(t.fullNameToReferences))
+	for fullName := range t.fullNameToReferences {
+		fullNames = append(fullNames, fullName)
+	}
+	slices.Sort(fullNames)
+	return fullNames
+}
+
+func (t *typeReferences) add(fullName protoreflect.FullName, referencingDescriptor protoreflect.Descriptor) {
+	t.fullNameToReferences[fullName] = append(t.fullNameToReferences[fullName], referencingDescriptor)
+}
+
+func (*typeReferences) isTypeReferences() {}
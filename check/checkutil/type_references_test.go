@@ -0,0 +1,170 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package checkutil
+
+import (
+	"context"
+	"testing"
+
+	"buf.build/go/bufplugin/descriptor"
+	"buf.build/go/bufplugin/descriptor/descriptortest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+func TestTypeReferences(t *testing.T) {
+	t.Parallel()
+
+	fileDescriptors := testCompileTypeReferencesSource(t)
+	typeReferences, err := NewTypeReferences(fileDescriptors)
+	require.NoError(t, err)
+
+	assert.Equal(
+		t,
+		[]protoreflect.FullName{
+			"a.Enum",
+			"a.Foo",
+			"b.Bar",
+			"b.Bar.ValuesEntry",
+		},
+		typeReferences.ReferencedFullNames(),
+	)
+	testCases := []struct {
+		fullName           protoreflect.FullName
+		expectedReferences []string
+	}{
+		{
+			fullName: "a.Foo",
+			expectedReferences: []string{
+				"a.Foo.self",
+				"b.Bar.foo",
+				"b.Bar.ValuesEntry.value",
+				"b.Service.Get",
+			},
+		},
+		{
+			fullName: "a.Enum",
+			expectedReferences: []string{
+				"a.Foo.enum",
+				"b.ext",
+			},
+		},
+		{
+			fullName: "b.Bar",
+			expectedReferences: []string{
+				"b.Service.Get",
+				"b.Service.Echo",
+			},
+		},
+		{
+			fullName:           "b.Bar.ValuesEntry",
+			expectedReferences: []string{"b.Bar.values"},
+		},
+		{
+			// Declared, but not referenced.
+			fullName:           "b.Unused",
+			expectedReferences: []string{},
+		},
+		{
+			// Not declared.
+			fullName:           "c.Missing",
+			expectedReferences: []string{},
+		},
+	}
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(string(testCase.fullName), func(t *testing.T) {
+			t.Parallel()
+			references := typeReferences.ReferencesTo(testCase.fullName)
+			require.NotNil(t, references)
+			assert.Equal(t, testCase.expectedReferences, testFullNames(references))
+			// The returned slice is a copy.
+			if len(references) > 0 {
+				references[0] = nil
+				assert.Equal(t, testCase.expectedReferences, testFullNames(typeReferences.ReferencesTo(testCase.fullName)))
+			}
+		})
+	}
+}
+
+func TestTypeReferencesWithoutImports(t *testing.T) {
+	t.Parallel()
+
+	fileDescriptors, err := descriptor.FileDescriptorsForFileDescriptorSet(
+		descriptor.FileDescriptorSetForFileDescriptors(testCompileTypeReferencesSource(t)),
+		descriptor.FileDescriptorSetWithImportFilePaths("a.proto"),
+	)
+	require.NoError(t, err)
+	typeReferences, err := NewTypeReferences(fileDescriptors, WithoutImports())
+	require.NoError(t, err)
+	// Types declared within imports can still be referenced from files that are not imports.
+	assert.Equal(
+		t,
+		[]string{
+			"b.Bar.foo",
+			"b.Bar.ValuesEntry.value",
+			"b.Service.Get",
+		},
+		testFullNames(typeReferences.ReferencesTo("a.Foo")),
+	)
+	assert.Equal(t, []string{"b.ext"}, testFullNames(typeReferences.ReferencesTo("a.Enum")))
+}
+
+func testCompileTypeReferencesSource(t *testing.T) []descriptor.FileDescriptor {
+	fileDescriptors, err := descriptortest.CompileStrings(
+		context.Background(),
+		map[string]string{
+			"a.proto": `syntax = "proto2";
+package a;
+message Foo {
+  optional Foo self = 1;
+  optional Enum enum = 2;
+  optional string name = 3;
+  extensions 100 to 200;
+}
+enum Enum {
+  ENUM_UNSPECIFIED = 0;
+}
+`,
+			"b.proto": `syntax = "proto2";
+package b;
+import "a.proto";
+message Bar {
+  optional a.Foo foo = 1;
+  map<string, a.Foo> values = 2;
+}
+message Unused {}
+extend a.Foo {
+  optional a.Enum ext = 100;
+}
+service Service {
+  rpc Get(a.Foo) returns (Bar);
+  rpc Echo(Bar) returns (Bar);
+}
+`,
+		},
+	)
+	require.NoError(t, err)
+	return fileDescriptors
+}
+
+func testFullNames(descriptors []protoreflect.Descriptor) []string {
+	fullNames := make([]string, len(descriptors))
+	for i, protoreflectDescriptor := range descriptors {
+		fullNames[i] = string(protoreflectDescriptor.FullName())
+	}
+	return fullNames
+}
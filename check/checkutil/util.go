@@ -23,12 +23,6 @@ import (
 	"google.golang.org/protobuf/reflect/protoreflect"
 )
 
-type container interface {
-	Enums() protoreflect.EnumDescriptors
-	Messages() protoreflect.MessageDescriptors
-	Extensions() protoreflect.ExtensionDescriptors
-}
-
 func getPathToFileDescriptor(fileDescriptors []descriptor.FileDescriptor) (map[string]descriptor.FileDescriptor, error) {
 	pathToFileDescriptorMap := make(map[string]descriptor.FileDescriptor, len(fileDescriptors))
 	for _, fileDescriptor := range fileDescriptors {
@@ -45,7 +39,7 @@ func getFullNameToEnumDescriptor(fileDescriptors []descriptor.FileDescriptor) (m
 	fullNameToEnumDescriptorMap := make(map[protoreflect.FullName]protoreflect.EnumDescriptor)
 	for _, fileDescriptor := range fileDescriptors {
 		if err := forEachEnum(
-			fileDescriptor.ProtoreflectFileDescriptor(),
+			fileDescriptor,
 			func(enumDescriptor protoreflect.EnumDescriptor) error {
 				fullName := enumDescriptor.FullName()
 				if _, ok := fullNameToEnumDescriptorMap[fullName]; ok {
@@ -93,7 +87,7 @@ func getFullNameToMessageDescriptor(fileDescriptors []descriptor.FileDescriptor)
 	fullNameToMessageDescriptorMap := make(map[protoreflect.FullName]protoreflect.MessageDescriptor)
 	for _, fileDescriptor := range fileDescriptors {
 		if err := forEachMessage(
-			fileDescriptor.ProtoreflectFileDescriptor(),
+			fileDescriptor,
 			func(messageDescriptor protoreflect.MessageDescriptor) error {
 				fullName := messageDescriptor.FullName()
 				if _, ok := fullNameToMessageDescriptorMap[fullName]; ok {
@@ -117,7 +111,7 @@ func getContainingMessageFullNameToNumberToFieldDescriptor(
 	)
 	for _, fileDescriptor := range fileDescriptors {
 		if err := forEachField(
-			fileDescriptor.ProtoreflectFileDescriptor(),
+			fileDescriptor,
 			func(fieldDescriptor protoreflect.FieldDescriptor) error {
 				number := fieldDescriptor.Number()
 				containingMessage := fieldDescriptor.ContainingMessage()
@@ -195,23 +189,10 @@ func forEachFileImport(
 }
 
 func forEachEnum(
-	container container,
+	fileDescriptor descriptor.FileDescriptor,
 	f func(protoreflect.EnumDescriptor) error,
 ) error {
-	enums := container.Enums()
-	for i := 0; i < enums.Len(); i++ {
-		if err := f(enums.Get(i)); err != nil {
-			return err
-		}
-	}
-	messages := container.Messages()
-	for i := 0; i < messages.Len(); i++ {
-		// Nested enums.
-		if err := forEachEnum(messages.Get(i), f); err != nil {
-			return err
-		}
-	}
-	return nil
+	return descriptor.Walk(fileDescriptor, &descriptor.VisitorFuncs{Enum: f})
 }
 
 func forEachEnumValue(
@@ -228,54 +209,17 @@ func forEachEnumValue(
 }
 
 func forEachMessage(
-	container container,
+	fileDescriptor descriptor.FileDescriptor,
 	f func(protoreflect.MessageDescriptor) error,
 ) error {
-	messages := container.Messages()
-	for i := 0; i < messages.Len(); i++ {
-		messageDescriptor := messages.Get(i)
-		if err := f(messageDescriptor); err != nil {
-			return err
-		}
-		// Nested messages.
-		if err := forEachMessage(messageDescriptor, f); err != nil {
-			return err
-		}
-	}
-	return nil
+	return descriptor.Walk(fileDescriptor, &descriptor.VisitorFuncs{Message: f})
 }
 
 func forEachField(
-	container container,
+	fileDescriptor descriptor.FileDescriptor,
 	f func(protoreflect.FieldDescriptor) error,
 ) error {
-	if err := forEachMessage(
-		container,
-		func(messageDescriptor protoreflect.MessageDescriptor) error {
-			fields := messageDescriptor.Fields()
-			for i := 0; i < fields.Len(); i++ {
-				if err := f(fields.Get(i)); err != nil {
-					return err
-				}
-			}
-			extensions := messageDescriptor.Extensions()
-			for i := 0; i < extensions.Len(); i++ {
-				if err := f(extensions.Get(i)); err != nil {
-					return err
-				}
-			}
-			return nil
-		},
-	); err != nil {
-		return err
-	}
-	extensions := container.Extensions()
-	for i := 0; i < extensions.Len(); i++ {
-		if err := f(extensions.Get(i)); err != nil {
-			return err
-		}
-	}
-	return nil
+	return descriptor.Walk(fileDescriptor, &descriptor.VisitorFuncs{Field: f})
 }
 
 func forEachOneof(
@@ -317,6 +261,10 @@ func forEachMethod(
 	return nil
 }
 
+func isInSyntheticOneof(fieldDescriptor protoreflect.FieldDescriptor) bool {
+	return descriptor.IsSyntheticOneof(fieldDescriptor.ContainingOneof())
+}
+
 func filterFileDescriptors(fileDescriptors []descriptor.FileDescriptor, withoutImports bool) []descriptor.FileDescriptor {
 	if !withoutImports {
 		return fileDescriptors
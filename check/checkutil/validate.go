@@ -0,0 +1,110 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package checkutil
+
+import (
+	"fmt"
+
+	"buf.build/gen/go/bufbuild/protovalidate/protocolbuffers/go/buf/validate"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// GetFieldConstraints gets the buf.validate.field constraints for the given field.
+//
+// If the field has no constraints, this returns nil.
+//
+// The options of the field are re-parsed with the buf.validate extensions registered, so this
+// works regardless of whether the extensions were resolved when the FileDescriptor was built.
+func GetFieldConstraints(fieldDescriptor protoreflect.FieldDescriptor) (*validate.FieldConstraints, error) {
+	fieldOptions := &descriptorpb.FieldOptions{}
+	if err := reparseOptions(fieldDescriptor, fieldOptions); err != nil {
+		return nil, err
+	}
+	if !proto.HasExtension(fieldOptions, validate.E_Field) {
+		return nil, nil
+	}
+	fieldConstraints, ok := proto.GetExtension(fieldOptions, validate.E_Field).(*validate.FieldConstraints)
+	if !ok {
+		return nil, fmt.Errorf("expected *validate.FieldConstraints for field %q", fieldDescriptor.FullName())
+	}
+	return fieldConstraints, nil
+}
+
+// GetMessageConstraints gets the buf.validate.message constraints for the given message.
+//
+// If the message has no constraints, this returns nil.
+//
+// The options of the message are re-parsed with the buf.validate extensions registered, so this
+// works regardless of whether the extensions were resolved when the FileDescriptor was built.
+func GetMessageConstraints(messageDescriptor protoreflect.MessageDescriptor) (*validate.MessageConstraints, error) {
+	messageOptions := &descriptorpb.MessageOptions{}
+	if err := reparseOptions(messageDescriptor, messageOptions); err != nil {
+		return nil, err
+	}
+	if !proto.HasExtension(messageOptions, validate.E_Message) {
+		return nil, nil
+	}
+	messageConstraints, ok := proto.GetExtension(messageOptions, validate.E_Message).(*validate.MessageConstraints)
+	if !ok {
+		return nil, fmt.Errorf("expected *validate.MessageConstraints for message %q", messageDescriptor.FullName())
+	}
+	return messageConstraints, nil
+}
+
+// GetOneofConstraints gets the buf.validate.oneof constraints for the given oneof.
+//
+// If the oneof has no constraints, this returns nil.
+//
+// The options of the oneof are re-parsed with the buf.validate extensions registered, so this
+// works regardless of whether the extensions were resolved when the FileDescriptor was built.
+func GetOneofConstraints(oneofDescriptor protoreflect.OneofDescriptor) (*validate.OneofConstraints, error) {
+	oneofOptions := &descriptorpb.OneofOptions{}
+	if err := reparseOptions(oneofDescriptor, oneofOptions); err != nil {
+		return nil, err
+	}
+	if !proto.HasExtension(oneofOptions, validate.E_Oneof) {
+		return nil, nil
+	}
+	oneofConstraints, ok := proto.GetExtension(oneofOptions, validate.E_Oneof).(*validate.OneofConstraints)
+	if !ok {
+		return nil, fmt.Errorf("expected *validate.OneofConstraints for oneof %q", oneofDescriptor.FullName())
+	}
+	return oneofConstraints, nil
+}
+
+// *** PRIVATE ***
+
+// reparseOptions marshals the options of the descriptor and unmarshals them into the target
+// options message using the global registry, which the validate package registers its
+// extensions with.
+//
+// The options of a descriptor may be a dynamic message, or may contain the extensions as
+// unknown fields if they were not registered at the time the FileDescriptor was built.
+func reparseOptions(protoreflectDescriptor protoreflect.Descriptor, target proto.Message) error {
+	options := protoreflectDescriptor.Options()
+	if options == nil {
+		return nil
+	}
+	data, err := proto.Marshal(options)
+	if err != nil {
+		return fmt.Errorf("could not marshal options for %q: %w", protoreflectDescriptor.FullName(), err)
+	}
+	if err := proto.Unmarshal(data, target); err != nil {
+		return fmt.Errorf("could not unmarshal options for %q: %w", protoreflectDescriptor.FullName(), err)
+	}
+	return nil
+}
@@ -0,0 +1,168 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package checkutil
+
+import (
+	"testing"
+
+	"buf.build/gen/go/bufbuild/protovalidate/protocolbuffers/go/buf/validate"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+func TestGetConstraints(t *testing.T) {
+	t.Parallel()
+
+	fieldConstraints := &validate.FieldConstraints{Required: proto.Bool(true)}
+	messageConstraints := &validate.MessageConstraints{Disabled: proto.Bool(true)}
+	oneofConstraints := &validate.OneofConstraints{Required: proto.Bool(true)}
+	testCases := []struct {
+		name string
+		// Whether the options are set with the constraints.
+		withConstraints bool
+		// Whether the constraints are within the unknown fields of the options, as when the
+		// buf.validate extensions were not resolved when the FileDescriptor was built.
+		asUnknownFields bool
+	}{
+		{
+			name: "absent",
+		},
+		{
+			name:            "present",
+			withConstraints: true,
+		},
+		{
+			name:            "present_as_unknown_fields",
+			withConstraints: true,
+			asUnknownFields: true,
+		},
+	}
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+			fieldOptions := &descriptorpb.FieldOptions{Deprecated: proto.Bool(true)}
+			messageOptions := &descriptorpb.MessageOptions{Deprecated: proto.Bool(true)}
+			oneofOptions := &descriptorpb.OneofOptions{}
+			if testCase.withConstraints {
+				proto.SetExtension(fieldOptions, validate.E_Field, fieldConstraints)
+				proto.SetExtension(messageOptions, validate.E_Message, messageConstraints)
+				proto.SetExtension(oneofOptions, validate.E_Oneof, oneofConstraints)
+			}
+			if testCase.asUnknownFields {
+				fieldOptions = testWithExtensionsAsUnknownFields(t, fieldOptions)
+				messageOptions = testWithExtensionsAsUnknownFields(t, messageOptions)
+				oneofOptions = testWithExtensionsAsUnknownFields(t, oneofOptions)
+				require.False(t, proto.HasExtension(fieldOptions, validate.E_Field))
+				require.NotEmpty(t, fieldOptions.ProtoReflect().GetUnknown())
+			}
+			messageDescriptor := testNewValidateMessageDescriptor(t, fieldOptions, messageOptions, oneofOptions)
+
+			actualFieldConstraints, err := GetFieldConstraints(messageDescriptor.Fields().ByName("name"))
+			require.NoError(t, err)
+			actualMessageConstraints, err := GetMessageConstraints(messageDescriptor)
+			require.NoError(t, err)
+			actualOneofConstraints, err := GetOneofConstraints(messageDescriptor.Oneofs().ByName("choice"))
+			require.NoError(t, err)
+			if !testCase.withConstraints {
+				assert.Nil(t, actualFieldConstraints)
+				assert.Nil(t, actualMessageConstraints)
+				assert.Nil(t, actualOneofConstraints)
+				return
+			}
+			assert.True(t, proto.Equal(fieldConstraints, actualFieldConstraints))
+			assert.True(t, proto.Equal(messageConstraints, actualMessageConstraints))
+			assert.True(t, proto.Equal(oneofConstraints, actualOneofConstraints))
+		})
+	}
+}
+
+func TestGetConstraintsWithoutOptions(t *testing.T) {
+	t.Parallel()
+
+	messageDescriptor := testNewValidateMessageDescriptor(t, nil, nil, nil)
+	fieldConstraints, err := GetFieldConstraints(messageDescriptor.Fields().ByName("name"))
+	require.NoError(t, err)
+	assert.Nil(t, fieldConstraints)
+	messageConstraints, err := GetMessageConstraints(messageDescriptor)
+	require.NoError(t, err)
+	assert.Nil(t, messageConstraints)
+	oneofConstraints, err := GetOneofConstraints(messageDescriptor.Oneofs().ByName("choice"))
+	require.NoError(t, err)
+	assert.Nil(t, oneofConstraints)
+}
+
+// testNewValidateMessageDescriptor returns the descriptor of the message:
+//
+//	message Foo {
+//	  oneof choice {
+//	    string name = 1;
+//	  }
+//	}
+func testNewValidateMessageDescriptor(
+	t *testing.T,
+	fieldOptions *descriptorpb.FieldOptions,
+	messageOptions *descriptorpb.MessageOptions,
+	oneofOptions *descriptorpb.OneofOptions,
+) protoreflect.MessageDescriptor {
+	fileDescriptor, err := protodesc.NewFile(
+		&descriptorpb.FileDescriptorProto{
+			Name:    proto.String("a.proto"),
+			Package: proto.String("a"),
+			Syntax:  proto.String("proto3"),
+			MessageType: []*descriptorpb.DescriptorProto{
+				{
+					Name: proto.String("Foo"),
+					Field: []*descriptorpb.FieldDescriptorProto{
+						{
+							Name:       proto.String("name"),
+							Number:     proto.Int32(1),
+							Label:      descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+							Type:       descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(),
+							OneofIndex: proto.Int32(0),
+							Options:    fieldOptions,
+						},
+					},
+					OneofDecl: []*descriptorpb.OneofDescriptorProto{
+						{
+							Name:    proto.String("choice"),
+							Options: oneofOptions,
+						},
+					},
+					Options: messageOptions,
+				},
+			},
+		},
+		&protoregistry.Files{},
+	)
+	require.NoError(t, err)
+	return fileDescriptor.Messages().ByName("Foo")
+}
+
+// testWithExtensionsAsUnknownFields returns a copy of the options with all extensions moved to
+// the unknown fields.
+func testWithExtensionsAsUnknownFields[T proto.Message](t *testing.T, options T) T {
+	data, err := proto.Marshal(options)
+	require.NoError(t, err)
+	unknownOptions, ok := options.ProtoReflect().New().Interface().(T)
+	require.True(t, ok)
+	require.NoError(t, proto.UnmarshalOptions{Resolver: &protoregistry.Types{}}.Unmarshal(data, unknownOptions))
+	return unknownOptions
+}
@@ -21,6 +21,7 @@ import (
 	"buf.build/go/bufplugin/info"
 	"buf.build/go/bufplugin/internal/gen/buf/plugin/check/v1/v1pluginrpc"
 	"buf.build/go/bufplugin/internal/pkg/cache"
+	"buf.build/go/bufplugin/internal/pkg/thread"
 	"buf.build/go/bufplugin/internal/pkg/xslices"
 	"pluginrpc.com/pluginrpc"
 )
@@ -40,12 +41,12 @@ type Client interface {
 	Check(ctx context.Context, request Request, options ...CheckCallOption) (Response, error)
 	// ListRules lists all available Rules from the plugin.
 	//
-	// The Rules will be sorted by Rule ID.
+	// The Rules will be sorted by Rule ID, unless ClientWithPreserveOrder is specified.
 	// Returns error if duplicate Rule IDs were detected from the underlying source.
 	ListRules(ctx context.Context, options ...ListRulesCallOption) ([]Rule, error)
 	// ListCategories lists all available Categories from the plugin.
 	//
-	// The Categories will be sorted by Category ID.
+	// The Categories will be sorted by Category ID, unless ClientWithPreserveOrder is specified.
 	// Returns error if duplicate Category IDs were detected from the underlying source.
 	ListCategories(ctx context.Context, options ...ListCategoriesCallOption) ([]Category, error)
 
@@ -58,7 +59,14 @@ func NewClient(pluginrpcClient pluginrpc.Client, options ...ClientOption) Client
 	for _, option := range options {
 		option.applyToClient(clientOptions)
 	}
-	return newClient(pluginrpcClient, clientOptions.caching)
+	return newClient(
+		pluginrpcClient,
+		clientOptions.caching,
+		clientOptions.preserveOrder,
+		clientOptions.ownership,
+		clientOptions.maxMessageLength,
+		clientOptions.docURLResolver,
+	)
 }
 
 // ClientOption is an option for a new Client.
@@ -80,6 +88,55 @@ func ClientWithCaching() ClientOption {
 	return clientWithCachingOption{}
 }
 
+// ClientWithPreserveOrder returns a new ClientOption that will result in the Rules from
+// ListRules and the Categories from ListCategories being returned in the order that the
+// plugin returned them, as opposed to sorted by ID.
+//
+// This should be used with plugins that set PreserveOrder on their Spec.
+//
+// The default is to sort by ID. When using NewClientForSpec, this option is implied if
+// the Spec has PreserveOrder set.
+func ClientWithPreserveOrder() ClientOption {
+	return clientWithPreserveOrderOption{}
+}
+
+// ClientWithOwnership returns a new ClientOption that will result in the owners of each
+// Annotation returned from Check being set according to the given Ownership.
+//
+// This can be overridden for a single call with CheckCallWithOwnership.
+//
+// The default is to not set owners.
+func ClientWithOwnership(ownership Ownership) ClientOption {
+	return clientWithOwnershipOption{ownership: ownership}
+}
+
+// ClientWithMaxMessageLength returns a new ClientOption that will result in the message of
+// each Annotation returned from Check being truncated to at most the given number of bytes.
+//
+// Some Rules embed large content such as diffs in messages, which can break downstream
+// renderers. Truncated messages end with TruncatedMessageSuffix, and are truncated on a UTF-8
+// character boundary. The full message is preserved, and is available via Annotation.FullMessage.
+// If maxMessageLength is less than the length of TruncatedMessageSuffix, the length of
+// TruncatedMessageSuffix is used.
+//
+// This can be overridden for a single call with CheckCallWithMaxMessageLength.
+//
+// The default is to not truncate. A value <= 0 indicates the default behavior.
+func ClientWithMaxMessageLength(maxMessageLength int) ClientOption {
+	return clientWithMaxMessageLengthOption{maxMessageLength: maxMessageLength}
+}
+
+// ClientWithDocURLResolver returns a new ClientOption that will result in the documentation
+// URL of each Rule returned from ListRules, and each Annotation returned from Check, being set
+// according to the given DocURLResolver.
+//
+// See Rule.DocURL and Annotation.DocURL.
+//
+// The default is to not set documentation URLs.
+func ClientWithDocURLResolver(docURLResolver DocURLResolver) ClientOption {
+	return clientWithDocURLResolverOption{docURLResolver: docURLResolver}
+}
+
 // NewClientForSpec return a new Client that directly uses the given Spec.
 //
 // This should primarily be used for testing.
@@ -97,6 +154,10 @@ func NewClientForSpec(spec *Spec, options ...ClientForSpecOption) (Client, error
 			pluginrpc.NewServerRunner(server),
 		),
 		clientForSpecOptions.caching,
+		clientForSpecOptions.preserveOrder || spec.PreserveOrder,
+		clientForSpecOptions.ownership,
+		clientForSpecOptions.maxMessageLength,
+		clientForSpecOptions.docURLResolver,
 	), nil
 }
 
@@ -108,6 +169,27 @@ type ClientForSpecOption interface {
 // CheckCallOption is an option for a Client.Check call.
 type CheckCallOption func(*checkCallOptions)
 
+// CheckCallWithOwnership returns a new CheckCallOption that will result in the owners of each
+// Annotation being set according to the given Ownership.
+//
+// This overrides any Ownership set with ClientWithOwnership.
+func CheckCallWithOwnership(ownership Ownership) CheckCallOption {
+	return func(checkCallOptions *checkCallOptions) {
+		checkCallOptions.ownership = ownership
+	}
+}
+
+// CheckCallWithMaxMessageLength returns a new CheckCallOption that will result in the message
+// of each Annotation being truncated to at most the given number of bytes.
+//
+// See ClientWithMaxMessageLength for more details. This overrides any maximum message length
+// set with ClientWithMaxMessageLength. A value <= 0 disables truncation.
+func CheckCallWithMaxMessageLength(maxMessageLength int) CheckCallOption {
+	return func(checkCallOptions *checkCallOptions) {
+		checkCallOptions.maxMessageLength = maxMessageLength
+	}
+}
+
 // ListRulesCallOption is an option for a Client.ListRules call.
 type ListRulesCallOption func(*listRulesCallOptions)
 
@@ -121,7 +203,11 @@ type client struct {
 
 	pluginrpcClient pluginrpc.Client
 
-	caching bool
+	caching          bool
+	preserveOrder    bool
+	ownership        Ownership
+	maxMessageLength int
+	docURLResolver   DocURLResolver
 
 	// Singleton ordering: rules -> categories -> checkServiceClient
 	rules              *cache.Singleton[[]Rule]
@@ -132,15 +218,23 @@ type client struct {
 func newClient(
 	pluginrpcClient pluginrpc.Client,
 	caching bool,
+	preserveOrder bool,
+	ownership Ownership,
+	maxMessageLength int,
+	docURLResolver DocURLResolver,
 ) *client {
 	var infoClientOptions []info.ClientOption
 	if caching {
 		infoClientOptions = append(infoClientOptions, info.ClientWithCaching())
 	}
 	client := &client{
-		Client:          info.NewClient(pluginrpcClient, infoClientOptions...),
-		pluginrpcClient: pluginrpcClient,
-		caching:         caching,
+		Client:           info.NewClient(pluginrpcClient, infoClientOptions...),
+		pluginrpcClient:  pluginrpcClient,
+		caching:          caching,
+		preserveOrder:    preserveOrder,
+		ownership:        ownership,
+		maxMessageLength: maxMessageLength,
+		docURLResolver:   docURLResolver,
 	}
 	client.rules = cache.NewSingleton(client.listRulesUncached)
 	client.categories = cache.NewSingleton(client.listCategoriesUncached)
@@ -148,11 +242,22 @@ func newClient(
 	return client
 }
 
-func (c *client) Check(ctx context.Context, request Request, _ ...CheckCallOption) (Response, error) {
+func (c *client) Check(ctx context.Context, request Request, options ...CheckCallOption) (Response, error) {
+	checkCallOptions := newCheckCallOptions(c.ownership, c.maxMessageLength, c.docURLResolver)
+	for _, option := range options {
+		option(checkCallOptions)
+	}
 	checkServiceClient, err := c.checkServiceClient.Get(ctx)
 	if err != nil {
 		return nil, err
 	}
+	var liveAnnotationSink *contextAnnotationSink
+	if checkCallOptions.annotationSink != nil {
+		liveAnnotationSink = &contextAnnotationSink{
+			annotationSink: annotationSinkForCheckCallOptions(checkCallOptions.annotationSink, checkCallOptions),
+		}
+		ctx = contextWithAnnotationSink(ctx, liveAnnotationSink)
+	}
 	multiResponseWriter, err := newMultiResponseWriter(request)
 	if err != nil {
 		return nil, err
@@ -181,7 +286,21 @@ func (c *client) Check(ctx context.Context, request Request, _ ...CheckCallOptio
 			)
 		}
 	}
-	return multiResponseWriter.toResponse()
+	response, err := multiResponseWriter.toResponse()
+	if err != nil {
+		return nil, err
+	}
+	response, err = responseForCheckCallOptions(response, checkCallOptions)
+	if err != nil {
+		return nil, err
+	}
+	if liveAnnotationSink != nil && !liveAnnotationSink.used.Load() {
+		// The plugin did not run in-process, so the Annotations could not be streamed.
+		for _, annotation := range response.Annotations() {
+			checkCallOptions.annotationSink(annotation)
+		}
+	}
+	return response, nil
 }
 
 func (c *client) ListRules(ctx context.Context, _ ...ListRulesCallOption) ([]Rule, error) {
@@ -199,33 +318,28 @@ func (c *client) ListCategories(ctx context.Context, _ ...ListCategoriesCallOpti
 }
 
 func (c *client) listRulesUncached(ctx context.Context) ([]Rule, error) {
-	checkServiceClient, err := c.checkServiceClient.Get(ctx)
-	if err != nil {
-		return nil, err
-	}
 	var protoRules []*checkv1.Rule
-	var pageToken string
-	for {
-		response, err := checkServiceClient.ListRules(
-			ctx,
-			&checkv1.ListRulesRequest{
-				PageSize:  listRulesPageSize,
-				PageToken: pageToken,
+	var categories []Category
+	// Rule pages and Categories do not depend on each other until we construct the Rules,
+	// so we fetch them concurrently. Rule pages themselves are fetched concurrently if the
+	// plugin returns index page tokens, see listPages. Once both calls complete, the Rules
+	// are constructed by resolving the Category IDs of each Rule against the Categories.
+	if err := thread.Parallelize(
+		ctx,
+		[]func(context.Context) error{
+			func(ctx context.Context) error {
+				var err error
+				protoRules, err = c.listProtoRules(ctx)
+				return err
 			},
-		)
-		if err != nil {
-			return nil, err
-		}
-		protoRules = append(protoRules, response.GetRules()...)
-		pageToken = response.GetNextPageToken()
-		if pageToken == "" {
-			break
-		}
-	}
-
-	// We acquire rules before categories.
-	categories, err := c.ListCategories(ctx)
-	if err != nil {
+			func(ctx context.Context) error {
+				var err error
+				categories, err = c.ListCategories(ctx)
+				return err
+			},
+		},
+		thread.ParallelizeWithCancelOnFailure(),
+	); err != nil {
 		return nil, err
 	}
 	categoryIDToCategory := make(map[string]Category)
@@ -236,7 +350,14 @@ func (c *client) listRulesUncached(ctx context.Context) ([]Rule, error) {
 	rules, err := xslices.MapError(
 		protoRules,
 		func(protoRule *checkv1.Rule) (Rule, error) {
-			return ruleForProtoRule(protoRule, categoryIDToCategory)
+			rule, err := ruleForProtoRule(protoRule, categoryIDToCategory)
+			if err != nil {
+				return nil, err
+			}
+			if c.docURLResolver != nil {
+				rule.docURL = c.docURLResolver(rule.ID())
+			}
+			return rule, nil
 		},
 	)
 	if err != nil {
@@ -245,33 +366,58 @@ func (c *client) listRulesUncached(ctx context.Context) ([]Rule, error) {
 	if err := validateRules(rules); err != nil {
 		return nil, err
 	}
-	sortRules(rules)
+	if !c.preserveOrder {
+		sortRules(rules)
+	}
 	return rules, nil
 }
 
+func (c *client) listProtoRules(ctx context.Context) ([]*checkv1.Rule, error) {
+	checkServiceClient, err := c.checkServiceClient.Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return listPages(
+		ctx,
+		func(ctx context.Context, pageToken string) ([]*checkv1.Rule, string, error) {
+			response, err := checkServiceClient.ListRules(
+				ctx,
+				&checkv1.ListRulesRequest{
+					PageSize:  listRulesPageSize,
+					PageToken: pageToken,
+				},
+			)
+			if err != nil {
+				return nil, "", err
+			}
+			return response.GetRules(), response.GetNextPageToken(), nil
+		},
+	)
+}
+
 func (c *client) listCategoriesUncached(ctx context.Context) ([]Category, error) {
 	checkServiceClient, err := c.checkServiceClient.Get(ctx)
 	if err != nil {
 		return nil, err
 	}
-	var protoCategories []*checkv1.Category
-	var pageToken string
-	for {
-		response, err := checkServiceClient.ListCategories(
-			ctx,
-			&checkv1.ListCategoriesRequest{
-				PageSize:  listCategoriesPageSize,
-				PageToken: pageToken,
-			},
-		)
-		if err != nil {
-			return nil, err
-		}
-		protoCategories = append(protoCategories, response.GetCategories()...)
-		pageToken = response.GetNextPageToken()
-		if pageToken == "" {
-			break
-		}
+	protoCategories, err := listPages(
+		ctx,
+		func(ctx context.Context, pageToken string) ([]*checkv1.Category, string, error) {
+			response, err := checkServiceClient.ListCategories(
+				ctx,
+				&checkv1.ListCategoriesRequest{
+					PageSize:  listCategoriesPageSize,
+					PageToken: pageToken,
+				},
+			)
+			if err != nil {
+				return nil, "", err
+			}
+			return response.GetCategories(), response.GetNextPageToken(), nil
+		},
+	)
+	if err != nil {
+		return nil, err
 	}
 	categories, err := xslices.MapError(protoCategories, categoryForProtoCategory)
 	if err != nil {
@@ -280,7 +426,9 @@ func (c *client) listCategoriesUncached(ctx context.Context) ([]Category, error)
 	if err := validateCategories(categories); err != nil {
 		return nil, err
 	}
-	sortCategories(categories)
+	if !c.preserveOrder {
+		sortCategories(categories)
+	}
 	return categories, nil
 }
 
@@ -305,7 +453,11 @@ func (c *client) getCheckServiceClientUncached(ctx context.Context) (v1pluginrpc
 func (*client) isClient() {}
 
 type clientOptions struct {
-	caching bool
+	caching          bool
+	preserveOrder    bool
+	ownership        Ownership
+	maxMessageLength int
+	docURLResolver   DocURLResolver
 }
 
 func newClientOptions() *clientOptions {
@@ -313,7 +465,11 @@ func newClientOptions() *clientOptions {
 }
 
 type clientForSpecOptions struct {
-	caching bool
+	caching          bool
+	preserveOrder    bool
+	ownership        Ownership
+	maxMessageLength int
+	docURLResolver   DocURLResolver
 }
 
 func newClientForSpecOptions() *clientForSpecOptions {
@@ -330,7 +486,131 @@ func (clientWithCachingOption) applyToClientForSpec(clientForSpecOptions *client
 	clientForSpecOptions.caching = true
 }
 
-type checkCallOptions struct{}
+type clientWithPreserveOrderOption struct{}
+
+func (clientWithPreserveOrderOption) applyToClient(clientOptions *clientOptions) {
+	clientOptions.preserveOrder = true
+}
+
+func (clientWithPreserveOrderOption) applyToClientForSpec(clientForSpecOptions *clientForSpecOptions) {
+	clientForSpecOptions.preserveOrder = true
+}
+
+type clientWithOwnershipOption struct {
+	ownership Ownership
+}
+
+func (c clientWithOwnershipOption) applyToClient(clientOptions *clientOptions) {
+	clientOptions.ownership = c.ownership
+}
+
+func (c clientWithOwnershipOption) applyToClientForSpec(clientForSpecOptions *clientForSpecOptions) {
+	clientForSpecOptions.ownership = c.ownership
+}
+
+type clientWithMaxMessageLengthOption struct {
+	maxMessageLength int
+}
+
+func (c clientWithMaxMessageLengthOption) applyToClient(clientOptions *clientOptions) {
+	clientOptions.maxMessageLength = c.maxMessageLength
+}
+
+func (c clientWithMaxMessageLengthOption) applyToClientForSpec(clientForSpecOptions *clientForSpecOptions) {
+	clientForSpecOptions.maxMessageLength = c.maxMessageLength
+}
+
+type clientWithDocURLResolverOption struct {
+	docURLResolver DocURLResolver
+}
+
+func (c clientWithDocURLResolverOption) applyToClient(clientOptions *clientOptions) {
+	clientOptions.docURLResolver = c.docURLResolver
+}
+
+func (c clientWithDocURLResolverOption) applyToClientForSpec(clientForSpecOptions *clientForSpecOptions) {
+	clientForSpecOptions.docURLResolver = c.docURLResolver
+}
+
+type checkCallOptions struct {
+	ownership        Ownership
+	maxMessageLength int
+	docURLResolver   DocURLResolver
+	annotationSink   AnnotationSink
+}
+
+func newCheckCallOptions(ownership Ownership, maxMessageLength int, docURLResolver DocURLResolver) *checkCallOptions {
+	return &checkCallOptions{
+		ownership:        ownership,
+		maxMessageLength: maxMessageLength,
+		docURLResolver:   docURLResolver,
+	}
+}
+
+// responseForCheckCallOptions returns a new Response with the owners, documentation URLs, and
+// message truncation of the checkCallOptions applied to each Annotation.
+func responseForCheckCallOptions(response Response, checkCallOptions *checkCallOptions) (Response, error) {
+	if !checkCallOptions.transformsAnnotations() {
+		return response, nil
+	}
+	annotations := response.Annotations()
+	for i, existingAnnotation := range annotations {
+		newAnnotation, err := annotationForCheckCallOptions(existingAnnotation, checkCallOptions)
+		if err != nil {
+			return nil, err
+		}
+		annotations[i] = newAnnotation
+	}
+	return newResponse(annotations)
+}
+
+// annotationSinkForCheckCallOptions returns an AnnotationSink that applies the owners, documentation
+// URLs, and message truncation of the checkCallOptions to each Annotation before passing it to the
+// given AnnotationSink.
+func annotationSinkForCheckCallOptions(annotationSink AnnotationSink, checkCallOptions *checkCallOptions) AnnotationSink {
+	if !checkCallOptions.transformsAnnotations() {
+		return annotationSink
+	}
+	return func(existingAnnotation Annotation) {
+		newAnnotation, err := annotationForCheckCallOptions(existingAnnotation, checkCallOptions)
+		if err != nil {
+			// Annotations are validated when created, so this should never happen. Pass
+			// the Annotation as-is rather than dropping it.
+			annotationSink(existingAnnotation)
+			return
+		}
+		annotationSink(newAnnotation)
+	}
+}
+
+func annotationForCheckCallOptions(existingAnnotation Annotation, checkCallOptions *checkCallOptions) (*annotation, error) {
+	newAnnotation, err := newAnnotation(
+		existingAnnotation.RuleID(),
+		existingAnnotation.Message(),
+		existingAnnotation.FileLocation(),
+		existingAnnotation.AgainstFileLocation(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	if checkCallOptions.ownership != nil {
+		newAnnotation.owners = ownersForAnnotation(existingAnnotation, checkCallOptions.ownership)
+	}
+	if checkCallOptions.docURLResolver != nil {
+		newAnnotation.docURL = checkCallOptions.docURLResolver(newAnnotation.ruleID)
+	}
+	if checkCallOptions.maxMessageLength > 0 {
+		if truncatedMessage, ok := truncateMessage(newAnnotation.message, checkCallOptions.maxMessageLength); ok {
+			newAnnotation.fullMessage = newAnnotation.message
+			newAnnotation.message = truncatedMessage
+		}
+	}
+	return newAnnotation, nil
+}
+
+func (c *checkCallOptions) transformsAnnotations() bool {
+	return c.ownership != nil || c.maxMessageLength > 0 || c.docURLResolver != nil
+}
 
 type listRulesCallOptions struct{}
 
@@ -18,9 +18,14 @@ import (
 	"context"
 	"fmt"
 	"slices"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	checkv1 "buf.build/gen/go/bufbuild/bufplugin/protocolbuffers/go/buf/plugin/check/v1"
 	"buf.build/go/bufplugin/info"
+	"buf.build/go/bufplugin/internal/gen/buf/plugin/check/v1/v1pluginrpc"
+	"buf.build/go/bufplugin/internal/pkg/cache"
 	"buf.build/go/bufplugin/internal/pkg/xslices"
 	"github.com/stretchr/testify/require"
 	"pluginrpc.com/pluginrpc"
@@ -155,6 +160,84 @@ func testClientListRulesCount(t *testing.T, count int) {
 	}
 }
 
+func TestClientListRulesCategoriesCount(t *testing.T) {
+	t.Parallel()
+
+	testClientListRulesCategoriesCount(t, listCategoriesPageSize-1)
+	testClientListRulesCategoriesCount(t, listCategoriesPageSize+1)
+	testClientListRulesCategoriesCount(t, (listCategoriesPageSize*2)+1)
+	testClientListRulesCategoriesCount(t, (listCategoriesPageSize*2)+1, ClientWithCaching())
+}
+
+func testClientListRulesCategoriesCount(t *testing.T, count int, options ...ClientForSpecOption) {
+	require.True(t, count < 10000, "count must be less than 10000 for sorting to work properly in this test")
+	ruleSpecs := make([]*RuleSpec, count)
+	categorySpecs := make([]*CategorySpec, count)
+	for i := 0; i < count; i++ {
+		categorySpecs[i] = &CategorySpec{
+			ID:      fmt.Sprintf("CATEGORY%05d", i),
+			Purpose: fmt.Sprintf("Test CATEGORY%05d.", i),
+		}
+		ruleSpecs[i] = &RuleSpec{
+			ID:          fmt.Sprintf("RULE%05d", i),
+			CategoryIDs: []string{categorySpecs[i].ID},
+			Purpose:     fmt.Sprintf("Test RULE%05d.", i),
+			Type:        RuleTypeLint,
+			Handler:     nopRuleHandler,
+		}
+	}
+	client, err := NewClientForSpec(&Spec{Rules: ruleSpecs, Categories: categorySpecs}, options...)
+	require.NoError(t, err)
+	rules, err := client.ListRules(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, count, len(rules))
+	for i := 0; i < count; i++ {
+		require.Equal(t, ruleSpecs[i].ID, rules[i].ID())
+		require.Equal(t, []string{categorySpecs[i].ID}, xslices.Map(rules[i].Categories(), Category.ID))
+	}
+	categories, err := client.ListCategories(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, count, len(categories))
+}
+
+func TestClientListRulesCategoriesPreserveOrder(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	spec := &Spec{
+		Rules: []*RuleSpec{
+			testNewSimpleLintRuleSpec("RULE3", []string{"CATEGORY2"}, true, false, nil),
+			testNewSimpleLintRuleSpec("RULE1", []string{"CATEGORY1"}, true, false, nil),
+			testNewSimpleLintRuleSpec("RULE2", nil, true, false, nil),
+		},
+		Categories: []*CategorySpec{
+			testNewSimpleCategorySpec("CATEGORY2", false, nil),
+			testNewSimpleCategorySpec("CATEGORY1", false, nil),
+		},
+		PreserveOrder: true,
+	}
+	client, err := NewClientForSpec(spec)
+	require.NoError(t, err)
+	rules, err := client.ListRules(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []string{"RULE3", "RULE1", "RULE2"}, xslices.Map(rules, Rule.ID))
+	categories, err := client.ListCategories(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []string{"CATEGORY2", "CATEGORY1"}, xslices.Map(categories, Category.ID))
+
+	// A Client that is not preserving order will sort, even if the plugin preserves order.
+	server, err := NewServer(spec)
+	require.NoError(t, err)
+	client = NewClient(pluginrpc.NewClient(pluginrpc.NewServerRunner(server)))
+	rules, err = client.ListRules(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []string{"RULE1", "RULE2", "RULE3"}, xslices.Map(rules, Rule.ID))
+	client = NewClient(pluginrpc.NewClient(pluginrpc.NewServerRunner(server)), ClientWithPreserveOrder())
+	rules, err = client.ListRules(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []string{"RULE3", "RULE1", "RULE2"}, xslices.Map(rules, Rule.ID))
+}
+
 func TestPluginInfo(t *testing.T) {
 	t.Parallel()
 
@@ -207,3 +290,105 @@ func TestPluginInfoUnimplemented(t *testing.T) {
 	require.ErrorAs(t, err, &pluginrpcError)
 	require.Equal(t, pluginrpc.CodeUnimplemented, pluginrpcError.Code())
 }
+
+func TestClientListRulesConcurrentPages(t *testing.T) {
+	t.Parallel()
+
+	// 1 page, then 4 pages at once, then 2 pages at once.
+	count := (listRulesPageSize * 6) + 1
+	ruleSpecs := make([]*RuleSpec, count)
+	for i := 0; i < count; i++ {
+		ruleSpecs[i] = &RuleSpec{
+			ID:      fmt.Sprintf("RULE%05d", i),
+			Purpose: fmt.Sprintf("Test RULE%05d.", i),
+			Type:    RuleTypeLint,
+			Handler: nopRuleHandler,
+		}
+	}
+	for _, legacyPageTokens := range []bool{false, true} {
+		checkServiceHandler, err := NewCheckServiceHandler(&Spec{Rules: ruleSpecs})
+		require.NoError(t, err)
+		testCheckServiceClient := &testCheckServiceClient{
+			checkServiceHandler: checkServiceHandler,
+			ruleSpecs:           ruleSpecs,
+			legacyPageTokens:    legacyPageTokens,
+		}
+		client := newClient(nil, false, false, nil, 0, nil)
+		client.checkServiceClient = cache.NewSingleton(
+			func(context.Context) (v1pluginrpc.CheckServiceClient, error) {
+				return testCheckServiceClient, nil
+			},
+		)
+		rules, err := client.ListRules(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, xslices.Map(ruleSpecs, func(ruleSpec *RuleSpec) string { return ruleSpec.ID }), xslices.Map(rules, Rule.ID))
+		require.Equal(t, int32(7), testCheckServiceClient.listRulesCalls.Load())
+		if legacyPageTokens {
+			// Pages can only be fetched serially if the page tokens are IDs.
+			require.Equal(t, int32(1), testCheckServiceClient.maxListRulesCallsInFlight.Load())
+		} else {
+			require.Greater(t, testCheckServiceClient.maxListRulesCallsInFlight.Load(), int32(1))
+			require.LessOrEqual(t, testCheckServiceClient.maxListRulesCallsInFlight.Load(), int32(listPagesParallelism))
+		}
+	}
+}
+
+// testCheckServiceClient is a v1pluginrpc.CheckServiceClient that calls a
+// v1pluginrpc.CheckServiceHandler, and records the ListRules calls.
+type testCheckServiceClient struct {
+	checkServiceHandler v1pluginrpc.CheckServiceHandler
+	ruleSpecs           []*RuleSpec
+	// If set, next page tokens are converted to the IDs of the first Rule of the page, as
+	// returned by previous versions of the CheckServiceHandler.
+	legacyPageTokens bool
+
+	listRulesCalls            atomic.Int32
+	listRulesCallsInFlight    atomic.Int32
+	maxListRulesCallsInFlight atomic.Int32
+}
+
+func (c *testCheckServiceClient) Check(
+	ctx context.Context,
+	request *checkv1.CheckRequest,
+	_ ...pluginrpc.CallOption,
+) (*checkv1.CheckResponse, error) {
+	return c.checkServiceHandler.Check(ctx, request)
+}
+
+func (c *testCheckServiceClient) ListRules(
+	ctx context.Context,
+	request *checkv1.ListRulesRequest,
+	_ ...pluginrpc.CallOption,
+) (*checkv1.ListRulesResponse, error) {
+	c.listRulesCalls.Add(1)
+	inFlight := c.listRulesCallsInFlight.Add(1)
+	defer c.listRulesCallsInFlight.Add(-1)
+	for {
+		maxInFlight := c.maxListRulesCallsInFlight.Load()
+		if inFlight <= maxInFlight || c.maxListRulesCallsInFlight.CompareAndSwap(maxInFlight, inFlight) {
+			break
+		}
+	}
+	// Simulate a slow transport, so that concurrent calls overlap.
+	time.Sleep(20 * time.Millisecond)
+	response, err := c.checkServiceHandler.ListRules(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	if c.legacyPageTokens && response.GetNextPageToken() != "" {
+		index, _, ok := indexForPageToken(response.GetNextPageToken())
+		if !ok {
+			return nil, fmt.Errorf("unexpected page token: %q", response.GetNextPageToken())
+		}
+		response.NextPageToken = c.ruleSpecs[index].ID
+	}
+	return response, nil
+}
+
+func (c *testCheckServiceClient) ListCategories(
+	ctx context.Context,
+	request *checkv1.ListCategoriesRequest,
+	_ ...pluginrpc.CallOption,
+) (*checkv1.ListCategoriesResponse, error) {
+	return c.checkServiceHandler.ListCategories(ctx, request)
+}
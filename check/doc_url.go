@@ -0,0 +1,72 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// DocURLRuleIDPlaceholder is the placeholder within a pattern passed to DocURLResolverForPattern
+// that is replaced with the Rule ID.
+const DocURLRuleIDPlaceholder = "{rule_id}"
+
+// DocURLResolver resolves the documentation URL for a Rule ID.
+//
+// A DocURLResolver is installed on a Client with ClientWithDocURLResolver. This allows hosts to
+// enrich Rules and Annotations with documentation, such as pages on an internal wiki, without
+// plugins hard-coding organization-specific URLs.
+//
+// Returns nil if there is no documentation URL for the Rule ID.
+type DocURLResolver func(ruleID string) *url.URL
+
+// DocURLResolverForPattern returns a new DocURLResolver that resolves documentation URLs by
+// replacing DocURLRuleIDPlaceholder within the pattern with the Rule ID.
+//
+//	resolver, err := check.DocURLResolverForPattern("https://wiki.example.com/lint/{rule_id}")
+//
+// The pattern must contain DocURLRuleIDPlaceholder, and must result in an absolute URL. The
+// Rule ID is escaped for use within a URL path.
+func DocURLResolverForPattern(pattern string) (DocURLResolver, error) {
+	if !strings.Contains(pattern, DocURLRuleIDPlaceholder) {
+		return nil, fmt.Errorf("documentation URL pattern %q does not contain %q", pattern, DocURLRuleIDPlaceholder)
+	}
+	if _, err := docURLForPattern(pattern, "RULE_ID"); err != nil {
+		return nil, err
+	}
+	return func(ruleID string) *url.URL {
+		docURL, err := docURLForPattern(pattern, ruleID)
+		if err != nil {
+			// The pattern was validated above, and the Rule ID is escaped.
+			return nil
+		}
+		return docURL
+	}, nil
+}
+
+// *** PRIVATE ***
+
+func docURLForPattern(pattern string, ruleID string) (*url.URL, error) {
+	rawURL := strings.ReplaceAll(pattern, DocURLRuleIDPlaceholder, url.PathEscape(ruleID))
+	docURL, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid documentation URL pattern %q: %w", pattern, err)
+	}
+	if !docURL.IsAbs() {
+		return nil, fmt.Errorf("documentation URL pattern %q does not result in an absolute URL", pattern)
+	}
+	return docURL, nil
+}
@@ -0,0 +1,93 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"context"
+	"net/url"
+	"testing"
+
+	descriptorv1 "buf.build/gen/go/bufbuild/bufplugin/protocolbuffers/go/buf/plugin/descriptor/v1"
+	"buf.build/go/bufplugin/descriptor"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+func TestDocURLResolverForPattern(t *testing.T) {
+	t.Parallel()
+
+	docURLResolver, err := DocURLResolverForPattern("https://wiki.example.com/lint/{rule_id}")
+	require.NoError(t, err)
+	require.Equal(t, "https://wiki.example.com/lint/RULE1", docURLResolver("RULE1").String())
+	require.Equal(t, "https://wiki.example.com/lint/A%2FB", docURLResolver("A/B").String())
+
+	_, err = DocURLResolverForPattern("https://wiki.example.com/lint")
+	require.Error(t, err)
+	_, err = DocURLResolverForPattern("/lint/{rule_id}")
+	require.Error(t, err)
+}
+
+func TestClientWithDocURLResolver(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	fileDescriptors, err := descriptor.FileDescriptorsForProtoFileDescriptors(
+		[]*descriptorv1.FileDescriptor{
+			{
+				FileDescriptorProto: &descriptorpb.FileDescriptorProto{
+					Name:           proto.String("foo.proto"),
+					SourceCodeInfo: &descriptorpb.SourceCodeInfo{},
+				},
+			},
+		},
+	)
+	require.NoError(t, err)
+	request, err := NewRequest(fileDescriptors)
+	require.NoError(t, err)
+	docURLResolver, err := DocURLResolverForPattern("https://wiki.example.com/lint/{rule_id}")
+	require.NoError(t, err)
+	client, err := NewClientForSpec(
+		&Spec{
+			Rules: []*RuleSpec{
+				testNewAnnotatingLintRuleSpec("RULE1", "foo.proto"),
+				testNewAnnotatingLintRuleSpec("RULE2", "foo.proto"),
+			},
+		},
+		ClientWithCaching(),
+		ClientWithDocURLResolver(
+			func(ruleID string) *url.URL {
+				if ruleID == "RULE2" {
+					return nil
+				}
+				return docURLResolver(ruleID)
+			},
+		),
+	)
+	require.NoError(t, err)
+
+	rules, err := client.ListRules(ctx)
+	require.NoError(t, err)
+	require.Len(t, rules, 2)
+	require.Equal(t, "https://wiki.example.com/lint/RULE1", rules[0].DocURL().String())
+	require.Nil(t, rules[1].DocURL())
+
+	response, err := client.Check(ctx, request)
+	require.NoError(t, err)
+	annotations := response.Annotations()
+	require.Len(t, annotations, 2)
+	require.Equal(t, "https://wiki.example.com/lint/RULE1", annotations[0].DocURL().String())
+	require.Nil(t, annotations[1].DocURL())
+}
@@ -0,0 +1,82 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"buf.build/go/bufplugin/descriptor"
+	"buf.build/go/bufplugin/internal/pkg/xslices"
+)
+
+// *** PRIVATE ***
+
+// requestForFileFilter returns a view of the Request that only contains the files selected by
+// the file filter, and false if no files are selected and the Rule should be skipped.
+//
+// A file path is selected if the file filter matches either the FileDescriptor or the
+// against FileDescriptor with that path, so that breaking change Rules still see both sides
+// of a pair. If only against FileDescriptors are selected, the FileDescriptors of the Request
+// are left unfiltered, as a Request must always have FileDescriptors.
+//
+// If the file filter is nil, the Request is returned as-is.
+func requestForFileFilter(
+	request Request,
+	fileFilter func(descriptor.FileDescriptor) bool,
+) (Request, bool, error) {
+	if fileFilter == nil {
+		return request, true, nil
+	}
+	fileDescriptors := request.FileDescriptors()
+	againstFileDescriptors := request.AgainstFileDescriptors()
+	selectedPaths := make(map[string]struct{})
+	for _, fileDescriptor := range fileDescriptors {
+		if fileFilter(fileDescriptor) {
+			selectedPaths[fileDescriptorPath(fileDescriptor)] = struct{}{}
+		}
+	}
+	for _, againstFileDescriptor := range againstFileDescriptors {
+		if fileFilter(againstFileDescriptor) {
+			selectedPaths[fileDescriptorPath(againstFileDescriptor)] = struct{}{}
+		}
+	}
+	if len(selectedPaths) == 0 {
+		return nil, false, nil
+	}
+	if len(selectedPaths) == len(fileDescriptors) && len(againstFileDescriptors) == 0 {
+		// Every file was selected.
+		return request, true, nil
+	}
+	isSelected := func(fileDescriptor descriptor.FileDescriptor) bool {
+		_, ok := selectedPaths[fileDescriptorPath(fileDescriptor)]
+		return ok
+	}
+	filteredFileDescriptors := xslices.Filter(fileDescriptors, isSelected)
+	if len(filteredFileDescriptors) == 0 {
+		filteredFileDescriptors = fileDescriptors
+	}
+	filteredRequest, err := NewRequest(
+		filteredFileDescriptors,
+		WithAgainstFileDescriptors(xslices.Filter(againstFileDescriptors, isSelected)),
+		WithOptions(request.Options()),
+		WithRuleIDs(request.RuleIDs()...),
+	)
+	if err != nil {
+		return nil, false, err
+	}
+	return filteredRequest, true, nil
+}
+
+func fileDescriptorPath(fileDescriptor descriptor.FileDescriptor) string {
+	return fileDescriptor.ProtoreflectFileDescriptor().Path()
+}
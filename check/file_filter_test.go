@@ -0,0 +1,113 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	descriptorv1 "buf.build/gen/go/bufbuild/bufplugin/protocolbuffers/go/buf/plugin/descriptor/v1"
+	"buf.build/go/bufplugin/descriptor"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+func TestFileFilter(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	fileDescriptors, err := descriptor.FileDescriptorsForProtoFileDescriptors(
+		[]*descriptorv1.FileDescriptor{
+			{
+				FileDescriptorProto: &descriptorpb.FileDescriptorProto{
+					Name:           proto.String("foo.proto"),
+					Service:        []*descriptorpb.ServiceDescriptorProto{{Name: proto.String("FooService")}},
+					SourceCodeInfo: &descriptorpb.SourceCodeInfo{},
+				},
+			},
+			{
+				FileDescriptorProto: &descriptorpb.FileDescriptorProto{
+					Name:           proto.String("bar.proto"),
+					SourceCodeInfo: &descriptorpb.SourceCodeInfo{},
+				},
+			},
+		},
+	)
+	require.NoError(t, err)
+	request, err := NewRequest(fileDescriptors)
+	require.NoError(t, err)
+
+	annotateAllFilesRuleHandler := RuleHandlerFunc(
+		func(_ context.Context, responseWriter ResponseWriter, request Request) error {
+			for _, fileDescriptor := range request.FileDescriptors() {
+				responseWriter.AddAnnotation(WithFileName(fileDescriptor.ProtoreflectFileDescriptor().Path()))
+			}
+			return nil
+		},
+	)
+	spec, err := NewSpec(
+		WithLintRule(
+			"SERVICES",
+			"Checks services.",
+			annotateAllFilesRuleHandler,
+			RuleWithDefault(),
+			RuleWithFileFilter(
+				func(fileDescriptor descriptor.FileDescriptor) bool {
+					return fileDescriptor.ProtoreflectFileDescriptor().Services().Len() > 0
+				},
+			),
+		),
+		WithLintRule(
+			"NEVER",
+			"Checks nothing.",
+			RuleHandlerFunc(
+				func(context.Context, ResponseWriter, Request) error {
+					return errors.New("should not be called")
+				},
+			),
+			RuleWithDefault(),
+			RuleWithFileFilter(func(descriptor.FileDescriptor) bool { return false }),
+		),
+		WithLintRule(
+			"ALL",
+			"Checks all files.",
+			annotateAllFilesRuleHandler,
+			RuleWithDefault(),
+		),
+	)
+	require.NoError(t, err)
+	client, err := NewClientForSpec(spec)
+	require.NoError(t, err)
+	response, err := client.Check(ctx, request)
+	require.NoError(t, err)
+	require.Equal(
+		t,
+		[]string{
+			"ALL:bar.proto",
+			"ALL:foo.proto",
+			"SERVICES:foo.proto",
+		},
+		testAnnotationStrings(response.Annotations()),
+	)
+
+	response, err = HandleRule(ctx, spec.Rules[0], request)
+	require.NoError(t, err)
+	require.Equal(t, []string{"SERVICES:foo.proto"}, testAnnotationStrings(response.Annotations()))
+	response, err = HandleRule(ctx, spec.Rules[1], request)
+	require.NoError(t, err)
+	require.Empty(t, response.Annotations())
+}
@@ -0,0 +1,429 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package main implements a plugin that enforces an organization's API conventions.
+//
+// This plugin is a tour of the check API in one place, and its tests are a tour of checktest.
+// The other example plugins each demonstrate a single feature, and are a better place to start.
+// This plugin demonstrates:
+//
+//   - Lint and breaking Rules, built with checkutil.
+//   - Categories, with Rules of both types in a single Category.
+//   - Default and non-default Rules.
+//   - A deprecated Rule with a replacement.
+//   - Options of every type that check.Request carries: bool, int64, float64, string,
+//     []int64, and []string, declared with OptionSpecs so that they appear in the manifest.
+//   - Plugin information with check.Spec.Info.
+//   - Spec-level RuleHandlerMiddlewares and a Before function.
+//
+// The Rules have IDs:
+//
+//   - API_MESSAGE_NAME_LENGTH (lint, default)
+//   - API_ENUM_ZERO_VALUE_SUFFIX (lint, default)
+//   - API_ENUM_ZERO_VALUE_UNSPECIFIED (lint, deprecated, replaced by API_ENUM_ZERO_VALUE_SUFFIX)
+//   - API_FIELD_COMMENTED (lint)
+//   - API_FIELD_NUMBER_NOT_RESERVED (lint)
+//   - API_FIELD_SAME_NAME (breaking, default)
+//
+// To use this plugin:
+//
+//	# buf.yaml
+//	version: v2
+//	lint:
+//	  use:
+//	   - STANDARD # omit if you do not want to use the rules builtin to buf
+//	   - API_NAMING
+//	   - API_DOCUMENTATION
+//	breaking:
+//	  use:
+//	   - WIRE_JSON # omit if you do not want to use the rules builtin to buf
+//	   - API_COMPATIBILITY
+//	plugins:
+//	  - plugin: buf-plugin-api-conventions
+//	    options:
+//	      max_message_name_length: 24
+//	      enum_zero_value_suffix: _UNKNOWN
+//	      min_commented_field_ratio: 0.5
+//	      allow_trailing_comments: true
+//	      exempt_messages:
+//	        - acme.v1.Legacy
+//	      reserved_field_numbers:
+//	        - 9999
+package main
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	"buf.build/go/bufplugin/check"
+	"buf.build/go/bufplugin/check/checkutil"
+	"buf.build/go/bufplugin/info"
+	"buf.build/go/bufplugin/option"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+const (
+	// messageNameLengthRuleID is the Rule ID of the message name length Rule.
+	messageNameLengthRuleID = "API_MESSAGE_NAME_LENGTH"
+	// enumZeroValueSuffixRuleID is the Rule ID of the enum zero value suffix Rule.
+	enumZeroValueSuffixRuleID = "API_ENUM_ZERO_VALUE_SUFFIX"
+	// enumZeroValueUnspecifiedRuleID is the Rule ID of the deprecated enum zero value unspecified Rule.
+	enumZeroValueUnspecifiedRuleID = "API_ENUM_ZERO_VALUE_UNSPECIFIED"
+	// fieldCommentedRuleID is the Rule ID of the field commented Rule.
+	fieldCommentedRuleID = "API_FIELD_COMMENTED"
+	// fieldNumberNotReservedRuleID is the Rule ID of the field number not reserved Rule.
+	fieldNumberNotReservedRuleID = "API_FIELD_NUMBER_NOT_RESERVED"
+	// fieldSameNameRuleID is the Rule ID of the field same name Rule.
+	fieldSameNameRuleID = "API_FIELD_SAME_NAME"
+
+	// namingCategoryID is the Category ID for the naming Rules.
+	namingCategoryID = "API_NAMING"
+	// documentationCategoryID is the Category ID for the documentation Rules.
+	documentationCategoryID = "API_DOCUMENTATION"
+	// compatibilityCategoryID is the Category ID for the compatibility Rules.
+	compatibilityCategoryID = "API_COMPATIBILITY"
+
+	// maxMessageNameLengthOptionKey is the option key to override the maximum message name length.
+	maxMessageNameLengthOptionKey = "max_message_name_length"
+	// enumZeroValueSuffixOptionKey is the option key to override the enum zero value suffix.
+	enumZeroValueSuffixOptionKey = "enum_zero_value_suffix"
+	// minCommentedFieldRatioOptionKey is the option key to override the minimum ratio of commented fields.
+	minCommentedFieldRatioOptionKey = "min_commented_field_ratio"
+	// allowTrailingCommentsOptionKey is the option key to count trailing comments as field comments.
+	allowTrailingCommentsOptionKey = "allow_trailing_comments"
+	// exemptMessagesOptionKey is the option key for the fully-qualified names of messages exempt
+	// from the documentation Rules.
+	exemptMessagesOptionKey = "exempt_messages"
+	// reservedFieldNumbersOptionKey is the option key for the field numbers reserved by the organization.
+	reservedFieldNumbersOptionKey = "reserved_field_numbers"
+
+	defaultMaxMessageNameLength   = 32
+	defaultEnumZeroValueSuffix    = "_UNSPECIFIED"
+	defaultMinCommentedFieldRatio = 1.0
+)
+
+var (
+	// messageNameLengthRuleSpec is the RuleSpec for the message name length Rule.
+	messageNameLengthRuleSpec = &check.RuleSpec{
+		ID:          messageNameLengthRuleID,
+		CategoryIDs: []string{namingCategoryID},
+		Default:     true,
+		Purpose:     "Checks that message names are not longer than a maximum length (default is 32).",
+		Type:        check.RuleTypeLint,
+		Handler:     checkutil.NewMessageRuleHandler(checkMessageNameLength),
+	}
+	// enumZeroValueSuffixRuleSpec is the RuleSpec for the enum zero value suffix Rule.
+	enumZeroValueSuffixRuleSpec = &check.RuleSpec{
+		ID:          enumZeroValueSuffixRuleID,
+		CategoryIDs: []string{namingCategoryID},
+		Default:     true,
+		Purpose:     `Checks that the zero value of every enum ends in a specific suffix (default is "_UNSPECIFIED").`,
+		Type:        check.RuleTypeLint,
+		Handler:     checkutil.NewEnumRuleHandler(checkEnumZeroValueSuffix),
+	}
+	// enumZeroValueUnspecifiedRuleSpec is the RuleSpec for the deprecated enum zero value unspecified Rule.
+	//
+	// This Rule was replaced by the enum zero value suffix Rule, which allows the suffix to be configured.
+	enumZeroValueUnspecifiedRuleSpec = &check.RuleSpec{
+		ID:             enumZeroValueUnspecifiedRuleID,
+		CategoryIDs:    []string{namingCategoryID},
+		Purpose:        `Checks that the zero value of every enum ends in "_UNSPECIFIED".`,
+		Type:           check.RuleTypeLint,
+		Deprecated:     true,
+		ReplacementIDs: []string{enumZeroValueSuffixRuleID},
+		Handler:        checkutil.NewEnumRuleHandler(checkEnumZeroValueUnspecified),
+	}
+	// fieldCommentedRuleSpec is the RuleSpec for the field commented Rule.
+	fieldCommentedRuleSpec = &check.RuleSpec{
+		ID:          fieldCommentedRuleID,
+		CategoryIDs: []string{documentationCategoryID},
+		Purpose:     "Checks that at least a minimum ratio of the fields of every message have comments (default is all fields).",
+		Type:        check.RuleTypeLint,
+		Handler:     checkutil.NewMessageRuleHandler(checkFieldCommented),
+	}
+	// fieldNumberNotReservedRuleSpec is the RuleSpec for the field number not reserved Rule.
+	fieldNumberNotReservedRuleSpec = &check.RuleSpec{
+		ID:          fieldNumberNotReservedRuleID,
+		CategoryIDs: []string{compatibilityCategoryID},
+		Purpose:     "Checks that fields do not use field numbers reserved by the organization.",
+		Type:        check.RuleTypeLint,
+		Handler:     checkutil.NewFieldRuleHandler(checkFieldNumberNotReserved),
+	}
+	// fieldSameNameRuleSpec is the RuleSpec for the field same name Rule.
+	fieldSameNameRuleSpec = &check.RuleSpec{
+		ID:          fieldSameNameRuleID,
+		CategoryIDs: []string{compatibilityCategoryID},
+		Default:     true,
+		Purpose:     "Checks that fields are not renamed, as renaming a field breaks JSON and source compatibility.",
+		Type:        check.RuleTypeBreaking,
+		Handler:     checkutil.NewFieldPairRuleHandler(checkFieldSameName),
+	}
+
+	// spec is the Spec for the API conventions plugin.
+	spec = &check.Spec{
+		Rules: []*check.RuleSpec{
+			messageNameLengthRuleSpec,
+			enumZeroValueSuffixRuleSpec,
+			enumZeroValueUnspecifiedRuleSpec,
+			fieldCommentedRuleSpec,
+			fieldNumberNotReservedRuleSpec,
+			fieldSameNameRuleSpec,
+		},
+		Categories: []*check.CategorySpec{
+			{
+				ID:      namingCategoryID,
+				Purpose: "Checks that elements follow the naming conventions of the organization.",
+			},
+			{
+				ID:      documentationCategoryID,
+				Purpose: "Checks that elements are documented.",
+			},
+			{
+				ID:      compatibilityCategoryID,
+				Purpose: "Checks that the schema stays compatible with existing clients.",
+			},
+		},
+		// Optional.
+		Info: &info.Spec{
+			URL:           "https://github.com/bufbuild/bufplugin-go",
+			SPDXLicenseID: "apache-2.0",
+			LicenseURL:    "https://github.com/bufbuild/bufplugin-go/blob/main/LICENSE",
+			DocShort:      "A plugin that enforces an organization's API conventions.",
+			DocLong: `A plugin that enforces an organization's API conventions.
+
+This plugin demonstrates the complete surface of the check API, including lint and breaking
+Rules, Categories, deprecation, and options of every type.`,
+		},
+		// Optional.
+		//
+		// Options are informational, and are included in the manifest of the plugin.
+		Options: []*check.OptionSpec{
+			{
+				Key:     maxMessageNameLengthOptionKey,
+				Purpose: "The maximum length of message names (default is 32).",
+				Type:    "int64",
+			},
+			{
+				Key:     enumZeroValueSuffixOptionKey,
+				Purpose: `The suffix that enum zero values must end in (default is "_UNSPECIFIED").`,
+				Type:    "string",
+			},
+			{
+				Key:     minCommentedFieldRatioOptionKey,
+				Purpose: "The minimum ratio of the fields of a message that must have comments, between 0 and 1 (default is 1).",
+				Type:    "float64",
+			},
+			{
+				Key:     allowTrailingCommentsOptionKey,
+				Purpose: "Whether trailing comments count as comments on fields (default is false).",
+				Type:    "bool",
+			},
+			{
+				Key:     exemptMessagesOptionKey,
+				Purpose: "The fully-qualified names of messages that are exempt from the documentation Rules.",
+				Type:    "[]string",
+			},
+			{
+				Key:     reservedFieldNumbersOptionKey,
+				Purpose: "The field numbers that are reserved by the organization.",
+				Type:    "[]int64",
+			},
+		},
+		// Optional.
+		//
+		// Recover from panics within RuleHandlers, so that a bug in a single Rule results in an
+		// error that names the Rule, instead of crashing the plugin.
+		RuleHandlerMiddlewares: []check.RuleHandlerMiddleware{
+			check.RecoverRuleHandlerMiddleware(),
+		},
+		// Optional.
+		//
+		// Skip imports in all RuleHandlers by default, as the user cannot fix findings within them.
+		Before: checkutil.DefaultIteratorOptionsBefore(checkutil.WithoutImports()),
+	}
+)
+
+func main() {
+	check.Main(spec)
+}
+
+func checkMessageNameLength(
+	_ context.Context,
+	responseWriter check.ResponseWriter,
+	request check.Request,
+	messageDescriptor protoreflect.MessageDescriptor,
+) error {
+	maxMessageNameLength, err := option.GetInt64Value(request.Options(), maxMessageNameLengthOptionKey)
+	if err != nil {
+		return err
+	}
+	if maxMessageNameLength == 0 {
+		maxMessageNameLength = defaultMaxMessageNameLength
+	}
+	if messageDescriptor.IsMapEntry() {
+		return nil
+	}
+	if messageName := string(messageDescriptor.Name()); int64(len(messageName)) > maxMessageNameLength {
+		responseWriter.AddAnnotation(
+			check.WithMessagef("Message name %q is longer than the maximum length of %d.", messageName, maxMessageNameLength),
+			check.WithDescriptor(messageDescriptor),
+		)
+	}
+	return nil
+}
+
+func checkEnumZeroValueSuffix(
+	_ context.Context,
+	responseWriter check.ResponseWriter,
+	request check.Request,
+	enumDescriptor protoreflect.EnumDescriptor,
+) error {
+	enumZeroValueSuffix, err := option.GetStringValue(request.Options(), enumZeroValueSuffixOptionKey)
+	if err != nil {
+		return err
+	}
+	if enumZeroValueSuffix == "" {
+		enumZeroValueSuffix = defaultEnumZeroValueSuffix
+	}
+	checkEnumZeroValueHasSuffix(responseWriter, enumDescriptor, enumZeroValueSuffix)
+	return nil
+}
+
+func checkEnumZeroValueUnspecified(
+	_ context.Context,
+	responseWriter check.ResponseWriter,
+	_ check.Request,
+	enumDescriptor protoreflect.EnumDescriptor,
+) error {
+	checkEnumZeroValueHasSuffix(responseWriter, enumDescriptor, defaultEnumZeroValueSuffix)
+	return nil
+}
+
+func checkEnumZeroValueHasSuffix(
+	responseWriter check.ResponseWriter,
+	enumDescriptor protoreflect.EnumDescriptor,
+	suffix string,
+) {
+	enumValueDescriptor := enumDescriptor.Values().ByNumber(0)
+	if enumValueDescriptor == nil {
+		// Only closed enums can lack a zero value.
+		return
+	}
+	if !strings.HasSuffix(string(enumValueDescriptor.Name()), suffix) {
+		responseWriter.AddAnnotation(
+			check.WithMessagef("Enum zero value %q should end in %q.", string(enumValueDescriptor.Name()), suffix),
+			check.WithDescriptor(enumValueDescriptor),
+		)
+	}
+}
+
+func checkFieldCommented(
+	_ context.Context,
+	responseWriter check.ResponseWriter,
+	request check.Request,
+	messageDescriptor protoreflect.MessageDescriptor,
+) error {
+	exemptMessages, err := option.GetStringSliceValue(request.Options(), exemptMessagesOptionKey)
+	if err != nil {
+		return err
+	}
+	if messageDescriptor.IsMapEntry() || slices.Contains(exemptMessages, string(messageDescriptor.FullName())) {
+		return nil
+	}
+	minCommentedFieldRatio, err := option.GetFloat64Value(request.Options(), minCommentedFieldRatioOptionKey)
+	if err != nil {
+		return err
+	}
+	if minCommentedFieldRatio == 0 {
+		minCommentedFieldRatio = defaultMinCommentedFieldRatio
+	}
+	allowTrailingComments, err := option.GetBoolValue(request.Options(), allowTrailingCommentsOptionKey)
+	if err != nil {
+		return err
+	}
+
+	fields := messageDescriptor.Fields()
+	if fields.Len() == 0 {
+		return nil
+	}
+	sourceLocations := messageDescriptor.ParentFile().SourceLocations()
+	var numCommented int
+	for i := 0; i < fields.Len(); i++ {
+		sourceLocation := sourceLocations.ByDescriptor(fields.Get(i))
+		if strings.TrimSpace(sourceLocation.LeadingComments) != "" ||
+			(allowTrailingComments && strings.TrimSpace(sourceLocation.TrailingComments) != "") {
+			numCommented++
+		}
+	}
+	if ratio := float64(numCommented) / float64(fields.Len()); ratio < minCommentedFieldRatio {
+		responseWriter.AddAnnotation(
+			check.WithMessagef(
+				"Message %q has %d of %d fields with comments, but at least %v of fields must have comments.",
+				string(messageDescriptor.FullName()),
+				numCommented,
+				fields.Len(),
+				minCommentedFieldRatio,
+			),
+			check.WithDescriptor(messageDescriptor),
+		)
+	}
+	return nil
+}
+
+func checkFieldNumberNotReserved(
+	_ context.Context,
+	responseWriter check.ResponseWriter,
+	request check.Request,
+	fieldDescriptor protoreflect.FieldDescriptor,
+) error {
+	reservedFieldNumbers, err := option.GetInt64SliceValue(request.Options(), reservedFieldNumbersOptionKey)
+	if err != nil {
+		return err
+	}
+	if slices.Contains(reservedFieldNumbers, int64(fieldDescriptor.Number())) {
+		responseWriter.AddAnnotation(
+			check.WithMessagef(
+				"Field %q uses field number %d, which is reserved by the organization.",
+				string(fieldDescriptor.FullName()),
+				fieldDescriptor.Number(),
+			),
+			check.WithDescriptor(fieldDescriptor),
+		)
+	}
+	return nil
+}
+
+func checkFieldSameName(
+	_ context.Context,
+	responseWriter check.ResponseWriter,
+	_ check.Request,
+	fieldDescriptor protoreflect.FieldDescriptor,
+	againstFieldDescriptor protoreflect.FieldDescriptor,
+) error {
+	if fieldDescriptor.Name() != againstFieldDescriptor.Name() {
+		responseWriter.AddAnnotation(
+			check.WithMessagef(
+				"Field %d on message %q changed name from %q to %q.",
+				fieldDescriptor.Number(),
+				string(fieldDescriptor.ContainingMessage().FullName()),
+				string(againstFieldDescriptor.Name()),
+				string(fieldDescriptor.Name()),
+			),
+			check.WithDescriptor(fieldDescriptor),
+			check.WithAgainstDescriptor(againstFieldDescriptor),
+		)
+	}
+	return nil
+}
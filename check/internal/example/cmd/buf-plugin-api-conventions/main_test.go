@@ -0,0 +1,403 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+
+	"buf.build/go/bufplugin/check"
+	"buf.build/go/bufplugin/check/checktest"
+	"buf.build/go/bufplugin/info"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMain(m *testing.M) {
+	// Every Rule must be covered by at least one test.
+	os.Exit(checktest.RunWithRuleCoverage(m, spec))
+}
+
+func TestSpec(t *testing.T) {
+	t.Parallel()
+	checktest.SpecTest(t, spec)
+}
+
+func TestInventory(t *testing.T) {
+	t.Parallel()
+
+	checktest.InventoryTest{
+		Spec:           spec,
+		GoldenFilePath: "testdata/inventory.golden.json",
+	}.Run(t)
+}
+
+func TestInfo(t *testing.T) {
+	t.Parallel()
+
+	checktest.InfoTest{
+		Spec: spec,
+		Expected: &info.Spec{
+			URL:           "https://github.com/bufbuild/bufplugin-go",
+			SPDXLicenseID: "Apache-2.0",
+			LicenseURL:    "https://github.com/bufbuild/bufplugin-go/blob/main/LICENSE",
+			DocShort:      "A plugin that enforces an organization's API conventions.",
+			DocLong:       spec.Info.DocLong,
+		},
+	}.Run(t)
+}
+
+func TestManifest(t *testing.T) {
+	t.Parallel()
+
+	manifest, err := check.ManifestForSpec(context.Background(), spec)
+	require.NoError(t, err)
+	assert.Len(t, manifest.Rules, len(spec.Rules))
+	assert.Len(t, manifest.Categories, len(spec.Categories))
+	assert.Len(t, manifest.Options, len(spec.Options))
+	require.NotNil(t, manifest.Info)
+	assert.Equal(t, "https://github.com/bufbuild/bufplugin-go", manifest.Info.URL)
+}
+
+func TestLint(t *testing.T) {
+	t.Parallel()
+
+	// Only the default Rules are run, and every Annotation is declared with a want comment.
+	// The Spec is called over OS pipes as if it was a separate process.
+	checktest.CheckTest{
+		Request: &checktest.RequestSpec{
+			Files: &checktest.ProtoFileSpec{
+				DirPaths:  []string{"testdata/lint"},
+				FilePaths: []string{"lint.proto"},
+			},
+		},
+		Spec:         spec,
+		Wire:         true,
+		WantComments: true,
+	}.Run(t)
+}
+
+func TestBefore(t *testing.T) {
+	t.Parallel()
+
+	// Before sets the default IteratorOptions on a new Context, and passes the Request through.
+	var inputCtx context.Context
+	var inputRequest check.Request
+	checktest.CheckTest{
+		Request: &checktest.RequestSpec{
+			Files: &checktest.ProtoFileSpec{
+				DirPaths:  []string{"testdata/lint"},
+				FilePaths: []string{"lint.proto"},
+			},
+		},
+		Spec:         spec,
+		WantComments: true,
+		AssertBeforeInput: func(t *testing.T, ctx context.Context, request check.Request) {
+			inputCtx = ctx
+			inputRequest = request
+		},
+		AssertBeforeOutput: func(t *testing.T, ctx context.Context, request check.Request, err error) {
+			require.NoError(t, err)
+			assert.NotEqual(t, inputCtx, ctx)
+			assert.Equal(t, inputRequest, request)
+		},
+	}.Run(t)
+}
+
+func TestBeforeError(t *testing.T) {
+	t.Parallel()
+
+	errorSpec := *spec
+	errorSpec.Before = func(ctx context.Context, request check.Request) (context.Context, check.Request, error) {
+		return nil, nil, errors.New("before failed")
+	}
+	checktest.CheckTest{
+		Request: &checktest.RequestSpec{
+			Files: &checktest.ProtoFileSpec{
+				DirPaths:  []string{"testdata/lint"},
+				FilePaths: []string{"lint.proto"},
+			},
+		},
+		Spec: &errorSpec,
+		Wire: true,
+		ExpectedError: &checktest.ExpectedError{
+			MessageContains: "before failed",
+		},
+		AssertBeforeOutput: func(t *testing.T, ctx context.Context, request check.Request, err error) {
+			assert.EqualError(t, err, "before failed")
+		},
+	}.Run(t)
+}
+
+func TestOptions(t *testing.T) {
+	t.Parallel()
+
+	legacyLocation := &checktest.ExpectedFileLocation{
+		FileName:    "options.proto",
+		StartLine:   5,
+		StartColumn: 0,
+		EndLine:     7,
+		EndColumn:   1,
+	}
+	orderStateUnknownLocation := &checktest.ExpectedFileLocation{
+		FileName:    "options.proto",
+		StartLine:   19,
+		StartColumn: 2,
+		EndLine:     19,
+		EndColumn:   26,
+	}
+
+	// The files are compiled once and shared by all cases, which run in parallel.
+	checktest.CheckTests{
+		Files: &checktest.ProtoFileSpec{
+			DirPaths:  []string{"testdata/options"},
+			FilePaths: []string{"options.proto"},
+		},
+		Spec:             spec,
+		Parallel:         true,
+		ReportTiming:     true,
+		ReportRuleCounts: true,
+		Cases: []checktest.CheckTestCase{
+			{
+				Name: "default",
+				ExpectedAnnotations: []checktest.ExpectedAnnotation{
+					{
+						RuleID:       enumZeroValueSuffixRuleID,
+						Message:      `Enum zero value "ORDER_STATE_UNKNOWN" should end in "_UNSPECIFIED".`,
+						FileLocation: orderStateUnknownLocation,
+					},
+				},
+			},
+			{
+				Name:    "max_message_name_length",
+				RuleIDs: []string{messageNameLengthRuleID},
+				Options: map[string]any{
+					maxMessageNameLengthOptionKey: int64(5),
+				},
+				ExpectedAnnotations: []checktest.ExpectedAnnotation{
+					{
+						RuleID:          messageNameLengthRuleID,
+						MessageContains: "maximum length of 5",
+						FileLocation:    legacyLocation,
+					},
+				},
+			},
+			{
+				Name:    "enum_zero_value_suffix",
+				RuleIDs: []string{enumZeroValueSuffixRuleID},
+				Options: map[string]any{
+					enumZeroValueSuffixOptionKey: "_UNKNOWN",
+				},
+			},
+			{
+				// The deprecated Rule does not respect the option, which is why it was replaced.
+				Name:    "deprecated",
+				RuleIDs: []string{enumZeroValueUnspecifiedRuleID},
+				Options: map[string]any{
+					enumZeroValueSuffixOptionKey: "_UNKNOWN",
+				},
+				ExpectedAnnotations: []checktest.ExpectedAnnotation{
+					{
+						RuleID:       enumZeroValueUnspecifiedRuleID,
+						FileLocation: orderStateUnknownLocation,
+					},
+				},
+			},
+			{
+				// The expectations are maintained in a fixture file instead of Go code.
+				Name:                        "field_commented",
+				RuleIDs:                     []string{fieldCommentedRuleID},
+				ExpectedAnnotationsFilePath: "testdata/fixtures/field_commented.yaml",
+			},
+			{
+				Name:    "field_commented_with_options",
+				RuleIDs: []string{fieldCommentedRuleID},
+				Options: map[string]any{
+					minCommentedFieldRatioOptionKey: 0.5,
+					allowTrailingCommentsOptionKey:  true,
+					exemptMessagesOptionKey:         []string{"acme.api.v1.Legacy"},
+				},
+			},
+			{
+				Name:    "reserved_field_numbers",
+				RuleIDs: []string{fieldNumberNotReservedRuleID},
+				Options: map[string]any{
+					reservedFieldNumbersOptionKey: []int64{9999},
+				},
+				ExpectedAnnotations: []checktest.ExpectedAnnotation{
+					{
+						RuleID: fieldNumberNotReservedRuleID,
+						FileLocation: &checktest.ExpectedFileLocation{
+							FileName:    "options.proto",
+							StartLine:   14,
+							StartColumn: 2,
+							EndLine:     14,
+							EndColumn:   21,
+						},
+					},
+				},
+			},
+		},
+	}.Run(t)
+}
+
+func TestConfig(t *testing.T) {
+	t.Parallel()
+
+	// The deprecated API_ENUM_ZERO_VALUE_UNSPECIFIED is not selected through API_NAMING, and
+	// API_FIELD_COMMENTED is ignored for options.proto only.
+	checktest.CheckTest{
+		Request: &checktest.RequestSpec{
+			Files: &checktest.ProtoFileSpec{
+				DirPaths:  []string{"testdata/lint", "testdata/options"},
+				FilePaths: []string{"lint.proto", "options.proto"},
+			},
+			Config: &checktest.Config{
+				Use:    []string{namingCategoryID, documentationCategoryID},
+				Except: []string{messageNameLengthRuleID},
+				IgnoreOnly: map[string][]string{
+					documentationCategoryID: {"options.proto"},
+				},
+			},
+		},
+		Spec: spec,
+		ExpectedAnnotations: []checktest.ExpectedAnnotation{
+			{
+				RuleID: enumZeroValueSuffixRuleID,
+				FileLocation: &checktest.ExpectedFileLocation{
+					FileName:    "lint.proto",
+					StartLine:   14,
+					StartColumn: 2,
+					EndLine:     14,
+					EndColumn:   30,
+				},
+			},
+			{
+				RuleID: enumZeroValueSuffixRuleID,
+				FileLocation: &checktest.ExpectedFileLocation{
+					FileName:    "options.proto",
+					StartLine:   19,
+					StartColumn: 2,
+					EndLine:     19,
+					EndColumn:   26,
+				},
+			},
+		},
+	}.Run(t)
+}
+
+func TestOptionsMatrix(t *testing.T) {
+	t.Parallel()
+
+	// The same enum zero value is checked across suffix configurations, and only the
+	// configuration that accepts the suffix produces no Annotations.
+	checktest.CheckTest{
+		Request: &checktest.RequestSpec{
+			Files: &checktest.ProtoFileSpec{
+				DirPaths:  []string{"testdata/options"},
+				FilePaths: []string{"options.proto"},
+			},
+			RuleIDs: []string{enumZeroValueSuffixRuleID},
+		},
+		Spec: spec,
+		ExpectedAnnotations: []checktest.ExpectedAnnotation{
+			{
+				RuleID: enumZeroValueSuffixRuleID,
+				FileLocation: &checktest.ExpectedFileLocation{
+					FileName:    "options.proto",
+					StartLine:   19,
+					StartColumn: 2,
+					EndLine:     19,
+					EndColumn:   26,
+				},
+			},
+		},
+		OptionsMatrix: []checktest.OptionsConfiguration{
+			{},
+			{
+				Options: map[string]any{
+					enumZeroValueSuffixOptionKey: "_UNSET",
+				},
+			},
+			{
+				Options: map[string]any{
+					enumZeroValueSuffixOptionKey: "_NONE",
+				},
+			},
+			{
+				Options: map[string]any{
+					enumZeroValueSuffixOptionKey: "_UNKNOWN",
+				},
+				ExpectedAnnotations: []checktest.ExpectedAnnotation{},
+			},
+		},
+	}.Run(t)
+}
+
+func TestBreaking(t *testing.T) {
+	t.Parallel()
+
+	docURLResolver, err := check.DocURLResolverForPattern("https://github.com/bufbuild/bufplugin-go/rules/{rule_id}")
+	require.NoError(t, err)
+
+	checktest.CheckTest{
+		Request: &checktest.RequestSpec{
+			Files: &checktest.ProtoFileSpec{
+				DirPaths:  []string{"testdata/breaking/current"},
+				FilePaths: []string{"user.proto"},
+			},
+			AgainstFiles: &checktest.ProtoFileSpec{
+				DirPaths:  []string{"testdata/breaking/previous"},
+				FilePaths: []string{"user.proto"},
+			},
+			RuleIDs: []string{fieldSameNameRuleID},
+		},
+		Spec: spec,
+		// The golden file includes the documentation URL attached by the Client.
+		ClientOptions: []check.ClientOption{
+			check.ClientWithDocURLResolver(docURLResolver),
+		},
+		GoldenFilePath: "testdata/breaking/annotations.golden.json",
+	}.Run(t)
+}
+
+func TestDeprecation(t *testing.T) {
+	t.Parallel()
+
+	// API_ENUM_ZERO_VALUE_SUFFIX must find every enum zero value that
+	// API_ENUM_ZERO_VALUE_UNSPECIFIED finds.
+	checktest.DeprecationTest{
+		Request: &checktest.RequestSpec{
+			Files: &checktest.ProtoFileSpec{
+				DirPaths:  []string{"testdata/lint", "testdata/options"},
+				FilePaths: []string{"lint.proto", "options.proto"},
+			},
+		},
+		Spec: spec,
+	}.Run(t)
+}
+
+func TestFuzz(t *testing.T) {
+	t.Parallel()
+
+	checktest.FuzzTest{
+		Spec: spec,
+		Options: map[string]any{
+			reservedFieldNumbersOptionKey: []int64{1, 2, 3},
+		},
+	}.Run(t)
+}
@@ -61,6 +61,10 @@ var (
 			fieldLowerSnakeCaseRuleSpec,
 		},
 		// Optional.
+		//
+		// Allows users to vendor files with the vendored_paths and vendored_packages options.
+		VendoredOptions: true,
+		// Optional.
 		Info: &info.Spec{
 			URL:           "https://github.com/bufbuild/bufplugin-go",
 			SPDXLicenseID: "apache-2.0",
@@ -15,9 +15,18 @@
 package main
 
 import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
 	"testing"
 
+	"buf.build/go/bufplugin/check"
 	"buf.build/go/bufplugin/check/checktest"
+	"buf.build/go/bufplugin/descriptor/descriptortest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/reflect/protoreflect"
 )
 
 func TestSpec(t *testing.T) {
@@ -50,3 +59,481 @@ func TestSimple(t *testing.T) {
 		},
 	}.Run(t)
 }
+
+func TestSyntaxes(t *testing.T) {
+	t.Parallel()
+
+	checktest.CheckTest{
+		Request: &checktest.RequestSpec{
+			Files: &checktest.ProtoFileSpec{
+				DirPaths:  []string{"testdata/simple"},
+				FilePaths: []string{"simple.proto"},
+			},
+		},
+		Spec:     spec,
+		Syntaxes: descriptortest.AllSyntaxes,
+		ExpectedAnnotations: []checktest.ExpectedAnnotation{
+			{
+				RuleID: fieldLowerSnakeCaseRuleID,
+				FileLocation: &checktest.ExpectedFileLocation{
+					FileName:    "simple.proto",
+					StartLine:   6,
+					StartColumn: 2,
+					EndLine:     6,
+					EndColumn:   23,
+				},
+			},
+		},
+	}.Run(t)
+}
+
+func TestSourcePath(t *testing.T) {
+	t.Parallel()
+
+	checktest.CheckTest{
+		Request: &checktest.RequestSpec{
+			Files: &checktest.ProtoFileSpec{
+				DirPaths:  []string{"testdata/simple"},
+				FilePaths: []string{"simple.proto"},
+			},
+		},
+		Spec: spec,
+		ExpectedAnnotations: []checktest.ExpectedAnnotation{
+			{
+				RuleID: fieldLowerSnakeCaseRuleID,
+				FileLocation: &checktest.ExpectedFileLocation{
+					FileName: "simple.proto",
+					// The second field of the first message, which is stable if simple.proto is reformatted.
+					SourcePath: protoreflect.SourcePath{4, 0, 2, 1},
+					IgnoreSpan: true,
+				},
+			},
+		},
+	}.Run(t)
+}
+
+func TestBinary(t *testing.T) {
+	t.Parallel()
+	if testing.Short() {
+		t.Skip("skipping building the plugin binary in short mode")
+	}
+
+	binary := filepath.Join(t.TempDir(), "buf-plugin-field-lower-snake-case")
+	output, err := exec.Command("go", "build", "-o", binary, ".").CombinedOutput()
+	require.NoError(t, err, string(output))
+
+	checktest.CheckTest{
+		Request: &checktest.RequestSpec{
+			Files: &checktest.ProtoFileSpec{
+				DirPaths:  []string{"testdata/simple"},
+				FilePaths: []string{"simple.proto"},
+			},
+		},
+		Binary: binary,
+		ExpectedAnnotations: []checktest.ExpectedAnnotation{
+			{
+				RuleID: fieldLowerSnakeCaseRuleID,
+				FileLocation: &checktest.ExpectedFileLocation{
+					FileName:    "simple.proto",
+					StartLine:   6,
+					StartColumn: 2,
+					EndLine:     6,
+					EndColumn:   23,
+				},
+			},
+		},
+	}.Run(t)
+}
+
+func TestBinaryArgs(t *testing.T) {
+	t.Parallel()
+	if testing.Short() {
+		t.Skip("skipping building the plugin binary in short mode")
+	}
+
+	dirPath := t.TempDir()
+	binary := filepath.Join(dirPath, "buf-plugin-field-lower-snake-case")
+	output, err := exec.Command("go", "build", "-o", binary, ".").CombinedOutput()
+	require.NoError(t, err, string(output))
+	// A binary that implements the plugin under the field-lower-snake-case sub-command.
+	multiPluginBinary := filepath.Join(dirPath, "buf-plugins")
+	require.NoError(
+		t,
+		os.WriteFile(
+			multiPluginBinary,
+			[]byte(`#!/bin/sh
+if [ "$1" != "field-lower-snake-case" ]; then
+  echo "unknown sub-command: $1" >&2
+  exit 1
+fi
+shift
+exec "`+binary+`" "$@"
+`),
+			0o700,
+		),
+	)
+
+	checktest.CheckTest{
+		Request: &checktest.RequestSpec{
+			Files: &checktest.ProtoFileSpec{
+				DirPaths:  []string{"testdata/simple"},
+				FilePaths: []string{"simple.proto"},
+			},
+		},
+		Binary:     multiPluginBinary,
+		BinaryArgs: []string{"field-lower-snake-case"},
+		ExpectedAnnotations: []checktest.ExpectedAnnotation{
+			{
+				RuleID: fieldLowerSnakeCaseRuleID,
+				FileLocation: &checktest.ExpectedFileLocation{
+					FileName:    "simple.proto",
+					StartLine:   6,
+					StartColumn: 2,
+					EndLine:     6,
+					EndColumn:   23,
+				},
+			},
+		},
+	}.Run(t)
+	checktest.InfoTest{
+		Binary:     multiPluginBinary,
+		BinaryArgs: []string{"field-lower-snake-case"},
+		Expected:   spec.Info,
+	}.Run(t)
+}
+
+func TestNotFired(t *testing.T) {
+	t.Parallel()
+
+	checktest.CheckTest{
+		Request: &checktest.RequestSpec{
+			Files: &checktest.ProtoFileSpec{
+				DirPaths:  []string{"testdata/simple"},
+				FilePaths: []string{"simple.proto"},
+			},
+		},
+		Spec: spec,
+		ForbiddenAnnotations: []checktest.ExpectedAnnotation{
+			{
+				RuleID: fieldLowerSnakeCaseRuleID,
+				FileLocation: &checktest.ExpectedFileLocation{
+					FileName:    "simple.proto",
+					StartLine:   5,
+					StartColumn: 2,
+					EndLine:     5,
+					EndColumn:   29,
+				},
+			},
+		},
+	}.Run(t)
+}
+
+func TestWantComments(t *testing.T) {
+	t.Parallel()
+
+	checktest.CheckTest{
+		Request: &checktest.RequestSpec{
+			Files: &checktest.ProtoFileSpec{
+				DirPaths:  []string{"testdata/want"},
+				FilePaths: []string{"want.proto"},
+			},
+		},
+		Spec:         spec,
+		WantComments: true,
+	}.Run(t)
+}
+
+func TestSuppressed(t *testing.T) {
+	t.Parallel()
+
+	checktest.CheckTest{
+		Request: &checktest.RequestSpec{
+			Files: &checktest.ProtoFileSpec{
+				Sources: map[string]string{
+					"a/a.proto": `syntax = "proto3";
+package a;
+message Foo {
+  string badName = 1;
+}
+`,
+					"third_party/b.proto": `syntax = "proto3";
+package b;
+message Bar {
+  string badName = 1;
+}
+`,
+					"google/type/c.proto": `syntax = "proto3";
+package google.type;
+message Baz {
+  string badName = 1;
+}
+`,
+				},
+			},
+			Options: map[string]any{
+				check.VendoredPathsOptionKey:    []string{"third_party"},
+				check.VendoredPackagesOptionKey: []string{"google"},
+			},
+		},
+		Spec: spec,
+		ExpectedAnnotations: []checktest.ExpectedAnnotation{
+			{
+				RuleID: fieldLowerSnakeCaseRuleID,
+				FileLocation: &checktest.ExpectedFileLocation{
+					FileName:    "a/a.proto",
+					StartLine:   3,
+					StartColumn: 2,
+					EndLine:     3,
+					EndColumn:   21,
+				},
+			},
+		},
+		SuppressedAnnotations: []checktest.ExpectedSuppressedAnnotation{
+			{
+				Annotation: checktest.ExpectedAnnotation{
+					RuleID: fieldLowerSnakeCaseRuleID,
+					FileLocation: &checktest.ExpectedFileLocation{
+						FileName:    "third_party/b.proto",
+						StartLine:   3,
+						StartColumn: 2,
+						EndLine:     3,
+						EndColumn:   21,
+					},
+				},
+				Reason: checktest.SuppressionReasonVendoredPath,
+			},
+			{
+				Annotation: checktest.ExpectedAnnotation{
+					RuleID: fieldLowerSnakeCaseRuleID,
+					FileLocation: &checktest.ExpectedFileLocation{
+						FileName:    "google/type/c.proto",
+						StartLine:   3,
+						StartColumn: 2,
+						EndLine:     3,
+						EndColumn:   21,
+					},
+				},
+				Reason: checktest.SuppressionReasonVendoredPackage,
+			},
+		},
+	}.Run(t)
+}
+
+func TestFileDescriptorSet(t *testing.T) {
+	t.Parallel()
+
+	checktest.CheckTest{
+		Request: &checktest.RequestSpec{
+			Files: &checktest.ProtoFileSpec{
+				// Built from testdata/simple/simple.proto.
+				FileDescriptorSetPath: "testdata/simple/simple.binpb",
+			},
+		},
+		Spec: spec,
+		ExpectedAnnotations: []checktest.ExpectedAnnotation{
+			{
+				RuleID: fieldLowerSnakeCaseRuleID,
+				FileLocation: &checktest.ExpectedFileLocation{
+					FileName:    "simple.proto",
+					StartLine:   6,
+					StartColumn: 2,
+					EndLine:     6,
+					EndColumn:   23,
+				},
+			},
+		},
+	}.Run(t)
+}
+
+func TestImage(t *testing.T) {
+	t.Parallel()
+
+	checktest.CheckTest{
+		Request: &checktest.RequestSpec{
+			Files: &checktest.ProtoFileSpec{
+				// A buf image containing a/a.proto, and b/b.proto marked as an import.
+				FileDescriptorSetPath: "testdata/image/image.binpb",
+			},
+		},
+		Spec: spec,
+		ExpectedAnnotations: []checktest.ExpectedAnnotation{
+			{
+				RuleID: fieldLowerSnakeCaseRuleID,
+				FileLocation: &checktest.ExpectedFileLocation{
+					FileName:    "a/a.proto",
+					StartLine:   5,
+					StartColumn: 2,
+					EndLine:     5,
+					EndColumn:   21,
+				},
+			},
+		},
+	}.Run(t)
+}
+
+func TestClientEnrichment(t *testing.T) {
+	t.Parallel()
+
+	ownership, err := check.NewOwnership(
+		[]check.OwnershipRule{
+			{
+				Path:   "simple.proto",
+				Owners: []string{"@acme/api"},
+			},
+		},
+	)
+	require.NoError(t, err)
+	docURLResolver, err := check.DocURLResolverForPattern("https://wiki.example.com/lint/{rule_id}")
+	require.NoError(t, err)
+
+	checktest.CheckTest{
+		Request: &checktest.RequestSpec{
+			Files: &checktest.ProtoFileSpec{
+				DirPaths:  []string{"testdata/simple"},
+				FilePaths: []string{"simple.proto"},
+			},
+		},
+		Spec: spec,
+		ClientOptions: []check.ClientOption{
+			check.ClientWithOwnership(ownership),
+			check.ClientWithDocURLResolver(docURLResolver),
+		},
+		ExpectedAnnotations: []checktest.ExpectedAnnotation{
+			{
+				RuleID: fieldLowerSnakeCaseRuleID,
+				FileLocation: &checktest.ExpectedFileLocation{
+					FileName:    "simple.proto",
+					StartLine:   6,
+					StartColumn: 2,
+					EndLine:     6,
+					EndColumn:   23,
+				},
+				Owners: []string{"@acme/api"},
+				DocURL: "https://wiki.example.com/lint/" + fieldLowerSnakeCaseRuleID,
+			},
+		},
+	}.Run(t)
+}
+
+func TestInline(t *testing.T) {
+	t.Parallel()
+
+	checktest.CheckTest{
+		Request: &checktest.RequestSpec{
+			Files: &checktest.ProtoFileSpec{
+				Sources: map[string]string{
+					"a/a.proto": `syntax = "proto3";
+package a;
+import "b/b.proto";
+message Foo {
+  b.Bar bar = 1;
+  string badName = 2;
+}
+`,
+					"b/b.proto": `syntax = "proto3";
+package b;
+message Bar {}
+`,
+				},
+			},
+		},
+		Spec: spec,
+		ExpectedAnnotations: []checktest.ExpectedAnnotation{
+			{
+				RuleID: fieldLowerSnakeCaseRuleID,
+				FileLocation: &checktest.ExpectedFileLocation{
+					FileName:    "a/a.proto",
+					StartLine:   5,
+					StartColumn: 2,
+					EndLine:     5,
+					EndColumn:   21,
+				},
+			},
+		},
+	}.Run(t)
+}
+
+func TestFuzz(t *testing.T) {
+	t.Parallel()
+
+	checktest.FuzzTest{
+		Spec: spec,
+	}.Run(t)
+}
+
+// Memory statistics are process-wide, so the memory tests do not call t.Parallel().
+
+func TestMemory(t *testing.T) {
+	fileDescriptors, err := checktest.NewSyntheticFileDescriptors(20, 20, 20)
+	require.NoError(t, err)
+	checktest.MemoryTest{
+		Spec:            spec,
+		FileDescriptors: fileDescriptors,
+		// About 14MB is allocated, and about 12MB is in use at the peak.
+		MaxTotalAllocatedBytes: 64 << 20,
+		MaxPeakHeapBytes:       64 << 20,
+	}.Run(t)
+}
+
+func TestMemoryExceeded(t *testing.T) {
+	fileDescriptors, err := checktest.NewSyntheticFileDescriptors(20, 20, 20)
+	require.NoError(t, err)
+	testCases := []struct {
+		name          string
+		memoryTest    checktest.MemoryTest
+		expectedError string
+	}{
+		{
+			name: "max_total_allocated_bytes",
+			memoryTest: checktest.MemoryTest{
+				Spec:                   spec,
+				FileDescriptors:        fileDescriptors,
+				MaxTotalAllocatedBytes: 1 << 10,
+			},
+			expectedError: "total allocated bytes exceeded MaxTotalAllocatedBytes",
+		},
+		{
+			name: "max_peak_heap_bytes",
+			memoryTest: checktest.MemoryTest{
+				Spec:             spec,
+				FileDescriptors:  fileDescriptors,
+				MaxPeakHeapBytes: 1 << 10,
+			},
+			expectedError: "peak heap bytes exceeded MaxPeakHeapBytes",
+		},
+	}
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			recordingTB := &testRecordingTB{TB: t}
+			testCase.memoryTest.Run(recordingTB)
+			require.Len(t, recordingTB.errors, 1)
+			assert.Contains(t, recordingTB.errors[0], testCase.expectedError)
+		})
+	}
+}
+
+func BenchmarkFieldLowerSnakeCase(b *testing.B) {
+	checktest.BenchmarkRule(
+		b,
+		spec,
+		&checktest.RequestSpec{
+			Files: &checktest.ProtoFileSpec{
+				DirPaths:  []string{"testdata/simple"},
+				FilePaths: []string{"simple.proto"},
+			},
+		},
+		fieldLowerSnakeCaseRuleID,
+	)
+}
+
+// testRecordingTB is a testing.TB that records errors instead of failing the test.
+type testRecordingTB struct {
+	testing.TB
+
+	errors []string
+}
+
+func (r *testRecordingTB) Errorf(format string, args ...any) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
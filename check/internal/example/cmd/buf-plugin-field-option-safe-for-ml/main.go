@@ -81,7 +81,7 @@ var (
 			fieldOptionSafeForMLCategoryID,
 		},
 		Type:    check.RuleTypeLint,
-		Handler: checkutil.NewFieldRuleHandler(checkFieldOptionSafeForMLSet, checkutil.WithoutImports()),
+		Handler: checkutil.NewFieldRuleHandler(checkFieldOptionSafeForMLSet),
 	}
 	// fieldOptionSafeForMLStaysTrueRuleSpec is the RuleSpec for the field option safe for ML stays  true Rule.
 	fieldOptionSafeForMLStaysTrueRuleSpec = &check.RuleSpec{
@@ -92,7 +92,7 @@ var (
 			fieldOptionSafeForMLCategoryID,
 		},
 		Type:    check.RuleTypeBreaking,
-		Handler: checkutil.NewFieldPairRuleHandler(checkFieldOptionSafeForMLStaysTrue, checkutil.WithoutImports()),
+		Handler: checkutil.NewFieldPairRuleHandler(checkFieldOptionSafeForMLStaysTrue),
 	}
 	fieldOptionSafeForMLCategorySpec = &check.CategorySpec{
 		ID:      fieldOptionSafeForMLCategoryID,
@@ -114,6 +114,9 @@ var (
 			SPDXLicenseID: "apache-2.0",
 			LicenseURL:    "https://github.com/bufbuild/bufplugin-go/blob/main/LICENSE",
 		},
+		// Applies WithoutImports() to every RuleHandler, so that it does not need to be
+		// passed to each one individually.
+		Before: checkutil.DefaultIteratorOptionsBefore(checkutil.WithoutImports()),
 	}
 )
 
@@ -155,3 +155,35 @@ func TestChangeFailure(t *testing.T) {
 		},
 	}.Run(t)
 }
+
+func TestChangeFailureGolden(t *testing.T) {
+	t.Parallel()
+
+	checktest.CheckTest{
+		Request: &checktest.RequestSpec{
+			Files: &checktest.ProtoFileSpec{
+				DirPaths: []string{
+					"../../proto",
+					"testdata/change_failure/current",
+				},
+				FilePaths: []string{
+					"acme/option/v1/option.proto",
+					"simple.proto",
+				},
+			},
+			AgainstFiles: &checktest.ProtoFileSpec{
+				DirPaths: []string{
+					"../../proto",
+					"testdata/change_failure/previous",
+				},
+				FilePaths: []string{
+					"acme/option/v1/option.proto",
+					"simple.proto",
+				},
+			},
+		},
+		Spec: spec,
+		// Run with -checktest.update to regenerate.
+		GoldenFilePath: "testdata/change_failure/annotations.golden.json",
+	}.Run(t)
+}
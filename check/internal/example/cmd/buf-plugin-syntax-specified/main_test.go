@@ -15,11 +15,17 @@
 package main
 
 import (
+	"embed"
 	"testing"
 
 	"buf.build/go/bufplugin/check/checktest"
 )
 
+// testdataFS is the testdata embedded within the test binary.
+//
+//go:embed testdata
+var testdataFS embed.FS
+
 func TestSpec(t *testing.T) {
 	t.Parallel()
 	checktest.SpecTest(t, spec)
@@ -60,3 +66,27 @@ func TestSimpleFailure(t *testing.T) {
 		},
 	}.Run(t)
 }
+
+func TestSimpleFailureEmbedded(t *testing.T) {
+	t.Parallel()
+
+	// The testdata is read from the test binary instead of the local file system.
+	checktest.CheckTest{
+		Request: &checktest.RequestSpec{
+			Files: &checktest.ProtoFileSpec{
+				FS:        testdataFS,
+				DirPaths:  []string{"testdata/simple_failure"},
+				FilePaths: []string{"simple.proto"},
+			},
+		},
+		Spec: spec,
+		ExpectedAnnotations: []checktest.ExpectedAnnotation{
+			{
+				RuleID: syntaxSpecifiedRuleID,
+				FileLocation: &checktest.ExpectedFileLocation{
+					FileName: "simple.proto",
+				},
+			},
+		},
+	}.Run(t)
+}
@@ -15,9 +15,11 @@
 package main
 
 import (
+	"regexp"
 	"testing"
 
 	"buf.build/go/bufplugin/check/checktest"
+	"pluginrpc.com/pluginrpc"
 )
 
 func TestSpec(t *testing.T) {
@@ -54,6 +56,35 @@ func TestSimple(t *testing.T) {
 	}.Run(t)
 }
 
+func TestSimpleSubtestGrouping(t *testing.T) {
+	t.Parallel()
+
+	checktest.CheckTest{
+		Request: &checktest.RequestSpec{
+			Files: &checktest.ProtoFileSpec{
+				DirPaths:  []string{"testdata/simple"},
+				FilePaths: []string{"simple.proto"},
+			},
+		},
+		Spec: spec,
+		ExpectedAnnotations: []checktest.ExpectedAnnotation{
+			{
+				RuleID: timestampSuffixRuleID,
+				FileLocation: &checktest.ExpectedFileLocation{
+					FileName:    "simple.proto",
+					StartLine:   8,
+					StartColumn: 2,
+					EndLine:     8,
+					EndColumn:   50,
+				},
+			},
+		},
+		// Verify the Annotations of each Rule and file in a separate subtest, so that
+		// failures identify the Rule and file that regressed.
+		SubtestGrouping: checktest.SubtestGroupingRuleAndFile,
+	}.Run(t)
+}
+
 func TestOption(t *testing.T) {
 	t.Parallel()
 
@@ -82,3 +113,131 @@ func TestOption(t *testing.T) {
 		},
 	}.Run(t)
 }
+
+func TestMessagePattern(t *testing.T) {
+	t.Parallel()
+
+	checktest.CheckTest{
+		Request: &checktest.RequestSpec{
+			Files: &checktest.ProtoFileSpec{
+				DirPaths:  []string{"testdata/simple"},
+				FilePaths: []string{"simple.proto"},
+			},
+		},
+		Spec: spec,
+		ExpectedAnnotations: []checktest.ExpectedAnnotation{
+			{
+				RuleID:         timestampSuffixRuleID,
+				MessagePattern: regexp.MustCompile(`^Fields of type google\.protobuf\.Timestamp must end in "_time" but field name was "\w+"\.$`),
+				FileLocation: &checktest.ExpectedFileLocation{
+					FileName:    "simple.proto",
+					StartLine:   8,
+					StartColumn: 2,
+					EndLine:     8,
+					EndColumn:   50,
+				},
+			},
+		},
+	}.Run(t)
+}
+
+func TestMessageContains(t *testing.T) {
+	t.Parallel()
+
+	checktest.CheckTest{
+		Request: &checktest.RequestSpec{
+			Files: &checktest.ProtoFileSpec{
+				DirPaths:  []string{"testdata/option"},
+				FilePaths: []string{"option.proto"},
+			},
+			Options: map[string]any{
+				timestampSuffixOptionKey: "_timestamp",
+			},
+		},
+		Spec: spec,
+		ExpectedAnnotations: []checktest.ExpectedAnnotation{
+			{
+				RuleID:          timestampSuffixRuleID,
+				MessageContains: `must end in "_timestamp"`,
+				FileLocation: &checktest.ExpectedFileLocation{
+					FileName:    "option.proto",
+					StartLine:   8,
+					StartColumn: 2,
+					EndLine:     8,
+					EndColumn:   45,
+				},
+			},
+		},
+	}.Run(t)
+}
+
+func TestOptionCases(t *testing.T) {
+	t.Parallel()
+
+	// The files are compiled once and shared between the cases.
+	checktest.CheckTests{
+		Files: &checktest.ProtoFileSpec{
+			DirPaths:  []string{"testdata/option"},
+			FilePaths: []string{"option.proto"},
+		},
+		Spec: spec,
+		Cases: []checktest.CheckTestCase{
+			{
+				Name: "default",
+				ExpectedAnnotations: []checktest.ExpectedAnnotation{
+					{
+						RuleID:          timestampSuffixRuleID,
+						MessageContains: `must end in "_time"`,
+						FileLocation: &checktest.ExpectedFileLocation{
+							FileName:    "option.proto",
+							StartLine:   7,
+							StartColumn: 2,
+							EndLine:     7,
+							EndColumn:   48,
+						},
+					},
+				},
+			},
+			{
+				Name: "option",
+				Options: map[string]any{
+					timestampSuffixOptionKey: "_timestamp",
+				},
+				ExpectedAnnotations: []checktest.ExpectedAnnotation{
+					{
+						RuleID:          timestampSuffixRuleID,
+						MessageContains: `must end in "_timestamp"`,
+						FileLocation: &checktest.ExpectedFileLocation{
+							FileName:    "option.proto",
+							StartLine:   8,
+							StartColumn: 2,
+							EndLine:     8,
+							EndColumn:   45,
+						},
+					},
+				},
+			},
+		},
+	}.Run(t)
+}
+
+func TestInvalidOption(t *testing.T) {
+	t.Parallel()
+
+	checktest.CheckTest{
+		Request: &checktest.RequestSpec{
+			Files: &checktest.ProtoFileSpec{
+				DirPaths:  []string{"testdata/simple"},
+				FilePaths: []string{"simple.proto"},
+			},
+			Options: map[string]any{
+				timestampSuffixOptionKey: int64(1),
+			},
+		},
+		Spec: spec,
+		ExpectedError: &checktest.ExpectedError{
+			MessageContains: timestampSuffixOptionKey,
+			Code:            pluginrpc.CodeUnknown,
+		},
+	}.Run(t)
+}
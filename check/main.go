@@ -15,6 +15,11 @@
 package check
 
 import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
 	"pluginrpc.com/pluginrpc"
 )
 
@@ -37,17 +42,33 @@ import (
 //			},
 //		)
 //	}
+//
+// If spec.Info marks the plugin as deprecated, a warning will be written to stderr on
+// every Check call. See NewServer.
+//
+// If the plugin is invoked with the single argument "manifest", the Manifest of the plugin
+// is written to stdout as JSON instead. See ManifestForSpec for more details.
 func Main(spec *Spec, options ...MainOption) {
 	mainOptions := newMainOptions()
 	for _, option := range options {
 		option(mainOptions)
 	}
+	if args := os.Args[1:]; len(args) == 1 && args[0] == ManifestArg {
+		if err := writeManifestForSpec(context.Background(), os.Stdout, spec); err != nil {
+			_, _ = fmt.Fprintln(os.Stderr, err.Error())
+			os.Exit(1)
+		}
+		return
+	}
 	pluginrpc.Main(
 		func() (pluginrpc.Server, error) {
-			return NewServer(
-				spec,
+			serverOptions := []ServerOption{
 				ServerWithParallelism(mainOptions.parallelism),
-			)
+			}
+			if mainOptions.withoutImportSourceCodeInfo {
+				serverOptions = append(serverOptions, ServerWithoutImportSourceCodeInfo())
+			}
+			return NewServer(spec, serverOptions...)
 		},
 	)
 }
@@ -71,10 +92,29 @@ func MainWithParallelism(parallelism int) MainOption {
 	}
 }
 
+// MainWithoutImportSourceCodeInfo returns a new MainOption that drops the SourceCodeInfo of
+// all files that are imports when constructing Requests.
+//
+// See CheckServiceHandlerWithoutImportSourceCodeInfo.
+func MainWithoutImportSourceCodeInfo() MainOption {
+	return func(mainOptions *mainOptions) {
+		mainOptions.withoutImportSourceCodeInfo = true
+	}
+}
+
 // *** PRIVATE ***
 
+func writeManifestForSpec(ctx context.Context, writer io.Writer, spec *Spec) error {
+	manifest, err := ManifestForSpec(ctx, spec)
+	if err != nil {
+		return err
+	}
+	return WriteManifest(writer, manifest)
+}
+
 type mainOptions struct {
-	parallelism int
+	parallelism                 int
+	withoutImportSourceCodeInfo bool
 }
 
 func newMainOptions() *mainOptions {
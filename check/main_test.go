@@ -0,0 +1,164 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"buf.build/go/bufplugin/info"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"pluginrpc.com/pluginrpc"
+)
+
+func TestNewServerDeprecationWarning(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name     string
+		info     *info.Spec
+		args     []string
+		expected string
+	}{
+		{
+			name: "no_info",
+			args: []string{"check"},
+		},
+		{
+			name: "not_deprecated",
+			info: &info.Spec{DocShort: "A plugin."},
+			args: []string{"check"},
+		},
+		{
+			name:     "deprecated",
+			info:     &info.Spec{Deprecated: true},
+			args:     []string{"check"},
+			expected: "warning: This plugin is deprecated.\n",
+		},
+		{
+			name: "deprecated_with_replacement",
+			info: &info.Spec{
+				Deprecated:     true,
+				ReplacementURL: "https://example.com/replacement",
+			},
+			args:     []string{"check", "--format", "json"},
+			expected: "warning: This plugin is deprecated. Use https://example.com/replacement instead.\n",
+		},
+		{
+			name: "deprecated_other_procedure",
+			info: &info.Spec{Deprecated: true},
+			args: []string{"list-rules"},
+		},
+		{
+			name: "deprecated_no_args",
+			info: &info.Spec{Deprecated: true},
+		},
+	}
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+			server, err := NewServer(
+				&Spec{
+					Rules: []*RuleSpec{
+						testNewSimpleLintRuleSpec("RULE1", nil, true, false, nil),
+					},
+					Info: testCase.info,
+				},
+			)
+			require.NoError(t, err)
+			stderr := &bytes.Buffer{}
+			// We only care about the warning, not whether the invocation itself succeeds.
+			_ = server.Serve(
+				context.Background(),
+				pluginrpc.Env{
+					Args:   testCase.args,
+					Stdin:  strings.NewReader(""),
+					Stdout: &bytes.Buffer{},
+					Stderr: stderr,
+				},
+			)
+			if testCase.expected != "" {
+				assert.True(t, strings.HasPrefix(stderr.String(), testCase.expected), stderr.String())
+			} else {
+				assert.NotContains(t, stderr.String(), "warning:")
+			}
+		})
+	}
+}
+
+func TestNewServerDeprecatedDoc(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name           string
+		info           *info.Spec
+		expectedPrefix string
+	}{
+		{
+			name:           "not_deprecated",
+			info:           &info.Spec{DocShort: "A plugin."},
+			expectedPrefix: "A plugin.\n\nCommands:",
+		},
+		{
+			name:           "deprecated",
+			info:           &info.Spec{Deprecated: true},
+			expectedPrefix: "This plugin is deprecated.\n\nCommands:",
+		},
+		{
+			name: "deprecated_with_doc",
+			info: &info.Spec{
+				DocShort:       "A plugin.",
+				Deprecated:     true,
+				ReplacementURL: "https://example.com/replacement",
+			},
+			expectedPrefix: "This plugin is deprecated. Use https://example.com/replacement instead.\n\nA plugin.\n\nCommands:",
+		},
+	}
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+			server, err := NewServer(
+				&Spec{
+					Rules: []*RuleSpec{
+						testNewSimpleLintRuleSpec("RULE1", nil, true, false, nil),
+					},
+					Info: testCase.info,
+				},
+			)
+			require.NoError(t, err)
+			stdout := &bytes.Buffer{}
+			stderr := &bytes.Buffer{}
+			_ = server.Serve(
+				context.Background(),
+				pluginrpc.Env{
+					Args:   []string{"--help"},
+					Stdin:  strings.NewReader(""),
+					Stdout: stdout,
+					Stderr: stderr,
+				},
+			)
+			assert.True(
+				t,
+				strings.HasPrefix(stderr.String(), testCase.expectedPrefix),
+				"expected prefix %q in %q", testCase.expectedPrefix, stderr.String(),
+			)
+		})
+	}
+}
@@ -0,0 +1,272 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/url"
+
+	"buf.build/go/bufplugin/info"
+	"buf.build/go/bufplugin/internal/gen/buf/plugin/check/v1/v1pluginrpc"
+	infov1pluginrpc "buf.build/go/bufplugin/internal/gen/buf/plugin/info/v1/v1pluginrpc"
+	"buf.build/go/bufplugin/internal/pkg/xslices"
+	"pluginrpc.com/pluginrpc"
+)
+
+const (
+	// ManifestArg is the argument that results in Main writing the Manifest of the plugin
+	// to stdout as JSON, instead of serving a procedure.
+	//
+	//	buf-plugin-foo manifest
+	ManifestArg = "manifest"
+
+	// pluginrpcProtocolVersion is the pluginrpc protocol version. pluginrpc.Client
+	// verifies that plugins use this version before returning a Spec.
+	pluginrpcProtocolVersion = 1
+)
+
+// Manifest is a machine-readable description of a plugin.
+//
+// A Manifest combines the plugin information, capabilities, Rules, Categories, and options of
+// a plugin into a single document for registries, scanners, and IDEs. Manifests are typically
+// serialized as JSON.
+type Manifest struct {
+	// ProtocolVersion is the pluginrpc protocol version of the plugin.
+	ProtocolVersion int `json:"protocol_version"`
+	// Procedures are the procedures that the plugin implements.
+	Procedures []ManifestProcedure `json:"procedures"`
+	// Capabilities are the capabilities of the plugin.
+	Capabilities ManifestCapabilities `json:"capabilities"`
+	// Info is the information about the plugin.
+	//
+	// Will be nil if the plugin does not implement the PluginInfoService.
+	Info *ManifestInfo `json:"info,omitempty"`
+	// Rules are the Rules of the plugin.
+	Rules []ManifestRule `json:"rules"`
+	// Categories are the Categories of the plugin.
+	Categories []ManifestCategory `json:"categories"`
+	// Options are the options that the plugin accepts.
+	//
+	// Options are not part of the plugin protocol, and are only available for Manifests
+	// created from a Spec.
+	Options []ManifestOption `json:"options,omitempty"`
+}
+
+// ManifestProcedure is a procedure within a Manifest.
+type ManifestProcedure struct {
+	Path string   `json:"path"`
+	Args []string `json:"args,omitempty"`
+}
+
+// ManifestCapabilities are the capabilities of a plugin within a Manifest.
+type ManifestCapabilities struct {
+	// Check is true if the plugin implements the CheckService.
+	Check bool `json:"check"`
+	// PluginInfo is true if the plugin implements the PluginInfoService.
+	PluginInfo bool `json:"plugin_info"`
+}
+
+// ManifestInfo is the information about a plugin within a Manifest.
+type ManifestInfo struct {
+	URL            string `json:"url,omitempty"`
+	DocShort       string `json:"doc_short,omitempty"`
+	DocLong        string `json:"doc_long,omitempty"`
+	SPDXLicenseID  string `json:"spdx_license_id,omitempty"`
+	LicenseText    string `json:"license_text,omitempty"`
+	LicenseURL     string `json:"license_url,omitempty"`
+	Deprecated     bool   `json:"deprecated,omitempty"`
+	ReplacementURL string `json:"replacement_url,omitempty"`
+}
+
+// ManifestRule is a Rule within a Manifest.
+type ManifestRule struct {
+	ID             string   `json:"id"`
+	CategoryIDs    []string `json:"category_ids,omitempty"`
+	Default        bool     `json:"default,omitempty"`
+	Purpose        string   `json:"purpose"`
+	Type           string   `json:"type"`
+	Deprecated     bool     `json:"deprecated,omitempty"`
+	ReplacementIDs []string `json:"replacement_ids,omitempty"`
+}
+
+// ManifestCategory is a Category within a Manifest.
+type ManifestCategory struct {
+	ID             string   `json:"id"`
+	Purpose        string   `json:"purpose"`
+	Deprecated     bool     `json:"deprecated,omitempty"`
+	ReplacementIDs []string `json:"replacement_ids,omitempty"`
+}
+
+// ManifestOption is an option within a Manifest.
+type ManifestOption struct {
+	Key     string `json:"key"`
+	Purpose string `json:"purpose"`
+	Type    string `json:"type,omitempty"`
+}
+
+// ManifestForClient returns the Manifest of the plugin behind the pluginrpc.Client.
+//
+// This can be used to describe any plugin binary, for example by using a pluginrpc.Client
+// created with pluginrpc.NewExecRunner. The Options of the Manifest will be empty, as
+// options are not part of the plugin protocol.
+func ManifestForClient(ctx context.Context, pluginrpcClient pluginrpc.Client, options ...ClientOption) (*Manifest, error) {
+	spec, err := pluginrpcClient.Spec(ctx)
+	if err != nil {
+		return nil, err
+	}
+	procedures := spec.Procedures()
+	manifest := &Manifest{
+		ProtocolVersion: pluginrpcProtocolVersion,
+		Procedures: xslices.Map(
+			procedures,
+			func(procedure pluginrpc.Procedure) ManifestProcedure {
+				return ManifestProcedure{
+					Path: procedure.Path(),
+					Args: procedure.Args(),
+				}
+			},
+		),
+		Capabilities: ManifestCapabilities{
+			Check:      spec.ProcedureForPath(v1pluginrpc.CheckServiceCheckPath) != nil,
+			PluginInfo: spec.ProcedureForPath(infov1pluginrpc.PluginInfoServiceGetPluginInfoPath) != nil,
+		},
+		Rules:      []ManifestRule{},
+		Categories: []ManifestCategory{},
+	}
+	client := NewClient(pluginrpcClient, options...)
+	if manifest.Capabilities.PluginInfo {
+		pluginInfo, err := client.GetPluginInfo(ctx)
+		if err != nil {
+			return nil, err
+		}
+		manifest.Info = manifestInfoForPluginInfo(pluginInfo)
+	}
+	if manifest.Capabilities.Check {
+		rules, err := client.ListRules(ctx)
+		if err != nil {
+			return nil, err
+		}
+		manifest.Rules = xslices.Map(rules, manifestRuleForRule)
+		categories, err := client.ListCategories(ctx)
+		if err != nil {
+			return nil, err
+		}
+		manifest.Categories = xslices.Map(categories, manifestCategoryForCategory)
+	}
+	return manifest, nil
+}
+
+// ManifestForSpec returns the Manifest of a plugin for the given Spec.
+//
+// Unlike ManifestForClient, this includes the Options of the Spec, and the deprecation of
+// the plugin if set on spec.Info. The Spec will be validated.
+func ManifestForSpec(ctx context.Context, spec *Spec) (*Manifest, error) {
+	server, err := NewServer(spec)
+	if err != nil {
+		return nil, err
+	}
+	var clientOptions []ClientOption
+	if spec.PreserveOrder {
+		clientOptions = append(clientOptions, ClientWithPreserveOrder())
+	}
+	manifest, err := ManifestForClient(
+		ctx,
+		pluginrpc.NewClient(pluginrpc.NewServerRunner(server)),
+		clientOptions...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if spec.Info != nil {
+		// The PluginInfo protocol does not carry deprecation, so use the PluginInfo
+		// directly from the Spec.
+		pluginInfo, err := info.NewPluginInfoForSpec(spec.Info)
+		if err != nil {
+			return nil, err
+		}
+		manifest.Info = manifestInfoForPluginInfo(pluginInfo)
+	}
+	manifest.Options = xslices.Map(
+		spec.Options,
+		func(optionSpec *OptionSpec) ManifestOption {
+			return ManifestOption{
+				Key:     optionSpec.Key,
+				Purpose: optionSpec.Purpose,
+				Type:    optionSpec.Type,
+			}
+		},
+	)
+	return manifest, nil
+}
+
+// WriteManifest writes the Manifest to the Writer as indented JSON.
+func WriteManifest(writer io.Writer, manifest *Manifest) error {
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = writer.Write(append(data, '\n'))
+	return err
+}
+
+// *** PRIVATE ***
+
+func manifestInfoForPluginInfo(pluginInfo info.PluginInfo) *ManifestInfo {
+	manifestInfo := &ManifestInfo{
+		URL:            urlString(pluginInfo.URL()),
+		Deprecated:     pluginInfo.Deprecated(),
+		ReplacementURL: urlString(pluginInfo.ReplacementURL()),
+	}
+	if doc := pluginInfo.Doc(); doc != nil {
+		manifestInfo.DocShort = doc.Short()
+		manifestInfo.DocLong = doc.Long()
+	}
+	if license := pluginInfo.License(); license != nil {
+		manifestInfo.SPDXLicenseID = license.SPDXLicenseID()
+		manifestInfo.LicenseText = license.Text()
+		manifestInfo.LicenseURL = urlString(license.URL())
+	}
+	return manifestInfo
+}
+
+func manifestRuleForRule(rule Rule) ManifestRule {
+	return ManifestRule{
+		ID:             rule.ID(),
+		CategoryIDs:    xslices.Map(rule.Categories(), Category.ID),
+		Default:        rule.Default(),
+		Purpose:        rule.Purpose(),
+		Type:           rule.Type().String(),
+		Deprecated:     rule.Deprecated(),
+		ReplacementIDs: rule.ReplacementIDs(),
+	}
+}
+
+func manifestCategoryForCategory(category Category) ManifestCategory {
+	return ManifestCategory{
+		ID:             category.ID(),
+		Purpose:        category.Purpose(),
+		Deprecated:     category.Deprecated(),
+		ReplacementIDs: category.ReplacementIDs(),
+	}
+}
+
+func urlString(uri *url.URL) string {
+	if uri == nil {
+		return ""
+	}
+	return uri.String()
+}
@@ -0,0 +1,164 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"buf.build/go/bufplugin/info"
+	"buf.build/go/bufplugin/internal/gen/buf/plugin/check/v1/v1pluginrpc"
+	"github.com/stretchr/testify/require"
+)
+
+func TestManifestForSpec(t *testing.T) {
+	t.Parallel()
+
+	manifest, err := ManifestForSpec(
+		context.Background(),
+		&Spec{
+			Rules: []*RuleSpec{
+				testNewSimpleLintRuleSpec("RULE1", []string{"CATEGORY1"}, true, false, nil),
+				testNewSimpleLintRuleSpec("RULE2", nil, false, true, []string{"RULE1"}),
+			},
+			Categories: []*CategorySpec{
+				testNewSimpleCategorySpec("CATEGORY1", false, nil),
+			},
+			Options: []*OptionSpec{
+				{
+					Key:     "timestamp_suffix",
+					Purpose: "Sets the suffix for google.protobuf.Timestamp fields.",
+					Type:    "string",
+				},
+			},
+			Info: &info.Spec{
+				SPDXLicenseID:  "apache-2.0",
+				LicenseURL:     "https://example.com/license",
+				DocShort:       "A plugin.",
+				Deprecated:     true,
+				ReplacementURL: "https://example.com/replacement",
+			},
+		},
+	)
+	require.NoError(t, err)
+	require.Equal(t, pluginrpcProtocolVersion, manifest.ProtocolVersion)
+	require.True(t, manifest.Capabilities.Check)
+	require.True(t, manifest.Capabilities.PluginInfo)
+	require.Contains(
+		t,
+		manifest.Procedures,
+		ManifestProcedure{Path: v1pluginrpc.CheckServiceCheckPath, Args: []string{"check"}},
+	)
+	require.Equal(
+		t,
+		&ManifestInfo{
+			DocShort:       "A plugin.",
+			SPDXLicenseID:  "Apache-2.0",
+			LicenseURL:     "https://example.com/license",
+			Deprecated:     true,
+			ReplacementURL: "https://example.com/replacement",
+		},
+		manifest.Info,
+	)
+	require.Equal(
+		t,
+		[]ManifestRule{
+			{
+				ID:          "RULE1",
+				CategoryIDs: []string{"CATEGORY1"},
+				Default:     true,
+				Purpose:     "Checks RULE1.",
+				Type:        "lint",
+			},
+			{
+				ID:             "RULE2",
+				Purpose:        "Checks RULE2.",
+				Type:           "lint",
+				Deprecated:     true,
+				ReplacementIDs: []string{"RULE1"},
+			},
+		},
+		manifest.Rules,
+	)
+	require.Equal(
+		t,
+		[]ManifestCategory{
+			{
+				ID:      "CATEGORY1",
+				Purpose: "Checks CATEGORY1.",
+			},
+		},
+		manifest.Categories,
+	)
+	require.Equal(
+		t,
+		[]ManifestOption{
+			{
+				Key:     "timestamp_suffix",
+				Purpose: "Sets the suffix for google.protobuf.Timestamp fields.",
+				Type:    "string",
+			},
+		},
+		manifest.Options,
+	)
+
+	buffer := bytes.NewBuffer(nil)
+	require.NoError(t, WriteManifest(buffer, manifest))
+	roundTripManifest := &Manifest{}
+	require.NoError(t, json.Unmarshal(buffer.Bytes(), roundTripManifest))
+	require.Equal(t, manifest, roundTripManifest)
+}
+
+func TestManifestForSpecWithoutInfo(t *testing.T) {
+	t.Parallel()
+
+	manifest, err := ManifestForSpec(
+		context.Background(),
+		&Spec{
+			Rules: []*RuleSpec{
+				testNewSimpleLintRuleSpec("RULE1", nil, true, false, nil),
+			},
+		},
+	)
+	require.NoError(t, err)
+	require.True(t, manifest.Capabilities.Check)
+	require.False(t, manifest.Capabilities.PluginInfo)
+	require.Nil(t, manifest.Info)
+	require.Empty(t, manifest.Categories)
+	require.Empty(t, manifest.Options)
+}
+
+func TestValidateSpecOptions(t *testing.T) {
+	t.Parallel()
+
+	validateSpecError := &validateSpecError{}
+	for _, optionSpecs := range [][]*OptionSpec{
+		{{Key: "", Purpose: "Sets a value."}},
+		{{Key: "foo", Purpose: "lowercase"}},
+		{{Key: "foo", Purpose: "Sets a value."}, {Key: "foo", Purpose: "Sets a value."}},
+	} {
+		err := ValidateSpec(
+			&Spec{
+				Rules: []*RuleSpec{
+					testNewSimpleLintRuleSpec("RULE1", nil, true, false, nil),
+				},
+				Options: optionSpecs,
+			},
+		)
+		require.ErrorAs(t, err, &validateSpecError)
+	}
+}
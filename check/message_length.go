@@ -0,0 +1,43 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"unicode/utf8"
+)
+
+// TruncatedMessageSuffix is the suffix of Annotation messages that were truncated.
+//
+// See ClientWithMaxMessageLength.
+const TruncatedMessageSuffix = "... [truncated]"
+
+// *** PRIVATE ***
+
+// truncateMessage truncates the message to at most maxLength bytes, including
+// TruncatedMessageSuffix.
+//
+// Returns false if the message did not need to be truncated.
+func truncateMessage(message string, maxLength int) (string, bool) {
+	maxLength = max(maxLength, len(TruncatedMessageSuffix))
+	if len(message) <= maxLength {
+		return message, false
+	}
+	end := maxLength - len(TruncatedMessageSuffix)
+	// Do not split a multi-byte UTF-8 character.
+	for end > 0 && !utf8.RuneStart(message[end]) {
+		end--
+	}
+	return message[:end] + TruncatedMessageSuffix, true
+}
@@ -0,0 +1,119 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	descriptorv1 "buf.build/gen/go/bufbuild/bufplugin/protocolbuffers/go/buf/plugin/descriptor/v1"
+	"buf.build/go/bufplugin/descriptor"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+func TestTruncateMessage(t *testing.T) {
+	t.Parallel()
+
+	message, ok := truncateMessage("short", 100)
+	require.False(t, ok)
+	require.Equal(t, "short", message)
+
+	message, ok = truncateMessage(strings.Repeat("a", 100), 20)
+	require.True(t, ok)
+	require.Equal(t, "aaaaa"+TruncatedMessageSuffix, message)
+	require.Len(t, message, 20)
+
+	// Multi-byte characters are not split.
+	message, ok = truncateMessage(strings.Repeat("é", 50), 20)
+	require.True(t, ok)
+	require.Equal(t, "éé"+TruncatedMessageSuffix, message)
+
+	// The maximum length is raised to the length of the suffix.
+	message, ok = truncateMessage(strings.Repeat("a", 100), 1)
+	require.True(t, ok)
+	require.Equal(t, TruncatedMessageSuffix, message)
+	message, ok = truncateMessage("a", 1)
+	require.False(t, ok)
+	require.Equal(t, "a", message)
+}
+
+func TestClientWithMaxMessageLength(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	fileDescriptors, err := descriptor.FileDescriptorsForProtoFileDescriptors(
+		[]*descriptorv1.FileDescriptor{
+			{
+				FileDescriptorProto: &descriptorpb.FileDescriptorProto{
+					Name:           proto.String("foo.proto"),
+					SourceCodeInfo: &descriptorpb.SourceCodeInfo{},
+				},
+			},
+		},
+	)
+	require.NoError(t, err)
+	request, err := NewRequest(fileDescriptors)
+	require.NoError(t, err)
+	longMessage := strings.Repeat("a", 1000)
+	client, err := NewClientForSpec(
+		&Spec{
+			Rules: []*RuleSpec{
+				{
+					ID:      "RULE1",
+					Default: true,
+					Purpose: "Checks RULE1.",
+					Type:    RuleTypeLint,
+					Handler: RuleHandlerFunc(
+						func(_ context.Context, responseWriter ResponseWriter, _ Request) error {
+							responseWriter.AddAnnotation(WithFileName("foo.proto"), WithMessage(longMessage))
+							responseWriter.AddAnnotation(WithFileName("foo.proto"), WithMessage("short"))
+							return nil
+						},
+					),
+				},
+			},
+		},
+		ClientWithMaxMessageLength(100),
+	)
+	require.NoError(t, err)
+
+	response, err := client.Check(ctx, request)
+	require.NoError(t, err)
+	annotations := response.Annotations()
+	require.Len(t, annotations, 2)
+	require.Len(t, annotations[0].Message(), 100)
+	require.True(t, strings.HasSuffix(annotations[0].Message(), TruncatedMessageSuffix))
+	require.Equal(t, longMessage, annotations[0].FullMessage())
+	require.Equal(t, "short", annotations[1].Message())
+	require.Equal(t, "short", annotations[1].FullMessage())
+
+	var sinkMessages []string
+	response, err = client.Check(
+		ctx,
+		request,
+		CheckCallWithMaxMessageLength(0),
+		CheckCallWithAnnotationSink(
+			func(annotation Annotation) {
+				sinkMessages = append(sinkMessages, annotation.Message())
+			},
+		),
+	)
+	require.NoError(t, err)
+	require.Equal(t, longMessage, response.Annotations()[0].Message())
+	require.Equal(t, []string{longMessage, "short"}, sinkMessages)
+}
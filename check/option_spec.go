@@ -0,0 +1,53 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"errors"
+	"fmt"
+)
+
+// OptionSpec is the spec for an option that a plugin accepts.
+//
+// OptionSpecs are informational. They are not part of the plugin protocol, and Options
+// on a Request are not validated against them. They are included in the Manifest of
+// the plugin, so that tooling can describe the options of a plugin.
+type OptionSpec struct {
+	// Required.
+	Key string
+	// Required.
+	Purpose string
+	// Type is a description of the type of the value, such as "string" or "[]string".
+	Type string
+}
+
+// *** PRIVATE ***
+
+func validateOptionSpecs(optionSpecs []*OptionSpec) error {
+	keys := make(map[string]struct{}, len(optionSpecs))
+	for _, optionSpec := range optionSpecs {
+		if optionSpec.Key == "" {
+			return errors.New("OptionSpec.Key is empty")
+		}
+		if _, ok := keys[optionSpec.Key]; ok {
+			return fmt.Errorf("duplicate OptionSpec.Key: %q", optionSpec.Key)
+		}
+		keys[optionSpec.Key] = struct{}{}
+		if err := validatePurpose(optionSpec.Key, optionSpec.Purpose); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,199 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+)
+
+const (
+	// OwnershipAllPath is the OwnershipRule Path that matches all files.
+	OwnershipAllPath = "*"
+
+	ownershipPackagePrefix = "package:"
+)
+
+// Ownership maps files to the owners of the files.
+//
+// An Ownership is used by a Client to attach owners to each Annotation, so that findings
+// can be routed to the correct teams. See ClientWithOwnership and CheckCallWithOwnership.
+type Ownership interface {
+	// OwnersForFile returns the owners of the file with the given path and Protobuf package.
+	//
+	// The owners of the last OwnershipRule that matches the file are returned, similar to
+	// CODEOWNERS files. Returns nil if no OwnershipRule matches the file.
+	OwnersForFile(path string, packageName string) []string
+
+	isOwnership()
+}
+
+// OwnershipRule maps a path or Protobuf package to owners.
+type OwnershipRule struct {
+	// Path is the path to match.
+	//
+	// The path is either a file path, or a directory path that matches all files within it.
+	// Paths are relative and use forward slashes. OwnershipAllPath matches all files.
+	//
+	// Exactly one of Path and Package must be set.
+	Path string
+	// Package is the Protobuf package to match.
+	//
+	// The package matches files with the package itself or any sub-package.
+	//
+	// Exactly one of Path and Package must be set.
+	Package string
+	// Owners are the owners of the matched files, for example "@acme/payments".
+	//
+	// Required to have at least one element.
+	Owners []string
+}
+
+// NewOwnership returns a new Ownership for the given OwnershipRules.
+//
+// Later OwnershipRules take precedence over earlier OwnershipRules.
+func NewOwnership(rules []OwnershipRule) (Ownership, error) {
+	return newOwnership(rules)
+}
+
+// ReadOwnership reads an Ownership in a CODEOWNERS-like format.
+//
+// Each line consists of a pattern followed by one or more owners, separated by whitespace.
+// A pattern is either a path, OwnershipAllPath, or a Protobuf package prefixed by "package:".
+// Empty lines and lines starting with "#" are ignored. For example:
+//
+//	# Everything is owned by the platform team by default.
+//	*                  @acme/platform
+//	acme/payments/     @acme/payments @acme/billing
+//	package:acme.ads   @acme/ads
+//
+// Later lines take precedence over earlier lines.
+func ReadOwnership(reader io.Reader) (Ownership, error) {
+	var rules []OwnershipRule
+	scanner := bufio.NewScanner(reader)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("ownership line %d: expected a pattern and at least one owner: %q", lineNumber, line)
+		}
+		rule := OwnershipRule{
+			Owners: fields[1:],
+		}
+		if packageName, ok := strings.CutPrefix(fields[0], ownershipPackagePrefix); ok {
+			rule.Package = packageName
+		} else {
+			rule.Path = fields[0]
+		}
+		if err := validateOwnershipRule(rule); err != nil {
+			return nil, fmt.Errorf("ownership line %d: %w", lineNumber, err)
+		}
+		rules = append(rules, rule)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return newOwnership(rules)
+}
+
+// *** PRIVATE ***
+
+type ownership struct {
+	rules []OwnershipRule
+}
+
+func newOwnership(rules []OwnershipRule) (*ownership, error) {
+	rules = slices.Clone(rules)
+	for i, rule := range rules {
+		if err := validateOwnershipRule(rule); err != nil {
+			return nil, err
+		}
+		rule.Path = normalizeVendoredPath(rule.Path)
+		rule.Owners = slices.Clone(rule.Owners)
+		rules[i] = rule
+	}
+	return &ownership{
+		rules: rules,
+	}, nil
+}
+
+func (o *ownership) OwnersForFile(path string, packageName string) []string {
+	for i := len(o.rules) - 1; i >= 0; i-- {
+		rule := o.rules[i]
+		var matches bool
+		switch {
+		case rule.Path == OwnershipAllPath:
+			matches = true
+		case rule.Path != "":
+			matches = matchesPathPrefix(path, rule.Path)
+		default:
+			matches = matchesPackagePrefix(packageName, rule.Package)
+		}
+		if matches {
+			return slices.Clone(rule.Owners)
+		}
+	}
+	return nil
+}
+
+func (*ownership) isOwnership() {}
+
+func validateOwnershipRule(rule OwnershipRule) error {
+	switch {
+	case rule.Path == "" && rule.Package == "":
+		return errors.New("ownership rule must have a Path or Package")
+	case rule.Path != "" && rule.Package != "":
+		return fmt.Errorf("ownership rule must not have both a Path and Package: %q, %q", rule.Path, rule.Package)
+	case rule.Path != "" && rule.Path != OwnershipAllPath:
+		if err := validateVendoredPaths([]string{rule.Path}); err != nil {
+			return err
+		}
+	case rule.Package != "":
+		if err := validateVendoredPackages([]string{rule.Package}); err != nil {
+			return err
+		}
+	}
+	if len(rule.Owners) == 0 {
+		return fmt.Errorf("ownership rule for %q has no owners", rule.Path+rule.Package)
+	}
+	for _, owner := range rule.Owners {
+		if owner == "" || strings.ContainsAny(owner, " \t\r\n") {
+			return fmt.Errorf("ownership rule for %q has an invalid owner: %q", rule.Path+rule.Package, owner)
+		}
+	}
+	return nil
+}
+
+// ownersForAnnotation returns the owners of the file that the Annotation is located within.
+func ownersForAnnotation(annotation Annotation, ownership Ownership) []string {
+	fileLocation := annotationPrimaryFileLocation(annotation)
+	if fileLocation == nil {
+		return nil
+	}
+	protoreflectFileDescriptor := fileLocation.FileDescriptor().ProtoreflectFileDescriptor()
+	return ownership.OwnersForFile(
+		protoreflectFileDescriptor.Path(),
+		string(protoreflectFileDescriptor.Package()),
+	)
+}
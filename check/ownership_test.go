@@ -0,0 +1,127 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	descriptorv1 "buf.build/gen/go/bufbuild/bufplugin/protocolbuffers/go/buf/plugin/descriptor/v1"
+	"buf.build/go/bufplugin/descriptor"
+	"buf.build/go/bufplugin/internal/pkg/xslices"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+func TestReadOwnership(t *testing.T) {
+	t.Parallel()
+
+	ownership, err := ReadOwnership(
+		strings.NewReader(`
+# Comment.
+*                  @acme/platform
+acme/payments/     @acme/payments @acme/billing
+package:acme.ads   @acme/ads
+`,
+		),
+	)
+	require.NoError(t, err)
+	require.Equal(t, []string{"@acme/platform"}, ownership.OwnersForFile("foo.proto", "foo"))
+	require.Equal(t, []string{"@acme/payments", "@acme/billing"}, ownership.OwnersForFile("acme/payments/v1/payments.proto", "acme.payments.v1"))
+	require.Equal(t, []string{"@acme/platform"}, ownership.OwnersForFile("acme/paymentsfoo/foo.proto", "acme.paymentsfoo"))
+	// Later lines take precedence.
+	require.Equal(t, []string{"@acme/ads"}, ownership.OwnersForFile("acme/payments/ads.proto", "acme.ads.v1"))
+
+	ownership, err = NewOwnership([]OwnershipRule{{Package: "acme", Owners: []string{"@acme/team"}}})
+	require.NoError(t, err)
+	require.Nil(t, ownership.OwnersForFile("foo.proto", "foo"))
+
+	for _, invalid := range []string{
+		"foo.proto",
+		"/foo.proto @acme/team",
+		"package:.acme @acme/team",
+	} {
+		_, err = ReadOwnership(strings.NewReader(invalid))
+		require.Error(t, err, invalid)
+	}
+	_, err = NewOwnership([]OwnershipRule{{Path: "foo", Package: "foo", Owners: []string{"@acme/team"}}})
+	require.Error(t, err)
+}
+
+func TestClientWithOwnership(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	fileDescriptors, err := descriptor.FileDescriptorsForProtoFileDescriptors(
+		[]*descriptorv1.FileDescriptor{
+			{
+				FileDescriptorProto: &descriptorpb.FileDescriptorProto{
+					Name:           proto.String("acme/payments/v1/payments.proto"),
+					Package:        proto.String("acme.payments.v1"),
+					SourceCodeInfo: &descriptorpb.SourceCodeInfo{},
+				},
+			},
+			{
+				FileDescriptorProto: &descriptorpb.FileDescriptorProto{
+					Name:           proto.String("foo.proto"),
+					Package:        proto.String("foo"),
+					SourceCodeInfo: &descriptorpb.SourceCodeInfo{},
+				},
+			},
+		},
+	)
+	require.NoError(t, err)
+	request, err := NewRequest(fileDescriptors)
+	require.NoError(t, err)
+	spec := &Spec{
+		Rules: []*RuleSpec{
+			testNewAnnotatingLintRuleSpec("RULE1", "acme/payments/v1/payments.proto", "foo.proto"),
+		},
+	}
+	ownership, err := NewOwnership(
+		[]OwnershipRule{
+			{
+				Package: "acme.payments",
+				Owners:  []string{"@acme/payments"},
+			},
+		},
+	)
+	require.NoError(t, err)
+
+	client, err := NewClientForSpec(spec, ClientWithOwnership(ownership))
+	require.NoError(t, err)
+	response, err := client.Check(ctx, request)
+	require.NoError(t, err)
+	require.Equal(t, [][]string{{"@acme/payments"}, nil}, testAnnotationOwners(response.Annotations()))
+
+	// CheckCallWithOwnership overrides the Ownership on the Client.
+	overrideOwnership, err := NewOwnership([]OwnershipRule{{Path: OwnershipAllPath, Owners: []string{"@acme/platform"}}})
+	require.NoError(t, err)
+	response, err = client.Check(ctx, request, CheckCallWithOwnership(overrideOwnership))
+	require.NoError(t, err)
+	require.Equal(t, [][]string{{"@acme/platform"}, {"@acme/platform"}}, testAnnotationOwners(response.Annotations()))
+
+	client, err = NewClientForSpec(spec)
+	require.NoError(t, err)
+	response, err = client.Check(ctx, request)
+	require.NoError(t, err)
+	require.Equal(t, [][]string{nil, nil}, testAnnotationOwners(response.Annotations()))
+}
+
+func testAnnotationOwners(annotations []Annotation) [][]string {
+	return xslices.Map(annotations, Annotation.Owners)
+}
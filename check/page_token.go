@@ -0,0 +1,147 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"buf.build/go/bufplugin/internal/pkg/thread"
+)
+
+const (
+	// pageTokenIndexPrefix is the prefix of page tokens that contain the index of the first
+	// item of the page, and the number of items.
+	//
+	// IDs cannot contain lowercase letters or colons, so these page tokens are never confused
+	// with the IDs that were used as page tokens by previous versions of the CheckServiceHandler.
+	pageTokenIndexPrefix = "index:"
+	// listPagesParallelism is the maximum number of pages that listPages fetches at once.
+	listPagesParallelism = 4
+)
+
+// pageTokenForIndex returns the page token of the page that starts at the index, within a list
+// with the given number of items.
+func pageTokenForIndex(index int, length int) string {
+	return pageTokenIndexPrefix + strconv.Itoa(index) + ":" + strconv.Itoa(length)
+}
+
+// indexForPageToken returns the index of the first item of the page for the page token, and
+// the number of items.
+//
+// Returns false if the page token is not an index.
+func indexForPageToken(pageToken string) (int, int, bool) {
+	indexAndLength, ok := strings.CutPrefix(pageToken, pageTokenIndexPrefix)
+	if !ok {
+		return 0, 0, false
+	}
+	indexString, lengthString, ok := strings.Cut(indexAndLength, ":")
+	if !ok {
+		return 0, 0, false
+	}
+	index, err := strconv.Atoi(indexString)
+	if err != nil {
+		return 0, 0, false
+	}
+	length, err := strconv.Atoi(lengthString)
+	if err != nil || index < 0 || index > length {
+		return 0, 0, false
+	}
+	return index, length, true
+}
+
+// listPages returns the items of all pages returned by listPage, in order.
+//
+// Page tokens are opaque, so in general each page can only be requested once the previous page
+// has been returned. If the next page token of the first page is an index, as returned by the
+// CheckServiceHandler, the page tokens of all remaining pages are known from the size of the
+// first page and the number of items, and up to listPagesParallelism pages are fetched at once.
+// Otherwise, the remaining pages are fetched serially.
+func listPages[T any](
+	ctx context.Context,
+	listPage func(ctx context.Context, pageToken string) ([]T, string, error),
+) ([]T, error) {
+	items, nextPageToken, err := listPage(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	if nextPageToken == "" {
+		return items, nil
+	}
+	pageSize, length, ok := indexForPageToken(nextPageToken)
+	if !ok || pageSize == 0 || pageSize != len(items) {
+		return listPagesSerially(ctx, listPage, items, nextPageToken)
+	}
+	// The first page has already been fetched.
+	numPages := (length + pageSize - 1) / pageSize
+	pages := make([][]T, numPages)
+	jobs := make([]func(context.Context) error, 0, numPages-1)
+	for i := 1; i < numPages; i++ {
+		i := i
+		jobs = append(
+			jobs,
+			func(ctx context.Context) error {
+				page, nextPageToken, err := listPage(ctx, pageTokenForIndex(i*pageSize, length))
+				if err != nil {
+					return err
+				}
+				// The server must return pages of the same size from the same list, as
+				// otherwise the pages we requested overlap or have gaps.
+				var expectedNextPageToken string
+				if i < numPages-1 {
+					expectedNextPageToken = pageTokenForIndex((i+1)*pageSize, length)
+				}
+				if nextPageToken != expectedNextPageToken {
+					return fmt.Errorf("unexpected next page token %q, expected %q", nextPageToken, expectedNextPageToken)
+				}
+				pages[i] = page
+				return nil
+			},
+		)
+	}
+	if err := thread.Parallelize(
+		ctx,
+		jobs,
+		thread.WithParallelism(listPagesParallelism),
+		thread.ParallelizeWithCancelOnFailure(),
+	); err != nil {
+		return nil, err
+	}
+	for _, page := range pages[1:] {
+		items = append(items, page...)
+	}
+	return items, nil
+}
+
+// listPagesSerially appends the items of the page for the page token, and of all pages after
+// it, to the items.
+func listPagesSerially[T any](
+	ctx context.Context,
+	listPage func(ctx context.Context, pageToken string) ([]T, string, error),
+	items []T,
+	pageToken string,
+) ([]T, error) {
+	for pageToken != "" {
+		page, nextPageToken, err := listPage(ctx, pageToken)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, page...)
+		pageToken = nextPageToken
+	}
+	return items, nil
+}
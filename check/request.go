@@ -107,12 +107,18 @@ func WithRuleIDs(ruleIDs ...string) RequestOption {
 }
 
 // RequestForProtoRequest returns a new Request for the given checkv1.Request.
-func RequestForProtoRequest(protoRequest *checkv1.CheckRequest) (Request, error) {
-	fileDescriptors, err := descriptor.FileDescriptorsForProtoFileDescriptors(protoRequest.GetFileDescriptors())
+//
+// The given FileDescriptorsOptions are used to construct both the FileDescriptors and the
+// against FileDescriptors, for example descriptor.FileDescriptorsWithoutImportSourceCodeInfo.
+func RequestForProtoRequest(
+	protoRequest *checkv1.CheckRequest,
+	fileDescriptorsOptions ...descriptor.FileDescriptorsOption,
+) (Request, error) {
+	fileDescriptors, err := descriptor.FileDescriptorsForProtoFileDescriptors(protoRequest.GetFileDescriptors(), fileDescriptorsOptions...)
 	if err != nil {
 		return nil, err
 	}
-	againstFileDescriptors, err := descriptor.FileDescriptorsForProtoFileDescriptors(protoRequest.GetAgainstFileDescriptors())
+	againstFileDescriptors, err := descriptor.FileDescriptorsForProtoFileDescriptors(protoRequest.GetAgainstFileDescriptors(), fileDescriptorsOptions...)
 	if err != nil {
 		return nil, err
 	}
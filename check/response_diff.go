@@ -0,0 +1,127 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"context"
+	"slices"
+)
+
+// ResponseDiff is the difference between the Annotations of two Responses for the same Request.
+//
+// ResponseDiffs are produced by DiffClients.
+type ResponseDiff interface {
+	// Request is the Request that produced the Responses.
+	Request() Request
+	// RemovedAnnotations are the Annotations that were returned from the "from" Client, but
+	// not from the "to" Client.
+	//
+	// The returned Annotations will be sorted.
+	RemovedAnnotations() []Annotation
+	// AddedAnnotations are the Annotations that were returned from the "to" Client, but not
+	// from the "from" Client.
+	//
+	// The returned Annotations will be sorted.
+	AddedAnnotations() []Annotation
+	// IsEmpty returns true if there were no differences between the Responses.
+	IsEmpty() bool
+
+	isResponseDiff()
+}
+
+// DiffClients calls Check with each Request on both Clients, and returns the differences between
+// the Annotations of the resulting Responses.
+//
+// This is typically used to compare two versions of the same plugin, by capturing a set of
+// Requests and replaying them against both versions before rolling out the new version.
+//
+// One ResponseDiff is returned for each Request, in the order of the Requests. Annotations are
+// considered equal if CompareAnnotations returns 0.
+func DiffClients(
+	ctx context.Context,
+	fromClient Client,
+	toClient Client,
+	requests []Request,
+	options ...CheckCallOption,
+) ([]ResponseDiff, error) {
+	responseDiffs := make([]ResponseDiff, 0, len(requests))
+	for _, request := range requests {
+		fromResponse, err := fromClient.Check(ctx, request, options...)
+		if err != nil {
+			return nil, err
+		}
+		toResponse, err := toClient.Check(ctx, request, options...)
+		if err != nil {
+			return nil, err
+		}
+		responseDiffs = append(responseDiffs, newResponseDiff(request, fromResponse, toResponse))
+	}
+	return responseDiffs, nil
+}
+
+// *** PRIVATE ***
+
+type responseDiff struct {
+	request            Request
+	removedAnnotations []Annotation
+	addedAnnotations   []Annotation
+}
+
+func newResponseDiff(request Request, fromResponse Response, toResponse Response) *responseDiff {
+	// Annotations are sorted on Responses.
+	fromAnnotations := fromResponse.Annotations()
+	toAnnotations := toResponse.Annotations()
+	var removedAnnotations []Annotation
+	var addedAnnotations []Annotation
+	var i, j int
+	for i < len(fromAnnotations) && j < len(toAnnotations) {
+		switch compare := CompareAnnotations(fromAnnotations[i], toAnnotations[j]); {
+		case compare < 0:
+			removedAnnotations = append(removedAnnotations, fromAnnotations[i])
+			i++
+		case compare > 0:
+			addedAnnotations = append(addedAnnotations, toAnnotations[j])
+			j++
+		default:
+			i++
+			j++
+		}
+	}
+	removedAnnotations = append(removedAnnotations, fromAnnotations[i:]...)
+	addedAnnotations = append(addedAnnotations, toAnnotations[j:]...)
+	return &responseDiff{
+		request:            request,
+		removedAnnotations: removedAnnotations,
+		addedAnnotations:   addedAnnotations,
+	}
+}
+
+func (r *responseDiff) Request() Request {
+	return r.request
+}
+
+func (r *responseDiff) RemovedAnnotations() []Annotation {
+	return slices.Clone(r.removedAnnotations)
+}
+
+func (r *responseDiff) AddedAnnotations() []Annotation {
+	return slices.Clone(r.addedAnnotations)
+}
+
+func (r *responseDiff) IsEmpty() bool {
+	return len(r.removedAnnotations) == 0 && len(r.addedAnnotations) == 0
+}
+
+func (*responseDiff) isResponseDiff() {}
@@ -0,0 +1,114 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"context"
+	"testing"
+
+	descriptorv1 "buf.build/gen/go/bufbuild/bufplugin/protocolbuffers/go/buf/plugin/descriptor/v1"
+	"buf.build/go/bufplugin/descriptor"
+	"buf.build/go/bufplugin/internal/pkg/xslices"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+func TestDiffClients(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	fromClient, err := NewClientForSpec(
+		&Spec{
+			Rules: []*RuleSpec{
+				testNewAnnotatingLintRuleSpec("RULE1", "foo.proto"),
+				testNewAnnotatingLintRuleSpec("RULE2", "foo.proto", "bar.proto"),
+			},
+		},
+	)
+	require.NoError(t, err)
+	toClient, err := NewClientForSpec(
+		&Spec{
+			Rules: []*RuleSpec{
+				testNewAnnotatingLintRuleSpec("RULE1", "foo.proto"),
+				testNewAnnotatingLintRuleSpec("RULE2", "bar.proto"),
+				testNewAnnotatingLintRuleSpec("RULE3", "bar.proto"),
+			},
+		},
+	)
+	require.NoError(t, err)
+	fileDescriptors, err := descriptor.FileDescriptorsForProtoFileDescriptors(
+		[]*descriptorv1.FileDescriptor{
+			{
+				FileDescriptorProto: &descriptorpb.FileDescriptorProto{
+					Name:           proto.String("foo.proto"),
+					SourceCodeInfo: &descriptorpb.SourceCodeInfo{},
+				},
+			},
+			{
+				FileDescriptorProto: &descriptorpb.FileDescriptorProto{
+					Name:           proto.String("bar.proto"),
+					SourceCodeInfo: &descriptorpb.SourceCodeInfo{},
+				},
+			},
+		},
+	)
+	require.NoError(t, err)
+	request, err := NewRequest(fileDescriptors, WithRuleIDs("RULE1", "RULE2"))
+	require.NoError(t, err)
+	noChangeRequest, err := NewRequest(fileDescriptors, WithRuleIDs("RULE1"))
+	require.NoError(t, err)
+
+	responseDiffs, err := DiffClients(ctx, fromClient, toClient, []Request{request, noChangeRequest})
+	require.NoError(t, err)
+	require.Len(t, responseDiffs, 2)
+	require.False(t, responseDiffs[0].IsEmpty())
+	require.Equal(t, []string{"RULE2:foo.proto"}, testAnnotationStrings(responseDiffs[0].RemovedAnnotations()))
+	require.Empty(t, responseDiffs[0].AddedAnnotations())
+	require.True(t, responseDiffs[1].IsEmpty())
+
+	// Swapping the Clients swaps the removed and added Annotations.
+	responseDiffs, err = DiffClients(ctx, toClient, fromClient, []Request{request})
+	require.NoError(t, err)
+	require.Len(t, responseDiffs, 1)
+	require.Empty(t, responseDiffs[0].RemovedAnnotations())
+	require.Equal(t, []string{"RULE2:foo.proto"}, testAnnotationStrings(responseDiffs[0].AddedAnnotations()))
+}
+
+func testNewAnnotatingLintRuleSpec(id string, fileNames ...string) *RuleSpec {
+	return &RuleSpec{
+		ID:      id,
+		Default: true,
+		Purpose: "Checks " + id + ".",
+		Type:    RuleTypeLint,
+		Handler: RuleHandlerFunc(
+			func(_ context.Context, responseWriter ResponseWriter, _ Request) error {
+				for _, fileName := range fileNames {
+					responseWriter.AddAnnotation(WithFileName(fileName))
+				}
+				return nil
+			},
+		),
+	}
+}
+
+func testAnnotationStrings(annotations []Annotation) []string {
+	return xslices.Map(
+		annotations,
+		func(annotation Annotation) string {
+			return annotation.RuleID() + ":" + annotation.FileLocation().FileDescriptor().ProtoreflectFileDescriptor().Path()
+		},
+	)
+}
@@ -161,6 +161,8 @@ func WithAgainstFileNameAndSourcePath(againstFileName string, againstSourcePath
 type multiResponseWriter struct {
 	fileNameToFileDescriptor        map[string]descriptor.FileDescriptor
 	againstFileNameToFileDescriptor map[string]descriptor.FileDescriptor
+	// annotationSink is called with every Annotation as it is added, if set.
+	annotationSink AnnotationSink
 
 	annotations []Annotation
 	written     bool
@@ -241,6 +243,10 @@ func (m *multiResponseWriter) addAnnotation(
 	}
 
 	m.annotations = append(m.annotations, annotation)
+	if m.annotationSink != nil {
+		// Called with the lock held so that the AnnotationSink is never called concurrently.
+		m.annotationSink(annotation)
+	}
 }
 
 func (m *multiResponseWriter) toResponse() (Response, error) {
@@ -326,10 +332,7 @@ func getFileLocationForAddAnnotationOptions(
 			if !ok {
 				return nil, fmt.Errorf("cannot add annotation for unknown file: %q", protoreflectFileDescriptor.Path())
 			}
-			return descriptor.NewFileLocation(
-				fileDescriptor,
-				protoreflectFileDescriptor.SourceLocations().ByDescriptor(protoreflectDescriptor),
-			), nil
+			return descriptor.FileLocationFor(fileDescriptor, protoreflectDescriptor), nil
 		}
 		return nil, nil
 	}
@@ -17,6 +17,7 @@ package check
 import (
 	"errors"
 	"fmt"
+	"net/url"
 	"slices"
 	"sort"
 
@@ -69,6 +70,12 @@ type Rule interface {
 	//
 	// It is not valid for a deprecated Rule to specfiy another deprecated Rule as a replacement.
 	ReplacementIDs() []string
+	// DocURL returns the documentation URL for the Rule.
+	//
+	// Documentation URLs are not sent by plugins. They are attached by a Client that was
+	// configured with a DocURLResolver, see ClientWithDocURLResolver. Will be nil if no
+	// DocURLResolver was configured, or if the DocURLResolver returned nil for the Rule.
+	DocURL() *url.URL
 
 	toProto() *checkv1.Rule
 
@@ -85,6 +92,7 @@ type rule struct {
 	ruleType       RuleType
 	deprecated     bool
 	replacementIDs []string
+	docURL         *url.URL
 }
 
 func newRule(
@@ -147,6 +155,10 @@ func (r *rule) ReplacementIDs() []string {
 	return slices.Clone(r.replacementIDs)
 }
 
+func (r *rule) DocURL() *url.URL {
+	return r.docURL
+}
+
 func (r *rule) toProto() *checkv1.Rule {
 	if r == nil {
 		return nil
@@ -165,7 +177,7 @@ func (r *rule) toProto() *checkv1.Rule {
 
 func (*rule) isRule() {}
 
-func ruleForProtoRule(protoRule *checkv1.Rule, idToCategory map[string]Category) (Rule, error) {
+func ruleForProtoRule(protoRule *checkv1.Rule, idToCategory map[string]Category) (*rule, error) {
 	categories, err := xslices.MapError(
 		protoRule.GetCategoryIds(),
 		func(id string) (Category, error) {
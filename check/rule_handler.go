@@ -34,3 +34,31 @@ type RuleHandlerFunc func(context.Context, ResponseWriter, Request) error
 func (r RuleHandlerFunc) Handle(ctx context.Context, responseWriter ResponseWriter, request Request) error {
 	return r(ctx, responseWriter, request)
 }
+
+// HandleRule calls the Handler of the RuleSpec directly with the Request, and returns a Response
+// containing the Annotations that the Handler added.
+//
+// This bypasses the Client and the CheckService, and is intended for testing and benchmarking
+// individual RuleHandlers without the overhead of serializing the Request. The RuleSpec is not
+// validated, and Spec.Before and Spec.RuleHandlerMiddlewares are not called. RuleIDs on the
+// Request are ignored.
+func HandleRule(ctx context.Context, ruleSpec *RuleSpec, request Request) (Response, error) {
+	if ruleSpec.Handler == nil {
+		return nil, newValidateRuleSpecErrorf("Handler is not set for ID %q", ruleSpec.ID)
+	}
+	multiResponseWriter, err := newMultiResponseWriter(request)
+	if err != nil {
+		return nil, err
+	}
+	ruleRequest, ok, err := requestForFileFilter(request, ruleSpec.FileFilter)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return multiResponseWriter.toResponse()
+	}
+	if err := ruleSpec.Handler.Handle(contextWithRuleID(ctx, ruleSpec.ID), multiResponseWriter.newResponseWriter(ruleSpec.ID), ruleRequest); err != nil {
+		return nil, err
+	}
+	return multiResponseWriter.toResponse()
+}
@@ -0,0 +1,85 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+)
+
+// RuleHandlerMiddleware wraps a RuleHandler with additional behavior.
+//
+// Middleware is used for cross-cutting concerns such as timing, recovery, logging, or
+// prefetching descriptors, so that these are composed once instead of within each RuleHandler.
+// The ID of the Rule being handled can be retrieved from the Context with RuleIDForContext.
+type RuleHandlerMiddleware func(RuleHandler) RuleHandler
+
+// WrapRuleHandler returns a new RuleHandler that wraps the RuleHandler with the given middleware.
+//
+// The first RuleHandlerMiddleware is the outermost, that is it is invoked first, and is the last
+// to see the result of the RuleHandler.
+func WrapRuleHandler(ruleHandler RuleHandler, middlewares ...RuleHandlerMiddleware) RuleHandler {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		ruleHandler = middlewares[i](ruleHandler)
+	}
+	return ruleHandler
+}
+
+// RecoverRuleHandlerMiddleware returns a new RuleHandlerMiddleware that recovers from panics
+// within the RuleHandler, and returns them as errors that include the Rule ID and stack trace.
+//
+// Without this middleware, a panic within a RuleHandler will crash the plugin.
+func RecoverRuleHandlerMiddleware() RuleHandlerMiddleware {
+	return func(ruleHandler RuleHandler) RuleHandler {
+		return RuleHandlerFunc(
+			func(ctx context.Context, responseWriter ResponseWriter, request Request) (retErr error) {
+				defer func() {
+					if recovered := recover(); recovered != nil {
+						ruleID, _ := RuleIDForContext(ctx)
+						retErr = fmt.Errorf("panic in RuleHandler for ID %q: %v\n%s", ruleID, recovered, debug.Stack())
+					}
+				}()
+				return ruleHandler.Handle(ctx, responseWriter, request)
+			},
+		)
+	}
+}
+
+// RuleIDForContext returns the ID of the Rule being handled.
+//
+// The ID is set on the Context passed to RuleHandlers by the plugin and by HandleRule.
+// Returns false if the Context is not for a RuleHandler invocation.
+func RuleIDForContext(ctx context.Context) (string, bool) {
+	ruleID, ok := ctx.Value(ruleIDContextKey{}).(string)
+	return ruleID, ok
+}
+
+// *** PRIVATE ***
+
+type ruleIDContextKey struct{}
+
+func contextWithRuleID(ctx context.Context, ruleID string) context.Context {
+	return context.WithValue(ctx, ruleIDContextKey{}, ruleID)
+}
+
+func validateRuleHandlerMiddlewares(middlewares []RuleHandlerMiddleware) error {
+	for i, middleware := range middlewares {
+		if middleware == nil {
+			return fmt.Errorf("RuleHandlerMiddlewares[%d] is nil", i)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	descriptorv1 "buf.build/gen/go/bufbuild/bufplugin/protocolbuffers/go/buf/plugin/descriptor/v1"
+	"buf.build/go/bufplugin/descriptor"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+func TestRuleHandlerMiddlewares(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	fileDescriptors, err := descriptor.FileDescriptorsForProtoFileDescriptors(
+		[]*descriptorv1.FileDescriptor{
+			{
+				FileDescriptorProto: &descriptorpb.FileDescriptorProto{
+					Name:           proto.String("foo.proto"),
+					SourceCodeInfo: &descriptorpb.SourceCodeInfo{},
+				},
+			},
+		},
+	)
+	require.NoError(t, err)
+	request, err := NewRequest(fileDescriptors)
+	require.NoError(t, err)
+
+	var lock sync.Mutex
+	var calls []string
+	newRecordingMiddleware := func(name string) RuleHandlerMiddleware {
+		return func(ruleHandler RuleHandler) RuleHandler {
+			return RuleHandlerFunc(
+				func(ctx context.Context, responseWriter ResponseWriter, request Request) error {
+					ruleID, ok := RuleIDForContext(ctx)
+					require.True(t, ok)
+					lock.Lock()
+					calls = append(calls, name+":"+ruleID)
+					lock.Unlock()
+					return ruleHandler.Handle(ctx, responseWriter, request)
+				},
+			)
+		}
+	}
+	client, err := NewClientForSpec(
+		&Spec{
+			Rules: []*RuleSpec{
+				testNewAnnotatingLintRuleSpec("RULE1", "foo.proto"),
+			},
+			RuleHandlerMiddlewares: []RuleHandlerMiddleware{
+				newRecordingMiddleware("outer"),
+				newRecordingMiddleware("inner"),
+			},
+		},
+	)
+	require.NoError(t, err)
+	response, err := client.Check(ctx, request)
+	require.NoError(t, err)
+	require.Equal(t, []string{"RULE1:foo.proto"}, testAnnotationStrings(response.Annotations()))
+	require.Equal(t, []string{"outer:RULE1", "inner:RULE1"}, calls)
+
+	_, ok := RuleIDForContext(ctx)
+	require.False(t, ok)
+
+	_, err = NewClientForSpec(
+		&Spec{
+			Rules: []*RuleSpec{
+				testNewAnnotatingLintRuleSpec("RULE1", "foo.proto"),
+			},
+			RuleHandlerMiddlewares: []RuleHandlerMiddleware{nil},
+		},
+	)
+	require.Error(t, err)
+}
+
+func TestRecoverRuleHandlerMiddleware(t *testing.T) {
+	t.Parallel()
+
+	fileDescriptors, err := descriptor.FileDescriptorsForProtoFileDescriptors(
+		[]*descriptorv1.FileDescriptor{
+			{
+				FileDescriptorProto: &descriptorpb.FileDescriptorProto{
+					Name:           proto.String("foo.proto"),
+					SourceCodeInfo: &descriptorpb.SourceCodeInfo{},
+				},
+			},
+		},
+	)
+	require.NoError(t, err)
+	request, err := NewRequest(fileDescriptors)
+	require.NoError(t, err)
+
+	ruleHandler := WrapRuleHandler(
+		RuleHandlerFunc(
+			func(_ context.Context, _ ResponseWriter, request Request) error {
+				fileDescriptors := request.FileDescriptors()
+				// Index out of range.
+				_ = fileDescriptors[len(fileDescriptors)]
+				return nil
+			},
+		),
+		RecoverRuleHandlerMiddleware(),
+	)
+	_, err = HandleRule(
+		context.Background(),
+		&RuleSpec{
+			ID:      "RULE1",
+			Handler: ruleHandler,
+		},
+		request,
+	)
+	require.ErrorContains(t, err, `panic in RuleHandler for ID "RULE1"`)
+}
@@ -0,0 +1,59 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"context"
+	"testing"
+
+	descriptorv1 "buf.build/gen/go/bufbuild/bufplugin/protocolbuffers/go/buf/plugin/descriptor/v1"
+	"buf.build/go/bufplugin/descriptor"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+func TestHandleRule(t *testing.T) {
+	t.Parallel()
+
+	fileDescriptors, err := descriptor.FileDescriptorsForProtoFileDescriptors(
+		[]*descriptorv1.FileDescriptor{
+			{
+				FileDescriptorProto: &descriptorpb.FileDescriptorProto{
+					Name:           proto.String("foo.proto"),
+					SourceCodeInfo: &descriptorpb.SourceCodeInfo{},
+				},
+			},
+			{
+				FileDescriptorProto: &descriptorpb.FileDescriptorProto{
+					Name:           proto.String("bar.proto"),
+					SourceCodeInfo: &descriptorpb.SourceCodeInfo{},
+				},
+			},
+		},
+	)
+	require.NoError(t, err)
+	request, err := NewRequest(fileDescriptors, WithRuleIDs("RULE2"))
+	require.NoError(t, err)
+
+	response, err := HandleRule(context.Background(), testNewAnnotatingLintRuleSpec("RULE1", "foo.proto", "bar.proto"), request)
+	require.NoError(t, err)
+	require.Equal(t, []string{"RULE1:bar.proto", "RULE1:foo.proto"}, testAnnotationStrings(response.Annotations()))
+
+	_, err = HandleRule(context.Background(), testNewAnnotatingLintRuleSpec("RULE1", "baz.proto"), request)
+	require.Error(t, err)
+	_, err = HandleRule(context.Background(), &RuleSpec{ID: "RULE1"}, request)
+	require.Error(t, err)
+}
@@ -20,6 +20,7 @@ import (
 	"regexp"
 	"sort"
 
+	"buf.build/go/bufplugin/descriptor"
 	"buf.build/go/bufplugin/internal/pkg/xslices"
 )
 
@@ -53,6 +54,20 @@ type RuleSpec struct {
 	ReplacementIDs []string
 	// Required.
 	Handler RuleHandler
+	// FileFilter selects the files that the Rule applies to.
+	//
+	// Optional.
+	//
+	// If set, the filter is evaluated once per Check call, and the Handler is given a Request
+	// that only contains the selected files. If no files are selected, the Handler is not called.
+	// This is useful for Rules that only apply to a few files, such as files with services, to
+	// avoid traversing every file in large Requests.
+	//
+	// A file path is selected if the filter matches either the FileDescriptor or the against
+	// FileDescriptor with that path, so that breaking change Rules still see both sides of a pair.
+	// If only against FileDescriptors are selected, the FileDescriptors on the Request are not
+	// filtered, as a Request always has FileDescriptors.
+	FileFilter func(descriptor.FileDescriptor) bool
 }
 
 // *** PRIVATE ***
@@ -15,6 +15,11 @@
 package check
 
 import (
+	"context"
+	"fmt"
+	"slices"
+
+	"buf.build/go/bufplugin/descriptor"
 	"buf.build/go/bufplugin/info"
 	checkv1pluginrpc "buf.build/go/bufplugin/internal/gen/buf/plugin/check/v1/v1pluginrpc"
 	infov1pluginrpc "buf.build/go/bufplugin/internal/gen/buf/plugin/info/v1/v1pluginrpc"
@@ -30,13 +35,28 @@ import (
 // - The ListRules RPC on the command "list-rules".
 // - The ListCategories RPC on the command "list-categories".
 // - The GetPluginInfo RPC on the command "info" (if spec.Info is present).
+//
+// If spec.Info marks the plugin as deprecated, a warning is written to the stderr of every
+// Check call. The Check RPC has no way to return diagnostics that are not tied to a Rule, so
+// stderr is the only channel available to surface this to users. Note that Clients created
+// with NewClientForSpec drop stderr, as with any pluginrpc.Client by default. Use
+// Client.GetPluginInfo to determine whether such a plugin is deprecated.
 func NewServer(spec *Spec, options ...ServerOption) (pluginrpc.Server, error) {
 	serverOptions := newServerOptions()
 	for _, option := range options {
 		option(serverOptions)
 	}
 
-	checkServiceHandler, err := NewCheckServiceHandler(spec, CheckServiceHandlerWithParallelism(serverOptions.parallelism))
+	checkServiceHandlerOptions := []CheckServiceHandlerOption{
+		CheckServiceHandlerWithParallelism(serverOptions.parallelism),
+	}
+	if serverOptions.withoutImportSourceCodeInfo {
+		checkServiceHandlerOptions = append(checkServiceHandlerOptions, CheckServiceHandlerWithoutImportSourceCodeInfo())
+	}
+	if serverOptions.fileDescriptorPool != nil {
+		checkServiceHandlerOptions = append(checkServiceHandlerOptions, CheckServiceHandlerWithFileDescriptorPool(serverOptions.fileDescriptorPool))
+	}
+	checkServiceHandler, err := NewCheckServiceHandler(spec, checkServiceHandlerOptions...)
 	if err != nil {
 		return nil, err
 	}
@@ -79,19 +99,43 @@ func NewServer(spec *Spec, options ...ServerOption) (pluginrpc.Server, error) {
 
 	// Add documentation to -h/--help.
 	var pluginrpcServerOptions []pluginrpc.ServerOption
+	var deprecationMessage string
 	if spec.Info != nil {
 		pluginInfo, err := info.NewPluginInfoForSpec(spec.Info)
 		if err != nil {
 			return nil, err
 		}
+		var docString string
 		if doc := pluginInfo.Doc(); doc != nil {
+			docString = doc.String()
+		}
+		deprecationMessage = pluginDeprecationMessage(pluginInfo)
+		if deprecationMessage != "" {
+			if docString != "" {
+				docString = deprecationMessage + "\n\n" + docString
+			} else {
+				docString = deprecationMessage
+			}
+		}
+		if docString != "" {
 			pluginrpcServerOptions = append(
 				pluginrpcServerOptions,
-				pluginrpc.ServerWithDoc(doc.String()),
+				pluginrpc.ServerWithDoc(docString),
 			)
 		}
 	}
-	return pluginrpc.NewServer(pluginrpcSpec, serverRegistrar, pluginrpcServerOptions...)
+	server, err := pluginrpc.NewServer(pluginrpcSpec, serverRegistrar, pluginrpcServerOptions...)
+	if err != nil {
+		return nil, err
+	}
+	if deprecationMessage != "" {
+		server = newDeprecationWarningServer(
+			server,
+			pluginrpcSpec.ProcedureForPath(checkv1pluginrpc.CheckServiceCheckPath).Args(),
+			deprecationMessage,
+		)
+	}
+	return server, nil
 }
 
 // ServerOption is an option for Server.
@@ -113,10 +157,78 @@ func ServerWithParallelism(parallelism int) ServerOption {
 	}
 }
 
+// ServerWithoutImportSourceCodeInfo returns a new ServerOption that drops the SourceCodeInfo
+// of all files that are imports when constructing Requests.
+//
+// See CheckServiceHandlerWithoutImportSourceCodeInfo.
+func ServerWithoutImportSourceCodeInfo() ServerOption {
+	return func(serverOptions *serverOptions) {
+		serverOptions.withoutImportSourceCodeInfo = true
+	}
+}
+
+// ServerWithFileDescriptorPool returns a new ServerOption that interns the files of all
+// Requests within the given descriptor.FileDescriptorPool.
+//
+// See CheckServiceHandlerWithFileDescriptorPool.
+func ServerWithFileDescriptorPool(fileDescriptorPool descriptor.FileDescriptorPool) ServerOption {
+	return func(serverOptions *serverOptions) {
+		serverOptions.fileDescriptorPool = fileDescriptorPool
+	}
+}
+
+// *** PRIVATE ***
+
 type serverOptions struct {
-	parallelism int
+	parallelism                 int
+	withoutImportSourceCodeInfo bool
+	fileDescriptorPool          descriptor.FileDescriptorPool
 }
 
 func newServerOptions() *serverOptions {
 	return &serverOptions{}
 }
+
+// pluginDeprecationMessage returns a user-readable message denoting that the plugin is
+// deprecated, or the empty string if the plugin is not deprecated.
+func pluginDeprecationMessage(pluginInfo info.PluginInfo) string {
+	if !pluginInfo.Deprecated() {
+		return ""
+	}
+	if replacementURL := pluginInfo.ReplacementURL(); replacementURL != nil {
+		return "This plugin is deprecated. Use " + replacementURL.String() + " instead."
+	}
+	return "This plugin is deprecated."
+}
+
+// deprecationWarningServer is a pluginrpc.Server that writes a warning to stderr when the
+// Check RPC is invoked on a deprecated plugin.
+type deprecationWarningServer struct {
+	pluginrpc.Server
+
+	checkArgs          []string
+	deprecationMessage string
+}
+
+func newDeprecationWarningServer(
+	server pluginrpc.Server,
+	checkArgs []string,
+	deprecationMessage string,
+) *deprecationWarningServer {
+	return &deprecationWarningServer{
+		Server:             server,
+		checkArgs:          checkArgs,
+		deprecationMessage: deprecationMessage,
+	}
+}
+
+func (d *deprecationWarningServer) Serve(ctx context.Context, env pluginrpc.Env) error {
+	if env.Stderr != nil && d.isCheck(env.Args) {
+		_, _ = fmt.Fprintln(env.Stderr, "warning: "+d.deprecationMessage)
+	}
+	return d.Server.Serve(ctx, env)
+}
+
+func (d *deprecationWarningServer) isCheck(args []string) bool {
+	return len(args) >= len(d.checkArgs) && slices.Equal(args[:len(d.checkArgs)], d.checkArgs)
+}
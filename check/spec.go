@@ -49,6 +49,62 @@ type Spec struct {
 	// If not set, the resulting server will not implement the PluginInfoService.
 	Info *info.Spec
 
+	// PreserveOrder specifies that Rules and Categories will be returned from ListRules and
+	// ListCategories in the order they are declared within Rules and Categories, as opposed
+	// to sorted by ID.
+	//
+	// Optional.
+	//
+	// This allows plugins to group Rules and Categories logically. Pagination is still
+	// deterministic. Clients will sort the returned Rules and Categories by ID unless
+	// ClientWithPreserveOrder is specified.
+	PreserveOrder bool
+
+	// VendoredPaths are paths of files that are vendored, i.e. files that are not owned by
+	// the user and whose findings cannot be fixed, such as third-party protos.
+	//
+	// Optional.
+	//
+	// Each path is either a file path, or a directory path that matches all files within it.
+	// Paths are relative and use forward slashes. Annotations located within a vendored file
+	// are suppressed and are not returned in the Response. The paths can be extended on a
+	// per-Request basis with the VendoredPathsOptionKey option if VendoredOptions is set.
+	VendoredPaths []string
+	// VendoredPackages are Protobuf packages that are vendored.
+	//
+	// Optional.
+	//
+	// Each package matches files with the package itself or any sub-package. For example,
+	// "google" matches files with the packages "google" and "google.type". Annotations located
+	// within a vendored file are suppressed and are not returned in the Response. The packages
+	// can be extended on a per-Request basis with the VendoredPackagesOptionKey option if
+	// VendoredOptions is set.
+	VendoredPackages []string
+	// VendoredOptions enables the VendoredPathsOptionKey and VendoredPackagesOptionKey options.
+	//
+	// Optional.
+	//
+	// If set, the vendored paths and packages on a Request are read from these options in
+	// addition to VendoredPaths and VendoredPackages, and Requests with values of these options
+	// that are not valid are rejected. If not set, these options are not read, so that plugins
+	// can use the keys for options of their own.
+	VendoredOptions bool
+
+	// RuleHandlerMiddlewares are applied to the RuleHandler of every Rule.
+	//
+	// Optional.
+	//
+	// The first RuleHandlerMiddleware is the outermost. See WrapRuleHandler for more details.
+	RuleHandlerMiddlewares []RuleHandlerMiddleware
+
+	// Options are the options that the plugin accepts.
+	//
+	// Optional.
+	//
+	// Options are informational only. They are not part of the plugin protocol, and are not
+	// used to validate the options on a Request. They are included in the Manifest of the plugin.
+	Options []*OptionSpec
+
 	// Before is a function that will be executed before any RuleHandlers are
 	// invoked that returns a new Context and Request. This new Context and
 	// Request will be passed to the RuleHandlers. This allows for any
@@ -80,6 +136,23 @@ func ValidateSpec(spec *Spec) error {
 	if err := validateCategorySpecs(spec.Categories, spec.Rules); err != nil {
 		return err
 	}
+	if err := validateVendoredPaths(spec.VendoredPaths); err != nil {
+		return wrapValidateSpecError(err)
+	}
+	if err := validateVendoredPackages(spec.VendoredPackages); err != nil {
+		return wrapValidateSpecError(err)
+	}
+	if err := validateRuleHandlerMiddlewares(spec.RuleHandlerMiddlewares); err != nil {
+		return wrapValidateSpecError(err)
+	}
+	if err := validateOptionSpecs(spec.Options); err != nil {
+		return wrapValidateSpecError(err)
+	}
+	if spec.VendoredOptions {
+		if err := validateOptionSpecsWithoutVendoredOptionKeys(spec.Options); err != nil {
+			return wrapValidateSpecError(err)
+		}
+	}
 	if spec.Info != nil {
 		if err := info.ValidateSpec(spec.Info); err != nil {
 			return err
@@ -0,0 +1,248 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"context"
+
+	"buf.build/go/bufplugin/descriptor"
+	"buf.build/go/bufplugin/info"
+)
+
+// SpecOption is an option for NewSpec.
+type SpecOption func(*specBuilder)
+
+// NewSpec returns a new validated Spec for the given options.
+//
+// This is an alternative to constructing a Spec as a struct literal:
+//
+//	spec, err := check.NewSpec(
+//		check.WithCategory("STYLE", "Checks style."),
+//		check.WithLintRule(
+//			"FIELD_LOWER_SNAKE_CASE",
+//			"Checks that all field names are lower_snake_case.",
+//			checkutil.NewFieldRuleHandler(checkFieldLowerSnakeCase),
+//			check.RuleWithCategoryIDs("STYLE"),
+//			check.RuleWithDefault(),
+//		),
+//	)
+//
+// Each Rule and Category is validated as it is added, so that errors refer to the first
+// invalid option. Once all options are applied, the resulting Spec is validated with
+// ValidateSpec, which validates references between Rules and Categories. Rules and
+// Categories can be added in any order.
+//
+// The returned Spec is a regular Spec, and can be further modified as needed.
+func NewSpec(options ...SpecOption) (*Spec, error) {
+	specBuilder := newSpecBuilder()
+	for _, option := range options {
+		if specBuilder.err != nil {
+			break
+		}
+		option(specBuilder)
+	}
+	if specBuilder.err != nil {
+		return nil, specBuilder.err
+	}
+	if err := ValidateSpec(specBuilder.spec); err != nil {
+		return nil, err
+	}
+	return specBuilder.spec, nil
+}
+
+// WithLintRule returns a new SpecOption that adds a lint Rule.
+func WithLintRule(id string, purpose string, handler RuleHandler, options ...RuleOption) SpecOption {
+	return withRule(id, purpose, RuleTypeLint, handler, options...)
+}
+
+// WithBreakingRule returns a new SpecOption that adds a breaking change Rule.
+func WithBreakingRule(id string, purpose string, handler RuleHandler, options ...RuleOption) SpecOption {
+	return withRule(id, purpose, RuleTypeBreaking, handler, options...)
+}
+
+// WithCategory returns a new SpecOption that adds a Category.
+func WithCategory(id string, purpose string, options ...CategoryOption) SpecOption {
+	return func(specBuilder *specBuilder) {
+		categorySpec := &CategorySpec{
+			ID:      id,
+			Purpose: purpose,
+		}
+		for _, option := range options {
+			option(categorySpec)
+		}
+		if err := validateID(categorySpec.ID); err != nil {
+			specBuilder.err = wrapValidateCategorySpecError(err)
+			return
+		}
+		if err := validatePurpose(categorySpec.ID, categorySpec.Purpose); err != nil {
+			specBuilder.err = wrapValidateCategorySpecError(err)
+			return
+		}
+		if !specBuilder.addID(categorySpec.ID) {
+			return
+		}
+		specBuilder.spec.Categories = append(specBuilder.spec.Categories, categorySpec)
+	}
+}
+
+// WithInfo returns a new SpecOption that sets the Info on the Spec.
+func WithInfo(infoSpec *info.Spec) SpecOption {
+	return func(specBuilder *specBuilder) {
+		specBuilder.spec.Info = infoSpec
+	}
+}
+
+// WithPreserveOrder returns a new SpecOption that sets PreserveOrder on the Spec.
+//
+// See Spec.PreserveOrder for more details.
+func WithPreserveOrder() SpecOption {
+	return func(specBuilder *specBuilder) {
+		specBuilder.spec.PreserveOrder = true
+	}
+}
+
+// WithRuleHandlerMiddlewares returns a new SpecOption that adds RuleHandlerMiddlewares to the Spec.
+//
+// See Spec.RuleHandlerMiddlewares for more details.
+func WithRuleHandlerMiddlewares(middlewares ...RuleHandlerMiddleware) SpecOption {
+	return func(specBuilder *specBuilder) {
+		specBuilder.spec.RuleHandlerMiddlewares = append(specBuilder.spec.RuleHandlerMiddlewares, middlewares...)
+	}
+}
+
+// WithBefore returns a new SpecOption that sets Before on the Spec.
+//
+// See Spec.Before for more details.
+func WithBefore(before func(ctx context.Context, request Request) (context.Context, Request, error)) SpecOption {
+	return func(specBuilder *specBuilder) {
+		specBuilder.spec.Before = before
+	}
+}
+
+// RuleOption is an option for a Rule added with WithLintRule or WithBreakingRule.
+type RuleOption func(*RuleSpec)
+
+// RuleWithCategoryIDs returns a new RuleOption that adds the given Category IDs to the Rule.
+//
+// The Categories can be added with WithCategory before or after the Rule.
+func RuleWithCategoryIDs(categoryIDs ...string) RuleOption {
+	return func(ruleSpec *RuleSpec) {
+		ruleSpec.CategoryIDs = append(ruleSpec.CategoryIDs, categoryIDs...)
+	}
+}
+
+// RuleWithDefault returns a new RuleOption that makes the Rule a default Rule.
+func RuleWithDefault() RuleOption {
+	return func(ruleSpec *RuleSpec) {
+		ruleSpec.Default = true
+	}
+}
+
+// RuleWithDeprecated returns a new RuleOption that deprecates the Rule, optionally
+// with the IDs of the Rules that replace it.
+func RuleWithDeprecated(replacementIDs ...string) RuleOption {
+	return func(ruleSpec *RuleSpec) {
+		ruleSpec.Deprecated = true
+		ruleSpec.ReplacementIDs = append(ruleSpec.ReplacementIDs, replacementIDs...)
+	}
+}
+
+// RuleWithFileFilter returns a new RuleOption that sets the FileFilter of the Rule.
+//
+// See RuleSpec.FileFilter for more details.
+func RuleWithFileFilter(fileFilter func(descriptor.FileDescriptor) bool) RuleOption {
+	return func(ruleSpec *RuleSpec) {
+		ruleSpec.FileFilter = fileFilter
+	}
+}
+
+// CategoryOption is an option for a Category added with WithCategory.
+type CategoryOption func(*CategorySpec)
+
+// CategoryWithDeprecated returns a new CategoryOption that deprecates the Category, optionally
+// with the IDs of the Categories that replace it.
+func CategoryWithDeprecated(replacementIDs ...string) CategoryOption {
+	return func(categorySpec *CategorySpec) {
+		categorySpec.Deprecated = true
+		categorySpec.ReplacementIDs = append(categorySpec.ReplacementIDs, replacementIDs...)
+	}
+}
+
+// *** PRIVATE ***
+
+type specBuilder struct {
+	spec *Spec
+	ids  map[string]struct{}
+	err  error
+}
+
+func newSpecBuilder() *specBuilder {
+	return &specBuilder{
+		spec: &Spec{},
+		ids:  make(map[string]struct{}),
+	}
+}
+
+// addID adds the ID to the set of seen Rule and Category IDs.
+//
+// If the ID was already seen, this sets err and returns false.
+func (s *specBuilder) addID(id string) bool {
+	if _, ok := s.ids[id]; ok {
+		s.err = wrapValidateSpecError(newDuplicateRuleOrCategoryIDError([]string{id}))
+		return false
+	}
+	s.ids[id] = struct{}{}
+	return true
+}
+
+func withRule(
+	id string,
+	purpose string,
+	ruleType RuleType,
+	handler RuleHandler,
+	options ...RuleOption,
+) SpecOption {
+	return func(specBuilder *specBuilder) {
+		ruleSpec := &RuleSpec{
+			ID:      id,
+			Purpose: purpose,
+			Type:    ruleType,
+			Handler: handler,
+		}
+		for _, option := range options {
+			option(ruleSpec)
+		}
+		if err := validateID(ruleSpec.ID); err != nil {
+			specBuilder.err = wrapValidateRuleSpecError(err)
+			return
+		}
+		if err := validatePurpose(ruleSpec.ID, ruleSpec.Purpose); err != nil {
+			specBuilder.err = wrapValidateRuleSpecError(err)
+			return
+		}
+		if ruleSpec.Handler == nil {
+			specBuilder.err = newValidateRuleSpecErrorf("Handler is not set for ID %q", ruleSpec.ID)
+			return
+		}
+		if ruleSpec.Default && ruleSpec.Deprecated {
+			specBuilder.err = newValidateRuleSpecErrorf("ID %q was a default Rule but was also Deprecated", ruleSpec.ID)
+			return
+		}
+		if !specBuilder.addID(ruleSpec.ID) {
+			return
+		}
+		specBuilder.spec.Rules = append(specBuilder.spec.Rules, ruleSpec)
+	}
+}
@@ -137,6 +137,67 @@ func TestValidateSpec(t *testing.T) {
 	require.ErrorAs(t, ValidateSpec(spec), &validateCategorySpecError)
 }
 
+func TestNewSpec(t *testing.T) {
+	t.Parallel()
+
+	validateRuleSpecError := &validateRuleSpecError{}
+	validateCategorySpecError := &validateCategorySpecError{}
+	validateSpecError := &validateSpecError{}
+
+	spec, err := NewSpec(
+		WithLintRule("RULE1", "Checks RULE1.", nopRuleHandler, RuleWithCategoryIDs("CATEGORY1"), RuleWithDefault()),
+		WithLintRule("RULE2", "Checks RULE2.", nopRuleHandler, RuleWithDeprecated("RULE1")),
+		WithBreakingRule("RULE3", "Checks RULE3.", nopRuleHandler),
+		WithCategory("CATEGORY1", "Checks CATEGORY1."),
+		WithPreserveOrder(),
+	)
+	require.NoError(t, err)
+	require.Len(t, spec.Rules, 3)
+	require.Equal(t, "RULE1", spec.Rules[0].ID)
+	require.Equal(t, []string{"CATEGORY1"}, spec.Rules[0].CategoryIDs)
+	require.True(t, spec.Rules[0].Default)
+	require.Equal(t, RuleTypeLint, spec.Rules[0].Type)
+	require.NotNil(t, spec.Rules[0].Handler)
+	require.Equal(t, "RULE2", spec.Rules[1].ID)
+	require.True(t, spec.Rules[1].Deprecated)
+	require.Equal(t, []string{"RULE1"}, spec.Rules[1].ReplacementIDs)
+	require.Equal(t, "RULE3", spec.Rules[2].ID)
+	require.Equal(t, RuleTypeBreaking, spec.Rules[2].Type)
+	require.Equal(t, []*CategorySpec{testNewSimpleCategorySpec("CATEGORY1", false, nil)}, spec.Categories)
+	require.True(t, spec.PreserveOrder)
+
+	_, err = NewSpec(
+		WithLintRule("RULE1", "Checks RULE1.", nopRuleHandler),
+		WithLintRule("rule2", "Checks RULE2.", nopRuleHandler),
+	)
+	require.ErrorAs(t, err, &validateRuleSpecError)
+	_, err = NewSpec(
+		WithLintRule("RULE1", "Checks RULE1.", nil),
+	)
+	require.ErrorAs(t, err, &validateRuleSpecError)
+	_, err = NewSpec(
+		WithLintRule("RULE1", "Checks RULE1.", nopRuleHandler, RuleWithDefault(), RuleWithDeprecated()),
+	)
+	require.ErrorAs(t, err, &validateRuleSpecError)
+	_, err = NewSpec(
+		WithLintRule("RULE1", "Checks RULE1.", nopRuleHandler),
+		WithCategory("CATEGORY1", "checks CATEGORY1"),
+	)
+	require.ErrorAs(t, err, &validateCategorySpecError)
+	_, err = NewSpec(
+		WithLintRule("RULE1", "Checks RULE1.", nopRuleHandler),
+		WithLintRule("RULE1", "Checks RULE1.", nopRuleHandler),
+	)
+	require.ErrorAs(t, err, &validateSpecError)
+	// Validated after all options are applied.
+	_, err = NewSpec(
+		WithLintRule("RULE1", "Checks RULE1.", nopRuleHandler, RuleWithCategoryIDs("CATEGORY1")),
+	)
+	require.ErrorAs(t, err, &validateRuleSpecError)
+	_, err = NewSpec()
+	require.ErrorAs(t, err, &validateSpecError)
+}
+
 func testNewSimpleLintRuleSpec(
 	id string,
 	categoryIDs []string,
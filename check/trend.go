@@ -0,0 +1,195 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strconv"
+
+	"buf.build/go/bufplugin/internal/pkg/xslices"
+)
+
+// Revision is a labeled Request within a sequence of historical Requests.
+//
+// A Revision typically corresponds to the files at a specific git commit, with the commit
+// as the Label. The Request can be constructed from a FileDescriptorSet for the commit with
+// descriptor.FileDescriptorsForProtoFileDescriptors and NewRequest.
+type Revision struct {
+	// Label is the label of the Revision, such as a git commit or a date.
+	//
+	// Required.
+	Label string
+	// Request is the Request for the Revision.
+	//
+	// Required.
+	Request Request
+}
+
+// TrendPoint is the number of Annotations per Rule for a single Revision.
+//
+// TrendPoints are produced by TrendClient.
+type TrendPoint interface {
+	// Label is the label of the Revision.
+	Label() string
+	// RuleIDs are the IDs of the Rules that were run for the Revision.
+	//
+	// This includes Rules that produced no Annotations, so that the TrendPoints for a
+	// sequence of Revisions form a dense time series. The returned IDs will be sorted.
+	RuleIDs() []string
+	// Count returns the number of Annotations produced by the Rule with the given ID.
+	//
+	// Returns 0 if the Rule produced no Annotations, or was not run.
+	Count(ruleID string) int
+	// TotalCount returns the total number of Annotations for the Revision.
+	TotalCount() int
+
+	isTrendPoint()
+}
+
+// TrendClient calls Check with the Request of each Revision on the Client, and returns the
+// number of Annotations per Rule for each Revision.
+//
+// This is typically used to track the adoption of newly introduced Rules, by replaying the
+// history of a repository against a plugin and charting the results.
+//
+// One TrendPoint is returned for each Revision, in the order of the Revisions.
+func TrendClient(
+	ctx context.Context,
+	client Client,
+	revisions []Revision,
+	options ...CheckCallOption,
+) ([]TrendPoint, error) {
+	rules, err := client.ListRules(ctx)
+	if err != nil {
+		return nil, err
+	}
+	trendPoints := make([]TrendPoint, 0, len(revisions))
+	for _, revision := range revisions {
+		if revision.Label == "" {
+			return nil, errors.New("check.Revision: Label is empty")
+		}
+		if revision.Request == nil {
+			return nil, fmt.Errorf("check.Revision: Request is nil for Revision %q", revision.Label)
+		}
+		response, err := client.Check(ctx, revision.Request, options...)
+		if err != nil {
+			return nil, err
+		}
+		trendPoints = append(
+			trendPoints,
+			newTrendPoint(
+				revision.Label,
+				ruleIDsForRequest(rules, revision.Request),
+				response.Annotations(),
+			),
+		)
+	}
+	return trendPoints, nil
+}
+
+// WriteTrendPointsCSV writes the TrendPoints as CSV to the Writer.
+//
+// The CSV has a header row, followed by one row per Revision and Rule, with the columns
+// label, rule_id, and count. Rows are written in the order of the TrendPoints, and then
+// sorted by Rule ID.
+func WriteTrendPointsCSV(writer io.Writer, trendPoints []TrendPoint) error {
+	csvWriter := csv.NewWriter(writer)
+	if err := csvWriter.Write([]string{"label", "rule_id", "count"}); err != nil {
+		return err
+	}
+	for _, trendPoint := range trendPoints {
+		for _, ruleID := range trendPoint.RuleIDs() {
+			if err := csvWriter.Write(
+				[]string{
+					trendPoint.Label(),
+					ruleID,
+					strconv.Itoa(trendPoint.Count(ruleID)),
+				},
+			); err != nil {
+				return err
+			}
+		}
+	}
+	csvWriter.Flush()
+	return csvWriter.Error()
+}
+
+// *** PRIVATE ***
+
+type trendPoint struct {
+	label         string
+	ruleIDs       []string
+	ruleIDToCount map[string]int
+	totalCount    int
+}
+
+func newTrendPoint(label string, ruleIDs []string, annotations []Annotation) *trendPoint {
+	ruleIDToCount := make(map[string]int)
+	for _, annotation := range annotations {
+		ruleIDToCount[annotation.RuleID()]++
+	}
+	// Include Rules that produced Annotations but were not expected to run, so that no
+	// Annotations are dropped from the counts.
+	for ruleID := range ruleIDToCount {
+		if !slices.Contains(ruleIDs, ruleID) {
+			ruleIDs = append(ruleIDs, ruleID)
+		}
+	}
+	sort.Strings(ruleIDs)
+	return &trendPoint{
+		label:         label,
+		ruleIDs:       ruleIDs,
+		ruleIDToCount: ruleIDToCount,
+		totalCount:    len(annotations),
+	}
+}
+
+func (t *trendPoint) Label() string {
+	return t.label
+}
+
+func (t *trendPoint) RuleIDs() []string {
+	return slices.Clone(t.ruleIDs)
+}
+
+func (t *trendPoint) Count(ruleID string) int {
+	return t.ruleIDToCount[ruleID]
+}
+
+func (t *trendPoint) TotalCount() int {
+	return t.totalCount
+}
+
+func (*trendPoint) isTrendPoint() {}
+
+// ruleIDsForRequest returns the IDs of the Rules that will be run for the Request.
+//
+// This mirrors the Rule selection of the server: the Rules with the Request's RuleIDs if
+// set, otherwise the default Rules.
+func ruleIDsForRequest(rules []Rule, request Request) []string {
+	if ruleIDs := request.RuleIDs(); len(ruleIDs) > 0 {
+		return slices.Clone(ruleIDs)
+	}
+	return xslices.Map(
+		xslices.Filter(rules, func(rule Rule) bool { return rule.Default() }),
+		Rule.ID,
+	)
+}
@@ -0,0 +1,104 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	descriptorv1 "buf.build/gen/go/bufbuild/bufplugin/protocolbuffers/go/buf/plugin/descriptor/v1"
+	"buf.build/go/bufplugin/descriptor"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+func TestTrendClient(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	client, err := NewClientForSpec(
+		&Spec{
+			Rules: []*RuleSpec{
+				testNewAnnotatingLintRuleSpec("RULE1", "foo.proto"),
+				testNewAnnotatingLintRuleSpec("RULE2", "foo.proto", "bar.proto"),
+			},
+		},
+	)
+	require.NoError(t, err)
+	oldFileDescriptors, err := descriptor.FileDescriptorsForProtoFileDescriptors(
+		[]*descriptorv1.FileDescriptor{
+			{
+				FileDescriptorProto: &descriptorpb.FileDescriptorProto{
+					Name:           proto.String("foo.proto"),
+					SourceCodeInfo: &descriptorpb.SourceCodeInfo{},
+				},
+			},
+			{
+				FileDescriptorProto: &descriptorpb.FileDescriptorProto{
+					Name:           proto.String("bar.proto"),
+					SourceCodeInfo: &descriptorpb.SourceCodeInfo{},
+				},
+			},
+		},
+	)
+	require.NoError(t, err)
+	oldRequest, err := NewRequest(oldFileDescriptors)
+	require.NoError(t, err)
+	newRequest, err := NewRequest(oldFileDescriptors, WithRuleIDs("RULE1"))
+	require.NoError(t, err)
+
+	trendPoints, err := TrendClient(
+		ctx,
+		client,
+		[]Revision{
+			{
+				Label:   "abc",
+				Request: oldRequest,
+			},
+			{
+				Label:   "def",
+				Request: newRequest,
+			},
+		},
+	)
+	require.NoError(t, err)
+	require.Len(t, trendPoints, 2)
+	require.Equal(t, "abc", trendPoints[0].Label())
+	require.Equal(t, []string{"RULE1", "RULE2"}, trendPoints[0].RuleIDs())
+	require.Equal(t, 1, trendPoints[0].Count("RULE1"))
+	require.Equal(t, 2, trendPoints[0].Count("RULE2"))
+	require.Equal(t, 3, trendPoints[0].TotalCount())
+	require.Equal(t, "def", trendPoints[1].Label())
+	require.Equal(t, []string{"RULE1"}, trendPoints[1].RuleIDs())
+	require.Equal(t, 0, trendPoints[1].Count("RULE2"))
+	require.Equal(t, 1, trendPoints[1].TotalCount())
+
+	buffer := bytes.NewBuffer(nil)
+	require.NoError(t, WriteTrendPointsCSV(buffer, trendPoints))
+	require.Equal(
+		t,
+		`label,rule_id,count
+abc,RULE1,1
+abc,RULE2,2
+def,RULE1,1
+`,
+		buffer.String(),
+	)
+
+	_, err = TrendClient(ctx, client, []Revision{{Request: oldRequest}})
+	require.Error(t, err)
+}
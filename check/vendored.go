@@ -0,0 +1,202 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"buf.build/go/bufplugin/descriptor"
+	"buf.build/go/bufplugin/option"
+)
+
+const (
+	// VendoredPathsOptionKey is the option key that can be used to specify additional vendored
+	// paths on a Request, in addition to Spec.VendoredPaths.
+	//
+	// The value must be a []string. See Spec.VendoredPaths for the semantics of each path. This
+	// option is only read if Spec.VendoredOptions is set.
+	VendoredPathsOptionKey = "vendored_paths"
+	// VendoredPackagesOptionKey is the option key that can be used to specify additional vendored
+	// packages on a Request, in addition to Spec.VendoredPackages.
+	//
+	// The value must be a []string. See Spec.VendoredPackages for the semantics of each package.
+	// This option is only read if Spec.VendoredOptions is set.
+	VendoredPackagesOptionKey = "vendored_packages"
+)
+
+// *** PRIVATE ***
+
+// vendoredMatcher determines if a file is vendored.
+type vendoredMatcher struct {
+	paths    []string
+	packages []string
+}
+
+// newVendoredMatcher returns a new vendoredMatcher for the Spec and the Options on a Request.
+//
+// The Options are only read if Spec.VendoredOptions is set. Returns nil if there are no
+// vendored paths or packages.
+func newVendoredMatcher(spec *Spec, options option.Options) (*vendoredMatcher, error) {
+	var optionPaths []string
+	var optionPackages []string
+	if spec.VendoredOptions {
+		var err error
+		optionPaths, err = option.GetStringSliceValue(options, VendoredPathsOptionKey)
+		if err != nil {
+			return nil, err
+		}
+		optionPackages, err = option.GetStringSliceValue(options, VendoredPackagesOptionKey)
+		if err != nil {
+			return nil, err
+		}
+		if err := validateVendoredPaths(optionPaths); err != nil {
+			return nil, err
+		}
+		if err := validateVendoredPackages(optionPackages); err != nil {
+			return nil, err
+		}
+	}
+	paths := append(normalizeVendoredPaths(spec.VendoredPaths), normalizeVendoredPaths(optionPaths)...)
+	packages := append(spec.VendoredPackages[:len(spec.VendoredPackages):len(spec.VendoredPackages)], optionPackages...)
+	if len(paths) == 0 && len(packages) == 0 {
+		return nil, nil
+	}
+	return &vendoredMatcher{
+		paths:    paths,
+		packages: packages,
+	}, nil
+}
+
+// filterAnnotations returns the Annotations that are not located within vendored files.
+//
+// An Annotation is located within a vendored file if its FileLocation is within a vendored file,
+// or if it has no FileLocation and its AgainstFileLocation is within a vendored file.
+// Annotations without any location are never filtered.
+func (v *vendoredMatcher) filterAnnotations(annotations []Annotation) []Annotation {
+	filtered := make([]Annotation, 0, len(annotations))
+	for _, annotation := range annotations {
+		if fileLocation := annotationPrimaryFileLocation(annotation); fileLocation != nil && v.isVendored(fileLocation.FileDescriptor()) {
+			continue
+		}
+		filtered = append(filtered, annotation)
+	}
+	return filtered
+}
+
+// annotationSinkWithVendoredMatcher returns an AnnotationSink that does not pass vendored
+// Annotations to the given AnnotationSink.
+//
+// If vendoredMatcher is nil, the AnnotationSink is returned as-is.
+func annotationSinkWithVendoredMatcher(annotationSink AnnotationSink, vendoredMatcher *vendoredMatcher) AnnotationSink {
+	if vendoredMatcher == nil {
+		return annotationSink
+	}
+	return func(annotation Annotation) {
+		if fileLocation := annotationPrimaryFileLocation(annotation); fileLocation != nil && vendoredMatcher.isVendored(fileLocation.FileDescriptor()) {
+			return
+		}
+		annotationSink(annotation)
+	}
+}
+
+func (v *vendoredMatcher) isVendored(fileDescriptor descriptor.FileDescriptor) bool {
+	protoreflectFileDescriptor := fileDescriptor.ProtoreflectFileDescriptor()
+	path := protoreflectFileDescriptor.Path()
+	for _, vendoredPath := range v.paths {
+		if matchesPathPrefix(path, vendoredPath) {
+			return true
+		}
+	}
+	packageName := string(protoreflectFileDescriptor.Package())
+	for _, vendoredPackage := range v.packages {
+		if matchesPackagePrefix(packageName, vendoredPackage) {
+			return true
+		}
+	}
+	return false
+}
+
+// annotationPrimaryFileLocation returns the FileLocation of the Annotation, or the
+// AgainstFileLocation if the Annotation has no FileLocation.
+//
+// Returns nil if the Annotation has no location.
+func annotationPrimaryFileLocation(annotation Annotation) descriptor.FileLocation {
+	if fileLocation := annotation.FileLocation(); fileLocation != nil {
+		return fileLocation
+	}
+	return annotation.AgainstFileLocation()
+}
+
+// matchesPathPrefix returns true if the path is equal to the prefix, or is within the
+// directory denoted by the prefix.
+func matchesPathPrefix(path string, prefix string) bool {
+	return path == prefix || strings.HasPrefix(path, prefix+"/")
+}
+
+// matchesPackagePrefix returns true if the package is equal to the prefix, or is a
+// sub-package of the prefix.
+func matchesPackagePrefix(packageName string, prefix string) bool {
+	return packageName == prefix || strings.HasPrefix(packageName, prefix+".")
+}
+
+func validateVendoredPaths(paths []string) error {
+	for _, path := range paths {
+		if normalizeVendoredPath(path) == "" {
+			return errors.New("vendored path is empty")
+		}
+		if strings.HasPrefix(path, "/") {
+			return fmt.Errorf("vendored path %q must be relative", path)
+		}
+	}
+	return nil
+}
+
+func validateVendoredPackages(packages []string) error {
+	for _, packageName := range packages {
+		if packageName == "" {
+			return errors.New("vendored package is empty")
+		}
+		if strings.HasPrefix(packageName, ".") || strings.HasSuffix(packageName, ".") {
+			return fmt.Errorf("vendored package %q must not start or end with a period", packageName)
+		}
+	}
+	return nil
+}
+
+func normalizeVendoredPaths(paths []string) []string {
+	normalizedPaths := make([]string, len(paths))
+	for i, path := range paths {
+		normalizedPaths[i] = normalizeVendoredPath(path)
+	}
+	return normalizedPaths
+}
+
+func normalizeVendoredPath(path string) string {
+	return strings.TrimSuffix(strings.TrimPrefix(path, "./"), "/")
+}
+
+// validateOptionSpecsWithoutVendoredOptionKeys validates that the OptionSpecs do not use the
+// keys of the vendored options, for Specs with VendoredOptions set.
+func validateOptionSpecsWithoutVendoredOptionKeys(optionSpecs []*OptionSpec) error {
+	for _, optionSpec := range optionSpecs {
+		switch optionSpec.Key {
+		case VendoredPathsOptionKey, VendoredPackagesOptionKey:
+			return fmt.Errorf("OptionSpec.Key %q is reserved when VendoredOptions is set", optionSpec.Key)
+		}
+	}
+	return nil
+}
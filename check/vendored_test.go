@@ -0,0 +1,172 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"context"
+	"testing"
+
+	descriptorv1 "buf.build/gen/go/bufbuild/bufplugin/protocolbuffers/go/buf/plugin/descriptor/v1"
+	"buf.build/go/bufplugin/descriptor"
+	"buf.build/go/bufplugin/option"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+func TestVendored(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	fileDescriptors, err := descriptor.FileDescriptorsForProtoFileDescriptors(
+		[]*descriptorv1.FileDescriptor{
+			{
+				FileDescriptorProto: &descriptorpb.FileDescriptorProto{
+					Name:           proto.String("foo.proto"),
+					Package:        proto.String("foo"),
+					SourceCodeInfo: &descriptorpb.SourceCodeInfo{},
+				},
+			},
+			{
+				FileDescriptorProto: &descriptorpb.FileDescriptorProto{
+					Name:           proto.String("third_party/a.proto"),
+					Package:        proto.String("a"),
+					SourceCodeInfo: &descriptorpb.SourceCodeInfo{},
+				},
+			},
+			{
+				FileDescriptorProto: &descriptorpb.FileDescriptorProto{
+					Name:           proto.String("google/type/date.proto"),
+					Package:        proto.String("google.type"),
+					SourceCodeInfo: &descriptorpb.SourceCodeInfo{},
+				},
+			},
+			{
+				FileDescriptorProto: &descriptorpb.FileDescriptorProto{
+					Name:           proto.String("third_party_not_vendored.proto"),
+					Package:        proto.String("googlenot"),
+					SourceCodeInfo: &descriptorpb.SourceCodeInfo{},
+				},
+			},
+		},
+	)
+	require.NoError(t, err)
+	rules := []*RuleSpec{
+		testNewAnnotatingLintRuleSpec(
+			"RULE1",
+			"foo.proto",
+			"third_party/a.proto",
+			"google/type/date.proto",
+			"third_party_not_vendored.proto",
+		),
+	}
+
+	client, err := NewClientForSpec(
+		&Spec{
+			Rules:            rules,
+			VendoredPaths:    []string{"third_party/"},
+			VendoredPackages: []string{"google"},
+		},
+	)
+	require.NoError(t, err)
+	request, err := NewRequest(fileDescriptors)
+	require.NoError(t, err)
+	response, err := client.Check(ctx, request)
+	require.NoError(t, err)
+	require.Equal(
+		t,
+		[]string{
+			"RULE1:foo.proto",
+			"RULE1:third_party_not_vendored.proto",
+		},
+		testAnnotationStrings(response.Annotations()),
+	)
+
+	// Options extend the vendored paths and packages on the Spec.
+	client, err = NewClientForSpec(&Spec{Rules: rules, VendoredOptions: true})
+	require.NoError(t, err)
+	options, err := option.NewOptions(
+		map[string]any{
+			VendoredPathsOptionKey:    []string{"foo.proto"},
+			VendoredPackagesOptionKey: []string{"a"},
+		},
+	)
+	require.NoError(t, err)
+	request, err = NewRequest(fileDescriptors, WithOptions(options))
+	require.NoError(t, err)
+	response, err = client.Check(ctx, request)
+	require.NoError(t, err)
+	require.Equal(
+		t,
+		[]string{
+			"RULE1:google/type/date.proto",
+			"RULE1:third_party_not_vendored.proto",
+		},
+		testAnnotationStrings(response.Annotations()),
+	)
+
+	// Options with an invalid type are rejected.
+	options, err = option.NewOptions(map[string]any{VendoredPathsOptionKey: true})
+	require.NoError(t, err)
+	request, err = NewRequest(fileDescriptors, WithOptions(options))
+	require.NoError(t, err)
+	_, err = client.Check(ctx, request)
+	require.Error(t, err)
+
+	// Options are not read unless the Spec opts in, so plugins can use the keys for options of
+	// their own, with values of any type.
+	noVendoredOptionsClient, err := NewClientForSpec(&Spec{Rules: rules})
+	require.NoError(t, err)
+	for _, value := range []any{true, []string{"foo.proto"}} {
+		options, err = option.NewOptions(map[string]any{VendoredPathsOptionKey: value})
+		require.NoError(t, err)
+		request, err = NewRequest(fileDescriptors, WithOptions(options))
+		require.NoError(t, err)
+		response, err = noVendoredOptionsClient.Check(ctx, request)
+		require.NoError(t, err)
+		require.Equal(
+			t,
+			[]string{
+				"RULE1:foo.proto",
+				"RULE1:google/type/date.proto",
+				"RULE1:third_party/a.proto",
+				"RULE1:third_party_not_vendored.proto",
+			},
+			testAnnotationStrings(response.Annotations()),
+		)
+	}
+
+	// Plugins that opt in cannot declare options with the same keys.
+	_, err = NewClientForSpec(
+		&Spec{
+			Rules:           rules,
+			VendoredOptions: true,
+			Options: []*OptionSpec{
+				{
+					Key:     VendoredPathsOptionKey,
+					Purpose: "Whether to check vendored paths.",
+					Type:    "bool",
+				},
+			},
+		},
+	)
+	require.Error(t, err)
+
+	// Invalid vendored paths on the Spec are rejected.
+	_, err = NewClientForSpec(&Spec{Rules: rules, VendoredPaths: []string{"/abs"}})
+	require.Error(t, err)
+	_, err = NewClientForSpec(&Spec{Rules: rules, VendoredPackages: []string{".google"}})
+	require.Error(t, err)
+}
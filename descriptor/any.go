@@ -0,0 +1,137 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package descriptor
+
+import (
+	"errors"
+	"fmt"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+)
+
+// UnmarshalAny unmarshals the google.protobuf.Any into a new message of the type named by its
+// type URL.
+//
+// The type is resolved with the resolver, which is typically a Resolver from NewResolver, or
+// the result of TypesForFileDescriptors, so that the message is a dynamicpb message of the type
+// declared within the FileDescriptors. This allows rules to inspect Any-typed options whose
+// concrete type is not linked into the plugin. Custom options and extensions within the
+// message are resolved with the resolver as well.
+//
+// The Any can be any message with the full name google.protobuf.Any, including the dynamicpb
+// messages within the results of SourceOptionsFor, and not only *anypb.Any. Use RangeAnys to
+// find the Any values within options.
+//
+// Returns an error wrapping protoregistry.NotFound if the type cannot be resolved.
+func UnmarshalAny(
+	anyMessage proto.Message,
+	resolver interface {
+		protoregistry.MessageTypeResolver
+		protoregistry.ExtensionTypeResolver
+	},
+) (proto.Message, error) {
+	if anyMessage == nil {
+		return nil, errors.New("nil Any")
+	}
+	typeURL, value, err := typeURLAndValueForAny(anyMessage.ProtoReflect())
+	if err != nil {
+		return nil, err
+	}
+	messageType, err := resolver.FindMessageByURL(typeURL)
+	if err != nil {
+		return nil, fmt.Errorf("could not resolve type %q: %w", typeURL, err)
+	}
+	message := messageType.New().Interface()
+	if err := (proto.UnmarshalOptions{Resolver: resolver}).Unmarshal(value, message); err != nil {
+		return nil, fmt.Errorf("could not unmarshal %q: %w", typeURL, err)
+	}
+	return message, nil
+}
+
+// RangeAnys calls f for each google.protobuf.Any within the message, including within repeated
+// fields, map values, and extensions, until f returns false.
+//
+// The contents of the Any values themselves are not searched, as they are not yet unmarshaled.
+// The message itself is passed to f if it is an Any. Only fields that are set are searched.
+func RangeAnys(message proto.Message, f func(anyMessage proto.Message) bool) {
+	if message == nil {
+		return
+	}
+	rangeAnys(message.ProtoReflect(), f)
+}
+
+// *** PRIVATE ***
+
+const anyFullName protoreflect.FullName = "google.protobuf.Any"
+
+// rangeAnys returns false if f returned false.
+func rangeAnys(message protoreflect.Message, f func(proto.Message) bool) bool {
+	if message.Descriptor().FullName() == anyFullName {
+		return f(message.Interface())
+	}
+	shouldContinue := true
+	message.Range(
+		func(fieldDescriptor protoreflect.FieldDescriptor, value protoreflect.Value) bool {
+			switch {
+			case fieldDescriptor.IsMap():
+				if !isMessageKind(fieldDescriptor.MapValue().Kind()) {
+					return true
+				}
+				value.Map().Range(
+					func(_ protoreflect.MapKey, mapValue protoreflect.Value) bool {
+						shouldContinue = rangeAnys(mapValue.Message(), f)
+						return shouldContinue
+					},
+				)
+			case fieldDescriptor.IsList():
+				if !isMessageKind(fieldDescriptor.Kind()) {
+					return true
+				}
+				list := value.List()
+				for i := 0; i < list.Len() && shouldContinue; i++ {
+					shouldContinue = rangeAnys(list.Get(i).Message(), f)
+				}
+			case isMessageKind(fieldDescriptor.Kind()):
+				shouldContinue = rangeAnys(value.Message(), f)
+			}
+			return shouldContinue
+		},
+	)
+	return shouldContinue
+}
+
+func typeURLAndValueForAny(message protoreflect.Message) (string, []byte, error) {
+	messageDescriptor := message.Descriptor()
+	if messageDescriptor.FullName() != anyFullName {
+		return "", nil, fmt.Errorf("%q is not %q", messageDescriptor.FullName(), anyFullName)
+	}
+	typeURLField := messageDescriptor.Fields().ByName("type_url")
+	valueField := messageDescriptor.Fields().ByName("value")
+	if typeURLField == nil || typeURLField.Kind() != protoreflect.StringKind ||
+		valueField == nil || valueField.Kind() != protoreflect.BytesKind {
+		return "", nil, fmt.Errorf("%q does not have the fields of %q", messageDescriptor.FullName(), anyFullName)
+	}
+	typeURL := message.Get(typeURLField).String()
+	if typeURL == "" {
+		return "", nil, errors.New("empty type URL within Any")
+	}
+	return typeURL, message.Get(valueField).Bytes(), nil
+}
+
+func isMessageKind(kind protoreflect.Kind) bool {
+	return kind == protoreflect.MessageKind || kind == protoreflect.GroupKind
+}
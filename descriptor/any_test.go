@@ -0,0 +1,261 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package descriptor_test
+
+import (
+	"context"
+	"sort"
+	"testing"
+
+	"buf.build/go/bufplugin/descriptor"
+	"buf.build/go/bufplugin/descriptor/descriptortest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+const testAnySource = `syntax = "proto3";
+package a;
+import "google/protobuf/any.proto";
+import "google/protobuf/descriptor.proto";
+message Bar {
+  string name = 1;
+}
+message Container {
+  message Nested {
+    google.protobuf.Any any = 1;
+  }
+  google.protobuf.Any single = 1;
+  repeated google.protobuf.Any list = 2;
+  map<string, google.protobuf.Any> map = 3;
+  Nested nested = 4;
+  repeated Nested nested_list = 5;
+  string other = 6;
+}
+extend google.protobuf.MessageOptions {
+  google.protobuf.Any any_option = 50000;
+}
+message WithOption {
+  option (a.any_option) = {
+    [type.googleapis.com/a.Bar] { name: "option" }
+  };
+}
+`
+
+func TestUnmarshalAny(t *testing.T) {
+	t.Parallel()
+
+	fileDescriptor, resolver := testCompileAnySource(t)
+	barMessage := testUnmarshalJSON(t, resolver, "a.Bar", `{"name": "foo"}`)
+	value, err := proto.Marshal(barMessage)
+	require.NoError(t, err)
+
+	t.Run("anypb", func(t *testing.T) {
+		t.Parallel()
+		message, err := descriptor.UnmarshalAny(
+			&anypb.Any{
+				TypeUrl: "type.googleapis.com/a.Bar",
+				Value:   value,
+			},
+			resolver,
+		)
+		require.NoError(t, err)
+		assert.True(t, proto.Equal(barMessage, message))
+	})
+	t.Run("source_options", func(t *testing.T) {
+		t.Parallel()
+		sourceOptions, err := fileDescriptor.SourceOptionsFor(
+			fileDescriptor.ProtoreflectFileDescriptor().Messages().ByName("WithOption"),
+		)
+		require.NoError(t, err)
+		var anyMessages []proto.Message
+		descriptor.RangeAnys(
+			sourceOptions,
+			func(anyMessage proto.Message) bool {
+				anyMessages = append(anyMessages, anyMessage)
+				return true
+			},
+		)
+		require.Len(t, anyMessages, 1)
+		message, err := descriptor.UnmarshalAny(anyMessages[0], resolver)
+		require.NoError(t, err)
+		assert.True(t, proto.Equal(testUnmarshalJSON(t, resolver, "a.Bar", `{"name": "option"}`), message))
+	})
+	t.Run("not_found", func(t *testing.T) {
+		t.Parallel()
+		_, err := descriptor.UnmarshalAny(
+			&anypb.Any{
+				TypeUrl: "type.googleapis.com/a.Baz",
+				Value:   value,
+			},
+			resolver,
+		)
+		assert.ErrorIs(t, err, protoregistry.NotFound)
+	})
+
+	testCases := []struct {
+		name                  string
+		anyMessage            proto.Message
+		expectedErrorContains string
+	}{
+		{
+			name:                  "nil",
+			expectedErrorContains: "nil Any",
+		},
+		{
+			name:                  "not_any",
+			anyMessage:            &descriptorpb.FileOptions{},
+			expectedErrorContains: `"google.protobuf.FileOptions" is not "google.protobuf.Any"`,
+		},
+		{
+			name:                  "empty_type_url",
+			anyMessage:            &anypb.Any{Value: value},
+			expectedErrorContains: "empty type URL",
+		},
+		{
+			name: "invalid_value",
+			anyMessage: &anypb.Any{
+				TypeUrl: "type.googleapis.com/a.Bar",
+				Value:   []byte{0xff},
+			},
+			expectedErrorContains: `could not unmarshal "type.googleapis.com/a.Bar"`,
+		},
+	}
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+			_, err := descriptor.UnmarshalAny(testCase.anyMessage, resolver)
+			require.ErrorContains(t, err, testCase.expectedErrorContains)
+		})
+	}
+}
+
+func TestRangeAnys(t *testing.T) {
+	t.Parallel()
+
+	_, resolver := testCompileAnySource(t)
+	containerMessage := testUnmarshalJSON(
+		t,
+		resolver,
+		"a.Container",
+		`{
+  "single": {"@type": "type.googleapis.com/a.Bar", "name": "single"},
+  "list": [
+    {"@type": "type.googleapis.com/a.Bar", "name": "list0"},
+    {"@type": "type.googleapis.com/a.Bar", "name": "list1"}
+  ],
+  "map": {"key": {"@type": "type.googleapis.com/a.Bar", "name": "map"}},
+  "nested": {"any": {"@type": "type.googleapis.com/a.Container.Nested"}},
+  "nestedList": [{"any": {"@type": "type.googleapis.com/a.Bar", "name": "nested_list"}}],
+  "other": "other"
+}`,
+	)
+
+	var fullNamesAndValues []string
+	descriptor.RangeAnys(
+		containerMessage,
+		func(anyMessage proto.Message) bool {
+			message, err := descriptor.UnmarshalAny(anyMessage, resolver)
+			require.NoError(t, err)
+			data, err := protojson.MarshalOptions{Resolver: resolver}.Marshal(message)
+			require.NoError(t, err)
+			fullNamesAndValues = append(fullNamesAndValues, string(message.ProtoReflect().Descriptor().FullName())+":"+string(data))
+			return true
+		},
+	)
+	sort.Strings(fullNamesAndValues)
+	// The contents of the Any values themselves are not searched.
+	assert.Equal(
+		t,
+		[]string{
+			`a.Bar:{"name":"list0"}`,
+			`a.Bar:{"name":"list1"}`,
+			`a.Bar:{"name":"map"}`,
+			`a.Bar:{"name":"nested_list"}`,
+			`a.Bar:{"name":"single"}`,
+			`a.Container.Nested:{}`,
+		},
+		fullNamesAndValues,
+	)
+
+	var count int
+	descriptor.RangeAnys(
+		containerMessage,
+		func(proto.Message) bool {
+			count++
+			return false
+		},
+	)
+	assert.Equal(t, 1, count)
+
+	anyMessage := &anypb.Any{TypeUrl: "type.googleapis.com/a.Bar"}
+	var anyMessages []proto.Message
+	descriptor.RangeAnys(
+		anyMessage,
+		func(anyMessage proto.Message) bool {
+			anyMessages = append(anyMessages, anyMessage)
+			return true
+		},
+	)
+	assert.Equal(t, []proto.Message{anyMessage}, anyMessages)
+
+	descriptor.RangeAnys(
+		nil,
+		func(proto.Message) bool {
+			require.FailNow(t, "f called for nil message")
+			return true
+		},
+	)
+	descriptor.RangeAnys(
+		testUnmarshalJSON(t, resolver, "a.Container", `{"other": "other"}`),
+		func(proto.Message) bool {
+			require.FailNow(t, "f called for message without Any values")
+			return true
+		},
+	)
+}
+
+func testCompileAnySource(t *testing.T) (descriptor.FileDescriptor, descriptor.Resolver) {
+	fileDescriptors, err := descriptortest.CompileStrings(
+		context.Background(),
+		map[string]string{
+			"a.proto": testAnySource,
+		},
+	)
+	require.NoError(t, err)
+	resolver, err := descriptor.NewResolver(fileDescriptors)
+	require.NoError(t, err)
+	for _, fileDescriptor := range fileDescriptors {
+		if fileDescriptor.FileDescriptorProto().GetName() == "a.proto" {
+			return fileDescriptor, resolver
+		}
+	}
+	require.FailNow(t, "a.proto not found")
+	return nil, nil
+}
+
+func testUnmarshalJSON(t *testing.T, resolver descriptor.Resolver, fullName protoreflect.FullName, data string) proto.Message {
+	messageType, err := resolver.FindMessageByName(fullName)
+	require.NoError(t, err)
+	message := messageType.New().Interface()
+	require.NoError(t, protojson.UnmarshalOptions{Resolver: resolver}.Unmarshal([]byte(data), message))
+	return message
+}
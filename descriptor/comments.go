@@ -0,0 +1,70 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package descriptor
+
+import (
+	"slices"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// Comments are the comments attached to a Descriptor within a FileDescriptor.
+//
+// Comments are never nil. If the Descriptor has no source location, or is not within the
+// FileDescriptor, all comments are empty.
+type Comments interface {
+	// Leading returns the leading comments, if any.
+	Leading() string
+	// Trailing returns the trailing comments, if any.
+	Trailing() string
+	// LeadingDetached returns the leading detached comments, if any.
+	LeadingDetached() []string
+
+	isComments()
+}
+
+// *** PRIVATE ***
+
+type comments struct {
+	sourceLocation protoreflect.SourceLocation
+}
+
+func newComments(
+	protoreflectFileDescriptor protoreflect.FileDescriptor,
+	descriptor protoreflect.Descriptor,
+) *comments {
+	comments := &comments{}
+	if descriptor == nil {
+		return comments
+	}
+	// If the Descriptor has no source location, or is not within the file, this is the
+	// zero value, which has no comments.
+	comments.sourceLocation = protoreflectFileDescriptor.SourceLocations().ByDescriptor(descriptor)
+	return comments
+}
+
+func (c *comments) Leading() string {
+	return c.sourceLocation.LeadingComments
+}
+
+func (c *comments) Trailing() string {
+	return c.sourceLocation.TrailingComments
+}
+
+func (c *comments) LeadingDetached() []string {
+	return slices.Clone(c.sourceLocation.LeadingDetachedComments)
+}
+
+func (*comments) isComments() {}
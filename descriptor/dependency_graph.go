@@ -0,0 +1,182 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package descriptor
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// DependencyGraph is the graph of imports between a set of FileDescriptors.
+//
+// Files are identified by their paths. All returned paths are sorted, except for
+// TopologicalOrder. Paths that are not within the DependencyGraph have no imports
+// or dependents.
+type DependencyGraph interface {
+	// Paths returns the paths of all files within the DependencyGraph.
+	Paths() []string
+	// DirectImports returns the paths of the files that the file at the path imports.
+	DirectImports(path string) []string
+	// TransitiveImports returns the paths of the files that the file at the path imports,
+	// directly or indirectly.
+	TransitiveImports(path string) []string
+	// DirectDependents returns the paths of the files that import the file at the path.
+	DirectDependents(path string) []string
+	// TransitiveDependents returns the paths of the files that import the file at the path,
+	// directly or indirectly.
+	TransitiveDependents(path string) []string
+	// TopologicalOrder returns the paths of all files, with every file after all of the files
+	// that it imports.
+	//
+	// The order is deterministic. Of the files whose imports have all been ordered, the file
+	// with the lexicographically smallest path is ordered first.
+	TopologicalOrder() []string
+
+	isDependencyGraph()
+}
+
+// NewDependencyGraph returns a new DependencyGraph for the FileDescriptors.
+//
+// Every file that is imported must be within the FileDescriptors. This is always the case
+// for the FileDescriptors of a check.Request, which include imports.
+func NewDependencyGraph(fileDescriptors []FileDescriptor) (DependencyGraph, error) {
+	pathToImports := make(map[string][]string, len(fileDescriptors))
+	for _, fileDescriptor := range fileDescriptors {
+		path := fileDescriptor.FileDescriptorProto().GetName()
+		if _, ok := pathToImports[path]; ok {
+			return nil, fmt.Errorf("duplicate file: %q", path)
+		}
+		imports := slices.Clone(fileDescriptor.FileDescriptorProto().GetDependency())
+		sort.Strings(imports)
+		pathToImports[path] = slices.Compact(imports)
+	}
+	pathToDependents := make(map[string][]string, len(pathToImports))
+	for path, imports := range pathToImports {
+		for _, importPath := range imports {
+			if _, ok := pathToImports[importPath]; !ok {
+				return nil, fmt.Errorf("file %q imports %q, which was not provided", path, importPath)
+			}
+			pathToDependents[importPath] = append(pathToDependents[importPath], path)
+		}
+	}
+	for _, dependents := range pathToDependents {
+		sort.Strings(dependents)
+	}
+	dependencyGraph := &dependencyGraph{
+		pathToImports:    pathToImports,
+		pathToDependents: pathToDependents,
+	}
+	topologicalOrder, err := dependencyGraph.computeTopologicalOrder()
+	if err != nil {
+		return nil, err
+	}
+	dependencyGraph.topologicalOrder = topologicalOrder
+	return dependencyGraph, nil
+}
+
+// *** PRIVATE ***
+
+type dependencyGraph struct {
+	pathToImports    map[string][]string
+	pathToDependents map[string][]string
+	topologicalOrder []string
+}
+
+func (d *dependencyGraph) Paths() []string {
+	paths := make([]string, 0, len(d.pathToImports))
+	for path := range d.pathToImports {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+func (d *dependencyGraph) DirectImports(path string) []string {
+	return slices.Clone(d.pathToImports[path])
+}
+
+func (d *dependencyGraph) TransitiveImports(path string) []string {
+	return transitiveClosure(path, d.pathToImports)
+}
+
+func (d *dependencyGraph) DirectDependents(path string) []string {
+	return slices.Clone(d.pathToDependents[path])
+}
+
+func (d *dependencyGraph) TransitiveDependents(path string) []string {
+	return transitiveClosure(path, d.pathToDependents)
+}
+
+func (d *dependencyGraph) TopologicalOrder() []string {
+	return slices.Clone(d.topologicalOrder)
+}
+
+func (*dependencyGraph) isDependencyGraph() {}
+
+func (d *dependencyGraph) computeTopologicalOrder() ([]string, error) {
+	pathToNumRemainingImports := make(map[string]int, len(d.pathToImports))
+	var ready []string
+	for path, imports := range d.pathToImports {
+		pathToNumRemainingImports[path] = len(imports)
+		if len(imports) == 0 {
+			ready = append(ready, path)
+		}
+	}
+	topologicalOrder := make([]string, 0, len(d.pathToImports))
+	for len(ready) > 0 {
+		sort.Strings(ready)
+		path := ready[0]
+		ready = ready[1:]
+		topologicalOrder = append(topologicalOrder, path)
+		for _, dependent := range d.pathToDependents[path] {
+			pathToNumRemainingImports[dependent]--
+			if pathToNumRemainingImports[dependent] == 0 {
+				ready = append(ready, dependent)
+			}
+		}
+	}
+	if len(topologicalOrder) != len(d.pathToImports) {
+		var cyclePaths []string
+		for path, numRemainingImports := range pathToNumRemainingImports {
+			if numRemainingImports > 0 {
+				cyclePaths = append(cyclePaths, path)
+			}
+		}
+		sort.Strings(cyclePaths)
+		return nil, fmt.Errorf("import cycle within the imports of files: %s", strings.Join(cyclePaths, ", "))
+	}
+	return topologicalOrder, nil
+}
+
+// transitiveClosure returns the sorted paths reachable from the path, excluding the path itself.
+func transitiveClosure(path string, pathToEdges map[string][]string) []string {
+	seen := map[string]struct{}{path: {}}
+	var result []string
+	stack := slices.Clone(pathToEdges[path])
+	for len(stack) > 0 {
+		next := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if _, ok := seen[next]; ok {
+			continue
+		}
+		seen[next] = struct{}{}
+		result = append(result, next)
+		stack = append(stack, pathToEdges[next]...)
+	}
+	sort.Strings(result)
+	return result
+}
@@ -0,0 +1,183 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package descriptor
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+func TestNewDependencyGraph(t *testing.T) {
+	t.Parallel()
+
+	// d imports b and c, which both import a.
+	dependencyGraph, err := NewDependencyGraph(
+		[]FileDescriptor{
+			testNewUnlinkedFileDescriptor("d.proto", "c.proto", "b.proto", "c.proto"),
+			testNewUnlinkedFileDescriptor("c.proto", "a.proto"),
+			testNewUnlinkedFileDescriptor("b.proto", "a.proto"),
+			testNewUnlinkedFileDescriptor("a.proto"),
+			testNewUnlinkedFileDescriptor("e.proto"),
+		},
+	)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a.proto", "b.proto", "c.proto", "d.proto", "e.proto"}, dependencyGraph.Paths())
+	assert.Equal(t, []string{"a.proto", "b.proto", "c.proto", "d.proto", "e.proto"}, dependencyGraph.TopologicalOrder())
+	testCases := []struct {
+		path                         string
+		expectedDirectImports        []string
+		expectedTransitiveImports    []string
+		expectedDirectDependents     []string
+		expectedTransitiveDependents []string
+	}{
+		{
+			path:                         "a.proto",
+			expectedDirectDependents:     []string{"b.proto", "c.proto"},
+			expectedTransitiveDependents: []string{"b.proto", "c.proto", "d.proto"},
+		},
+		{
+			path:                         "b.proto",
+			expectedDirectImports:        []string{"a.proto"},
+			expectedTransitiveImports:    []string{"a.proto"},
+			expectedDirectDependents:     []string{"d.proto"},
+			expectedTransitiveDependents: []string{"d.proto"},
+		},
+		{
+			// Duplicate imports are only returned once.
+			path:                      "d.proto",
+			expectedDirectImports:     []string{"b.proto", "c.proto"},
+			expectedTransitiveImports: []string{"a.proto", "b.proto", "c.proto"},
+		},
+		{
+			path: "e.proto",
+		},
+		{
+			path: "unknown.proto",
+		},
+	}
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.path, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, testCase.expectedDirectImports, nilIfEmpty(dependencyGraph.DirectImports(testCase.path)))
+			assert.Equal(t, testCase.expectedTransitiveImports, nilIfEmpty(dependencyGraph.TransitiveImports(testCase.path)))
+			assert.Equal(t, testCase.expectedDirectDependents, nilIfEmpty(dependencyGraph.DirectDependents(testCase.path)))
+			assert.Equal(t, testCase.expectedTransitiveDependents, nilIfEmpty(dependencyGraph.TransitiveDependents(testCase.path)))
+		})
+	}
+}
+
+func TestNewDependencyGraphTopologicalOrder(t *testing.T) {
+	t.Parallel()
+
+	// Of the files whose imports have all been ordered, the smallest path is next.
+	dependencyGraph, err := NewDependencyGraph(
+		[]FileDescriptor{
+			testNewUnlinkedFileDescriptor("a.proto", "z.proto"),
+			testNewUnlinkedFileDescriptor("b.proto"),
+			testNewUnlinkedFileDescriptor("z.proto"),
+			testNewUnlinkedFileDescriptor("c.proto", "a.proto"),
+		},
+	)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"b.proto", "z.proto", "a.proto", "c.proto"}, dependencyGraph.TopologicalOrder())
+}
+
+func TestNewDependencyGraphError(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name            string
+		fileDescriptors []FileDescriptor
+		expectedError   string
+	}{
+		{
+			name: "missing_import",
+			fileDescriptors: []FileDescriptor{
+				testNewUnlinkedFileDescriptor("a.proto", "b.proto"),
+			},
+			expectedError: `file "a.proto" imports "b.proto", which was not provided`,
+		},
+		{
+			name: "duplicate_file",
+			fileDescriptors: []FileDescriptor{
+				testNewUnlinkedFileDescriptor("a.proto"),
+				testNewUnlinkedFileDescriptor("a.proto"),
+			},
+			expectedError: `duplicate file: "a.proto"`,
+		},
+		{
+			name: "self_import",
+			fileDescriptors: []FileDescriptor{
+				testNewUnlinkedFileDescriptor("a.proto", "a.proto"),
+			},
+			expectedError: "import cycle within the imports of files: a.proto",
+		},
+		{
+			// Files that import a cycle are within the error, files that do not are not.
+			name: "cycle",
+			fileDescriptors: []FileDescriptor{
+				testNewUnlinkedFileDescriptor("a.proto", "b.proto"),
+				testNewUnlinkedFileDescriptor("b.proto", "c.proto"),
+				testNewUnlinkedFileDescriptor("c.proto", "a.proto", "d.proto"),
+				testNewUnlinkedFileDescriptor("d.proto"),
+				testNewUnlinkedFileDescriptor("e.proto", "a.proto"),
+			},
+			expectedError: "import cycle within the imports of files: a.proto, b.proto, c.proto, e.proto",
+		},
+	}
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+			_, err := NewDependencyGraph(testCase.fileDescriptors)
+			require.EqualError(t, err, testCase.expectedError)
+		})
+	}
+}
+
+// testNewUnlinkedFileDescriptor returns a new FileDescriptor with the path and imports that
+// does not link.
+//
+// This allows FileDescriptors with import cycles, which FileDescriptorsForProtoFileDescriptors
+// rejects, to be constructed.
+func testNewUnlinkedFileDescriptor(path string, imports ...string) FileDescriptor {
+	return newFileDescriptor(
+		func() (protoreflect.FileDescriptor, error) {
+			return nil, errors.New("not linked")
+		},
+		&descriptorpb.FileDescriptorProto{
+			Name:           proto.String(path),
+			Dependency:     imports,
+			SourceCodeInfo: &descriptorpb.SourceCodeInfo{},
+		},
+		false,
+		false,
+		nil,
+	)
+}
+
+func nilIfEmpty(paths []string) []string {
+	if len(paths) == 0 {
+		return nil
+	}
+	return paths
+}
@@ -0,0 +1,241 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package descriptordiff
+
+import (
+	"slices"
+	"strconv"
+
+	"buf.build/go/bufplugin/descriptor"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+const (
+	// ChangeKindAdded is an element that is only within the FileDescriptors.
+	ChangeKindAdded ChangeKind = iota + 1
+	// ChangeKindRemoved is an element that is only within the against FileDescriptors.
+	ChangeKindRemoved
+	// ChangeKindChanged is an element that is within both the FileDescriptors and the against
+	// FileDescriptors, with at least one differing Property.
+	ChangeKindChanged
+)
+
+const (
+	// ElementTypeFile is a file, identified by its path.
+	ElementTypeFile ElementType = iota + 1
+	// ElementTypeMessage is a message, identified by its full name.
+	ElementTypeMessage
+	// ElementTypeField is a field or extension, identified by the full name of the message it
+	// is within or extends, and its number.
+	ElementTypeField
+	// ElementTypeEnum is an enum, identified by its full name.
+	ElementTypeEnum
+	// ElementTypeEnumValue is an enum value, identified by the full name of its enum and its
+	// number.
+	//
+	// If multiple values of an enum have the same number, only the first is considered.
+	ElementTypeEnumValue
+	// ElementTypeService is a service, identified by its full name.
+	ElementTypeService
+	// ElementTypeMethod is a method, identified by its full name.
+	ElementTypeMethod
+)
+
+const (
+	// PropertyPackage is the package of a file.
+	PropertyPackage Property = iota + 1
+	// PropertyEdition is the edition of a file, which includes its syntax.
+	PropertyEdition
+	// PropertyImports is the set of imports of a file.
+	PropertyImports
+	// PropertyFile is the file that a message, enum, service, or extension is declared within.
+	PropertyFile
+	// PropertyName is the name of a field or enum value.
+	PropertyName
+	// PropertyJSONName is the JSON name of a field.
+	PropertyJSONName
+	// PropertyCardinality is the cardinality of a field.
+	PropertyCardinality
+	// PropertyKind is the kind of a field, for example string or message.
+	PropertyKind
+	// PropertyTypeName is the full name of the message or enum type of a field.
+	PropertyTypeName
+	// PropertyOneof is the name of the oneof that a field is within, if any.
+	//
+	// Synthetic oneofs for proto3 optional fields are not considered.
+	PropertyOneof
+	// PropertyDefault is the default value of a field.
+	PropertyDefault
+	// PropertyInputType is the full name of the input type of a method.
+	PropertyInputType
+	// PropertyOutputType is the full name of the output type of a method.
+	PropertyOutputType
+	// PropertyClientStreaming is whether or not a method is client streaming.
+	PropertyClientStreaming
+	// PropertyServerStreaming is whether or not a method is server streaming.
+	PropertyServerStreaming
+	// PropertyOptions is the options of any element, including custom options.
+	PropertyOptions
+)
+
+var (
+	changeKindToString = map[ChangeKind]string{
+		ChangeKindAdded:   "added",
+		ChangeKindRemoved: "removed",
+		ChangeKindChanged: "changed",
+	}
+	elementTypeToString = map[ElementType]string{
+		ElementTypeFile:      "file",
+		ElementTypeMessage:   "message",
+		ElementTypeField:     "field",
+		ElementTypeEnum:      "enum",
+		ElementTypeEnumValue: "enum_value",
+		ElementTypeService:   "service",
+		ElementTypeMethod:    "method",
+	}
+	propertyToString = map[Property]string{
+		PropertyPackage:         "package",
+		PropertyEdition:         "edition",
+		PropertyImports:         "imports",
+		PropertyFile:            "file",
+		PropertyName:            "name",
+		PropertyJSONName:        "json_name",
+		PropertyCardinality:     "cardinality",
+		PropertyKind:            "kind",
+		PropertyTypeName:        "type_name",
+		PropertyOneof:           "oneof",
+		PropertyDefault:         "default",
+		PropertyInputType:       "input_type",
+		PropertyOutputType:      "output_type",
+		PropertyClientStreaming: "client_streaming",
+		PropertyServerStreaming: "server_streaming",
+		PropertyOptions:         "options",
+	}
+)
+
+// ChangeKind is the kind of a Change.
+type ChangeKind int
+
+// String implements fmt.Stringer.
+func (c ChangeKind) String() string {
+	if s, ok := changeKindToString[c]; ok {
+		return s
+	}
+	return strconv.Itoa(int(c))
+}
+
+// ElementType is the type of the element that a Change is for.
+type ElementType int
+
+// String implements fmt.Stringer.
+func (e ElementType) String() string {
+	if s, ok := elementTypeToString[e]; ok {
+		return s
+	}
+	return strconv.Itoa(int(e))
+}
+
+// Property is a property of an element that has changed.
+type Property int
+
+// String implements fmt.Stringer.
+func (p Property) String() string {
+	if s, ok := propertyToString[p]; ok {
+		return s
+	}
+	return strconv.Itoa(int(p))
+}
+
+// Change is a single semantic change to an element between two sets of FileDescriptors.
+type Change interface {
+	// Kind returns the kind of the Change.
+	Kind() ChangeKind
+	// ElementType returns the type of the element.
+	ElementType() ElementType
+	// Name returns the name of the element.
+	//
+	// This is the path for files, and the full name otherwise. If the name of a field or enum
+	// value changed, this is the new name.
+	Name() string
+	// Descriptor returns the element within the FileDescriptors.
+	//
+	// This is nil for ChangeKindRemoved.
+	Descriptor() protoreflect.Descriptor
+	// AgainstDescriptor returns the element within the against FileDescriptors.
+	//
+	// This is nil for ChangeKindAdded.
+	AgainstDescriptor() protoreflect.Descriptor
+	// FileLocation returns the location of the element within the FileDescriptors.
+	//
+	// This is nil for ChangeKindRemoved.
+	FileLocation() descriptor.FileLocation
+	// AgainstFileLocation returns the location of the element within the against FileDescriptors.
+	//
+	// This is nil for ChangeKindAdded.
+	AgainstFileLocation() descriptor.FileLocation
+	// Properties returns the Properties that differ, in the order they are declared.
+	//
+	// This is only set for ChangeKindChanged.
+	Properties() []Property
+
+	isChange()
+}
+
+// *** PRIVATE ***
+
+type change struct {
+	kind                ChangeKind
+	elementType         ElementType
+	name                string
+	descriptor          protoreflect.Descriptor
+	againstDescriptor   protoreflect.Descriptor
+	fileLocation        descriptor.FileLocation
+	againstFileLocation descriptor.FileLocation
+	properties          []Property
+}
+
+func (c *change) Kind() ChangeKind {
+	return c.kind
+}
+
+func (c *change) ElementType() ElementType {
+	return c.elementType
+}
+
+func (c *change) Name() string {
+	return c.name
+}
+
+func (c *change) Descriptor() protoreflect.Descriptor {
+	return c.descriptor
+}
+
+func (c *change) AgainstDescriptor() protoreflect.Descriptor {
+	return c.againstDescriptor
+}
+
+func (c *change) FileLocation() descriptor.FileLocation {
+	return c.fileLocation
+}
+
+func (c *change) AgainstFileLocation() descriptor.FileLocation {
+	return c.againstFileLocation
+}
+
+func (c *change) Properties() []Property {
+	return slices.Clone(c.properties)
+}
+
+func (*change) isChange() {}
@@ -0,0 +1,398 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package descriptordiff computes semantic diffs between sets of descriptors.
+//
+// This is typically used for breaking change Rules, which can compute a diff once between
+// the FileDescriptors and AgainstFileDescriptors of a check.Request, instead of each
+// re-deriving the differences within pair RuleHandlers.
+package descriptordiff // import "buf.build/go/bufplugin/descriptor/descriptordiff"
+
+import (
+	"bytes"
+	"fmt"
+	"slices"
+	"sort"
+
+	"buf.build/go/bufplugin/descriptor"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// DiffOption is an option for Diff.
+type DiffOption func(*diffOptions)
+
+// DiffWithoutImports returns a new DiffOption that ignores all imports within both sets of
+// FileDescriptors.
+//
+// The default is to include imports.
+func DiffWithoutImports() DiffOption {
+	return func(diffOptions *diffOptions) {
+		diffOptions.withoutImports = true
+	}
+}
+
+// Diff returns the Changes from the against FileDescriptors to the FileDescriptors.
+//
+// Elements are paired up between the two sets of FileDescriptors by the identity described
+// on each ElementType, in the same way as the pair RuleHandlers of checkutil. Elements are
+// considered independently, so that for example removing a message results in a Change for
+// the message, and a Change for each of its fields.
+//
+// Changes are sorted by ElementType, then by Name, then by ChangeKind.
+//
+// Returns an error if either set of FileDescriptors contains duplicate elements.
+func Diff(
+	fileDescriptors []descriptor.FileDescriptor,
+	againstFileDescriptors []descriptor.FileDescriptor,
+	options ...DiffOption,
+) ([]Change, error) {
+	diffOptions := newDiffOptions()
+	for _, option := range options {
+		option(diffOptions)
+	}
+	index, err := newIndex(filterFileDescriptors(fileDescriptors, diffOptions.withoutImports))
+	if err != nil {
+		return nil, err
+	}
+	againstIndex, err := newIndex(filterFileDescriptors(againstFileDescriptors, diffOptions.withoutImports))
+	if err != nil {
+		return nil, err
+	}
+	var changes []*change
+	for _, elementType := range allElementTypes {
+		keyToDescriptor := index.elementTypeToKeyToDescriptor[elementType]
+		againstKeyToDescriptor := againstIndex.elementTypeToKeyToDescriptor[elementType]
+		for key, againstDescriptor := range againstKeyToDescriptor {
+			protoreflectDescriptor, ok := keyToDescriptor[key]
+			if !ok {
+				changes = append(
+					changes,
+					&change{
+						kind:                ChangeKindRemoved,
+						elementType:         elementType,
+						name:                nameForDescriptor(againstDescriptor),
+						againstDescriptor:   againstDescriptor,
+						againstFileLocation: againstIndex.fileLocationForDescriptor(againstDescriptor),
+					},
+				)
+				continue
+			}
+			properties, err := propertiesFor(elementType, index, protoreflectDescriptor, againstIndex, againstDescriptor)
+			if err != nil {
+				return nil, err
+			}
+			if len(properties) == 0 {
+				continue
+			}
+			changes = append(
+				changes,
+				&change{
+					kind:                ChangeKindChanged,
+					elementType:         elementType,
+					name:                nameForDescriptor(protoreflectDescriptor),
+					descriptor:          protoreflectDescriptor,
+					againstDescriptor:   againstDescriptor,
+					fileLocation:        index.fileLocationForDescriptor(protoreflectDescriptor),
+					againstFileLocation: againstIndex.fileLocationForDescriptor(againstDescriptor),
+					properties:          properties,
+				},
+			)
+		}
+		for key, protoreflectDescriptor := range keyToDescriptor {
+			if _, ok := againstKeyToDescriptor[key]; ok {
+				continue
+			}
+			changes = append(
+				changes,
+				&change{
+					kind:         ChangeKindAdded,
+					elementType:  elementType,
+					name:         nameForDescriptor(protoreflectDescriptor),
+					descriptor:   protoreflectDescriptor,
+					fileLocation: index.fileLocationForDescriptor(protoreflectDescriptor),
+				},
+			)
+		}
+	}
+	sort.Slice(
+		changes,
+		func(i int, j int) bool {
+			one := changes[i]
+			two := changes[j]
+			if one.elementType != two.elementType {
+				return one.elementType < two.elementType
+			}
+			if one.name != two.name {
+				return one.name < two.name
+			}
+			return one.kind < two.kind
+		},
+	)
+	result := make([]Change, len(changes))
+	for i, change := range changes {
+		result[i] = change
+	}
+	return result, nil
+}
+
+// *** PRIVATE ***
+
+var allElementTypes = []ElementType{
+	ElementTypeFile,
+	ElementTypeMessage,
+	ElementTypeField,
+	ElementTypeEnum,
+	ElementTypeEnumValue,
+	ElementTypeService,
+	ElementTypeMethod,
+}
+
+type diffOptions struct {
+	withoutImports bool
+}
+
+func newDiffOptions() *diffOptions {
+	return &diffOptions{}
+}
+
+type index struct {
+	pathToFileDescriptor         map[string]descriptor.FileDescriptor
+	elementTypeToKeyToDescriptor map[ElementType]map[string]protoreflect.Descriptor
+}
+
+func newIndex(fileDescriptors []descriptor.FileDescriptor) (*index, error) {
+	index := &index{
+		pathToFileDescriptor:         make(map[string]descriptor.FileDescriptor, len(fileDescriptors)),
+		elementTypeToKeyToDescriptor: make(map[ElementType]map[string]protoreflect.Descriptor, len(allElementTypes)),
+	}
+	for _, elementType := range allElementTypes {
+		index.elementTypeToKeyToDescriptor[elementType] = make(map[string]protoreflect.Descriptor)
+	}
+	for _, fileDescriptor := range fileDescriptors {
+		if err := fileDescriptor.Link(); err != nil {
+			return nil, err
+		}
+		protoreflectFileDescriptor := fileDescriptor.ProtoreflectFileDescriptor()
+		path := protoreflectFileDescriptor.Path()
+		if _, ok := index.pathToFileDescriptor[path]; ok {
+			return nil, fmt.Errorf("duplicate file: %q", path)
+		}
+		index.pathToFileDescriptor[path] = fileDescriptor
+		if err := index.add(ElementTypeFile, path, protoreflectFileDescriptor); err != nil {
+			return nil, err
+		}
+		if err := descriptor.Walk(
+			fileDescriptor,
+			&descriptor.VisitorFuncs{
+				Message: func(messageDescriptor protoreflect.MessageDescriptor) error {
+					if messageDescriptor.IsMapEntry() {
+						// Map entries are compared as the fields of the message they are within.
+						return descriptor.SkipChildren
+					}
+					return index.add(ElementTypeMessage, string(messageDescriptor.FullName()), messageDescriptor)
+				},
+				Field: func(fieldDescriptor protoreflect.FieldDescriptor) error {
+					key := fmt.Sprintf("%s:%d", fieldDescriptor.ContainingMessage().FullName(), fieldDescriptor.Number())
+					return index.add(ElementTypeField, key, fieldDescriptor)
+				},
+				Enum: func(enumDescriptor protoreflect.EnumDescriptor) error {
+					return index.add(ElementTypeEnum, string(enumDescriptor.FullName()), enumDescriptor)
+				},
+				EnumValue: func(enumValueDescriptor protoreflect.EnumValueDescriptor) error {
+					key := fmt.Sprintf("%s:%d", enumValueDescriptor.Parent().FullName(), enumValueDescriptor.Number())
+					if _, ok := index.elementTypeToKeyToDescriptor[ElementTypeEnumValue][key]; ok {
+						// An alias, only the first value for each number is considered.
+						return nil
+					}
+					return index.add(ElementTypeEnumValue, key, enumValueDescriptor)
+				},
+				Service: func(serviceDescriptor protoreflect.ServiceDescriptor) error {
+					return index.add(ElementTypeService, string(serviceDescriptor.FullName()), serviceDescriptor)
+				},
+				Method: func(methodDescriptor protoreflect.MethodDescriptor) error {
+					return index.add(ElementTypeMethod, string(methodDescriptor.FullName()), methodDescriptor)
+				},
+			},
+		); err != nil {
+			return nil, err
+		}
+	}
+	return index, nil
+}
+
+func (i *index) add(elementType ElementType, key string, protoreflectDescriptor protoreflect.Descriptor) error {
+	keyToDescriptor := i.elementTypeToKeyToDescriptor[elementType]
+	if _, ok := keyToDescriptor[key]; ok {
+		return fmt.Errorf("duplicate %v: %q", elementType, key)
+	}
+	keyToDescriptor[key] = protoreflectDescriptor
+	return nil
+}
+
+func (i *index) fileLocationForDescriptor(protoreflectDescriptor protoreflect.Descriptor) descriptor.FileLocation {
+	protoreflectFileDescriptor := protoreflectDescriptor.ParentFile()
+	fileDescriptor, ok := i.pathToFileDescriptor[protoreflectFileDescriptor.Path()]
+	if !ok {
+		// All Descriptors within an index are within its files.
+		return nil
+	}
+	if _, ok := protoreflectDescriptor.(protoreflect.FileDescriptor); ok {
+		// Files are referenced as a whole, rather than by the SourceLocation that spans the file.
+		return descriptor.FileLocationFor(fileDescriptor, nil)
+	}
+	return descriptor.FileLocationFor(fileDescriptor, protoreflectDescriptor)
+}
+
+func propertiesFor(
+	elementType ElementType,
+	index *index,
+	protoreflectDescriptor protoreflect.Descriptor,
+	againstIndex *index,
+	againstDescriptor protoreflect.Descriptor,
+) ([]Property, error) {
+	var properties []Property
+	addIf := func(property Property, changed bool) {
+		if changed {
+			properties = append(properties, property)
+		}
+	}
+	switch elementType {
+	case ElementTypeFile:
+		fileDescriptor := index.pathToFileDescriptor[protoreflectDescriptor.ParentFile().Path()]
+		againstFileDescriptor := againstIndex.pathToFileDescriptor[againstDescriptor.ParentFile().Path()]
+		addIf(PropertyPackage, protoreflectDescriptor.ParentFile().Package() != againstDescriptor.ParentFile().Package())
+		addIf(PropertyEdition, fileDescriptor.Edition() != againstFileDescriptor.Edition())
+		addIf(PropertyImports, !slices.Equal(importPaths(protoreflectDescriptor.ParentFile()), importPaths(againstDescriptor.ParentFile())))
+	case ElementTypeMessage, ElementTypeEnum, ElementTypeService:
+		addIf(PropertyFile, protoreflectDescriptor.ParentFile().Path() != againstDescriptor.ParentFile().Path())
+	case ElementTypeField:
+		fieldDescriptor, ok := protoreflectDescriptor.(protoreflect.FieldDescriptor)
+		if !ok {
+			return nil, fmt.Errorf("expected protoreflect.FieldDescriptor, got %T", protoreflectDescriptor)
+		}
+		againstFieldDescriptor, ok := againstDescriptor.(protoreflect.FieldDescriptor)
+		if !ok {
+			return nil, fmt.Errorf("expected protoreflect.FieldDescriptor, got %T", againstDescriptor)
+		}
+		addIf(PropertyFile, fieldDescriptor.IsExtension() && fieldDescriptor.ParentFile().Path() != againstFieldDescriptor.ParentFile().Path())
+		addIf(PropertyName, fieldDescriptor.Name() != againstFieldDescriptor.Name())
+		addIf(PropertyJSONName, fieldDescriptor.JSONName() != againstFieldDescriptor.JSONName())
+		addIf(PropertyCardinality, fieldDescriptor.Cardinality() != againstFieldDescriptor.Cardinality())
+		addIf(PropertyKind, fieldDescriptor.Kind() != againstFieldDescriptor.Kind())
+		addIf(PropertyTypeName, fieldTypeName(fieldDescriptor) != fieldTypeName(againstFieldDescriptor))
+		addIf(PropertyOneof, oneofName(fieldDescriptor) != oneofName(againstFieldDescriptor))
+		addIf(PropertyDefault, defaultString(fieldDescriptor) != defaultString(againstFieldDescriptor))
+	case ElementTypeEnumValue:
+		addIf(PropertyName, protoreflectDescriptor.Name() != againstDescriptor.Name())
+	case ElementTypeMethod:
+		methodDescriptor, ok := protoreflectDescriptor.(protoreflect.MethodDescriptor)
+		if !ok {
+			return nil, fmt.Errorf("expected protoreflect.MethodDescriptor, got %T", protoreflectDescriptor)
+		}
+		againstMethodDescriptor, ok := againstDescriptor.(protoreflect.MethodDescriptor)
+		if !ok {
+			return nil, fmt.Errorf("expected protoreflect.MethodDescriptor, got %T", againstDescriptor)
+		}
+		addIf(PropertyInputType, methodDescriptor.Input().FullName() != againstMethodDescriptor.Input().FullName())
+		addIf(PropertyOutputType, methodDescriptor.Output().FullName() != againstMethodDescriptor.Output().FullName())
+		addIf(PropertyClientStreaming, methodDescriptor.IsStreamingClient() != againstMethodDescriptor.IsStreamingClient())
+		addIf(PropertyServerStreaming, methodDescriptor.IsStreamingServer() != againstMethodDescriptor.IsStreamingServer())
+	}
+	optionsEqual, err := optionsEqual(protoreflectDescriptor, againstDescriptor)
+	if err != nil {
+		return nil, err
+	}
+	addIf(PropertyOptions, !optionsEqual)
+	return properties, nil
+}
+
+func nameForDescriptor(protoreflectDescriptor protoreflect.Descriptor) string {
+	if protoreflectFileDescriptor, ok := protoreflectDescriptor.(protoreflect.FileDescriptor); ok {
+		return protoreflectFileDescriptor.Path()
+	}
+	return string(protoreflectDescriptor.FullName())
+}
+
+func importPaths(protoreflectFileDescriptor protoreflect.FileDescriptor) []string {
+	imports := protoreflectFileDescriptor.Imports()
+	paths := make([]string, imports.Len())
+	for i := 0; i < imports.Len(); i++ {
+		paths[i] = imports.Get(i).Path()
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+func fieldTypeName(fieldDescriptor protoreflect.FieldDescriptor) protoreflect.FullName {
+	if messageDescriptor := fieldDescriptor.Message(); messageDescriptor != nil {
+		return messageDescriptor.FullName()
+	}
+	if enumDescriptor := fieldDescriptor.Enum(); enumDescriptor != nil {
+		return enumDescriptor.FullName()
+	}
+	return ""
+}
+
+func oneofName(fieldDescriptor protoreflect.FieldDescriptor) protoreflect.Name {
+	if oneofDescriptor := fieldDescriptor.ContainingOneof(); oneofDescriptor != nil && !descriptor.IsSyntheticOneof(oneofDescriptor) {
+		return oneofDescriptor.Name()
+	}
+	return ""
+}
+
+func defaultString(fieldDescriptor protoreflect.FieldDescriptor) string {
+	if !fieldDescriptor.HasDefault() {
+		return ""
+	}
+	if enumValueDescriptor := fieldDescriptor.DefaultEnumValue(); enumValueDescriptor != nil {
+		return string(enumValueDescriptor.Name())
+	}
+	return fmt.Sprintf("%v", fieldDescriptor.Default().Interface())
+}
+
+// optionsEqual compares options by their deterministic serialization, so that options from
+// separately-built sets of FileDescriptors, including custom options, compare equal.
+func optionsEqual(one protoreflect.Descriptor, two protoreflect.Descriptor) (bool, error) {
+	oneData, err := marshalOptions(one)
+	if err != nil {
+		return false, err
+	}
+	twoData, err := marshalOptions(two)
+	if err != nil {
+		return false, err
+	}
+	return bytes.Equal(oneData, twoData), nil
+}
+
+func marshalOptions(protoreflectDescriptor protoreflect.Descriptor) ([]byte, error) {
+	options := protoreflectDescriptor.Options()
+	if options == nil || !options.ProtoReflect().IsValid() {
+		return nil, nil
+	}
+	return proto.MarshalOptions{Deterministic: true}.Marshal(options)
+}
+
+func filterFileDescriptors(fileDescriptors []descriptor.FileDescriptor, withoutImports bool) []descriptor.FileDescriptor {
+	if !withoutImports {
+		return fileDescriptors
+	}
+	filteredFileDescriptors := make([]descriptor.FileDescriptor, 0, len(fileDescriptors))
+	for _, fileDescriptor := range fileDescriptors {
+		if !fileDescriptor.IsImport() {
+			filteredFileDescriptors = append(filteredFileDescriptors, fileDescriptor)
+		}
+	}
+	return filteredFileDescriptors
+}
@@ -0,0 +1,143 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package descriptordiff
+
+import (
+	"testing"
+
+	"buf.build/go/bufplugin/descriptor"
+	"buf.build/go/bufplugin/descriptor/descriptortest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+func TestDiff(t *testing.T) {
+	t.Parallel()
+
+	againstFileDescriptorSet := newTestCorpusFileDescriptorSet(t)
+	fileDescriptorSet := newTestCorpusFileDescriptorSet(t)
+	fileDescriptorProto := fileDescriptorSet.GetFile()[0]
+	fieldDescriptorProto := fileDescriptorProto.GetMessageType()[0].GetField()[0]
+	fieldDescriptorProto.Name = proto.String("renamed")
+	fieldDescriptorProto.JsonName = proto.String("renamed")
+	fileDescriptorProto.MessageType = append(
+		fileDescriptorProto.MessageType,
+		&descriptorpb.DescriptorProto{
+			Name: proto.String("Added"),
+		},
+	)
+	enumDescriptorProto := fileDescriptorProto.GetEnumType()[0]
+	enumDescriptorProto.Value = enumDescriptorProto.GetValue()[:len(enumDescriptorProto.GetValue())-1]
+	fileDescriptorProto.GetService()[0].GetMethod()[0].ClientStreaming = proto.Bool(true)
+
+	fileDescriptors, err := descriptor.FileDescriptorsForFileDescriptorSet(fileDescriptorSet)
+	require.NoError(t, err)
+	againstFileDescriptors, err := descriptor.FileDescriptorsForFileDescriptorSet(againstFileDescriptorSet)
+	require.NoError(t, err)
+
+	changes, err := Diff(fileDescriptors, againstFileDescriptors)
+	require.NoError(t, err)
+	require.Len(t, changes, 4)
+
+	assert.Equal(t, ChangeKindAdded, changes[0].Kind())
+	assert.Equal(t, ElementTypeMessage, changes[0].ElementType())
+	assert.Equal(t, "corpus.file0.Added", changes[0].Name())
+	assert.NotNil(t, changes[0].Descriptor())
+	assert.Nil(t, changes[0].AgainstDescriptor())
+	assert.Nil(t, changes[0].AgainstFileLocation())
+
+	assert.Equal(t, ChangeKindChanged, changes[1].Kind())
+	assert.Equal(t, ElementTypeField, changes[1].ElementType())
+	assert.Equal(t, "corpus.file0.Message0.renamed", changes[1].Name())
+	assert.Equal(t, []Property{PropertyName, PropertyJSONName}, changes[1].Properties())
+	require.NotNil(t, changes[1].FileLocation())
+	require.NotNil(t, changes[1].AgainstFileLocation())
+	assert.Equal(t, changes[1].FileLocation().StartLine(), changes[1].AgainstFileLocation().StartLine())
+
+	assert.Equal(t, ChangeKindRemoved, changes[2].Kind())
+	assert.Equal(t, ElementTypeEnumValue, changes[2].ElementType())
+	assert.Nil(t, changes[2].Descriptor())
+	assert.NotNil(t, changes[2].AgainstDescriptor())
+	assert.Nil(t, changes[2].FileLocation())
+
+	assert.Equal(t, ChangeKindChanged, changes[3].Kind())
+	assert.Equal(t, ElementTypeMethod, changes[3].ElementType())
+	assert.Equal(t, []Property{PropertyClientStreaming}, changes[3].Properties())
+}
+
+func TestDiffEqual(t *testing.T) {
+	t.Parallel()
+
+	fileDescriptors, err := descriptor.FileDescriptorsForFileDescriptorSet(newTestCorpusFileDescriptorSet(t))
+	require.NoError(t, err)
+	againstFileDescriptors, err := descriptor.FileDescriptorsForFileDescriptorSet(newTestCorpusFileDescriptorSet(t))
+	require.NoError(t, err)
+	changes, err := Diff(fileDescriptors, againstFileDescriptors)
+	require.NoError(t, err)
+	assert.Empty(t, changes)
+}
+
+func TestDiffOptions(t *testing.T) {
+	t.Parallel()
+
+	againstFileDescriptorSet := newTestCorpusFileDescriptorSet(t)
+	fileDescriptorSet := newTestCorpusFileDescriptorSet(t)
+	fileDescriptorSet.GetFile()[0].GetOptions().GoPackage = proto.String("example.com/changed")
+
+	fileDescriptors, err := descriptor.FileDescriptorsForFileDescriptorSet(fileDescriptorSet)
+	require.NoError(t, err)
+	againstFileDescriptors, err := descriptor.FileDescriptorsForFileDescriptorSet(againstFileDescriptorSet)
+	require.NoError(t, err)
+	changes, err := Diff(fileDescriptors, againstFileDescriptors)
+	require.NoError(t, err)
+	require.Len(t, changes, 1)
+	assert.Equal(t, ElementTypeFile, changes[0].ElementType())
+	assert.Equal(t, "corpus/file0.proto", changes[0].Name())
+	assert.Equal(t, []Property{PropertyOptions}, changes[0].Properties())
+
+	// Imports are excluded on both sides, so every element within the file is added.
+	againstFileDescriptors, err = descriptor.FileDescriptorsForFileDescriptorSet(
+		againstFileDescriptorSet,
+		descriptor.FileDescriptorSetWithImportFilePaths("corpus/file0.proto"),
+	)
+	require.NoError(t, err)
+	changes, err = Diff(fileDescriptors, againstFileDescriptors, DiffWithoutImports())
+	require.NoError(t, err)
+	require.NotEmpty(t, changes)
+	assert.Equal(t, ElementTypeFile, changes[0].ElementType())
+	assert.Equal(t, "corpus/file0.proto", changes[0].Name())
+	for _, change := range changes {
+		assert.Equal(t, ChangeKindAdded, change.Kind())
+	}
+}
+
+func newTestCorpusFileDescriptorSet(t *testing.T) *descriptorpb.FileDescriptorSet {
+	fileDescriptorSet, err := descriptortest.NewCorpusFileDescriptorSet(
+		&descriptortest.CorpusSpec{
+			NumFiles:             1,
+			NumMessagesPerFile:   2,
+			NumFieldsPerMessage:  3,
+			NumEnumsPerFile:      1,
+			NumValuesPerEnum:     2,
+			NumServicesPerFile:   1,
+			NumMethodsPerService: 1,
+			WithOptions:          true,
+		},
+	)
+	require.NoError(t, err)
+	return fileDescriptorSet
+}
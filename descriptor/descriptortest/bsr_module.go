@@ -0,0 +1,152 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package descriptortest
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"buf.build/go/bufplugin/descriptor"
+	"buf.build/go/bufplugin/internal/pkg/bufimage"
+	"buf.build/go/bufplugin/internal/pkg/cache"
+)
+
+// ErrBufNotFound is returned from BSRModuleSpec.ToFileDescriptors if buf could not be found.
+//
+// Tests typically skip if this is returned, so that tests against BSR modules are only run on
+// machines where buf is installed.
+var ErrBufNotFound = errors.New("buf not found")
+
+var (
+	globalBSRModuleKeyToSingleton = make(map[string]*cache.Singleton[[]descriptor.FileDescriptor])
+	globalBSRModuleLock           sync.Mutex
+)
+
+// BSRModuleSpec specifies a module on the Buf Schema Registry to produce FileDescriptors for.
+//
+// This allows plugins to be tested against real-world public schemas, for example
+// buf.build/googleapis/googleapis, without vendoring them into testdata.
+type BSRModuleSpec struct {
+	// Name is the full name of the module, for example "buf.build/googleapis/googleapis".
+	//
+	// Required.
+	Name string
+	// Commit is the commit ID of the module.
+	//
+	// Required. This should be a commit ID rather than a label such as "main", so that the
+	// produced FileDescriptors do not change as the module is updated.
+	Commit string
+	// FilePaths are the specific paths within the module to mark as non-imports.
+	//
+	// If empty, all files within the module are non-imports, and all files from the
+	// dependencies of the module are imports.
+	FilePaths []string
+	// BufPath is the path to the buf binary.
+	//
+	// If empty, buf is looked up on the PATH.
+	BufPath string
+}
+
+// ToFileDescriptors builds the module with buf, and returns the FileDescriptors of the
+// module and its dependencies.
+//
+// This requires buf, and network access to the Buf Schema Registry unless the module is within
+// the buf cache. Returns an error wrapping ErrBufNotFound if buf could not be found. The
+// FileDescriptors are in the same deterministic order as with CompileStrings.
+//
+// Results are cached for the lifetime of the process, keyed by the Name, Commit, and FilePaths.
+// The returned FileDescriptors may be shared between callers, and must not be modified.
+func (b *BSRModuleSpec) ToFileDescriptors(ctx context.Context) ([]descriptor.FileDescriptor, error) {
+	if err := validateBSRModuleSpec(b); err != nil {
+		return nil, err
+	}
+	key := b.Name + ":" + b.Commit + ":" + strings.Join(b.FilePaths, ",")
+	globalBSRModuleLock.Lock()
+	singleton, ok := globalBSRModuleKeyToSingleton[key]
+	if !ok {
+		// Clone the spec, as the build may happen after the caller has modified it.
+		bsrModuleSpec := *b
+		bsrModuleSpec.FilePaths = slices.Clone(b.FilePaths)
+		singleton = cache.NewSingleton(
+			func(ctx context.Context) ([]descriptor.FileDescriptor, error) {
+				return buildBSRModule(ctx, &bsrModuleSpec)
+			},
+		)
+		globalBSRModuleKeyToSingleton[key] = singleton
+	}
+	globalBSRModuleLock.Unlock()
+	return singleton.Get(ctx)
+}
+
+// *** PRIVATE ***
+
+func validateBSRModuleSpec(bsrModuleSpec *BSRModuleSpec) error {
+	if bsrModuleSpec == nil {
+		return errors.New("BSRModuleSpec is nil")
+	}
+	if bsrModuleSpec.Name == "" {
+		return errors.New("BSRModuleSpec.Name is empty")
+	}
+	if strings.ContainsAny(bsrModuleSpec.Name, ":@") || strings.Count(bsrModuleSpec.Name, "/") != 2 {
+		return fmt.Errorf("BSRModuleSpec.Name %q is not a module full name of the form remote/owner/module", bsrModuleSpec.Name)
+	}
+	if bsrModuleSpec.Commit == "" {
+		return errors.New("BSRModuleSpec.Commit is empty")
+	}
+	return nil
+}
+
+func buildBSRModule(ctx context.Context, bsrModuleSpec *BSRModuleSpec) ([]descriptor.FileDescriptor, error) {
+	bufPath := bsrModuleSpec.BufPath
+	if bufPath == "" {
+		bufPath = "buf"
+	}
+	bufPath, err := exec.LookPath(bufPath)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrBufNotFound, err)
+	}
+	tempDirPath, err := os.MkdirTemp("", "bufplugin-bsr-module")
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = os.RemoveAll(tempDirPath)
+	}()
+	moduleRef := bsrModuleSpec.Name + ":" + bsrModuleSpec.Commit
+	outputFilePath := filepath.Join(tempDirPath, "image.binpb")
+	stderr := bytes.NewBuffer(nil)
+	cmd := exec.CommandContext(ctx, bufPath, "build", moduleRef, "-o", outputFilePath)
+	cmd.Stderr = stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("buf build %s: %w: %s", moduleRef, err, strings.TrimSpace(stderr.String()))
+	}
+	data, err := os.ReadFile(outputFilePath)
+	if err != nil {
+		return nil, err
+	}
+	fileDescriptors, err := bufimage.FileDescriptorsForData(data, bsrModuleSpec.FilePaths)
+	if err != nil {
+		return nil, fmt.Errorf("buf build %s: %w", moduleRef, err)
+	}
+	return fileDescriptors, nil
+}
@@ -0,0 +1,117 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package descriptortest
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"buf.build/go/bufplugin/descriptor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestBSRModuleSpecFakeBuf(t *testing.T) {
+	t.Parallel()
+
+	if runtime.GOOS == "windows" {
+		t.Skip("fake buf is a shell script")
+	}
+	ctx := context.Background()
+	fileDescriptors, err := CompileStrings(
+		ctx,
+		map[string]string{
+			"foo/v1/foo.proto": `syntax = "proto3"; package foo.v1; import "foo/v1/bar.proto"; message Foo { Bar bar = 1; }`,
+			"foo/v1/bar.proto": `syntax = "proto3"; package foo.v1; message Bar {}`,
+		},
+	)
+	require.NoError(t, err)
+	bufPath := newTestFakeBuf(t, "buf.build/acme/foo:0123456789abcdef", descriptor.FileDescriptorSetForFileDescriptors(fileDescriptors))
+
+	fileDescriptors, err = (&BSRModuleSpec{
+		Name:      "buf.build/acme/foo",
+		Commit:    "0123456789abcdef",
+		FilePaths: []string{"foo/v1/foo.proto"},
+		BufPath:   bufPath,
+	}).ToFileDescriptors(ctx)
+	require.NoError(t, err)
+	pathToIsImport := make(map[string]bool)
+	for _, fileDescriptor := range fileDescriptors {
+		pathToIsImport[fileDescriptor.ProtoreflectFileDescriptor().Path()] = fileDescriptor.IsImport()
+	}
+	assert.Equal(
+		t,
+		map[string]bool{
+			"foo/v1/foo.proto": false,
+			"foo/v1/bar.proto": true,
+		},
+		pathToIsImport,
+	)
+
+	_, err = (&BSRModuleSpec{
+		Name:    "buf.build/acme/foo",
+		Commit:  "fedcba9876543210",
+		BufPath: bufPath,
+	}).ToFileDescriptors(ctx)
+	require.ErrorContains(t, err, "buf build buf.build/acme/foo:fedcba9876543210")
+}
+
+func TestBSRModuleSpecError(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	_, err := (&BSRModuleSpec{
+		Name:    "buf.build/acme/foo",
+		Commit:  "0123456789abcdef",
+		BufPath: filepath.Join(t.TempDir(), "buf"),
+	}).ToFileDescriptors(ctx)
+	require.ErrorIs(t, err, ErrBufNotFound)
+	_, err = (&BSRModuleSpec{Commit: "0123456789abcdef"}).ToFileDescriptors(ctx)
+	require.Error(t, err)
+	_, err = (&BSRModuleSpec{Name: "buf.build/acme/foo:main", Commit: "0123456789abcdef"}).ToFileDescriptors(ctx)
+	require.Error(t, err)
+	_, err = (&BSRModuleSpec{Name: "buf.build/acme/foo"}).ToFileDescriptors(ctx)
+	require.Error(t, err)
+}
+
+// newTestFakeBuf returns the path to a script that writes the FileDescriptorSet to the path
+// given by -o when building the module reference, and fails otherwise.
+func newTestFakeBuf(t *testing.T, moduleRef string, fileDescriptorSet proto.Message) string {
+	dirPath := t.TempDir()
+	data, err := proto.Marshal(fileDescriptorSet)
+	require.NoError(t, err)
+	fileDescriptorSetFilePath := filepath.Join(dirPath, "image.binpb")
+	require.NoError(t, os.WriteFile(fileDescriptorSetFilePath, data, 0600))
+	bufPath := filepath.Join(dirPath, "buf")
+	require.NoError(
+		t,
+		os.WriteFile(
+			bufPath,
+			[]byte(`#!/bin/sh
+if [ "$1" != "build" ] || [ "$2" != "`+moduleRef+`" ] || [ "$3" != "-o" ]; then
+  echo "module not found" >&2
+  exit 1
+fi
+cp "`+fileDescriptorSetFilePath+`" "$4"
+`),
+			0700,
+		),
+	)
+	return bufPath
+}
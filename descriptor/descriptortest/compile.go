@@ -0,0 +1,415 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package descriptortest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+
+	"buf.build/go/bufplugin/descriptor"
+	"buf.build/go/bufplugin/internal/pkg/xprotocompile"
+	"buf.build/go/bufplugin/internal/pkg/xslices"
+	"github.com/bufbuild/protocompile"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+const (
+	// SourceInfoModeBuf produces source info as buf does, including locations for the fields
+	// set within message literal option values.
+	//
+	// This is the default.
+	SourceInfoModeBuf SourceInfoMode = iota
+	// SourceInfoModeStandard produces source info as protoc does, without locations for the
+	// fields set within message literal option values.
+	SourceInfoModeStandard
+	// SourceInfoModeNone produces no source info.
+	//
+	// The FileDescriptors have empty SourceCodeInfo, which is useful for testing how rules
+	// behave when plugins are invoked without source info.
+	SourceInfoModeNone
+)
+
+// SourceInfoMode is the mode used to produce source info when compiling.
+type SourceInfoMode int
+
+// CompileOption is an option for Compile, CompileStrings, and CompileCurrentAndAgainst.
+type CompileOption func(*compileOptions)
+
+// CompileWithSourceInfoMode returns a new CompileOption that sets the SourceInfoMode.
+//
+// The default is SourceInfoModeBuf.
+func CompileWithSourceInfoMode(sourceInfoMode SourceInfoMode) CompileOption {
+	return func(compileOptions *compileOptions) {
+		compileOptions.sourceInfoMode = sourceInfoMode
+	}
+}
+
+// CompileWithMaxEdition returns a new CompileOption that results in an error if any compiled
+// file has an edition greater than the given edition.
+//
+// proto2 files are considered to be EDITION_PROTO2, and proto3 files EDITION_PROTO3, so
+// EDITION_PROTO3 disallows editions files. This is useful for testing rules against the
+// editions that a plugin supports. The default is EDITION_UNKNOWN, which allows all editions
+// supported by protocompile.
+func CompileWithMaxEdition(maxEdition descriptorpb.Edition) CompileOption {
+	return func(compileOptions *compileOptions) {
+		compileOptions.maxEdition = maxEdition
+	}
+}
+
+// CompileWithFS returns a new CompileOption that reads the dir paths given to Compile from the
+// fs.FS instead of the local file system.
+//
+// This allows testdata to be embedded with embed.FS, or to be constructed with fstest.MapFS.
+// The dir paths must be valid paths within the fs.FS per fs.ValidPath, for example "testdata"
+// instead of "./testdata". This has no effect on CompileStrings.
+func CompileWithFS(fsys fs.FS) CompileOption {
+	return func(compileOptions *compileOptions) {
+		compileOptions.fsys = fsys
+	}
+}
+
+// CompileWithResolver returns a new CompileOption that resolves files with the given
+// protocompile.Resolver before the dir paths or sources.
+//
+// This allows virtual files and custom import rewriting. Files the Resolver does not find are
+// resolved as usual, and the Well-Known Types are always available. With a Resolver, Compile
+// does not require dir paths.
+//
+// Results are not cached if a Resolver is set, as the files a Resolver returns cannot be part
+// of the key.
+func CompileWithResolver(resolver protocompile.Resolver) CompileOption {
+	return func(compileOptions *compileOptions) {
+		compileOptions.resolver = resolver
+	}
+}
+
+// CompileCacheStats are statistics for the process-wide compilation cache used by Compile
+// and CompileStrings.
+//
+// These are useful for debugging whether tests are served from the cache.
+type CompileCacheStats struct {
+	// Hits is the number of compilations that reused a previous result.
+	Hits int
+	// Misses is the number of compilations that were not cached.
+	Misses int
+	// Entries is the number of distinct compilations within the cache.
+	Entries int
+}
+
+// CompileResult is the result of CompileToResult and CompileStringsToResult.
+type CompileResult struct {
+	// FileDescriptors are the compiled FileDescriptors.
+	FileDescriptors []descriptor.FileDescriptor
+	// Warnings are the warnings produced by the compiler.
+	//
+	// Warnings for files without a syntax and for unused imports are not included, as these
+	// are reported via IsSyntaxUnspecified and UnusedDependencyIndexes on the FileDescriptors.
+	// Warnings are sorted by file path, position, and message.
+	Warnings []CompileWarning
+}
+
+// CompileWarning is a warning produced by the compiler, for example for a deprecated feature
+// or for conflicting JSON names within a proto2 file.
+type CompileWarning struct {
+	// FilePath is the path of the file the warning is for.
+	FilePath string
+	// Line is the one-indexed line of the warning, or 0 if unknown.
+	Line int
+	// Column is the one-indexed column of the warning, or 0 if unknown.
+	Column int
+	// Message is the message of the warning, without the position.
+	Message string
+}
+
+// Compile compiles the .proto files at the file paths within the dir paths into
+// FileDescriptors.
+//
+// The dir paths correspond to the -I flag in protoc, and the file paths are relative to the
+// dir paths. Paths use forward slashes. Any imports of the file paths are compiled as well, and
+// marked as imports. Files are compiled the same way as with buf, see CompileStrings.
+//
+// The FileDescriptors are in a deterministic order, see CompileStrings.
+//
+// Results are cached for the lifetime of the process, keyed by the dir paths, file paths,
+// options, and the paths and contents of all .proto files within the dir paths, so that repeated
+// compilations within table tests only compile once, and changes to testdata invalidate the
+// cache. Errors are cached as well. The returned FileDescriptors may be shared between callers,
+// and must not be modified. The cache is shared with checktest.ProtoFileSpec.
+func Compile(
+	ctx context.Context,
+	dirPaths []string,
+	filePaths []string,
+	options ...CompileOption,
+) ([]descriptor.FileDescriptor, error) {
+	compileResult, err := CompileToResult(ctx, dirPaths, filePaths, options...)
+	if err != nil {
+		return nil, err
+	}
+	return compileResult.FileDescriptors, nil
+}
+
+// CompileToResult is Compile, additionally returning the warnings produced by the compiler.
+//
+// The FileDescriptors are cached as with Compile, and must not be modified.
+func CompileToResult(
+	ctx context.Context,
+	dirPaths []string,
+	filePaths []string,
+	options ...CompileOption,
+) (*CompileResult, error) {
+	compileOptions := newCompileOptions()
+	for _, option := range options {
+		option(compileOptions)
+	}
+	if len(dirPaths) == 0 && compileOptions.resolver == nil {
+		return nil, errors.New("no dir paths to compile")
+	}
+	if len(filePaths) == 0 {
+		return nil, errors.New("no file paths to compile")
+	}
+	if compileOptions.fsys != nil {
+		for _, dirPath := range dirPaths {
+			if !fs.ValidPath(dirPath) {
+				return nil, fmt.Errorf("invalid dir path %q for fs.FS", dirPath)
+			}
+		}
+	}
+	xprotocompileOptions, err := compileOptions.xprotocompileOptions()
+	if err != nil {
+		return nil, err
+	}
+	result, err := xprotocompile.CachedCompileResult(ctx, compileOptions.fsys, dirPaths, nil, filePaths, xprotocompileOptions...)
+	if err != nil {
+		return nil, err
+	}
+	return compileResultForResult(result), nil
+}
+
+// CompileStrings compiles the given .proto file contents into FileDescriptors, without
+// touching the filesystem.
+//
+// The sources are keyed by their path, using forward slashes. Every file within the sources is
+// compiled. Files can import each other and the Well-Known Types, and any Well-Known Types that
+// are imported are included as imports. Files are compiled the same way as with buf, so the
+// FileDescriptors have complete SourceCodeInfo, and files without a syntax or with unused
+// imports are reported via IsSyntaxUnspecified and UnusedDependencyIndexes.
+//
+// The FileDescriptors are ordered topologically, so that every file is after the files it
+// imports, and otherwise lexically by path. That is, of the files whose imports have all been
+// placed, the file with the lexically smallest path is placed next. The order only depends on
+// the set of files, so golden tests and requests split across files are deterministic.
+//
+// Results are cached for the lifetime of the process, keyed by the sources, as with Compile.
+// The returned FileDescriptors may be shared between callers, and must not be modified.
+func CompileStrings(
+	ctx context.Context,
+	pathToSource map[string]string,
+	options ...CompileOption,
+) ([]descriptor.FileDescriptor, error) {
+	compileResult, err := CompileStringsToResult(ctx, pathToSource, options...)
+	if err != nil {
+		return nil, err
+	}
+	return compileResult.FileDescriptors, nil
+}
+
+// CompileStringsToResult is CompileStrings, additionally returning the warnings produced by
+// the compiler.
+//
+// The FileDescriptors are cached as with CompileStrings, and must not be modified.
+func CompileStringsToResult(
+	ctx context.Context,
+	pathToSource map[string]string,
+	options ...CompileOption,
+) (*CompileResult, error) {
+	if len(pathToSource) == 0 {
+		return nil, errors.New("no sources to compile")
+	}
+	compileOptions := newCompileOptions()
+	for _, option := range options {
+		option(compileOptions)
+	}
+	xprotocompileOptions, err := compileOptions.xprotocompileOptions()
+	if err != nil {
+		return nil, err
+	}
+	result, err := xprotocompile.CachedCompileResult(
+		ctx,
+		nil,
+		nil,
+		pathToSource,
+		xslices.MapKeysToSortedSlice(pathToSource),
+		xprotocompileOptions...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return compileResultForResult(result), nil
+}
+
+// ProtoFileSetSpec specifies a set of files to compile with CompileCurrentAndAgainst.
+type ProtoFileSetSpec struct {
+	// DirPaths are the paths where .proto files are contained.
+	//
+	// See Compile. This must contain at least one element, unless Sources is set or
+	// CompileWithResolver is used.
+	DirPaths []string
+	// Sources are .proto file contents, keyed by their path.
+	//
+	// See CompileStrings. Sources take precedence over files with the same path within
+	// DirPaths, and can import files within DirPaths.
+	Sources map[string]string
+	// FilePaths are the specific paths to compile within the DirPaths and Sources.
+	//
+	// This must contain at least one element, unless Sources is set, in which case this
+	// defaults to all paths within Sources.
+	FilePaths []string
+}
+
+// CompileCurrentAndAgainst compiles the current and against ProtoFileSetSpecs with the same
+// options, and returns the FileDescriptors of both.
+//
+// This is the pair of compilations needed by every test of a breaking change rule. Both
+// compilations use the process-wide cache, as with Compile, so files and imports that are
+// identical between the current and against files, and between tests, are only compiled once
+// per distinct set of files. Errors are prefixed with "current" or "against".
+//
+// The returned FileDescriptors may be shared between callers, and must not be modified.
+func CompileCurrentAndAgainst(
+	ctx context.Context,
+	current *ProtoFileSetSpec,
+	against *ProtoFileSetSpec,
+	options ...CompileOption,
+) ([]descriptor.FileDescriptor, []descriptor.FileDescriptor, error) {
+	compileOptions := newCompileOptions()
+	for _, option := range options {
+		option(compileOptions)
+	}
+	currentFileDescriptors, err := compileProtoFileSetSpec(ctx, current, compileOptions)
+	if err != nil {
+		return nil, nil, fmt.Errorf("current: %w", err)
+	}
+	againstFileDescriptors, err := compileProtoFileSetSpec(ctx, against, compileOptions)
+	if err != nil {
+		return nil, nil, fmt.Errorf("against: %w", err)
+	}
+	return currentFileDescriptors, againstFileDescriptors, nil
+}
+
+// CurrentCompileCacheStats returns the current CompileCacheStats.
+//
+// The cache is process-wide, so these include compilations from all tests within the test
+// binary, including those made via checktest.
+func CurrentCompileCacheStats() CompileCacheStats {
+	cacheStats := xprotocompile.GlobalCacheStats()
+	return CompileCacheStats{
+		Hits:    cacheStats.Hits,
+		Misses:  cacheStats.Misses,
+		Entries: cacheStats.Entries,
+	}
+}
+
+// *** PRIVATE ***
+
+type compileOptions struct {
+	sourceInfoMode SourceInfoMode
+	maxEdition     descriptorpb.Edition
+	fsys           fs.FS
+	resolver       protocompile.Resolver
+}
+
+func newCompileOptions() *compileOptions {
+	return &compileOptions{}
+}
+
+// compileResultForResult returns a new CompileResult for the xprotocompile.Result.
+//
+// The FileDescriptors are shared with the xprotocompile.Result, which may be cached.
+func compileResultForResult(result *xprotocompile.Result) *CompileResult {
+	compileWarnings := make([]CompileWarning, len(result.Warnings))
+	for i, warning := range result.Warnings {
+		compileWarnings[i] = CompileWarning{
+			FilePath: warning.FilePath,
+			Line:     warning.Line,
+			Column:   warning.Column,
+			Message:  warning.Message,
+		}
+	}
+	return &CompileResult{
+		FileDescriptors: result.FileDescriptors,
+		Warnings:        compileWarnings,
+	}
+}
+
+func (c *compileOptions) xprotocompileOptions() ([]xprotocompile.CompileOption, error) {
+	var sourceInfoMode protocompile.SourceInfoMode
+	switch c.sourceInfoMode {
+	case SourceInfoModeBuf:
+		sourceInfoMode = protocompile.SourceInfoExtraOptionLocations
+	case SourceInfoModeStandard:
+		sourceInfoMode = protocompile.SourceInfoStandard
+	case SourceInfoModeNone:
+		sourceInfoMode = protocompile.SourceInfoNone
+	default:
+		return nil, fmt.Errorf("unknown SourceInfoMode: %d", c.sourceInfoMode)
+	}
+	return []xprotocompile.CompileOption{
+		xprotocompile.CompileWithSourceInfoMode(sourceInfoMode),
+		xprotocompile.CompileWithMaxEdition(c.maxEdition),
+		xprotocompile.CompileWithResolver(c.resolver),
+	}, nil
+}
+
+func compileProtoFileSetSpec(
+	ctx context.Context,
+	protoFileSetSpec *ProtoFileSetSpec,
+	compileOptions *compileOptions,
+) ([]descriptor.FileDescriptor, error) {
+	if protoFileSetSpec == nil {
+		return nil, errors.New("ProtoFileSetSpec is nil")
+	}
+	if len(protoFileSetSpec.DirPaths) == 0 && len(protoFileSetSpec.Sources) == 0 && compileOptions.resolver == nil {
+		return nil, errors.New("no dir paths or sources to compile")
+	}
+	filePaths := protoFileSetSpec.FilePaths
+	if len(filePaths) == 0 {
+		filePaths = xslices.MapKeysToSortedSlice(protoFileSetSpec.Sources)
+	}
+	if len(filePaths) == 0 {
+		return nil, errors.New("no file paths to compile")
+	}
+	if compileOptions.fsys != nil {
+		for _, dirPath := range protoFileSetSpec.DirPaths {
+			if !fs.ValidPath(dirPath) {
+				return nil, fmt.Errorf("invalid dir path %q for fs.FS", dirPath)
+			}
+		}
+	}
+	xprotocompileOptions, err := compileOptions.xprotocompileOptions()
+	if err != nil {
+		return nil, err
+	}
+	return xprotocompile.CachedCompile(
+		ctx,
+		compileOptions.fsys,
+		protoFileSetSpec.DirPaths,
+		protoFileSetSpec.Sources,
+		filePaths,
+		xprotocompileOptions...,
+	)
+}
@@ -0,0 +1,296 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package descriptortest
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"buf.build/go/bufplugin/descriptor"
+	"github.com/bufbuild/protocompile"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+func TestCompileStrings(t *testing.T) {
+	t.Parallel()
+
+	fileDescriptors, err := CompileStrings(
+		context.Background(),
+		map[string]string{
+			"foo/v1/foo.proto": `syntax = "proto3";
+package foo.v1;
+import "google/protobuf/timestamp.proto";
+import "foo/v1/bar.proto";
+// Foo is a foo.
+message Foo {
+  google.protobuf.Timestamp created = 1;
+}
+`,
+			"foo/v1/bar.proto": `package foo.v1;
+message Bar {}
+`,
+		},
+	)
+	require.NoError(t, err)
+	pathToIsImport := make(map[string]bool)
+	for _, fileDescriptor := range fileDescriptors {
+		pathToIsImport[fileDescriptor.ProtoreflectFileDescriptor().Path()] = fileDescriptor.IsImport()
+	}
+	assert.Equal(
+		t,
+		map[string]bool{
+			"foo/v1/foo.proto":                false,
+			"foo/v1/bar.proto":                false,
+			"google/protobuf/timestamp.proto": true,
+		},
+		pathToIsImport,
+	)
+	for _, fileDescriptor := range fileDescriptors {
+		switch fileDescriptor.ProtoreflectFileDescriptor().Path() {
+		case "foo/v1/foo.proto":
+			messageDescriptor := fileDescriptor.ProtoreflectFileDescriptor().Messages().Get(0)
+			assert.Equal(t, " Foo is a foo.\n", fileDescriptor.CommentsFor(messageDescriptor).Leading())
+			assert.Equal(t, []int32{1}, fileDescriptor.UnusedDependencyIndexes())
+			assert.Equal(t, []string{"foo/v1/bar.proto"}, fileDescriptor.UnusedDependencyPaths())
+		case "foo/v1/bar.proto":
+			assert.True(t, fileDescriptor.IsSyntaxUnspecified())
+		}
+	}
+}
+
+func TestCompileStringsError(t *testing.T) {
+	t.Parallel()
+
+	_, err := CompileStrings(context.Background(), nil)
+	require.Error(t, err)
+	_, err = CompileStrings(
+		context.Background(),
+		map[string]string{
+			"foo.proto": `syntax = "proto3"; message Foo { Bar bar = 1; }`,
+		},
+	)
+	require.Error(t, err)
+}
+
+func TestCompileStringsToResult(t *testing.T) {
+	t.Parallel()
+
+	compileResult, err := CompileStringsToResult(
+		context.Background(),
+		map[string]string{
+			"foo.proto": `syntax = "proto2";
+import "bar.proto";
+message Foo {
+  optional string foo_bar = 1;
+  optional string fooBar = 2;
+}
+`,
+			"bar.proto": `message Bar {}
+`,
+		},
+	)
+	require.NoError(t, err)
+	require.Len(t, compileResult.FileDescriptors, 2)
+	// The warnings for the unused import and the unspecified syntax are folded into the
+	// FileDescriptors.
+	require.Len(t, compileResult.Warnings, 1)
+	warning := compileResult.Warnings[0]
+	assert.Equal(t, "foo.proto", warning.FilePath)
+	assert.Equal(t, 5, warning.Line)
+	assert.Equal(t, 3, warning.Column)
+	assert.Contains(t, warning.Message, "JSON name")
+}
+
+func TestCompile(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	dirPath := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dirPath, "foo.proto"), []byte(`syntax = "proto3"; message Foo {}`), 0600))
+	fileDescriptors, err := Compile(ctx, []string{dirPath}, []string{"foo.proto"})
+	require.NoError(t, err)
+	require.Len(t, fileDescriptors, 1)
+	compileCacheStats := CurrentCompileCacheStats()
+	cachedFileDescriptors, err := Compile(ctx, []string{dirPath}, []string{"foo.proto"})
+	require.NoError(t, err)
+	require.Len(t, cachedFileDescriptors, 1)
+	assert.Same(t, fileDescriptors[0], cachedFileDescriptors[0])
+	// Other tests may compile in parallel, so only the lower bound is known.
+	assert.GreaterOrEqual(t, CurrentCompileCacheStats().Hits, compileCacheStats.Hits+1)
+
+	// Changes to the contents of files within the dir paths invalidate the cache.
+	require.NoError(t, os.WriteFile(filepath.Join(dirPath, "foo.proto"), []byte(`syntax = "proto3"; message Bar {}`), 0600))
+	fileDescriptors, err = Compile(ctx, []string{dirPath}, []string{"foo.proto"})
+	require.NoError(t, err)
+	require.Len(t, fileDescriptors, 1)
+	assert.Equal(t, "Bar", string(fileDescriptors[0].ProtoreflectFileDescriptor().Messages().Get(0).Name()))
+
+	_, err = Compile(ctx, nil, []string{"foo.proto"})
+	require.Error(t, err)
+	_, err = Compile(ctx, []string{dirPath}, nil)
+	require.Error(t, err)
+}
+
+func TestCompileStringsOptions(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	pathToSource := map[string]string{
+		"foo.proto": `edition = "2023";
+package foo;
+// Foo is a foo.
+message Foo {
+  string name = 1 [features = { field_presence: IMPLICIT }];
+}
+`,
+	}
+	numLocations := func(fileDescriptors []descriptor.FileDescriptor) int {
+		require.Len(t, fileDescriptors, 1)
+		return fileDescriptors[0].ProtoreflectFileDescriptor().SourceLocations().Len()
+	}
+	fileDescriptors, err := CompileStrings(ctx, pathToSource)
+	require.NoError(t, err)
+	numBufLocations := numLocations(fileDescriptors)
+	fileDescriptors, err = CompileStrings(ctx, pathToSource, CompileWithSourceInfoMode(SourceInfoModeStandard))
+	require.NoError(t, err)
+	numStandardLocations := numLocations(fileDescriptors)
+	assert.Less(t, numStandardLocations, numBufLocations)
+	assert.Positive(t, numStandardLocations)
+	fileDescriptors, err = CompileStrings(ctx, pathToSource, CompileWithSourceInfoMode(SourceInfoModeNone))
+	require.NoError(t, err)
+	assert.Equal(t, 0, numLocations(fileDescriptors))
+
+	_, err = CompileStrings(ctx, pathToSource, CompileWithMaxEdition(descriptorpb.Edition_EDITION_2023))
+	require.NoError(t, err)
+	_, err = CompileStrings(ctx, pathToSource, CompileWithMaxEdition(descriptorpb.Edition_EDITION_PROTO3))
+	require.ErrorContains(t, err, "foo.proto: edition EDITION_2023 is greater than the maximum edition EDITION_PROTO3")
+	_, err = CompileStrings(ctx, pathToSource, CompileWithSourceInfoMode(SourceInfoMode(100)))
+	require.Error(t, err)
+}
+
+func TestCompileStringsOrder(t *testing.T) {
+	t.Parallel()
+
+	fileDescriptors, err := CompileStrings(
+		context.Background(),
+		map[string]string{
+			"c.proto": `syntax = "proto3"; import "a.proto"; import "google/protobuf/timestamp.proto"; message C { A a = 1; google.protobuf.Timestamp t = 2; }`,
+			"b.proto": `syntax = "proto3"; message B {}`,
+			"a.proto": `syntax = "proto3"; import "z.proto"; message A { Z z = 1; }`,
+			"z.proto": `syntax = "proto3"; message Z {}`,
+		},
+	)
+	require.NoError(t, err)
+	paths := make([]string, len(fileDescriptors))
+	for i, fileDescriptor := range fileDescriptors {
+		paths[i] = fileDescriptor.ProtoreflectFileDescriptor().Path()
+	}
+	assert.Equal(
+		t,
+		[]string{
+			"b.proto",
+			"google/protobuf/timestamp.proto",
+			"z.proto",
+			"a.proto",
+			"c.proto",
+		},
+		paths,
+	)
+}
+
+func TestCompileFSAndResolver(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	fsys := fstest.MapFS{
+		"testdata/foo.proto": &fstest.MapFile{
+			Data: []byte(`syntax = "proto3"; import "vendor/bar.proto"; message Foo { Bar bar = 1; }`),
+		},
+	}
+	resolver := &protocompile.SourceResolver{
+		Accessor: protocompile.SourceAccessorFromMap(
+			map[string]string{
+				"vendor/bar.proto": `syntax = "proto3"; message Bar {}`,
+			},
+		),
+	}
+	fileDescriptors, err := Compile(
+		ctx,
+		[]string{"testdata"},
+		[]string{"foo.proto"},
+		CompileWithFS(fsys),
+		CompileWithResolver(resolver),
+	)
+	require.NoError(t, err)
+	paths := make([]string, len(fileDescriptors))
+	for i, fileDescriptor := range fileDescriptors {
+		paths[i] = fileDescriptor.ProtoreflectFileDescriptor().Path()
+	}
+	assert.Equal(t, []string{"vendor/bar.proto", "foo.proto"}, paths)
+
+	fileDescriptors, err = Compile(ctx, nil, []string{"vendor/bar.proto"}, CompileWithResolver(resolver))
+	require.NoError(t, err)
+	require.Len(t, fileDescriptors, 1)
+
+	_, err = Compile(ctx, []string{"./testdata"}, []string{"foo.proto"}, CompileWithFS(fsys))
+	require.Error(t, err)
+	_, err = Compile(ctx, []string{"testdata"}, []string{"foo.proto"}, CompileWithFS(fsys))
+	require.Error(t, err)
+}
+
+func TestCompileCurrentAndAgainst(t *testing.T) {
+	t.Parallel()
+
+	currentFileDescriptors, againstFileDescriptors, err := CompileCurrentAndAgainst(
+		context.Background(),
+		&ProtoFileSetSpec{
+			Sources: map[string]string{
+				"foo.proto": `syntax = "proto3"; message Foo { string bar = 2; }`,
+			},
+		},
+		&ProtoFileSetSpec{
+			Sources: map[string]string{
+				"foo.proto": `syntax = "proto3"; message Foo { string bar = 1; }`,
+			},
+		},
+		CompileWithSourceInfoMode(SourceInfoModeNone),
+	)
+	require.NoError(t, err)
+	require.Len(t, currentFileDescriptors, 1)
+	require.Len(t, againstFileDescriptors, 1)
+	assert.Equal(t, int32(2), currentFileDescriptors[0].FileDescriptorProto().GetMessageType()[0].GetField()[0].GetNumber())
+	assert.Equal(t, int32(1), againstFileDescriptors[0].FileDescriptorProto().GetMessageType()[0].GetField()[0].GetNumber())
+	assert.Empty(t, currentFileDescriptors[0].FileDescriptorProto().GetSourceCodeInfo().GetLocation())
+
+	_, _, err = CompileCurrentAndAgainst(
+		context.Background(),
+		&ProtoFileSetSpec{
+			Sources: map[string]string{
+				"foo.proto": `syntax = "proto3"; message Foo {}`,
+			},
+		},
+		&ProtoFileSetSpec{
+			Sources: map[string]string{
+				"foo.proto": `syntax = "proto3"; message Foo { Bar bar = 1; }`,
+			},
+		},
+	)
+	require.ErrorContains(t, err, "against: ")
+}
@@ -0,0 +1,520 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package descriptortest
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	descriptorv1 "buf.build/gen/go/bufbuild/bufplugin/protocolbuffers/go/buf/plugin/descriptor/v1"
+	"buf.build/go/bufplugin/descriptor"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+const (
+	// Field numbers within descriptorpb.FileDescriptorProto.
+	fileSyntaxFieldNumber      = 12
+	filePackageFieldNumber     = 2
+	fileDependencyFieldNumber  = 3
+	fileOptionsFieldNumber     = 8
+	fileMessageTypeFieldNumber = 4
+	fileEnumTypeFieldNumber    = 5
+	fileServiceFieldNumber     = 6
+	// Field numbers within descriptorpb.FileOptions.
+	fileOptionsJavaPackageFieldNumber = 1
+	fileOptionsGoPackageFieldNumber   = 11
+	// Field numbers within descriptorpb.DescriptorProto.
+	messageFieldFieldNumber = 2
+	// Field numbers within descriptorpb.EnumDescriptorProto.
+	enumValueFieldNumber = 2
+	// Field numbers within descriptorpb.ServiceDescriptorProto.
+	serviceMethodFieldNumber = 2
+)
+
+// CorpusSpec specifies a synthetic corpus of files to generate with NewCorpus.
+//
+// All generation is deterministic: the same CorpusSpec always results in the same files.
+//
+// Files are named "corpus/fileN.proto" with package "corpus.fileN". Each file declares
+// messages named "MessageN", enums named "EnumN", and services named "ServiceN". Every
+// message, enum, service, and method has a leading comment, and the files have complete
+// SourceCodeInfo as if they were formatted with buf format.
+//
+// Fields cycle between string, int64, enum, and message types. Enum fields reference
+// an enum in the same file. Message fields reference either a previously-declared message in
+// the same file, or the first message of an imported file. Methods use messages in the same
+// file as their input and output types.
+type CorpusSpec struct {
+	// NumFiles is the number of files to generate.
+	//
+	// Required to be at least 1.
+	NumFiles int
+	// NumMessagesPerFile is the number of top-level messages within each file.
+	NumMessagesPerFile int
+	// NumFieldsPerMessage is the number of fields within each message.
+	NumFieldsPerMessage int
+	// NumEnumsPerFile is the number of top-level enums within each file.
+	NumEnumsPerFile int
+	// NumValuesPerEnum is the number of values within each enum, not including the
+	// zero UNSPECIFIED value that is always generated.
+	NumValuesPerEnum int
+	// NumServicesPerFile is the number of services within each file.
+	//
+	// If greater than 0, NumMessagesPerFile must also be greater than 0.
+	NumServicesPerFile int
+	// NumMethodsPerService is the number of methods within each service.
+	NumMethodsPerService int
+	// NumImportsPerFile is the maximum number of files that each file imports.
+	//
+	// File N imports the NumImportsPerFile files preceding it. An import is only used if a
+	// message field references it, so imports may be marked as unused if there are not enough
+	// message-typed fields.
+	NumImportsPerFile int
+	// WithOptions says to set options on the generated elements.
+	//
+	// If set, each file has the java_package and go_package options, and every fifth message,
+	// field, enum value, and method has the deprecated option.
+	WithOptions bool
+}
+
+// NewCorpus returns a new set of generated FileDescriptors for the CorpusSpec.
+//
+// The FileDescriptors are returned in dependency order. None of the FileDescriptors are imports.
+func NewCorpus(corpusSpec *CorpusSpec) ([]descriptor.FileDescriptor, error) {
+	protoFileDescriptors, err := newCorpusProtoFileDescriptors(corpusSpec)
+	if err != nil {
+		return nil, err
+	}
+	return fileDescriptorsInOrder(protoFileDescriptors)
+}
+
+// NewCorpusFileDescriptorSet returns a new FileDescriptorSet for the CorpusSpec.
+//
+// This is useful for writing a corpus to disk for use outside of Go, for example as a buf image.
+func NewCorpusFileDescriptorSet(corpusSpec *CorpusSpec) (*descriptorpb.FileDescriptorSet, error) {
+	protoFileDescriptors, err := newCorpusProtoFileDescriptors(corpusSpec)
+	if err != nil {
+		return nil, err
+	}
+	fileDescriptorSet := &descriptorpb.FileDescriptorSet{
+		File: make([]*descriptorpb.FileDescriptorProto, len(protoFileDescriptors)),
+	}
+	for i, protoFileDescriptor := range protoFileDescriptors {
+		fileDescriptorSet.File[i] = protoFileDescriptor.GetFileDescriptorProto()
+	}
+	return fileDescriptorSet, nil
+}
+
+// *** PRIVATE ***
+
+func newCorpusProtoFileDescriptors(corpusSpec *CorpusSpec) ([]*descriptorv1.FileDescriptor, error) {
+	if err := validateCorpusSpec(corpusSpec); err != nil {
+		return nil, err
+	}
+	protoFileDescriptors := make([]*descriptorv1.FileDescriptor, corpusSpec.NumFiles)
+	for i := 0; i < corpusSpec.NumFiles; i++ {
+		protoFileDescriptors[i] = newCorpusFileBuilder(corpusSpec, i).build()
+	}
+	return protoFileDescriptors, nil
+}
+
+// fileDescriptorsInOrder returns the FileDescriptors for the descriptorv1.FileDescriptors
+// in the same order as the descriptorv1.FileDescriptors.
+//
+// descriptor.FileDescriptorsForProtoFileDescriptors does not preserve order.
+func fileDescriptorsInOrder(protoFileDescriptors []*descriptorv1.FileDescriptor) ([]descriptor.FileDescriptor, error) {
+	fileDescriptors, err := descriptor.FileDescriptorsForProtoFileDescriptors(protoFileDescriptors)
+	if err != nil {
+		return nil, err
+	}
+	fileNameToIndex := make(map[string]int, len(protoFileDescriptors))
+	for i, protoFileDescriptor := range protoFileDescriptors {
+		fileNameToIndex[protoFileDescriptor.GetFileDescriptorProto().GetName()] = i
+	}
+	slices.SortFunc(
+		fileDescriptors,
+		func(one descriptor.FileDescriptor, two descriptor.FileDescriptor) int {
+			return fileNameToIndex[one.ProtoreflectFileDescriptor().Path()] - fileNameToIndex[two.ProtoreflectFileDescriptor().Path()]
+		},
+	)
+	return fileDescriptors, nil
+}
+
+func validateCorpusSpec(corpusSpec *CorpusSpec) error {
+	if corpusSpec == nil {
+		return errors.New("CorpusSpec is nil")
+	}
+	if corpusSpec.NumFiles < 1 {
+		return errors.New("CorpusSpec.NumFiles must be at least 1")
+	}
+	for name, value := range map[string]int{
+		"NumMessagesPerFile":   corpusSpec.NumMessagesPerFile,
+		"NumFieldsPerMessage":  corpusSpec.NumFieldsPerMessage,
+		"NumEnumsPerFile":      corpusSpec.NumEnumsPerFile,
+		"NumValuesPerEnum":     corpusSpec.NumValuesPerEnum,
+		"NumServicesPerFile":   corpusSpec.NumServicesPerFile,
+		"NumMethodsPerService": corpusSpec.NumMethodsPerService,
+		"NumImportsPerFile":    corpusSpec.NumImportsPerFile,
+	} {
+		if value < 0 {
+			return fmt.Errorf("CorpusSpec.%s must not be negative", name)
+		}
+	}
+	if corpusSpec.NumServicesPerFile > 0 && corpusSpec.NumMessagesPerFile == 0 {
+		return errors.New("CorpusSpec.NumMessagesPerFile must be greater than 0 if CorpusSpec.NumServicesPerFile is greater than 0")
+	}
+	return nil
+}
+
+// corpusFileBuilder builds a single file, tracking the lines of the notional source file
+// so that SourceCodeInfo can be generated.
+type corpusFileBuilder struct {
+	corpusSpec *CorpusSpec
+	fileIndex  int
+	// importFileIndexes are the indexes of the files this file imports, in order.
+	importFileIndexes []int
+	// usedImports are the indexes within importFileIndexes that are used.
+	usedImports map[int]struct{}
+	// numMessageFields is the number of message-typed fields generated so far, used to
+	// cycle through message references.
+	numMessageFields int
+	numLines         int
+	locations        []*descriptorpb.SourceCodeInfo_Location
+}
+
+func newCorpusFileBuilder(corpusSpec *CorpusSpec, fileIndex int) *corpusFileBuilder {
+	var importFileIndexes []int
+	for i := max(0, fileIndex-corpusSpec.NumImportsPerFile); i < fileIndex; i++ {
+		importFileIndexes = append(importFileIndexes, i)
+	}
+	return &corpusFileBuilder{
+		corpusSpec:        corpusSpec,
+		fileIndex:         fileIndex,
+		importFileIndexes: importFileIndexes,
+		usedImports:       make(map[int]struct{}),
+	}
+}
+
+func (b *corpusFileBuilder) build() *descriptorv1.FileDescriptor {
+	fileDescriptorProto := &descriptorpb.FileDescriptorProto{
+		Name:    proto.String(corpusFileName(b.fileIndex)),
+		Package: proto.String(corpusPackageName(b.fileIndex)),
+		Syntax:  proto.String("proto3"),
+	}
+	b.addLine([]int32{fileSyntaxFieldNumber}, 0, `syntax = "proto3";`, "")
+	b.addBlankLine()
+	b.addLine([]int32{filePackageFieldNumber}, 0, "package "+fileDescriptorProto.GetPackage()+";", "")
+	if len(b.importFileIndexes) > 0 {
+		b.addBlankLine()
+	}
+	for i, importFileIndex := range b.importFileIndexes {
+		fileDescriptorProto.Dependency = append(fileDescriptorProto.Dependency, corpusFileName(importFileIndex))
+		b.addLine([]int32{fileDependencyFieldNumber, int32(i)}, 0, `import "`+corpusFileName(importFileIndex)+`";`, "")
+	}
+	if b.corpusSpec.WithOptions {
+		b.addBlankLine()
+		javaPackage := "com." + fileDescriptorProto.GetPackage()
+		goPackage := "example.com/corpus/file" + fmt.Sprint(b.fileIndex)
+		fileDescriptorProto.Options = &descriptorpb.FileOptions{
+			JavaPackage: proto.String(javaPackage),
+			GoPackage:   proto.String(goPackage),
+		}
+		b.addOptionLine(
+			[]int32{fileOptionsFieldNumber, fileOptionsGoPackageFieldNumber},
+			0,
+			`option go_package = "`+goPackage+`";`,
+		)
+		b.addOptionLine(
+			[]int32{fileOptionsFieldNumber, fileOptionsJavaPackageFieldNumber},
+			0,
+			`option java_package = "`+javaPackage+`";`,
+		)
+	}
+	for i := 0; i < b.corpusSpec.NumMessagesPerFile; i++ {
+		b.addBlankLine()
+		fileDescriptorProto.MessageType = append(fileDescriptorProto.MessageType, b.buildMessage(i))
+	}
+	for i := 0; i < b.corpusSpec.NumEnumsPerFile; i++ {
+		b.addBlankLine()
+		fileDescriptorProto.EnumType = append(fileDescriptorProto.EnumType, b.buildEnum(i))
+	}
+	for i := 0; i < b.corpusSpec.NumServicesPerFile; i++ {
+		b.addBlankLine()
+		fileDescriptorProto.Service = append(fileDescriptorProto.Service, b.buildService(i))
+	}
+	fileDescriptorProto.SourceCodeInfo = &descriptorpb.SourceCodeInfo{
+		Location: append(
+			[]*descriptorpb.SourceCodeInfo_Location{
+				{
+					Path: []int32{},
+					Span: []int32{0, 0, int32(b.numLines), 0},
+				},
+			},
+			b.locations...,
+		),
+	}
+	var unusedDependencyIndexes []int32
+	for i := range b.importFileIndexes {
+		if _, ok := b.usedImports[i]; !ok {
+			unusedDependencyIndexes = append(unusedDependencyIndexes, int32(i))
+		}
+	}
+	return &descriptorv1.FileDescriptor{
+		FileDescriptorProto: fileDescriptorProto,
+		UnusedDependency:    unusedDependencyIndexes,
+	}
+}
+
+func (b *corpusFileBuilder) buildMessage(messageIndex int) *descriptorpb.DescriptorProto {
+	name := fmt.Sprintf("Message%d", messageIndex)
+	path := []int32{fileMessageTypeFieldNumber, int32(messageIndex)}
+	startLine := b.addBlockStartLine(path, 0, "message "+name+" {", " "+name+" is a synthetic message.\n")
+	descriptorProto := &descriptorpb.DescriptorProto{
+		Name: proto.String(name),
+	}
+	if b.corpusSpec.WithOptions && isEveryFifth(messageIndex) {
+		descriptorProto.Options = &descriptorpb.MessageOptions{
+			Deprecated: proto.Bool(true),
+		}
+		b.addOptionLine(append(path, 7, 3), 2, "option deprecated = true;")
+	}
+	for i := 0; i < b.corpusSpec.NumFieldsPerMessage; i++ {
+		descriptorProto.Field = append(descriptorProto.Field, b.buildField(path, messageIndex, i))
+	}
+	b.addBlockEndLine(path, 0, startLine)
+	return descriptorProto
+}
+
+func (b *corpusFileBuilder) buildField(
+	messagePath []int32,
+	messageIndex int,
+	fieldIndex int,
+) *descriptorpb.FieldDescriptorProto {
+	name := fmt.Sprintf("field_%d", fieldIndex)
+	fieldDescriptorProto := &descriptorpb.FieldDescriptorProto{
+		Name:     proto.String(name),
+		JsonName: proto.String(fmt.Sprintf("field%d", fieldIndex)),
+		Number:   proto.Int32(int32(fieldIndex + 1)),
+		Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+	}
+	var typeName string
+	switch {
+	case fieldIndex%4 == 3 && b.setMessageFieldType(fieldDescriptorProto, messageIndex):
+		typeName = strings.TrimPrefix(fieldDescriptorProto.GetTypeName(), ".")
+	case fieldIndex%4 == 2 && b.corpusSpec.NumEnumsPerFile > 0:
+		enumName := fmt.Sprintf("Enum%d", fieldIndex%b.corpusSpec.NumEnumsPerFile)
+		fieldDescriptorProto.Type = descriptorpb.FieldDescriptorProto_TYPE_ENUM.Enum()
+		fieldDescriptorProto.TypeName = proto.String("." + corpusPackageName(b.fileIndex) + "." + enumName)
+		typeName = enumName
+	case fieldIndex%4 == 1:
+		fieldDescriptorProto.Type = descriptorpb.FieldDescriptorProto_TYPE_INT64.Enum()
+		typeName = "int64"
+	default:
+		fieldDescriptorProto.Type = descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum()
+		typeName = "string"
+	}
+	line := fmt.Sprintf("%s %s = %d", typeName, name, fieldIndex+1)
+	if b.corpusSpec.WithOptions && isEveryFifth(fieldIndex) {
+		fieldDescriptorProto.Options = &descriptorpb.FieldOptions{
+			Deprecated: proto.Bool(true),
+		}
+		line += " [deprecated = true]"
+	}
+	b.addLine(append(messagePath, messageFieldFieldNumber, int32(fieldIndex)), 2, line+";", "")
+	return fieldDescriptorProto
+}
+
+// setMessageFieldType sets the type of the field to a message, cycling through the messages
+// declared before the given message in this file and the first message of each imported file.
+//
+// Returns false if there is no message to reference.
+func (b *corpusFileBuilder) setMessageFieldType(
+	fieldDescriptorProto *descriptorpb.FieldDescriptorProto,
+	messageIndex int,
+) bool {
+	numImportCandidates := len(b.importFileIndexes)
+	if b.corpusSpec.NumMessagesPerFile == 0 {
+		numImportCandidates = 0
+	}
+	numCandidates := messageIndex + numImportCandidates
+	if numCandidates == 0 {
+		return false
+	}
+	candidate := b.numMessageFields % numCandidates
+	b.numMessageFields++
+	fieldDescriptorProto.Type = descriptorpb.FieldDescriptorProto_TYPE_MESSAGE.Enum()
+	if candidate < numImportCandidates {
+		b.usedImports[candidate] = struct{}{}
+		fieldDescriptorProto.TypeName = proto.String("." + corpusPackageName(b.importFileIndexes[candidate]) + ".Message0")
+		return true
+	}
+	fieldDescriptorProto.TypeName = proto.String(
+		fmt.Sprintf(".%s.Message%d", corpusPackageName(b.fileIndex), candidate-numImportCandidates),
+	)
+	return true
+}
+
+func (b *corpusFileBuilder) buildEnum(enumIndex int) *descriptorpb.EnumDescriptorProto {
+	name := fmt.Sprintf("Enum%d", enumIndex)
+	valuePrefix := strings.ToUpper(name)
+	path := []int32{fileEnumTypeFieldNumber, int32(enumIndex)}
+	startLine := b.addBlockStartLine(path, 0, "enum "+name+" {", " "+name+" is a synthetic enum.\n")
+	enumDescriptorProto := &descriptorpb.EnumDescriptorProto{
+		Name: proto.String(name),
+	}
+	for i := 0; i <= b.corpusSpec.NumValuesPerEnum; i++ {
+		valueName := valuePrefix + "_UNSPECIFIED"
+		if i > 0 {
+			valueName = fmt.Sprintf("%s_VALUE%d", valuePrefix, i)
+		}
+		enumValueDescriptorProto := &descriptorpb.EnumValueDescriptorProto{
+			Name:   proto.String(valueName),
+			Number: proto.Int32(int32(i)),
+		}
+		line := fmt.Sprintf("%s = %d", valueName, i)
+		if b.corpusSpec.WithOptions && i > 0 && isEveryFifth(i-1) {
+			enumValueDescriptorProto.Options = &descriptorpb.EnumValueOptions{
+				Deprecated: proto.Bool(true),
+			}
+			line += " [deprecated = true]"
+		}
+		b.addLine(append(path, enumValueFieldNumber, int32(i)), 2, line+";", "")
+		enumDescriptorProto.Value = append(enumDescriptorProto.Value, enumValueDescriptorProto)
+	}
+	b.addBlockEndLine(path, 0, startLine)
+	return enumDescriptorProto
+}
+
+func (b *corpusFileBuilder) buildService(serviceIndex int) *descriptorpb.ServiceDescriptorProto {
+	name := fmt.Sprintf("Service%d", serviceIndex)
+	path := []int32{fileServiceFieldNumber, int32(serviceIndex)}
+	startLine := b.addBlockStartLine(path, 0, "service "+name+" {", " "+name+" is a synthetic service.\n")
+	serviceDescriptorProto := &descriptorpb.ServiceDescriptorProto{
+		Name: proto.String(name),
+	}
+	numMessages := b.corpusSpec.NumMessagesPerFile
+	for i := 0; i < b.corpusSpec.NumMethodsPerService; i++ {
+		methodName := fmt.Sprintf("Method%d", i)
+		inputName := fmt.Sprintf("Message%d", i%numMessages)
+		outputName := fmt.Sprintf("Message%d", (i+1)%numMessages)
+		methodDescriptorProto := &descriptorpb.MethodDescriptorProto{
+			Name:       proto.String(methodName),
+			InputType:  proto.String("." + corpusPackageName(b.fileIndex) + "." + inputName),
+			OutputType: proto.String("." + corpusPackageName(b.fileIndex) + "." + outputName),
+		}
+		line := fmt.Sprintf("rpc %s(%s) returns (%s)", methodName, inputName, outputName)
+		methodPath := append(path, serviceMethodFieldNumber, int32(i))
+		leadingComments := " " + methodName + " is a synthetic method.\n"
+		if b.corpusSpec.WithOptions && isEveryFifth(i) {
+			methodDescriptorProto.Options = &descriptorpb.MethodOptions{
+				Deprecated: proto.Bool(true),
+			}
+			methodStartLine := b.addBlockStartLine(methodPath, 2, line+" {", leadingComments)
+			b.addOptionLine(append(methodPath, 4, 33), 4, "option deprecated = true;")
+			b.addBlockEndLine(methodPath, 2, methodStartLine)
+		} else {
+			b.addLine(methodPath, 2, line+";", leadingComments)
+		}
+		serviceDescriptorProto.Method = append(serviceDescriptorProto.Method, methodDescriptorProto)
+	}
+	b.addBlockEndLine(path, 0, startLine)
+	return serviceDescriptorProto
+}
+
+// addLine adds a single line with the given indent, and a location spanning the line.
+//
+// If leadingComments is not empty, a comment line is added first.
+func (b *corpusFileBuilder) addLine(path []int32, indent int, text string, leadingComments string) {
+	line := b.addCommentLines(leadingComments)
+	b.addLocation(path, []int32{line, int32(indent), int32(indent + len(text))}, leadingComments)
+}
+
+// addOptionLine adds a line for an option, along with the location for the option path.
+//
+// The path is the path of the option value itself. This also adds a location for the options
+// message, which is the path without its last element, matching what protoc produces.
+func (b *corpusFileBuilder) addOptionLine(path []int32, indent int, text string) {
+	line := b.nextLine()
+	span := []int32{line, int32(indent), int32(indent + len(text))}
+	b.addLocation(path[:len(path)-1], span, "")
+	b.addLocation(path, span, "")
+}
+
+// addBlockStartLine adds the opening line of a block, and returns the line number so that
+// the location can be added in addBlockEndLine.
+func (b *corpusFileBuilder) addBlockStartLine(path []int32, indent int, text string, leadingComments string) int32 {
+	line := b.addCommentLines(leadingComments)
+	// The end of the span is not known until addBlockEndLine is called, but locations
+	// should be in source order, so the location is added now and completed later.
+	b.addLocation(path, []int32{line, int32(indent)}, leadingComments)
+	return line
+}
+
+func (b *corpusFileBuilder) addBlockEndLine(path []int32, indent int, startLine int32) {
+	endLine := b.nextLine()
+	for i := len(b.locations) - 1; i >= 0; i-- {
+		location := b.locations[i]
+		if len(location.Span) == 2 && location.Span[0] == startLine && slices.Equal(location.Path, path) {
+			location.Span = append(location.Span, endLine, int32(indent+1))
+			return
+		}
+	}
+}
+
+// addCommentLines adds a line for each line of the comment, and then returns the next line.
+//
+// Comments always end in a newline.
+func (b *corpusFileBuilder) addCommentLines(leadingComments string) int32 {
+	for i := 0; i < strings.Count(leadingComments, "\n"); i++ {
+		b.nextLine()
+	}
+	return b.nextLine()
+}
+
+func (b *corpusFileBuilder) addBlankLine() {
+	b.nextLine()
+}
+
+func (b *corpusFileBuilder) nextLine() int32 {
+	line := int32(b.numLines)
+	b.numLines++
+	return line
+}
+
+func (b *corpusFileBuilder) addLocation(path []int32, span []int32, leadingComments string) {
+	location := &descriptorpb.SourceCodeInfo_Location{
+		Path: slices.Clone(path),
+		Span: span,
+	}
+	if leadingComments != "" {
+		location.LeadingComments = proto.String(leadingComments)
+	}
+	b.locations = append(b.locations, location)
+}
+
+func corpusFileName(fileIndex int) string {
+	return fmt.Sprintf("corpus/file%d.proto", fileIndex)
+}
+
+func corpusPackageName(fileIndex int) string {
+	return fmt.Sprintf("corpus.file%d", fileIndex)
+}
+
+func isEveryFifth(index int) bool {
+	return index%5 == 4
+}
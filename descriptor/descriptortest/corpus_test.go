@@ -0,0 +1,150 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package descriptortest
+
+import (
+	"testing"
+
+	descriptorv1 "buf.build/gen/go/bufbuild/bufplugin/protocolbuffers/go/buf/plugin/descriptor/v1"
+	"buf.build/go/bufplugin/descriptor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+func TestNewCorpus(t *testing.T) {
+	t.Parallel()
+
+	corpusSpec := &CorpusSpec{
+		NumFiles:             4,
+		NumMessagesPerFile:   3,
+		NumFieldsPerMessage:  8,
+		NumEnumsPerFile:      2,
+		NumValuesPerEnum:     5,
+		NumServicesPerFile:   1,
+		NumMethodsPerService: 6,
+		NumImportsPerFile:    2,
+		WithOptions:          true,
+	}
+	fileDescriptors, err := NewCorpus(corpusSpec)
+	require.NoError(t, err)
+	require.Len(t, fileDescriptors, 4)
+
+	for i, fileDescriptor := range fileDescriptors {
+		protoreflectFileDescriptor := fileDescriptor.ProtoreflectFileDescriptor()
+		assert.Equal(t, corpusFileName(i), protoreflectFileDescriptor.Path())
+		assert.Equal(t, min(i, 2), protoreflectFileDescriptor.Imports().Len())
+		assert.Empty(t, fileDescriptor.UnusedDependencyIndexes())
+		assert.Equal(t, 3, protoreflectFileDescriptor.Messages().Len())
+		assert.Equal(t, 2, protoreflectFileDescriptor.Enums().Len())
+		assert.Equal(t, 6, protoreflectFileDescriptor.Enums().Get(0).Values().Len())
+		assert.Equal(t, 6, protoreflectFileDescriptor.Services().Get(0).Methods().Len())
+
+		messageDescriptor := protoreflectFileDescriptor.Messages().Get(1)
+		location := protoreflectFileDescriptor.SourceLocations().ByDescriptor(messageDescriptor)
+		assert.Equal(t, " Message1 is a synthetic message.\n", location.LeadingComments)
+		assert.Less(t, location.StartLine, location.EndLine)
+		fieldLocation := protoreflectFileDescriptor.SourceLocations().ByDescriptor(messageDescriptor.Fields().Get(0))
+		assert.Less(t, location.StartLine, fieldLocation.StartLine)
+		assert.Less(t, fieldLocation.EndLine, location.EndLine)
+		assert.Equal(t, 2, fieldLocation.StartColumn)
+	}
+
+	fileDescriptorSet, err := NewCorpusFileDescriptorSet(corpusSpec)
+	require.NoError(t, err)
+	require.Len(t, fileDescriptorSet.GetFile(), 4)
+	for i, fileDescriptor := range fileDescriptors {
+		assert.True(t, proto.Equal(fileDescriptor.FileDescriptorProto(), fileDescriptorSet.GetFile()[i]))
+	}
+}
+
+func TestNewCorpusUnusedImports(t *testing.T) {
+	t.Parallel()
+
+	fileDescriptors, err := NewCorpus(
+		&CorpusSpec{
+			NumFiles:            3,
+			NumMessagesPerFile:  1,
+			NumFieldsPerMessage: 4,
+			NumImportsPerFile:   2,
+		},
+	)
+	require.NoError(t, err)
+	require.Len(t, fileDescriptors, 3)
+	assert.Empty(t, fileDescriptors[1].UnusedDependencyIndexes())
+	// Only a single message-typed field, so only the first import is used.
+	assert.Equal(t, []int32{1}, fileDescriptors[2].UnusedDependencyIndexes())
+}
+
+func TestNewCorpusValidate(t *testing.T) {
+	t.Parallel()
+
+	_, err := NewCorpus(nil)
+	assert.Error(t, err)
+	_, err = NewCorpus(&CorpusSpec{})
+	assert.Error(t, err)
+	_, err = NewCorpus(&CorpusSpec{NumFiles: 1, NumFieldsPerMessage: -1})
+	assert.Error(t, err)
+	_, err = NewCorpus(&CorpusSpec{NumFiles: 1, NumServicesPerFile: 1})
+	assert.Error(t, err)
+}
+
+func TestCorpusDigest(t *testing.T) {
+	t.Parallel()
+
+	corpusSpec := &CorpusSpec{
+		NumFiles:            2,
+		NumMessagesPerFile:  2,
+		NumFieldsPerMessage: 3,
+	}
+	fileDescriptors, err := NewCorpus(corpusSpec)
+	require.NoError(t, err)
+	otherFileDescriptors, err := NewCorpus(corpusSpec)
+	require.NoError(t, err)
+
+	for i, fileDescriptor := range fileDescriptors {
+		digest := fileDescriptor.Digest()
+		assert.Len(t, digest, 64)
+		assert.Equal(t, digest, otherFileDescriptors[i].Digest())
+		assert.NotEqual(t, digest, fileDescriptor.Digest(descriptor.DigestWithSourceCodeInfo()))
+		assert.Equal(
+			t,
+			fileDescriptor.Digest(descriptor.DigestWithSourceCodeInfo()),
+			otherFileDescriptors[i].Digest(descriptor.DigestWithSourceCodeInfo()),
+		)
+		// The SourceCodeInfo must not be stripped from the shared FileDescriptorProto.
+		assert.NotNil(t, fileDescriptor.FileDescriptorProto().GetSourceCodeInfo())
+	}
+	assert.NotEqual(t, fileDescriptors[0].Digest(), fileDescriptors[1].Digest())
+
+	// Changing only comments changes the digest with SourceCodeInfo, but not the default digest.
+	fileDescriptorProto := proto.Clone(fileDescriptors[0].FileDescriptorProto()).(*descriptorpb.FileDescriptorProto)
+	fileDescriptorProto.GetSourceCodeInfo().GetLocation()[0].LeadingComments = proto.String(" changed\n")
+	changedFileDescriptors, err := descriptor.FileDescriptorsForProtoFileDescriptors(
+		[]*descriptorv1.FileDescriptor{
+			{
+				FileDescriptorProto: fileDescriptorProto,
+			},
+		},
+	)
+	require.NoError(t, err)
+	assert.Equal(t, fileDescriptors[0].Digest(), changedFileDescriptors[0].Digest())
+	assert.NotEqual(
+		t,
+		fileDescriptors[0].Digest(descriptor.DigestWithSourceCodeInfo()),
+		changedFileDescriptors[0].Digest(descriptor.DigestWithSourceCodeInfo()),
+	)
+}
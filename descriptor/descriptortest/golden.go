@@ -0,0 +1,134 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package descriptortest
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"buf.build/go/bufplugin/descriptor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/prototext"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// The flag is namespaced so that it does not conflict with an -update flag defined
+// by the package under test.
+var updateGoldenFiles = flag.Bool(
+	"descriptortest.update",
+	false,
+	"update descriptortest golden files instead of comparing against them",
+)
+
+// AssertFileDescriptorSetGolden asserts that the FileDescriptorSet for the FileDescriptors equals
+// the binary FileDescriptorSet stored in the golden file at the given path.
+//
+// This allows plugin repositories to check in compiled test fixtures, and to detect when the
+// descriptors produced for the fixtures change, for example due to a new version of the compiler.
+// The golden file can be read back with ReadFileDescriptorSetGolden.
+//
+// If the test is run with the -descriptortest.update flag, the golden file is written with the
+// FileDescriptorSet instead, creating any parent directories as needed:
+//
+//	go test ./path/to/plugin -descriptortest.update
+//
+// The flag is only defined in test binaries that import descriptortest, so it should only be
+// passed when running the tests of such packages.
+//
+// The FileDescriptorProtos are compared file by file, in order, including SourceCodeInfo. For
+// each file that differs, the text format of both FileDescriptorProtos is compared so that the
+// failure shows the differences.
+func AssertFileDescriptorSetGolden(t *testing.T, goldenFilePath string, fileDescriptors []descriptor.FileDescriptor) {
+	fileDescriptorSet := descriptor.FileDescriptorSetForFileDescriptors(fileDescriptors)
+	if *updateGoldenFiles {
+		data, err := proto.MarshalOptions{Deterministic: true}.Marshal(fileDescriptorSet)
+		require.NoError(t, err)
+		require.NoError(t, os.MkdirAll(filepath.Dir(goldenFilePath), 0750))
+		require.NoError(t, os.WriteFile(goldenFilePath, data, 0600))
+		return
+	}
+	goldenFileDescriptorSet, err := readFileDescriptorSet(goldenFilePath)
+	require.NoError(t, err, "could not read golden file, run with -descriptortest.update to create it")
+	assertFileDescriptorSetsEqual(t, goldenFileDescriptorSet, fileDescriptorSet)
+}
+
+// ReadFileDescriptorSetGolden reads the golden file written by AssertFileDescriptorSetGolden,
+// and returns the FileDescriptors within it.
+//
+// The files with the given paths are imports, all other files are not. This allows the compiled
+// fixtures to be used in tests without compiling them again.
+func ReadFileDescriptorSetGolden(goldenFilePath string, importFilePaths ...string) ([]descriptor.FileDescriptor, error) {
+	fileDescriptorSet, err := readFileDescriptorSet(goldenFilePath)
+	if err != nil {
+		return nil, err
+	}
+	return descriptor.FileDescriptorsForFileDescriptorSet(
+		fileDescriptorSet,
+		descriptor.FileDescriptorSetWithImportFilePaths(importFilePaths...),
+	)
+}
+
+// *** PRIVATE ***
+
+func readFileDescriptorSet(filePath string) (*descriptorpb.FileDescriptorSet, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	fileDescriptorSet := &descriptorpb.FileDescriptorSet{}
+	if err := proto.Unmarshal(data, fileDescriptorSet); err != nil {
+		return nil, err
+	}
+	return fileDescriptorSet, nil
+}
+
+func assertFileDescriptorSetsEqual(
+	t *testing.T,
+	expectedFileDescriptorSet *descriptorpb.FileDescriptorSet,
+	actualFileDescriptorSet *descriptorpb.FileDescriptorSet,
+) {
+	const updateMessage = "the descriptors changed, run with -descriptortest.update to update the golden file if this is intended"
+	expectedFileNames := fileNamesForFileDescriptorSet(expectedFileDescriptorSet)
+	actualFileNames := fileNamesForFileDescriptorSet(actualFileDescriptorSet)
+	if !assert.Equal(t, expectedFileNames, actualFileNames, updateMessage) {
+		return
+	}
+	for i, expectedFileDescriptorProto := range expectedFileDescriptorSet.GetFile() {
+		actualFileDescriptorProto := actualFileDescriptorSet.GetFile()[i]
+		if proto.Equal(expectedFileDescriptorProto, actualFileDescriptorProto) {
+			continue
+		}
+		assert.Equal(
+			t,
+			prototext.Format(expectedFileDescriptorProto),
+			prototext.Format(actualFileDescriptorProto),
+			"%s: %s",
+			expectedFileDescriptorProto.GetName(),
+			updateMessage,
+		)
+	}
+}
+
+func fileNamesForFileDescriptorSet(fileDescriptorSet *descriptorpb.FileDescriptorSet) []string {
+	fileNames := make([]string, len(fileDescriptorSet.GetFile()))
+	for i, fileDescriptorProto := range fileDescriptorSet.GetFile() {
+		fileNames[i] = fileDescriptorProto.GetName()
+	}
+	return fileNames
+}
@@ -0,0 +1,62 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package descriptortest
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"buf.build/go/bufplugin/descriptor"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestAssertFileDescriptorSetGolden(t *testing.T) {
+	t.Parallel()
+
+	fileDescriptors, err := CompileStrings(
+		context.Background(),
+		map[string]string{
+			"foo/v1/foo.proto": `syntax = "proto3";
+package foo.v1;
+import "google/protobuf/timestamp.proto";
+// Foo is a foo.
+message Foo {
+  google.protobuf.Timestamp created = 1;
+}
+`,
+		},
+	)
+	require.NoError(t, err)
+	data, err := proto.MarshalOptions{Deterministic: true}.Marshal(
+		descriptor.FileDescriptorSetForFileDescriptors(fileDescriptors),
+	)
+	require.NoError(t, err)
+	goldenFilePath := filepath.Join(t.TempDir(), "testdata", "image.binpb")
+	require.NoError(t, os.MkdirAll(filepath.Dir(goldenFilePath), 0750))
+	require.NoError(t, os.WriteFile(goldenFilePath, data, 0600))
+
+	AssertFileDescriptorSetGolden(t, goldenFilePath, fileDescriptors)
+
+	goldenFileDescriptors, err := ReadFileDescriptorSetGolden(goldenFilePath, "google/protobuf/timestamp.proto")
+	require.NoError(t, err)
+	require.Len(t, goldenFileDescriptors, len(fileDescriptors))
+	for i, goldenFileDescriptor := range goldenFileDescriptors {
+		require.Equal(t, fileDescriptors[i].IsImport(), goldenFileDescriptor.IsImport())
+		require.True(t, proto.Equal(fileDescriptors[i].FileDescriptorProto(), goldenFileDescriptor.FileDescriptorProto()))
+	}
+}
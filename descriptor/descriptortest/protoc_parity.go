@@ -0,0 +1,292 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package descriptortest
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"buf.build/go/bufplugin/internal/pkg/xprotocompile"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// maxProtocParityDifferences is the maximum number of differences reported by CheckProtocParity.
+const maxProtocParityDifferences = 20
+
+// ErrProtocNotFound is returned from CheckProtocParity if protoc could not be found.
+//
+// Tests typically skip if this is returned, so that protoc parity is only verified on machines
+// where protoc is installed.
+var ErrProtocNotFound = errors.New("protoc not found")
+
+// ProtocParitySpec specifies the files to compile with CheckProtocParity.
+type ProtocParitySpec struct {
+	// DirPaths are the paths where .proto files are contained.
+	//
+	// This corresponds to the -I flag in protoc. This must contain at least one element.
+	DirPaths []string
+	// FilePaths are the specific paths to compile within the DirPaths.
+	//
+	// Paths are relative to the DirPaths, and use forward slashes. This must contain at least
+	// one element. Only these files are compared, and not their imports, as the Well-Known
+	// Types may differ between versions of protoc.
+	FilePaths []string
+	// ProtocPath is the path to the protoc binary.
+	//
+	// If empty, protoc is looked up on the PATH.
+	ProtocPath string
+}
+
+// CheckProtocParity compiles the files with both protoc and protocompile, and verifies that
+// the produced descriptors are the same.
+//
+// Plugins are typically tested with descriptors compiled by protocompile, which is what buf
+// uses, but may be invoked with descriptors compiled by protoc. This catches subtle differences
+// in source information or options between compilers that could change plugin behavior.
+//
+// The FileDescriptorProtos are compared without SourceCodeInfo, treating an empty syntax as
+// proto2, as protoc does not set the syntax for proto2 files. Every location produced by protoc
+// must then have the same span and comments within the locations produced by protocompile.
+// protocompile may produce additional locations, as buf includes locations for each option.
+//
+// Returns an error wrapping ErrProtocNotFound if protoc could not be found, and an error
+// describing the differences if the descriptors differ.
+func CheckProtocParity(ctx context.Context, protocParitySpec *ProtocParitySpec) error {
+	if err := validateProtocParitySpec(protocParitySpec); err != nil {
+		return err
+	}
+	protocFileDescriptorSet, err := compileWithProtoc(ctx, protocParitySpec)
+	if err != nil {
+		return err
+	}
+	fileDescriptors, err := xprotocompile.Compile(ctx, nil, protocParitySpec.DirPaths, nil, protocParitySpec.FilePaths)
+	if err != nil {
+		return fmt.Errorf("protocompile: %w", err)
+	}
+	pathToFileDescriptorProto := make(map[string]*descriptorpb.FileDescriptorProto, len(fileDescriptors))
+	for _, fileDescriptor := range fileDescriptors {
+		pathToFileDescriptorProto[fileDescriptor.ProtoreflectFileDescriptor().Path()] = fileDescriptor.FileDescriptorProto()
+	}
+	protocPathToFileDescriptorProto := make(map[string]*descriptorpb.FileDescriptorProto, len(protocFileDescriptorSet.GetFile()))
+	for _, fileDescriptorProto := range protocFileDescriptorSet.GetFile() {
+		protocPathToFileDescriptorProto[fileDescriptorProto.GetName()] = fileDescriptorProto
+	}
+	var differences []string
+	for _, filePath := range protocParitySpec.FilePaths {
+		filePath = filepath.ToSlash(filepath.Clean(filePath))
+		protocFileDescriptorProto, ok := protocPathToFileDescriptorProto[filePath]
+		if !ok {
+			differences = append(differences, fmt.Sprintf("%s: not produced by protoc", filePath))
+			continue
+		}
+		fileDescriptorProto, ok := pathToFileDescriptorProto[filePath]
+		if !ok {
+			differences = append(differences, fmt.Sprintf("%s: not produced by protocompile", filePath))
+			continue
+		}
+		differences = append(differences, fileDescriptorProtoDifferences(filePath, protocFileDescriptorProto, fileDescriptorProto)...)
+	}
+	if len(differences) == 0 {
+		return nil
+	}
+	if len(differences) > maxProtocParityDifferences {
+		numOmitted := len(differences) - maxProtocParityDifferences
+		differences = append(differences[:maxProtocParityDifferences], fmt.Sprintf("...and %d more", numOmitted))
+	}
+	return fmt.Errorf("protoc and protocompile produced different descriptors:\n  %s", strings.Join(differences, "\n  "))
+}
+
+// *** PRIVATE ***
+
+func validateProtocParitySpec(protocParitySpec *ProtocParitySpec) error {
+	if protocParitySpec == nil {
+		return errors.New("ProtocParitySpec is nil")
+	}
+	if len(protocParitySpec.DirPaths) == 0 {
+		return errors.New("ProtocParitySpec.DirPaths is empty")
+	}
+	if len(protocParitySpec.FilePaths) == 0 {
+		return errors.New("ProtocParitySpec.FilePaths is empty")
+	}
+	return nil
+}
+
+func compileWithProtoc(ctx context.Context, protocParitySpec *ProtocParitySpec) (*descriptorpb.FileDescriptorSet, error) {
+	protocPath := protocParitySpec.ProtocPath
+	if protocPath == "" {
+		protocPath = "protoc"
+	}
+	protocPath, err := exec.LookPath(protocPath)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrProtocNotFound, err)
+	}
+	tempDirPath, err := os.MkdirTemp("", "bufplugin-protoc-parity")
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = os.RemoveAll(tempDirPath)
+	}()
+	outputFilePath := filepath.Join(tempDirPath, "image.binpb")
+	args := []string{
+		"--include_imports",
+		"--include_source_info",
+		"--descriptor_set_out=" + outputFilePath,
+	}
+	for _, dirPath := range protocParitySpec.DirPaths {
+		args = append(args, "-I", filepath.FromSlash(dirPath))
+	}
+	for _, filePath := range protocParitySpec.FilePaths {
+		args = append(args, filepath.FromSlash(filePath))
+	}
+	stderr := bytes.NewBuffer(nil)
+	cmd := exec.CommandContext(ctx, protocPath, args...)
+	cmd.Stderr = stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("protoc: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	data, err := os.ReadFile(outputFilePath)
+	if err != nil {
+		return nil, err
+	}
+	fileDescriptorSet := &descriptorpb.FileDescriptorSet{}
+	if err := proto.Unmarshal(data, fileDescriptorSet); err != nil {
+		return nil, fmt.Errorf("protoc: %w", err)
+	}
+	return fileDescriptorSet, nil
+}
+
+func fileDescriptorProtoDifferences(
+	filePath string,
+	protocFileDescriptorProto *descriptorpb.FileDescriptorProto,
+	fileDescriptorProto *descriptorpb.FileDescriptorProto,
+) []string {
+	var differences []string
+	protocWithoutSourceCodeInfo := normalizedFileDescriptorProtoWithoutSourceCodeInfo(protocFileDescriptorProto)
+	withoutSourceCodeInfo := normalizedFileDescriptorProtoWithoutSourceCodeInfo(fileDescriptorProto)
+	messageDifferences(
+		filePath,
+		protocWithoutSourceCodeInfo.ProtoReflect(),
+		withoutSourceCodeInfo.ProtoReflect(),
+		&differences,
+	)
+	pathKeyToLocation := make(map[string]*descriptorpb.SourceCodeInfo_Location)
+	for _, location := range fileDescriptorProto.GetSourceCodeInfo().GetLocation() {
+		pathKey := protoreflect.SourcePath(location.GetPath()).String()
+		if _, ok := pathKeyToLocation[pathKey]; !ok {
+			pathKeyToLocation[pathKey] = location
+		}
+	}
+	seenPathKeys := make(map[string]struct{})
+	for _, protocLocation := range protocFileDescriptorProto.GetSourceCodeInfo().GetLocation() {
+		pathKey := protoreflect.SourcePath(protocLocation.GetPath()).String()
+		if _, ok := seenPathKeys[pathKey]; ok {
+			// Only the first location for each path is compared, as with SourceLocations.ByPath.
+			continue
+		}
+		seenPathKeys[pathKey] = struct{}{}
+		location, ok := pathKeyToLocation[pathKey]
+		if !ok {
+			differences = append(differences, fmt.Sprintf("%s: location %s: not produced by protocompile", filePath, pathKey))
+			continue
+		}
+		if !slices.Equal(protocLocation.GetSpan(), location.GetSpan()) {
+			differences = append(
+				differences,
+				fmt.Sprintf("%s: location %s: span %v from protoc, %v from protocompile", filePath, pathKey, protocLocation.GetSpan(), location.GetSpan()),
+			)
+		}
+		if protocLocation.GetLeadingComments() != location.GetLeadingComments() ||
+			protocLocation.GetTrailingComments() != location.GetTrailingComments() ||
+			!slices.Equal(protocLocation.GetLeadingDetachedComments(), location.GetLeadingDetachedComments()) {
+			differences = append(differences, fmt.Sprintf("%s: location %s: comments differ", filePath, pathKey))
+		}
+	}
+	return differences
+}
+
+func normalizedFileDescriptorProtoWithoutSourceCodeInfo(fileDescriptorProto *descriptorpb.FileDescriptorProto) *descriptorpb.FileDescriptorProto {
+	clone, ok := proto.Clone(fileDescriptorProto).(*descriptorpb.FileDescriptorProto)
+	if !ok {
+		// A clone of a FileDescriptorProto is always a FileDescriptorProto.
+		return &descriptorpb.FileDescriptorProto{}
+	}
+	clone.SourceCodeInfo = nil
+	if clone.GetSyntax() == "proto2" {
+		clone.Syntax = nil
+	}
+	return clone
+}
+
+// messageDifferences appends a difference for every field that differs between the messages,
+// identified by its path from the root message.
+func messageDifferences(
+	path string,
+	protocMessage protoreflect.Message,
+	message protoreflect.Message,
+	differences *[]string,
+) {
+	fields := protocMessage.Descriptor().Fields()
+	for i := 0; i < fields.Len(); i++ {
+		field := fields.Get(i)
+		fieldPath := path + "." + string(field.Name())
+		protocHas := protocMessage.Has(field)
+		has := message.Has(field)
+		switch {
+		case !protocHas && !has:
+			continue
+		case protocHas != has:
+			*differences = append(*differences, fmt.Sprintf("%s: set by %s only", fieldPath, setByCompiler(protocHas)))
+		case field.IsList():
+			protocList := protocMessage.Get(field).List()
+			list := message.Get(field).List()
+			if protocList.Len() != list.Len() {
+				*differences = append(*differences, fmt.Sprintf("%s: %d elements from protoc, %d from protocompile", fieldPath, protocList.Len(), list.Len()))
+				continue
+			}
+			for j := 0; j < protocList.Len(); j++ {
+				elementPath := fmt.Sprintf("%s[%d]", fieldPath, j)
+				if field.Message() != nil {
+					messageDifferences(elementPath, protocList.Get(j).Message(), list.Get(j).Message(), differences)
+				} else if !protocList.Get(j).Equal(list.Get(j)) {
+					*differences = append(*differences, fmt.Sprintf("%s: %v from protoc, %v from protocompile", elementPath, protocList.Get(j), list.Get(j)))
+				}
+			}
+		case field.Message() != nil && !field.IsMap():
+			messageDifferences(fieldPath, protocMessage.Get(field).Message(), message.Get(field).Message(), differences)
+		case !protocMessage.Get(field).Equal(message.Get(field)):
+			*differences = append(*differences, fmt.Sprintf("%s: %v from protoc, %v from protocompile", fieldPath, protocMessage.Get(field), message.Get(field)))
+		}
+	}
+	if !bytes.Equal(protocMessage.GetUnknown(), message.GetUnknown()) {
+		*differences = append(*differences, fmt.Sprintf("%s: unknown fields differ", path))
+	}
+}
+
+func setByCompiler(protocHas bool) string {
+	if protocHas {
+		return "protoc"
+	}
+	return "protocompile"
+}
@@ -0,0 +1,155 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package descriptortest
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"buf.build/go/bufplugin/descriptor"
+	"buf.build/go/bufplugin/internal/pkg/xprotocompile"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+)
+
+const testProtocParityFileContent = `syntax = "proto3";
+
+package foo.v1;
+
+import "google/protobuf/timestamp.proto";
+
+// Foo is a foo.
+message Foo {
+  // created is when the Foo was created.
+  google.protobuf.Timestamp created = 1 [deprecated = true];
+  optional string name = 2; // The name.
+}
+`
+
+func TestCheckProtocParity(t *testing.T) {
+	t.Parallel()
+
+	dirPath := newTestProtocParityDir(t)
+	err := CheckProtocParity(
+		context.Background(),
+		&ProtocParitySpec{
+			DirPaths:  []string{dirPath},
+			FilePaths: []string{"foo/v1/foo.proto"},
+		},
+	)
+	if errors.Is(err, ErrProtocNotFound) {
+		t.Skip("protoc not found")
+	}
+	require.NoError(t, err)
+}
+
+func TestCheckProtocParityFakeProtoc(t *testing.T) {
+	t.Parallel()
+
+	if runtime.GOOS == "windows" {
+		t.Skip("fake protoc is a shell script")
+	}
+	ctx := context.Background()
+	dirPath := newTestProtocParityDir(t)
+	fileDescriptors, err := xprotocompile.Compile(ctx, nil, []string{dirPath}, nil, []string{"foo/v1/foo.proto"})
+	require.NoError(t, err)
+	fileDescriptorSet := descriptor.FileDescriptorSetForFileDescriptors(fileDescriptors)
+
+	protocPath := newTestFakeProtoc(t, fileDescriptorSet)
+	err = CheckProtocParity(
+		ctx,
+		&ProtocParitySpec{
+			DirPaths:   []string{dirPath},
+			FilePaths:  []string{"foo/v1/foo.proto"},
+			ProtocPath: protocPath,
+		},
+	)
+	require.NoError(t, err)
+
+	for _, fileDescriptorProto := range fileDescriptorSet.GetFile() {
+		if fileDescriptorProto.GetName() != "foo/v1/foo.proto" {
+			continue
+		}
+		fileDescriptorProto.GetMessageType()[0].GetField()[1].JsonName = proto.String("otherName")
+		for _, location := range fileDescriptorProto.GetSourceCodeInfo().GetLocation() {
+			if location.GetLeadingComments() == " Foo is a foo.\n" {
+				location.LeadingComments = proto.String(" Foo is not a foo.\n")
+			}
+		}
+	}
+	protocPath = newTestFakeProtoc(t, fileDescriptorSet)
+	err = CheckProtocParity(
+		ctx,
+		&ProtocParitySpec{
+			DirPaths:   []string{dirPath},
+			FilePaths:  []string{"foo/v1/foo.proto"},
+			ProtocPath: protocPath,
+		},
+	)
+	require.Error(t, err)
+	require.ErrorContains(t, err, "foo/v1/foo.proto.message_type[0].field[1].json_name")
+	require.ErrorContains(t, err, "foo/v1/foo.proto: location .message_type[0]: comments differ")
+}
+
+func TestCheckProtocParityNotFound(t *testing.T) {
+	t.Parallel()
+
+	err := CheckProtocParity(
+		context.Background(),
+		&ProtocParitySpec{
+			DirPaths:   []string{newTestProtocParityDir(t)},
+			FilePaths:  []string{"foo/v1/foo.proto"},
+			ProtocPath: filepath.Join(t.TempDir(), "protoc"),
+		},
+	)
+	require.ErrorIs(t, err, ErrProtocNotFound)
+}
+
+func newTestProtocParityDir(t *testing.T) string {
+	dirPath := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dirPath, "foo", "v1"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dirPath, "foo", "v1", "foo.proto"), []byte(testProtocParityFileContent), 0600))
+	return dirPath
+}
+
+// newTestFakeProtoc returns the path to a script that writes the FileDescriptorSet to the
+// path given by --descriptor_set_out.
+func newTestFakeProtoc(t *testing.T, fileDescriptorSet proto.Message) string {
+	dirPath := t.TempDir()
+	data, err := proto.Marshal(fileDescriptorSet)
+	require.NoError(t, err)
+	fileDescriptorSetFilePath := filepath.Join(dirPath, "image.binpb")
+	require.NoError(t, os.WriteFile(fileDescriptorSetFilePath, data, 0600))
+	protocPath := filepath.Join(dirPath, "protoc")
+	require.NoError(
+		t,
+		os.WriteFile(
+			protocPath,
+			[]byte(`#!/bin/sh
+for arg in "$@"; do
+  case "${arg}" in
+    --descriptor_set_out=*) cp "`+fileDescriptorSetFilePath+`" "${arg#--descriptor_set_out=}" ;;
+  esac
+done
+`),
+			0700,
+		),
+	)
+	return protocPath
+}
@@ -0,0 +1,529 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package descriptortest
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"strings"
+
+	descriptorv1 "buf.build/gen/go/bufbuild/bufplugin/protocolbuffers/go/buf/plugin/descriptor/v1"
+	"buf.build/go/bufplugin/descriptor"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+const (
+	randomExtensionRangeStart = 1000
+	// randomExtensionRangeEnd is exclusive, and is one greater than the maximum field number.
+	randomExtensionRangeEnd = 536870912
+)
+
+var (
+	randomScalarTypes = []descriptorpb.FieldDescriptorProto_Type{
+		descriptorpb.FieldDescriptorProto_TYPE_DOUBLE,
+		descriptorpb.FieldDescriptorProto_TYPE_FLOAT,
+		descriptorpb.FieldDescriptorProto_TYPE_INT64,
+		descriptorpb.FieldDescriptorProto_TYPE_UINT64,
+		descriptorpb.FieldDescriptorProto_TYPE_INT32,
+		descriptorpb.FieldDescriptorProto_TYPE_FIXED64,
+		descriptorpb.FieldDescriptorProto_TYPE_FIXED32,
+		descriptorpb.FieldDescriptorProto_TYPE_BOOL,
+		descriptorpb.FieldDescriptorProto_TYPE_STRING,
+		descriptorpb.FieldDescriptorProto_TYPE_BYTES,
+		descriptorpb.FieldDescriptorProto_TYPE_UINT32,
+		descriptorpb.FieldDescriptorProto_TYPE_SFIXED32,
+		descriptorpb.FieldDescriptorProto_TYPE_SFIXED64,
+		descriptorpb.FieldDescriptorProto_TYPE_SINT32,
+		descriptorpb.FieldDescriptorProto_TYPE_SINT64,
+	}
+	randomMapKeyTypes = []descriptorpb.FieldDescriptorProto_Type{
+		descriptorpb.FieldDescriptorProto_TYPE_INT64,
+		descriptorpb.FieldDescriptorProto_TYPE_INT32,
+		descriptorpb.FieldDescriptorProto_TYPE_BOOL,
+		descriptorpb.FieldDescriptorProto_TYPE_STRING,
+		descriptorpb.FieldDescriptorProto_TYPE_UINT32,
+		descriptorpb.FieldDescriptorProto_TYPE_SINT64,
+	}
+)
+
+// RandomSpec specifies the shape of randomly-generated files to generate with NewRandom.
+//
+// Each value is a maximum. The actual number of each element is chosen uniformly between
+// zero and the maximum, so that the generated files include unusual shapes such as empty
+// files, empty messages, empty services, and unused imports.
+//
+// The generated files are valid, and mix proto2 and proto3 syntax. In addition to the elements
+// specified below, the generated files include recursive message references, map fields,
+// oneofs, proto2 extension ranges, enum value aliases, streaming methods, files without
+// a package, and files marked as imports. The files do not have SourceCodeInfo.
+type RandomSpec struct {
+	// MaxFiles is the maximum number of files to generate.
+	//
+	// Required to be at least 1. At least one file is always generated, and the last file
+	// is never an import.
+	MaxFiles int
+	// MaxMessagesPerFile is the maximum number of top-level messages within each file.
+	MaxMessagesPerFile int
+	// MaxFieldsPerMessage is the maximum number of fields within each message.
+	MaxFieldsPerMessage int
+	// MaxNestingDepth is the maximum depth of nested messages and enums.
+	//
+	// If 0, no nested messages or enums are generated.
+	MaxNestingDepth int
+	// MaxNestedTypesPerMessage is the maximum number of nested messages, and separately
+	// the maximum number of nested enums, within each message.
+	MaxNestedTypesPerMessage int
+	// MaxEnumsPerFile is the maximum number of top-level enums within each file.
+	MaxEnumsPerFile int
+	// MaxValuesPerEnum is the maximum number of values within each enum, not including the
+	// first value that is always generated.
+	MaxValuesPerEnum int
+	// MaxServicesPerFile is the maximum number of services within each file.
+	MaxServicesPerFile int
+	// MaxMethodsPerService is the maximum number of methods within each service.
+	MaxMethodsPerService int
+	// MaxExtensionsPerFile is the maximum number of extensions within each proto2 file.
+	MaxExtensionsPerFile int
+	// MaxImportsPerFile is the maximum number of files that each file imports.
+	MaxImportsPerFile int
+}
+
+// NewRandom returns a new set of randomly-generated FileDescriptors for the RandomSpec.
+//
+// Generation is deterministic for a given seed: the same RandomSpec and seed always result
+// in the same files. The FileDescriptors are returned in dependency order.
+func NewRandom(randomSpec *RandomSpec, seed int64) ([]descriptor.FileDescriptor, error) {
+	if err := validateRandomSpec(randomSpec); err != nil {
+		return nil, err
+	}
+	return fileDescriptorsInOrder(newRandomBuilder(randomSpec, rand.New(rand.NewSource(seed))).build())
+}
+
+// *** PRIVATE ***
+
+func validateRandomSpec(randomSpec *RandomSpec) error {
+	if randomSpec == nil {
+		return errors.New("RandomSpec is nil")
+	}
+	if randomSpec.MaxFiles < 1 {
+		return errors.New("RandomSpec.MaxFiles must be at least 1")
+	}
+	for name, value := range map[string]int{
+		"MaxMessagesPerFile":       randomSpec.MaxMessagesPerFile,
+		"MaxFieldsPerMessage":      randomSpec.MaxFieldsPerMessage,
+		"MaxNestingDepth":          randomSpec.MaxNestingDepth,
+		"MaxNestedTypesPerMessage": randomSpec.MaxNestedTypesPerMessage,
+		"MaxEnumsPerFile":          randomSpec.MaxEnumsPerFile,
+		"MaxValuesPerEnum":         randomSpec.MaxValuesPerEnum,
+		"MaxServicesPerFile":       randomSpec.MaxServicesPerFile,
+		"MaxMethodsPerService":     randomSpec.MaxMethodsPerService,
+		"MaxExtensionsPerFile":     randomSpec.MaxExtensionsPerFile,
+		"MaxImportsPerFile":        randomSpec.MaxImportsPerFile,
+	} {
+		if value < 0 {
+			return fmt.Errorf("RandomSpec.%s must not be negative", name)
+		}
+	}
+	return nil
+}
+
+type randomType struct {
+	// fullName is the fully-qualified name, with a leading period.
+	fullName  string
+	fileIndex int
+	isProto3  bool
+	// isExtendable is only set for messages with extension ranges.
+	isExtendable bool
+}
+
+// randomBuilder builds all files, tracking the declared types so that references are only
+// made to types that are in scope.
+type randomBuilder struct {
+	randomSpec *RandomSpec
+	rand       *rand.Rand
+	// nextID is used to give every declaration a unique name across all files, so that files
+	// without a package cannot conflict.
+	nextID   int
+	messages []randomType
+	enums    []randomType
+	// extendeeToNextNumber is the next extension number to use for each extendee, as extension
+	// numbers must be unique across all files.
+	extendeeToNextNumber map[string]int32
+
+	// The below are for the file currently being built.
+	fileIndex         int
+	isProto3          bool
+	importFileIndexes map[int]struct{}
+}
+
+func newRandomBuilder(randomSpec *RandomSpec, rand *rand.Rand) *randomBuilder {
+	return &randomBuilder{
+		randomSpec:           randomSpec,
+		rand:                 rand,
+		extendeeToNextNumber: make(map[string]int32),
+	}
+}
+
+func (b *randomBuilder) build() []*descriptorv1.FileDescriptor {
+	numFiles := 1 + b.rand.Intn(b.randomSpec.MaxFiles)
+	protoFileDescriptors := make([]*descriptorv1.FileDescriptor, numFiles)
+	for i := 0; i < numFiles; i++ {
+		protoFileDescriptors[i] = &descriptorv1.FileDescriptor{
+			FileDescriptorProto: b.buildFile(i),
+			IsImport:            i < numFiles-1 && b.oneIn(4),
+		}
+	}
+	return protoFileDescriptors
+}
+
+func (b *randomBuilder) buildFile(fileIndex int) *descriptorpb.FileDescriptorProto {
+	b.fileIndex = fileIndex
+	b.isProto3 = b.oneIn(2)
+	b.importFileIndexes = make(map[int]struct{})
+	fileDescriptorProto := &descriptorpb.FileDescriptorProto{
+		Name:           proto.String(fmt.Sprintf("random/file%d.proto", fileIndex)),
+		Syntax:         proto.String("proto2"),
+		SourceCodeInfo: &descriptorpb.SourceCodeInfo{},
+	}
+	if b.isProto3 {
+		fileDescriptorProto.Syntax = proto.String("proto3")
+	}
+	var prefix string
+	if !b.oneIn(5) {
+		fileDescriptorProto.Package = proto.String(fmt.Sprintf("random.file%d", fileIndex))
+		prefix = "." + fileDescriptorProto.GetPackage()
+	}
+	if fileIndex > 0 {
+		for i := b.intn(b.randomSpec.MaxImportsPerFile); i > 0; i-- {
+			importFileIndex := b.rand.Intn(fileIndex)
+			if _, ok := b.importFileIndexes[importFileIndex]; ok {
+				continue
+			}
+			b.importFileIndexes[importFileIndex] = struct{}{}
+			fileDescriptorProto.Dependency = append(
+				fileDescriptorProto.Dependency,
+				fmt.Sprintf("random/file%d.proto", importFileIndex),
+			)
+		}
+	}
+	for i := b.intn(b.randomSpec.MaxEnumsPerFile); i > 0; i-- {
+		fileDescriptorProto.EnumType = append(fileDescriptorProto.EnumType, b.buildEnum(prefix))
+	}
+	for i := b.intn(b.randomSpec.MaxMessagesPerFile); i > 0; i-- {
+		fileDescriptorProto.MessageType = append(fileDescriptorProto.MessageType, b.buildMessage(prefix, 0))
+	}
+	if !b.isProto3 {
+		// Only proto2 files can declare extensions of messages.
+		for i := b.intn(b.randomSpec.MaxExtensionsPerFile); i > 0; i-- {
+			if extension := b.buildExtension(); extension != nil {
+				fileDescriptorProto.Extension = append(fileDescriptorProto.Extension, extension)
+			}
+		}
+	}
+	for i := b.intn(b.randomSpec.MaxServicesPerFile); i > 0; i-- {
+		fileDescriptorProto.Service = append(fileDescriptorProto.Service, b.buildService())
+	}
+	return fileDescriptorProto
+}
+
+func (b *randomBuilder) buildMessage(prefix string, depth int) *descriptorpb.DescriptorProto {
+	name := fmt.Sprintf("Message%d", b.nextUniqueID())
+	fullName := prefix + "." + name
+	messageDescriptorProto := &descriptorpb.DescriptorProto{
+		Name: proto.String(name),
+	}
+	isExtendable := !b.isProto3 && b.oneIn(3)
+	if isExtendable {
+		messageDescriptorProto.ExtensionRange = []*descriptorpb.DescriptorProto_ExtensionRange{
+			{
+				Start: proto.Int32(randomExtensionRangeStart),
+				End:   proto.Int32(randomExtensionRangeEnd),
+			},
+		}
+	}
+	// The message is registered before its fields are built, so that it can reference itself.
+	b.messages = append(
+		b.messages,
+		randomType{
+			fullName:     fullName,
+			fileIndex:    b.fileIndex,
+			isProto3:     b.isProto3,
+			isExtendable: isExtendable,
+		},
+	)
+	if depth < b.randomSpec.MaxNestingDepth {
+		for i := b.intn(b.randomSpec.MaxNestedTypesPerMessage); i > 0; i-- {
+			messageDescriptorProto.EnumType = append(messageDescriptorProto.EnumType, b.buildEnum(fullName))
+		}
+		for i := b.intn(b.randomSpec.MaxNestedTypesPerMessage); i > 0; i-- {
+			messageDescriptorProto.NestedType = append(messageDescriptorProto.NestedType, b.buildMessage(fullName, depth+1))
+		}
+	}
+	numFields := b.intn(b.randomSpec.MaxFieldsPerMessage)
+	for i := 0; i < numFields; i++ {
+		fieldName := fmt.Sprintf("field_%d", i+1)
+		if b.oneIn(6) {
+			fieldDescriptorProto, mapEntryDescriptorProto := b.buildMapField(fieldName, int32(i+1), fullName)
+			messageDescriptorProto.Field = append(messageDescriptorProto.Field, fieldDescriptorProto)
+			messageDescriptorProto.NestedType = append(messageDescriptorProto.NestedType, mapEntryDescriptorProto)
+			continue
+		}
+		messageDescriptorProto.Field = append(
+			messageDescriptorProto.Field,
+			b.buildField(fieldName, int32(i+1), b.randomFieldLabel(true)),
+		)
+	}
+	b.maybeAddOneof(messageDescriptorProto)
+	return messageDescriptorProto
+}
+
+func (b *randomBuilder) buildField(
+	name string,
+	number int32,
+	label descriptorpb.FieldDescriptorProto_Label,
+) *descriptorpb.FieldDescriptorProto {
+	fieldDescriptorProto := &descriptorpb.FieldDescriptorProto{
+		Name:   proto.String(name),
+		Number: proto.Int32(number),
+		Label:  label.Enum(),
+	}
+	switch b.rand.Intn(4) {
+	case 0:
+		if messages := b.inScope(b.messages, false); len(messages) > 0 {
+			fieldDescriptorProto.Type = descriptorpb.FieldDescriptorProto_TYPE_MESSAGE.Enum()
+			fieldDescriptorProto.TypeName = proto.String(messages[b.rand.Intn(len(messages))].fullName)
+			return fieldDescriptorProto
+		}
+	case 1:
+		// Fields within proto3 files can only reference open enums, which are the enums
+		// declared within proto3 files.
+		if enums := b.inScope(b.enums, b.isProto3); len(enums) > 0 {
+			fieldDescriptorProto.Type = descriptorpb.FieldDescriptorProto_TYPE_ENUM.Enum()
+			fieldDescriptorProto.TypeName = proto.String(enums[b.rand.Intn(len(enums))].fullName)
+			return fieldDescriptorProto
+		}
+	}
+	fieldDescriptorProto.Type = randomScalarTypes[b.rand.Intn(len(randomScalarTypes))].Enum()
+	return fieldDescriptorProto
+}
+
+func (b *randomBuilder) buildMapField(
+	name string,
+	number int32,
+	messageFullName string,
+) (*descriptorpb.FieldDescriptorProto, *descriptorpb.DescriptorProto) {
+	mapEntryName := mapEntryNameForFieldName(name)
+	valueFieldDescriptorProto := b.buildField("value", 2, descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL)
+	mapEntryDescriptorProto := &descriptorpb.DescriptorProto{
+		Name: proto.String(mapEntryName),
+		Field: []*descriptorpb.FieldDescriptorProto{
+			{
+				Name:   proto.String("key"),
+				Number: proto.Int32(1),
+				Label:  descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+				Type:   randomMapKeyTypes[b.rand.Intn(len(randomMapKeyTypes))].Enum(),
+			},
+			valueFieldDescriptorProto,
+		},
+		Options: &descriptorpb.MessageOptions{
+			MapEntry: proto.Bool(true),
+		},
+	}
+	fieldDescriptorProto := &descriptorpb.FieldDescriptorProto{
+		Name:     proto.String(name),
+		Number:   proto.Int32(number),
+		Label:    descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum(),
+		Type:     descriptorpb.FieldDescriptorProto_TYPE_MESSAGE.Enum(),
+		TypeName: proto.String(messageFullName + "." + mapEntryName),
+	}
+	return fieldDescriptorProto, mapEntryDescriptorProto
+}
+
+// maybeAddOneof adds a oneof containing a contiguous run of singular fields, if there are any.
+func (b *randomBuilder) maybeAddOneof(messageDescriptorProto *descriptorpb.DescriptorProto) {
+	if len(messageDescriptorProto.Field) == 0 || !b.oneIn(3) {
+		return
+	}
+	start := b.rand.Intn(len(messageDescriptorProto.Field))
+	end := start
+	for end < len(messageDescriptorProto.Field) &&
+		messageDescriptorProto.Field[end].GetLabel() == descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL &&
+		b.rand.Intn(4) != 0 {
+		end++
+	}
+	if start == end {
+		return
+	}
+	messageDescriptorProto.OneofDecl = append(
+		messageDescriptorProto.OneofDecl,
+		&descriptorpb.OneofDescriptorProto{
+			Name: proto.String(fmt.Sprintf("oneof_%d", b.nextUniqueID())),
+		},
+	)
+	for _, fieldDescriptorProto := range messageDescriptorProto.Field[start:end] {
+		fieldDescriptorProto.OneofIndex = proto.Int32(0)
+	}
+}
+
+func (b *randomBuilder) buildEnum(prefix string) *descriptorpb.EnumDescriptorProto {
+	id := b.nextUniqueID()
+	name := fmt.Sprintf("Enum%d", id)
+	enumDescriptorProto := &descriptorpb.EnumDescriptorProto{
+		Name: proto.String(name),
+	}
+	// Enum values are scoped to the parent of the enum, so the values are prefixed with the
+	// unique name of the enum.
+	numValues := 1 + b.intn(b.randomSpec.MaxValuesPerEnum)
+	for i := 0; i < numValues; i++ {
+		enumDescriptorProto.Value = append(
+			enumDescriptorProto.Value,
+			&descriptorpb.EnumValueDescriptorProto{
+				Name:   proto.String(fmt.Sprintf("ENUM%d_VALUE_%d", id, i)),
+				Number: proto.Int32(int32(i)),
+			},
+		)
+	}
+	if b.oneIn(5) {
+		enumDescriptorProto.Value = append(
+			enumDescriptorProto.Value,
+			&descriptorpb.EnumValueDescriptorProto{
+				Name:   proto.String(fmt.Sprintf("ENUM%d_ALIAS", id)),
+				Number: proto.Int32(0),
+			},
+		)
+		enumDescriptorProto.Options = &descriptorpb.EnumOptions{
+			AllowAlias: proto.Bool(true),
+		}
+	}
+	b.enums = append(
+		b.enums,
+		randomType{
+			fullName:  prefix + "." + name,
+			fileIndex: b.fileIndex,
+			isProto3:  b.isProto3,
+		},
+	)
+	return enumDescriptorProto
+}
+
+// buildExtension returns nil if there are no extendable messages in scope.
+func (b *randomBuilder) buildExtension() *descriptorpb.FieldDescriptorProto {
+	var extendees []randomType
+	for _, message := range b.inScope(b.messages, false) {
+		if message.isExtendable {
+			extendees = append(extendees, message)
+		}
+	}
+	if len(extendees) == 0 {
+		return nil
+	}
+	extendee := extendees[b.rand.Intn(len(extendees))].fullName
+	number, ok := b.extendeeToNextNumber[extendee]
+	if !ok {
+		number = randomExtensionRangeStart
+	}
+	b.extendeeToNextNumber[extendee] = number + 1
+	fieldDescriptorProto := b.buildField(
+		fmt.Sprintf("extension_%d", b.nextUniqueID()),
+		number,
+		// Extensions cannot be required.
+		b.randomFieldLabel(false),
+	)
+	fieldDescriptorProto.Extendee = proto.String(extendee)
+	return fieldDescriptorProto
+}
+
+func (b *randomBuilder) buildService() *descriptorpb.ServiceDescriptorProto {
+	serviceDescriptorProto := &descriptorpb.ServiceDescriptorProto{
+		Name: proto.String(fmt.Sprintf("Service%d", b.nextUniqueID())),
+	}
+	messages := b.inScope(b.messages, false)
+	if len(messages) == 0 {
+		return serviceDescriptorProto
+	}
+	for i := b.intn(b.randomSpec.MaxMethodsPerService); i > 0; i-- {
+		serviceDescriptorProto.Method = append(
+			serviceDescriptorProto.Method,
+			&descriptorpb.MethodDescriptorProto{
+				Name:            proto.String(fmt.Sprintf("Method%d", b.nextUniqueID())),
+				InputType:       proto.String(messages[b.rand.Intn(len(messages))].fullName),
+				OutputType:      proto.String(messages[b.rand.Intn(len(messages))].fullName),
+				ClientStreaming: proto.Bool(b.oneIn(4)),
+				ServerStreaming: proto.Bool(b.oneIn(4)),
+			},
+		)
+	}
+	return serviceDescriptorProto
+}
+
+func (b *randomBuilder) randomFieldLabel(allowRequired bool) descriptorpb.FieldDescriptorProto_Label {
+	switch {
+	case b.oneIn(4):
+		return descriptorpb.FieldDescriptorProto_LABEL_REPEATED
+	case allowRequired && !b.isProto3 && b.oneIn(8):
+		return descriptorpb.FieldDescriptorProto_LABEL_REQUIRED
+	default:
+		return descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL
+	}
+}
+
+// inScope returns the types that are declared within the current file or its imports.
+func (b *randomBuilder) inScope(types []randomType, proto3Only bool) []randomType {
+	var inScope []randomType
+	for _, randomType := range types {
+		if proto3Only && !randomType.isProto3 {
+			continue
+		}
+		if _, ok := b.importFileIndexes[randomType.fileIndex]; ok || randomType.fileIndex == b.fileIndex {
+			inScope = append(inScope, randomType)
+		}
+	}
+	return inScope
+}
+
+func (b *randomBuilder) nextUniqueID() int {
+	b.nextID++
+	return b.nextID
+}
+
+// intn returns a value in [0, n].
+func (b *randomBuilder) intn(n int) int {
+	return b.rand.Intn(n + 1)
+}
+
+func (b *randomBuilder) oneIn(n int) bool {
+	return b.rand.Intn(n) == 0
+}
+
+// mapEntryNameForFieldName returns the name of the map entry message for the map field,
+// for example "Field1Entry" for "field_1".
+func mapEntryNameForFieldName(fieldName string) string {
+	var builder strings.Builder
+	upperNext := true
+	for _, r := range fieldName {
+		if r == '_' {
+			upperNext = true
+			continue
+		}
+		if upperNext && 'a' <= r && r <= 'z' {
+			r -= 'a' - 'A'
+		}
+		upperNext = false
+		_, _ = builder.WriteRune(r)
+	}
+	return builder.String() + "Entry"
+}
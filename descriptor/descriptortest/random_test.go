@@ -0,0 +1,68 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package descriptortest
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestNewRandom(t *testing.T) {
+	t.Parallel()
+
+	randomSpec := &RandomSpec{
+		MaxFiles:                 6,
+		MaxMessagesPerFile:       4,
+		MaxFieldsPerMessage:      8,
+		MaxNestingDepth:          3,
+		MaxNestedTypesPerMessage: 2,
+		MaxEnumsPerFile:          2,
+		MaxValuesPerEnum:         4,
+		MaxServicesPerFile:       2,
+		MaxMethodsPerService:     3,
+		MaxExtensionsPerFile:     4,
+		MaxImportsPerFile:        3,
+	}
+	for seed := int64(0); seed < 500; seed++ {
+		fileDescriptors, err := NewRandom(randomSpec, seed)
+		require.NoError(t, err, "seed %d", seed)
+		require.NotEmpty(t, fileDescriptors)
+		assert.False(t, fileDescriptors[len(fileDescriptors)-1].IsImport())
+
+		otherFileDescriptors, err := NewRandom(randomSpec, seed)
+		require.NoError(t, err)
+		require.Len(t, otherFileDescriptors, len(fileDescriptors))
+		for i, fileDescriptor := range fileDescriptors {
+			assert.True(t, proto.Equal(fileDescriptor.FileDescriptorProto(), otherFileDescriptors[i].FileDescriptorProto()))
+		}
+	}
+}
+
+func TestNewRandomValidate(t *testing.T) {
+	t.Parallel()
+
+	_, err := NewRandom(nil, 0)
+	require.Error(t, err)
+	_, err = NewRandom(&RandomSpec{}, 0)
+	require.Error(t, err)
+	_, err = NewRandom(&RandomSpec{MaxFiles: 1, MaxFieldsPerMessage: -1}, 0)
+	require.Error(t, err)
+	fileDescriptors, err := NewRandom(&RandomSpec{MaxFiles: 1}, 0)
+	require.NoError(t, err)
+	assert.Len(t, fileDescriptors, 1)
+}
@@ -0,0 +1,249 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package descriptortest
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+
+	descriptorv1 "buf.build/gen/go/bufbuild/bufplugin/protocolbuffers/go/buf/plugin/descriptor/v1"
+	"buf.build/go/bufplugin/descriptor"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+const (
+	// SyntaxProto2 is the proto2 syntax.
+	SyntaxProto2 Syntax = 1
+	// SyntaxProto3 is the proto3 syntax.
+	SyntaxProto3 Syntax = 2
+	// SyntaxEditions2023 is edition 2023.
+	SyntaxEditions2023 Syntax = 3
+)
+
+const (
+	// Field numbers within descriptorpb.DescriptorProto.
+	messageNestedTypeFieldNumber = 3
+	messageOneofDeclFieldNumber  = 8
+)
+
+var (
+	// AllSyntaxes are all Syntaxes, in order.
+	AllSyntaxes = []Syntax{
+		SyntaxProto2,
+		SyntaxProto3,
+		SyntaxEditions2023,
+	}
+
+	syntaxToString = map[Syntax]string{
+		SyntaxProto2:       "proto2",
+		SyntaxProto3:       "proto3",
+		SyntaxEditions2023: "editions_2023",
+	}
+)
+
+// Syntax is a syntax or edition that files can be converted to with ConvertSyntax.
+type Syntax int
+
+// String implements fmt.Stringer.
+func (s Syntax) String() string {
+	if str, ok := syntaxToString[s]; ok {
+		return str
+	}
+	return strconv.Itoa(int(s))
+}
+
+// ConvertSyntax converts the proto3 files that are not imports to the given Syntax.
+//
+// This allows the same testdata to be run against each syntax, so that Rules that claim to be
+// independent of syntax are exercised across syntaxes. The files are converted in the same way
+// a migration tool would, so that the semantics of the files are preserved:
+//
+//   - For proto2, proto3 optional fields become proto2 optional fields, and repeated scalar
+//     numeric fields are marked as packed.
+//   - For edition 2023, files have implicit field presence, proto3 optional fields have
+//     explicit field presence, and fields that are not packed have expanded encoding.
+//
+// In both cases, the synthetic oneofs of proto3 optional fields are removed. Converting to
+// SyntaxProto3 returns equivalent files. Files that are imports are not converted, and the
+// SourceCodeInfo of converted files still refers to the original source.
+//
+// All files that are not imports must be proto3, as proto3 is the common subset of the
+// syntaxes. The input FileDescriptors are not modified.
+func ConvertSyntax(fileDescriptors []descriptor.FileDescriptor, syntax Syntax) ([]descriptor.FileDescriptor, error) {
+	if _, ok := syntaxToString[syntax]; !ok {
+		return nil, fmt.Errorf("unknown Syntax: %v", syntax)
+	}
+	protoFileDescriptors := make([]*descriptorv1.FileDescriptor, len(fileDescriptors))
+	for i, fileDescriptor := range fileDescriptors {
+		protoFileDescriptor := fileDescriptor.ToProto()
+		if !fileDescriptor.IsImport() {
+			fileDescriptorProto := fileDescriptor.FileDescriptorProto()
+			if fileDescriptorProto.GetSyntax() != "proto3" {
+				return nil, fmt.Errorf("%q must be proto3 to be converted, but was %q", fileDescriptorProto.GetName(), fileDescriptorProto.GetSyntax())
+			}
+			fileDescriptorProto = proto.Clone(fileDescriptorProto).(*descriptorpb.FileDescriptorProto)
+			convertFileDescriptorProto(fileDescriptorProto, syntax)
+			protoFileDescriptor = &descriptorv1.FileDescriptor{
+				FileDescriptorProto: fileDescriptorProto,
+				IsImport:            false,
+				IsSyntaxUnspecified: false,
+				UnusedDependency:    fileDescriptor.UnusedDependencyIndexes(),
+			}
+		}
+		protoFileDescriptors[i] = protoFileDescriptor
+	}
+	return fileDescriptorsInOrder(protoFileDescriptors)
+}
+
+// *** PRIVATE ***
+
+func convertFileDescriptorProto(fileDescriptorProto *descriptorpb.FileDescriptorProto, syntax Syntax) {
+	switch syntax {
+	case SyntaxProto2:
+		fileDescriptorProto.Syntax = proto.String("proto2")
+	case SyntaxProto3:
+		return
+	case SyntaxEditions2023:
+		fileDescriptorProto.Syntax = proto.String("editions")
+		fileDescriptorProto.Edition = descriptorpb.Edition_EDITION_2023.Enum()
+		if fileDescriptorProto.Options == nil {
+			fileDescriptorProto.Options = &descriptorpb.FileOptions{}
+		}
+		if fileDescriptorProto.Options.Features == nil {
+			fileDescriptorProto.Options.Features = &descriptorpb.FeatureSet{}
+		}
+		fileDescriptorProto.Options.Features.FieldPresence = descriptorpb.FeatureSet_IMPLICIT.Enum()
+	}
+	var removedOneofPaths [][]int32
+	for i, messageDescriptorProto := range fileDescriptorProto.GetMessageType() {
+		removedOneofPaths = convertMessageDescriptorProto(
+			messageDescriptorProto,
+			syntax,
+			[]int32{fileMessageTypeFieldNumber, int32(i)},
+			removedOneofPaths,
+		)
+	}
+	for _, fieldDescriptorProto := range fileDescriptorProto.GetExtension() {
+		convertFieldDescriptorProto(fieldDescriptorProto, syntax)
+	}
+	if sourceCodeInfo := fileDescriptorProto.GetSourceCodeInfo(); sourceCodeInfo != nil && len(removedOneofPaths) > 0 {
+		sourceCodeInfo.Location = slices.DeleteFunc(
+			sourceCodeInfo.Location,
+			func(location *descriptorpb.SourceCodeInfo_Location) bool {
+				return slices.ContainsFunc(
+					removedOneofPaths,
+					func(removedOneofPath []int32) bool {
+						path := location.GetPath()
+						return len(path) >= len(removedOneofPath) && slices.Equal(path[:len(removedOneofPath)], removedOneofPath)
+					},
+				)
+			},
+		)
+	}
+}
+
+// convertMessageDescriptorProto converts the message, and appends the paths of the removed
+// synthetic oneofs to removedOneofPaths.
+func convertMessageDescriptorProto(
+	messageDescriptorProto *descriptorpb.DescriptorProto,
+	syntax Syntax,
+	path []int32,
+	removedOneofPaths [][]int32,
+) [][]int32 {
+	// Synthetic oneofs are always declared after all other oneofs, so removing
+	// them does not change the indexes of other oneofs.
+	numOneofs := len(messageDescriptorProto.GetOneofDecl())
+	for _, fieldDescriptorProto := range messageDescriptorProto.GetField() {
+		if fieldDescriptorProto.GetProto3Optional() {
+			numOneofs = min(numOneofs, int(fieldDescriptorProto.GetOneofIndex()))
+			fieldDescriptorProto.OneofIndex = nil
+			fieldDescriptorProto.Proto3Optional = nil
+			// Message fields always have explicit presence, and cannot specify field presence.
+			if syntax == SyntaxEditions2023 && fieldDescriptorProto.GetType() != descriptorpb.FieldDescriptorProto_TYPE_MESSAGE {
+				fieldFeatures(fieldDescriptorProto).FieldPresence = descriptorpb.FeatureSet_EXPLICIT.Enum()
+			}
+			continue
+		}
+		convertFieldDescriptorProto(fieldDescriptorProto, syntax)
+	}
+	for i := numOneofs; i < len(messageDescriptorProto.GetOneofDecl()); i++ {
+		removedOneofPaths = append(removedOneofPaths, append(slices.Clone(path), messageOneofDeclFieldNumber, int32(i)))
+	}
+	messageDescriptorProto.OneofDecl = messageDescriptorProto.GetOneofDecl()[:numOneofs]
+	for _, fieldDescriptorProto := range messageDescriptorProto.GetExtension() {
+		convertFieldDescriptorProto(fieldDescriptorProto, syntax)
+	}
+	for i, nestedMessageDescriptorProto := range messageDescriptorProto.GetNestedType() {
+		removedOneofPaths = convertMessageDescriptorProto(
+			nestedMessageDescriptorProto,
+			syntax,
+			append(slices.Clone(path), messageNestedTypeFieldNumber, int32(i)),
+			removedOneofPaths,
+		)
+	}
+	return removedOneofPaths
+}
+
+func convertFieldDescriptorProto(fieldDescriptorProto *descriptorpb.FieldDescriptorProto, syntax Syntax) {
+	switch syntax {
+	case SyntaxProto2:
+		// Repeated scalar numeric fields are packed by default in proto3, but not in proto2.
+		if fieldDescriptorProto.GetLabel() == descriptorpb.FieldDescriptorProto_LABEL_REPEATED &&
+			isPackableType(fieldDescriptorProto.GetType()) &&
+			(fieldDescriptorProto.GetOptions() == nil || fieldDescriptorProto.GetOptions().Packed == nil) {
+			if fieldDescriptorProto.Options == nil {
+				fieldDescriptorProto.Options = &descriptorpb.FieldOptions{}
+			}
+			fieldDescriptorProto.Options.Packed = proto.Bool(true)
+		}
+	case SyntaxEditions2023:
+		// Message fields, fields within oneofs, and extensions always have explicit presence,
+		// regardless of the implicit presence of the file, and cannot specify field presence.
+		//
+		// Packed is not allowed in editions, and repeated scalar numeric fields are packed by default.
+		if fieldDescriptorProto.GetOptions() != nil && fieldDescriptorProto.GetOptions().Packed != nil {
+			packed := fieldDescriptorProto.GetOptions().GetPacked()
+			fieldDescriptorProto.Options.Packed = nil
+			if !packed {
+				fieldFeatures(fieldDescriptorProto).RepeatedFieldEncoding = descriptorpb.FeatureSet_EXPANDED.Enum()
+			}
+		}
+	}
+}
+
+// fieldFeatures returns the FeatureSet of the field, creating it if it does not exist.
+func fieldFeatures(fieldDescriptorProto *descriptorpb.FieldDescriptorProto) *descriptorpb.FeatureSet {
+	if fieldDescriptorProto.Options == nil {
+		fieldDescriptorProto.Options = &descriptorpb.FieldOptions{}
+	}
+	if fieldDescriptorProto.Options.Features == nil {
+		fieldDescriptorProto.Options.Features = &descriptorpb.FeatureSet{}
+	}
+	return fieldDescriptorProto.Options.Features
+}
+
+func isPackableType(fieldType descriptorpb.FieldDescriptorProto_Type) bool {
+	switch fieldType {
+	case descriptorpb.FieldDescriptorProto_TYPE_STRING,
+		descriptorpb.FieldDescriptorProto_TYPE_BYTES,
+		descriptorpb.FieldDescriptorProto_TYPE_MESSAGE,
+		descriptorpb.FieldDescriptorProto_TYPE_GROUP:
+		return false
+	default:
+		return true
+	}
+}
@@ -0,0 +1,221 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package descriptortest
+
+import (
+	"testing"
+
+	descriptorv1 "buf.build/gen/go/bufbuild/bufplugin/protocolbuffers/go/buf/plugin/descriptor/v1"
+	"buf.build/go/bufplugin/descriptor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+func TestConvertSyntax(t *testing.T) {
+	t.Parallel()
+
+	fileDescriptors, err := descriptor.FileDescriptorsForProtoFileDescriptors(
+		[]*descriptorv1.FileDescriptor{
+			{
+				FileDescriptorProto: newSyntaxTestFileDescriptorProto(),
+			},
+		},
+	)
+	require.NoError(t, err)
+	proto3Fields := fieldsForFileDescriptors(fileDescriptors)
+
+	for _, syntax := range AllSyntaxes {
+		syntax := syntax
+		t.Run(
+			syntax.String(),
+			func(t *testing.T) {
+				t.Parallel()
+				convertedFileDescriptors, err := ConvertSyntax(fileDescriptors, syntax)
+				require.NoError(t, err)
+				require.Len(t, convertedFileDescriptors, 1)
+				switch syntax {
+				case SyntaxProto2:
+					assert.Equal(t, protoreflect.Proto2, convertedFileDescriptors[0].ProtoreflectFileDescriptor().Syntax())
+				case SyntaxProto3:
+					assert.Equal(t, protoreflect.Proto3, convertedFileDescriptors[0].ProtoreflectFileDescriptor().Syntax())
+				case SyntaxEditions2023:
+					assert.Equal(t, protoreflect.Editions, convertedFileDescriptors[0].ProtoreflectFileDescriptor().Syntax())
+				}
+				// Presence and encoding are preserved, except that proto2 has no implicit presence.
+				convertedFields := fieldsForFileDescriptors(convertedFileDescriptors)
+				require.Len(t, convertedFields, len(proto3Fields))
+				for i, proto3Field := range proto3Fields {
+					convertedField := convertedFields[i]
+					assert.Equal(t, proto3Field.FullName(), convertedField.FullName())
+					assert.Equal(t, proto3Field.IsPacked(), convertedField.IsPacked(), convertedField.FullName())
+					if syntax == SyntaxProto2 {
+						assert.Equal(t, !convertedField.IsList(), convertedField.HasPresence(), convertedField.FullName())
+					} else {
+						assert.Equal(t, proto3Field.HasPresence(), convertedField.HasPresence(), convertedField.FullName())
+					}
+				}
+				// The synthetic oneof is removed.
+				messageDescriptor := convertedFileDescriptors[0].ProtoreflectFileDescriptor().Messages().Get(0)
+				if syntax == SyntaxProto3 {
+					assert.Equal(t, 3, messageDescriptor.Oneofs().Len())
+				} else {
+					assert.Equal(t, 1, messageDescriptor.Oneofs().Len())
+				}
+				// The input is not modified.
+				assert.Equal(t, "proto3", fileDescriptors[0].FileDescriptorProto().GetSyntax())
+			},
+		)
+	}
+}
+
+func TestConvertSyntaxCorpus(t *testing.T) {
+	t.Parallel()
+
+	fileDescriptors, err := NewCorpus(
+		&CorpusSpec{
+			NumFiles:             4,
+			NumMessagesPerFile:   3,
+			NumFieldsPerMessage:  6,
+			NumEnumsPerFile:      2,
+			NumValuesPerEnum:     3,
+			NumServicesPerFile:   1,
+			NumMethodsPerService: 2,
+			NumImportsPerFile:    2,
+		},
+	)
+	require.NoError(t, err)
+	for _, syntax := range AllSyntaxes {
+		convertedFileDescriptors, err := ConvertSyntax(fileDescriptors, syntax)
+		require.NoError(t, err, syntax.String())
+		require.Len(t, convertedFileDescriptors, len(fileDescriptors))
+		for i, convertedFileDescriptor := range convertedFileDescriptors {
+			assert.Equal(t, fileDescriptors[i].ProtoreflectFileDescriptor().Path(), convertedFileDescriptor.ProtoreflectFileDescriptor().Path())
+		}
+	}
+}
+
+func TestConvertSyntaxNotProto3(t *testing.T) {
+	t.Parallel()
+
+	fileDescriptorProto := newSyntaxTestFileDescriptorProto()
+	fileDescriptorProto.Syntax = proto.String("proto2")
+	for _, fieldDescriptorProto := range fileDescriptorProto.GetMessageType()[0].GetField() {
+		if fieldDescriptorProto.GetProto3Optional() {
+			fieldDescriptorProto.Proto3Optional = nil
+			fieldDescriptorProto.OneofIndex = nil
+		}
+	}
+	fileDescriptorProto.GetMessageType()[0].OneofDecl = fileDescriptorProto.GetMessageType()[0].GetOneofDecl()[:1]
+	fileDescriptors, err := descriptor.FileDescriptorsForProtoFileDescriptors(
+		[]*descriptorv1.FileDescriptor{
+			{
+				FileDescriptorProto: fileDescriptorProto,
+			},
+		},
+	)
+	require.NoError(t, err)
+	_, err = ConvertSyntax(fileDescriptors, SyntaxEditions2023)
+	require.Error(t, err)
+	_, err = ConvertSyntax(fileDescriptors, Syntax(0))
+	require.Error(t, err)
+}
+
+// newSyntaxTestFileDescriptorProto returns a proto3 file equivalent to:
+//
+//	syntax = "proto3";
+//	package syntax.v1;
+//	message Foo {
+//	  string name = 1;
+//	  optional int32 count = 2;
+//	  repeated int64 ids = 3;
+//	  repeated int64 unpacked_ids = 4 [packed = false];
+//	  Foo parent = 5;
+//	  optional Foo other = 6;
+//	  oneof value {
+//	    string text = 7;
+//	    Foo child = 8;
+//	  }
+//	}
+func newSyntaxTestFileDescriptorProto() *descriptorpb.FileDescriptorProto {
+	newField := func(
+		name string,
+		number int32,
+		label descriptorpb.FieldDescriptorProto_Label,
+		fieldType descriptorpb.FieldDescriptorProto_Type,
+	) *descriptorpb.FieldDescriptorProto {
+		fieldDescriptorProto := &descriptorpb.FieldDescriptorProto{
+			Name:     proto.String(name),
+			Number:   proto.Int32(number),
+			Label:    label.Enum(),
+			Type:     fieldType.Enum(),
+			JsonName: proto.String(name),
+		}
+		if fieldType == descriptorpb.FieldDescriptorProto_TYPE_MESSAGE {
+			fieldDescriptorProto.TypeName = proto.String(".syntax.v1.Foo")
+		}
+		return fieldDescriptorProto
+	}
+	optional := descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL
+	repeated := descriptorpb.FieldDescriptorProto_LABEL_REPEATED
+	name := newField("name", 1, optional, descriptorpb.FieldDescriptorProto_TYPE_STRING)
+	count := newField("count", 2, optional, descriptorpb.FieldDescriptorProto_TYPE_INT32)
+	count.Proto3Optional = proto.Bool(true)
+	count.OneofIndex = proto.Int32(1)
+	ids := newField("ids", 3, repeated, descriptorpb.FieldDescriptorProto_TYPE_INT64)
+	unpackedIDs := newField("unpacked_ids", 4, repeated, descriptorpb.FieldDescriptorProto_TYPE_INT64)
+	unpackedIDs.Options = &descriptorpb.FieldOptions{Packed: proto.Bool(false)}
+	parent := newField("parent", 5, optional, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE)
+	other := newField("other", 6, optional, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE)
+	other.Proto3Optional = proto.Bool(true)
+	other.OneofIndex = proto.Int32(2)
+	text := newField("text", 7, optional, descriptorpb.FieldDescriptorProto_TYPE_STRING)
+	text.OneofIndex = proto.Int32(0)
+	child := newField("child", 8, optional, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE)
+	child.OneofIndex = proto.Int32(0)
+	return &descriptorpb.FileDescriptorProto{
+		Name:    proto.String("syntax/v1/syntax.proto"),
+		Package: proto.String("syntax.v1"),
+		Syntax:  proto.String("proto3"),
+		MessageType: []*descriptorpb.DescriptorProto{
+			{
+				Name: proto.String("Foo"),
+				// Fields within a oneof must be declared consecutively.
+				Field: []*descriptorpb.FieldDescriptorProto{name, text, child, count, ids, unpackedIDs, parent, other},
+				OneofDecl: []*descriptorpb.OneofDescriptorProto{
+					{Name: proto.String("value")},
+					{Name: proto.String("_count")},
+					{Name: proto.String("_other")},
+				},
+			},
+		},
+	}
+}
+
+func fieldsForFileDescriptors(fileDescriptors []descriptor.FileDescriptor) []protoreflect.FieldDescriptor {
+	var fieldDescriptors []protoreflect.FieldDescriptor
+	for _, fileDescriptor := range fileDescriptors {
+		messageDescriptors := fileDescriptor.ProtoreflectFileDescriptor().Messages()
+		for i := 0; i < messageDescriptors.Len(); i++ {
+			fields := messageDescriptors.Get(i).Fields()
+			for j := 0; j < fields.Len(); j++ {
+				fieldDescriptors = append(fieldDescriptors, fields.Get(j))
+			}
+		}
+	}
+	return fieldDescriptors
+}
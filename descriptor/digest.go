@@ -0,0 +1,128 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package descriptor
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// DigestOption is an option for FileDescriptor.Digest.
+type DigestOption func(*digestOptions)
+
+// DigestWithSourceCodeInfo returns a new DigestOption that will result in the SourceCodeInfo
+// of the FileDescriptorProto being included in the digest.
+//
+// By default, SourceCodeInfo is excluded, so that changes to comments and formatting do not
+// change the digest.
+func DigestWithSourceCodeInfo() DigestOption {
+	return func(digestOptions *digestOptions) {
+		digestOptions.withSourceCodeInfo = true
+	}
+}
+
+// Digest returns a stable digest of the FileDescriptors.
+//
+// The digest is the hex-encoded SHA-256 hash of the path, Digest, and the IsImport,
+// IsSyntaxUnspecified, and UnusedDependencyIndexes properties of each FileDescriptor, sorted by
+// path. The digest is therefore independent of the order of the FileDescriptors, and two sets of
+// FileDescriptors that would produce the same results from a plugin have the same digest. This
+// is suitable as a cache key, for example by clients that cache responses or only check files
+// that changed. Digests are stable across runs, but may change between versions of this library.
+//
+// The options are applied to the Digest of each FileDescriptor.
+func Digest(fileDescriptors []FileDescriptor, options ...DigestOption) string {
+	sortedFileDescriptors := slices.Clone(fileDescriptors)
+	slices.SortFunc(
+		sortedFileDescriptors,
+		func(one FileDescriptor, two FileDescriptor) int {
+			return strings.Compare(one.FileDescriptorProto().GetName(), two.FileDescriptorProto().GetName())
+		},
+	)
+	hash := sha256.New()
+	for _, fileDescriptor := range sortedFileDescriptors {
+		_, _ = fmt.Fprintf(
+			hash,
+			"%q %s %t %t %v\n",
+			fileDescriptor.FileDescriptorProto().GetName(),
+			fileDescriptor.Digest(options...),
+			fileDescriptor.IsImport(),
+			fileDescriptor.IsSyntaxUnspecified(),
+			fileDescriptor.UnusedDependencyIndexes(),
+		)
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// *** PRIVATE ***
+
+var fileDescriptorProtoSourceCodeInfoFieldNumber = (&descriptorpb.FileDescriptorProto{}).
+	ProtoReflect().
+	Descriptor().
+	Fields().
+	ByName("source_code_info").
+	Number()
+
+type digestOptions struct {
+	withSourceCodeInfo bool
+}
+
+func newDigestOptions() *digestOptions {
+	return &digestOptions{}
+}
+
+// digestForFileDescriptorProto returns the hex-encoded SHA-256 digest of the deterministic
+// binary serialization of the FileDescriptorProto.
+func digestForFileDescriptorProto(fileDescriptorProto *descriptorpb.FileDescriptorProto, withSourceCodeInfo bool) string {
+	if !withSourceCodeInfo && fileDescriptorProto.GetSourceCodeInfo() != nil {
+		fileDescriptorProto = fileDescriptorProtoWithoutSourceCodeInfo(fileDescriptorProto)
+	}
+	data, err := proto.MarshalOptions{Deterministic: true}.Marshal(fileDescriptorProto)
+	if err != nil {
+		// A FileDescriptorProto that was used to construct a protoreflect.FileDescriptor can
+		// always be marshaled. Fall back to hashing the text representation to stay total.
+		data = []byte(fileDescriptorProto.String())
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// fileDescriptorProtoWithoutSourceCodeInfo returns a shallow copy of the FileDescriptorProto
+// without SourceCodeInfo.
+//
+// The FileDescriptorProto is shared and must not be modified, and a deep clone would copy
+// the SourceCodeInfo, which is usually the largest part of the FileDescriptorProto.
+func fileDescriptorProtoWithoutSourceCodeInfo(fileDescriptorProto *descriptorpb.FileDescriptorProto) *descriptorpb.FileDescriptorProto {
+	src := fileDescriptorProto.ProtoReflect()
+	dst := &descriptorpb.FileDescriptorProto{}
+	dstReflect := dst.ProtoReflect()
+	src.Range(
+		func(fieldDescriptor protoreflect.FieldDescriptor, value protoreflect.Value) bool {
+			if fieldDescriptor.Number() != fileDescriptorProtoSourceCodeInfoFieldNumber {
+				dstReflect.Set(fieldDescriptor, value)
+			}
+			return true
+		},
+	)
+	dstReflect.SetUnknown(src.GetUnknown())
+	return dst
+}
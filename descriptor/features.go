@@ -0,0 +1,191 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package descriptor
+
+import (
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// *** PRIVATE ***
+
+var featureSetMessageDescriptor = (&descriptorpb.FeatureSet{}).ProtoReflect().Descriptor()
+
+// editionForFileDescriptorProto returns the Edition of the file.
+//
+// Files that use proto2 or proto3 syntax have the editions EDITION_PROTO2 and EDITION_PROTO3.
+// This only uses the FileDescriptorProto, so that the file does not need to be linked.
+func editionForFileDescriptorProto(fileDescriptorProto *descriptorpb.FileDescriptorProto) descriptorpb.Edition {
+	switch fileDescriptorProto.GetSyntax() {
+	case "editions":
+		return fileDescriptorProto.GetEdition()
+	case "proto3":
+		return descriptorpb.Edition_EDITION_PROTO3
+	default:
+		return descriptorpb.Edition_EDITION_PROTO2
+	}
+}
+
+// defaultFeaturesForEdition returns the default features for the Edition.
+//
+// The defaults are read from the edition_defaults options of the fields of
+// google.protobuf.FeatureSet. For each field, the default of the latest edition that is not
+// after the given Edition is used.
+func defaultFeaturesForEdition(edition descriptorpb.Edition) *descriptorpb.FeatureSet {
+	featureSet := &descriptorpb.FeatureSet{}
+	message := featureSet.ProtoReflect()
+	fields := featureSetMessageDescriptor.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		field := fields.Get(i)
+		fieldOptions, ok := field.Options().(*descriptorpb.FieldOptions)
+		if !ok {
+			continue
+		}
+		var defaultValue string
+		defaultEdition := descriptorpb.Edition_EDITION_UNKNOWN
+		for _, editionDefault := range fieldOptions.GetEditionDefaults() {
+			if editionDefault.GetEdition() <= edition && editionDefault.GetEdition() >= defaultEdition {
+				defaultEdition = editionDefault.GetEdition()
+				defaultValue = editionDefault.GetValue()
+			}
+		}
+		if defaultEdition == descriptorpb.Edition_EDITION_UNKNOWN {
+			continue
+		}
+		if value, ok := featureValueForString(field, defaultValue); ok {
+			message.Set(field, value)
+		}
+	}
+	return featureSet
+}
+
+// featureValueForString parses the value of an edition default.
+//
+// All features of google.protobuf.FeatureSet are enums, and bools are handled for completeness.
+func featureValueForString(field protoreflect.FieldDescriptor, value string) (protoreflect.Value, bool) {
+	switch field.Kind() {
+	case protoreflect.EnumKind:
+		enumValue := field.Enum().Values().ByName(protoreflect.Name(value))
+		if enumValue == nil {
+			return protoreflect.Value{}, false
+		}
+		return protoreflect.ValueOfEnum(enumValue.Number()), true
+	case protoreflect.BoolKind:
+		switch value {
+		case "true":
+			return protoreflect.ValueOfBool(true), true
+		case "false":
+			return protoreflect.ValueOfBool(false), true
+		}
+	}
+	return protoreflect.Value{}, false
+}
+
+// resolveFeatures returns the resolved features for the Descriptor.
+//
+// The features of each ancestor of the Descriptor, starting at the file, are merged on top
+// of the default features of the edition, and then the features of the Descriptor itself.
+// Fields within a non-synthetic oneof inherit the features of the oneof.
+//
+// For files that use proto2 or proto3 syntax, features cannot be set explicitly. Instead,
+// the features implied by required and group fields, proto3 optional fields, and the packed
+// option are applied, matching how protoc converts these files to editions.
+func resolveFeatures(
+	fileFeatures *descriptorpb.FeatureSet,
+	edition descriptorpb.Edition,
+	descriptor protoreflect.Descriptor,
+) *descriptorpb.FeatureSet {
+	var ancestors []protoreflect.Descriptor
+	for current := descriptor; current != nil; current = featureParent(current) {
+		if _, ok := current.(protoreflect.FileDescriptor); ok {
+			break
+		}
+		ancestors = append(ancestors, current)
+	}
+	resolvedFeatures := &descriptorpb.FeatureSet{}
+	proto.Merge(resolvedFeatures, fileFeatures)
+	for i := len(ancestors) - 1; i >= 0; i-- {
+		if featureSet := explicitFeaturesForDescriptor(ancestors[i]); featureSet != nil {
+			proto.Merge(resolvedFeatures, featureSet)
+		}
+	}
+	if edition == descriptorpb.Edition_EDITION_PROTO2 || edition == descriptorpb.Edition_EDITION_PROTO3 {
+		if fieldDescriptor, ok := descriptor.(protoreflect.FieldDescriptor); ok {
+			applyLegacyFieldFeatures(resolvedFeatures, fieldDescriptor)
+		}
+	}
+	return resolvedFeatures
+}
+
+// featureParent returns the Descriptor that the Descriptor inherits features from.
+func featureParent(descriptor protoreflect.Descriptor) protoreflect.Descriptor {
+	if fieldDescriptor, ok := descriptor.(protoreflect.FieldDescriptor); ok {
+		if oneofDescriptor := fieldDescriptor.ContainingOneof(); oneofDescriptor != nil && !IsSyntheticOneof(oneofDescriptor) {
+			return oneofDescriptor
+		}
+	}
+	return descriptor.Parent()
+}
+
+// explicitFeaturesForDescriptor returns the features set within the options of the Descriptor,
+// if any.
+func explicitFeaturesForDescriptor(descriptor protoreflect.Descriptor) *descriptorpb.FeatureSet {
+	options := descriptor.Options()
+	if options == nil {
+		return nil
+	}
+	message := options.ProtoReflect()
+	if !message.IsValid() {
+		return nil
+	}
+	featuresField := message.Descriptor().Fields().ByName("features")
+	if featuresField == nil || !message.Has(featuresField) {
+		return nil
+	}
+	featuresMessage := message.Get(featuresField).Message().Interface()
+	if featureSet, ok := featuresMessage.(*descriptorpb.FeatureSet); ok {
+		return featureSet
+	}
+	// The options were not built with descriptorpb, for example with dynamicpb.
+	data, err := proto.Marshal(featuresMessage)
+	if err != nil {
+		return nil
+	}
+	featureSet := &descriptorpb.FeatureSet{}
+	if err := proto.Unmarshal(data, featureSet); err != nil {
+		return nil
+	}
+	return featureSet
+}
+
+func applyLegacyFieldFeatures(featureSet *descriptorpb.FeatureSet, fieldDescriptor protoreflect.FieldDescriptor) {
+	switch {
+	case fieldDescriptor.Cardinality() == protoreflect.Required:
+		featureSet.FieldPresence = descriptorpb.FeatureSet_LEGACY_REQUIRED.Enum()
+	case fieldDescriptor.HasOptionalKeyword():
+		featureSet.FieldPresence = descriptorpb.FeatureSet_EXPLICIT.Enum()
+	}
+	if fieldDescriptor.Kind() == protoreflect.GroupKind {
+		featureSet.MessageEncoding = descriptorpb.FeatureSet_DELIMITED.Enum()
+	}
+	if fieldOptions, ok := fieldDescriptor.Options().(*descriptorpb.FieldOptions); ok && fieldOptions != nil && fieldOptions.Packed != nil {
+		if fieldOptions.GetPacked() {
+			featureSet.RepeatedFieldEncoding = descriptorpb.FeatureSet_PACKED.Enum()
+		} else {
+			featureSet.RepeatedFieldEncoding = descriptorpb.FeatureSet_EXPANDED.Enum()
+		}
+	}
+}
@@ -0,0 +1,369 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package descriptor_test
+
+import (
+	"context"
+	"testing"
+
+	"buf.build/go/bufplugin/descriptor/descriptortest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+func TestResolvedFeaturesFor(t *testing.T) {
+	t.Parallel()
+
+	fileDescriptors, err := descriptortest.CompileStrings(
+		context.Background(),
+		map[string]string{
+			"a.proto": `syntax = "proto2";
+package a;
+message Foo {
+  optional string field = 1;
+  required string required_field = 2;
+  optional group Group = 3 {
+    optional string group_field = 1;
+  }
+  repeated int32 packed_field = 4 [packed = true];
+  repeated int32 expanded_field = 5;
+}
+enum Enum {
+  ENUM_VALUE = 0;
+}
+`,
+			"b.proto": `syntax = "proto3";
+package b;
+message Foo {
+  string field = 1;
+  optional string optional_field = 2;
+  repeated int32 packed_field = 3;
+  repeated int32 expanded_field = 4 [packed = false];
+}
+`,
+			"c.proto": `edition = "2023";
+package c;
+option features.field_presence = IMPLICIT;
+message Foo {
+  option features.json_format = LEGACY_BEST_EFFORT;
+  string field = 1;
+  string explicit_field = 2 [features.field_presence = EXPLICIT];
+  oneof choice {
+    string choice_field = 3;
+  }
+  repeated int32 expanded_field = 4 [features.repeated_field_encoding = EXPANDED];
+  message Nested {
+    string nested_field = 1;
+  }
+  enum NestedEnum {
+    option features.enum_type = CLOSED;
+    NESTED_ENUM_VALUE = 0;
+  }
+}
+enum Enum {
+  ENUM_VALUE = 0;
+}
+`,
+		},
+	)
+	require.NoError(t, err)
+	require.Len(t, fileDescriptors, 3)
+
+	proto2Features := testNewFeatureSet(
+		descriptorpb.FeatureSet_EXPLICIT,
+		descriptorpb.FeatureSet_CLOSED,
+		descriptorpb.FeatureSet_EXPANDED,
+		descriptorpb.FeatureSet_LENGTH_PREFIXED,
+		descriptorpb.FeatureSet_LEGACY_BEST_EFFORT,
+	)
+	proto3Features := testNewFeatureSet(
+		descriptorpb.FeatureSet_IMPLICIT,
+		descriptorpb.FeatureSet_OPEN,
+		descriptorpb.FeatureSet_PACKED,
+		descriptorpb.FeatureSet_LENGTH_PREFIXED,
+		descriptorpb.FeatureSet_ALLOW,
+	)
+	editionFileFeatures := testNewFeatureSet(
+		descriptorpb.FeatureSet_IMPLICIT,
+		descriptorpb.FeatureSet_OPEN,
+		descriptorpb.FeatureSet_PACKED,
+		descriptorpb.FeatureSet_LENGTH_PREFIXED,
+		descriptorpb.FeatureSet_ALLOW,
+	)
+	editionMessageFeatures := testNewFeatureSet(
+		descriptorpb.FeatureSet_IMPLICIT,
+		descriptorpb.FeatureSet_OPEN,
+		descriptorpb.FeatureSet_PACKED,
+		descriptorpb.FeatureSet_LENGTH_PREFIXED,
+		descriptorpb.FeatureSet_LEGACY_BEST_EFFORT,
+	)
+
+	testCases := []struct {
+		name             string
+		fileIndex        int
+		fullName         protoreflect.FullName
+		expectedFeatures *descriptorpb.FeatureSet
+	}{
+		{
+			name:             "proto2_message",
+			fileIndex:        0,
+			fullName:         "a.Foo",
+			expectedFeatures: proto2Features,
+		},
+		{
+			name:             "proto2_optional_field",
+			fileIndex:        0,
+			fullName:         "a.Foo.field",
+			expectedFeatures: proto2Features,
+		},
+		{
+			name:      "proto2_required_field",
+			fileIndex: 0,
+			fullName:  "a.Foo.required_field",
+			expectedFeatures: testWithFeatures(proto2Features, func(featureSet *descriptorpb.FeatureSet) {
+				featureSet.FieldPresence = descriptorpb.FeatureSet_LEGACY_REQUIRED.Enum()
+			}),
+		},
+		{
+			name:      "proto2_group_field",
+			fileIndex: 0,
+			fullName:  "a.Foo.group",
+			expectedFeatures: testWithFeatures(proto2Features, func(featureSet *descriptorpb.FeatureSet) {
+				featureSet.MessageEncoding = descriptorpb.FeatureSet_DELIMITED.Enum()
+			}),
+		},
+		{
+			name:      "proto2_packed_field",
+			fileIndex: 0,
+			fullName:  "a.Foo.packed_field",
+			expectedFeatures: testWithFeatures(proto2Features, func(featureSet *descriptorpb.FeatureSet) {
+				featureSet.RepeatedFieldEncoding = descriptorpb.FeatureSet_PACKED.Enum()
+			}),
+		},
+		{
+			name:             "proto2_expanded_field",
+			fileIndex:        0,
+			fullName:         "a.Foo.expanded_field",
+			expectedFeatures: proto2Features,
+		},
+		{
+			name:             "proto2_enum",
+			fileIndex:        0,
+			fullName:         "a.Enum",
+			expectedFeatures: proto2Features,
+		},
+		{
+			name:             "proto3_field",
+			fileIndex:        1,
+			fullName:         "b.Foo.field",
+			expectedFeatures: proto3Features,
+		},
+		{
+			name:      "proto3_optional_field",
+			fileIndex: 1,
+			fullName:  "b.Foo.optional_field",
+			expectedFeatures: testWithFeatures(proto3Features, func(featureSet *descriptorpb.FeatureSet) {
+				featureSet.FieldPresence = descriptorpb.FeatureSet_EXPLICIT.Enum()
+			}),
+		},
+		{
+			name:             "proto3_packed_field",
+			fileIndex:        1,
+			fullName:         "b.Foo.packed_field",
+			expectedFeatures: proto3Features,
+		},
+		{
+			name:      "proto3_expanded_field",
+			fileIndex: 1,
+			fullName:  "b.Foo.expanded_field",
+			expectedFeatures: testWithFeatures(proto3Features, func(featureSet *descriptorpb.FeatureSet) {
+				featureSet.RepeatedFieldEncoding = descriptorpb.FeatureSet_EXPANDED.Enum()
+			}),
+		},
+		{
+			name:             "edition_message",
+			fileIndex:        2,
+			fullName:         "c.Foo",
+			expectedFeatures: editionMessageFeatures,
+		},
+		{
+			name:             "edition_field_inherits_file_and_message",
+			fileIndex:        2,
+			fullName:         "c.Foo.field",
+			expectedFeatures: editionMessageFeatures,
+		},
+		{
+			name:      "edition_field_override",
+			fileIndex: 2,
+			fullName:  "c.Foo.explicit_field",
+			expectedFeatures: testWithFeatures(editionMessageFeatures, func(featureSet *descriptorpb.FeatureSet) {
+				featureSet.FieldPresence = descriptorpb.FeatureSet_EXPLICIT.Enum()
+			}),
+		},
+		{
+			name:             "edition_oneof",
+			fileIndex:        2,
+			fullName:         "c.Foo.choice",
+			expectedFeatures: editionMessageFeatures,
+		},
+		{
+			name:             "edition_oneof_field",
+			fileIndex:        2,
+			fullName:         "c.Foo.choice_field",
+			expectedFeatures: editionMessageFeatures,
+		},
+		{
+			name:      "edition_repeated_field_override",
+			fileIndex: 2,
+			fullName:  "c.Foo.expanded_field",
+			expectedFeatures: testWithFeatures(editionMessageFeatures, func(featureSet *descriptorpb.FeatureSet) {
+				featureSet.RepeatedFieldEncoding = descriptorpb.FeatureSet_EXPANDED.Enum()
+			}),
+		},
+		{
+			name:             "edition_nested_message_field",
+			fileIndex:        2,
+			fullName:         "c.Foo.Nested.nested_field",
+			expectedFeatures: editionMessageFeatures,
+		},
+		{
+			name:      "edition_nested_enum_override",
+			fileIndex: 2,
+			fullName:  "c.Foo.NestedEnum",
+			expectedFeatures: testWithFeatures(editionMessageFeatures, func(featureSet *descriptorpb.FeatureSet) {
+				featureSet.EnumType = descriptorpb.FeatureSet_CLOSED.Enum()
+			}),
+		},
+		{
+			name:      "edition_nested_enum_value",
+			fileIndex: 2,
+			fullName:  "c.Foo.NESTED_ENUM_VALUE",
+			expectedFeatures: testWithFeatures(editionMessageFeatures, func(featureSet *descriptorpb.FeatureSet) {
+				featureSet.EnumType = descriptorpb.FeatureSet_CLOSED.Enum()
+			}),
+		},
+		{
+			name:             "edition_enum",
+			fileIndex:        2,
+			fullName:         "c.Enum",
+			expectedFeatures: editionFileFeatures,
+		},
+	}
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+			fileDescriptor := fileDescriptors[testCase.fileIndex]
+			protoreflectDescriptor := testFindDescriptor(t, fileDescriptor.ProtoreflectFileDescriptor(), testCase.fullName)
+			assertFeatureSetsEqual(t, testCase.expectedFeatures, fileDescriptor.ResolvedFeaturesFor(protoreflectDescriptor))
+		})
+	}
+}
+
+func TestResolvedFeaturesForFile(t *testing.T) {
+	t.Parallel()
+
+	fileDescriptors, err := descriptortest.CompileStrings(
+		context.Background(),
+		map[string]string{
+			"a.proto": `edition = "2023";
+package a;
+option features.field_presence = IMPLICIT;
+message Foo {}
+`,
+			"b.proto": `syntax = "proto3";
+package b;
+message Foo {}
+`,
+		},
+	)
+	require.NoError(t, err)
+	require.Len(t, fileDescriptors, 2)
+	fileDescriptor := fileDescriptors[0]
+	expectedFeatures := testNewFeatureSet(
+		descriptorpb.FeatureSet_IMPLICIT,
+		descriptorpb.FeatureSet_OPEN,
+		descriptorpb.FeatureSet_PACKED,
+		descriptorpb.FeatureSet_LENGTH_PREFIXED,
+		descriptorpb.FeatureSet_ALLOW,
+	)
+	assertFeatureSetsEqual(t, expectedFeatures, fileDescriptor.ResolvedFeatures())
+	assertFeatureSetsEqual(t, expectedFeatures, fileDescriptor.ResolvedFeaturesFor(nil))
+	assertFeatureSetsEqual(
+		t,
+		expectedFeatures,
+		fileDescriptor.ResolvedFeaturesFor(fileDescriptor.ProtoreflectFileDescriptor()),
+	)
+	// Descriptors within other files have no resolved features within the file.
+	assert.Nil(t, fileDescriptor.ResolvedFeaturesFor(fileDescriptors[1].ProtoreflectFileDescriptor()))
+	assert.Nil(
+		t,
+		fileDescriptor.ResolvedFeaturesFor(fileDescriptors[1].ProtoreflectFileDescriptor().Messages().ByName("Foo")),
+	)
+
+	// The returned FeatureSet is a copy.
+	fileDescriptor.ResolvedFeaturesFor(nil).FieldPresence = descriptorpb.FeatureSet_EXPLICIT.Enum()
+	assertFeatureSetsEqual(t, expectedFeatures, fileDescriptor.ResolvedFeaturesFor(nil))
+}
+
+func testNewFeatureSet(
+	fieldPresence descriptorpb.FeatureSet_FieldPresence,
+	enumType descriptorpb.FeatureSet_EnumType,
+	repeatedFieldEncoding descriptorpb.FeatureSet_RepeatedFieldEncoding,
+	messageEncoding descriptorpb.FeatureSet_MessageEncoding,
+	jsonFormat descriptorpb.FeatureSet_JsonFormat,
+) *descriptorpb.FeatureSet {
+	return &descriptorpb.FeatureSet{
+		FieldPresence:         fieldPresence.Enum(),
+		EnumType:              enumType.Enum(),
+		RepeatedFieldEncoding: repeatedFieldEncoding.Enum(),
+		MessageEncoding:       messageEncoding.Enum(),
+		JsonFormat:            jsonFormat.Enum(),
+	}
+}
+
+func testWithFeatures(featureSet *descriptorpb.FeatureSet, modify func(*descriptorpb.FeatureSet)) *descriptorpb.FeatureSet {
+	clone, _ := proto.Clone(featureSet).(*descriptorpb.FeatureSet)
+	modify(clone)
+	return clone
+}
+
+// assertFeatureSetsEqual asserts that the features within expectedFeatures are equal to the
+// same features within actualFeatures.
+//
+// Only the features set within expectedFeatures are compared, so that the tests do not need to
+// change when features are added to new editions.
+func assertFeatureSetsEqual(t *testing.T, expectedFeatures *descriptorpb.FeatureSet, actualFeatures *descriptorpb.FeatureSet) {
+	t.Helper()
+	require.NotNil(t, actualFeatures)
+	actualSubset := &descriptorpb.FeatureSet{}
+	expectedFeatures.ProtoReflect().Range(func(field protoreflect.FieldDescriptor, _ protoreflect.Value) bool {
+		actualMessage := actualFeatures.ProtoReflect()
+		if actualMessage.Has(field) {
+			actualSubset.ProtoReflect().Set(field, actualMessage.Get(field))
+		}
+		return true
+	})
+	assert.True(
+		t,
+		proto.Equal(expectedFeatures, actualSubset),
+		"expected %v, got %v",
+		expectedFeatures,
+		actualSubset,
+	)
+}
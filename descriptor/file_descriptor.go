@@ -15,12 +15,15 @@
 package descriptor
 
 import (
+	"errors"
 	"fmt"
 	"slices"
+	"sync"
 
 	descriptorv1 "buf.build/gen/go/bufbuild/bufplugin/protocolbuffers/go/buf/plugin/descriptor/v1"
-	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
 	"google.golang.org/protobuf/types/descriptorpb"
 )
 
@@ -31,8 +34,19 @@ import (
 type FileDescriptor interface {
 	// ProtoreflectFileDescriptor returns the protoreflect.FileDescriptor representing this FileDescriptor.
 	//
-	// This will always contain SourceCodeInfo.
+	// This will always contain SourceCodeInfo. The SourceCodeInfo of imports is empty if
+	// FileDescriptorsWithoutImportSourceCodeInfo was used, in which case there are no source
+	// locations or comments for any descriptors within imports.
+	//
+	// This is never nil, unless FileDescriptorsWithLazyLinking was used and the file or one of
+	// its imports does not link, in which case Link returns the error.
 	ProtoreflectFileDescriptor() protoreflect.FileDescriptor
+	// Link links the protoreflect.FileDescriptor if it has not yet been linked, and returns
+	// the error from linking the file or any of its imports, if any.
+	//
+	// This always returns nil unless FileDescriptorsWithLazyLinking was used, as otherwise
+	// FileDescriptorsForProtoFileDescriptors returns an error for any file that does not link.
+	Link() error
 
 	// FileDescriptorProto returns the FileDescriptorProto representing this File.
 	//
@@ -63,6 +77,73 @@ type FileDescriptor interface {
 	//
 	// This matches the shape of the PublicDependency and WeakDependency fields.
 	UnusedDependencyIndexes() []int32
+	// UnusedDependencyPaths are the paths of the dependencies that are not used.
+	//
+	// This is UnusedDependencyIndexes resolved against the Dependency field on
+	// FileDescriptorProto, in the same order. Indexes that are out of range are ignored.
+	UnusedDependencyPaths() []string
+
+	// Digest returns a stable digest of the FileDescriptorProto.
+	//
+	// The digest is the hex-encoded SHA-256 hash of the deterministic binary serialization of
+	// the FileDescriptorProto. This can be used to cheaply detect changes to a file between runs,
+	// for example by caching layers. Digests are stable across runs, but may change between
+	// versions of the Protobuf runtime.
+	//
+	// By default, SourceCodeInfo is excluded from the digest. Use DigestWithSourceCodeInfo to
+	// include it. Digests are computed once and then cached.
+	Digest(options ...DigestOption) string
+
+	// CommentsFor returns the Comments for the given Descriptor within the FileDescriptor.
+	//
+	// The returned Comments are never nil. If the Descriptor is nil, has no source location,
+	// or is not within the FileDescriptor, all comments are empty. This avoids navigating
+	// SourceLocations directly.
+	CommentsFor(descriptor protoreflect.Descriptor) Comments
+
+	// Edition returns the Edition of the file.
+	//
+	// Files that use proto2 or proto3 syntax, including files that do not specify a syntax,
+	// return EDITION_PROTO2 and EDITION_PROTO3. This allows edition-aware checks to compare
+	// editions without special-casing syntax.
+	Edition() descriptorpb.Edition
+	// ResolvedFeatures returns the resolved features of the file.
+	//
+	// These are the default features of the Edition, with the features set within the
+	// options of the file merged on top. All fields of google.protobuf.FeatureSet are set.
+	// The returned FeatureSet is a copy, and may be modified.
+	ResolvedFeatures() *descriptorpb.FeatureSet
+	// ResolvedFeaturesFor returns the resolved features of the given Descriptor within the
+	// FileDescriptor.
+	//
+	// Features are inherited from the file and each enclosing Descriptor, and fields within
+	// a oneof inherit the features of the oneof. For files that use proto2 or proto3 syntax, the
+	// features implied by required, group, proto3 optional, and packed fields are applied.
+	//
+	// Returns the result of ResolvedFeatures if the Descriptor is nil or the file itself, and
+	// nil if the Descriptor is not within the FileDescriptor. The returned FeatureSet is a copy,
+	// and may be modified.
+	ResolvedFeaturesFor(descriptor protoreflect.Descriptor) *descriptorpb.FeatureSet
+
+	// SourceOptionsFor returns the options of the given Descriptor within the FileDescriptor
+	// as they appeared in source.
+	//
+	// This includes options with retention = RETENTION_SOURCE. Custom options that are declared
+	// within the file or its imports are resolved as extensions, so that they can be inspected
+	// via protoreflect instead of remaining as unknown fields. The returned message is of the
+	// options type of the Descriptor, for example *descriptorpb.FieldOptions, and is a copy.
+	//
+	// Returns an error if the Descriptor is not within the FileDescriptor.
+	SourceOptionsFor(descriptor protoreflect.Descriptor) (protoreflect.ProtoMessage, error)
+	// RuntimeOptionsFor returns the options of the given Descriptor within the FileDescriptor
+	// as they would be retained at runtime.
+	//
+	// This is the result of SourceOptionsFor, with all options with retention = RETENTION_SOURCE
+	// removed, including fields nested within message options. This matches what protoc
+	// retains within generated code.
+	//
+	// Returns an error if the Descriptor is not within the FileDescriptor.
+	RuntimeOptionsFor(descriptor protoreflect.Descriptor) (protoreflect.ProtoMessage, error)
 
 	// ToProto converts the FileDescriptor to its Protobuf representation.
 	ToProto() *descriptorv1.FileDescriptor
@@ -70,95 +151,207 @@ type FileDescriptor interface {
 	isFileDescriptor()
 }
 
+// FileDescriptorsOption is an option for FileDescriptorsForProtoFileDescriptors.
+type FileDescriptorsOption func(*fileDescriptorsOptions)
+
+// FileDescriptorsWithoutImportSourceCodeInfo returns a new FileDescriptorsOption that drops the
+// SourceCodeInfo of all files that are imports.
+//
+// SourceCodeInfo can be the majority of the memory used by large dependency trees, and is
+// rarely needed for imports. The SourceCodeInfo of imports is replaced with an empty
+// SourceCodeInfo, so there are no source locations or comments for any imports, and
+// FileLocations within imports have no line or column information. The given
+// descriptorv1.FileDescriptors are not modified.
+func FileDescriptorsWithoutImportSourceCodeInfo() FileDescriptorsOption {
+	return func(fileDescriptorsOptions *fileDescriptorsOptions) {
+		fileDescriptorsOptions.withoutImportSourceCodeInfo = true
+	}
+}
+
+// FileDescriptorsWithLazyLinking returns a new FileDescriptorsOption that links each file on
+// first use instead of upfront.
+//
+// Each file is linked on the first call to ProtoreflectFileDescriptor or Link, or to any other
+// method that requires the protoreflect.FileDescriptor, on the file or a file that imports it.
+// This avoids the cost of linking for plugins that only use FileDescriptorProtos. Only the names,
+// dependencies, and import cycles of the files are validated upfront, and the errors from
+// linking, for example for references that do not resolve, are returned from Link. If a file
+// or one of its imports does not link, ProtoreflectFileDescriptor returns nil.
+func FileDescriptorsWithLazyLinking() FileDescriptorsOption {
+	return func(fileDescriptorsOptions *fileDescriptorsOptions) {
+		fileDescriptorsOptions.lazyLinking = true
+	}
+}
+
 // FileDescriptorsForProtoFileDescriptors returns a new slice of FileDescriptors for the given descriptorv1.FileDescriptorDescriptors.
-func FileDescriptorsForProtoFileDescriptors(protoFileDescriptors []*descriptorv1.FileDescriptor) ([]FileDescriptor, error) {
+//
+// The FileDescriptors are in the same order as the given descriptorv1.FileDescriptors.
+//
+// Returns an error if any file has an empty or duplicate name, a missing import, or an import
+// cycle, or does not link. See FileDescriptorsWithLazyLinking to defer linking until first use.
+func FileDescriptorsForProtoFileDescriptors(
+	protoFileDescriptors []*descriptorv1.FileDescriptor,
+	options ...FileDescriptorsOption,
+) ([]FileDescriptor, error) {
 	if len(protoFileDescriptors) == 0 {
 		return nil, nil
 	}
-	fileNameToProtoFileDescriptor := make(map[string]*descriptorv1.FileDescriptor, len(protoFileDescriptors))
+	fileDescriptorsOptions := newFileDescriptorsOptions()
+	for _, option := range options {
+		option(fileDescriptorsOptions)
+	}
+	if fileDescriptorsOptions.withoutImportSourceCodeInfo {
+		protoFileDescriptors = protoFileDescriptorsWithoutImportSourceCodeInfo(protoFileDescriptors)
+	}
 	fileDescriptorProtos := make([]*descriptorpb.FileDescriptorProto, len(protoFileDescriptors))
 	for i, protoFileDescriptor := range protoFileDescriptors {
-		fileDescriptorProto := protoFileDescriptor.GetFileDescriptorProto()
-		fileName := fileDescriptorProto.GetName()
-		if _, ok := fileNameToProtoFileDescriptor[fileName]; ok {
-			//  This should have been validated via protovalidate.
-			return nil, fmt.Errorf("duplicate file name: %q", fileName)
-		}
-		fileDescriptorProtos[i] = fileDescriptorProto
-		fileNameToProtoFileDescriptor[fileName] = protoFileDescriptor
+		fileDescriptorProtos[i] = protoFileDescriptor.GetFileDescriptorProto()
 	}
-
-	protoregistryFiles, err := protodesc.NewFiles(
-		&descriptorpb.FileDescriptorSet{
-			File: fileDescriptorProtos,
-		},
-	)
+	fileNameToFileDescriptorProto, err := validateFileDescriptorProtos(fileDescriptorProtos)
 	if err != nil {
 		return nil, err
 	}
-
-	fileDescriptors := make([]FileDescriptor, 0, len(protoFileDescriptors))
-	protoregistryFiles.RangeFiles(
-		func(protoreflectFileDescriptor protoreflect.FileDescriptor) bool {
-			protoFileDescriptor, ok := fileNameToProtoFileDescriptor[protoreflectFileDescriptor.Path()]
-			if !ok {
-				// If the protoreflect API is sane, this should never happen.
-				// However, the protoreflect API is not sane.
-				err = fmt.Errorf("unknown file: %q", protoreflectFileDescriptor.Path())
-				return false
-			}
-			fileDescriptors = append(
-				fileDescriptors,
-				newFileDescriptor(
-					protoreflectFileDescriptor,
-					protoFileDescriptor.GetFileDescriptorProto(),
-					protoFileDescriptor.GetIsImport(),
-					protoFileDescriptor.GetIsSyntaxUnspecified(),
-					protoFileDescriptor.GetUnusedDependency(),
-				),
-			)
-			return true
-		},
-	)
-	if err != nil {
-		return nil, err
+	var lazyFiles *lazyFiles
+	if fileDescriptorsOptions.pool != nil {
+		lazyFiles, fileDescriptorProtos = fileDescriptorsOptions.pool.lazyFiles(fileDescriptorProtos, fileNameToFileDescriptorProto)
+	} else {
+		lazyFiles = newLazyFiles(fileDescriptorProtos)
 	}
-	if len(fileDescriptors) != len(protoFileDescriptors) {
-		// If the protoreflect API is sane, this should never happen.
-		// However, the protoreflect API is not sane.
-		return nil, fmt.Errorf("expected %d files from protoregistry, got %d", len(protoFileDescriptors), len(fileDescriptors))
+	fileDescriptors := make([]FileDescriptor, len(protoFileDescriptors))
+	for i, protoFileDescriptor := range protoFileDescriptors {
+		fileName := protoFileDescriptor.GetFileDescriptorProto().GetName()
+		fileDescriptors[i] = newFileDescriptor(
+			func() (protoreflect.FileDescriptor, error) {
+				return lazyFiles.Get(fileName)
+			},
+			fileDescriptorProtos[i],
+			protoFileDescriptor.GetIsImport(),
+			protoFileDescriptor.GetIsSyntaxUnspecified(),
+			protoFileDescriptor.GetUnusedDependency(),
+		)
+	}
+	if !fileDescriptorsOptions.lazyLinking {
+		for _, fileDescriptor := range fileDescriptors {
+			if err := fileDescriptor.Link(); err != nil {
+				return nil, err
+			}
+		}
 	}
 	return fileDescriptors, nil
 }
 
 // *** PRIVATE ***
 
+type fileDescriptorsOptions struct {
+	withoutImportSourceCodeInfo bool
+	lazyLinking                 bool
+	pool                        *fileDescriptorPool
+}
+
+func newFileDescriptorsOptions() *fileDescriptorsOptions {
+	return &fileDescriptorsOptions{}
+}
+
+// protoFileDescriptorsWithoutImportSourceCodeInfo returns a new slice with the SourceCodeInfo
+// of the imports dropped, without modifying the given descriptorv1.FileDescriptors.
+//
+// Only the imports are copied, and the shallow copies never include the SourceCodeInfo, so that
+// the SourceCodeInfo of the originals can be garbage collected once they are no longer referenced.
+func protoFileDescriptorsWithoutImportSourceCodeInfo(protoFileDescriptors []*descriptorv1.FileDescriptor) []*descriptorv1.FileDescriptor {
+	result := make([]*descriptorv1.FileDescriptor, len(protoFileDescriptors))
+	for i, protoFileDescriptor := range protoFileDescriptors {
+		fileDescriptorProto := protoFileDescriptor.GetFileDescriptorProto()
+		if !protoFileDescriptor.GetIsImport() || len(fileDescriptorProto.GetSourceCodeInfo().GetLocation()) == 0 {
+			result[i] = protoFileDescriptor
+			continue
+		}
+		fileDescriptorProto = fileDescriptorProtoWithoutSourceCodeInfo(fileDescriptorProto)
+		fileDescriptorProto.SourceCodeInfo = &descriptorpb.SourceCodeInfo{}
+		result[i] = &descriptorv1.FileDescriptor{
+			FileDescriptorProto: fileDescriptorProto,
+			IsImport:            protoFileDescriptor.GetIsImport(),
+			IsSyntaxUnspecified: protoFileDescriptor.GetIsSyntaxUnspecified(),
+			UnusedDependency:    protoFileDescriptor.GetUnusedDependency(),
+		}
+	}
+	return result
+}
+
 type fileDescriptor struct {
-	protoreflectFileDescriptor protoreflect.FileDescriptor
+	protoreflectFileDescriptor func() (protoreflect.FileDescriptor, error)
 	fileDescriptorProto        *descriptorpb.FileDescriptorProto
 	isImport                   bool
 	isSyntaxUnspecified        bool
 	unusedDependencyIndexes    []int32
+
+	edition                  descriptorpb.Edition
+	resolvedFeatures         func() *descriptorpb.FeatureSet
+	extensionResolver        func() (*protoregistry.Types, error)
+	digest                   func() string
+	digestWithSourceCodeInfo func() string
 }
 
+// newFileDescriptor returns a new fileDescriptor.
+//
+// protoreflectFileDescriptor is only called once the protoreflect.FileDescriptor is needed,
+// and must return the same result on every call.
 func newFileDescriptor(
-	protoreflectFileDescriptor protoreflect.FileDescriptor,
+	protoreflectFileDescriptor func() (protoreflect.FileDescriptor, error),
 	fileDescriptorProto *descriptorpb.FileDescriptorProto,
 	isImport bool,
 	isSyntaxUnspecified bool,
 	unusedDependencyIndexes []int32,
 ) *fileDescriptor {
+	edition := editionForFileDescriptorProto(fileDescriptorProto)
 	return &fileDescriptor{
 		protoreflectFileDescriptor: protoreflectFileDescriptor,
 		fileDescriptorProto:        fileDescriptorProto,
 		isImport:                   isImport,
 		isSyntaxUnspecified:        isSyntaxUnspecified,
 		unusedDependencyIndexes:    unusedDependencyIndexes,
+		edition:                    edition,
+		resolvedFeatures: sync.OnceValue(
+			func() *descriptorpb.FeatureSet {
+				featureSet := defaultFeaturesForEdition(edition)
+				// Files that do not link only have the default features.
+				if protoreflectFileDescriptor, err := protoreflectFileDescriptor(); err == nil {
+					if fileFeatures := explicitFeaturesForDescriptor(protoreflectFileDescriptor); fileFeatures != nil {
+						proto.Merge(featureSet, fileFeatures)
+					}
+				}
+				return featureSet
+			},
+		),
+		extensionResolver: sync.OnceValues(
+			func() (*protoregistry.Types, error) {
+				protoreflectFileDescriptor, err := protoreflectFileDescriptor()
+				if err != nil {
+					return nil, err
+				}
+				return newExtensionResolverForFileDescriptor(protoreflectFileDescriptor)
+			},
+		),
+		digest: sync.OnceValue(
+			func() string {
+				return digestForFileDescriptorProto(fileDescriptorProto, false)
+			},
+		),
+		digestWithSourceCodeInfo: sync.OnceValue(
+			func() string {
+				return digestForFileDescriptorProto(fileDescriptorProto, true)
+			},
+		),
 	}
 }
 
 func (f *fileDescriptor) ProtoreflectFileDescriptor() protoreflect.FileDescriptor {
-	return f.protoreflectFileDescriptor
+	protoreflectFileDescriptor, _ := f.protoreflectFileDescriptor()
+	return protoreflectFileDescriptor
+}
+
+func (f *fileDescriptor) Link() error {
+	_, err := f.protoreflectFileDescriptor()
+	return err
 }
 
 func (f *fileDescriptor) FileDescriptorProto() *descriptorpb.FileDescriptorProto {
@@ -177,6 +370,81 @@ func (f *fileDescriptor) UnusedDependencyIndexes() []int32 {
 	return slices.Clone(f.unusedDependencyIndexes)
 }
 
+func (f *fileDescriptor) UnusedDependencyPaths() []string {
+	dependencyPaths := f.fileDescriptorProto.GetDependency()
+	unusedDependencyPaths := make([]string, 0, len(f.unusedDependencyIndexes))
+	for _, unusedDependencyIndex := range f.unusedDependencyIndexes {
+		if unusedDependencyIndex < 0 || int(unusedDependencyIndex) >= len(dependencyPaths) {
+			continue
+		}
+		unusedDependencyPaths = append(unusedDependencyPaths, dependencyPaths[unusedDependencyIndex])
+	}
+	return unusedDependencyPaths
+}
+
+func (f *fileDescriptor) Digest(options ...DigestOption) string {
+	digestOptions := newDigestOptions()
+	for _, option := range options {
+		option(digestOptions)
+	}
+	if digestOptions.withSourceCodeInfo {
+		return f.digestWithSourceCodeInfo()
+	}
+	return f.digest()
+}
+
+func (f *fileDescriptor) CommentsFor(descriptor protoreflect.Descriptor) Comments {
+	protoreflectFileDescriptor, err := f.protoreflectFileDescriptor()
+	if err != nil {
+		// Descriptors cannot be within a file that does not link.
+		return newComments(nil, nil)
+	}
+	return newComments(protoreflectFileDescriptor, descriptor)
+}
+
+func (f *fileDescriptor) Edition() descriptorpb.Edition {
+	return f.edition
+}
+
+func (f *fileDescriptor) ResolvedFeatures() *descriptorpb.FeatureSet {
+	featureSet := &descriptorpb.FeatureSet{}
+	proto.Merge(featureSet, f.resolvedFeatures())
+	return featureSet
+}
+
+func (f *fileDescriptor) ResolvedFeaturesFor(descriptor protoreflect.Descriptor) *descriptorpb.FeatureSet {
+	if descriptor == nil {
+		return f.ResolvedFeatures()
+	}
+	if descriptor.ParentFile() == nil || descriptor.ParentFile().Path() != f.fileDescriptorProto.GetName() {
+		return nil
+	}
+	return resolveFeatures(f.resolvedFeatures(), f.edition, descriptor)
+}
+
+func (f *fileDescriptor) SourceOptionsFor(descriptor protoreflect.Descriptor) (protoreflect.ProtoMessage, error) {
+	if descriptor == nil {
+		return nil, errors.New("nil Descriptor")
+	}
+	if descriptor.ParentFile() == nil || descriptor.ParentFile().Path() != f.fileDescriptorProto.GetName() {
+		return nil, fmt.Errorf("%q is not within %q", descriptor.FullName(), f.fileDescriptorProto.GetName())
+	}
+	extensionResolver, err := f.extensionResolver()
+	if err != nil {
+		return nil, err
+	}
+	return sourceOptionsForDescriptor(descriptor, extensionResolver)
+}
+
+func (f *fileDescriptor) RuntimeOptionsFor(descriptor protoreflect.Descriptor) (protoreflect.ProtoMessage, error) {
+	options, err := f.SourceOptionsFor(descriptor)
+	if err != nil {
+		return nil, err
+	}
+	clearSourceRetentionFields(options.ProtoReflect())
+	return options, nil
+}
+
 func (f *fileDescriptor) ToProto() *descriptorv1.FileDescriptor {
 	if f == nil {
 		return nil
@@ -0,0 +1,147 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package descriptor
+
+import (
+	"errors"
+	"fmt"
+
+	descriptorv1 "buf.build/gen/go/bufbuild/bufplugin/protocolbuffers/go/buf/plugin/descriptor/v1"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// FileDescriptorSetOption is an option for FileDescriptorsForFileDescriptorSet.
+type FileDescriptorSetOption func(*fileDescriptorSetOptions)
+
+// FileDescriptorSetWithImportFilePaths returns a new FileDescriptorSetOption that marks the
+// files at the given paths as imports.
+//
+// Every path must be within the FileDescriptorSet. By default, no files are imports.
+func FileDescriptorSetWithImportFilePaths(importFilePaths ...string) FileDescriptorSetOption {
+	return func(fileDescriptorSetOptions *fileDescriptorSetOptions) {
+		fileDescriptorSetOptions.importFilePaths = append(fileDescriptorSetOptions.importFilePaths, importFilePaths...)
+	}
+}
+
+// FileDescriptorSetWithTargetFilePaths returns a new FileDescriptorSetOption that marks all
+// files except the files at the given paths as imports.
+//
+// This matches the file_to_generate field of a protoc CodeGeneratorRequest. Every path must be
+// within the FileDescriptorSet. This cannot be used with FileDescriptorSetWithImportFilePaths.
+func FileDescriptorSetWithTargetFilePaths(targetFilePaths ...string) FileDescriptorSetOption {
+	return func(fileDescriptorSetOptions *fileDescriptorSetOptions) {
+		fileDescriptorSetOptions.targetFilePaths = append(fileDescriptorSetOptions.targetFilePaths, targetFilePaths...)
+	}
+}
+
+// FileDescriptorsForFileDescriptorSet returns a new slice of FileDescriptors for the given
+// FileDescriptorSet.
+//
+// The FileDescriptorSet must be self-contained, that is it must include all imports. This is
+// the equivalent of the --include_imports flag in protoc. FileDescriptorProtos without
+// SourceCodeInfo are given empty SourceCodeInfo, without modifying the FileDescriptorSet.
+//
+// A FileDescriptorSet cannot represent the IsSyntaxUnspecified and UnusedDependencyIndexes
+// properties, so these are always false and empty.
+func FileDescriptorsForFileDescriptorSet(
+	fileDescriptorSet *descriptorpb.FileDescriptorSet,
+	options ...FileDescriptorSetOption,
+) ([]FileDescriptor, error) {
+	fileDescriptorSetOptions := newFileDescriptorSetOptions()
+	for _, option := range options {
+		option(fileDescriptorSetOptions)
+	}
+	if len(fileDescriptorSetOptions.importFilePaths) > 0 && len(fileDescriptorSetOptions.targetFilePaths) > 0 {
+		return nil, errors.New("cannot use both FileDescriptorSetWithImportFilePaths and FileDescriptorSetWithTargetFilePaths")
+	}
+	fileDescriptorProtos := fileDescriptorSet.GetFile()
+	fileNames := make(map[string]struct{}, len(fileDescriptorProtos))
+	for _, fileDescriptorProto := range fileDescriptorProtos {
+		fileNames[fileDescriptorProto.GetName()] = struct{}{}
+	}
+	importFilePathMap, err := filePathMapForFileNames(fileDescriptorSetOptions.importFilePaths, fileNames)
+	if err != nil {
+		return nil, err
+	}
+	targetFilePathMap, err := filePathMapForFileNames(fileDescriptorSetOptions.targetFilePaths, fileNames)
+	if err != nil {
+		return nil, err
+	}
+	protoFileDescriptors := make([]*descriptorv1.FileDescriptor, len(fileDescriptorProtos))
+	for i, fileDescriptorProto := range fileDescriptorProtos {
+		if fileDescriptorProto.GetSourceCodeInfo() == nil {
+			// FileDescriptors always contain SourceCodeInfo.
+			clone, ok := proto.Clone(fileDescriptorProto).(*descriptorpb.FileDescriptorProto)
+			if !ok {
+				return nil, fmt.Errorf("could not clone %q", fileDescriptorProto.GetName())
+			}
+			clone.SourceCodeInfo = &descriptorpb.SourceCodeInfo{}
+			fileDescriptorProto = clone
+		}
+		var isImport bool
+		switch {
+		case len(importFilePathMap) > 0:
+			_, isImport = importFilePathMap[fileDescriptorProto.GetName()]
+		case len(targetFilePathMap) > 0:
+			_, isTarget := targetFilePathMap[fileDescriptorProto.GetName()]
+			isImport = !isTarget
+		}
+		protoFileDescriptors[i] = &descriptorv1.FileDescriptor{
+			FileDescriptorProto: fileDescriptorProto,
+			IsImport:            isImport,
+		}
+	}
+	return FileDescriptorsForProtoFileDescriptors(protoFileDescriptors)
+}
+
+// FileDescriptorSetForFileDescriptors returns a new FileDescriptorSet for the given FileDescriptors.
+//
+// The FileDescriptorProtos are in the same order as the FileDescriptors, and are not copies - do
+// not modify! Whether or not each file is an import is not represented in a FileDescriptorSet;
+// use FileDescriptorSetWithImportFilePaths with the paths of the imports to round-trip this.
+func FileDescriptorSetForFileDescriptors(fileDescriptors []FileDescriptor) *descriptorpb.FileDescriptorSet {
+	fileDescriptorProtos := make([]*descriptorpb.FileDescriptorProto, len(fileDescriptors))
+	for i, fileDescriptor := range fileDescriptors {
+		fileDescriptorProtos[i] = fileDescriptor.FileDescriptorProto()
+	}
+	return &descriptorpb.FileDescriptorSet{
+		File: fileDescriptorProtos,
+	}
+}
+
+// *** PRIVATE ***
+
+type fileDescriptorSetOptions struct {
+	importFilePaths []string
+	targetFilePaths []string
+}
+
+func newFileDescriptorSetOptions() *fileDescriptorSetOptions {
+	return &fileDescriptorSetOptions{}
+}
+
+// filePathMapForFileNames returns a map of the file paths, validating that each file path is
+// within the file names.
+func filePathMapForFileNames(filePaths []string, fileNames map[string]struct{}) (map[string]struct{}, error) {
+	filePathMap := make(map[string]struct{}, len(filePaths))
+	for _, filePath := range filePaths {
+		if _, ok := fileNames[filePath]; !ok {
+			return nil, fmt.Errorf("file %q not found in FileDescriptorSet", filePath)
+		}
+		filePathMap[filePath] = struct{}{}
+	}
+	return filePathMap, nil
+}
@@ -0,0 +1,276 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package descriptor_test
+
+import (
+	"testing"
+
+	descriptorv1 "buf.build/gen/go/bufbuild/bufplugin/protocolbuffers/go/buf/plugin/descriptor/v1"
+	"buf.build/go/bufplugin/descriptor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+func TestFileDescriptorsForProtoFileDescriptorsInvalid(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name                 string
+		fileDescriptorProtos []*descriptorpb.FileDescriptorProto
+		expectedError        string
+	}{
+		{
+			name: "unresolved_type_name",
+			fileDescriptorProtos: []*descriptorpb.FileDescriptorProto{
+				testNewFileDescriptorProto(
+					"a.proto",
+					"a",
+					testNewMessageDescriptorProto("Foo", testNewMessageFieldDescriptorProto("bar", 1, "Bar")),
+				),
+			},
+			expectedError: `message field "a.Foo.bar" cannot resolve type`,
+		},
+		{
+			name: "type_name_not_imported",
+			fileDescriptorProtos: []*descriptorpb.FileDescriptorProto{
+				testNewFileDescriptorProto(
+					"a.proto",
+					"a",
+					testNewMessageDescriptorProto("Foo", testNewMessageFieldDescriptorProto("bar", 1, "Bar")),
+				),
+				testNewFileDescriptorProto(
+					"b.proto",
+					"a",
+					testNewMessageDescriptorProto("Bar"),
+				),
+			},
+			expectedError: `message field "a.Foo.bar" cannot resolve type`,
+		},
+		{
+			name: "duplicate_symbol",
+			fileDescriptorProtos: []*descriptorpb.FileDescriptorProto{
+				testNewFileDescriptorProto(
+					"a.proto",
+					"a",
+					testNewMessageDescriptorProto("Foo"),
+				),
+				testNewFileDescriptorProto(
+					"b.proto",
+					"a",
+					testNewMessageDescriptorProto("Foo"),
+				),
+			},
+			expectedError: `file "b.proto" has a name conflict over a.Foo`,
+		},
+		{
+			name: "duplicate_symbol_within_file",
+			fileDescriptorProtos: []*descriptorpb.FileDescriptorProto{
+				testNewFileDescriptorProto(
+					"a.proto",
+					"a",
+					testNewMessageDescriptorProto("Foo"),
+					testNewMessageDescriptorProto("Foo"),
+				),
+			},
+			expectedError: `descriptor "a.Foo" already declared`,
+		},
+	}
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+			_, err := descriptor.FileDescriptorsForProtoFileDescriptors(
+				testNewProtoFileDescriptors(testCase.fileDescriptorProtos...),
+			)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), testCase.expectedError)
+
+			// With lazy linking, the error is returned once the files are linked.
+			fileDescriptors, err := descriptor.FileDescriptorsForProtoFileDescriptors(
+				testNewProtoFileDescriptors(testCase.fileDescriptorProtos...),
+				descriptor.FileDescriptorsWithLazyLinking(),
+			)
+			require.NoError(t, err)
+			var linkErrs []error
+			for _, fileDescriptor := range fileDescriptors {
+				if err := fileDescriptor.Link(); err != nil {
+					assert.Nil(t, fileDescriptor.ProtoreflectFileDescriptor())
+					linkErrs = append(linkErrs, err)
+				}
+			}
+			require.NotEmpty(t, linkErrs)
+			assert.Contains(t, linkErrs[len(linkErrs)-1].Error(), testCase.expectedError)
+		})
+	}
+}
+
+func TestFileDescriptorsForProtoFileDescriptorsResolvesRelativeNames(t *testing.T) {
+	t.Parallel()
+
+	_, err := descriptor.FileDescriptorsForProtoFileDescriptors(
+		testNewProtoFileDescriptors(
+			testNewFileDescriptorProto(
+				"a.proto",
+				"a.b",
+				testNewMessageDescriptorProto(
+					"Foo",
+					testNewMessageFieldDescriptorProto("bar", 1, "Bar"),
+					testNewMessageFieldDescriptorProto("baz", 2, "b.Baz"),
+				),
+			),
+			testNewFileDescriptorProto(
+				"b.proto",
+				"a",
+				testNewMessageDescriptorProto("Bar"),
+			),
+			testNewFileDescriptorProto(
+				"c.proto",
+				"a.b",
+				testNewMessageDescriptorProto("Baz"),
+			),
+		),
+	)
+	// b.proto and c.proto are not imported.
+	require.Error(t, err)
+
+	aFileDescriptorProto := testNewFileDescriptorProto(
+		"a.proto",
+		"a.b",
+		testNewMessageDescriptorProto(
+			"Foo",
+			testNewMessageFieldDescriptorProto("bar", 1, "Bar"),
+			testNewMessageFieldDescriptorProto("baz", 2, "b.Baz"),
+		),
+	)
+	aFileDescriptorProto.Dependency = []string{"b.proto", "c.proto"}
+	fileDescriptors, err := descriptor.FileDescriptorsForProtoFileDescriptors(
+		testNewProtoFileDescriptors(
+			aFileDescriptorProto,
+			testNewFileDescriptorProto(
+				"b.proto",
+				"a",
+				testNewMessageDescriptorProto("Bar"),
+			),
+			testNewFileDescriptorProto(
+				"c.proto",
+				"a.b",
+				testNewMessageDescriptorProto("Baz"),
+			),
+		),
+	)
+	require.NoError(t, err)
+	require.Len(t, fileDescriptors, 3)
+	fields := fileDescriptors[0].ProtoreflectFileDescriptor().Messages().ByName("Foo").Fields()
+	assert.Equal(t, "a.Bar", string(fields.ByName("bar").Message().FullName()))
+	assert.Equal(t, "a.b.Baz", string(fields.ByName("baz").Message().FullName()))
+}
+
+func TestFileDescriptorLinkError(t *testing.T) {
+	t.Parallel()
+
+	// Only detected by linking the file.
+	newFileDescriptorProtos := func() []*descriptorpb.FileDescriptorProto {
+		fileDescriptorProto := testNewFileDescriptorProto(
+			"a.proto",
+			"a",
+			testNewMessageDescriptorProto(
+				"Foo",
+				&descriptorpb.FieldDescriptorProto{
+					Name:   proto.String("bar"),
+					Number: proto.Int32(1),
+					Label:  descriptorpb.FieldDescriptorProto_LABEL_REQUIRED.Enum(),
+					Type:   descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(),
+				},
+			),
+		)
+		fileDescriptorProto.Syntax = proto.String("proto3")
+		importingFileDescriptorProto := testNewFileDescriptorProto(
+			"b.proto",
+			"b",
+			testNewMessageDescriptorProto("Bar", testNewMessageFieldDescriptorProto("foo", 1, "a.Foo")),
+		)
+		importingFileDescriptorProto.Dependency = []string{"a.proto"}
+		return []*descriptorpb.FileDescriptorProto{fileDescriptorProto, importingFileDescriptorProto}
+	}
+
+	_, err := descriptor.FileDescriptorsForProtoFileDescriptors(
+		testNewProtoFileDescriptors(newFileDescriptorProtos()...),
+	)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "cannot be required")
+
+	fileDescriptors, err := descriptor.FileDescriptorsForProtoFileDescriptors(
+		testNewProtoFileDescriptors(newFileDescriptorProtos()...),
+		descriptor.FileDescriptorsWithLazyLinking(),
+	)
+	require.NoError(t, err)
+	require.Len(t, fileDescriptors, 2)
+	for _, fileDescriptor := range fileDescriptors {
+		err := fileDescriptor.Link()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "cannot be required")
+		// The error is returned on every call.
+		assert.Equal(t, err, fileDescriptor.Link())
+		assert.Nil(t, fileDescriptor.ProtoreflectFileDescriptor())
+		assert.NotNil(t, fileDescriptor.FileDescriptorProto())
+	}
+	_, err = descriptor.NewResolver(fileDescriptors)
+	require.Error(t, err)
+}
+
+func testNewProtoFileDescriptors(fileDescriptorProtos ...*descriptorpb.FileDescriptorProto) []*descriptorv1.FileDescriptor {
+	protoFileDescriptors := make([]*descriptorv1.FileDescriptor, len(fileDescriptorProtos))
+	for i, fileDescriptorProto := range fileDescriptorProtos {
+		protoFileDescriptors[i] = &descriptorv1.FileDescriptor{
+			FileDescriptorProto: fileDescriptorProto,
+		}
+	}
+	return protoFileDescriptors
+}
+
+func testNewFileDescriptorProto(
+	name string,
+	pkg string,
+	messageDescriptorProtos ...*descriptorpb.DescriptorProto,
+) *descriptorpb.FileDescriptorProto {
+	return &descriptorpb.FileDescriptorProto{
+		Name:           proto.String(name),
+		Package:        proto.String(pkg),
+		MessageType:    messageDescriptorProtos,
+		SourceCodeInfo: &descriptorpb.SourceCodeInfo{},
+	}
+}
+
+func testNewMessageDescriptorProto(
+	name string,
+	fieldDescriptorProtos ...*descriptorpb.FieldDescriptorProto,
+) *descriptorpb.DescriptorProto {
+	return &descriptorpb.DescriptorProto{
+		Name:  proto.String(name),
+		Field: fieldDescriptorProtos,
+	}
+}
+
+func testNewMessageFieldDescriptorProto(name string, number int32, typeName string) *descriptorpb.FieldDescriptorProto {
+	return &descriptorpb.FieldDescriptorProto{
+		Name:     proto.String(name),
+		Number:   proto.Int32(number),
+		Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+		Type:     descriptorpb.FieldDescriptorProto_TYPE_MESSAGE.Enum(),
+		TypeName: proto.String(typeName),
+	}
+}
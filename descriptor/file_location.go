@@ -47,6 +47,11 @@ type FileLocation interface {
 	// LeadingDetachedComments returns any leading detached comments, if known.
 	LeadingDetachedComments() []string
 	// ToProto converts the FileLocation to its Protobuf representation.
+	//
+	// The Protobuf representation only has the file name and SourcePath. Line and column
+	// information is not included, as buf.plugin.descriptor.v1.FileLocation has no fields for
+	// it. Recipients resolve the SourcePath against the SourceCodeInfo of the corresponding
+	// FileDescriptorProto, which they must have to render lines and columns.
 	ToProto() *descriptorv1.FileLocation
 
 	unclonedSourcePath() protoreflect.SourcePath
@@ -66,6 +71,61 @@ func NewFileLocation(
 	}
 }
 
+// FileLocationFor returns a new FileLocation for the Descriptor within the FileDescriptor.
+//
+// The FileLocation spans the whole declaration of the Descriptor, for example an entire
+// message, and has the comments of the declaration. Use NewNameFileLocation to span only the
+// name. The SourceLocation is looked up by the SourcePath of the Descriptor, so the Descriptor
+// may be from any protoreflect.FileDescriptor with the same path, such as a file from
+// another set of FileDescriptors that was built from the same source.
+//
+// If the Descriptor is nil, is not within the FileDescriptor, or has no SourceLocation, for
+// example if the file was built without SourceCodeInfo, the FileLocation references the file
+// as a whole. Returns nil if the FileDescriptor is nil.
+func FileLocationFor(
+	fileDescriptor FileDescriptor,
+	descriptor protoreflect.Descriptor,
+) FileLocation {
+	if fileDescriptor == nil {
+		return nil
+	}
+	var sourceLocation protoreflect.SourceLocation
+	// Technically, ParentFile() can be nil.
+	if descriptor != nil && descriptor.ParentFile() != nil &&
+		descriptor.ParentFile().Path() == fileDescriptor.FileDescriptorProto().GetName() {
+		if path := PathForDescriptor(descriptor); path != nil && fileDescriptor.Link() == nil {
+			sourceLocation = fileDescriptor.ProtoreflectFileDescriptor().SourceLocations().ByPath(path)
+		}
+	}
+	return NewFileLocation(fileDescriptor, sourceLocation)
+}
+
+// NewNameFileLocation returns a new FileLocation for the name of the Descriptor within the
+// FileDescriptor.
+//
+// The FileLocation spans only the declared identifier, for example the name of a message,
+// rather than the whole declaration, so that editors can underline just the symbol. The
+// SourcePath is the result of PathForName. Name locations do not have comments.
+//
+// If the name has no SourceLocation, for example if the file was built without SourceCodeInfo,
+// this is equivalent to NewFileLocation with the SourceLocation of the whole declaration.
+func NewNameFileLocation(
+	fileDescriptor FileDescriptor,
+	descriptor protoreflect.Descriptor,
+) FileLocation {
+	// Descriptors cannot be within a file that does not link.
+	if path := PathForName(descriptor); path != nil && fileDescriptor.Link() == nil {
+		sourceLocations := fileDescriptor.ProtoreflectFileDescriptor().SourceLocations()
+		if sourceLocation := sourceLocations.ByPath(path); len(sourceLocation.Path) > 0 {
+			return NewFileLocation(fileDescriptor, sourceLocation)
+		}
+	}
+	if _, ok := descriptor.(protoreflect.FileDescriptor); ok {
+		return NewFileLocation(fileDescriptor, protoreflect.SourceLocation{})
+	}
+	return FileLocationFor(fileDescriptor, descriptor)
+}
+
 // *** PRIVATE ***
 
 type fileLocation struct {
@@ -114,7 +174,8 @@ func (l *fileLocation) ToProto() *descriptorv1.FileLocation {
 		return nil
 	}
 	return &descriptorv1.FileLocation{
-		FileName:   l.fileDescriptor.ProtoreflectFileDescriptor().Path(),
+		// Use the FileDescriptorProto so that the file does not need to be linked.
+		FileName:   l.fileDescriptor.FileDescriptorProto().GetName(),
 		SourcePath: l.sourceLocation.Path,
 	}
 }
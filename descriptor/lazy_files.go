@@ -0,0 +1,185 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package descriptor
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// lazyFiles links the files of a set with protodesc on demand.
+//
+// Each file is linked at most once, the first time it or a file that imports it is linked.
+// Linked files are registered with a shared protoregistry.Files, which is used to resolve
+// the names referenced by the files that import them.
+//
+// lazyFiles implements protodesc.Resolver for use while linking.
+type lazyFiles struct {
+	fileNameToLink map[string]func() (protoreflect.FileDescriptor, error)
+
+	files *protoregistry.Files
+	lock  sync.RWMutex
+}
+
+// newLazyFiles returns a new lazyFiles for the FileDescriptorProtos.
+//
+// The FileDescriptorProtos must have been validated with validateFileDescriptorProtos.
+func newLazyFiles(fileDescriptorProtos []*descriptorpb.FileDescriptorProto) *lazyFiles {
+	lazyFiles := &lazyFiles{
+		fileNameToLink: make(map[string]func() (protoreflect.FileDescriptor, error), len(fileDescriptorProtos)),
+		files:          &protoregistry.Files{},
+	}
+	for _, fileDescriptorProto := range fileDescriptorProtos {
+		fileDescriptorProto := fileDescriptorProto
+		lazyFiles.fileNameToLink[fileDescriptorProto.GetName()] = sync.OnceValues(
+			func() (protoreflect.FileDescriptor, error) {
+				return lazyFiles.link(fileDescriptorProto)
+			},
+		)
+	}
+	return lazyFiles
+}
+
+// Get returns the linked protoreflect.FileDescriptor for the file name, linking it if
+// it has not yet been linked.
+//
+// Returns the error from linking the file or any of its imports, if any, in which case the
+// protoreflect.FileDescriptor is nil. Errors are returned on every call. The file name must
+// be within the set.
+func (l *lazyFiles) Get(fileName string) (protoreflect.FileDescriptor, error) {
+	return l.fileNameToLink[fileName]()
+}
+
+// FindFileByPath implements protodesc.Resolver.
+func (l *lazyFiles) FindFileByPath(fileName string) (protoreflect.FileDescriptor, error) {
+	link, ok := l.fileNameToLink[fileName]
+	if !ok {
+		return nil, protoregistry.NotFound
+	}
+	return link()
+}
+
+// FindDescriptorByName implements protodesc.Resolver.
+//
+// All imports of a file are linked, and therefore registered, before protodesc resolves
+// any names within the file.
+func (l *lazyFiles) FindDescriptorByName(name protoreflect.FullName) (protoreflect.Descriptor, error) {
+	l.lock.RLock()
+	defer l.lock.RUnlock()
+	return l.files.FindDescriptorByName(name)
+}
+
+// link links the FileDescriptorProto with linkFileDescriptorProto, and registers the result.
+func (l *lazyFiles) link(fileDescriptorProto *descriptorpb.FileDescriptorProto) (protoreflect.FileDescriptor, error) {
+	protoreflectFileDescriptor, err := linkFileDescriptorProto(fileDescriptorProto, l)
+	if err != nil {
+		return nil, err
+	}
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if err := l.files.RegisterFile(protoreflectFileDescriptor); err != nil {
+		return nil, fmt.Errorf("could not register %q: %w", fileDescriptorProto.GetName(), err)
+	}
+	return protoreflectFileDescriptor, nil
+}
+
+// linkFileDescriptorProto links the FileDescriptorProto against the resolver.
+func linkFileDescriptorProto(
+	fileDescriptorProto *descriptorpb.FileDescriptorProto,
+	resolver protodesc.Resolver,
+) (protoreflect.FileDescriptor, error) {
+	protoreflectFileDescriptor, err := protodesc.NewFile(fileDescriptorProto, resolver)
+	if err != nil {
+		return nil, fmt.Errorf("could not link %q: %w", fileDescriptorProto.GetName(), err)
+	}
+	return protoreflectFileDescriptor, nil
+}
+
+// validateFileDescriptorProtos validates that the FileDescriptorProtos have unique, non-empty
+// names, and have all of their dependencies within the set without import cycles, as linking
+// would otherwise never terminate or fail for every file that imports them.
+//
+// All other validation happens when a file is linked. Returns a map from file name to
+// FileDescriptorProto.
+func validateFileDescriptorProtos(
+	fileDescriptorProtos []*descriptorpb.FileDescriptorProto,
+) (map[string]*descriptorpb.FileDescriptorProto, error) {
+	fileNameToFileDescriptorProto := make(map[string]*descriptorpb.FileDescriptorProto, len(fileDescriptorProtos))
+	for _, fileDescriptorProto := range fileDescriptorProtos {
+		fileName := fileDescriptorProto.GetName()
+		if fileName == "" {
+			return nil, errors.New("file path must be populated")
+		}
+		if _, ok := fileNameToFileDescriptorProto[fileName]; ok {
+			//  This should have been validated via protovalidate.
+			return nil, fmt.Errorf("duplicate file name: %q", fileName)
+		}
+		fileNameToFileDescriptorProto[fileName] = fileDescriptorProto
+	}
+	if err := validateDependencies(fileDescriptorProtos, fileNameToFileDescriptorProto); err != nil {
+		return nil, err
+	}
+	return fileNameToFileDescriptorProto, nil
+}
+
+// validateDependencies validates that all dependencies of the FileDescriptorProtos are within
+// the set, and that there are no import cycles.
+//
+// Weak dependencies may be missing, as protodesc replaces them with placeholders.
+func validateDependencies(
+	fileDescriptorProtos []*descriptorpb.FileDescriptorProto,
+	fileNameToFileDescriptorProto map[string]*descriptorpb.FileDescriptorProto,
+) error {
+	// Files that are false are on the current path, files that are true have been validated.
+	fileNameToValidated := make(map[string]bool, len(fileDescriptorProtos))
+	var visit func(*descriptorpb.FileDescriptorProto) error
+	visit = func(fileDescriptorProto *descriptorpb.FileDescriptorProto) error {
+		fileName := fileDescriptorProto.GetName()
+		if validated, ok := fileNameToValidated[fileName]; ok {
+			if !validated {
+				return fmt.Errorf("import cycle including %q", fileName)
+			}
+			return nil
+		}
+		fileNameToValidated[fileName] = false
+		for i, dependency := range fileDescriptorProto.GetDependency() {
+			dependencyFileDescriptorProto, ok := fileNameToFileDescriptorProto[dependency]
+			if !ok {
+				if slices.Contains(fileDescriptorProto.GetWeakDependency(), int32(i)) {
+					continue
+				}
+				return fmt.Errorf("%q: could not resolve import %q", fileName, dependency)
+			}
+			if err := visit(dependencyFileDescriptorProto); err != nil {
+				return err
+			}
+		}
+		fileNameToValidated[fileName] = true
+		return nil
+	}
+	for _, fileDescriptorProto := range fileDescriptorProtos {
+		if err := visit(fileDescriptorProto); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package descriptor
+
+import (
+	"fmt"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// *** PRIVATE ***
+
+// newExtensionResolverForFileDescriptor returns a new protoregistry.Types that contains every
+// extension declared within the file or its transitive imports.
+func newExtensionResolverForFileDescriptor(fileDescriptor protoreflect.FileDescriptor) (*protoregistry.Types, error) {
+	types := &protoregistry.Types{}
+	var err error
+	rangeExtensionsWithImports(
+		fileDescriptor,
+		func(extensionDescriptor protoreflect.ExtensionDescriptor) {
+			if err != nil {
+				return
+			}
+			err = types.RegisterExtension(dynamicpb.NewExtensionType(extensionDescriptor))
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+	return types, nil
+}
+
+// sourceOptionsForDescriptor returns a copy of the options of the Descriptor, with custom
+// options resolved using the resolver.
+//
+// Custom options that cannot be resolved remain as unknown fields.
+func sourceOptionsForDescriptor(
+	descriptor protoreflect.Descriptor,
+	resolver *protoregistry.Types,
+) (protoreflect.ProtoMessage, error) {
+	options := descriptor.Options()
+	if options == nil {
+		return nil, fmt.Errorf("%q has no options type", descriptor.FullName())
+	}
+	data, err := proto.Marshal(options)
+	if err != nil {
+		return nil, err
+	}
+	sourceOptions := options.ProtoReflect().New().Interface()
+	if err := (proto.UnmarshalOptions{Resolver: resolver}).Unmarshal(data, sourceOptions); err != nil {
+		return nil, err
+	}
+	return sourceOptions, nil
+}
+
+// clearSourceRetentionFields clears every field within the message that has
+// retention = RETENTION_SOURCE, recursing into message fields.
+func clearSourceRetentionFields(message protoreflect.Message) {
+	var sourceRetentionFieldDescriptors []protoreflect.FieldDescriptor
+	message.Range(
+		func(fieldDescriptor protoreflect.FieldDescriptor, value protoreflect.Value) bool {
+			if isSourceRetention(fieldDescriptor) {
+				sourceRetentionFieldDescriptors = append(sourceRetentionFieldDescriptors, fieldDescriptor)
+				return true
+			}
+			switch {
+			case fieldDescriptor.IsMap():
+				if fieldDescriptor.MapValue().Message() != nil {
+					value.Map().Range(
+						func(_ protoreflect.MapKey, mapValue protoreflect.Value) bool {
+							clearSourceRetentionFields(mapValue.Message())
+							return true
+						},
+					)
+				}
+			case fieldDescriptor.Message() != nil && fieldDescriptor.IsList():
+				list := value.List()
+				for i := 0; i < list.Len(); i++ {
+					clearSourceRetentionFields(list.Get(i).Message())
+				}
+			case fieldDescriptor.Message() != nil:
+				clearSourceRetentionFields(value.Message())
+			}
+			return true
+		},
+	)
+	for _, fieldDescriptor := range sourceRetentionFieldDescriptors {
+		message.Clear(fieldDescriptor)
+	}
+}
+
+func isSourceRetention(fieldDescriptor protoreflect.FieldDescriptor) bool {
+	fieldOptions, ok := fieldDescriptor.Options().(*descriptorpb.FieldOptions)
+	return ok && fieldOptions.GetRetention() == descriptorpb.FieldOptions_RETENTION_SOURCE
+}
@@ -0,0 +1,204 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package descriptor_test
+
+import (
+	"context"
+	"sort"
+	"testing"
+
+	"buf.build/go/bufplugin/descriptor"
+	"buf.build/go/bufplugin/descriptor/descriptortest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+func TestSourceAndRuntimeOptionsFor(t *testing.T) {
+	t.Parallel()
+
+	fileDescriptor, optionsFileDescriptor := testCompileOptionsSource(t)
+	testCases := []struct {
+		name                      string
+		fullName                  protoreflect.FullName
+		expectedSourceFieldPaths  []string
+		expectedRuntimeFieldPaths []string
+	}{
+		{
+			name:     "field",
+			fullName: "a.Foo.field",
+			expectedSourceFieldPaths: []string{
+				"[options.message_option].runtime_field",
+				"[options.message_option].source_field",
+				"[options.repeated_message_option].runtime_field",
+				"[options.repeated_message_option].source_field",
+				"[options.runtime_option]",
+				"[options.source_option]",
+				"deprecated",
+			},
+			expectedRuntimeFieldPaths: []string{
+				"[options.message_option].runtime_field",
+				"[options.repeated_message_option].runtime_field",
+				"[options.runtime_option]",
+				"deprecated",
+			},
+		},
+		{
+			name:     "message",
+			fullName: "a.Foo",
+			expectedSourceFieldPaths: []string{
+				"[options.message_source_option]",
+				"deprecated",
+			},
+			expectedRuntimeFieldPaths: []string{
+				"deprecated",
+			},
+		},
+		{
+			name:     "field_without_options",
+			fullName: "a.Foo.plain_field",
+		},
+	}
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+			protoreflectDescriptor := testFindDescriptor(t, fileDescriptor.ProtoreflectFileDescriptor(), testCase.fullName)
+			sourceOptions, err := fileDescriptor.SourceOptionsFor(protoreflectDescriptor)
+			require.NoError(t, err)
+			assert.Empty(t, sourceOptions.ProtoReflect().GetUnknown())
+			assert.Equal(t, testCase.expectedSourceFieldPaths, testSetFieldPaths(sourceOptions.ProtoReflect()))
+			runtimeOptions, err := fileDescriptor.RuntimeOptionsFor(protoreflectDescriptor)
+			require.NoError(t, err)
+			assert.Equal(t, testCase.expectedRuntimeFieldPaths, testSetFieldPaths(runtimeOptions.ProtoReflect()))
+			// The options of the Descriptor itself are not modified.
+			sourceOptions, err = fileDescriptor.SourceOptionsFor(protoreflectDescriptor)
+			require.NoError(t, err)
+			assert.Equal(t, testCase.expectedSourceFieldPaths, testSetFieldPaths(sourceOptions.ProtoReflect()))
+		})
+	}
+
+	t.Run("options_type", func(t *testing.T) {
+		t.Parallel()
+		protoreflectDescriptor := testFindDescriptor(t, fileDescriptor.ProtoreflectFileDescriptor(), "a.Foo.field")
+		sourceOptions, err := fileDescriptor.SourceOptionsFor(protoreflectDescriptor)
+		require.NoError(t, err)
+		fieldOptions, ok := sourceOptions.(*descriptorpb.FieldOptions)
+		require.True(t, ok)
+		assert.True(t, fieldOptions.GetDeprecated())
+	})
+	t.Run("nil_descriptor", func(t *testing.T) {
+		t.Parallel()
+		_, err := fileDescriptor.SourceOptionsFor(nil)
+		require.Error(t, err)
+		_, err = fileDescriptor.RuntimeOptionsFor(nil)
+		require.Error(t, err)
+	})
+	t.Run("descriptor_within_other_file", func(t *testing.T) {
+		t.Parallel()
+		protoreflectDescriptor := testFindDescriptor(t, optionsFileDescriptor.ProtoreflectFileDescriptor(), "options.MessageOption")
+		_, err := fileDescriptor.SourceOptionsFor(protoreflectDescriptor)
+		require.ErrorContains(t, err, `"options.MessageOption" is not within "a.proto"`)
+		_, err = fileDescriptor.RuntimeOptionsFor(protoreflectDescriptor)
+		require.ErrorContains(t, err, `"options.MessageOption" is not within "a.proto"`)
+	})
+}
+
+func testCompileOptionsSource(t *testing.T) (descriptor.FileDescriptor, descriptor.FileDescriptor) {
+	fileDescriptors, err := descriptortest.CompileStrings(
+		context.Background(),
+		map[string]string{
+			"options.proto": `syntax = "proto2";
+package options;
+import "google/protobuf/descriptor.proto";
+message MessageOption {
+  optional string runtime_field = 1;
+  optional string source_field = 2 [retention = RETENTION_SOURCE];
+}
+extend google.protobuf.FieldOptions {
+  optional string runtime_option = 50000;
+  optional string source_option = 50001 [retention = RETENTION_SOURCE];
+  optional MessageOption message_option = 50002;
+  repeated MessageOption repeated_message_option = 50003;
+}
+extend google.protobuf.MessageOptions {
+  optional string message_source_option = 50000 [retention = RETENTION_SOURCE];
+}
+`,
+			"a.proto": `syntax = "proto3";
+package a;
+import "options.proto";
+message Foo {
+  option deprecated = true;
+  option (options.message_source_option) = "source";
+  string field = 1 [
+    deprecated = true,
+    (options.runtime_option) = "runtime",
+    (options.source_option) = "source",
+    (options.message_option) = {runtime_field: "runtime", source_field: "source"},
+    (options.repeated_message_option) = {runtime_field: "runtime", source_field: "source"}
+  ];
+  string plain_field = 2;
+}
+`,
+		},
+	)
+	require.NoError(t, err)
+	var fileDescriptor descriptor.FileDescriptor
+	var optionsFileDescriptor descriptor.FileDescriptor
+	for _, candidate := range fileDescriptors {
+		switch candidate.FileDescriptorProto().GetName() {
+		case "a.proto":
+			fileDescriptor = candidate
+		case "options.proto":
+			optionsFileDescriptor = candidate
+		}
+	}
+	require.NotNil(t, fileDescriptor)
+	require.NotNil(t, optionsFileDescriptor)
+	return fileDescriptor, optionsFileDescriptor
+}
+
+// testSetFieldPaths returns the sorted paths of the set fields within the message, with the
+// fields of message values included as "field.nested_field".
+func testSetFieldPaths(message protoreflect.Message) []string {
+	var fieldPaths []string
+	message.Range(
+		func(fieldDescriptor protoreflect.FieldDescriptor, value protoreflect.Value) bool {
+			fieldPath := fieldDescriptor.TextName()
+			var nestedMessages []protoreflect.Message
+			switch {
+			case fieldDescriptor.Message() != nil && fieldDescriptor.IsList():
+				list := value.List()
+				for i := 0; i < list.Len(); i++ {
+					nestedMessages = append(nestedMessages, list.Get(i).Message())
+				}
+			case fieldDescriptor.Message() != nil:
+				nestedMessages = append(nestedMessages, value.Message())
+			default:
+				fieldPaths = append(fieldPaths, fieldPath)
+			}
+			for _, nestedMessage := range nestedMessages {
+				for _, nestedFieldPath := range testSetFieldPaths(nestedMessage) {
+					fieldPaths = append(fieldPaths, fieldPath+"."+nestedFieldPath)
+				}
+			}
+			return true
+		},
+	)
+	sort.Strings(fieldPaths)
+	return fieldPaths
+}
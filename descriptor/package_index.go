@@ -0,0 +1,130 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package descriptor
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// PackageIndex is an index of a set of FileDescriptors by package.
+//
+// Files without a package are within the empty package "". Packages that are not within the
+// PackageIndex have no files or Descriptors.
+type PackageIndex interface {
+	// Packages returns the sorted packages of all files within the PackageIndex.
+	Packages() []string
+	// FilesForPackage returns the files within the package, sorted by path.
+	FilesForPackage(pkg string) []FileDescriptor
+	// DescriptorsForPackage returns the top-level Descriptors declared within the package.
+	//
+	// These are the messages, enums, extensions, and services declared at the top level of
+	// the files within the package. Nested Descriptors are not included, see Walk. The
+	// Descriptors are ordered by the path of their file, and then as within the
+	// FileDescriptorProto: messages, enums, extensions, and then services, each in the order
+	// they are declared. Files that do not link, see FileDescriptorsWithLazyLinking, are skipped.
+	DescriptorsForPackage(pkg string) []protoreflect.Descriptor
+
+	isPackageIndex()
+}
+
+// NewPackageIndex returns a new PackageIndex for the FileDescriptors.
+//
+// Packages are read from the FileDescriptorProtos, so only DescriptorsForPackage requires
+// the files to be linked. Returns an error if two FileDescriptors have the same path.
+func NewPackageIndex(fileDescriptors []FileDescriptor) (PackageIndex, error) {
+	pathMap := make(map[string]struct{}, len(fileDescriptors))
+	packageToFileDescriptors := make(map[string][]FileDescriptor)
+	for _, fileDescriptor := range fileDescriptors {
+		path := fileDescriptor.FileDescriptorProto().GetName()
+		if _, ok := pathMap[path]; ok {
+			return nil, fmt.Errorf("duplicate file: %q", path)
+		}
+		pathMap[path] = struct{}{}
+		pkg := fileDescriptor.FileDescriptorProto().GetPackage()
+		packageToFileDescriptors[pkg] = append(packageToFileDescriptors[pkg], fileDescriptor)
+	}
+	packages := make([]string, 0, len(packageToFileDescriptors))
+	for pkg, packageFileDescriptors := range packageToFileDescriptors {
+		packages = append(packages, pkg)
+		slices.SortFunc(
+			packageFileDescriptors,
+			func(one FileDescriptor, two FileDescriptor) int {
+				return strings.Compare(one.FileDescriptorProto().GetName(), two.FileDescriptorProto().GetName())
+			},
+		)
+	}
+	sort.Strings(packages)
+	return &packageIndex{
+		packages:                 packages,
+		packageToFileDescriptors: packageToFileDescriptors,
+	}, nil
+}
+
+// *** PRIVATE ***
+
+type packageIndex struct {
+	packages                 []string
+	packageToFileDescriptors map[string][]FileDescriptor
+}
+
+func (p *packageIndex) Packages() []string {
+	return slices.Clone(p.packages)
+}
+
+func (p *packageIndex) FilesForPackage(pkg string) []FileDescriptor {
+	return slices.Clone(p.packageToFileDescriptors[pkg])
+}
+
+func (p *packageIndex) DescriptorsForPackage(pkg string) []protoreflect.Descriptor {
+	var descriptors []protoreflect.Descriptor
+	for _, fileDescriptor := range p.packageToFileDescriptors[pkg] {
+		if fileDescriptor.Link() != nil {
+			continue
+		}
+		descriptors = appendTopLevelDescriptors(descriptors, fileDescriptor.ProtoreflectFileDescriptor())
+	}
+	return descriptors
+}
+
+func (*packageIndex) isPackageIndex() {}
+
+// appendTopLevelDescriptors appends the top-level Descriptors of the file.
+func appendTopLevelDescriptors(
+	descriptors []protoreflect.Descriptor,
+	protoreflectFileDescriptor protoreflect.FileDescriptor,
+) []protoreflect.Descriptor {
+	messages := protoreflectFileDescriptor.Messages()
+	for i := 0; i < messages.Len(); i++ {
+		descriptors = append(descriptors, messages.Get(i))
+	}
+	enums := protoreflectFileDescriptor.Enums()
+	for i := 0; i < enums.Len(); i++ {
+		descriptors = append(descriptors, enums.Get(i))
+	}
+	extensions := protoreflectFileDescriptor.Extensions()
+	for i := 0; i < extensions.Len(); i++ {
+		descriptors = append(descriptors, extensions.Get(i))
+	}
+	services := protoreflectFileDescriptor.Services()
+	for i := 0; i < services.Len(); i++ {
+		descriptors = append(descriptors, services.Get(i))
+	}
+	return descriptors
+}
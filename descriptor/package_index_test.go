@@ -0,0 +1,175 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package descriptor_test
+
+import (
+	"context"
+	"testing"
+
+	"buf.build/go/bufplugin/descriptor"
+	"buf.build/go/bufplugin/descriptor/descriptortest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+func TestPackageIndex(t *testing.T) {
+	t.Parallel()
+
+	fileDescriptors, err := descriptortest.CompileStrings(
+		context.Background(),
+		map[string]string{
+			"foo/b.proto": `syntax = "proto2";
+package foo;
+import "foo/a.proto";
+service Service {}
+extend foo.Foo {
+  optional string ext = 100;
+}
+enum Enum {
+  ENUM_UNSPECIFIED = 0;
+}
+message Bar {
+  message Nested {}
+}
+`,
+			"foo/a.proto": `syntax = "proto2";
+package foo;
+message Foo {
+  extensions 100 to 200;
+}
+`,
+			"bar/a.proto": `syntax = "proto3";
+package bar;
+message Foo {}
+`,
+			"none.proto": `syntax = "proto3";
+message None {}
+`,
+		},
+	)
+	require.NoError(t, err)
+	packageIndex, err := descriptor.NewPackageIndex(fileDescriptors)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"", "bar", "foo"}, packageIndex.Packages())
+	// The returned slice is a copy.
+	packageIndex.Packages()[0] = "baz"
+	assert.Equal(t, []string{"", "bar", "foo"}, packageIndex.Packages())
+
+	testCases := []struct {
+		pkg                     string
+		expectedPaths           []string
+		expectedDescriptorNames []string
+	}{
+		{
+			pkg:                     "",
+			expectedPaths:           []string{"none.proto"},
+			expectedDescriptorNames: []string{"None"},
+		},
+		{
+			pkg:                     "bar",
+			expectedPaths:           []string{"bar/a.proto"},
+			expectedDescriptorNames: []string{"bar.Foo"},
+		},
+		{
+			pkg:           "foo",
+			expectedPaths: []string{"foo/a.proto", "foo/b.proto"},
+			expectedDescriptorNames: []string{
+				"foo.Foo",
+				"foo.Bar",
+				"foo.Enum",
+				"foo.ext",
+				"foo.Service",
+			},
+		},
+		{
+			pkg: "baz",
+		},
+		{
+			// Packages are matched exactly, not by prefix.
+			pkg: "fo",
+		},
+	}
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.pkg, func(t *testing.T) {
+			t.Parallel()
+			var paths []string
+			for _, fileDescriptor := range packageIndex.FilesForPackage(testCase.pkg) {
+				paths = append(paths, fileDescriptor.FileDescriptorProto().GetName())
+			}
+			assert.Equal(t, testCase.expectedPaths, paths)
+			assert.Equal(t, testCase.expectedDescriptorNames, testDescriptorNames(packageIndex.DescriptorsForPackage(testCase.pkg)))
+		})
+	}
+}
+
+func TestPackageIndexSkipsFilesThatDoNotLink(t *testing.T) {
+	t.Parallel()
+
+	unlinkableFileDescriptorProto := testNewFileDescriptorProto(
+		"a/b.proto",
+		"a",
+		testNewMessageDescriptorProto(
+			"Bar",
+			&descriptorpb.FieldDescriptorProto{
+				Name:   proto.String("baz"),
+				Number: proto.Int32(1),
+				Label:  descriptorpb.FieldDescriptorProto_LABEL_REQUIRED.Enum(),
+				Type:   descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(),
+			},
+		),
+	)
+	// Required fields are not allowed in proto3, which is not detected until the file is linked.
+	unlinkableFileDescriptorProto.Syntax = proto.String("proto3")
+	fileDescriptors, err := descriptor.FileDescriptorsForProtoFileDescriptors(
+		testNewProtoFileDescriptors(
+			testNewFileDescriptorProto("a/a.proto", "a", testNewMessageDescriptorProto("Foo")),
+			unlinkableFileDescriptorProto,
+		),
+		descriptor.FileDescriptorsWithLazyLinking(),
+	)
+	require.NoError(t, err)
+	packageIndex, err := descriptor.NewPackageIndex(fileDescriptors)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a"}, packageIndex.Packages())
+	assert.Len(t, packageIndex.FilesForPackage("a"), 2)
+	assert.Equal(t, []string{"a.Foo"}, testDescriptorNames(packageIndex.DescriptorsForPackage("a")))
+}
+
+func TestNewPackageIndexDuplicateFile(t *testing.T) {
+	t.Parallel()
+
+	fileDescriptors, err := descriptortest.CompileStrings(
+		context.Background(),
+		map[string]string{
+			"a.proto": `syntax = "proto3"; package a;`,
+		},
+	)
+	require.NoError(t, err)
+	_, err = descriptor.NewPackageIndex(append(fileDescriptors, fileDescriptors...))
+	require.ErrorContains(t, err, `duplicate file: "a.proto"`)
+}
+
+func testDescriptorNames(descriptors []protoreflect.Descriptor) []string {
+	var names []string
+	for _, protoreflectDescriptor := range descriptors {
+		names = append(names, string(protoreflectDescriptor.FullName()))
+	}
+	return names
+}
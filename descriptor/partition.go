@@ -0,0 +1,154 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package descriptor
+
+import (
+	"errors"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// PartitionFileDescriptors splits the FileDescriptors into groups that are each self-contained,
+// and whose serialized size is at most maxSize bytes where possible.
+//
+// Every file that is not an import is within exactly one group, and each group also contains the
+// transitive imports of its files. Files that are not imports in the given FileDescriptors, but
+// are only within a group as an import of another file, are imports within that group. This
+// allows large requests to be split into multiple smaller requests, each of which can be
+// checked independently, and together check every file exactly once.
+//
+// Files are assigned to groups in topological order, so that files that import each other tend
+// to be within the same group. The size of a group is the sum of the serialized sizes of the
+// FileDescriptorProtos within it. If a single file and its transitive imports exceed maxSize,
+// the file is within a group of its own that exceeds maxSize. Within each group, the files are
+// in the same order as within the given FileDescriptors.
+//
+// Every file that is imported must be within the FileDescriptors, and maxSize must be positive.
+func PartitionFileDescriptors(fileDescriptors []FileDescriptor, maxSize int) ([][]FileDescriptor, error) {
+	if maxSize <= 0 {
+		return nil, errors.New("maxSize must be positive")
+	}
+	dependencyGraph, err := NewDependencyGraph(fileDescriptors)
+	if err != nil {
+		return nil, err
+	}
+	pathToIndex := make(map[string]int, len(fileDescriptors))
+	sizes := make([]int, len(fileDescriptors))
+	for i, fileDescriptor := range fileDescriptors {
+		pathToIndex[fileDescriptor.FileDescriptorProto().GetName()] = i
+		sizes[i] = proto.Size(fileDescriptor.FileDescriptorProto())
+	}
+	var partitions []*partition
+	var current *partition
+	for _, path := range dependencyGraph.TopologicalOrder() {
+		index := pathToIndex[path]
+		if fileDescriptors[index].IsImport() {
+			continue
+		}
+		indexes := []int{index}
+		for _, importPath := range dependencyGraph.TransitiveImports(path) {
+			indexes = append(indexes, pathToIndex[importPath])
+		}
+		if current == nil || current.size+current.additionalSize(indexes, sizes) > maxSize {
+			current = newPartition(len(fileDescriptors))
+			partitions = append(partitions, current)
+		}
+		current.add(index, indexes, sizes)
+	}
+	groups := make([][]FileDescriptor, len(partitions))
+	for i, groupPartition := range partitions {
+		groups[i] = groupPartition.fileDescriptors(fileDescriptors)
+	}
+	return groups, nil
+}
+
+// *** PRIVATE ***
+
+// partition is a group of files being built by PartitionFileDescriptors.
+//
+// Files are identified by their index within the FileDescriptors.
+type partition struct {
+	// Files that are not imports within the group are true, imports are false.
+	indexToIsTarget map[int]bool
+	size            int
+}
+
+func newPartition(capacity int) *partition {
+	return &partition{
+		indexToIsTarget: make(map[int]bool, capacity),
+	}
+}
+
+// additionalSize returns the size that adding the files would add to the partition.
+func (p *partition) additionalSize(indexes []int, sizes []int) int {
+	var additionalSize int
+	for _, index := range indexes {
+		if _, ok := p.indexToIsTarget[index]; !ok {
+			additionalSize += sizes[index]
+		}
+	}
+	return additionalSize
+}
+
+// add adds the target file and its transitive imports, which include the target, to the
+// partition.
+func (p *partition) add(targetIndex int, indexes []int, sizes []int) {
+	p.size += p.additionalSize(indexes, sizes)
+	for _, index := range indexes {
+		if _, ok := p.indexToIsTarget[index]; !ok {
+			p.indexToIsTarget[index] = false
+		}
+	}
+	p.indexToIsTarget[targetIndex] = true
+}
+
+// fileDescriptors returns the FileDescriptors within the partition.
+//
+// Files that are not imports within the FileDescriptors, but are imports within the partition,
+// are copied with IsImport set.
+func (p *partition) fileDescriptors(fileDescriptors []FileDescriptor) []FileDescriptor {
+	group := make([]FileDescriptor, 0, len(p.indexToIsTarget))
+	for index, fileDescriptor := range fileDescriptors {
+		isTarget, ok := p.indexToIsTarget[index]
+		if !ok {
+			continue
+		}
+		if !isTarget && !fileDescriptor.IsImport() {
+			fileDescriptor = fileDescriptorAsImport(fileDescriptor)
+		}
+		group = append(group, fileDescriptor)
+	}
+	return group
+}
+
+// fileDescriptorAsImport returns a copy of the FileDescriptor with IsImport set.
+//
+// The copy shares the FileDescriptorProto and protoreflect.FileDescriptor of the FileDescriptor,
+// and does not link the file if it has not yet been linked.
+func fileDescriptorAsImport(fileDescriptor FileDescriptor) FileDescriptor {
+	return newFileDescriptor(
+		func() (protoreflect.FileDescriptor, error) {
+			if err := fileDescriptor.Link(); err != nil {
+				return nil, err
+			}
+			return fileDescriptor.ProtoreflectFileDescriptor(), nil
+		},
+		fileDescriptor.FileDescriptorProto(),
+		true,
+		fileDescriptor.IsSyntaxUnspecified(),
+		fileDescriptor.UnusedDependencyIndexes(),
+	)
+}
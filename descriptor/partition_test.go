@@ -0,0 +1,190 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package descriptor_test
+
+import (
+	"context"
+	"testing"
+
+	"buf.build/go/bufplugin/descriptor"
+	"buf.build/go/bufplugin/descriptor/descriptortest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestPartitionFileDescriptors(t *testing.T) {
+	t.Parallel()
+
+	fileDescriptors, err := descriptortest.CompileStrings(
+		context.Background(),
+		map[string]string{
+			"a.proto": `syntax = "proto3";
+package a;
+message A {}
+`,
+			"b.proto": `syntax = "proto3";
+package b;
+import "a.proto";
+message B {
+  a.A a = 1;
+}
+`,
+			"c.proto": `syntax = "proto3";
+package c;
+import "a.proto";
+message C {
+  a.A a = 1;
+  string name = 2;
+}
+`,
+			"d.proto": `syntax = "proto3";
+package d;
+message D {}
+`,
+		},
+	)
+	require.NoError(t, err)
+	a := testFileDescriptorForPath(t, fileDescriptors, "a.proto")
+	b := testFileDescriptorForPath(t, fileDescriptors, "b.proto")
+	c := testFileDescriptorForPath(t, fileDescriptors, "c.proto")
+	d := testFileDescriptorForPath(t, fileDescriptors, "d.proto")
+	sizeA := proto.Size(a.FileDescriptorProto())
+	sizeB := proto.Size(b.FileDescriptorProto())
+	sizeC := proto.Size(c.FileDescriptorProto())
+	sizeD := proto.Size(d.FileDescriptorProto())
+	aAsImport := testFileDescriptorAsImport(t, a)
+
+	testCases := []struct {
+		name            string
+		fileDescriptors []descriptor.FileDescriptor
+		maxSize         int
+		// Paths of files that are imports within the group have the suffix " (import)".
+		expectedGroups [][]string
+	}{
+		{
+			name:            "single_group",
+			fileDescriptors: []descriptor.FileDescriptor{a, b, c, d},
+			maxSize:         sizeA + sizeB + sizeC + sizeD,
+			expectedGroups: [][]string{
+				{"a.proto", "b.proto", "c.proto", "d.proto"},
+			},
+		},
+		{
+			name:            "target_imported_by_other_group",
+			fileDescriptors: []descriptor.FileDescriptor{a, b, c},
+			maxSize:         sizeA + max(sizeB, sizeC),
+			expectedGroups: [][]string{
+				{"a.proto", "b.proto"},
+				{"a.proto (import)", "c.proto"},
+			},
+		},
+		{
+			name:            "oversized_files",
+			fileDescriptors: []descriptor.FileDescriptor{a, b, c, d},
+			maxSize:         1,
+			expectedGroups: [][]string{
+				{"a.proto"},
+				{"a.proto (import)", "b.proto"},
+				{"a.proto (import)", "c.proto"},
+				{"d.proto"},
+			},
+		},
+		{
+			name:            "imports_are_not_targets",
+			fileDescriptors: []descriptor.FileDescriptor{aAsImport, b, c, d},
+			maxSize:         1,
+			expectedGroups: [][]string{
+				{"a.proto (import)", "b.proto"},
+				{"a.proto (import)", "c.proto"},
+				{"d.proto"},
+			},
+		},
+		{
+			name:            "order_of_given_files",
+			fileDescriptors: []descriptor.FileDescriptor{d, c, b, a},
+			maxSize:         sizeA + sizeB + sizeC + sizeD,
+			expectedGroups: [][]string{
+				{"d.proto", "c.proto", "b.proto", "a.proto"},
+			},
+		},
+	}
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+			groups, err := descriptor.PartitionFileDescriptors(testCase.fileDescriptors, testCase.maxSize)
+			require.NoError(t, err)
+			actualGroups := make([][]string, len(groups))
+			for i, group := range groups {
+				for _, fileDescriptor := range group {
+					path := fileDescriptor.FileDescriptorProto().GetName()
+					if fileDescriptor.IsImport() {
+						path += " (import)"
+					}
+					actualGroups[i] = append(actualGroups[i], path)
+					// Files are shared with, or linked the same as, the given FileDescriptors.
+					require.NoError(t, fileDescriptor.Link())
+					assert.Equal(
+						t,
+						testFileDescriptorForPath(t, testCase.fileDescriptors, fileDescriptor.FileDescriptorProto().GetName()).ProtoreflectFileDescriptor(),
+						fileDescriptor.ProtoreflectFileDescriptor(),
+					)
+				}
+			}
+			assert.Equal(t, testCase.expectedGroups, actualGroups)
+			// The given FileDescriptors are not modified.
+			for _, fileDescriptor := range testCase.fileDescriptors {
+				if fileDescriptor != aAsImport {
+					assert.False(t, fileDescriptor.IsImport())
+				}
+			}
+		})
+	}
+}
+
+func TestPartitionFileDescriptorsError(t *testing.T) {
+	t.Parallel()
+
+	fileDescriptors, err := descriptortest.CompileStrings(
+		context.Background(),
+		map[string]string{
+			"a.proto": `syntax = "proto3"; package a; message A {}`,
+			"b.proto": `syntax = "proto3"; package b; import "a.proto"; message B { a.A a = 1; }`,
+		},
+	)
+	require.NoError(t, err)
+	_, err = descriptor.PartitionFileDescriptors(fileDescriptors, 0)
+	require.ErrorContains(t, err, "maxSize must be positive")
+	_, err = descriptor.PartitionFileDescriptors(fileDescriptors, -1)
+	require.ErrorContains(t, err, "maxSize must be positive")
+	// Every file that is imported must be within the FileDescriptors.
+	_, err = descriptor.PartitionFileDescriptors(
+		[]descriptor.FileDescriptor{testFileDescriptorForPath(t, fileDescriptors, "b.proto")},
+		1024,
+	)
+	require.Error(t, err)
+}
+
+// testFileDescriptorAsImport returns the FileDescriptor with IsImport set.
+func testFileDescriptorAsImport(t *testing.T, fileDescriptor descriptor.FileDescriptor) descriptor.FileDescriptor {
+	fileDescriptors, err := descriptor.FileDescriptorsForFileDescriptorSet(
+		descriptor.FileDescriptorSetForFileDescriptors([]descriptor.FileDescriptor{fileDescriptor}),
+		descriptor.FileDescriptorSetWithImportFilePaths(fileDescriptor.FileDescriptorProto().GetName()),
+	)
+	require.NoError(t, err)
+	require.Len(t, fileDescriptors, 1)
+	return fileDescriptors[0]
+}
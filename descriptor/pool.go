@@ -0,0 +1,306 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package descriptor
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sync"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// FileDescriptorPool interns files across calls to FileDescriptorsForProtoFileDescriptors.
+//
+// Long-lived processes that see the same files repeatedly, such as plugins that serve many
+// requests or test harnesses, can share a FileDescriptorPool so that each distinct file is
+// only linked once. Files are keyed by a digest of their FileDescriptorProto, including
+// SourceCodeInfo, and the keys of their dependencies, so that a file is only shared if it
+// and all of its transitive imports are identical.
+//
+// Files that do not link are evicted from the FileDescriptorPool once linking fails, so
+// that an invalid request does not occupy the FileDescriptorPool. The error is still
+// returned by every FileDescriptor that refers to the file.
+//
+// FileDescriptorPools are safe for concurrent use.
+type FileDescriptorPool interface {
+	// Len returns the number of files within the FileDescriptorPool.
+	Len() int
+
+	isFileDescriptorPool()
+}
+
+// DefaultFileDescriptorPoolMaxSize is the default maximum number of files within a
+// FileDescriptorPool.
+//
+// See FileDescriptorPoolWithMaxSize.
+const DefaultFileDescriptorPoolMaxSize = 4096
+
+// FileDescriptorPoolOption is an option for NewFileDescriptorPool.
+type FileDescriptorPoolOption func(*fileDescriptorPoolOptions)
+
+// FileDescriptorPoolWithMaxSize returns a new FileDescriptorPoolOption that limits the number
+// of files within the FileDescriptorPool.
+//
+// Once the limit is reached, the least recently used files are evicted. The default is
+// DefaultFileDescriptorPoolMaxSize. A value of 0 means there is no limit, which should only
+// be used if the set of files that the FileDescriptorPool will see is bounded. A value < 0
+// has no effect.
+func FileDescriptorPoolWithMaxSize(maxSize int) FileDescriptorPoolOption {
+	return func(fileDescriptorPoolOptions *fileDescriptorPoolOptions) {
+		if maxSize < 0 {
+			return
+		}
+		fileDescriptorPoolOptions.maxSize = maxSize
+	}
+}
+
+// NewFileDescriptorPool returns a new FileDescriptorPool.
+func NewFileDescriptorPool(options ...FileDescriptorPoolOption) FileDescriptorPool {
+	fileDescriptorPoolOptions := newFileDescriptorPoolOptions()
+	for _, option := range options {
+		option(fileDescriptorPoolOptions)
+	}
+	return newFileDescriptorPool(fileDescriptorPoolOptions.maxSize)
+}
+
+// FileDescriptorsWithPool returns a new FileDescriptorsOption that interns files within the
+// given FileDescriptorPool.
+//
+// Files that are already within the FileDescriptorPool reuse both the FileDescriptorProto and
+// the linked protoreflect.FileDescriptor from the FileDescriptorPool. Files that are not are
+// added to the FileDescriptorPool, and are linked as usual.
+//
+// As files are shared, the FileDescriptorProtos of the returned FileDescriptors may not be
+// the ones that were given. This is not visible unless the FileDescriptorProtos are modified,
+// which is never allowed.
+func FileDescriptorsWithPool(pool FileDescriptorPool) FileDescriptorsOption {
+	return func(fileDescriptorsOptions *fileDescriptorsOptions) {
+		// FileDescriptorPool can only be implemented within this package, so this is only
+		// false if pool is nil, in which case no pool is used.
+		if fileDescriptorPool, ok := pool.(*fileDescriptorPool); ok {
+			fileDescriptorsOptions.pool = fileDescriptorPool
+		}
+	}
+}
+
+// *** PRIVATE ***
+
+type fileDescriptorPool struct {
+	maxSize int
+
+	// Elements are *pooledFiles, with the most recently used at the front.
+	lruList      *list.List
+	keyToElement map[string]*list.Element
+	lock         sync.Mutex
+}
+
+func newFileDescriptorPool(maxSize int) *fileDescriptorPool {
+	return &fileDescriptorPool{
+		maxSize:      maxSize,
+		lruList:      list.New(),
+		keyToElement: make(map[string]*list.Element),
+	}
+}
+
+func (p *fileDescriptorPool) Len() int {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.lruList.Len()
+}
+
+// lazyFiles returns a new lazyFiles for the FileDescriptorProtos that links files within the
+// pool, along with the FileDescriptorProtos from the pool.
+//
+// The FileDescriptorProtos must have been validated with validateFileDescriptorProtos, which
+// returned fileNameToFileDescriptorProto.
+func (p *fileDescriptorPool) lazyFiles(
+	fileDescriptorProtos []*descriptorpb.FileDescriptorProto,
+	fileNameToFileDescriptorProto map[string]*descriptorpb.FileDescriptorProto,
+) (*lazyFiles, []*descriptorpb.FileDescriptorProto) {
+	fileNameToPooledFile := make(map[string]*pooledFile, len(fileDescriptorProtos))
+	var getPooledFile func(*descriptorpb.FileDescriptorProto) *pooledFile
+	getPooledFile = func(fileDescriptorProto *descriptorpb.FileDescriptorProto) *pooledFile {
+		if pooledFile, ok := fileNameToPooledFile[fileDescriptorProto.GetName()]; ok {
+			return pooledFile
+		}
+		hash := sha256.New()
+		_, _ = hash.Write([]byte(digestForFileDescriptorProto(fileDescriptorProto, true)))
+		dependencyPooledFiles := make([]*pooledFile, len(fileDescriptorProto.GetDependency()))
+		for i, dependency := range fileDescriptorProto.GetDependency() {
+			_, _ = hash.Write([]byte{'\n'})
+			// Missing weak dependencies do not have a pooledFile.
+			if dependencyFileDescriptorProto, ok := fileNameToFileDescriptorProto[dependency]; ok {
+				dependencyPooledFiles[i] = getPooledFile(dependencyFileDescriptorProto)
+				_, _ = hash.Write([]byte(dependencyPooledFiles[i].key))
+			}
+		}
+		pooledFile := p.getOrAdd(
+			hex.EncodeToString(hash.Sum(nil)),
+			fileDescriptorProto,
+			dependencyPooledFiles,
+		)
+		fileNameToPooledFile[fileDescriptorProto.GetName()] = pooledFile
+		return pooledFile
+	}
+	lazyFiles := &lazyFiles{
+		fileNameToLink: make(map[string]func() (protoreflect.FileDescriptor, error), len(fileDescriptorProtos)),
+	}
+	pooledFileDescriptorProtos := make([]*descriptorpb.FileDescriptorProto, len(fileDescriptorProtos))
+	for i, fileDescriptorProto := range fileDescriptorProtos {
+		pooledFile := getPooledFile(fileDescriptorProto)
+		lazyFiles.fileNameToLink[fileDescriptorProto.GetName()] = pooledFile.link
+		pooledFileDescriptorProtos[i] = pooledFile.fileDescriptorProto
+	}
+	return lazyFiles, pooledFileDescriptorProtos
+}
+
+// getOrAdd returns the pooledFile for the key, adding a new pooledFile if the key is not
+// within the pool.
+func (p *fileDescriptorPool) getOrAdd(
+	key string,
+	fileDescriptorProto *descriptorpb.FileDescriptorProto,
+	dependencyPooledFiles []*pooledFile,
+) *pooledFile {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if element, ok := p.keyToElement[key]; ok {
+		p.lruList.MoveToFront(element)
+		pooledFile, ok := element.Value.(*pooledFile)
+		if ok {
+			return pooledFile
+		}
+	}
+	addedPooledFile := newPooledFile(key, fileDescriptorProto, dependencyPooledFiles, p.remove)
+	p.keyToElement[key] = p.lruList.PushFront(addedPooledFile)
+	if p.maxSize > 0 {
+		for p.lruList.Len() > p.maxSize {
+			element := p.lruList.Back()
+			p.lruList.Remove(element)
+			if evictedPooledFile, ok := element.Value.(*pooledFile); ok {
+				delete(p.keyToElement, evictedPooledFile.key)
+			}
+		}
+	}
+	return addedPooledFile
+}
+
+// remove removes the pooledFile from the pool, if it is still within the pool.
+func (p *fileDescriptorPool) remove(pooledFile *pooledFile) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	element, ok := p.keyToElement[pooledFile.key]
+	if !ok || element.Value != pooledFile {
+		return
+	}
+	p.lruList.Remove(element)
+	delete(p.keyToElement, pooledFile.key)
+}
+
+func (*fileDescriptorPool) isFileDescriptorPool() {}
+
+// pooledFile is a file within a fileDescriptorPool.
+//
+// Evicted pooledFiles remain valid, and are still used by the files that import them.
+// pooledFiles that do not link are evicted once linking fails.
+type pooledFile struct {
+	key                 string
+	fileDescriptorProto *descriptorpb.FileDescriptorProto
+	link                func() (protoreflect.FileDescriptor, error)
+}
+
+// onLinkError is called with the pooledFile if it does not link.
+func newPooledFile(
+	key string,
+	fileDescriptorProto *descriptorpb.FileDescriptorProto,
+	dependencyPooledFiles []*pooledFile,
+	onLinkError func(*pooledFile),
+) *pooledFile {
+	pooledFile := &pooledFile{
+		key:                 key,
+		fileDescriptorProto: fileDescriptorProto,
+	}
+	pooledFile.link = sync.OnceValues(
+		func() (protoreflect.FileDescriptor, error) {
+			protoreflectFileDescriptor, err := linkPooledFile(fileDescriptorProto, dependencyPooledFiles)
+			if err != nil {
+				onLinkError(pooledFile)
+				return nil, err
+			}
+			return protoreflectFileDescriptor, nil
+		},
+	)
+	return pooledFile
+}
+
+// linkPooledFile links the FileDescriptorProto against the transitive imports of its
+// dependencies, as the names referenced by a file can only be within its transitive imports.
+func linkPooledFile(
+	fileDescriptorProto *descriptorpb.FileDescriptorProto,
+	dependencyPooledFiles []*pooledFile,
+) (protoreflect.FileDescriptor, error) {
+	files := &protoregistry.Files{}
+	seen := make(map[string]struct{})
+	for _, dependencyPooledFile := range dependencyPooledFiles {
+		if dependencyPooledFile == nil {
+			continue
+		}
+		dependencyFileDescriptor, err := dependencyPooledFile.link()
+		if err != nil {
+			return nil, err
+		}
+		if err := registerFileTransitively(files, dependencyFileDescriptor, seen); err != nil {
+			return nil, fmt.Errorf("could not register %q: %w", dependencyFileDescriptor.Path(), err)
+		}
+	}
+	return linkFileDescriptorProto(fileDescriptorProto, files)
+}
+
+type fileDescriptorPoolOptions struct {
+	maxSize int
+}
+
+func newFileDescriptorPoolOptions() *fileDescriptorPoolOptions {
+	return &fileDescriptorPoolOptions{
+		maxSize: DefaultFileDescriptorPoolMaxSize,
+	}
+}
+
+// registerFileTransitively registers the file and its transitive imports that have not
+// yet been seen.
+//
+// The transitive imports of a pooledFile are always the same files as within the request
+// that added it, as its key includes the keys of its dependencies, so conflicts are errors.
+func registerFileTransitively(
+	files *protoregistry.Files,
+	fileDescriptor protoreflect.FileDescriptor,
+	seen map[string]struct{},
+) error {
+	if _, ok := seen[fileDescriptor.Path()]; ok || fileDescriptor.IsPlaceholder() {
+		return nil
+	}
+	seen[fileDescriptor.Path()] = struct{}{}
+	imports := fileDescriptor.Imports()
+	for i := 0; i < imports.Len(); i++ {
+		if err := registerFileTransitively(files, imports.Get(i).FileDescriptor, seen); err != nil {
+			return err
+		}
+	}
+	return files.RegisterFile(fileDescriptor)
+}
@@ -0,0 +1,127 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package descriptor_test
+
+import (
+	"fmt"
+	"testing"
+
+	"buf.build/go/bufplugin/descriptor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+func TestFileDescriptorPoolDefaultMaxSize(t *testing.T) {
+	t.Parallel()
+
+	fileDescriptorProtos := make([]*descriptorpb.FileDescriptorProto, descriptor.DefaultFileDescriptorPoolMaxSize+1)
+	for i := range fileDescriptorProtos {
+		fileDescriptorProtos[i] = testNewFileDescriptorProto(fmt.Sprintf("%d.proto", i), "a")
+	}
+	fileDescriptorPool := descriptor.NewFileDescriptorPool()
+	_, err := descriptor.FileDescriptorsForProtoFileDescriptors(
+		testNewProtoFileDescriptors(fileDescriptorProtos...),
+		descriptor.FileDescriptorsWithPool(fileDescriptorPool),
+	)
+	require.NoError(t, err)
+	assert.Equal(t, descriptor.DefaultFileDescriptorPoolMaxSize, fileDescriptorPool.Len())
+
+	fileDescriptorPool = descriptor.NewFileDescriptorPool(descriptor.FileDescriptorPoolWithMaxSize(0))
+	_, err = descriptor.FileDescriptorsForProtoFileDescriptors(
+		testNewProtoFileDescriptors(fileDescriptorProtos...),
+		descriptor.FileDescriptorsWithPool(fileDescriptorPool),
+	)
+	require.NoError(t, err)
+	assert.Equal(t, len(fileDescriptorProtos), fileDescriptorPool.Len())
+}
+
+func TestFileDescriptorPoolMaxSize(t *testing.T) {
+	t.Parallel()
+
+	fileDescriptorPool := descriptor.NewFileDescriptorPool(descriptor.FileDescriptorPoolWithMaxSize(2))
+	for _, fileName := range []string{"a.proto", "b.proto", "c.proto"} {
+		_, err := descriptor.FileDescriptorsForProtoFileDescriptors(
+			testNewProtoFileDescriptors(testNewFileDescriptorProto(fileName, "a")),
+			descriptor.FileDescriptorsWithPool(fileDescriptorPool),
+		)
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 2, fileDescriptorPool.Len())
+}
+
+func TestFileDescriptorPoolLinkError(t *testing.T) {
+	t.Parallel()
+
+	fileDescriptorPool := descriptor.NewFileDescriptorPool()
+	newFileDescriptors := func(aSyntax string) ([]descriptor.FileDescriptor, error) {
+		aFileDescriptorProto := testNewFileDescriptorProto(
+			"a.proto",
+			"a",
+			testNewMessageDescriptorProto(
+				"Foo",
+				&descriptorpb.FieldDescriptorProto{
+					Name:   proto.String("bar"),
+					Number: proto.Int32(1),
+					Label:  descriptorpb.FieldDescriptorProto_LABEL_REQUIRED.Enum(),
+					Type:   descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(),
+				},
+			),
+		)
+		aFileDescriptorProto.Syntax = proto.String(aSyntax)
+		bFileDescriptorProto := testNewFileDescriptorProto(
+			"b.proto",
+			"b",
+			testNewMessageDescriptorProto("Bar", testNewMessageFieldDescriptorProto("foo", 1, "a.Foo")),
+		)
+		bFileDescriptorProto.Dependency = []string{"a.proto"}
+		return descriptor.FileDescriptorsForProtoFileDescriptors(
+			testNewProtoFileDescriptors(aFileDescriptorProto, bFileDescriptorProto),
+			descriptor.FileDescriptorsWithPool(fileDescriptorPool),
+			descriptor.FileDescriptorsWithLazyLinking(),
+		)
+	}
+
+	// Required fields are not allowed in proto3.
+	fileDescriptors, err := newFileDescriptors("proto3")
+	require.NoError(t, err)
+	assert.Equal(t, 2, fileDescriptorPool.Len())
+	require.Error(t, fileDescriptors[1].Link())
+	require.Error(t, fileDescriptors[0].Link())
+	assert.Nil(t, fileDescriptors[1].ProtoreflectFileDescriptor())
+	// The files that do not link are evicted.
+	assert.Equal(t, 0, fileDescriptorPool.Len())
+
+	// The same files are added again, and still do not link.
+	fileDescriptors, err = newFileDescriptors("proto3")
+	require.NoError(t, err)
+	assert.Equal(t, 2, fileDescriptorPool.Len())
+	require.Error(t, fileDescriptors[1].Link())
+	assert.Equal(t, 0, fileDescriptorPool.Len())
+
+	// Valid files are not affected by the files that did not link.
+	fileDescriptors, err = newFileDescriptors("proto2")
+	require.NoError(t, err)
+	for _, fileDescriptor := range fileDescriptors {
+		require.NoError(t, fileDescriptor.Link())
+	}
+	assert.Equal(t, 2, fileDescriptorPool.Len())
+	assert.Equal(
+		t,
+		"a.Foo",
+		string(fileDescriptors[1].ProtoreflectFileDescriptor().Messages().Get(0).Fields().Get(0).Message().FullName()),
+	)
+}
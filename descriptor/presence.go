@@ -0,0 +1,68 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package descriptor
+
+import (
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// IsSyntheticOneof returns true if the oneof is a synthetic oneof.
+//
+// Synthetic oneofs are generated by the compiler for each proto3 field with the optional
+// keyword, to track the presence of the field. They are not declared within the source, and
+// rules should usually ignore them, for example when checking that all oneofs are named
+// consistently. Files that use editions never have synthetic oneofs, as presence is set
+// via the field_presence feature instead.
+//
+// Returns false if the oneof is nil.
+func IsSyntheticOneof(oneofDescriptor protoreflect.OneofDescriptor) bool {
+	return oneofDescriptor != nil && oneofDescriptor.IsSynthetic()
+}
+
+// HasExplicitPresence returns true if the field tracks whether it is set, separately from its
+// value.
+//
+// This is the case for:
+//
+//   - Singular message fields and extensions.
+//   - Fields within a oneof, including proto3 fields with the optional keyword.
+//   - Singular fields within proto2 files, including required fields.
+//   - Singular fields within files that use editions, if the resolved field_presence feature
+//     is EXPLICIT or LEGACY_REQUIRED.
+//
+// Repeated and map fields, and singular scalar fields within proto3 files without the
+// optional keyword, have implicit presence, and are only distinguished from their zero value
+// by being set to a non-zero value. Returns false if the field is nil.
+func HasExplicitPresence(fieldDescriptor protoreflect.FieldDescriptor) bool {
+	if fieldDescriptor == nil || fieldDescriptor.Cardinality() == protoreflect.Repeated {
+		return false
+	}
+	if fieldDescriptor.IsExtension() || fieldDescriptor.Message() != nil || fieldDescriptor.ContainingOneof() != nil {
+		return true
+	}
+	// Technically, ParentFile() can be nil.
+	if parentFile := fieldDescriptor.ParentFile(); parentFile != nil {
+		switch parentFile.Syntax() {
+		case protoreflect.Proto2:
+			return true
+		case protoreflect.Proto3:
+			return false
+		case protoreflect.Editions:
+			// The field_presence feature is resolved by protoreflect, taking into account the
+			// features of the file and all enclosing Descriptors.
+		}
+	}
+	return fieldDescriptor.HasPresence()
+}
@@ -0,0 +1,189 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package descriptor_test
+
+import (
+	"context"
+	"testing"
+
+	"buf.build/go/bufplugin/descriptor"
+	"buf.build/go/bufplugin/descriptor/descriptortest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+)
+
+func TestHasExplicitPresence(t *testing.T) {
+	t.Parallel()
+
+	files := testCompilePresenceSource(t)
+	testCases := []struct {
+		fullName                    protoreflect.FullName
+		expectedHasExplicitPresence bool
+	}{
+		{fullName: "a.Foo.optional_scalar", expectedHasExplicitPresence: true},
+		{fullName: "a.Foo.required_scalar", expectedHasExplicitPresence: true},
+		{fullName: "a.Foo.message", expectedHasExplicitPresence: true},
+		{fullName: "a.Foo.oneof_scalar", expectedHasExplicitPresence: true},
+		{fullName: "a.Foo.repeated_scalar", expectedHasExplicitPresence: false},
+		{fullName: "a.Foo.repeated_message", expectedHasExplicitPresence: false},
+		{fullName: "a.Foo.map", expectedHasExplicitPresence: false},
+		{fullName: "a.ext", expectedHasExplicitPresence: true},
+		{fullName: "a.repeated_ext", expectedHasExplicitPresence: false},
+		{fullName: "b.Foo.scalar", expectedHasExplicitPresence: false},
+		{fullName: "b.Foo.enum", expectedHasExplicitPresence: false},
+		{fullName: "b.Foo.optional_scalar", expectedHasExplicitPresence: true},
+		{fullName: "b.Foo.message", expectedHasExplicitPresence: true},
+		{fullName: "b.Foo.oneof_scalar", expectedHasExplicitPresence: true},
+		{fullName: "b.Foo.repeated_scalar", expectedHasExplicitPresence: false},
+		{fullName: "b.Foo.map", expectedHasExplicitPresence: false},
+		{fullName: "c.Foo.scalar", expectedHasExplicitPresence: true},
+		{fullName: "c.Foo.implicit_scalar", expectedHasExplicitPresence: false},
+		{fullName: "c.Foo.required_scalar", expectedHasExplicitPresence: true},
+		{fullName: "c.Foo.message", expectedHasExplicitPresence: true},
+		{fullName: "c.Foo.repeated_scalar", expectedHasExplicitPresence: false},
+		{fullName: "c.Bar.scalar", expectedHasExplicitPresence: false},
+		{fullName: "c.Bar.explicit_scalar", expectedHasExplicitPresence: true},
+		{fullName: "c.Bar.message", expectedHasExplicitPresence: true},
+		{fullName: "c.Bar.oneof_scalar", expectedHasExplicitPresence: true},
+	}
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(string(testCase.fullName), func(t *testing.T) {
+			t.Parallel()
+			fieldDescriptor, ok := testFindPresenceDescriptor(t, files, testCase.fullName).(protoreflect.FieldDescriptor)
+			require.True(t, ok)
+			assert.Equal(t, testCase.expectedHasExplicitPresence, descriptor.HasExplicitPresence(fieldDescriptor))
+		})
+	}
+	t.Run("nil", func(t *testing.T) {
+		t.Parallel()
+		assert.False(t, descriptor.HasExplicitPresence(nil))
+	})
+}
+
+func TestIsSyntheticOneof(t *testing.T) {
+	t.Parallel()
+
+	files := testCompilePresenceSource(t)
+	testCases := []struct {
+		fullName                 protoreflect.FullName
+		expectedIsSyntheticOneof bool
+	}{
+		{fullName: "a.Foo.choice", expectedIsSyntheticOneof: false},
+		{fullName: "b.Foo._optional_scalar", expectedIsSyntheticOneof: true},
+		{fullName: "b.Foo.choice", expectedIsSyntheticOneof: false},
+		{fullName: "c.Bar.choice", expectedIsSyntheticOneof: false},
+	}
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(string(testCase.fullName), func(t *testing.T) {
+			t.Parallel()
+			oneofDescriptor, ok := testFindPresenceDescriptor(t, files, testCase.fullName).(protoreflect.OneofDescriptor)
+			require.True(t, ok)
+			assert.Equal(t, testCase.expectedIsSyntheticOneof, descriptor.IsSyntheticOneof(oneofDescriptor))
+		})
+	}
+	t.Run("nil", func(t *testing.T) {
+		t.Parallel()
+		assert.False(t, descriptor.IsSyntheticOneof(nil))
+	})
+	t.Run("editions", func(t *testing.T) {
+		t.Parallel()
+		// Files that use editions never have synthetic oneofs.
+		messageDescriptor, ok := testFindPresenceDescriptor(t, files, "c.Foo").(protoreflect.MessageDescriptor)
+		require.True(t, ok)
+		assert.Equal(t, 0, messageDescriptor.Oneofs().Len())
+	})
+}
+
+func testCompilePresenceSource(t *testing.T) *protoregistry.Files {
+	fileDescriptors, err := descriptortest.CompileStrings(
+		context.Background(),
+		map[string]string{
+			"a.proto": `syntax = "proto2";
+package a;
+message Foo {
+  optional int32 optional_scalar = 1;
+  required int32 required_scalar = 2;
+  optional Foo message = 3;
+  oneof choice {
+    int32 oneof_scalar = 4;
+  }
+  repeated int32 repeated_scalar = 5;
+  repeated Foo repeated_message = 6;
+  map<string, int32> map = 7;
+  extensions 100 to 200;
+}
+extend Foo {
+  optional int32 ext = 100;
+  repeated int32 repeated_ext = 101;
+}
+`,
+			"b.proto": `syntax = "proto3";
+package b;
+enum Enum {
+  ENUM_UNSPECIFIED = 0;
+}
+message Foo {
+  int32 scalar = 1;
+  Enum enum = 2;
+  optional int32 optional_scalar = 3;
+  Foo message = 4;
+  oneof choice {
+    int32 oneof_scalar = 5;
+  }
+  repeated int32 repeated_scalar = 6;
+  map<string, int32> map = 7;
+}
+`,
+			"c.proto": `edition = "2023";
+package c;
+message Foo {
+  int32 scalar = 1;
+  int32 implicit_scalar = 2 [features.field_presence = IMPLICIT];
+  int32 required_scalar = 3 [features.field_presence = LEGACY_REQUIRED];
+  Foo message = 4;
+  repeated int32 repeated_scalar = 5;
+}
+`,
+			"d.proto": `edition = "2023";
+package c;
+option features.field_presence = IMPLICIT;
+message Bar {
+  int32 scalar = 1;
+  int32 explicit_scalar = 2 [features.field_presence = EXPLICIT];
+  Bar message = 3;
+  oneof choice {
+    int32 oneof_scalar = 4;
+  }
+}
+`,
+		},
+	)
+	require.NoError(t, err)
+	files := &protoregistry.Files{}
+	for _, fileDescriptor := range fileDescriptors {
+		require.NoError(t, files.RegisterFile(fileDescriptor.ProtoreflectFileDescriptor()))
+	}
+	return files
+}
+
+func testFindPresenceDescriptor(t *testing.T, files *protoregistry.Files, fullName protoreflect.FullName) protoreflect.Descriptor {
+	protoreflectDescriptor, err := files.FindDescriptorByName(fullName)
+	require.NoError(t, err)
+	return protoreflectDescriptor
+}
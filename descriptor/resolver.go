@@ -0,0 +1,116 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package descriptor
+
+import (
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+)
+
+// Resolver resolves files, descriptors, and types by name over a set of FileDescriptors.
+//
+// Resolver implements protodesc.Resolver, protoregistry.MessageTypeResolver, and
+// protoregistry.ExtensionTypeResolver, and can be used wherever these are accepted, for example
+// as the Resolver of proto.UnmarshalOptions or protojson.UnmarshalOptions. Types are backed by
+// dynamicpb, see TypesForFileDescriptors.
+//
+// All methods return an error wrapping protoregistry.NotFound if the file, descriptor, or type
+// is not found.
+type Resolver interface {
+	// FindFileByPath returns the file with the given path.
+	FindFileByPath(path string) (protoreflect.FileDescriptor, error)
+	// FindDescriptorByName returns the descriptor with the given full name.
+	//
+	// This includes messages, fields, oneofs, enums, enum values, extensions, services,
+	// and methods.
+	FindDescriptorByName(name protoreflect.FullName) (protoreflect.Descriptor, error)
+	// FindMessageByName returns the message type with the given full name.
+	FindMessageByName(message protoreflect.FullName) (protoreflect.MessageType, error)
+	// FindMessageByURL returns the message type with the given type URL, for example
+	// "type.googleapis.com/foo.v1.Bar".
+	FindMessageByURL(url string) (protoreflect.MessageType, error)
+	// FindEnumByName returns the enum type with the given full name.
+	FindEnumByName(enum protoreflect.FullName) (protoreflect.EnumType, error)
+	// FindExtensionByName returns the extension type with the given full name.
+	FindExtensionByName(field protoreflect.FullName) (protoreflect.ExtensionType, error)
+	// FindExtensionByNumber returns the extension type that extends the message with the given
+	// full name with the given field number.
+	FindExtensionByNumber(message protoreflect.FullName, field protoreflect.FieldNumber) (protoreflect.ExtensionType, error)
+
+	isResolver()
+}
+
+// NewResolver returns a new Resolver for the FileDescriptors.
+//
+// All FileDescriptors should have been created together, for example from the files of a
+// single check.Request, and every file that is imported must be within the FileDescriptors.
+// Returns an error if two FileDescriptors have the same path, or declare the same name, or if
+// any file does not link.
+func NewResolver(fileDescriptors []FileDescriptor) (Resolver, error) {
+	files := &protoregistry.Files{}
+	for _, fileDescriptor := range fileDescriptors {
+		if err := fileDescriptor.Link(); err != nil {
+			return nil, err
+		}
+		if err := files.RegisterFile(fileDescriptor.ProtoreflectFileDescriptor()); err != nil {
+			return nil, err
+		}
+	}
+	types, err := TypesForFileDescriptors(fileDescriptors)
+	if err != nil {
+		return nil, err
+	}
+	return &resolver{
+		files: files,
+		types: types,
+	}, nil
+}
+
+// *** PRIVATE ***
+
+type resolver struct {
+	files *protoregistry.Files
+	types *protoregistry.Types
+}
+
+func (r *resolver) FindFileByPath(path string) (protoreflect.FileDescriptor, error) {
+	return r.files.FindFileByPath(path)
+}
+
+func (r *resolver) FindDescriptorByName(name protoreflect.FullName) (protoreflect.Descriptor, error) {
+	return r.files.FindDescriptorByName(name)
+}
+
+func (r *resolver) FindMessageByName(message protoreflect.FullName) (protoreflect.MessageType, error) {
+	return r.types.FindMessageByName(message)
+}
+
+func (r *resolver) FindMessageByURL(url string) (protoreflect.MessageType, error) {
+	return r.types.FindMessageByURL(url)
+}
+
+func (r *resolver) FindEnumByName(enum protoreflect.FullName) (protoreflect.EnumType, error) {
+	return r.types.FindEnumByName(enum)
+}
+
+func (r *resolver) FindExtensionByName(field protoreflect.FullName) (protoreflect.ExtensionType, error) {
+	return r.types.FindExtensionByName(field)
+}
+
+func (r *resolver) FindExtensionByNumber(message protoreflect.FullName, field protoreflect.FieldNumber) (protoreflect.ExtensionType, error) {
+	return r.types.FindExtensionByNumber(message, field)
+}
+
+func (*resolver) isResolver() {}
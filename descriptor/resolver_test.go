@@ -0,0 +1,203 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package descriptor_test
+
+import (
+	"context"
+	"testing"
+
+	"buf.build/go/bufplugin/descriptor"
+	"buf.build/go/bufplugin/descriptor/descriptortest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+const (
+	testResolverSourceA = `syntax = "proto2";
+package a;
+message Foo {
+  optional string name = 1;
+  optional Enum enum = 2;
+  map<string, string> labels = 3;
+  extensions 100 to 200;
+}
+enum Enum {
+  ENUM_UNSPECIFIED = 0;
+  ENUM_VALUE = 1;
+}
+`
+	testResolverSourceB = `syntax = "proto2";
+package b;
+import "a.proto";
+extend a.Foo {
+  optional string ext = 100;
+}
+service Service {
+  rpc Method(a.Foo) returns (a.Foo);
+}
+`
+)
+
+func TestResolverFind(t *testing.T) {
+	t.Parallel()
+
+	resolver, err := descriptor.NewResolver(testCompileResolverSource(t))
+	require.NoError(t, err)
+
+	fileDescriptor, err := resolver.FindFileByPath("b.proto")
+	require.NoError(t, err)
+	assert.Equal(t, "b.proto", fileDescriptor.Path())
+	for _, fullName := range []protoreflect.FullName{
+		"a.Foo",
+		"a.Foo.name",
+		"a.Enum",
+		"a.ENUM_VALUE",
+		"b.ext",
+		"b.Service",
+		"b.Service.Method",
+	} {
+		protoreflectDescriptor, err := resolver.FindDescriptorByName(fullName)
+		require.NoError(t, err, fullName)
+		assert.Equal(t, fullName, protoreflectDescriptor.FullName())
+	}
+
+	messageType, err := resolver.FindMessageByName("a.Foo")
+	require.NoError(t, err)
+	assert.Equal(t, protoreflect.FullName("a.Foo"), messageType.Descriptor().FullName())
+	messageType, err = resolver.FindMessageByURL("type.googleapis.com/a.Foo")
+	require.NoError(t, err)
+	assert.Equal(t, protoreflect.FullName("a.Foo"), messageType.Descriptor().FullName())
+	enumType, err := resolver.FindEnumByName("a.Enum")
+	require.NoError(t, err)
+	assert.Equal(t, protoreflect.FullName("a.Enum"), enumType.Descriptor().FullName())
+	extensionType, err := resolver.FindExtensionByName("b.ext")
+	require.NoError(t, err)
+	assert.Equal(t, protoreflect.FullName("b.ext"), extensionType.TypeDescriptor().FullName())
+	extensionType, err = resolver.FindExtensionByNumber("a.Foo", 100)
+	require.NoError(t, err)
+	assert.Equal(t, protoreflect.FullName("b.ext"), extensionType.TypeDescriptor().FullName())
+}
+
+func TestResolverNotFound(t *testing.T) {
+	t.Parallel()
+
+	resolver, err := descriptor.NewResolver(testCompileResolverSource(t))
+	require.NoError(t, err)
+
+	_, err = resolver.FindFileByPath("c.proto")
+	assert.ErrorIs(t, err, protoregistry.NotFound)
+	_, err = resolver.FindDescriptorByName("a.Bar")
+	assert.ErrorIs(t, err, protoregistry.NotFound)
+	_, err = resolver.FindMessageByName("a.Bar")
+	assert.ErrorIs(t, err, protoregistry.NotFound)
+	_, err = resolver.FindMessageByURL("type.googleapis.com/a.Bar")
+	assert.ErrorIs(t, err, protoregistry.NotFound)
+	_, err = resolver.FindEnumByName("a.Bar")
+	assert.ErrorIs(t, err, protoregistry.NotFound)
+	_, err = resolver.FindExtensionByName("b.other_ext")
+	assert.ErrorIs(t, err, protoregistry.NotFound)
+	_, err = resolver.FindExtensionByNumber("a.Foo", 101)
+	assert.ErrorIs(t, err, protoregistry.NotFound)
+	// Map entry messages are descriptors, but not message types.
+	_, err = resolver.FindDescriptorByName("a.Foo.LabelsEntry")
+	require.NoError(t, err)
+	_, err = resolver.FindMessageByName("a.Foo.LabelsEntry")
+	assert.ErrorIs(t, err, protoregistry.NotFound)
+}
+
+func TestResolverUnmarshal(t *testing.T) {
+	t.Parallel()
+
+	resolver, err := descriptor.NewResolver(testCompileResolverSource(t))
+	require.NoError(t, err)
+	messageType, err := resolver.FindMessageByName("a.Foo")
+	require.NoError(t, err)
+
+	message := messageType.New().Interface()
+	require.NoError(
+		t,
+		protojson.UnmarshalOptions{Resolver: resolver}.Unmarshal(
+			[]byte(`{"name": "foo", "enum": "ENUM_VALUE", "[b.ext]": "bar"}`),
+			message,
+		),
+	)
+	data, err := proto.Marshal(message)
+	require.NoError(t, err)
+
+	unmarshaledMessage := dynamicpb.NewMessage(messageType.Descriptor())
+	require.NoError(t, proto.UnmarshalOptions{Resolver: resolver}.Unmarshal(data, unmarshaledMessage))
+	assert.Empty(t, unmarshaledMessage.GetUnknown())
+	extensionType, err := resolver.FindExtensionByName("b.ext")
+	require.NoError(t, err)
+	assert.Equal(t, "bar", unmarshaledMessage.Get(extensionType.TypeDescriptor()).String())
+	assert.True(t, proto.Equal(message, unmarshaledMessage))
+}
+
+func TestNewResolverDuplicates(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	testCases := []struct {
+		name          string
+		pathToSources []map[string]string
+	}{
+		{
+			name: "duplicate_path",
+			pathToSources: []map[string]string{
+				{"a.proto": testResolverSourceA},
+				{"a.proto": testResolverSourceA},
+			},
+		},
+		{
+			name: "duplicate_name",
+			pathToSources: []map[string]string{
+				{"a.proto": `syntax = "proto3"; package a; message Foo {}`},
+				{"b.proto": `syntax = "proto3"; package a; message Foo {}`},
+			},
+		},
+	}
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+			var fileDescriptors []descriptor.FileDescriptor
+			for _, pathToSource := range testCase.pathToSources {
+				compiledFileDescriptors, err := descriptortest.CompileStrings(ctx, pathToSource)
+				require.NoError(t, err)
+				fileDescriptors = append(fileDescriptors, compiledFileDescriptors...)
+			}
+			_, err := descriptor.NewResolver(fileDescriptors)
+			require.Error(t, err)
+		})
+	}
+}
+
+func testCompileResolverSource(t *testing.T) []descriptor.FileDescriptor {
+	fileDescriptors, err := descriptortest.CompileStrings(
+		context.Background(),
+		map[string]string{
+			"a.proto": testResolverSourceA,
+			"b.proto": testResolverSourceB,
+		},
+	)
+	require.NoError(t, err)
+	require.Len(t, fileDescriptors, 2)
+	return fileDescriptors
+}
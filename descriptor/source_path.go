@@ -0,0 +1,409 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package descriptor
+
+import (
+	"fmt"
+	"slices"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+const (
+	// The field number of the name within all descriptor protos except
+	// descriptorpb.FileDescriptorProto.
+	nameFieldNumber = 1
+	// Field numbers within descriptorpb.FileDescriptorProto.
+	filePackageFieldNumber     = 2
+	fileMessageTypeFieldNumber = 4
+	fileEnumTypeFieldNumber    = 5
+	fileServiceFieldNumber     = 6
+	fileExtensionFieldNumber   = 7
+	fileOptionsFieldNumber     = 8
+	// Field numbers within descriptorpb.DescriptorProto.
+	messageFieldFieldNumber      = 2
+	messageNestedTypeFieldNumber = 3
+	messageEnumTypeFieldNumber   = 4
+	messageExtensionFieldNumber  = 6
+	messageOptionsFieldNumber    = 7
+	messageOneofDeclFieldNumber  = 8
+	// Field numbers within descriptorpb.FieldDescriptorProto.
+	fieldOptionsFieldNumber = 8
+	// Field numbers within descriptorpb.OneofDescriptorProto.
+	oneofOptionsFieldNumber = 2
+	// Field numbers within descriptorpb.EnumDescriptorProto.
+	enumValueFieldNumber   = 2
+	enumOptionsFieldNumber = 3
+	// Field numbers within descriptorpb.EnumValueDescriptorProto.
+	enumValueOptionsFieldNumber = 3
+	// Field numbers within descriptorpb.ServiceDescriptorProto.
+	serviceMethodFieldNumber  = 2
+	serviceOptionsFieldNumber = 3
+	// Field numbers within descriptorpb.MethodDescriptorProto.
+	methodOptionsFieldNumber = 4
+)
+
+// PathForMessage returns the SourcePath of the message at the index within the parent.
+//
+// The parentPath is the SourcePath of a message for nested messages, or empty for
+// messages declared at the top level of a file.
+func PathForMessage(parentPath protoreflect.SourcePath, index int) protoreflect.SourcePath {
+	if len(parentPath) == 0 {
+		return appendPath(parentPath, fileMessageTypeFieldNumber, index)
+	}
+	return appendPath(parentPath, messageNestedTypeFieldNumber, index)
+}
+
+// PathForField returns the SourcePath of the field at the index within the message.
+//
+// For extensions, use PathForExtension.
+func PathForField(messagePath protoreflect.SourcePath, index int) protoreflect.SourcePath {
+	return appendPath(messagePath, messageFieldFieldNumber, index)
+}
+
+// PathForOneof returns the SourcePath of the oneof at the index within the message.
+func PathForOneof(messagePath protoreflect.SourcePath, index int) protoreflect.SourcePath {
+	return appendPath(messagePath, messageOneofDeclFieldNumber, index)
+}
+
+// PathForExtension returns the SourcePath of the extension at the index within the parent.
+//
+// The parentPath is the SourcePath of a message for extensions declared within a message, or
+// empty for extensions declared at the top level of a file.
+func PathForExtension(parentPath protoreflect.SourcePath, index int) protoreflect.SourcePath {
+	if len(parentPath) == 0 {
+		return appendPath(parentPath, fileExtensionFieldNumber, index)
+	}
+	return appendPath(parentPath, messageExtensionFieldNumber, index)
+}
+
+// PathForEnum returns the SourcePath of the enum at the index within the parent.
+//
+// The parentPath is the SourcePath of a message for nested enums, or empty for enums
+// declared at the top level of a file.
+func PathForEnum(parentPath protoreflect.SourcePath, index int) protoreflect.SourcePath {
+	if len(parentPath) == 0 {
+		return appendPath(parentPath, fileEnumTypeFieldNumber, index)
+	}
+	return appendPath(parentPath, messageEnumTypeFieldNumber, index)
+}
+
+// PathForEnumValue returns the SourcePath of the value at the index within the enum.
+func PathForEnumValue(enumPath protoreflect.SourcePath, index int) protoreflect.SourcePath {
+	return appendPath(enumPath, enumValueFieldNumber, index)
+}
+
+// PathForService returns the SourcePath of the service at the index within a file.
+func PathForService(index int) protoreflect.SourcePath {
+	return appendPath(nil, fileServiceFieldNumber, index)
+}
+
+// PathForMethod returns the SourcePath of the method at the index within the service.
+func PathForMethod(servicePath protoreflect.SourcePath, index int) protoreflect.SourcePath {
+	return appendPath(servicePath, serviceMethodFieldNumber, index)
+}
+
+// PathForOption returns the SourcePath of an option on the Descriptor at the descriptorPath.
+//
+// The optionPath is the path within the options message of the Descriptor, for example
+// the field number of the option, followed by the field numbers of any nested fields
+// and the indexes within any repeated fields. For example, the SourcePath of the
+// deprecated option of the first field of the first message within a file is:
+//
+//	descriptor.PathForOption(
+//		descriptor.PathForField(descriptor.PathForMessage(nil, 0), 0),
+//		3, // FieldOptions.deprecated
+//	)
+//
+// An empty descriptorPath refers to the file. Returns an error if the descriptorPath is
+// not the SourcePath of a Descriptor.
+func PathForOption(descriptorPath protoreflect.SourcePath, optionPath ...int32) (protoreflect.SourcePath, error) {
+	elements, rest := parseSourcePath(descriptorPath)
+	if len(rest) > 0 {
+		return nil, fmt.Errorf("source path %v is not the path of a descriptor", descriptorPath)
+	}
+	path := make(protoreflect.SourcePath, 0, len(descriptorPath)+1+len(optionPath))
+	path = append(path, descriptorPath...)
+	path = append(path, optionsFieldNumberForKind(innermostSourcePathKind(elements)))
+	return append(path, optionPath...), nil
+}
+
+// PathForDescriptor returns the SourcePath of the Descriptor within its file.
+//
+// This is the same SourcePath that is used to look up the SourceLocation of the Descriptor.
+// Returns an empty SourcePath for a FileDescriptor, and nil for nil or unknown Descriptors.
+func PathForDescriptor(descriptor protoreflect.Descriptor) protoreflect.SourcePath {
+	switch descriptor := descriptor.(type) {
+	case protoreflect.FileDescriptor:
+		return protoreflect.SourcePath{}
+	case protoreflect.MessageDescriptor:
+		return pathForChild(descriptor, PathForMessage)
+	case protoreflect.FieldDescriptor:
+		if descriptor.IsExtension() {
+			return pathForChild(descriptor, PathForExtension)
+		}
+		return pathForChild(descriptor, PathForField)
+	case protoreflect.OneofDescriptor:
+		return pathForChild(descriptor, PathForOneof)
+	case protoreflect.EnumDescriptor:
+		return pathForChild(descriptor, PathForEnum)
+	case protoreflect.EnumValueDescriptor:
+		return pathForChild(descriptor, PathForEnumValue)
+	case protoreflect.ServiceDescriptor:
+		return PathForService(descriptor.Index())
+	case protoreflect.MethodDescriptor:
+		return pathForChild(descriptor, PathForMethod)
+	default:
+		return nil
+	}
+}
+
+// DescriptorForPath returns the innermost Descriptor that the SourcePath refers to within the file,
+// along with the remainder of the SourcePath after the Descriptor.
+//
+// The remainder is empty if the SourcePath refers to the Descriptor itself, and otherwise refers
+// to an element of the Descriptor, such as its name or options. For example, for the SourcePath
+// [4, 0, 2, 1, 1], this returns the second field of the first message, with the remainder [1],
+// which is the name of the field. An empty SourcePath refers to the file itself.
+//
+// Returns an error if an index within the SourcePath is out of range.
+func DescriptorForPath(
+	fileDescriptor protoreflect.FileDescriptor,
+	path protoreflect.SourcePath,
+) (protoreflect.Descriptor, protoreflect.SourcePath, error) {
+	elements, rest := parseSourcePath(path)
+	var descriptor protoreflect.Descriptor = fileDescriptor
+	for _, element := range elements {
+		child, ok := childDescriptor(descriptor, element)
+		if !ok {
+			return nil, nil, fmt.Errorf("index %d out of range within source path %v", element.index, path)
+		}
+		descriptor = child
+	}
+	return descriptor, slices.Clone(rest), nil
+}
+
+// PathForName returns the SourcePath of the name of the Descriptor within its file.
+//
+// The SourceLocation for this SourcePath spans only the declared identifier, rather than the
+// whole declaration. For a FileDescriptor, this is the SourcePath of the package declaration.
+// Returns nil for nil or unknown Descriptors.
+func PathForName(descriptor protoreflect.Descriptor) protoreflect.SourcePath {
+	if _, ok := descriptor.(protoreflect.FileDescriptor); ok {
+		return protoreflect.SourcePath{filePackageFieldNumber}
+	}
+	path := PathForDescriptor(descriptor)
+	if path == nil {
+		return nil
+	}
+	return append(path, nameFieldNumber)
+}
+
+// *** PRIVATE ***
+
+type sourcePathKind int
+
+const (
+	sourcePathKindMessage sourcePathKind = iota + 1
+	sourcePathKindField
+	sourcePathKindExtension
+	sourcePathKindOneof
+	sourcePathKindEnum
+	sourcePathKindEnumValue
+	sourcePathKindService
+	sourcePathKindMethod
+)
+
+// sourcePathKindFile is the kind of the file, which is never the kind of a sourcePathElement.
+const sourcePathKindFile sourcePathKind = 0
+
+// sourcePathElement is a single Descriptor within a SourcePath.
+type sourcePathElement struct {
+	kind  sourcePathKind
+	index int
+}
+
+// parseSourcePath parses the Descriptors within the SourcePath, starting from the file.
+//
+// It returns the Descriptors in order, and the remainder of the SourcePath after the innermost
+// Descriptor. The kinds of the elements are always valid for their parents, so that the
+// elements can be resolved against a file without further checks other than the indexes.
+func parseSourcePath(path protoreflect.SourcePath) ([]sourcePathElement, protoreflect.SourcePath) {
+	var elements []sourcePathElement
+	parentKind := sourcePathKindFile
+	for len(path) >= 2 {
+		kind := childSourcePathKind(parentKind, path[0])
+		if kind == 0 {
+			break
+		}
+		elements = append(elements, sourcePathElement{kind: kind, index: int(path[1])})
+		parentKind = kind
+		path = path[2:]
+	}
+	return elements, path
+}
+
+// childSourcePathKind returns the kind of the child Descriptor at the field number within
+// a Descriptor of the parent kind, or 0 if the field number does not refer to a Descriptor.
+func childSourcePathKind(parentKind sourcePathKind, fieldNumber int32) sourcePathKind {
+	switch parentKind {
+	case sourcePathKindFile:
+		switch fieldNumber {
+		case fileMessageTypeFieldNumber:
+			return sourcePathKindMessage
+		case fileEnumTypeFieldNumber:
+			return sourcePathKindEnum
+		case fileServiceFieldNumber:
+			return sourcePathKindService
+		case fileExtensionFieldNumber:
+			return sourcePathKindExtension
+		}
+	case sourcePathKindMessage:
+		switch fieldNumber {
+		case messageFieldFieldNumber:
+			return sourcePathKindField
+		case messageNestedTypeFieldNumber:
+			return sourcePathKindMessage
+		case messageEnumTypeFieldNumber:
+			return sourcePathKindEnum
+		case messageExtensionFieldNumber:
+			return sourcePathKindExtension
+		case messageOneofDeclFieldNumber:
+			return sourcePathKindOneof
+		}
+	case sourcePathKindEnum:
+		if fieldNumber == enumValueFieldNumber {
+			return sourcePathKindEnumValue
+		}
+	case sourcePathKindService:
+		if fieldNumber == serviceMethodFieldNumber {
+			return sourcePathKindMethod
+		}
+	}
+	return 0
+}
+
+// innermostSourcePathKind returns the kind of the last element, or sourcePathKindFile if there are no elements.
+func innermostSourcePathKind(elements []sourcePathElement) sourcePathKind {
+	if len(elements) == 0 {
+		return sourcePathKindFile
+	}
+	return elements[len(elements)-1].kind
+}
+
+// optionsFieldNumberForKind returns the field number of the options within a Descriptor of the kind.
+func optionsFieldNumberForKind(kind sourcePathKind) int32 {
+	switch kind {
+	case sourcePathKindMessage:
+		return messageOptionsFieldNumber
+	case sourcePathKindField, sourcePathKindExtension:
+		return fieldOptionsFieldNumber
+	case sourcePathKindOneof:
+		return oneofOptionsFieldNumber
+	case sourcePathKindEnum:
+		return enumOptionsFieldNumber
+	case sourcePathKindEnumValue:
+		return enumValueOptionsFieldNumber
+	case sourcePathKindService:
+		return serviceOptionsFieldNumber
+	case sourcePathKindMethod:
+		return methodOptionsFieldNumber
+	default:
+		return fileOptionsFieldNumber
+	}
+}
+
+// childDescriptor returns the child Descriptor of the parent for the element, or false if
+// the index of the element is out of range.
+func childDescriptor(parent protoreflect.Descriptor, element sourcePathElement) (protoreflect.Descriptor, bool) {
+	index := element.index
+	inRange := func(length int) bool {
+		return index >= 0 && index < length
+	}
+	switch parent := parent.(type) {
+	case protoreflect.FileDescriptor:
+		switch element.kind {
+		case sourcePathKindMessage:
+			if messages := parent.Messages(); inRange(messages.Len()) {
+				return messages.Get(index), true
+			}
+		case sourcePathKindEnum:
+			if enums := parent.Enums(); inRange(enums.Len()) {
+				return enums.Get(index), true
+			}
+		case sourcePathKindExtension:
+			if extensions := parent.Extensions(); inRange(extensions.Len()) {
+				return extensions.Get(index), true
+			}
+		case sourcePathKindService:
+			if services := parent.Services(); inRange(services.Len()) {
+				return services.Get(index), true
+			}
+		}
+	case protoreflect.MessageDescriptor:
+		switch element.kind {
+		case sourcePathKindMessage:
+			if messages := parent.Messages(); inRange(messages.Len()) {
+				return messages.Get(index), true
+			}
+		case sourcePathKindField:
+			if fields := parent.Fields(); inRange(fields.Len()) {
+				return fields.Get(index), true
+			}
+		case sourcePathKindExtension:
+			if extensions := parent.Extensions(); inRange(extensions.Len()) {
+				return extensions.Get(index), true
+			}
+		case sourcePathKindOneof:
+			if oneofs := parent.Oneofs(); inRange(oneofs.Len()) {
+				return oneofs.Get(index), true
+			}
+		case sourcePathKindEnum:
+			if enums := parent.Enums(); inRange(enums.Len()) {
+				return enums.Get(index), true
+			}
+		}
+	case protoreflect.EnumDescriptor:
+		if values := parent.Values(); element.kind == sourcePathKindEnumValue && inRange(values.Len()) {
+			return values.Get(index), true
+		}
+	case protoreflect.ServiceDescriptor:
+		if methods := parent.Methods(); element.kind == sourcePathKindMethod && inRange(methods.Len()) {
+			return methods.Get(index), true
+		}
+	}
+	return nil, false
+}
+
+// pathForChild returns the SourcePath of the Descriptor using the SourcePath of its parent.
+func pathForChild(
+	descriptor protoreflect.Descriptor,
+	pathFor func(parentPath protoreflect.SourcePath, index int) protoreflect.SourcePath,
+) protoreflect.SourcePath {
+	parentPath := PathForDescriptor(descriptor.Parent())
+	if parentPath == nil {
+		return nil
+	}
+	return pathFor(parentPath, descriptor.Index())
+}
+
+// appendPath returns a new SourcePath with the field number and index appended to the path.
+//
+// The path is never modified.
+func appendPath(path protoreflect.SourcePath, fieldNumber int32, index int) protoreflect.SourcePath {
+	newPath := make(protoreflect.SourcePath, len(path), len(path)+2)
+	copy(newPath, path)
+	return append(newPath, fieldNumber, int32(index))
+}
@@ -0,0 +1,247 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package descriptor
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// SourcePathIndex is an index from the SourcePaths of the SourceCodeInfo of a FileDescriptor
+// to the Descriptors they describe.
+//
+// This allows SourcePaths from external tools to be resolved back to Descriptors.
+type SourcePathIndex interface {
+	// Lookup returns the SourcePathTarget for the SourcePath.
+	//
+	// If the SourcePath does not have a location within the SourceCodeInfo, the nearest ancestor
+	// of the SourcePath that has a location is used. Returns false if neither the SourcePath nor
+	// any of its ancestors have a location.
+	Lookup(path protoreflect.SourcePath) (SourcePathTarget, bool)
+
+	isSourcePathIndex()
+}
+
+// NewSourcePathIndex returns a new SourcePathIndex for the FileDescriptor.
+//
+// Returns an error if a location within the SourceCodeInfo does not refer to an element
+// of the file, or if the file does not link.
+func NewSourcePathIndex(fileDescriptor FileDescriptor) (SourcePathIndex, error) {
+	if err := fileDescriptor.Link(); err != nil {
+		return nil, err
+	}
+	protoreflectFileDescriptor := fileDescriptor.ProtoreflectFileDescriptor()
+	optionFieldResolver := newOptionFieldResolver(protoreflectFileDescriptor)
+	sourceLocations := protoreflectFileDescriptor.SourceLocations()
+	pathKeyToTarget := make(map[string]*sourcePathTarget, sourceLocations.Len())
+	for i := 0; i < sourceLocations.Len(); i++ {
+		sourceLocation := sourceLocations.Get(i)
+		key := sourcePathKey(sourceLocation.Path)
+		if _, ok := pathKeyToTarget[key]; ok {
+			// Multiple locations are allowed for the same path, for example for
+			// repeated options. The first location is the primary location.
+			continue
+		}
+		descriptor, rest, err := DescriptorForPath(protoreflectFileDescriptor, sourceLocation.Path)
+		if err != nil {
+			return nil, err
+		}
+		target := &sourcePathTarget{
+			descriptor:     descriptor,
+			sourceLocation: sourceLocation,
+		}
+		elements, _ := parseSourcePath(sourceLocation.Path)
+		kind := innermostSourcePathKind(elements)
+		if len(rest) >= 2 && rest[0] == optionsFieldNumberForKind(kind) {
+			target.optionField = optionFieldResolver.resolve(kind, protoreflect.FieldNumber(rest[1]))
+		}
+		pathKeyToTarget[key] = target
+	}
+	return &sourcePathIndex{
+		pathKeyToTarget: pathKeyToTarget,
+	}, nil
+}
+
+// SourcePathTarget is the target of a SourcePath within a SourcePathIndex.
+type SourcePathTarget interface {
+	// Descriptor returns the innermost Descriptor that contains the location.
+	//
+	// This is the FileDescriptor for locations that are not within any other Descriptor,
+	// such as the package or imports.
+	Descriptor() protoreflect.Descriptor
+	// OptionField returns the the field of the option that the location is within, if any.
+	//
+	// This is set if the location is within the options of the Descriptor. For custom options,
+	// this is the extension, and otherwise this is the field of the options message, for
+	// example the deprecated field of google.protobuf.FieldOptions. For locations within
+	// nested fields of an option, this is the top-level option. Returns nil if the location
+	// is not within an option, or if the option is unknown.
+	OptionField() protoreflect.FieldDescriptor
+	// SourceLocation returns the SourceLocation that was matched.
+	//
+	// The path of the SourceLocation is the SourcePath given to Lookup, or its nearest ancestor
+	// with a location.
+	SourceLocation() protoreflect.SourceLocation
+
+	isSourcePathTarget()
+}
+
+// *** PRIVATE ***
+
+type sourcePathIndex struct {
+	pathKeyToTarget map[string]*sourcePathTarget
+}
+
+func (s *sourcePathIndex) Lookup(path protoreflect.SourcePath) (SourcePathTarget, bool) {
+	for length := len(path); length >= 0; length-- {
+		if target, ok := s.pathKeyToTarget[sourcePathKey(path[:length])]; ok {
+			return target, true
+		}
+	}
+	return nil, false
+}
+
+func (*sourcePathIndex) isSourcePathIndex() {}
+
+type sourcePathTarget struct {
+	descriptor     protoreflect.Descriptor
+	optionField    protoreflect.FieldDescriptor
+	sourceLocation protoreflect.SourceLocation
+}
+
+func (s *sourcePathTarget) Descriptor() protoreflect.Descriptor {
+	return s.descriptor
+}
+
+func (s *sourcePathTarget) OptionField() protoreflect.FieldDescriptor {
+	return s.optionField
+}
+
+func (s *sourcePathTarget) SourceLocation() protoreflect.SourceLocation {
+	sourceLocation := s.sourceLocation
+	sourceLocation.Path = slices.Clone(sourceLocation.Path)
+	sourceLocation.LeadingDetachedComments = slices.Clone(sourceLocation.LeadingDetachedComments)
+	return sourceLocation
+}
+
+func (*sourcePathTarget) isSourcePathTarget() {}
+
+// optionFieldResolver resolves the fields of options, including custom options declared
+// within the file or its transitive imports.
+type optionFieldResolver struct {
+	fileDescriptor protoreflect.FileDescriptor
+	// extensions is populated on first use.
+	extensions map[protoreflect.FullName]map[protoreflect.FieldNumber]protoreflect.FieldDescriptor
+}
+
+func newOptionFieldResolver(fileDescriptor protoreflect.FileDescriptor) *optionFieldResolver {
+	return &optionFieldResolver{
+		fileDescriptor: fileDescriptor,
+	}
+}
+
+func (o *optionFieldResolver) resolve(kind sourcePathKind, number protoreflect.FieldNumber) protoreflect.FieldDescriptor {
+	optionsMessageDescriptor := optionsMessageDescriptorForKind(kind)
+	if fieldDescriptor := optionsMessageDescriptor.Fields().ByNumber(number); fieldDescriptor != nil {
+		return fieldDescriptor
+	}
+	if o.extensions == nil {
+		o.extensions = make(map[protoreflect.FullName]map[protoreflect.FieldNumber]protoreflect.FieldDescriptor)
+		rangeExtensionsWithImports(o.fileDescriptor, o.addExtension)
+	}
+	return o.extensions[optionsMessageDescriptor.FullName()][number]
+}
+
+func (o *optionFieldResolver) addExtension(extensionDescriptor protoreflect.ExtensionDescriptor) {
+	extendee := extensionDescriptor.ContainingMessage().FullName()
+	numberToExtension, ok := o.extensions[extendee]
+	if !ok {
+		numberToExtension = make(map[protoreflect.FieldNumber]protoreflect.FieldDescriptor)
+		o.extensions[extendee] = numberToExtension
+	}
+	numberToExtension[extensionDescriptor.Number()] = extensionDescriptor
+}
+
+// rangeExtensionsWithImports calls f for every extension declared within the file or its
+// transitive imports, visiting each file once.
+func rangeExtensionsWithImports(
+	fileDescriptor protoreflect.FileDescriptor,
+	f func(protoreflect.ExtensionDescriptor),
+) {
+	rangeExtensionsWithImportsRec(fileDescriptor, f, make(map[string]struct{}))
+}
+
+func rangeExtensionsWithImportsRec(
+	fileDescriptor protoreflect.FileDescriptor,
+	f func(protoreflect.ExtensionDescriptor),
+	seen map[string]struct{},
+) {
+	if _, ok := seen[fileDescriptor.Path()]; ok {
+		return
+	}
+	seen[fileDescriptor.Path()] = struct{}{}
+	// Errors are never returned from the VisitorFuncs.
+	_ = walkContainer(
+		fileDescriptor,
+		&VisitorFuncs{
+			Field: func(fieldDescriptor protoreflect.FieldDescriptor) error {
+				if fieldDescriptor.IsExtension() {
+					f(fieldDescriptor)
+				}
+				return nil
+			},
+		},
+	)
+	imports := fileDescriptor.Imports()
+	for i := 0; i < imports.Len(); i++ {
+		rangeExtensionsWithImportsRec(imports.Get(i).FileDescriptor, f, seen)
+	}
+}
+
+func optionsMessageDescriptorForKind(kind sourcePathKind) protoreflect.MessageDescriptor {
+	switch kind {
+	case sourcePathKindMessage:
+		return (*descriptorpb.MessageOptions)(nil).ProtoReflect().Descriptor()
+	case sourcePathKindField, sourcePathKindExtension:
+		return (*descriptorpb.FieldOptions)(nil).ProtoReflect().Descriptor()
+	case sourcePathKindOneof:
+		return (*descriptorpb.OneofOptions)(nil).ProtoReflect().Descriptor()
+	case sourcePathKindEnum:
+		return (*descriptorpb.EnumOptions)(nil).ProtoReflect().Descriptor()
+	case sourcePathKindEnumValue:
+		return (*descriptorpb.EnumValueOptions)(nil).ProtoReflect().Descriptor()
+	case sourcePathKindService:
+		return (*descriptorpb.ServiceOptions)(nil).ProtoReflect().Descriptor()
+	case sourcePathKindMethod:
+		return (*descriptorpb.MethodOptions)(nil).ProtoReflect().Descriptor()
+	default:
+		return (*descriptorpb.FileOptions)(nil).ProtoReflect().Descriptor()
+	}
+}
+
+func sourcePathKey(path protoreflect.SourcePath) string {
+	var sb strings.Builder
+	for i, element := range path {
+		if i > 0 {
+			_, _ = sb.WriteRune(',')
+		}
+		_, _ = sb.WriteString(strconv.Itoa(int(element)))
+	}
+	return sb.String()
+}
@@ -0,0 +1,180 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package descriptor_test
+
+import (
+	"context"
+	"testing"
+
+	"buf.build/go/bufplugin/descriptor"
+	"buf.build/go/bufplugin/descriptor/descriptortest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+func TestNewSourcePathIndex(t *testing.T) {
+	t.Parallel()
+
+	fileDescriptors, err := descriptortest.CompileStrings(
+		context.Background(),
+		map[string]string{
+			"options.proto": `syntax = "proto3";
+package options;
+import "google/protobuf/descriptor.proto";
+message Rule {
+  string name = 1;
+}
+extend google.protobuf.FieldOptions {
+  Rule rule = 50000;
+}
+`,
+			"a.proto": `syntax = "proto3";
+package a;
+import "options.proto";
+message Foo {
+  string bar = 1 [
+    deprecated = true,
+    (options.rule).name = "baz"
+  ];
+  message Nested {}
+}
+`,
+		},
+	)
+	require.NoError(t, err)
+	sourcePathIndex, err := descriptor.NewSourcePathIndex(testFileDescriptorForPath(t, fileDescriptors, "a.proto"))
+	require.NoError(t, err)
+	testCases := []struct {
+		name                string
+		path                protoreflect.SourcePath
+		expectedFullName    protoreflect.FullName
+		expectedOptionField protoreflect.FullName
+		expectedPath        protoreflect.SourcePath
+	}{
+		{
+			name:             "file",
+			path:             protoreflect.SourcePath{},
+			expectedFullName: "a",
+			expectedPath:     protoreflect.SourcePath{},
+		},
+		{
+			name:             "package",
+			path:             protoreflect.SourcePath{2},
+			expectedFullName: "a",
+			expectedPath:     protoreflect.SourcePath{2},
+		},
+		{
+			name:             "message",
+			path:             protoreflect.SourcePath{4, 0},
+			expectedFullName: "a.Foo",
+			expectedPath:     protoreflect.SourcePath{4, 0},
+		},
+		{
+			name:             "field_name",
+			path:             protoreflect.SourcePath{4, 0, 2, 0, 1},
+			expectedFullName: "a.Foo.bar",
+			expectedPath:     protoreflect.SourcePath{4, 0, 2, 0, 1},
+		},
+		{
+			name:             "nested_message",
+			path:             protoreflect.SourcePath{4, 0, 3, 0},
+			expectedFullName: "a.Foo.Nested",
+			expectedPath:     protoreflect.SourcePath{4, 0, 3, 0},
+		},
+		{
+			name:                "option",
+			path:                protoreflect.SourcePath{4, 0, 2, 0, 8, 3},
+			expectedFullName:    "a.Foo.bar",
+			expectedOptionField: "google.protobuf.FieldOptions.deprecated",
+			expectedPath:        protoreflect.SourcePath{4, 0, 2, 0, 8, 3},
+		},
+		{
+			name:                "custom_option",
+			path:                protoreflect.SourcePath{4, 0, 2, 0, 8, 50000, 1},
+			expectedFullName:    "a.Foo.bar",
+			expectedOptionField: "options.rule",
+			expectedPath:        protoreflect.SourcePath{4, 0, 2, 0, 8, 50000, 1},
+		},
+		{
+			// The nearest ancestor with a location is used.
+			name:             "ancestor",
+			path:             protoreflect.SourcePath{4, 0, 3, 0, 7, 3},
+			expectedFullName: "a.Foo.Nested",
+			expectedPath:     protoreflect.SourcePath{4, 0, 3, 0},
+		},
+	}
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+			sourcePathTarget, ok := sourcePathIndex.Lookup(testCase.path)
+			require.True(t, ok)
+			assert.Equal(t, testCase.expectedFullName, sourcePathTarget.Descriptor().FullName())
+			if testCase.expectedOptionField == "" {
+				assert.Nil(t, sourcePathTarget.OptionField())
+			} else {
+				require.NotNil(t, sourcePathTarget.OptionField())
+				assert.Equal(t, testCase.expectedOptionField, sourcePathTarget.OptionField().FullName())
+			}
+			assert.Equal(t, testCase.expectedPath, sourcePathTarget.SourceLocation().Path)
+		})
+	}
+}
+
+func TestNewSourcePathIndexWithoutLocations(t *testing.T) {
+	t.Parallel()
+
+	newFileDescriptor := func(sourceCodeInfo *descriptorpb.SourceCodeInfo) descriptor.FileDescriptor {
+		fileDescriptorProto := testNewFileDescriptorProto("a.proto", "a", testNewMessageDescriptorProto("Foo"))
+		fileDescriptorProto.SourceCodeInfo = sourceCodeInfo
+		fileDescriptors, err := descriptor.FileDescriptorsForProtoFileDescriptors(
+			testNewProtoFileDescriptors(fileDescriptorProto),
+		)
+		require.NoError(t, err)
+		return fileDescriptors[0]
+	}
+
+	sourcePathIndex, err := descriptor.NewSourcePathIndex(newFileDescriptor(&descriptorpb.SourceCodeInfo{}))
+	require.NoError(t, err)
+	_, ok := sourcePathIndex.Lookup(protoreflect.SourcePath{4, 0})
+	assert.False(t, ok)
+
+	// Locations must refer to elements of the file.
+	_, err = descriptor.NewSourcePathIndex(
+		newFileDescriptor(
+			&descriptorpb.SourceCodeInfo{
+				Location: []*descriptorpb.SourceCodeInfo_Location{
+					{
+						Path: []int32{4, 1},
+						Span: []int32{0, 0, 1},
+					},
+				},
+			},
+		),
+	)
+	require.Error(t, err)
+}
+
+func testFileDescriptorForPath(t *testing.T, fileDescriptors []descriptor.FileDescriptor, path string) descriptor.FileDescriptor {
+	for _, fileDescriptor := range fileDescriptors {
+		if fileDescriptor.FileDescriptorProto().GetName() == path {
+			return fileDescriptor
+		}
+	}
+	require.Fail(t, "file not found: "+path)
+	return nil
+}
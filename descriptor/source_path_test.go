@@ -0,0 +1,153 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package descriptor_test
+
+import (
+	"slices"
+	"testing"
+
+	"buf.build/go/bufplugin/descriptor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+)
+
+func TestPathForDescriptor(t *testing.T) {
+	t.Parallel()
+
+	protoreflectFileDescriptor := testCompileWalkSource(t).ProtoreflectFileDescriptor()
+	testCases := []struct {
+		name         string
+		fullName     protoreflect.FullName
+		expectedPath protoreflect.SourcePath
+	}{
+		{name: "message", fullName: "a.Foo", expectedPath: protoreflect.SourcePath{4, 0}},
+		{name: "nested_message", fullName: "a.Foo.Nested", expectedPath: protoreflect.SourcePath{4, 0, 3, 0}},
+		{name: "field", fullName: "a.Foo.field", expectedPath: protoreflect.SourcePath{4, 0, 2, 0}},
+		{name: "nested_field", fullName: "a.Foo.Nested.nested_field", expectedPath: protoreflect.SourcePath{4, 0, 3, 0, 2, 0}},
+		{name: "oneof", fullName: "a.Foo.choice", expectedPath: protoreflect.SourcePath{4, 0, 8, 0}},
+		{name: "extension", fullName: "a.ext", expectedPath: protoreflect.SourcePath{7, 0}},
+		{name: "nested_extension", fullName: "a.Foo.nested_ext", expectedPath: protoreflect.SourcePath{4, 0, 6, 0}},
+		{name: "enum", fullName: "a.Enum", expectedPath: protoreflect.SourcePath{5, 0}},
+		{name: "nested_enum", fullName: "a.Foo.NestedEnum", expectedPath: protoreflect.SourcePath{4, 0, 4, 0}},
+		{name: "enum_value", fullName: "a.ENUM_VALUE", expectedPath: protoreflect.SourcePath{5, 0, 2, 1}},
+		{name: "service", fullName: "a.Service", expectedPath: protoreflect.SourcePath{6, 0}},
+		{name: "method", fullName: "a.Service.Method", expectedPath: protoreflect.SourcePath{6, 0, 2, 0}},
+	}
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+			protoreflectDescriptor := testFindDescriptor(t, protoreflectFileDescriptor, testCase.fullName)
+			path := descriptor.PathForDescriptor(protoreflectDescriptor)
+			assert.Equal(t, testCase.expectedPath, path)
+			assert.Equal(t, append(slices.Clone(testCase.expectedPath), 1), descriptor.PathForName(protoreflectDescriptor))
+			// The path round-trips.
+			actualDescriptor, rest, err := descriptor.DescriptorForPath(protoreflectFileDescriptor, path)
+			require.NoError(t, err)
+			assert.Empty(t, rest)
+			assert.Equal(t, testCase.fullName, actualDescriptor.FullName())
+		})
+	}
+	assert.Equal(t, protoreflect.SourcePath{}, descriptor.PathForDescriptor(protoreflectFileDescriptor))
+	assert.Equal(t, protoreflect.SourcePath{2}, descriptor.PathForName(protoreflectFileDescriptor))
+	assert.Nil(t, descriptor.PathForDescriptor(nil))
+	assert.Nil(t, descriptor.PathForName(nil))
+}
+
+func TestDescriptorForPath(t *testing.T) {
+	t.Parallel()
+
+	protoreflectFileDescriptor := testCompileWalkSource(t).ProtoreflectFileDescriptor()
+	testCases := []struct {
+		name             string
+		path             protoreflect.SourcePath
+		expectedFullName protoreflect.FullName
+		expectedRest     protoreflect.SourcePath
+		expectedError    bool
+	}{
+		{name: "file", path: protoreflect.SourcePath{}, expectedFullName: "a", expectedRest: protoreflect.SourcePath{}},
+		{name: "file_package", path: protoreflect.SourcePath{2}, expectedFullName: "a", expectedRest: protoreflect.SourcePath{2}},
+		{name: "field_name", path: protoreflect.SourcePath{4, 0, 2, 1, 1}, expectedFullName: "a.Foo.choice_field", expectedRest: protoreflect.SourcePath{1}},
+		{name: "field_options", path: protoreflect.SourcePath{4, 0, 2, 1, 8, 3}, expectedFullName: "a.Foo.choice_field", expectedRest: protoreflect.SourcePath{8, 3}},
+		{name: "extension_range", path: protoreflect.SourcePath{4, 0, 5, 0}, expectedFullName: "a.Foo", expectedRest: protoreflect.SourcePath{5, 0}},
+		{name: "message_out_of_range", path: protoreflect.SourcePath{4, 2}, expectedError: true},
+		{name: "field_out_of_range", path: protoreflect.SourcePath{4, 0, 2, 3}, expectedError: true},
+	}
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+			protoreflectDescriptor, rest, err := descriptor.DescriptorForPath(protoreflectFileDescriptor, testCase.path)
+			if testCase.expectedError {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, testCase.expectedFullName, protoreflectDescriptor.FullName())
+			assert.Equal(t, testCase.expectedRest, rest)
+		})
+	}
+}
+
+func TestPathForOption(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name           string
+		descriptorPath protoreflect.SourcePath
+		optionPath     []int32
+		expectedPath   protoreflect.SourcePath
+		expectedError  bool
+	}{
+		{name: "file", descriptorPath: nil, optionPath: []int32{11}, expectedPath: protoreflect.SourcePath{8, 11}},
+		{name: "message", descriptorPath: descriptor.PathForMessage(nil, 1), optionPath: []int32{3}, expectedPath: protoreflect.SourcePath{4, 1, 7, 3}},
+		{name: "field", descriptorPath: descriptor.PathForField(descriptor.PathForMessage(nil, 0), 0), optionPath: []int32{3}, expectedPath: protoreflect.SourcePath{4, 0, 2, 0, 8, 3}},
+		{name: "oneof", descriptorPath: descriptor.PathForOneof(descriptor.PathForMessage(nil, 0), 0), optionPath: []int32{999}, expectedPath: protoreflect.SourcePath{4, 0, 8, 0, 2, 999}},
+		{name: "extension", descriptorPath: descriptor.PathForExtension(nil, 0), optionPath: []int32{3}, expectedPath: protoreflect.SourcePath{7, 0, 8, 3}},
+		{name: "enum", descriptorPath: descriptor.PathForEnum(nil, 0), optionPath: []int32{2}, expectedPath: protoreflect.SourcePath{5, 0, 3, 2}},
+		{name: "enum_value", descriptorPath: descriptor.PathForEnumValue(descriptor.PathForEnum(nil, 0), 1), optionPath: []int32{1}, expectedPath: protoreflect.SourcePath{5, 0, 2, 1, 3, 1}},
+		{name: "service", descriptorPath: descriptor.PathForService(0), optionPath: []int32{33}, expectedPath: protoreflect.SourcePath{6, 0, 3, 33}},
+		{name: "method", descriptorPath: descriptor.PathForMethod(descriptor.PathForService(0), 0), optionPath: []int32{33}, expectedPath: protoreflect.SourcePath{6, 0, 2, 0, 4, 33}},
+		{name: "nested_option", descriptorPath: descriptor.PathForMessage(nil, 0), optionPath: []int32{1000, 1, 2}, expectedPath: protoreflect.SourcePath{4, 0, 7, 1000, 1, 2}},
+		{name: "not_a_descriptor", descriptorPath: protoreflect.SourcePath{4, 0, 1}, optionPath: []int32{3}, expectedError: true},
+	}
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+			path, err := descriptor.PathForOption(testCase.descriptorPath, testCase.optionPath...)
+			if testCase.expectedError {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, testCase.expectedPath, path)
+		})
+	}
+}
+
+func testFindDescriptor(
+	t *testing.T,
+	protoreflectFileDescriptor protoreflect.FileDescriptor,
+	fullName protoreflect.FullName,
+) protoreflect.Descriptor {
+	files := &protoregistry.Files{}
+	require.NoError(t, files.RegisterFile(protoreflectFileDescriptor))
+	protoreflectDescriptor, err := files.FindDescriptorByName(fullName)
+	require.NoError(t, err)
+	return protoreflectDescriptor
+}
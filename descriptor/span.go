@@ -0,0 +1,163 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package descriptor
+
+import (
+	"strings"
+
+	"buf.build/go/bufplugin/internal/pkg/compare"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// FileLocationContains returns true if the span of one contains the span of two.
+//
+// Spans are the start and end lines and columns, with the end exclusive as within
+// SourceCodeInfo. A span contains itself, and empty spans are contained within any span that
+// they are within or on the boundary of.
+//
+// Returns false if the FileLocations are within different files, or if either is nil.
+func FileLocationContains(one FileLocation, two FileLocation) bool {
+	if !fileLocationsInSameFile(one, two) {
+		return false
+	}
+	return spanForFileLocation(one).contains(spanForFileLocation(two))
+}
+
+// FileLocationsOverlap returns true if the spans of one and two share at least one character.
+//
+// Empty spans never overlap. Returns false if the FileLocations are within different files,
+// or if either is nil.
+func FileLocationsOverlap(one FileLocation, two FileLocation) bool {
+	if !fileLocationsInSameFile(one, two) {
+		return false
+	}
+	return spanForFileLocation(one).overlaps(spanForFileLocation(two))
+}
+
+// CompareFileLocationSpans returns -1 if one < two, 1 if one > two, 0 otherwise, comparing
+// only the file paths and spans of the FileLocations.
+//
+// FileLocations are ordered by file path, then start, and then end. Unlike CompareFileLocations,
+// SourcePaths and comments are not compared, so that FileLocations that refer to the same
+// source are equal. nil FileLocations are less than all other FileLocations.
+func CompareFileLocationSpans(one FileLocation, two FileLocation) int {
+	if one == nil && two == nil {
+		return 0
+	}
+	if one == nil && two != nil {
+		return -1
+	}
+	if one != nil && two == nil {
+		return 1
+	}
+	if compare := strings.Compare(fileLocationFileName(one), fileLocationFileName(two)); compare != 0 {
+		return compare
+	}
+	return spanForFileLocation(one).compare(spanForFileLocation(two))
+}
+
+// SourceLocationContains returns true if the span of one contains the span of two.
+//
+// See FileLocationContains. The SourceLocations are assumed to be within the same file.
+func SourceLocationContains(one protoreflect.SourceLocation, two protoreflect.SourceLocation) bool {
+	return spanForSourceLocation(one).contains(spanForSourceLocation(two))
+}
+
+// SourceLocationsOverlap returns true if the spans of one and two share at least one character.
+//
+// See FileLocationsOverlap. The SourceLocations are assumed to be within the same file.
+func SourceLocationsOverlap(one protoreflect.SourceLocation, two protoreflect.SourceLocation) bool {
+	return spanForSourceLocation(one).overlaps(spanForSourceLocation(two))
+}
+
+// CompareSourceLocations returns -1 if one < two, 1 if one > two, 0 otherwise, comparing
+// only the spans of the SourceLocations.
+//
+// SourceLocations are ordered by start, and then end. The SourceLocations are assumed to be
+// within the same file.
+func CompareSourceLocations(one protoreflect.SourceLocation, two protoreflect.SourceLocation) int {
+	return spanForSourceLocation(one).compare(spanForSourceLocation(two))
+}
+
+// *** PRIVATE ***
+
+// span is a zero-indexed span within a file, with the end exclusive.
+type span struct {
+	startLine   int
+	startColumn int
+	endLine     int
+	endColumn   int
+}
+
+func spanForFileLocation(fileLocation FileLocation) span {
+	return span{
+		startLine:   fileLocation.StartLine(),
+		startColumn: fileLocation.StartColumn(),
+		endLine:     fileLocation.EndLine(),
+		endColumn:   fileLocation.EndColumn(),
+	}
+}
+
+func spanForSourceLocation(sourceLocation protoreflect.SourceLocation) span {
+	return span{
+		startLine:   sourceLocation.StartLine,
+		startColumn: sourceLocation.StartColumn,
+		endLine:     sourceLocation.EndLine,
+		endColumn:   sourceLocation.EndColumn,
+	}
+}
+
+func (s span) contains(other span) bool {
+	return comparePositions(s.startLine, s.startColumn, other.startLine, other.startColumn) <= 0 &&
+		comparePositions(other.endLine, other.endColumn, s.endLine, s.endColumn) <= 0
+}
+
+// overlaps returns false if either span is empty, as empty spans have no characters to share.
+func (s span) overlaps(other span) bool {
+	if s.isEmpty() || other.isEmpty() {
+		return false
+	}
+	return comparePositions(s.startLine, s.startColumn, other.endLine, other.endColumn) < 0 &&
+		comparePositions(other.startLine, other.startColumn, s.endLine, s.endColumn) < 0
+}
+
+func (s span) isEmpty() bool {
+	return comparePositions(s.startLine, s.startColumn, s.endLine, s.endColumn) >= 0
+}
+
+func (s span) compare(other span) int {
+	if compare := comparePositions(s.startLine, s.startColumn, other.startLine, other.startColumn); compare != 0 {
+		return compare
+	}
+	return comparePositions(s.endLine, s.endColumn, other.endLine, other.endColumn)
+}
+
+func comparePositions(oneLine int, oneColumn int, twoLine int, twoColumn int) int {
+	if compare := compare.CompareInts(oneLine, twoLine); compare != 0 {
+		return compare
+	}
+	return compare.CompareInts(oneColumn, twoColumn)
+}
+
+func fileLocationsInSameFile(one FileLocation, two FileLocation) bool {
+	if one == nil || two == nil {
+		return false
+	}
+	return fileLocationFileName(one) == fileLocationFileName(two)
+}
+
+func fileLocationFileName(fileLocation FileLocation) string {
+	return fileLocation.FileDescriptor().FileDescriptorProto().GetName()
+}
@@ -0,0 +1,252 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package descriptor_test
+
+import (
+	"context"
+	"testing"
+
+	"buf.build/go/bufplugin/descriptor"
+	"buf.build/go/bufplugin/descriptor/descriptortest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+func TestSpans(t *testing.T) {
+	t.Parallel()
+
+	fileDescriptor, _ := testCompileSpanSource(t)
+	testCases := []struct {
+		name string
+		// Spans are start line, start column, end line, and end column.
+		one [4]int
+		two [4]int
+		// Whether one contains two, and whether two contains one.
+		expectedOneContainsTwo bool
+		expectedTwoContainsOne bool
+		expectedOverlap        bool
+		expectedCompare        int
+	}{
+		{
+			name:                   "equal",
+			one:                    [4]int{1, 0, 1, 5},
+			two:                    [4]int{1, 0, 1, 5},
+			expectedOneContainsTwo: true,
+			expectedTwoContainsOne: true,
+			expectedOverlap:        true,
+			expectedCompare:        0,
+		},
+		{
+			name:                   "nested",
+			one:                    [4]int{1, 0, 3, 0},
+			two:                    [4]int{2, 2, 2, 4},
+			expectedOneContainsTwo: true,
+			expectedOverlap:        true,
+			expectedCompare:        -1,
+		},
+		{
+			name:                   "same_start",
+			one:                    [4]int{1, 0, 1, 5},
+			two:                    [4]int{1, 0, 1, 3},
+			expectedOneContainsTwo: true,
+			expectedOverlap:        true,
+			expectedCompare:        1,
+		},
+		{
+			name:                   "same_end",
+			one:                    [4]int{1, 0, 1, 5},
+			two:                    [4]int{1, 3, 1, 5},
+			expectedOneContainsTwo: true,
+			expectedOverlap:        true,
+			expectedCompare:        -1,
+		},
+		{
+			name:            "partial_overlap",
+			one:             [4]int{1, 0, 1, 6},
+			two:             [4]int{1, 5, 1, 10},
+			expectedOverlap: true,
+			expectedCompare: -1,
+		},
+		{
+			name:            "partial_overlap_across_lines",
+			one:             [4]int{1, 5, 3, 0},
+			two:             [4]int{2, 0, 4, 0},
+			expectedOverlap: true,
+			expectedCompare: -1,
+		},
+		{
+			name:            "shared_boundary",
+			one:             [4]int{1, 0, 1, 5},
+			two:             [4]int{1, 5, 1, 10},
+			expectedCompare: -1,
+		},
+		{
+			name:            "shared_boundary_across_lines",
+			one:             [4]int{1, 0, 2, 0},
+			two:             [4]int{2, 0, 3, 0},
+			expectedCompare: -1,
+		},
+		{
+			name:            "disjoint",
+			one:             [4]int{2, 0, 2, 5},
+			two:             [4]int{1, 0, 1, 10},
+			expectedCompare: 1,
+		},
+		{
+			// Lines are compared before columns.
+			name:            "disjoint_later_column_earlier_line",
+			one:             [4]int{1, 10, 1, 12},
+			two:             [4]int{2, 0, 2, 2},
+			expectedCompare: -1,
+		},
+		{
+			name:                   "empty_within",
+			one:                    [4]int{1, 0, 1, 5},
+			two:                    [4]int{1, 3, 1, 3},
+			expectedOneContainsTwo: true,
+			expectedCompare:        -1,
+		},
+		{
+			name:                   "empty_on_start_boundary",
+			one:                    [4]int{1, 0, 1, 5},
+			two:                    [4]int{1, 0, 1, 0},
+			expectedOneContainsTwo: true,
+			expectedCompare:        1,
+		},
+		{
+			name:                   "empty_on_end_boundary",
+			one:                    [4]int{1, 0, 1, 5},
+			two:                    [4]int{1, 5, 1, 5},
+			expectedOneContainsTwo: true,
+			expectedCompare:        -1,
+		},
+		{
+			name:            "empty_outside",
+			one:             [4]int{1, 0, 1, 5},
+			two:             [4]int{1, 6, 1, 6},
+			expectedCompare: -1,
+		},
+		{
+			name:                   "empty_equal",
+			one:                    [4]int{1, 3, 1, 3},
+			two:                    [4]int{1, 3, 1, 3},
+			expectedOneContainsTwo: true,
+			expectedTwoContainsOne: true,
+			expectedCompare:        0,
+		},
+	}
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+			oneSourceLocation := testNewSourceLocation(testCase.one)
+			twoSourceLocation := testNewSourceLocation(testCase.two)
+			one := descriptor.NewFileLocation(fileDescriptor, oneSourceLocation)
+			two := descriptor.NewFileLocation(fileDescriptor, twoSourceLocation)
+
+			assert.Equal(t, testCase.expectedOneContainsTwo, descriptor.FileLocationContains(one, two))
+			assert.Equal(t, testCase.expectedTwoContainsOne, descriptor.FileLocationContains(two, one))
+			assert.Equal(t, testCase.expectedOverlap, descriptor.FileLocationsOverlap(one, two))
+			assert.Equal(t, testCase.expectedOverlap, descriptor.FileLocationsOverlap(two, one))
+			assert.Equal(t, testCase.expectedCompare, descriptor.CompareFileLocationSpans(one, two))
+			assert.Equal(t, -testCase.expectedCompare, descriptor.CompareFileLocationSpans(two, one))
+
+			assert.Equal(t, testCase.expectedOneContainsTwo, descriptor.SourceLocationContains(oneSourceLocation, twoSourceLocation))
+			assert.Equal(t, testCase.expectedTwoContainsOne, descriptor.SourceLocationContains(twoSourceLocation, oneSourceLocation))
+			assert.Equal(t, testCase.expectedOverlap, descriptor.SourceLocationsOverlap(oneSourceLocation, twoSourceLocation))
+			assert.Equal(t, testCase.expectedOverlap, descriptor.SourceLocationsOverlap(twoSourceLocation, oneSourceLocation))
+			assert.Equal(t, testCase.expectedCompare, descriptor.CompareSourceLocations(oneSourceLocation, twoSourceLocation))
+			assert.Equal(t, -testCase.expectedCompare, descriptor.CompareSourceLocations(twoSourceLocation, oneSourceLocation))
+		})
+	}
+}
+
+func TestSpansDifferentFiles(t *testing.T) {
+	t.Parallel()
+
+	fileDescriptor, otherFileDescriptor := testCompileSpanSource(t)
+	sourceLocation := testNewSourceLocation([4]int{1, 0, 1, 5})
+	one := descriptor.NewFileLocation(fileDescriptor, sourceLocation)
+	two := descriptor.NewFileLocation(otherFileDescriptor, sourceLocation)
+
+	// Equal spans within different files neither contain nor overlap each other.
+	assert.False(t, descriptor.FileLocationContains(one, two))
+	assert.False(t, descriptor.FileLocationsOverlap(one, two))
+	// FileLocations are ordered by file path before spans.
+	assert.Equal(t, -1, descriptor.CompareFileLocationSpans(one, two))
+	assert.Equal(t, 1, descriptor.CompareFileLocationSpans(two, one))
+	assert.Equal(
+		t,
+		-1,
+		descriptor.CompareFileLocationSpans(
+			descriptor.NewFileLocation(otherFileDescriptor, testNewSourceLocation([4]int{0, 0, 0, 1})),
+			descriptor.NewFileLocation(otherFileDescriptor, sourceLocation),
+		),
+	)
+	assert.Equal(
+		t,
+		-1,
+		descriptor.CompareFileLocationSpans(
+			descriptor.NewFileLocation(fileDescriptor, testNewSourceLocation([4]int{9, 0, 9, 1})),
+			two,
+		),
+	)
+
+	assert.False(t, descriptor.FileLocationContains(one, nil))
+	assert.False(t, descriptor.FileLocationContains(nil, one))
+	assert.False(t, descriptor.FileLocationsOverlap(one, nil))
+	assert.False(t, descriptor.FileLocationsOverlap(nil, one))
+	assert.Equal(t, 0, descriptor.CompareFileLocationSpans(nil, nil))
+	assert.Equal(t, -1, descriptor.CompareFileLocationSpans(nil, one))
+	assert.Equal(t, 1, descriptor.CompareFileLocationSpans(one, nil))
+}
+
+func TestSpansIgnoreSourcePathsAndComments(t *testing.T) {
+	t.Parallel()
+
+	fileDescriptor, _ := testCompileSpanSource(t)
+	sourceLocation := testNewSourceLocation([4]int{1, 0, 1, 5})
+	otherSourceLocation := sourceLocation
+	otherSourceLocation.Path = protoreflect.SourcePath{4, 0}
+	otherSourceLocation.LeadingComments = "comment"
+	one := descriptor.NewFileLocation(fileDescriptor, sourceLocation)
+	two := descriptor.NewFileLocation(fileDescriptor, otherSourceLocation)
+	assert.Equal(t, 0, descriptor.CompareFileLocationSpans(one, two))
+	assert.NotEqual(t, 0, descriptor.CompareFileLocations(one, two))
+	assert.True(t, descriptor.FileLocationContains(one, two))
+	assert.True(t, descriptor.FileLocationsOverlap(one, two))
+}
+
+func testCompileSpanSource(t *testing.T) (descriptor.FileDescriptor, descriptor.FileDescriptor) {
+	fileDescriptors, err := descriptortest.CompileStrings(
+		context.Background(),
+		map[string]string{
+			"a.proto": `syntax = "proto3"; package a;`,
+			"b.proto": `syntax = "proto3"; package b;`,
+		},
+	)
+	require.NoError(t, err)
+	return testFileDescriptorForPath(t, fileDescriptors, "a.proto"), testFileDescriptorForPath(t, fileDescriptors, "b.proto")
+}
+
+func testNewSourceLocation(span [4]int) protoreflect.SourceLocation {
+	return protoreflect.SourceLocation{
+		StartLine:   span[0],
+		StartColumn: span[1],
+		EndLine:     span[2],
+		EndColumn:   span[3],
+	}
+}
@@ -0,0 +1,114 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package descriptor
+
+import (
+	"errors"
+	"fmt"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// TransformFileDescriptor returns a new FileDescriptor from the FileDescriptor, with its
+// FileDescriptorProto transformed by the given function.
+//
+// The function is given a copy of the FileDescriptorProto, which it modifies in place, for
+// example to strip options or remove source info. The result is re-linked against the imports
+// of the FileDescriptor, so any imports of the transformed FileDescriptorProto must have been
+// imported, directly or transitively, by the FileDescriptor. If the SourceCodeInfo is removed,
+// it is replaced with an empty SourceCodeInfo, as FileDescriptors always contain SourceCodeInfo.
+//
+// IsImport and IsSyntaxUnspecified are preserved. UnusedDependencyIndexes are preserved by path,
+// so that the unused imports that remain are still reported if imports are reordered or removed.
+//
+// The FileDescriptor itself is not modified. Other FileDescriptors that import the
+// FileDescriptor are not re-linked, and continue to refer to the original.
+func TransformFileDescriptor(
+	fileDescriptor FileDescriptor,
+	transform func(*descriptorpb.FileDescriptorProto) error,
+) (FileDescriptor, error) {
+	if fileDescriptor == nil {
+		return nil, errors.New("nil FileDescriptor")
+	}
+	if err := fileDescriptor.Link(); err != nil {
+		return nil, err
+	}
+	fileDescriptorProto, ok := proto.Clone(fileDescriptor.FileDescriptorProto()).(*descriptorpb.FileDescriptorProto)
+	if !ok {
+		// A clone of a FileDescriptorProto is always a FileDescriptorProto.
+		return nil, fmt.Errorf("could not clone FileDescriptorProto for %q", fileDescriptor.FileDescriptorProto().GetName())
+	}
+	if err := transform(fileDescriptorProto); err != nil {
+		return nil, err
+	}
+	if fileDescriptorProto.SourceCodeInfo == nil {
+		fileDescriptorProto.SourceCodeInfo = &descriptorpb.SourceCodeInfo{}
+	}
+	files := &protoregistry.Files{}
+	if err := registerImportsTransitively(files, fileDescriptor.ProtoreflectFileDescriptor(), make(map[string]struct{})); err != nil {
+		return nil, err
+	}
+	protoreflectFileDescriptor, err := protodesc.NewFile(fileDescriptorProto, files)
+	if err != nil {
+		return nil, fmt.Errorf("could not link transformed %q: %w", fileDescriptorProto.GetName(), err)
+	}
+	unusedDependencyPathMap := make(map[string]struct{})
+	for _, unusedDependencyPath := range fileDescriptor.UnusedDependencyPaths() {
+		unusedDependencyPathMap[unusedDependencyPath] = struct{}{}
+	}
+	var unusedDependencyIndexes []int32
+	for i, dependencyPath := range fileDescriptorProto.GetDependency() {
+		if _, ok := unusedDependencyPathMap[dependencyPath]; ok {
+			unusedDependencyIndexes = append(unusedDependencyIndexes, int32(i))
+		}
+	}
+	return newFileDescriptor(
+		func() (protoreflect.FileDescriptor, error) {
+			return protoreflectFileDescriptor, nil
+		},
+		fileDescriptorProto,
+		fileDescriptor.IsImport(),
+		fileDescriptor.IsSyntaxUnspecified(),
+		unusedDependencyIndexes,
+	), nil
+}
+
+// *** PRIVATE ***
+
+func registerImportsTransitively(
+	files *protoregistry.Files,
+	fileDescriptor protoreflect.FileDescriptor,
+	seen map[string]struct{},
+) error {
+	imports := fileDescriptor.Imports()
+	for i := 0; i < imports.Len(); i++ {
+		importFileDescriptor := imports.Get(i).FileDescriptor
+		if _, ok := seen[importFileDescriptor.Path()]; ok {
+			continue
+		}
+		seen[importFileDescriptor.Path()] = struct{}{}
+		if err := registerImportsTransitively(files, importFileDescriptor, seen); err != nil {
+			return err
+		}
+		if err := files.RegisterFile(importFileDescriptor); err != nil {
+			return err
+		}
+	}
+	return nil
+}
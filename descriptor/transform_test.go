@@ -0,0 +1,234 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package descriptor_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"buf.build/go/bufplugin/descriptor"
+	"buf.build/go/bufplugin/descriptor/descriptortest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+func TestTransformFileDescriptor(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name                          string
+		transform                     func(*descriptorpb.FileDescriptorProto) error
+		expectedDependencies          []string
+		expectedUnusedDependencyPaths []string
+		expectedMessageDeprecated     bool
+		expectedErrorContains         string
+	}{
+		{
+			name: "identity",
+			transform: func(*descriptorpb.FileDescriptorProto) error {
+				return nil
+			},
+			expectedDependencies:          []string{"a.proto", "b.proto"},
+			expectedUnusedDependencyPaths: []string{"b.proto"},
+			expectedMessageDeprecated:     true,
+		},
+		{
+			name: "strip_options",
+			transform: func(fileDescriptorProto *descriptorpb.FileDescriptorProto) error {
+				for _, messageDescriptorProto := range fileDescriptorProto.GetMessageType() {
+					messageDescriptorProto.Options = nil
+				}
+				return nil
+			},
+			expectedDependencies:          []string{"a.proto", "b.proto"},
+			expectedUnusedDependencyPaths: []string{"b.proto"},
+		},
+		{
+			name: "reorder_imports",
+			transform: func(fileDescriptorProto *descriptorpb.FileDescriptorProto) error {
+				fileDescriptorProto.Dependency = []string{"b.proto", "a.proto"}
+				return nil
+			},
+			expectedDependencies:          []string{"b.proto", "a.proto"},
+			expectedUnusedDependencyPaths: []string{"b.proto"},
+			expectedMessageDeprecated:     true,
+		},
+		{
+			name: "remove_unused_import",
+			transform: func(fileDescriptorProto *descriptorpb.FileDescriptorProto) error {
+				fileDescriptorProto.Dependency = []string{"a.proto"}
+				return nil
+			},
+			expectedDependencies:          []string{"a.proto"},
+			expectedUnusedDependencyPaths: []string{},
+			expectedMessageDeprecated:     true,
+		},
+		{
+			name: "remove_used_import",
+			transform: func(fileDescriptorProto *descriptorpb.FileDescriptorProto) error {
+				fileDescriptorProto.Dependency = []string{"b.proto"}
+				return nil
+			},
+			expectedErrorContains: `could not link transformed "c.proto"`,
+		},
+		{
+			name: "add_import_not_imported",
+			transform: func(fileDescriptorProto *descriptorpb.FileDescriptorProto) error {
+				fileDescriptorProto.Dependency = append(fileDescriptorProto.Dependency, "d.proto")
+				return nil
+			},
+			expectedErrorContains: `could not link transformed "c.proto"`,
+		},
+		{
+			name: "transform_error",
+			transform: func(*descriptorpb.FileDescriptorProto) error {
+				return errors.New("transform error")
+			},
+			expectedErrorContains: "transform error",
+		},
+	}
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+			fileDescriptor := testCompileTransformSource(t)
+			originalFileDescriptorProto := proto.Clone(fileDescriptor.FileDescriptorProto())
+			transformedFileDescriptor, err := descriptor.TransformFileDescriptor(fileDescriptor, testCase.transform)
+			// The FileDescriptor itself is never modified.
+			assert.True(t, proto.Equal(originalFileDescriptorProto, fileDescriptor.FileDescriptorProto()))
+			if testCase.expectedErrorContains != "" {
+				require.ErrorContains(t, err, testCase.expectedErrorContains)
+				return
+			}
+			require.NoError(t, err)
+			require.NoError(t, transformedFileDescriptor.Link())
+			assert.Equal(t, testCase.expectedDependencies, transformedFileDescriptor.FileDescriptorProto().GetDependency())
+			assert.Equal(t, testCase.expectedUnusedDependencyPaths, transformedFileDescriptor.UnusedDependencyPaths())
+			messageDescriptor := transformedFileDescriptor.ProtoreflectFileDescriptor().Messages().ByName("Foo")
+			require.NotNil(t, messageDescriptor)
+			messageOptions, ok := messageDescriptor.Options().(*descriptorpb.MessageOptions)
+			require.True(t, ok)
+			assert.Equal(t, testCase.expectedMessageDeprecated, messageOptions.GetDeprecated())
+			// The imports are the same as those of the original FileDescriptor.
+			assert.Equal(
+				t,
+				fileDescriptor.ProtoreflectFileDescriptor().Messages().ByName("Foo").Fields().ByName("bar").Message(),
+				messageDescriptor.Fields().ByName("bar").Message(),
+			)
+			assert.Equal(t, fileDescriptor.IsImport(), transformedFileDescriptor.IsImport())
+			assert.Equal(t, fileDescriptor.IsSyntaxUnspecified(), transformedFileDescriptor.IsSyntaxUnspecified())
+		})
+	}
+}
+
+func TestTransformFileDescriptorRemoveSourceCodeInfo(t *testing.T) {
+	t.Parallel()
+
+	fileDescriptor := testCompileTransformSource(t)
+	require.NotEmpty(t, fileDescriptor.FileDescriptorProto().GetSourceCodeInfo().GetLocation())
+	transformedFileDescriptor, err := descriptor.TransformFileDescriptor(
+		fileDescriptor,
+		func(fileDescriptorProto *descriptorpb.FileDescriptorProto) error {
+			fileDescriptorProto.SourceCodeInfo = nil
+			return nil
+		},
+	)
+	require.NoError(t, err)
+	// FileDescriptors always contain SourceCodeInfo.
+	require.NotNil(t, transformedFileDescriptor.FileDescriptorProto().GetSourceCodeInfo())
+	assert.Empty(t, transformedFileDescriptor.FileDescriptorProto().GetSourceCodeInfo().GetLocation())
+	assert.NotEmpty(t, fileDescriptor.FileDescriptorProto().GetSourceCodeInfo().GetLocation())
+}
+
+func TestTransformFileDescriptorPreservesFlags(t *testing.T) {
+	t.Parallel()
+
+	fileDescriptors, err := descriptortest.CompileStrings(
+		context.Background(),
+		map[string]string{
+			"a.proto": `package a;
+message Foo {}
+`,
+		},
+	)
+	require.NoError(t, err)
+	fileDescriptors, err = descriptor.FileDescriptorsForFileDescriptorSet(
+		descriptor.FileDescriptorSetForFileDescriptors(fileDescriptors),
+		descriptor.FileDescriptorSetWithImportFilePaths("a.proto"),
+	)
+	require.NoError(t, err)
+	require.Len(t, fileDescriptors, 1)
+	fileDescriptor := fileDescriptors[0]
+	require.True(t, fileDescriptor.IsImport())
+	transformedFileDescriptor, err := descriptor.TransformFileDescriptor(
+		fileDescriptor,
+		func(*descriptorpb.FileDescriptorProto) error {
+			return nil
+		},
+	)
+	require.NoError(t, err)
+	assert.True(t, transformedFileDescriptor.IsImport())
+	assert.Equal(t, fileDescriptor.IsSyntaxUnspecified(), transformedFileDescriptor.IsSyntaxUnspecified())
+}
+
+func TestTransformFileDescriptorNil(t *testing.T) {
+	t.Parallel()
+
+	_, err := descriptor.TransformFileDescriptor(
+		nil,
+		func(*descriptorpb.FileDescriptorProto) error {
+			return nil
+		},
+	)
+	require.Error(t, err)
+}
+
+// testCompileTransformSource returns the FileDescriptor for c.proto, which imports a.proto and
+// the unused b.proto.
+func testCompileTransformSource(t *testing.T) descriptor.FileDescriptor {
+	fileDescriptors, err := descriptortest.CompileStrings(
+		context.Background(),
+		map[string]string{
+			"a.proto": `syntax = "proto3";
+package a;
+message Bar {}
+`,
+			"b.proto": `syntax = "proto3";
+package b;
+message Baz {}
+`,
+			"c.proto": `syntax = "proto3";
+package c;
+import "a.proto";
+import "b.proto";
+message Foo {
+  option deprecated = true;
+  a.Bar bar = 1;
+}
+`,
+		},
+	)
+	require.NoError(t, err)
+	for _, fileDescriptor := range fileDescriptors {
+		if fileDescriptor.FileDescriptorProto().GetName() == "c.proto" {
+			return fileDescriptor
+		}
+	}
+	require.FailNow(t, "c.proto not found")
+	return nil
+}
@@ -0,0 +1,60 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package descriptor
+
+import (
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// TypesForFileDescriptors returns a new protoregistry.Types that contains every message, enum,
+// and extension declared within the FileDescriptors.
+//
+// Types are backed by dynamicpb, and the result is suitable as the Resolver of
+// proto.UnmarshalOptions or protojson.UnmarshalOptions. This allows plugins to fully reparse
+// options, including custom options, and the contents of google.protobuf.Any values, with the
+// types of the files they were given. Map entry messages are not registered.
+//
+// Returns an error if two FileDescriptors declare the same name, or the same extension number
+// for a message.
+func TypesForFileDescriptors(fileDescriptors []FileDescriptor) (*protoregistry.Types, error) {
+	types := &protoregistry.Types{}
+	for _, fileDescriptor := range fileDescriptors {
+		if err := Walk(
+			fileDescriptor,
+			&VisitorFuncs{
+				Message: func(messageDescriptor protoreflect.MessageDescriptor) error {
+					if messageDescriptor.IsMapEntry() {
+						return nil
+					}
+					return types.RegisterMessage(dynamicpb.NewMessageType(messageDescriptor))
+				},
+				Field: func(fieldDescriptor protoreflect.FieldDescriptor) error {
+					if !fieldDescriptor.IsExtension() {
+						return nil
+					}
+					return types.RegisterExtension(dynamicpb.NewExtensionType(fieldDescriptor))
+				},
+				Enum: func(enumDescriptor protoreflect.EnumDescriptor) error {
+					return types.RegisterEnum(dynamicpb.NewEnumType(enumDescriptor))
+				},
+			},
+		); err != nil {
+			return nil, err
+		}
+	}
+	return types, nil
+}
@@ -0,0 +1,169 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package descriptor_test
+
+import (
+	"context"
+	"sort"
+	"testing"
+
+	"buf.build/go/bufplugin/descriptor"
+	"buf.build/go/bufplugin/descriptor/descriptortest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+func TestTypesForFileDescriptors(t *testing.T) {
+	t.Parallel()
+
+	fileDescriptors, err := descriptortest.CompileStrings(
+		context.Background(),
+		map[string]string{
+			"a.proto": `syntax = "proto2";
+package a;
+message Foo {
+  message Nested {
+    enum NestedEnum {
+      NESTED_ENUM_UNSPECIFIED = 0;
+    }
+  }
+  map<string, Nested> nested = 1;
+  extend Foo {
+    optional string nested_ext = 101;
+  }
+  extensions 100 to 200;
+}
+enum Enum {
+  ENUM_UNSPECIFIED = 0;
+}
+`,
+			"b.proto": `syntax = "proto2";
+package b;
+import "a.proto";
+message Bar {}
+extend a.Foo {
+  optional Bar ext = 100;
+}
+`,
+		},
+	)
+	require.NoError(t, err)
+	types, err := descriptor.TypesForFileDescriptors(fileDescriptors)
+	require.NoError(t, err)
+
+	var messageNames []string
+	types.RangeMessages(
+		func(messageType protoreflect.MessageType) bool {
+			messageNames = append(messageNames, string(messageType.Descriptor().FullName()))
+			return true
+		},
+	)
+	sort.Strings(messageNames)
+	// Map entry messages are not registered.
+	assert.Equal(t, []string{"a.Foo", "a.Foo.Nested", "b.Bar"}, messageNames)
+	var enumNames []string
+	types.RangeEnums(
+		func(enumType protoreflect.EnumType) bool {
+			enumNames = append(enumNames, string(enumType.Descriptor().FullName()))
+			return true
+		},
+	)
+	sort.Strings(enumNames)
+	assert.Equal(t, []string{"a.Enum", "a.Foo.Nested.NestedEnum"}, enumNames)
+	var extensionNames []string
+	types.RangeExtensionsByMessage(
+		"a.Foo",
+		func(extensionType protoreflect.ExtensionType) bool {
+			extensionNames = append(extensionNames, string(extensionType.TypeDescriptor().FullName()))
+			return true
+		},
+	)
+	sort.Strings(extensionNames)
+	assert.Equal(t, []string{"a.Foo.nested_ext", "b.ext"}, extensionNames)
+
+	// Types are backed by dynamicpb, and extension values use the registered message types.
+	extensionType, err := types.FindExtensionByNumber("a.Foo", 100)
+	require.NoError(t, err)
+	assert.Equal(t, protoreflect.FullName("b.Bar"), extensionType.New().Message().Descriptor().FullName())
+}
+
+func TestTypesForFileDescriptorsDuplicates(t *testing.T) {
+	t.Parallel()
+
+	const baseSource = `syntax = "proto2";
+package a;
+message Foo {
+  extensions 100 to 200;
+}
+`
+	testCases := []struct {
+		name          string
+		pathToSources []map[string]string
+		// The paths of the files from each compile to pass to TypesForFileDescriptors.
+		paths [][]string
+	}{
+		{
+			name: "duplicate_message",
+			pathToSources: []map[string]string{
+				{"a.proto": `syntax = "proto3"; package a; message Foo {}`},
+				{"b.proto": `syntax = "proto3"; package a; message Foo {}`},
+			},
+			paths: [][]string{{"a.proto"}, {"b.proto"}},
+		},
+		{
+			name: "duplicate_enum",
+			pathToSources: []map[string]string{
+				{"a.proto": `syntax = "proto3"; package a; enum Enum { ENUM_UNSPECIFIED = 0; }`},
+				{"b.proto": `syntax = "proto3"; package a; enum Enum { ENUM_UNSPECIFIED = 0; }`},
+			},
+			paths: [][]string{{"a.proto"}, {"b.proto"}},
+		},
+		{
+			name: "duplicate_extension_number",
+			pathToSources: []map[string]string{
+				{
+					"a.proto": baseSource,
+					"b.proto": `syntax = "proto2"; package b; import "a.proto"; extend a.Foo { optional string ext = 100; }`,
+				},
+				{
+					"a.proto": baseSource,
+					"c.proto": `syntax = "proto2"; package c; import "a.proto"; extend a.Foo { optional string ext = 100; }`,
+				},
+			},
+			paths: [][]string{{"a.proto", "b.proto"}, {"c.proto"}},
+		},
+	}
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+			var fileDescriptors []descriptor.FileDescriptor
+			for i, pathToSource := range testCase.pathToSources {
+				compiledFileDescriptors, err := descriptortest.CompileStrings(context.Background(), pathToSource)
+				require.NoError(t, err)
+				for _, fileDescriptor := range compiledFileDescriptors {
+					for _, path := range testCase.paths[i] {
+						if fileDescriptor.FileDescriptorProto().GetName() == path {
+							fileDescriptors = append(fileDescriptors, fileDescriptor)
+						}
+					}
+				}
+			}
+			_, err := descriptor.TypesForFileDescriptors(fileDescriptors)
+			require.Error(t, err)
+		})
+	}
+}
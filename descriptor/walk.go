@@ -0,0 +1,272 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package descriptor
+
+import (
+	"errors"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+var (
+	// SkipChildren is used as a return value from the methods of a Visitor to indicate that
+	// the children of the Descriptor should not be visited.
+	//
+	// It is not returned as an error by Walk. It only has an effect when returned when visiting
+	// a message, enum, or service, and is otherwise treated as nil.
+	SkipChildren = errors.New("skip children")
+	// SkipAll is used as a return value from the methods of a Visitor to indicate that all
+	// remaining Descriptors should not be visited.
+	//
+	// It is not returned as an error by Walk.
+	SkipAll = errors.New("skip all")
+)
+
+// Visitor visits the Descriptors within a FileDescriptor.
+//
+// Each method may return SkipChildren or SkipAll to control the traversal. Any other non-nil
+// error stops the traversal, and is returned from Walk.
+//
+// To implement only some of the methods, see VisitorFuncs.
+type Visitor interface {
+	// VisitMessage visits a message. The children of a message are its fields, oneofs,
+	// extensions, nested enums, and nested messages.
+	VisitMessage(messageDescriptor protoreflect.MessageDescriptor) error
+	// VisitField visits a field. This includes extensions, whether declared at the top
+	// level of a file or nested within a message.
+	VisitField(fieldDescriptor protoreflect.FieldDescriptor) error
+	// VisitOneof visits a oneof. This includes synthetic oneofs.
+	//
+	// The fields within a oneof are visited as the fields of the containing message.
+	VisitOneof(oneofDescriptor protoreflect.OneofDescriptor) error
+	// VisitEnum visits an enum. The children of an enum are its values.
+	VisitEnum(enumDescriptor protoreflect.EnumDescriptor) error
+	// VisitEnumValue visits an enum value.
+	VisitEnumValue(enumValueDescriptor protoreflect.EnumValueDescriptor) error
+	// VisitService visits a service. The children of a service are its methods.
+	VisitService(serviceDescriptor protoreflect.ServiceDescriptor) error
+	// VisitMethod visits a method.
+	VisitMethod(methodDescriptor protoreflect.MethodDescriptor) error
+}
+
+// VisitorFuncs is a Visitor that calls the function for each kind of Descriptor, if set.
+//
+// Functions that are not set are not called, and the children of the Descriptor are still
+// visited. For example, to visit every enum value within a file:
+//
+//	err := descriptor.Walk(
+//		fileDescriptor,
+//		&descriptor.VisitorFuncs{
+//			EnumValue: func(enumValueDescriptor protoreflect.EnumValueDescriptor) error {
+//				...
+//			},
+//		},
+//	)
+type VisitorFuncs struct {
+	Message   func(messageDescriptor protoreflect.MessageDescriptor) error
+	Field     func(fieldDescriptor protoreflect.FieldDescriptor) error
+	Oneof     func(oneofDescriptor protoreflect.OneofDescriptor) error
+	Enum      func(enumDescriptor protoreflect.EnumDescriptor) error
+	EnumValue func(enumValueDescriptor protoreflect.EnumValueDescriptor) error
+	Service   func(serviceDescriptor protoreflect.ServiceDescriptor) error
+	Method    func(methodDescriptor protoreflect.MethodDescriptor) error
+}
+
+// VisitMessage implements Visitor.
+func (v *VisitorFuncs) VisitMessage(messageDescriptor protoreflect.MessageDescriptor) error {
+	return callVisitorFunc(v.Message, messageDescriptor)
+}
+
+// VisitField implements Visitor.
+func (v *VisitorFuncs) VisitField(fieldDescriptor protoreflect.FieldDescriptor) error {
+	return callVisitorFunc(v.Field, fieldDescriptor)
+}
+
+// VisitOneof implements Visitor.
+func (v *VisitorFuncs) VisitOneof(oneofDescriptor protoreflect.OneofDescriptor) error {
+	return callVisitorFunc(v.Oneof, oneofDescriptor)
+}
+
+// VisitEnum implements Visitor.
+func (v *VisitorFuncs) VisitEnum(enumDescriptor protoreflect.EnumDescriptor) error {
+	return callVisitorFunc(v.Enum, enumDescriptor)
+}
+
+// VisitEnumValue implements Visitor.
+func (v *VisitorFuncs) VisitEnumValue(enumValueDescriptor protoreflect.EnumValueDescriptor) error {
+	return callVisitorFunc(v.EnumValue, enumValueDescriptor)
+}
+
+// VisitService implements Visitor.
+func (v *VisitorFuncs) VisitService(serviceDescriptor protoreflect.ServiceDescriptor) error {
+	return callVisitorFunc(v.Service, serviceDescriptor)
+}
+
+// VisitMethod implements Visitor.
+func (v *VisitorFuncs) VisitMethod(methodDescriptor protoreflect.MethodDescriptor) error {
+	return callVisitorFunc(v.Method, methodDescriptor)
+}
+
+// Walk visits every Descriptor within the FileDescriptor with the Visitor.
+//
+// Descriptors are visited depth-first, with each Descriptor visited before its children.
+// Within a file, the enums are visited first, then the messages, then the extensions, and then
+// the services. Within a message, the fields are visited first, then the oneofs, then the
+// extensions, then the nested enums, and then the nested messages. Within each kind, Descriptors
+// are visited in the order they are declared.
+//
+// This is the supported way to traverse a FileDescriptor. Plugins should use Walk instead of
+// their own recursive traversals, so that nested types and extensions are not missed. Returns
+// the error from FileDescriptor.Link if the file does not link.
+func Walk(fileDescriptor FileDescriptor, visitor Visitor) error {
+	if err := fileDescriptor.Link(); err != nil {
+		return err
+	}
+	if err := walkContainer(fileDescriptor.ProtoreflectFileDescriptor(), visitor); err != nil {
+		if errors.Is(err, SkipAll) {
+			return nil
+		}
+		return err
+	}
+	return nil
+}
+
+// *** PRIVATE ***
+
+type container interface {
+	Enums() protoreflect.EnumDescriptors
+	Messages() protoreflect.MessageDescriptors
+	Extensions() protoreflect.ExtensionDescriptors
+}
+
+func walkContainer(container container, visitor Visitor) error {
+	enums := container.Enums()
+	for i := 0; i < enums.Len(); i++ {
+		if err := walkEnum(enums.Get(i), visitor); err != nil {
+			return err
+		}
+	}
+	messages := container.Messages()
+	for i := 0; i < messages.Len(); i++ {
+		if err := walkMessage(messages.Get(i), visitor); err != nil {
+			return err
+		}
+	}
+	if err := walkExtensions(container.Extensions(), visitor); err != nil {
+		return err
+	}
+	if fileDescriptor, ok := container.(protoreflect.FileDescriptor); ok {
+		services := fileDescriptor.Services()
+		for i := 0; i < services.Len(); i++ {
+			if err := walkService(services.Get(i), visitor); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func walkMessage(messageDescriptor protoreflect.MessageDescriptor, visitor Visitor) error {
+	if err := visitor.VisitMessage(messageDescriptor); err != nil {
+		if errors.Is(err, SkipChildren) {
+			return nil
+		}
+		return err
+	}
+	fields := messageDescriptor.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		if err := skipChildrenToNil(visitor.VisitField(fields.Get(i))); err != nil {
+			return err
+		}
+	}
+	oneofs := messageDescriptor.Oneofs()
+	for i := 0; i < oneofs.Len(); i++ {
+		if err := skipChildrenToNil(visitor.VisitOneof(oneofs.Get(i))); err != nil {
+			return err
+		}
+	}
+	if err := walkExtensions(messageDescriptor.Extensions(), visitor); err != nil {
+		return err
+	}
+	enums := messageDescriptor.Enums()
+	for i := 0; i < enums.Len(); i++ {
+		if err := walkEnum(enums.Get(i), visitor); err != nil {
+			return err
+		}
+	}
+	messages := messageDescriptor.Messages()
+	for i := 0; i < messages.Len(); i++ {
+		if err := walkMessage(messages.Get(i), visitor); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func walkExtensions(extensions protoreflect.ExtensionDescriptors, visitor Visitor) error {
+	for i := 0; i < extensions.Len(); i++ {
+		if err := skipChildrenToNil(visitor.VisitField(extensions.Get(i))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func walkEnum(enumDescriptor protoreflect.EnumDescriptor, visitor Visitor) error {
+	if err := visitor.VisitEnum(enumDescriptor); err != nil {
+		if errors.Is(err, SkipChildren) {
+			return nil
+		}
+		return err
+	}
+	values := enumDescriptor.Values()
+	for i := 0; i < values.Len(); i++ {
+		if err := skipChildrenToNil(visitor.VisitEnumValue(values.Get(i))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func walkService(serviceDescriptor protoreflect.ServiceDescriptor, visitor Visitor) error {
+	if err := visitor.VisitService(serviceDescriptor); err != nil {
+		if errors.Is(err, SkipChildren) {
+			return nil
+		}
+		return err
+	}
+	methods := serviceDescriptor.Methods()
+	for i := 0; i < methods.Len(); i++ {
+		if err := skipChildrenToNil(visitor.VisitMethod(methods.Get(i))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// skipChildrenToNil returns nil if err is SkipChildren, for Descriptors without children.
+func skipChildrenToNil(err error) error {
+	if errors.Is(err, SkipChildren) {
+		return nil
+	}
+	return err
+}
+
+func callVisitorFunc[D protoreflect.Descriptor](f func(D) error, descriptor D) error {
+	if f == nil {
+		return nil
+	}
+	return f(descriptor)
+}
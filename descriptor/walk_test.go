@@ -0,0 +1,254 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package descriptor_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"buf.build/go/bufplugin/descriptor"
+	"buf.build/go/bufplugin/descriptor/descriptortest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+const testWalkSource = `syntax = "proto2";
+package a;
+service Service {
+  rpc Method(Foo) returns (Foo);
+}
+extend Foo {
+  optional string ext = 100;
+}
+message Foo {
+  message Nested {
+    optional string nested_field = 1;
+  }
+  enum NestedEnum {
+    NESTED_ENUM_UNSPECIFIED = 0;
+  }
+  optional string field = 1;
+  oneof choice {
+    string choice_field = 2;
+  }
+  optional string other_field = 3;
+  extend Foo {
+    optional string nested_ext = 101;
+  }
+  extensions 100 to 200;
+}
+enum Enum {
+  ENUM_UNSPECIFIED = 0;
+  ENUM_VALUE = 1;
+}
+message Bar {}
+`
+
+func TestWalkOrder(t *testing.T) {
+	t.Parallel()
+
+	fileDescriptor := testCompileWalkSource(t)
+	visitor := &testRecordingVisitor{}
+	require.NoError(t, descriptor.Walk(fileDescriptor, visitor))
+	assert.Equal(
+		t,
+		[]string{
+			"enum:a.Enum",
+			"enum_value:a.ENUM_UNSPECIFIED",
+			"enum_value:a.ENUM_VALUE",
+			"message:a.Foo",
+			"field:a.Foo.field",
+			"field:a.Foo.choice_field",
+			"field:a.Foo.other_field",
+			"oneof:a.Foo.choice",
+			"field:a.Foo.nested_ext",
+			"enum:a.Foo.NestedEnum",
+			"enum_value:a.Foo.NESTED_ENUM_UNSPECIFIED",
+			"message:a.Foo.Nested",
+			"field:a.Foo.Nested.nested_field",
+			"message:a.Bar",
+			"field:a.ext",
+			"service:a.Service",
+			"method:a.Service.Method",
+		},
+		visitor.visited,
+	)
+}
+
+func TestWalkSkipChildren(t *testing.T) {
+	t.Parallel()
+
+	fileDescriptor := testCompileWalkSource(t)
+	visitor := &testRecordingVisitor{
+		errFor: func(fullName string) error {
+			switch fullName {
+			case "message:a.Foo", "enum:a.Enum", "service:a.Service":
+				return descriptor.SkipChildren
+			case "field:a.ext":
+				// Treated as nil, as fields have no children.
+				return descriptor.SkipChildren
+			default:
+				return nil
+			}
+		},
+	}
+	require.NoError(t, descriptor.Walk(fileDescriptor, visitor))
+	assert.Equal(
+		t,
+		[]string{
+			"enum:a.Enum",
+			"message:a.Foo",
+			"message:a.Bar",
+			"field:a.ext",
+			"service:a.Service",
+		},
+		visitor.visited,
+	)
+}
+
+func TestWalkSkipAll(t *testing.T) {
+	t.Parallel()
+
+	fileDescriptor := testCompileWalkSource(t)
+	visitor := &testRecordingVisitor{
+		errFor: func(fullName string) error {
+			if fullName == "field:a.Foo.choice_field" {
+				return descriptor.SkipAll
+			}
+			return nil
+		},
+	}
+	require.NoError(t, descriptor.Walk(fileDescriptor, visitor))
+	assert.Equal(
+		t,
+		[]string{
+			"enum:a.Enum",
+			"enum_value:a.ENUM_UNSPECIFIED",
+			"enum_value:a.ENUM_VALUE",
+			"message:a.Foo",
+			"field:a.Foo.field",
+			"field:a.Foo.choice_field",
+		},
+		visitor.visited,
+	)
+}
+
+func TestWalkError(t *testing.T) {
+	t.Parallel()
+
+	fileDescriptor := testCompileWalkSource(t)
+	testErr := errors.New("test error")
+	visitor := &testRecordingVisitor{
+		errFor: func(fullName string) error {
+			if fullName == "enum_value:a.Foo.NESTED_ENUM_UNSPECIFIED" {
+				return testErr
+			}
+			return nil
+		},
+	}
+	err := descriptor.Walk(fileDescriptor, visitor)
+	require.ErrorIs(t, err, testErr)
+	assert.Equal(t, "enum_value:a.Foo.NESTED_ENUM_UNSPECIFIED", visitor.visited[len(visitor.visited)-1])
+
+	// Wrapped errors are returned as-is.
+	wrappedErr := errors.Join(testErr, errors.New("other error"))
+	err = descriptor.Walk(
+		fileDescriptor,
+		&descriptor.VisitorFuncs{
+			Method: func(protoreflect.MethodDescriptor) error {
+				return wrappedErr
+			},
+		},
+	)
+	require.Equal(t, wrappedErr, err)
+}
+
+func TestWalkVisitorFuncs(t *testing.T) {
+	t.Parallel()
+
+	fileDescriptor := testCompileWalkSource(t)
+	var enumValueNames []string
+	require.NoError(
+		t,
+		descriptor.Walk(
+			fileDescriptor,
+			&descriptor.VisitorFuncs{
+				EnumValue: func(enumValueDescriptor protoreflect.EnumValueDescriptor) error {
+					enumValueNames = append(enumValueNames, string(enumValueDescriptor.Name()))
+					return nil
+				},
+			},
+		),
+	)
+	assert.Equal(t, []string{"ENUM_UNSPECIFIED", "ENUM_VALUE", "NESTED_ENUM_UNSPECIFIED"}, enumValueNames)
+}
+
+func testCompileWalkSource(t *testing.T) descriptor.FileDescriptor {
+	fileDescriptors, err := descriptortest.CompileStrings(
+		context.Background(),
+		map[string]string{
+			"a.proto": testWalkSource,
+		},
+	)
+	require.NoError(t, err)
+	require.Len(t, fileDescriptors, 1)
+	return fileDescriptors[0]
+}
+
+// testRecordingVisitor records the Descriptors that are visited as "kind:full_name".
+type testRecordingVisitor struct {
+	// errFor returns the error to return when visiting the Descriptor, if set.
+	errFor  func(string) error
+	visited []string
+}
+
+func (v *testRecordingVisitor) VisitMessage(messageDescriptor protoreflect.MessageDescriptor) error {
+	return v.visit("message", messageDescriptor)
+}
+
+func (v *testRecordingVisitor) VisitField(fieldDescriptor protoreflect.FieldDescriptor) error {
+	return v.visit("field", fieldDescriptor)
+}
+
+func (v *testRecordingVisitor) VisitOneof(oneofDescriptor protoreflect.OneofDescriptor) error {
+	return v.visit("oneof", oneofDescriptor)
+}
+
+func (v *testRecordingVisitor) VisitEnum(enumDescriptor protoreflect.EnumDescriptor) error {
+	return v.visit("enum", enumDescriptor)
+}
+
+func (v *testRecordingVisitor) VisitEnumValue(enumValueDescriptor protoreflect.EnumValueDescriptor) error {
+	return v.visit("enum_value", enumValueDescriptor)
+}
+
+func (v *testRecordingVisitor) VisitService(serviceDescriptor protoreflect.ServiceDescriptor) error {
+	return v.visit("service", serviceDescriptor)
+}
+
+func (v *testRecordingVisitor) VisitMethod(methodDescriptor protoreflect.MethodDescriptor) error {
+	return v.visit("method", methodDescriptor)
+}
+
+func (v *testRecordingVisitor) visit(kind string, descriptor protoreflect.Descriptor) error {
+	visited := kind + ":" + string(descriptor.FullName())
+	v.visited = append(v.visited, visited)
+	if v.errFor == nil {
+		return nil
+	}
+	return v.errFor(visited)
+}
@@ -39,6 +39,18 @@ type PluginInfo interface {
 	//
 	// Optional.
 	Doc() Doc
+	// Deprecated returns whether or not the plugin is deprecated.
+	//
+	// This is not carried by the PluginInfo protocol, and will therefore always be false
+	// for PluginInfos returned from a Client.
+	Deprecated() bool
+	// ReplacementURL returns the URL of the plugin that replaces this plugin, if this
+	// plugin is deprecated.
+	//
+	// Optional.
+	//
+	// Will always be absolute. Will only be present if Deprecated is true.
+	ReplacementURL() *url.URL
 
 	toProto() *infov1.PluginInfo
 
@@ -86,7 +98,14 @@ func NewPluginInfoForSpec(spec *Spec) (PluginInfo, error) {
 			return nil, err
 		}
 	}
-	return newPluginInfo(uri, license, doc)
+	var replacementURI *url.URL
+	if spec.ReplacementURL != "" {
+		replacementURI, err = url.Parse(spec.ReplacementURL)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return newPluginInfo(uri, license, doc, spec.Deprecated, replacementURI)
 }
 
 // *** PRIVATE ***
@@ -96,21 +115,35 @@ type pluginInfo struct {
 	// Need to keep as pointer for Go nil is not nil problem.
 	license *license
 	// Need to keep as pointer for Go nil is not nil problem.
-	doc *doc
+	doc            *doc
+	deprecated     bool
+	replacementURL *url.URL
 }
 
 func newPluginInfo(
 	url *url.URL,
 	license *license,
 	doc *doc,
+	deprecated bool,
+	replacementURL *url.URL,
 ) (*pluginInfo, error) {
 	if url != nil && url.Host == "" {
 		return nil, fmt.Errorf("url %v must be absolute", url)
 	}
+	if replacementURL != nil {
+		if !deprecated {
+			return nil, fmt.Errorf("replacement url %v specified but deprecated is false", replacementURL)
+		}
+		if replacementURL.Host == "" {
+			return nil, fmt.Errorf("replacement url %v must be absolute", replacementURL)
+		}
+	}
 	return &pluginInfo{
-		url:     url,
-		license: license,
-		doc:     doc,
+		url:            url,
+		license:        license,
+		doc:            doc,
+		deprecated:     deprecated,
+		replacementURL: replacementURL,
 	}, nil
 }
 
@@ -134,6 +167,14 @@ func (p *pluginInfo) Doc() Doc {
 	return p.doc
 }
 
+func (p *pluginInfo) Deprecated() bool {
+	return p.deprecated
+}
+
+func (p *pluginInfo) ReplacementURL() *url.URL {
+	return p.replacementURL
+}
+
 func (p *pluginInfo) toProto() *infov1.PluginInfo {
 	var urlString string
 	if p.url != nil {
@@ -168,5 +209,5 @@ func pluginInfoForProtoPluginInfo(protoPluginInfo *infov1.PluginInfo) (PluginInf
 	if err != nil {
 		return nil, err
 	}
-	return newPluginInfo(uri, license, doc)
+	return newPluginInfo(uri, license, doc, false, nil)
 }
@@ -0,0 +1,56 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package info
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewPluginInfoForSpecDeprecated(t *testing.T) {
+	t.Parallel()
+
+	pluginInfo, err := NewPluginInfoForSpec(&Spec{})
+	require.NoError(t, err)
+	require.False(t, pluginInfo.Deprecated())
+	require.Nil(t, pluginInfo.ReplacementURL())
+
+	pluginInfo, err = NewPluginInfoForSpec(
+		&Spec{
+			Deprecated:     true,
+			ReplacementURL: "https://foo.com/replacement",
+		},
+	)
+	require.NoError(t, err)
+	require.True(t, pluginInfo.Deprecated())
+	require.NotNil(t, pluginInfo.ReplacementURL())
+	require.Equal(t, "https://foo.com/replacement", pluginInfo.ReplacementURL().String())
+
+	validateSpecError := &validateSpecError{}
+	_, err = NewPluginInfoForSpec(
+		&Spec{
+			ReplacementURL: "https://foo.com/replacement",
+		},
+	)
+	require.ErrorAs(t, err, &validateSpecError)
+	_, err = NewPluginInfoForSpec(
+		&Spec{
+			Deprecated:     true,
+			ReplacementURL: "foo/replacement",
+		},
+	)
+	require.ErrorAs(t, err, &validateSpecError)
+}
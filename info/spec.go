@@ -63,6 +63,22 @@ type Spec struct {
 	//
 	// May not be set if DocShort is not set.
 	DocLong string
+	// Deprecated denotes that the plugin is deprecated.
+	//
+	// Optional.
+	//
+	// The PluginInfo protocol does not carry deprecation, so this is surfaced on the
+	// PluginInfo returned from NewPluginInfoForSpec, in the -h/--help output of the plugin,
+	// and as a warning written to stderr on every Check when using check.Main.
+	Deprecated bool
+	// ReplacementURL is the URL of the plugin that replaces this plugin, if this plugin is
+	// deprecated.
+	//
+	// Optional.
+	//
+	// May not be set if Deprecated is false.
+	// Must be absolute if set.
+	ReplacementURL string
 }
 
 // ValidateSpec validates all values on a Spec.
@@ -88,6 +104,14 @@ func ValidateSpec(spec *Spec) error {
 	if spec.DocShort == "" && spec.DocLong != "" {
 		return newValidateSpecError("DocShort is empty while DocLong is not empty")
 	}
+	if spec.ReplacementURL != "" {
+		if !spec.Deprecated {
+			return newValidateSpecError("ReplacementURL is set while Deprecated is false")
+		}
+		if err := validateSpecAbsoluteURL(spec.ReplacementURL); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
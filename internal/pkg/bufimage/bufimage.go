@@ -0,0 +1,188 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package bufimage reads buf images.
+package bufimage
+
+import (
+	"errors"
+	"fmt"
+
+	descriptorv1 "buf.build/gen/go/bufbuild/bufplugin/protocolbuffers/go/buf/plugin/descriptor/v1"
+	"buf.build/go/bufplugin/descriptor"
+	"buf.build/go/bufplugin/internal/pkg/xdescriptorpb"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+const (
+	// imageFileExtensionFieldNumber is the field number of buf_extension on buf.alpha.image.v1.ImageFile.
+	//
+	// A buf.alpha.image.v1.Image is wire-compatible with a FileDescriptorSet, and each ImageFile is
+	// wire-compatible with a FileDescriptorProto, with an additional ImageFileExtension field.
+	// Reading an Image as a FileDescriptorSet results in the ImageFileExtension being an unknown
+	// field on each FileDescriptorProto.
+	imageFileExtensionFieldNumber protowire.Number = 8042
+
+	imageFileExtensionIsImportFieldNumber            protowire.Number = 1
+	imageFileExtensionIsSyntaxUnspecifiedFieldNumber protowire.Number = 3
+	imageFileExtensionUnusedDependencyFieldNumber    protowire.Number = 4
+)
+
+// imageFileExtension are the fields of buf.alpha.image.v1.ImageFileExtension that are used.
+type imageFileExtension struct {
+	isImport            bool
+	isSyntaxUnspecified bool
+	unusedDependency    []int32
+}
+
+// FileDescriptorsForData reads the FileDescriptors from the serialized FileDescriptorSet or
+// buf image.
+//
+// If filePaths is set, files not within filePaths are marked as imports. Otherwise, files are
+// marked as imports per the buf image, or not marked as imports if the data is a FileDescriptorSet.
+//
+// The FileDescriptors are ordered per xdescriptorpb.SortFileDescriptorProtos.
+func FileDescriptorsForData(data []byte, filePaths []string) ([]descriptor.FileDescriptor, error) {
+	fileDescriptorSet := &descriptorpb.FileDescriptorSet{}
+	if err := proto.Unmarshal(data, fileDescriptorSet); err != nil {
+		return nil, fmt.Errorf("could not read as a FileDescriptorSet or buf image: %w", err)
+	}
+	if len(fileDescriptorSet.GetFile()) == 0 {
+		return nil, errors.New("no files")
+	}
+	xdescriptorpb.SortFileDescriptorProtos(fileDescriptorSet.GetFile())
+	var filePathMap map[string]struct{}
+	if len(filePaths) > 0 {
+		filePathMap = make(map[string]struct{}, len(filePaths))
+		for _, filePath := range filePaths {
+			filePathMap[filePath] = struct{}{}
+		}
+	}
+	protoFileDescriptors := make([]*descriptorv1.FileDescriptor, len(fileDescriptorSet.GetFile()))
+	for i, fileDescriptorProto := range fileDescriptorSet.GetFile() {
+		imageFileExtension, err := removeImageFileExtension(fileDescriptorProto)
+		if err != nil {
+			return nil, err
+		}
+		if fileDescriptorProto.SourceCodeInfo == nil {
+			// Images and FileDescriptorSets can be built without source code info, however
+			// FileDescriptors always contain SourceCodeInfo.
+			fileDescriptorProto.SourceCodeInfo = &descriptorpb.SourceCodeInfo{}
+		}
+		isImport := imageFileExtension.isImport
+		if filePathMap != nil {
+			_, isNotImport := filePathMap[fileDescriptorProto.GetName()]
+			delete(filePathMap, fileDescriptorProto.GetName())
+			isImport = !isNotImport
+		}
+		protoFileDescriptors[i] = &descriptorv1.FileDescriptor{
+			FileDescriptorProto: fileDescriptorProto,
+			IsImport:            isImport,
+			IsSyntaxUnspecified: imageFileExtension.isSyntaxUnspecified,
+			UnusedDependency:    imageFileExtension.unusedDependency,
+		}
+	}
+	for filePath := range filePathMap {
+		return nil, fmt.Errorf("FilePath %q not found", filePath)
+	}
+	return descriptor.FileDescriptorsForProtoFileDescriptors(protoFileDescriptors)
+}
+
+// removeImageFileExtension removes the ImageFileExtension from the unknown fields of the
+// FileDescriptorProto, and returns it.
+//
+// If the FileDescriptorProto has no ImageFileExtension, an empty imageFileExtension is returned.
+func removeImageFileExtension(fileDescriptorProto *descriptorpb.FileDescriptorProto) (*imageFileExtension, error) {
+	imageFileExtension := &imageFileExtension{}
+	unknown := fileDescriptorProto.ProtoReflect().GetUnknown()
+	var remaining []byte
+	for len(unknown) > 0 {
+		number, wireType, n := protowire.ConsumeTag(unknown)
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		fieldLength := protowire.ConsumeFieldValue(number, wireType, unknown[n:])
+		if fieldLength < 0 {
+			return nil, protowire.ParseError(fieldLength)
+		}
+		field := unknown[:n+fieldLength]
+		if number == imageFileExtensionFieldNumber && wireType == protowire.BytesType {
+			value, _ := protowire.ConsumeBytes(unknown[n:])
+			if err := mergeImageFileExtension(imageFileExtension, value); err != nil {
+				return nil, err
+			}
+		} else {
+			remaining = append(remaining, field...)
+		}
+		unknown = unknown[n+fieldLength:]
+	}
+	fileDescriptorProto.ProtoReflect().SetUnknown(remaining)
+	return imageFileExtension, nil
+}
+
+func mergeImageFileExtension(imageFileExtension *imageFileExtension, data []byte) error {
+	for len(data) > 0 {
+		number, wireType, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		data = data[n:]
+		switch {
+		case number == imageFileExtensionIsImportFieldNumber && wireType == protowire.VarintType:
+			value, n := protowire.ConsumeVarint(data)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			imageFileExtension.isImport = protowire.DecodeBool(value)
+			data = data[n:]
+		case number == imageFileExtensionIsSyntaxUnspecifiedFieldNumber && wireType == protowire.VarintType:
+			value, n := protowire.ConsumeVarint(data)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			imageFileExtension.isSyntaxUnspecified = protowire.DecodeBool(value)
+			data = data[n:]
+		case number == imageFileExtensionUnusedDependencyFieldNumber && wireType == protowire.VarintType:
+			value, n := protowire.ConsumeVarint(data)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			imageFileExtension.unusedDependency = append(imageFileExtension.unusedDependency, int32(value))
+			data = data[n:]
+		case number == imageFileExtensionUnusedDependencyFieldNumber && wireType == protowire.BytesType:
+			packed, n := protowire.ConsumeBytes(data)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			for len(packed) > 0 {
+				value, m := protowire.ConsumeVarint(packed)
+				if m < 0 {
+					return protowire.ParseError(m)
+				}
+				imageFileExtension.unusedDependency = append(imageFileExtension.unusedDependency, int32(value))
+				packed = packed[m:]
+			}
+			data = data[n:]
+		default:
+			n := protowire.ConsumeFieldValue(number, wireType, data)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			data = data[n:]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package xdescriptorpb provides extensions to descriptorpb.
+package xdescriptorpb
+
+import (
+	"cmp"
+	"slices"
+
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// SortFileDescriptorProtos sorts the FileDescriptorProtos topologically, so that every file is
+// after the files it imports, and otherwise lexically by name.
+//
+// That is, of the files whose imports have all been placed, the file with the lexically smallest
+// name is placed next. The result only depends on the set of files, and not their input order.
+// Imports that are not within the FileDescriptorProtos are ignored. The sort is in place.
+func SortFileDescriptorProtos(fileDescriptorProtos []*descriptorpb.FileDescriptorProto) {
+	remaining := slices.Clone(fileDescriptorProtos)
+	slices.SortFunc(
+		remaining,
+		func(one *descriptorpb.FileDescriptorProto, two *descriptorpb.FileDescriptorProto) int {
+			return cmp.Compare(one.GetName(), two.GetName())
+		},
+	)
+	nameToNotPlaced := make(map[string]struct{}, len(remaining))
+	for _, fileDescriptorProto := range remaining {
+		nameToNotPlaced[fileDescriptorProto.GetName()] = struct{}{}
+	}
+	for i := range fileDescriptorProtos {
+		// Files are placed in sorted order, so the first file with all imports placed is the
+		// lexically smallest. If there is an import cycle, the lexically smallest remaining file
+		// is placed, so that the sort always terminates.
+		index := slices.IndexFunc(
+			remaining,
+			func(fileDescriptorProto *descriptorpb.FileDescriptorProto) bool {
+				for _, dependency := range fileDescriptorProto.GetDependency() {
+					if _, notPlaced := nameToNotPlaced[dependency]; notPlaced && dependency != fileDescriptorProto.GetName() {
+						return false
+					}
+				}
+				return true
+			},
+		)
+		if index < 0 {
+			index = 0
+		}
+		fileDescriptorProtos[i] = remaining[index]
+		delete(nameToNotPlaced, remaining[index].GetName())
+		remaining = slices.Delete(remaining, index, index+1)
+	}
+}
@@ -0,0 +1,287 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xprotocompile
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"hash"
+	"io"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"sync"
+
+	"buf.build/go/bufplugin/descriptor"
+	"buf.build/go/bufplugin/internal/pkg/cache"
+	"buf.build/go/bufplugin/internal/pkg/xslices"
+)
+
+// globalCompileCache is the process-wide compilation cache.
+//
+// Tests commonly compile the same testdata many times, and compilation usually dominates
+// the runtime of a test. Compiled FileDescriptors are not modified by callers, so they are
+// shared between tests.
+var globalCompileCache = newCompileCache()
+
+// CacheStats are statistics for the cache used by CachedCompile.
+type CacheStats struct {
+	// Hits is the number of calls that reused the result of a previous call.
+	Hits int
+	// Misses is the number of calls that compiled.
+	Misses int
+	// Entries is the number of distinct inputs within the cache.
+	Entries int
+}
+
+// CachedCompile is CachedCompileResult, returning only the FileDescriptors.
+func CachedCompile(
+	ctx context.Context,
+	fsys fs.FS,
+	dirPaths []string,
+	sources map[string]string,
+	filePaths []string,
+	options ...CompileOption,
+) ([]descriptor.FileDescriptor, error) {
+	result, err := CachedCompileResult(ctx, fsys, dirPaths, sources, filePaths, options...)
+	if err != nil {
+		return nil, err
+	}
+	return result.FileDescriptors, nil
+}
+
+// CachedCompileResult is CompileResult, reusing the result of a previous CachedCompileResult
+// or CachedCompile with the same inputs within the process.
+//
+// The key consists of the dir paths, file paths, sources, options, and the paths and contents
+// of all .proto files within the dir paths, so that changes to testdata invalidate the cache.
+// Errors are cached as well, except for errors from the cancellation or expiration of a
+// context, so that a cancelled compile is retried by the next call. The returned Result is shared, and must not be modified.
+// If a Resolver is set via CompileWithResolver, this is equivalent to CompileResult.
+func CachedCompileResult(
+	ctx context.Context,
+	fsys fs.FS,
+	dirPaths []string,
+	sources map[string]string,
+	filePaths []string,
+	options ...CompileOption,
+) (*Result, error) {
+	compileOptions := newCompileOptions()
+	for _, option := range options {
+		option(compileOptions)
+	}
+	return globalCompileCache.compile(ctx, fsys, dirPaths, sources, filePaths, compileOptions)
+}
+
+// GlobalCacheStats returns the current CacheStats of the cache used by CachedCompile.
+func GlobalCacheStats() CacheStats {
+	return globalCompileCache.stats()
+}
+
+type compileCache struct {
+	keyToSingleton map[string]*cache.Singleton[*Result]
+	hits           int
+	misses         int
+	lock           sync.Mutex
+}
+
+func newCompileCache() *compileCache {
+	return &compileCache{
+		keyToSingleton: make(map[string]*cache.Singleton[*Result]),
+	}
+}
+
+func (c *compileCache) compile(
+	ctx context.Context,
+	fsys fs.FS,
+	dirPaths []string,
+	sources map[string]string,
+	filePaths []string,
+	compileOptions *compileOptions,
+) (*Result, error) {
+	if compileOptions.resolver != nil {
+		return compile(ctx, fsys, dirPaths, sources, filePaths, compileOptions)
+	}
+	key, err := compileCacheKey(fsys, dirPaths, sources, filePaths, compileOptions)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		singleton := c.getOrAdd(key, fsys, dirPaths, sources, filePaths, compileOptions)
+		result, err := singleton.Get(ctx)
+		if err == nil || (!isContextError(err) && ctx.Err() == nil) {
+			return result, err
+		}
+		// The compile was cancelled, which says nothing about the inputs, so the error is
+		// not cached. If our context was not the one that was cancelled, the compile was
+		// started by another caller, and we compile again.
+		c.remove(key, singleton)
+		if ctx.Err() != nil {
+			return nil, err
+		}
+	}
+}
+
+// getOrAdd returns the Singleton for the key, adding a new Singleton that compiles the inputs
+// if the key is not within the cache.
+func (c *compileCache) getOrAdd(
+	key string,
+	fsys fs.FS,
+	dirPaths []string,
+	sources map[string]string,
+	filePaths []string,
+	compileOptions *compileOptions,
+) *cache.Singleton[*Result] {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if singleton, ok := c.keyToSingleton[key]; ok {
+		c.hits++
+		return singleton
+	}
+	c.misses++
+	// Clone the inputs, as the compile may happen after the caller has modified them.
+	dirPaths = slices.Clone(dirPaths)
+	sources = maps.Clone(sources)
+	filePaths = slices.Clone(filePaths)
+	singleton := cache.NewSingleton(
+		func(ctx context.Context) (*Result, error) {
+			return compile(ctx, fsys, dirPaths, sources, filePaths, compileOptions)
+		},
+	)
+	c.keyToSingleton[key] = singleton
+	return singleton
+}
+
+// remove removes the Singleton for the key, if it is still the given Singleton.
+func (c *compileCache) remove(key string, singleton *cache.Singleton[*Result]) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.keyToSingleton[key] == singleton {
+		delete(c.keyToSingleton, key)
+	}
+}
+
+func (c *compileCache) stats() CacheStats {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return CacheStats{
+		Hits:    c.hits,
+		Misses:  c.misses,
+		Entries: len(c.keyToSingleton),
+	}
+}
+
+func compileCacheKey(
+	fsys fs.FS,
+	dirPaths []string,
+	sources map[string]string,
+	filePaths []string,
+	compileOptions *compileOptions,
+) (string, error) {
+	hash := sha256.New()
+	writeCompileCacheKeyStrings(hash, "dirPaths", dirPaths)
+	writeCompileCacheKeyStrings(hash, "filePaths", filePaths)
+	writeCompileCacheKeyStrings(
+		hash,
+		"options",
+		[]string{
+			strconv.Itoa(int(compileOptions.sourceInfoMode)),
+			strconv.Itoa(int(compileOptions.maxEdition)),
+		},
+	)
+	sourcePaths := xslices.MapKeysToSortedSlice(sources)
+	writeCompileCacheKeyStrings(hash, "sources", sourcePaths)
+	for _, sourcePath := range sourcePaths {
+		writeCompileCacheKeyString(hash, sources[sourcePath])
+	}
+	walkDir := filepath.WalkDir
+	openFile := func(path string) (fs.File, error) {
+		return os.Open(path)
+	}
+	if fsys != nil {
+		walkDir = func(root string, walkDirFunc fs.WalkDirFunc) error {
+			return fs.WalkDir(fsys, root, walkDirFunc)
+		}
+		openFile = fsys.Open
+	}
+	for _, dirPath := range dirPaths {
+		if fsys == nil {
+			dirPath = filepath.FromSlash(dirPath)
+		}
+		if err := walkDir(
+			dirPath,
+			func(path string, dirEntry fs.DirEntry, err error) error {
+				if err != nil {
+					return err
+				}
+				if dirEntry.IsDir() || filepath.Ext(path) != ".proto" {
+					return nil
+				}
+				writeCompileCacheKeyString(hash, path)
+				return writeCompileCacheKeyFile(hash, openFile, path)
+			},
+		); err != nil {
+			return "", err
+		}
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+func writeCompileCacheKeyStrings(hash hash.Hash, name string, values []string) {
+	writeCompileCacheKeyString(hash, name)
+	writeCompileCacheKeyString(hash, strconv.Itoa(len(values)))
+	for _, value := range values {
+		writeCompileCacheKeyString(hash, value)
+	}
+}
+
+// writeCompileCacheKeyString writes a length-prefixed string so that
+// adjacent values cannot collide.
+func writeCompileCacheKeyString(hash hash.Hash, value string) {
+	_, _ = hash.Write([]byte(strconv.Itoa(len(value)) + ":" + value))
+}
+
+func writeCompileCacheKeyFile(
+	hash hash.Hash,
+	openFile func(string) (fs.File, error),
+	path string,
+) (retErr error) {
+	file, err := openFile(path)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := file.Close(); err != nil && retErr == nil {
+			retErr = err
+		}
+	}()
+	fileInfo, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	writeCompileCacheKeyString(hash, strconv.FormatInt(fileInfo.Size(), 10))
+	_, err = io.Copy(hash, file)
+	return err
+}
+
+// isContextError returns true if the error is from the cancellation or expiration of a context.
+func isContextError(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
@@ -0,0 +1,68 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xprotocompile
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompileCacheCancelled(t *testing.T) {
+	t.Parallel()
+
+	compileCache := newCompileCache()
+	sources := map[string]string{
+		"foo.proto": `syntax = "proto3";
+package foo;
+message Foo {}
+`,
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := compileCache.compile(ctx, nil, nil, sources, []string{"foo.proto"}, newCompileOptions())
+	require.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, CacheStats{Misses: 1}, compileCache.stats())
+
+	// The cancelled compile is not cached.
+	result, err := compileCache.compile(context.Background(), nil, nil, sources, []string{"foo.proto"}, newCompileOptions())
+	require.NoError(t, err)
+	require.Len(t, result.FileDescriptors, 1)
+	assert.Equal(t, CacheStats{Misses: 2, Entries: 1}, compileCache.stats())
+
+	result2, err := compileCache.compile(context.Background(), nil, nil, sources, []string{"foo.proto"}, newCompileOptions())
+	require.NoError(t, err)
+	assert.Same(t, result, result2)
+	assert.Equal(t, CacheStats{Hits: 1, Misses: 2, Entries: 1}, compileCache.stats())
+}
+
+func TestCompileCacheError(t *testing.T) {
+	t.Parallel()
+
+	compileCache := newCompileCache()
+	sources := map[string]string{
+		"foo.proto": `syntax = "proto3";
+message Foo {
+`,
+	}
+	_, err := compileCache.compile(context.Background(), nil, nil, sources, []string{"foo.proto"}, newCompileOptions())
+	require.Error(t, err)
+	// Errors other than cancellation are cached.
+	_, err = compileCache.compile(context.Background(), nil, nil, sources, []string{"foo.proto"}, newCompileOptions())
+	require.Error(t, err)
+	assert.Equal(t, CacheStats{Hits: 1, Misses: 1, Entries: 1}, compileCache.stats())
+}
@@ -0,0 +1,377 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package xprotocompile provides extensions to protocompile.
+package xprotocompile
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path/filepath"
+	"slices"
+
+	descriptorv1 "buf.build/gen/go/bufbuild/bufplugin/protocolbuffers/go/buf/plugin/descriptor/v1"
+	"buf.build/go/bufplugin/descriptor"
+	"buf.build/go/bufplugin/internal/pkg/xdescriptorpb"
+	"github.com/bufbuild/protocompile"
+	"github.com/bufbuild/protocompile/linker"
+	"github.com/bufbuild/protocompile/parser"
+	"github.com/bufbuild/protocompile/protoutil"
+	"github.com/bufbuild/protocompile/reporter"
+	"github.com/bufbuild/protocompile/wellknownimports"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// Result is the result of CompileResult.
+type Result struct {
+	FileDescriptors []descriptor.FileDescriptor
+	// Warnings are the warnings that were not folded into the FileDescriptors.
+	//
+	// Warnings for files without a syntax and for unused imports are reported via
+	// IsSyntaxUnspecified and UnusedDependencyIndexes instead. Warnings are sorted by
+	// file path, position, and message.
+	Warnings []Warning
+}
+
+// Warning is a warning produced by the compiler.
+type Warning struct {
+	// FilePath is the path of the file the warning is for.
+	FilePath string
+	// Line is the one-indexed line of the warning, or 0 if unknown.
+	Line int
+	// Column is the one-indexed column of the warning, or 0 if unknown.
+	Column int
+	// Message is the message of the warning, without the position.
+	Message string
+}
+
+// CompileOption is an option for Compile.
+type CompileOption func(*compileOptions)
+
+// CompileWithSourceInfoMode returns a new CompileOption that sets the SourceInfoMode.
+//
+// The default is protocompile.SourceInfoExtraOptionLocations, which is what buf uses.
+func CompileWithSourceInfoMode(sourceInfoMode protocompile.SourceInfoMode) CompileOption {
+	return func(compileOptions *compileOptions) {
+		compileOptions.sourceInfoMode = sourceInfoMode
+	}
+}
+
+// CompileWithMaxEdition returns a new CompileOption that results in an error if any compiled
+// file has an edition greater than maxEdition.
+//
+// proto2 files are considered to be EDITION_PROTO2, and proto3 files EDITION_PROTO3. The
+// default is EDITION_UNKNOWN, which allows all editions supported by protocompile.
+func CompileWithMaxEdition(maxEdition descriptorpb.Edition) CompileOption {
+	return func(compileOptions *compileOptions) {
+		compileOptions.maxEdition = maxEdition
+	}
+}
+
+// CompileWithResolver returns a new CompileOption that resolves files with the given
+// protocompile.Resolver before the sources and dirPaths.
+//
+// Results are never cached by CachedCompile if a Resolver is set, as the files a Resolver
+// returns cannot be part of the key.
+func CompileWithResolver(resolver protocompile.Resolver) CompileOption {
+	return func(compileOptions *compileOptions) {
+		compileOptions.resolver = resolver
+	}
+}
+
+// Compile is CompileResult, returning only the FileDescriptors.
+func Compile(
+	ctx context.Context,
+	fsys fs.FS,
+	dirPaths []string,
+	sources map[string]string,
+	filePaths []string,
+	options ...CompileOption,
+) ([]descriptor.FileDescriptor, error) {
+	result, err := CompileResult(ctx, fsys, dirPaths, sources, filePaths, options...)
+	if err != nil {
+		return nil, err
+	}
+	return result.FileDescriptors, nil
+}
+
+// CompileResult compiles the files at the filePaths into descriptor.FileDescriptors.
+//
+// Files are resolved from the Resolver first if set via CompileWithResolver, then from the
+// sources, keyed by their path, and then from the dirPaths, read from the fsys if set. The Well-Known Types are always available. Paths use forward
+// slashes. Any imports of the filePaths are compiled as well, and marked as imports.
+//
+// The FileDescriptors are ordered per xdescriptorpb.SortFileDescriptorProtos.
+func CompileResult(
+	ctx context.Context,
+	fsys fs.FS,
+	dirPaths []string,
+	sources map[string]string,
+	filePaths []string,
+	options ...CompileOption,
+) (*Result, error) {
+	compileOptions := newCompileOptions()
+	for _, option := range options {
+		option(compileOptions)
+	}
+	return compile(ctx, fsys, dirPaths, sources, filePaths, compileOptions)
+}
+
+type compileOptions struct {
+	sourceInfoMode protocompile.SourceInfoMode
+	maxEdition     descriptorpb.Edition
+	resolver       protocompile.Resolver
+}
+
+func newCompileOptions() *compileOptions {
+	return &compileOptions{
+		// This is what buf uses.
+		sourceInfoMode: protocompile.SourceInfoExtraOptionLocations,
+	}
+}
+
+func compile(
+	ctx context.Context,
+	fsys fs.FS,
+	dirPaths []string,
+	sources map[string]string,
+	filePaths []string,
+	compileOptions *compileOptions,
+) (*Result, error) {
+	dirPaths = fromSlashPaths(dirPaths)
+	filePaths = fromSlashPaths(filePaths)
+	toSlashFilePathMap := make(map[string]struct{}, len(filePaths))
+	for _, filePath := range filePaths {
+		toSlashFilePathMap[filepath.ToSlash(filePath)] = struct{}{}
+	}
+
+	var resolvers protocompile.CompositeResolver
+	if compileOptions.resolver != nil {
+		resolvers = append(resolvers, compileOptions.resolver)
+	}
+	if len(sources) > 0 {
+		sourceAccessor := protocompile.SourceAccessorFromMap(sources)
+		resolvers = append(
+			resolvers,
+			&protocompile.SourceResolver{
+				Accessor: func(path string) (io.ReadCloser, error) {
+					return sourceAccessor(filepath.ToSlash(path))
+				},
+			},
+		)
+	}
+	// If only Sources are specified, we do not want to fall back to the current directory.
+	if len(dirPaths) > 0 {
+		sourceResolver := &protocompile.SourceResolver{
+			ImportPaths: dirPaths,
+		}
+		if fsys != nil {
+			// Paths within an fs.FS always use forward slashes.
+			sourceResolver.Accessor = func(path string) (io.ReadCloser, error) {
+				return fsys.Open(filepath.ToSlash(path))
+			}
+		}
+		resolvers = append(resolvers, sourceResolver)
+	}
+	var warningErrorsWithPos []reporter.ErrorWithPos
+	compiler := protocompile.Compiler{
+		Resolver: wellknownimports.WithStandardImports(resolvers),
+		Reporter: reporter.NewReporter(
+			func(reporter.ErrorWithPos) error {
+				return nil
+			},
+			func(errorWithPos reporter.ErrorWithPos) {
+				warningErrorsWithPos = append(warningErrorsWithPos, errorWithPos)
+			},
+		),
+		SourceInfoMode: compileOptions.sourceInfoMode,
+	}
+	files, err := compiler.Compile(ctx, filePaths...)
+	if err != nil {
+		return nil, err
+	}
+	syntaxUnspecifiedFilePaths := make(map[string]struct{})
+	filePathToUnusedDependencyFilePaths := make(map[string]map[string]struct{})
+	var warnings []Warning
+	for _, warningErrorWithPos := range warningErrorsWithPos {
+		if maybeAddSyntaxUnspecified(syntaxUnspecifiedFilePaths, warningErrorWithPos) {
+			continue
+		}
+		if maybeAddUnusedDependency(filePathToUnusedDependencyFilePaths, warningErrorWithPos) {
+			continue
+		}
+		warnings = append(warnings, warningForErrorWithPos(warningErrorWithPos))
+	}
+	sortWarnings(warnings)
+	fileDescriptorSet := fileDescriptorSetForFileDescriptors(files)
+	xdescriptorpb.SortFileDescriptorProtos(fileDescriptorSet.GetFile())
+
+	protoFileDescriptors := make([]*descriptorv1.FileDescriptor, len(fileDescriptorSet.GetFile()))
+	for i, fileDescriptorProto := range fileDescriptorSet.GetFile() {
+		_, isNotImport := toSlashFilePathMap[fileDescriptorProto.GetName()]
+		_, isSyntaxUnspecified := syntaxUnspecifiedFilePaths[fileDescriptorProto.GetName()]
+		unusedDependencyIndexes := unusedDependencyIndexesForFilePathToUnusedDependencyFilePaths(
+			fileDescriptorProto,
+			filePathToUnusedDependencyFilePaths[fileDescriptorProto.GetName()],
+		)
+		if fileDescriptorProto.SourceCodeInfo == nil {
+			// protocompile.SourceInfoNone produces no source code info, however
+			// FileDescriptors always contain SourceCodeInfo.
+			fileDescriptorProto.SourceCodeInfo = &descriptorpb.SourceCodeInfo{}
+		}
+		protoFileDescriptors[i] = &descriptorv1.FileDescriptor{
+			FileDescriptorProto: fileDescriptorProto,
+			IsImport:            !isNotImport,
+			IsSyntaxUnspecified: isSyntaxUnspecified,
+			UnusedDependency:    unusedDependencyIndexes,
+		}
+	}
+	fileDescriptors, err := descriptor.FileDescriptorsForProtoFileDescriptors(protoFileDescriptors)
+	if err != nil {
+		return nil, err
+	}
+	if compileOptions.maxEdition != descriptorpb.Edition_EDITION_UNKNOWN {
+		for _, fileDescriptor := range fileDescriptors {
+			if edition := fileDescriptor.Edition(); edition > compileOptions.maxEdition {
+				return nil, fmt.Errorf(
+					"%s: edition %v is greater than the maximum edition %v",
+					fileDescriptor.ProtoreflectFileDescriptor().Path(),
+					edition,
+					compileOptions.maxEdition,
+				)
+			}
+		}
+	}
+	return &Result{
+		FileDescriptors: fileDescriptors,
+		Warnings:        warnings,
+	}, nil
+}
+
+func unusedDependencyIndexesForFilePathToUnusedDependencyFilePaths(
+	fileDescriptorProto *descriptorpb.FileDescriptorProto,
+	unusedDependencyFilePaths map[string]struct{},
+) []int32 {
+	unusedDependencyIndexes := make([]int32, 0, len(unusedDependencyFilePaths))
+	if len(unusedDependencyFilePaths) == 0 {
+		return unusedDependencyIndexes
+	}
+	dependencyFilePaths := fileDescriptorProto.GetDependency()
+	for i := 0; i < len(dependencyFilePaths); i++ {
+		if _, ok := unusedDependencyFilePaths[dependencyFilePaths[i]]; ok {
+			unusedDependencyIndexes = append(unusedDependencyIndexes, int32(i))
+		}
+	}
+	return unusedDependencyIndexes
+}
+
+// maybeAddSyntaxUnspecified returns true if the warning was for a file without a syntax.
+func maybeAddSyntaxUnspecified(
+	syntaxUnspecifiedFilePaths map[string]struct{},
+	errorWithPos reporter.ErrorWithPos,
+) bool {
+	if !errors.Is(errorWithPos, parser.ErrNoSyntax) {
+		return false
+	}
+	syntaxUnspecifiedFilePaths[errorWithPos.GetPosition().Filename] = struct{}{}
+	return true
+}
+
+// maybeAddUnusedDependency returns true if the warning was for an unused import.
+func maybeAddUnusedDependency(
+	filePathToUnusedDependencyFilePaths map[string]map[string]struct{},
+	errorWithPos reporter.ErrorWithPos,
+) bool {
+	var errorUnusedImport linker.ErrorUnusedImport
+	if !errors.As(errorWithPos, &errorUnusedImport) {
+		return false
+	}
+	pos := errorWithPos.GetPosition()
+	unusedDependencyFilePaths, ok := filePathToUnusedDependencyFilePaths[pos.Filename]
+	if !ok {
+		unusedDependencyFilePaths = make(map[string]struct{})
+		filePathToUnusedDependencyFilePaths[pos.Filename] = unusedDependencyFilePaths
+	}
+	unusedDependencyFilePaths[errorUnusedImport.UnusedImport()] = struct{}{}
+	return true
+}
+
+func warningForErrorWithPos(errorWithPos reporter.ErrorWithPos) Warning {
+	pos := errorWithPos.GetPosition()
+	message := errorWithPos.Error()
+	if err := errorWithPos.Unwrap(); err != nil {
+		message = err.Error()
+	}
+	return Warning{
+		FilePath: filepath.ToSlash(pos.Filename),
+		Line:     pos.Line,
+		Column:   pos.Col,
+		Message:  message,
+	}
+}
+
+// sortWarnings sorts the warnings, as files are compiled concurrently, and warnings are
+// therefore reported in a nondeterministic order.
+func sortWarnings(warnings []Warning) {
+	slices.SortFunc(
+		warnings,
+		func(one Warning, two Warning) int {
+			if c := cmp.Compare(one.FilePath, two.FilePath); c != 0 {
+				return c
+			}
+			if c := cmp.Compare(one.Line, two.Line); c != 0 {
+				return c
+			}
+			if c := cmp.Compare(one.Column, two.Column); c != 0 {
+				return c
+			}
+			return cmp.Compare(one.Message, two.Message)
+		},
+	)
+}
+
+func fileDescriptorSetForFileDescriptors[D protoreflect.FileDescriptor](files []D) *descriptorpb.FileDescriptorSet {
+	soFar := make(map[string]struct{}, len(files))
+	slice := make([]*descriptorpb.FileDescriptorProto, 0, len(files))
+	for _, file := range files {
+		toFileDescriptorProtoSlice(file, &slice, soFar)
+	}
+	return &descriptorpb.FileDescriptorSet{File: slice}
+}
+
+func toFileDescriptorProtoSlice(file protoreflect.FileDescriptor, results *[]*descriptorpb.FileDescriptorProto, soFar map[string]struct{}) {
+	if _, exists := soFar[file.Path()]; exists {
+		return
+	}
+	soFar[file.Path()] = struct{}{}
+	// Add dependencies first so the resulting slice is in topological order
+	imports := file.Imports()
+	for i, length := 0, imports.Len(); i < length; i++ {
+		toFileDescriptorProtoSlice(imports.Get(i).FileDescriptor, results, soFar)
+	}
+	*results = append(*results, protoutil.ProtoFromFileDescriptor(file))
+}
+
+func fromSlashPaths(paths []string) []string {
+	fromSlashPaths := make([]string, len(paths))
+	for i, path := range paths {
+		fromSlashPaths[i] = filepath.Clean(filepath.FromSlash(path))
+	}
+	return fromSlashPaths
+}
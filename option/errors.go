@@ -43,3 +43,24 @@ func (u *unexpectedOptionValueTypeError) Error() string {
 	_, _ = sb.WriteString(fmt.Sprintf(`": expected %T, got %T`, u.expected, u.actual))
 	return sb.String()
 }
+
+type modifiedOptionValueError struct {
+	key string
+}
+
+func newModifiedOptionValueError(key string) *modifiedOptionValueError {
+	return &modifiedOptionValueError{
+		key: key,
+	}
+}
+
+func (m *modifiedOptionValueError) Error() string {
+	if m == nil {
+		return ""
+	}
+	var sb strings.Builder
+	_, _ = sb.WriteString(`option value "`)
+	_, _ = sb.WriteString(m.key)
+	_, _ = sb.WriteString(`" was modified after the Options were created, values returned from BorrowBytesValue must not be modified`)
+	return sb.String()
+}
@@ -24,9 +24,12 @@
 package option
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
+	"os"
 	"reflect"
+	"sync"
 
 	optionv1 "buf.build/gen/go/bufbuild/bufplugin/protocolbuffers/go/buf/plugin/option/v1"
 )
@@ -157,8 +160,25 @@ func GetStringValue(options Options, key string) (string, error) {
 
 // GetBytesValue gets a bytes value from the Options.
 //
+// This is equivalent to BorrowBytesValue. The returned slice must not be modified.
+//
 // If the value is present and is not of type bytes, an error is returned.
 func GetBytesValue(options Options, key string) ([]byte, error) {
+	return BorrowBytesValue(options, key)
+}
+
+// BorrowBytesValue gets a bytes value from the Options without copying it.
+//
+// The returned slice is shared with the Options and every other caller, and must not be modified.
+// Use this for large values that are only read, such as embedded configuration. If the value needs
+// to be modified, use CloneBytesValue instead.
+//
+// If the environment variable BUFPLUGIN_DEBUG_OPTIONS is set to "1" when the process starts,
+// bytes values are checked on every call to BorrowBytesValue and GetBytesValue, and an error is
+// returned if a value was modified since the Options were created.
+//
+// If the value is present and is not of type bytes, an error is returned.
+func BorrowBytesValue(options Options, key string) ([]byte, error) {
 	anyValue, ok := options.Get(key)
 	if !ok {
 		return nil, nil
@@ -167,9 +187,27 @@ func GetBytesValue(options Options, key string) ([]byte, error) {
 	if !ok {
 		return nil, newUnexpectedOptionValueTypeError(key, []byte{}, anyValue)
 	}
+	if checker, ok := options.(bytesValueModificationChecker); ok {
+		if err := checker.checkBytesValueNotModified(key, value); err != nil {
+			return nil, err
+		}
+	}
 	return value, nil
 }
 
+// CloneBytesValue gets a copy of a bytes value from the Options.
+//
+// The returned slice is owned by the caller and may be modified.
+//
+// If the value is present and is not of type bytes, an error is returned.
+func CloneBytesValue(options Options, key string) ([]byte, error) {
+	value, err := BorrowBytesValue(options, key)
+	if err != nil || value == nil {
+		return nil, err
+	}
+	return bytes.Clone(value), nil
+}
+
 // GetInt64SliceValue gets a []int64 value from the Options.
 //
 // If the value is present and is not of type []int64, an error is returned.
@@ -217,16 +255,44 @@ func GetStringSliceValue(options Options, key string) ([]string, error) {
 
 // *** PRIVATE ***
 
+const debugOptionsEnvKey = "BUFPLUGIN_DEBUG_OPTIONS"
+
+var getDebugOptions = sync.OnceValue(
+	func() bool {
+		return os.Getenv(debugOptionsEnvKey) == "1"
+	},
+)
+
+type bytesValueModificationChecker interface {
+	checkBytesValueNotModified(key string, value []byte) error
+}
+
 type options struct {
 	keyToValue map[string]any
+	// keyToBytesValueSnapshot contains a copy of every bytes value. Only populated in debug mode.
+	keyToBytesValueSnapshot map[string][]byte
 }
 
 func newOptionsNoValidate(keyToValue map[string]any) *options {
+	return newOptionsNoValidateWithDebug(keyToValue, getDebugOptions())
+}
+
+func newOptionsNoValidateWithDebug(keyToValue map[string]any, debug bool) *options {
 	if keyToValue == nil {
 		keyToValue = make(map[string]any)
 	}
+	var keyToBytesValueSnapshot map[string][]byte
+	if debug {
+		keyToBytesValueSnapshot = make(map[string][]byte)
+		for key, value := range keyToValue {
+			if bytesValue, ok := value.([]byte); ok {
+				keyToBytesValueSnapshot[key] = bytes.Clone(bytesValue)
+			}
+		}
+	}
 	return &options{
-		keyToValue: keyToValue,
+		keyToValue:              keyToValue,
+		keyToBytesValueSnapshot: keyToBytesValueSnapshot,
 	}
 }
 
@@ -263,6 +329,20 @@ func (o *options) ToProto() ([]*optionv1.Option, error) {
 	return protoOptions, nil
 }
 
+func (o *options) checkBytesValueNotModified(key string, value []byte) error {
+	if o.keyToBytesValueSnapshot == nil {
+		return nil
+	}
+	snapshot, ok := o.keyToBytesValueSnapshot[key]
+	if !ok {
+		return nil
+	}
+	if !bytes.Equal(snapshot, value) {
+		return newModifiedOptionValueError(key)
+	}
+	return nil
+}
+
 func (*options) isOption() {}
 
 // You can assume that value is a valid value.
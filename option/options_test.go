@@ -83,3 +83,44 @@ func testOptionsRoundTripDifferentInputOutput(t *testing.T, input any, expectedO
 	require.NoError(t, err)
 	assert.Equal(t, expectedOutput, actualValue)
 }
+
+func TestBorrowCloneBytesValue(t *testing.T) {
+	t.Parallel()
+
+	options := newOptionsNoValidateWithDebug(map[string]any{"foo_key": []byte("foo")}, false)
+	borrowedValue, err := BorrowBytesValue(options, "foo_key")
+	require.NoError(t, err)
+	clonedValue, err := CloneBytesValue(options, "foo_key")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("foo"), borrowedValue)
+	assert.Equal(t, []byte("foo"), clonedValue)
+	clonedValue[0] = 'b'
+	value, err := GetBytesValue(options, "foo_key")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("foo"), value)
+	value, err = CloneBytesValue(options, "bar_key")
+	require.NoError(t, err)
+	assert.Nil(t, value)
+	_, err = CloneBytesValue(newOptionsNoValidateWithDebug(map[string]any{"foo_key": "foo"}, false), "foo_key")
+	assert.Error(t, err)
+}
+
+func TestBorrowBytesValueDebugModified(t *testing.T) {
+	t.Parallel()
+
+	options := newOptionsNoValidateWithDebug(map[string]any{"foo_key": []byte("foo")}, true)
+	value, err := BorrowBytesValue(options, "foo_key")
+	require.NoError(t, err)
+	value[0] = 'b'
+	_, err = BorrowBytesValue(options, "foo_key")
+	assert.Error(t, err)
+	_, err = GetBytesValue(options, "foo_key")
+	assert.Error(t, err)
+
+	options = newOptionsNoValidateWithDebug(map[string]any{"foo_key": []byte("foo")}, false)
+	value, err = BorrowBytesValue(options, "foo_key")
+	require.NoError(t, err)
+	value[0] = 'b'
+	_, err = BorrowBytesValue(options, "foo_key")
+	assert.NoError(t, err)
+}